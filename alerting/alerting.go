@@ -0,0 +1,229 @@
+// Package alerting sends the alerts of the scraper, such as failed jobs, to
+// notification channels: webhooks, Slack, email and Telegram. The channels
+// an alert goes to depend on its severity, so that the critical alerts can
+// page while the warnings go to a chat.
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is how urgent an alert is.
+type Severity string
+
+// The severities of the alerts.
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Valid reports whether s is a known severity.
+func (s Severity) Valid() bool {
+	return s == SeverityInfo || s == SeverityWarning || s == SeverityCritical
+}
+
+// Alert is a notification about the scraper.
+type Alert struct {
+	Severity Severity  `json:"severity"`
+	Title    string    `json:"title"`
+	Text     string    `json:"text"`
+	Time     time.Time `json:"time"`
+}
+
+// message returns the alert as the text of a chat message or of an email.
+func (a *Alert) message() string {
+	return fmt.Sprintf("[%s] %s\n%s", a.Severity, a.Title, a.Text)
+}
+
+// NotificationChannel sends alerts somewhere people see them.
+type NotificationChannel interface {
+	// Name is the name of the channel in the routes of the config
+	Name() string
+	Notify(ctx context.Context, a *Alert) error
+}
+
+// Router sends the alerts to the channels of their severity.
+type Router struct {
+	channels map[string]NotificationChannel
+	routes   map[Severity][]string
+}
+
+// NewRouter returns a router that sends the alerts of each severity to the
+// channels named in routes. It fails when a route names an unknown channel.
+func NewRouter(channels []NotificationChannel, routes map[Severity][]string) (*Router, error) {
+	ans := Router{
+		channels: make(map[string]NotificationChannel, len(channels)),
+		routes:   routes,
+	}
+
+	for _, ch := range channels {
+		if _, ok := ans.channels[ch.Name()]; ok {
+			return nil, fmt.Errorf("duplicate notification channel %q", ch.Name())
+		}
+
+		ans.channels[ch.Name()] = ch
+	}
+
+	for severity, names := range routes {
+		if !severity.Valid() {
+			return nil, fmt.Errorf("invalid severity %q", severity)
+		}
+
+		for _, name := range names {
+			if _, ok := ans.channels[name]; !ok {
+				return nil, fmt.Errorf("severity %s is routed to unknown channel %q", severity, name)
+			}
+		}
+	}
+
+	return &ans, nil
+}
+
+// Notify sends a to the channels of its severity. A channel that fails does
+// not stop the others, the errors are joined. It does nothing when r is nil.
+func (r *Router) Notify(ctx context.Context, a *Alert) error {
+	if r == nil {
+		return nil
+	}
+
+	if a.Time.IsZero() {
+		a.Time = time.Now().UTC()
+	}
+
+	var errs []error
+
+	for _, name := range r.routes[a.Severity] {
+		if err := r.channels[name].Notify(ctx, a); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Config is the alerting config file, e.g.:
+//
+//	channels:
+//	  - name: oncall
+//	    type: smtp
+//	    addr: smtp.example.com:587
+//	    username: alerts@example.com
+//	    password: ${SMTP_PASSWORD}
+//	    from: alerts@example.com
+//	    to: [oncall@example.com]
+//	  - name: chat
+//	    type: telegram
+//	    token: ${TELEGRAM_BOT_TOKEN}
+//	    chat_id: "-1001234567890"
+//	routes:
+//	  critical: [oncall, chat]
+//	  warning: [chat]
+//	rules:
+//	  - name: jobs_blocked
+//	    metric: block_rate
+//	    op: ">"
+//	    threshold: 0.5
+//	    for: 15m
+//	    severity: critical
+//
+// The ${VAR} references are replaced with the environment variables, so the
+// secrets need not be written in the file. Without rules the DefaultRules
+// are used.
+type Config struct {
+	Channels []ChannelConfig       `yaml:"channels"`
+	Routes   map[Severity][]string `yaml:"routes"`
+	Rules    []Rule                `yaml:"rules"`
+}
+
+// The types of the channels.
+const (
+	ChannelWebhook  = "webhook"
+	ChannelSMTP     = "smtp"
+	ChannelTelegram = "telegram"
+)
+
+// ChannelConfig configures a channel. The fields used depend on its type.
+type ChannelConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// URL is the url of the webhook, a Slack incoming webhook gets messages
+	URL string `yaml:"url"`
+	// Addr is the host:port of the SMTP server
+	Addr     string   `yaml:"addr"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	// Token and ChatID are the token of the Telegram bot and the chat it writes to
+	Token  string `yaml:"token"`
+	ChatID string `yaml:"chat_id"`
+}
+
+// LoadConfig reads the config file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ans Config
+
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &ans); err != nil {
+		return nil, fmt.Errorf("invalid alerting config %s: %w", path, err)
+	}
+
+	return &ans, nil
+}
+
+// NewRouter returns the router of the channels and routes of c.
+func (c *Config) NewRouter() (*Router, error) {
+	channels := make([]NotificationChannel, 0, len(c.Channels))
+
+	for i := range c.Channels {
+		ch, err := c.Channels[i].newChannel()
+		if err != nil {
+			return nil, err
+		}
+
+		channels = append(channels, ch)
+	}
+
+	return NewRouter(channels, c.Routes)
+}
+
+func (c *ChannelConfig) newChannel() (NotificationChannel, error) {
+	if c.Name == "" {
+		return nil, errors.New("notification channel without a name")
+	}
+
+	switch c.Type {
+	case ChannelWebhook:
+		if c.URL == "" {
+			return nil, fmt.Errorf("webhook channel %s needs a url", c.Name)
+		}
+
+		return NewWebhookChannel(c.Name, c.URL), nil
+	case ChannelSMTP:
+		if c.Addr == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("smtp channel %s needs an addr, a from and a to", c.Name)
+		}
+
+		return NewSMTPChannel(c.Name, c.Addr, c.Username, c.Password, c.From, slices.Clone(c.To)), nil
+	case ChannelTelegram:
+		if c.Token == "" || c.ChatID == "" {
+			return nil, fmt.Errorf("telegram channel %s needs a token and a chat_id", c.Name)
+		}
+
+		return NewTelegramChannel(c.Name, c.Token, c.ChatID), nil
+	}
+
+	return nil, fmt.Errorf("invalid type %q of notification channel %s", c.Type, c.Name)
+}
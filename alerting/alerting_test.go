@@ -0,0 +1,193 @@
+package alerting_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/alerting"
+)
+
+type fakeChannel struct {
+	name   string
+	err    error
+	alerts []*alerting.Alert
+}
+
+func (f *fakeChannel) Name() string {
+	return f.name
+}
+
+func (f *fakeChannel) Notify(_ context.Context, a *alerting.Alert) error {
+	f.alerts = append(f.alerts, a)
+
+	return f.err
+}
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	pager := &fakeChannel{name: "pager", err: errors.New("down")}
+	chat := &fakeChannel{name: "chat"}
+
+	router, err := alerting.NewRouter([]alerting.NotificationChannel{pager, chat}, map[alerting.Severity][]string{
+		alerting.SeverityCritical: {"pager", "chat"},
+		alerting.SeverityWarning:  {"chat"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, router.Notify(context.Background(), &alerting.Alert{Severity: alerting.SeverityWarning, Title: "slow"}))
+	require.NoError(t, router.Notify(context.Background(), &alerting.Alert{Severity: alerting.SeverityInfo, Title: "started"}))
+
+	// the failing pager does not stop the chat
+	err = router.Notify(context.Background(), &alerting.Alert{Severity: alerting.SeverityCritical, Title: "blocked"})
+	require.ErrorContains(t, err, "pager: down")
+
+	require.Len(t, pager.alerts, 1)
+	require.Len(t, chat.alerts, 2)
+	require.False(t, chat.alerts[0].Time.IsZero())
+
+	_, err = alerting.NewRouter([]alerting.NotificationChannel{chat}, map[alerting.Severity][]string{
+		alerting.SeverityCritical: {"pager"},
+	})
+	require.ErrorContains(t, err, "unknown channel")
+
+	var none *alerting.Router
+	require.NoError(t, none.Notify(context.Background(), &alerting.Alert{}))
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("ALERTING_TEST_TOKEN", "123:abc")
+
+	path := filepath.Join(t.TempDir(), "alerts.yaml")
+
+	err := os.WriteFile(path, []byte(`
+channels:
+  - name: chat
+    type: telegram
+    token: ${ALERTING_TEST_TOKEN}
+    chat_id: "-100"
+  - name: hook
+    type: webhook
+    url: https://example.com/hook
+routes:
+  critical: [chat, hook]
+  warning: [hook]
+`), 0o600)
+	require.NoError(t, err)
+
+	cfg, err := alerting.LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "123:abc", cfg.Channels[0].Token)
+	require.Equal(t, []string{"chat", "hook"}, cfg.Routes[alerting.SeverityCritical])
+
+	_, err = cfg.NewRouter()
+	require.NoError(t, err)
+
+	cfg.Channels[1].URL = ""
+
+	_, err = cfg.NewRouter()
+	require.ErrorContains(t, err, "needs a url")
+}
+
+func TestTelegramChannel(t *testing.T) {
+	var got map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/bot123:abc/sendMessage", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	api := alerting.TelegramAPI
+	alerting.TelegramAPI = srv.URL
+
+	defer func() { alerting.TelegramAPI = api }()
+
+	ch := alerting.NewTelegramChannel("chat", "123:abc", "-100")
+
+	err := ch.Notify(context.Background(), &alerting.Alert{Severity: alerting.SeverityCritical, Title: "blocked", Text: "no places"})
+	require.NoError(t, err)
+	require.Equal(t, "-100", got["chat_id"])
+	require.Equal(t, "[critical] blocked\nno places", got["text"])
+}
+
+func TestSMTPChannel(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	data := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		w := bufio.NewWriter(conn)
+		reply := func(s string) {
+			_, _ = w.WriteString(s + "\r\n")
+			_ = w.Flush()
+		}
+
+		reply("220 mx.example.com ESMTP")
+
+		scanner := bufio.NewScanner(conn)
+
+		var msg strings.Builder
+
+		inData := false
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			switch {
+			case inData && line == ".":
+				inData = false
+
+				data <- msg.String()
+
+				reply("250 ok")
+			case inData:
+				msg.WriteString(line + "\n")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+
+				reply("354 go ahead")
+			case strings.HasPrefix(line, "QUIT"):
+				reply("221 bye")
+
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	ch := alerting.NewSMTPChannel("oncall", ln.Addr().String(), "", "", "alerts@example.com", []string{"oncall@example.com"})
+
+	err = ch.Notify(context.Background(), &alerting.Alert{Severity: alerting.SeverityCritical, Title: "blocked", Text: "no places"})
+	require.NoError(t, err)
+
+	msg := <-data
+	require.Contains(t, msg, "Subject: [critical] blocked")
+	require.Contains(t, msg, "To: oncall@example.com")
+	require.Contains(t, msg, "no places")
+}
@@ -0,0 +1,179 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const httpTimeout = 30 * time.Second
+
+// WebhookChannel posts the alerts to a webhook as json, or as messages when
+// it is a Slack incoming webhook (https://hooks.slack.com/...).
+type WebhookChannel struct {
+	name  string
+	url   string
+	slack bool
+	http  *http.Client
+}
+
+// NewWebhookChannel returns the channel name of the webhook at rawURL.
+func NewWebhookChannel(name, rawURL string) *WebhookChannel {
+	ans := WebhookChannel{
+		name: name,
+		url:  rawURL,
+		http: &http.Client{Timeout: httpTimeout},
+	}
+
+	if u, err := url.Parse(rawURL); err == nil {
+		ans.slack = u.Host == "hooks.slack.com"
+	}
+
+	return &ans
+}
+
+func (c *WebhookChannel) Name() string {
+	return c.name
+}
+
+func (c *WebhookChannel) Notify(ctx context.Context, a *Alert) error {
+	var payload any = a
+
+	if c.slack {
+		payload = map[string]string{"text": a.message()}
+	}
+
+	return postJSON(ctx, c.http, c.url, payload)
+}
+
+// TelegramAPI is the url of the Telegram bot API.
+var TelegramAPI = "https://api.telegram.org"
+
+// TelegramChannel sends the alerts as messages of a Telegram bot to a chat.
+type TelegramChannel struct {
+	name   string
+	token  string
+	chatID string
+	http   *http.Client
+}
+
+// NewTelegramChannel returns the channel name of the bot of token, which
+// writes to the chat chatID, the id of a user, a group or a channel.
+func NewTelegramChannel(name, token, chatID string) *TelegramChannel {
+	return &TelegramChannel{
+		name:   name,
+		token:  token,
+		chatID: chatID,
+		http:   &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (c *TelegramChannel) Name() string {
+	return c.name
+}
+
+func (c *TelegramChannel) Notify(ctx context.Context, a *Alert) error {
+	payload := map[string]string{
+		"chat_id": c.chatID,
+		"text":    a.message(),
+	}
+
+	err := postJSON(ctx, c.http, TelegramAPI+"/bot"+c.token+"/sendMessage", payload)
+
+	// the url of the request holds the token
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+
+	return err
+}
+
+// SMTPChannel emails the alerts.
+type SMTPChannel struct {
+	name     string
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewSMTPChannel returns the channel name that emails the alerts from from
+// to the addresses to through the SMTP server at addr, host:port. The server
+// is authenticated with PLAIN when username is set, which needs STARTTLS
+// unless the server is local.
+func NewSMTPChannel(name, addr, username, password, from string, to []string) *SMTPChannel {
+	return &SMTPChannel{
+		name:     name,
+		addr:     addr,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+	}
+}
+
+func (c *SMTPChannel) Name() string {
+	return c.name
+}
+
+func (c *SMTPChannel) Notify(_ context.Context, a *Alert) error {
+	var auth smtp.Auth
+
+	if c.username != "" {
+		host, _, err := net.SplitHostPort(c.addr)
+		if err != nil {
+			return err
+		}
+
+		auth = smtp.PlainAuth("", c.username, c.password, host)
+	}
+
+	var msg strings.Builder
+
+	msg.WriteString("From: " + c.from + "\r\n")
+	msg.WriteString("To: " + strings.Join(c.to, ", ") + "\r\n")
+	msg.WriteString("Subject: [" + string(a.Severity) + "] " + a.Title + "\r\n")
+	msg.WriteString("Date: " + a.Time.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(a.Text, "\n", "\r\n") + "\r\n")
+
+	return smtp.SendMail(c.addr, auth, c.from, c.to, []byte(msg.String()))
+}
+
+func postJSON(ctx context.Context, client *http.Client, u string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,284 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Rule raises an alert when a metric stays above or below a threshold for a
+// duration, and a resolved alert when it no longer does.
+type Rule struct {
+	Name   string `yaml:"name"`
+	Metric string `yaml:"metric"`
+	// Op compares the metric with the threshold: >, >=, < or <=
+	Op        string        `yaml:"op"`
+	Threshold float64       `yaml:"threshold"`
+	For       time.Duration `yaml:"for"`
+	Severity  Severity      `yaml:"severity"`
+}
+
+// DefaultRules are the rules used when the config has none: the jobs fail,
+// the jobs are blocked, or Google serves captchas, for a while.
+func DefaultRules() []Rule {
+	return []Rule{
+		{Name: "jobs_failing", Metric: "error_rate", Op: ">", Threshold: 0.5, For: 15 * time.Minute, Severity: SeverityCritical},
+		{Name: "jobs_blocked", Metric: "block_rate", Op: ">", Threshold: 0.5, For: 15 * time.Minute, Severity: SeverityCritical},
+		{Name: "captchas", Metric: "captchas_per_minute", Op: ">", Threshold: 1, For: 10 * time.Minute, Severity: SeverityWarning},
+	}
+}
+
+func (r *Rule) validate(metrics []string) error {
+	switch {
+	case r.Name == "":
+		return errors.New("alert rule without a name")
+	case !slices.Contains(metrics, r.Metric):
+		return fmt.Errorf("alert rule %s: unknown metric %q, one of %v", r.Name, r.Metric, metrics)
+	case !slices.Contains([]string{">", ">=", "<", "<="}, r.Op):
+		return fmt.Errorf("alert rule %s: invalid op %q", r.Name, r.Op)
+	case !r.Severity.Valid():
+		return fmt.Errorf("alert rule %s: invalid severity %q", r.Name, r.Severity)
+	case r.For < 0:
+		return fmt.Errorf("alert rule %s: negative for", r.Name)
+	}
+
+	return nil
+}
+
+func (r *Rule) holds(v float64) bool {
+	switch r.Op {
+	case ">":
+		return v > r.Threshold
+	case ">=":
+		return v >= r.Threshold
+	case "<":
+		return v < r.Threshold
+	case "<=":
+		return v <= r.Threshold
+	}
+
+	return false
+}
+
+type ruleState struct {
+	since  time.Time
+	firing bool
+}
+
+// Alerter sends the alerts to the channels of the config and raises the
+// alerts of its rules. Its config can be reloaded while it runs. A nil
+// alerter sends nothing.
+type Alerter struct {
+	metrics []string
+
+	mu     sync.Mutex
+	router *Router
+	rules  []Rule
+	state  map[string]*ruleState
+}
+
+// NewAlerter returns the alerter of cfg, whose rules watch the metrics named
+// metrics.
+func NewAlerter(cfg *Config, metrics []string) (*Alerter, error) {
+	ans := Alerter{
+		metrics: metrics,
+		state:   map[string]*ruleState{},
+	}
+
+	if err := ans.Reload(cfg); err != nil {
+		return nil, err
+	}
+
+	return &ans, nil
+}
+
+// Reload replaces the channels, routes and rules with the ones of cfg, the
+// default rules when it has none. The rules that are still there keep their
+// state. It keeps the current config when cfg is invalid.
+func (a *Alerter) Reload(cfg *Config) error {
+	router, err := cfg.NewRouter()
+	if err != nil {
+		return err
+	}
+
+	rules := cfg.Rules
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+
+	names := make(map[string]bool, len(rules))
+
+	for i := range rules {
+		if err := rules[i].validate(a.metrics); err != nil {
+			return err
+		}
+
+		if names[rules[i].Name] {
+			return fmt.Errorf("duplicate alert rule %s", rules[i].Name)
+		}
+
+		names[rules[i].Name] = true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.router = router
+	a.rules = rules
+
+	for name := range a.state {
+		if !names[name] {
+			delete(a.state, name)
+		}
+	}
+
+	return nil
+}
+
+// Rules returns the current rules.
+func (a *Alerter) Rules() []Rule {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return slices.Clone(a.rules)
+}
+
+// SetRouter replaces the channels and routes of the config with router,
+// until the next reload.
+func (a *Alerter) SetRouter(router *Router) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.router = router
+}
+
+// Notify sends alert to the channels of its severity.
+func (a *Alerter) Notify(ctx context.Context, alert *Alert) error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	router := a.router
+	a.mu.Unlock()
+
+	return router.Notify(ctx, alert)
+}
+
+// Evaluate checks the rules against the metrics of sample, taken at now,
+// and sends the alerts of the rules that start or stop firing. The rules of
+// the metrics missing from sample are skipped.
+func (a *Alerter) Evaluate(ctx context.Context, now time.Time, sample map[string]float64) error {
+	if a == nil {
+		return nil
+	}
+
+	var alerts []*Alert
+
+	a.mu.Lock()
+
+	for i := range a.rules {
+		r := &a.rules[i]
+
+		v, ok := sample[r.Metric]
+		if !ok {
+			continue
+		}
+
+		st := a.state[r.Name]
+		if st == nil {
+			st = &ruleState{}
+			a.state[r.Name] = st
+		}
+
+		switch {
+		case r.holds(v):
+			if st.since.IsZero() {
+				st.since = now
+			}
+
+			if !st.firing && now.Sub(st.since) >= r.For {
+				st.firing = true
+
+				alerts = append(alerts, &Alert{
+					Severity: r.Severity,
+					Title:    r.Name,
+					Text:     fmt.Sprintf("%s is %.2f, %s %.2f since %s.", r.Metric, v, r.Op, r.Threshold, st.since.Format(time.RFC3339)),
+					Time:     now,
+				})
+			}
+		case st.firing:
+			alerts = append(alerts, &Alert{
+				Severity: r.Severity,
+				Title:    "resolved: " + r.Name,
+				Text:     fmt.Sprintf("%s is %.2f.", r.Metric, v),
+				Time:     now,
+			})
+
+			fallthrough
+		default:
+			*st = ruleState{}
+		}
+	}
+
+	router := a.router
+
+	a.mu.Unlock()
+
+	var errs []error
+
+	for _, alert := range alerts {
+		if err := router.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Watch reloads the config from path, every interval, when the file
+// changed, until ctx is done. An invalid config is logged and the current
+// one kept.
+func (a *Alerter) Watch(ctx context.Context, path string, interval time.Duration) error {
+	var modTime time.Time
+
+	if fi, err := os.Stat(path); err == nil {
+		modTime = fi.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil || fi.ModTime().Equal(modTime) {
+			continue
+		}
+
+		modTime = fi.ModTime()
+
+		cfg, err := LoadConfig(path)
+		if err == nil {
+			err = a.Reload(cfg)
+		}
+
+		if err != nil {
+			log.Printf("failed to reload the alerting config %s: %v", path, err)
+
+			continue
+		}
+
+		log.Printf("reloaded the alerting config %s", path)
+	}
+}
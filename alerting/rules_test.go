@@ -0,0 +1,137 @@
+package alerting_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/alerting"
+)
+
+const rulesConfig = `
+channels:
+  - name: hook
+    type: webhook
+    url: https://example.com/hook
+routes:
+  critical: [hook]
+rules:
+  - name: blocked
+    metric: block_rate
+    op: ">"
+    threshold: 0.5
+    for: 10m
+    severity: critical
+`
+
+func TestAlerterEvaluate(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "alerts.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(rulesConfig), 0o600))
+
+	cfg, err := alerting.LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, cfg.Rules[0].For)
+
+	alerter, err := alerting.NewAlerter(cfg, []string{"block_rate"})
+	require.NoError(t, err)
+
+	hook := &fakeChannel{name: "hook"}
+
+	// the channels of the file are replaced by the fake
+	router, err := alerting.NewRouter([]alerting.NotificationChannel{hook}, map[alerting.Severity][]string{
+		alerting.SeverityCritical: {"hook"},
+	})
+	require.NoError(t, err)
+
+	alerter.SetRouter(router)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sample := map[string]float64{"block_rate": 0.8}
+
+	ctx := context.Background()
+
+	require.NoError(t, alerter.Evaluate(ctx, start, sample))
+	require.NoError(t, alerter.Evaluate(ctx, start.Add(5*time.Minute), sample))
+	require.Empty(t, hook.alerts)
+
+	require.NoError(t, alerter.Evaluate(ctx, start.Add(10*time.Minute), sample))
+	require.Len(t, hook.alerts, 1)
+	require.Equal(t, "blocked", hook.alerts[0].Title)
+
+	// it fires once while the rule holds
+	require.NoError(t, alerter.Evaluate(ctx, start.Add(11*time.Minute), sample))
+	require.Len(t, hook.alerts, 1)
+
+	require.NoError(t, alerter.Evaluate(ctx, start.Add(12*time.Minute), map[string]float64{"block_rate": 0.1}))
+	require.Len(t, hook.alerts, 2)
+	require.Equal(t, "resolved: blocked", hook.alerts[1].Title)
+}
+
+func TestAlerterReload(t *testing.T) {
+	t.Parallel()
+
+	cfg := alerting.Config{}
+
+	alerter, err := alerting.NewAlerter(&cfg, []string{"error_rate", "block_rate", "captchas_per_minute"})
+	require.NoError(t, err)
+
+	// without rules the defaults are used
+	require.Len(t, alerter.Rules(), len(alerting.DefaultRules()))
+
+	cfg.Rules = []alerting.Rule{{Name: "slow", Metric: "jobs_per_minute", Op: "<", Threshold: 1, Severity: alerting.SeverityWarning}}
+	require.ErrorContains(t, alerter.Reload(&cfg), "unknown metric")
+
+	cfg.Rules[0].Metric = "error_rate"
+	cfg.Rules[0].Op = "!="
+	require.ErrorContains(t, alerter.Reload(&cfg), "invalid op")
+
+	// an invalid config keeps the current one
+	require.Len(t, alerter.Rules(), len(alerting.DefaultRules()))
+
+	cfg.Rules[0].Op = ">"
+	require.NoError(t, alerter.Reload(&cfg))
+	require.Equal(t, cfg.Rules, alerter.Rules())
+}
+
+func TestAlerterWatch(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "alerts.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(rulesConfig), 0o600))
+
+	cfg, err := alerting.LoadConfig(path)
+	require.NoError(t, err)
+
+	alerter, err := alerting.NewAlerter(cfg, []string{"block_rate", "error_rate"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = alerter.Watch(ctx, path, 10*time.Millisecond)
+	}()
+
+	updated := rulesConfig + `
+  - name: failing
+    metric: error_rate
+    op: ">="
+    threshold: 0.9
+    severity: critical
+`
+
+	// the modification time must change for the reload
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte(updated), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+
+	require.Eventually(t, func() bool {
+		return len(alerter.Rules()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+}
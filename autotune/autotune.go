@@ -0,0 +1,276 @@
+// Package autotune adapts the pace of a crawl to how Google responds. A
+// Tuner lets at most its limit of pages load at the same time, up to the
+// concurrency of scrapemate, and pauses before each of them. Every interval
+// it looks at the pages loaded since the last adjustment: it halves the
+// limit and doubles the pause as soon as Google serves a captcha, backs off
+// on errors and slow responses, and speeds up again while the crawl is
+// healthy.
+package autotune
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInterval is how often the limit and the delay are adjusted.
+	DefaultInterval = 30 * time.Second
+
+	// minSamples is the number of pages needed to speed up
+	minSamples = 5
+	// maxErrorRate is the share of failed pages above which the tuner backs off
+	maxErrorRate = 0.2
+	// slowFactor is how much slower than the fastest window the pages may get
+	slowFactor = 2
+	// baseDelay is the first pause after a block
+	baseDelay = time.Second
+	// maxDelay caps the pause
+	maxDelay = time.Minute
+)
+
+// Stats is the state of the tuner.
+type Stats struct {
+	Limit   int
+	InUse   int
+	Delay   time.Duration
+	Blocks  int
+	Errors  int
+	Samples int
+}
+
+// Tuner limits and paces the pages loaded from Google.
+type Tuner struct {
+	mu   sync.Mutex
+	wake chan struct{}
+
+	max      int
+	limit    int
+	inUse    int
+	delay    time.Duration
+	interval time.Duration
+
+	// the window since the last adjustment
+	samples int
+	errors  int
+	blocks  int
+	total   time.Duration
+
+	// fastest is the lowest average response time of a healthy window
+	fastest time.Duration
+}
+
+// Option configures a Tuner.
+type Option func(*Tuner)
+
+// WithInterval sets how often the tuner adjusts.
+func WithInterval(d time.Duration) Option {
+	return func(t *Tuner) {
+		t.interval = d
+	}
+}
+
+// WithDelay sets the pause the tuner starts with.
+func WithDelay(d time.Duration) Option {
+	return func(t *Tuner) {
+		t.delay = d
+	}
+}
+
+// New returns a Tuner that allows up to maxConcurrency pages at the same time.
+// It starts at maxConcurrency.
+func New(maxConcurrency int, opts ...Option) *Tuner {
+	maxConcurrency = max(1, maxConcurrency)
+
+	t := Tuner{
+		wake:     make(chan struct{}),
+		max:      maxConcurrency,
+		limit:    maxConcurrency,
+		interval: DefaultInterval,
+	}
+
+	for _, opt := range opts {
+		opt(&t)
+	}
+
+	return &t
+}
+
+// Acquire waits until a page may be loaded and pauses for the current delay.
+// Each Acquire that returns nil must be followed by a Release.
+func (t *Tuner) Acquire(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+
+		if t.inUse < t.limit {
+			t.inUse++
+			delay := t.delay
+			t.mu.Unlock()
+
+			if err := sleep(ctx, delay); err != nil {
+				t.release()
+
+				return err
+			}
+
+			return nil
+		}
+
+		wake := t.wake
+		t.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Release records how the page went: how long it took, whether it failed and
+// whether Google served a captcha instead.
+func (t *Tuner) Release(latency time.Duration, err error, blocked bool) {
+	t.mu.Lock()
+
+	t.samples++
+	t.total += latency
+
+	if err != nil {
+		t.errors++
+	}
+
+	if blocked {
+		t.blocks++
+	}
+
+	t.mu.Unlock()
+
+	t.release()
+}
+
+func (t *Tuner) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inUse--
+	t.broadcast()
+}
+
+// broadcast wakes up the waiting Acquire calls. t.mu must be held.
+func (t *Tuner) broadcast() {
+	close(t.wake)
+	t.wake = make(chan struct{})
+}
+
+// Stats returns the current limit and delay and the counters of the window.
+func (t *Tuner) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return Stats{
+		Limit:   t.limit,
+		InUse:   t.inUse,
+		Delay:   t.delay,
+		Blocks:  t.blocks,
+		Errors:  t.errors,
+		Samples: t.samples,
+	}
+}
+
+// Run adjusts the tuner every interval until ctx is done.
+func (t *Tuner) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if msg := t.Adjust(); msg != "" {
+				log.Printf("autotune: %s", msg)
+			}
+		}
+	}
+}
+
+// Adjust updates the limit and the delay from the pages loaded since the
+// last call and starts a new window. It returns a description of the change,
+// empty when nothing changed.
+func (t *Tuner) Adjust() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	defer func() {
+		t.samples, t.errors, t.blocks, t.total = 0, 0, 0, 0
+	}()
+
+	limit, delay := t.limit, t.delay
+
+	var (
+		reason  string
+		average time.Duration
+	)
+
+	if t.samples > 0 {
+		average = t.total / time.Duration(t.samples)
+	}
+
+	switch {
+	case t.blocks > 0:
+		reason = fmt.Sprintf("%d captchas", t.blocks)
+		limit = max(1, limit/2)
+		delay = min(maxDelay, max(baseDelay, delay*2))
+	case t.samples > 0 && float64(t.errors)/float64(t.samples) > maxErrorRate:
+		reason = fmt.Sprintf("%d of %d pages failed", t.errors, t.samples)
+		limit = max(1, limit-1)
+		delay = min(maxDelay, max(baseDelay, delay*3/2))
+	case t.samples > 0 && t.fastest > 0 && average > slowFactor*t.fastest:
+		reason = fmt.Sprintf("pages take %s against %s", average.Round(time.Millisecond), t.fastest.Round(time.Millisecond))
+		limit = max(1, limit-1)
+	case t.samples >= minSamples:
+		reason = "healthy"
+		limit = min(t.max, limit+1)
+		delay /= 2
+
+		if delay < 100*time.Millisecond {
+			delay = 0
+		}
+
+		if t.fastest == 0 || average < t.fastest {
+			t.fastest = average
+		}
+	}
+
+	if limit == t.limit && delay == t.delay {
+		return ""
+	}
+
+	msg := fmt.Sprintf("%s, concurrency %d -> %d, delay %s -> %s", reason, t.limit, limit, t.delay, delay)
+
+	if limit > t.limit {
+		defer t.broadcast()
+	}
+
+	t.limit, t.delay = limit, delay
+
+	return msg
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package autotune_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/autotune"
+)
+
+func load(t *testing.T, tuner *autotune.Tuner, n int, latency time.Duration, err error, blocked bool) {
+	t.Helper()
+
+	for range n {
+		require.NoError(t, tuner.Acquire(context.Background()))
+		tuner.Release(latency, err, blocked)
+	}
+}
+
+func Test_TunerBacksOff(t *testing.T) {
+	t.Parallel()
+
+	tuner := autotune.New(8)
+
+	load(t, tuner, 1, time.Second, nil, true)
+	require.NotEmpty(t, tuner.Adjust())
+	require.Equal(t, 4, tuner.Stats().Limit)
+	require.Equal(t, time.Second, tuner.Stats().Delay)
+
+	tuner = autotune.New(8)
+
+	load(t, tuner, 10, time.Second, errors.New("timeout"), false)
+	require.NotEmpty(t, tuner.Adjust())
+	require.Equal(t, 7, tuner.Stats().Limit)
+}
+
+func Test_TunerSpeedsUp(t *testing.T) {
+	t.Parallel()
+
+	tuner := autotune.New(4, autotune.WithDelay(150*time.Millisecond))
+
+	load(t, tuner, 5, 10*time.Millisecond, nil, false)
+	require.NotEmpty(t, tuner.Adjust())
+	require.Equal(t, 4, tuner.Stats().Limit)
+	require.Zero(t, tuner.Stats().Delay)
+
+	load(t, tuner, 5, time.Second, nil, false)
+	require.NotEmpty(t, tuner.Adjust())
+	require.Equal(t, 3, tuner.Stats().Limit)
+
+	load(t, tuner, 5, 10*time.Millisecond, nil, false)
+	require.NotEmpty(t, tuner.Adjust())
+	require.Equal(t, 4, tuner.Stats().Limit)
+
+	// not above the concurrency of scrapemate
+	load(t, tuner, 5, 10*time.Millisecond, nil, false)
+	require.Empty(t, tuner.Adjust())
+	require.Equal(t, 4, tuner.Stats().Limit)
+}
+
+func Test_TunerLimitsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	tuner := autotune.New(1)
+
+	require.NoError(t, tuner.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, tuner.Acquire(ctx), context.DeadlineExceeded)
+
+	tuner.Release(time.Second, nil, false)
+
+	require.NoError(t, tuner.Acquire(context.Background()))
+}
@@ -0,0 +1,112 @@
+package browser
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gosom/scrapemate"
+	parser "github.com/gosom/scrapemate/adapters/parsers/goqueryparser"
+	memprovider "github.com/gosom/scrapemate/adapters/providers/memory"
+	"github.com/gosom/scrapemate/scrapemateapp"
+	"golang.org/x/sync/errgroup"
+)
+
+// App runs scrapemate like scrapemateapp.ScrapemateApp, with the pages
+// loaded by a Fetcher.
+type App struct {
+	cfg  *scrapemateapp.Config
+	opts Options
+}
+
+// NewApp returns an app with the settings of cfg. Its concurrency, proxies,
+// reuse limits and javascript options are used for the browsers.
+func NewApp(cfg *scrapemateapp.Config, opts Options) *App {
+	opts.Headless = !cfg.JSOpts.Headfull
+	opts.DisableImages = cfg.JSOpts.DisableImages
+	opts.PoolSize = cfg.Concurrency
+	opts.PageReuseLimit = cfg.PageReuseLimit
+	opts.BrowserReuseLimit = cfg.BrowserReuseLimit
+	opts.Proxies = cfg.Proxies
+
+	if opts.UserAgent == "" {
+		opts.UserAgent = cfg.JSOpts.UA
+	}
+
+	return &App{cfg: cfg, opts: opts}
+}
+
+// Start runs the seed jobs and the jobs they create until the provider of
+// the config is exhausted or ctx is done.
+func (app *App) Start(ctx context.Context, seedJobs ...scrapemate.IJob) error {
+	g, ctx := errgroup.WithContext(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	defer cancel(errors.New("closing app"))
+
+	fetcher, err := NewFetcher(app.opts)
+	if err != nil {
+		return err
+	}
+
+	defer fetcher.Close()
+
+	provider := app.cfg.Provider
+	if provider == nil {
+		provider = memprovider.New()
+	}
+
+	params := []func(*scrapemate.ScrapeMate) error{
+		scrapemate.WithContext(ctx, nil),
+		scrapemate.WithJobProvider(provider),
+		scrapemate.WithHTTPFetcher(fetcher),
+		scrapemate.WithHTMLParser(parser.New()),
+		scrapemate.WithConcurrency(app.cfg.Concurrency),
+		scrapemate.WithExitBecauseOfInactivity(app.cfg.ExitOnInactivityDuration),
+	}
+
+	if app.cfg.InitJob != nil {
+		params = append(params, scrapemate.WithInitJob(app.cfg.InitJob))
+	}
+
+	mate, err := scrapemate.New(params...)
+	if err != nil {
+		return err
+	}
+
+	defer mate.Close()
+
+	for i := range app.cfg.Writers {
+		writer := app.cfg.Writers[i]
+
+		g.Go(func() error {
+			if err := writer.Run(ctx, mate.Results()); err != nil {
+				cancel(err)
+
+				return err
+			}
+
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		return mate.Start()
+	})
+
+	g.Go(func() error {
+		for i := range seedJobs {
+			if err := provider.Push(ctx, seedJobs[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// Close does nothing, the browsers are closed when Start returns.
+func (app *App) Close() error {
+	return nil
+}
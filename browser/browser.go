@@ -0,0 +1,417 @@
+// Package browser runs the browser pages of scrapemate in browsers that
+// scrapemate cannot start itself: firefox and webkit, and remote browsers
+// (browserless, a Chrome started with --remote-debugging-port or a
+// Playwright server), so the browser work can run on other machines than
+// the scraper.
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+)
+
+// The protocols of the remote browsers.
+const (
+	// ProtocolCDP is the Chrome DevTools protocol of chromium browsers,
+	// http://host:9222 or the ws:// url of the browser.
+	ProtocolCDP = "cdp"
+	// ProtocolPlaywright is the protocol of a Playwright server, launched
+	// with `playwright run-server` or browserless.
+	ProtocolPlaywright = "playwright"
+)
+
+// The browser engines.
+const (
+	EngineChromium = "chromium"
+	EngineFirefox  = "firefox"
+	EngineWebKit   = "webkit"
+)
+
+// Options configure the browsers of a Fetcher.
+type Options struct {
+	// Engine is EngineChromium, EngineFirefox or EngineWebKit, chromium when empty
+	Engine string
+	// VideoDir records a video of each page in this folder, the pages are then not reused
+	VideoDir string
+	// Headless hides the windows of the local browsers
+	Headless bool
+	// DisableImages does not load the images
+	DisableImages bool
+
+	// Endpoint is the url of the remote browser, the browsers are launched locally when it is empty
+	Endpoint string
+	// Protocol is ProtocolCDP or ProtocolPlaywright
+	Protocol string
+
+	// PoolSize is the number of browsers kept open
+	PoolSize int
+	// PageReuseLimit is the number of jobs of a page before it is closed, 0 closes it after each job
+	PageReuseLimit int
+	// BrowserReuseLimit is the number of jobs of a browser before it is closed, 0 for no limit
+	BrowserReuseLimit int
+	// Proxies are used in turn by the browsers
+	Proxies []string
+	// UserAgent overrides the user agent of the browsers
+	UserAgent string
+}
+
+// ValidateEngine returns an error when the engine is not supported. An empty
+// engine is chromium.
+func ValidateEngine(engine string) error {
+	switch engine {
+	case "", EngineChromium, EngineFirefox, EngineWebKit:
+		return nil
+	default:
+		return fmt.Errorf("invalid browser engine: %s", engine)
+	}
+}
+
+// ValidateProtocol returns an error when the protocol is not supported.
+func ValidateProtocol(protocol string) error {
+	switch protocol {
+	case ProtocolCDP, ProtocolPlaywright:
+		return nil
+	default:
+		return fmt.Errorf("invalid browser protocol: %s", protocol)
+	}
+}
+
+var _ scrapemate.HTTPFetcher = (*Fetcher)(nil)
+
+// Fetcher loads the pages of the jobs with their BrowserActions in the
+// browsers of the options, like the javascript fetcher of scrapemate.
+type Fetcher struct {
+	opts    Options
+	pw      *playwright.Playwright
+	pool    chan *browser
+	proxies []playwright.Proxy
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewFetcher starts the Playwright driver and connects PoolSize browsers.
+func NewFetcher(opts Options) (*Fetcher, error) {
+	if opts.Engine == "" {
+		opts.Engine = EngineChromium
+	}
+
+	if err := ValidateEngine(opts.Engine); err != nil {
+		return nil, err
+	}
+
+	install := playwright.RunOptions{Browsers: []string{opts.Engine}}
+
+	if opts.Endpoint != "" {
+		if err := ValidateProtocol(opts.Protocol); err != nil {
+			return nil, err
+		}
+
+		if opts.Protocol == ProtocolCDP && opts.Engine != EngineChromium {
+			return nil, fmt.Errorf("the %s protocol only supports chromium", ProtocolCDP)
+		}
+
+		// the browsers run elsewhere, only the driver is needed
+		install = playwright.RunOptions{SkipInstallBrowsers: true}
+	}
+
+	proxies, err := parseProxies(opts.Proxies)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := playwright.Install(&install); err != nil {
+		return nil, err
+	}
+
+	pw, err := playwright.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	ans := Fetcher{
+		opts:    opts,
+		pw:      pw,
+		pool:    make(chan *browser, max(1, opts.PoolSize)),
+		proxies: proxies,
+	}
+
+	for range opts.PoolSize {
+		b, err := ans.newBrowser()
+		if err != nil {
+			_ = ans.Close()
+
+			return nil, err
+		}
+
+		ans.pool <- b
+	}
+
+	return &ans, nil
+}
+
+// Fetch runs the BrowserActions of the job in a page of a pooled browser.
+func (f *Fetcher) Fetch(ctx context.Context, job scrapemate.IJob) scrapemate.Response {
+	b, err := f.getBrowser(ctx)
+	if err != nil {
+		return scrapemate.Response{Error: err}
+	}
+
+	defer f.putBrowser(ctx, b)
+
+	if job.GetTimeout() > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, job.GetTimeout())
+		defer cancel()
+	}
+
+	var page playwright.Page
+
+	if pages := b.ctx.Pages(); len(pages) > 0 {
+		page = pages[0]
+
+		for i := 1; i < len(pages); i++ {
+			_ = pages[i].Close()
+		}
+	} else {
+		page, err = b.ctx.NewPage()
+		if err != nil {
+			return scrapemate.Response{Error: err}
+		}
+	}
+
+	if job.GetTimeout() > 0 {
+		page.SetDefaultTimeout(float64(job.GetTimeout().Milliseconds()))
+	}
+
+	b.pageUsage++
+	b.browserUsage++
+
+	// the video of a page is written when the page is closed
+	defer func() {
+		if f.opts.VideoDir != "" || f.opts.PageReuseLimit == 0 || b.pageUsage >= f.opts.PageReuseLimit {
+			_ = page.Close()
+
+			b.pageUsage = 0
+		}
+	}()
+
+	return job.BrowserActions(ctx, page)
+}
+
+// Close closes the pooled browsers and stops the driver.
+func (f *Fetcher) Close() error {
+	close(f.pool)
+
+	for b := range f.pool {
+		b.close()
+	}
+
+	return f.pw.Stop()
+}
+
+func (f *Fetcher) getBrowser(ctx context.Context) (*browser, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case b := <-f.pool:
+		if b.browser.IsConnected() && (f.opts.BrowserReuseLimit <= 0 || b.browserUsage < f.opts.BrowserReuseLimit) {
+			return b, nil
+		}
+
+		b.close()
+	default:
+	}
+
+	return f.newBrowser()
+}
+
+func (f *Fetcher) putBrowser(ctx context.Context, b *browser) {
+	if !b.browser.IsConnected() {
+		b.close()
+
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		b.close()
+	case f.pool <- b:
+	default:
+		b.close()
+	}
+}
+
+type browser struct {
+	browser      playwright.Browser
+	ctx          playwright.BrowserContext
+	pageUsage    int
+	browserUsage int
+}
+
+// close closes the context of the browser and the browser, or disconnects
+// from a remote browser, which keeps running.
+func (b *browser) close() {
+	_ = b.ctx.Close()
+	_ = b.browser.Close()
+}
+
+func (f *Fetcher) newBrowser() (*browser, error) {
+	var (
+		br  playwright.Browser
+		err error
+	)
+
+	browserType := f.pw.Chromium
+
+	switch f.opts.Engine {
+	case EngineFirefox:
+		browserType = f.pw.Firefox
+	case EngineWebKit:
+		browserType = f.pw.WebKit
+	}
+
+	switch {
+	case f.opts.Endpoint == "":
+		br, err = browserType.Launch(f.launchOptions())
+	case f.opts.Protocol == ProtocolPlaywright:
+		br, err = browserType.Connect(f.opts.Endpoint)
+	default:
+		br, err = browserType.ConnectOverCDP(f.opts.Endpoint)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the %s browser: %w", f.opts.Engine, err)
+	}
+
+	ctxOpts := playwright.BrowserNewContextOptions{
+		Viewport: &playwright.Size{Width: 1920, Height: 1080},
+		Proxy:    f.nextProxy(),
+	}
+
+	if f.opts.UserAgent != "" {
+		ctxOpts.UserAgent = playwright.String(f.opts.UserAgent)
+	}
+
+	if f.opts.VideoDir != "" {
+		ctxOpts.RecordVideo = &playwright.RecordVideo{Dir: f.opts.VideoDir}
+	}
+
+	bctx, err := br.NewContext(ctxOpts)
+	if err != nil {
+		_ = br.Close()
+
+		return nil, fmt.Errorf("failed to create the browser context: %w", err)
+	}
+
+	return &browser{browser: br, ctx: bctx}, nil
+}
+
+// launchOptions are the options of the local browsers, the chromium
+// arguments are the ones of scrapemate.
+func (f *Fetcher) launchOptions() playwright.BrowserTypeLaunchOptions {
+	opts := playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(f.opts.Headless),
+	}
+
+	switch f.opts.Engine {
+	case EngineChromium:
+		opts.Args = []string{
+			`--start-maximized`,
+			`--no-default-browser-check`,
+			`--disable-dev-shm-usage`,
+			`--no-sandbox`,
+			`--disable-setuid-sandbox`,
+			`--no-zygote`,
+			`--disable-gpu`,
+			`--mute-audio`,
+			`--disable-extensions`,
+			`--single-process`,
+			`--disable-breakpad`,
+			`--disable-features=TranslateUI,BlinkGenPropertyTrees`,
+			`--disable-ipc-flooding-protection`,
+			`--enable-features=NetworkService,NetworkServiceInProcess`,
+			`--disable-default-apps`,
+			`--disable-notifications`,
+			`--disable-webgl`,
+			`--disable-blink-features=AutomationControlled`,
+			`--ignore-certificate-errors`,
+			`--ignore-certificate-errors-spki-list`,
+			`--disable-web-security`,
+		}
+
+		if f.opts.DisableImages {
+			opts.Args = append(opts.Args, `--blink-settings=imagesEnabled=false`)
+		}
+	case EngineFirefox:
+		if f.opts.DisableImages {
+			opts.FirefoxUserPrefs = map[string]any{"permissions.default.image": 2}
+		}
+	}
+
+	return opts
+}
+
+// nextProxy returns the proxies in turn, nil without proxies.
+func (f *Fetcher) nextProxy() *playwright.Proxy {
+	if len(f.proxies) == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	p := f.proxies[f.next%len(f.proxies)]
+	f.next++
+	f.mu.Unlock()
+
+	return &p
+}
+
+// parseProxies splits the credentials of the proxies, the remote browsers
+// authenticate with them themselves.
+func parseProxies(proxies []string) ([]playwright.Proxy, error) {
+	ans := make([]playwright.Proxy, 0, len(proxies))
+
+	for _, p := range proxies {
+		u, err := url.Parse(p)
+		if err != nil || u.Host == "" {
+			return nil, errors.New("invalid proxy: " + redact(p))
+		}
+
+		proxy := playwright.Proxy{Server: u.Scheme + "://" + u.Host}
+
+		if u.User != nil {
+			proxy.Username = playwright.String(u.User.Username())
+
+			if password, ok := u.User.Password(); ok {
+				proxy.Password = playwright.String(password)
+			}
+		}
+
+		ans = append(ans, proxy)
+	}
+
+	return ans, nil
+}
+
+// redact hides the credentials of a proxy in the error messages.
+func redact(p string) string {
+	u, err := url.Parse(p)
+	if err != nil {
+		return "(unparsable)"
+	}
+
+	if u.User == nil {
+		return p
+	}
+
+	u.User = url.User("xxx")
+
+	return u.String()
+}
@@ -0,0 +1,127 @@
+package capsolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/captcha"
+)
+
+const defaultBaseURL = "https://api.capsolver.com"
+
+var _ captcha.Solver = (*client)(nil)
+
+type client struct {
+	baseURL string
+	key     string
+	http    *http.Client
+}
+
+// New returns a solver backed by the CapSolver API. key is a CapSolver API key.
+func New(key string) captcha.Solver {
+	return &client{
+		baseURL: defaultBaseURL,
+		key:     key,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type task struct {
+	Type              string            `json:"type"`
+	WebsiteURL        string            `json:"websiteURL"`
+	WebsiteKey        string            `json:"websiteKey"`
+	EnterprisePayload map[string]string `json:"enterprisePayload,omitempty"`
+}
+
+type request struct {
+	ClientKey string `json:"clientKey"`
+	Task      *task  `json:"task,omitempty"`
+	TaskID    string `json:"taskId,omitempty"`
+}
+
+type response struct {
+	ErrorID          int    `json:"errorId"`
+	ErrorCode        string `json:"errorCode"`
+	ErrorDescription string `json:"errorDescription"`
+	TaskID           string `json:"taskId"`
+	Status           string `json:"status"`
+	Solution         struct {
+		GRecaptchaResponse string `json:"gRecaptchaResponse"`
+	} `json:"solution"`
+}
+
+func (c *client) Solve(ctx context.Context, t captcha.Task) (string, error) {
+	created, err := c.post(ctx, "/createTask", request{
+		ClientKey: c.key,
+		Task: &task{
+			Type:       "ReCaptchaV2TaskProxyLess",
+			WebsiteURL: t.PageURL,
+			WebsiteKey: t.SiteKey,
+			EnterprisePayload: func() map[string]string {
+				if t.DataS == "" {
+					return nil
+				}
+
+				return map[string]string{"s": t.DataS}
+			}(),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if err := captcha.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		result, err := c.post(ctx, "/getTaskResult", request{ClientKey: c.key, TaskID: created.TaskID})
+		if err != nil {
+			return "", err
+		}
+
+		switch result.Status {
+		case "ready":
+			return result.Solution.GRecaptchaResponse, nil
+		case "failed":
+			return "", fmt.Errorf("capsolver: %w", captcha.ErrUnsolvable)
+		}
+	}
+}
+
+func (c *client) post(ctx context.Context, path string, body request) (response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return response{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return response{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return response{}, fmt.Errorf("capsolver: request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	var ans response
+
+	if err := json.NewDecoder(resp.Body).Decode(&ans); err != nil {
+		return response{}, fmt.Errorf("capsolver: unexpected status code %d: %w", resp.StatusCode, err)
+	}
+
+	if ans.ErrorID != 0 {
+		return response{}, fmt.Errorf("capsolver: %s: %s", ans.ErrorCode, ans.ErrorDescription)
+	}
+
+	return ans, nil
+}
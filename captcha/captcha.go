@@ -0,0 +1,81 @@
+// Package captcha solves the reCAPTCHA that Google serves on its "unusual
+// traffic" page with a paid solving service.
+package captcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// The supported solving services.
+const (
+	ProviderTwoCaptcha = "2captcha"
+	ProviderCapSolver  = "capsolver"
+)
+
+// ErrUnsolvable is returned when the service could not solve the captcha.
+var ErrUnsolvable = errors.New("captcha not solved")
+
+// PollInterval is how often the services are asked for the solution.
+var PollInterval = 5 * time.Second
+
+// keyEnv holds the environment variable of the API key of the providers.
+var keyEnv = map[string]string{
+	ProviderTwoCaptcha: "TWOCAPTCHA_API_KEY",
+	ProviderCapSolver:  "CAPSOLVER_API_KEY",
+}
+
+// Task is a reCAPTCHA v2 to solve.
+type Task struct {
+	// PageURL is the url of the page that shows the captcha
+	PageURL string
+	// SiteKey is the data-sitekey of the captcha
+	SiteKey string
+	// DataS is the data-s token of the captcha of the Google pages
+	DataS string
+}
+
+// Solver solves captchas.
+type Solver interface {
+	// Solve returns the g-recaptcha-response token of the task.
+	Solve(ctx context.Context, task Task) (string, error)
+}
+
+// Key returns the API key of the provider from its environment variable.
+func Key(provider string) string {
+	if env := keyEnv[provider]; env != "" {
+		return os.Getenv(env)
+	}
+
+	return ""
+}
+
+// ValidateProvider checks that the provider is supported and has an API key.
+func ValidateProvider(provider string) error {
+	env, ok := keyEnv[provider]
+	if !ok {
+		return fmt.Errorf("invalid captcha solver: %s", provider)
+	}
+
+	if Key(provider) == "" {
+		return fmt.Errorf("captcha solver %s needs an API key, set %s", provider, env)
+	}
+
+	return nil
+}
+
+// Wait sleeps for the poll interval or until ctx is done.
+func Wait(ctx context.Context) error {
+	timer := time.NewTimer(PollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,19 @@
+package captcha_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/captcha"
+)
+
+func Test_ValidateProvider(t *testing.T) {
+	t.Setenv("TWOCAPTCHA_API_KEY", "")
+	t.Setenv("CAPSOLVER_API_KEY", "key")
+
+	require.Error(t, captcha.ValidateProvider("anticaptcha"))
+	require.Error(t, captcha.ValidateProvider(captcha.ProviderTwoCaptcha))
+	require.NoError(t, captcha.ValidateProvider(captcha.ProviderCapSolver))
+	require.Equal(t, "key", captcha.Key(captcha.ProviderCapSolver))
+}
@@ -0,0 +1,118 @@
+package twocaptcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/captcha"
+)
+
+const defaultBaseURL = "https://2captcha.com"
+
+var _ captcha.Solver = (*client)(nil)
+
+type client struct {
+	baseURL string
+	key     string
+	http    *http.Client
+}
+
+// New returns a solver backed by the 2captcha API. key is a 2captcha API key.
+func New(key string) captcha.Solver {
+	return &client{
+		baseURL: defaultBaseURL,
+		key:     key,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type response struct {
+	Status  int    `json:"status"`
+	Request string `json:"request"`
+}
+
+func (c *client) Solve(ctx context.Context, task captcha.Task) (string, error) {
+	params := url.Values{}
+	params.Set("key", c.key)
+	params.Set("method", "userrecaptcha")
+	params.Set("googlekey", task.SiteKey)
+	params.Set("pageurl", task.PageURL)
+	params.Set("json", "1")
+
+	if task.DataS != "" {
+		params.Set("data-s", task.DataS)
+	}
+
+	created, err := c.get(ctx, "/in.php", params)
+	if err != nil {
+		return "", err
+	}
+
+	if created.Status != 1 {
+		return "", fmt.Errorf("2captcha: %s", created.Request)
+	}
+
+	params = url.Values{}
+	params.Set("key", c.key)
+	params.Set("action", "get")
+	params.Set("id", created.Request)
+	params.Set("json", "1")
+
+	for {
+		if err := captcha.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		result, err := c.get(ctx, "/res.php", params)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case result.Status == 1:
+			return result.Request, nil
+		case result.Request == "CAPCHA_NOT_READY":
+			continue
+		case strings.HasPrefix(result.Request, "ERROR_CAPTCHA_UNSOLVABLE"):
+			return "", fmt.Errorf("2captcha: %w", captcha.ErrUnsolvable)
+		default:
+			return "", fmt.Errorf("2captcha: %s", result.Request)
+		}
+	}
+}
+
+func (c *client) get(ctx context.Context, path string, params url.Values) (response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return response{}, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// the error holds the url, which holds the key
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+
+		return response{}, fmt.Errorf("2captcha: request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return response{}, fmt.Errorf("2captcha: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var body response
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return response{}, fmt.Errorf("2captcha: %w", err)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,460 @@
+// Package client is a typed Go client of the REST API of the web runner,
+// described by the OpenAPI specification served at /api/openapi.json. It
+// uses the types of the web package, so it stays in sync with the server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+const defaultTimeout = time.Minute
+
+// Client calls the API of a server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	apiKey   string
+	username string
+	password string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with c instead of a client with a
+// timeout of a minute.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.httpClient = c
+	}
+}
+
+// WithAPIKey authenticates the requests with an API key of a server running
+// with -auth.
+func WithAPIKey(key string) Option {
+	return func(cl *Client) {
+		cl.apiKey = key
+	}
+}
+
+// WithBasicAuth authenticates the requests with the username and password
+// of a user of a server running with -auth.
+func WithBasicAuth(username, password string) Option {
+	return func(cl *Client) {
+		cl.username = username
+		cl.password = password
+	}
+}
+
+// New returns a client of the server at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) *Client {
+	ans := Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(&ans)
+	}
+
+	return &ans
+}
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	Message    string
+	// Rows are the invalid rows of a bulk creation
+	Rows []web.BulkRowError
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+}
+
+// Created is a created job.
+type Created struct {
+	ID string `json:"id"`
+	// Warnings are the settings of the job more aggressive than recommended
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// jobRequest is the body of the requests that create jobs, templates and
+// schedules. The max time is in seconds.
+type jobRequest struct {
+	Name string `json:"name"`
+	Cron string `json:"cron,omitempty"`
+	web.JobData
+}
+
+func newJobRequest(name string, data *web.JobData) jobRequest {
+	ans := jobRequest{Name: name, JobData: *data}
+	ans.MaxTime /= time.Second
+
+	return ans
+}
+
+// CreateJob creates a pending job.
+func (c *Client) CreateJob(ctx context.Context, name string, data *web.JobData) (Created, error) {
+	var ans Created
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs", newJobRequest(name, data), &ans)
+
+	return ans, err
+}
+
+// CreateJobFromTemplate creates a pending job searching keywords with the
+// settings of the template templateID.
+func (c *Client) CreateJobFromTemplate(ctx context.Context, templateID, name string, keywords []string) (Created, error) {
+	req := struct {
+		Name       string   `json:"name"`
+		TemplateID string   `json:"template_id"`
+		Keywords   []string `json:"keywords,omitempty"`
+	}{
+		Name:       name,
+		TemplateID: templateID,
+		Keywords:   keywords,
+	}
+
+	var ans Created
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs", req, &ans)
+
+	return ans, err
+}
+
+// CreateJobs creates a job for each row of the csv r, with the settings of
+// the template templateID when it is set. No job is created when a row is
+// invalid, the returned *Error has the reason of each invalid row.
+func (c *Client) CreateJobs(ctx context.Context, r io.Reader, templateID string) ([]web.BulkJob, error) {
+	path := "/api/v1/jobs/bulk"
+	if templateID != "" {
+		path += "?template_id=" + url.QueryEscape(templateID)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "text/csv")
+
+	var ans struct {
+		Jobs []web.BulkJob `json:"jobs"`
+	}
+
+	err = c.send(req, &ans)
+
+	return ans.Jobs, err
+}
+
+// Jobs returns the jobs the user of the client may see.
+func (c *Client) Jobs(ctx context.Context) ([]web.Job, error) {
+	var ans []web.Job
+
+	err := c.do(ctx, http.MethodGet, "/api/v1/jobs", nil, &ans)
+
+	return ans, err
+}
+
+// Job returns the job id.
+func (c *Client) Job(ctx context.Context, id string) (web.Job, error) {
+	var ans web.Job
+
+	err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(id), nil, &ans)
+
+	return ans, err
+}
+
+// DeleteJob deletes the job id with its results.
+func (c *Client) DeleteJob(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/jobs/"+url.PathEscape(id), nil, nil)
+}
+
+// PauseJob pauses a pending or running job.
+func (c *Client) PauseJob(ctx context.Context, id string) (web.Job, error) {
+	var ans web.Job
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs/"+url.PathEscape(id)+"/pause", nil, &ans)
+
+	return ans, err
+}
+
+// ResumeJob resumes a paused job, or a failed job from its last checkpoint.
+func (c *Client) ResumeJob(ctx context.Context, id string) (web.Job, error) {
+	var ans web.Job
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs/"+url.PathEscape(id)+"/resume", nil, &ans)
+
+	return ans, err
+}
+
+// CloneJob creates a pending job with the settings of the job id, named name
+// or after the job when it is empty.
+func (c *Client) CloneJob(ctx context.Context, id, name string) (Created, error) {
+	req := struct {
+		Name string `json:"name,omitempty"`
+	}{
+		Name: name,
+	}
+
+	var ans Created
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs/"+url.PathEscape(id)+"/clone", req, &ans)
+
+	return ans, err
+}
+
+// DeadLetters returns the places of the job id that failed after all their
+// retries.
+func (c *Client) DeadLetters(ctx context.Context, id string) ([]web.DeadLetter, error) {
+	var ans []web.DeadLetter
+
+	err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(id)+"/deadletters", nil, &ans)
+
+	return ans, err
+}
+
+// RequeueDeadLetters requeues the dead letters ids of the job id, or all of
+// them when ids is empty, and returns how many were requeued.
+func (c *Client) RequeueDeadLetters(ctx context.Context, id string, ids []string) (int, error) {
+	req := struct {
+		IDs []string `json:"ids,omitempty"`
+	}{
+		IDs: ids,
+	}
+
+	var ans struct {
+		Requeued int `json:"requeued"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs/"+url.PathEscape(id)+"/deadletters/requeue", req, &ans)
+
+	return ans.Requeued, err
+}
+
+// Download returns the csv of the results of a completed job. The caller
+// closes it.
+func (c *Client) Download(ctx context.Context, id string) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(id)+"/download", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		return nil, responseError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+// Results returns a page of the results of the job id that match params,
+// with the given fields only, or all of them when fields is empty.
+// params.JobID is ignored.
+func (c *Client) Results(ctx context.Context, id string, params *web.ResultParams, fields []string) (web.ResultPage, error) {
+	q := url.Values{}
+
+	if params.Query != "" {
+		q.Set("q", params.Query)
+	}
+
+	if params.Category != "" {
+		q.Set("category", params.Category)
+	}
+
+	if params.City != "" {
+		q.Set("city", params.City)
+	}
+
+	if params.MinRating > 0 {
+		q.Set("min_rating", strconv.FormatFloat(params.MinRating, 'f', -1, 64))
+	}
+
+	if params.HasEmail != nil {
+		q.Set("has_email", strconv.FormatBool(*params.HasEmail))
+	}
+
+	if params.Offset > 0 {
+		q.Set("offset", strconv.Itoa(params.Offset))
+	}
+
+	if params.Limit > 0 {
+		q.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	if len(fields) > 0 {
+		q.Set("fields", strings.Join(fields, ","))
+	}
+
+	path := "/api/v1/jobs/" + url.PathEscape(id) + "/results"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var ans web.ResultPage
+
+	err := c.do(ctx, http.MethodGet, path, nil, &ans)
+
+	return ans, err
+}
+
+// CreateTemplate saves the settings of data as the template name and
+// returns its id. The keywords are optional.
+func (c *Client) CreateTemplate(ctx context.Context, name string, data *web.JobData) (string, error) {
+	var ans struct {
+		ID string `json:"id"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/templates", newJobRequest(name, data), &ans)
+
+	return ans.ID, err
+}
+
+// Templates returns the job templates by name.
+func (c *Client) Templates(ctx context.Context) ([]web.JobTemplate, error) {
+	var ans []web.JobTemplate
+
+	err := c.do(ctx, http.MethodGet, "/api/v1/templates", nil, &ans)
+
+	return ans, err
+}
+
+// DeleteTemplate deletes the template id, its jobs are kept.
+func (c *Client) DeleteTemplate(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/templates/"+url.PathEscape(id), nil, nil)
+}
+
+// CreateSchedule creates the job of data each time the cron expression is
+// due and returns the id of the schedule.
+func (c *Client) CreateSchedule(ctx context.Context, name, cron string, data *web.JobData) (string, error) {
+	req := newJobRequest(name, data)
+	req.Cron = cron
+
+	var ans struct {
+		ID string `json:"id"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/api/v1/schedules", req, &ans)
+
+	return ans.ID, err
+}
+
+// Schedules returns the schedules.
+func (c *Client) Schedules(ctx context.Context) ([]web.Schedule, error) {
+	var ans []web.Schedule
+
+	err := c.do(ctx, http.MethodGet, "/api/v1/schedules", nil, &ans)
+
+	return ans, err
+}
+
+// DeleteSchedule stops the schedule id, its jobs are kept.
+func (c *Client) DeleteSchedule(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/schedules/"+url.PathEscape(id), nil, nil)
+}
+
+// do sends body as json and decodes the response in ans, unless it is nil.
+func (c *Client) do(ctx context.Context, method, path string, body, ans any) error {
+	var r io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(data)
+	}
+
+	req, err := c.newRequest(ctx, method, path, r)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.send(req, ans)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return req, nil
+}
+
+func (c *Client) send(req *http.Request, ans any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp)
+	}
+
+	if ans == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(ans)
+}
+
+// responseError returns the error of a response that is not a success.
+func responseError(resp *http.Response) error {
+	ans := Error{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Message string             `json:"message"`
+		Rows    []web.BulkRowError `json:"rows"`
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if err := json.Unmarshal(data, &body); err == nil && body.Message != "" {
+		ans.Message = body.Message
+		ans.Rows = body.Rows
+	} else {
+		ans.Message = strings.TrimSpace(string(data))
+	}
+
+	if ans.Message == "" {
+		ans.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return &ans
+}
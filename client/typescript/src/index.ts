@@ -0,0 +1,43 @@
+// Typed client of the REST API of google-maps-scraper. The types are
+// generated from the OpenAPI specification in src/schema.d.ts with
+// `npm run generate`, so they follow the server.
+import createClient, { type Middleware } from "openapi-fetch";
+
+import type { components, paths } from "./schema";
+
+export type { components, paths };
+
+export type Job = components["schemas"]["Job"];
+export type JobTemplate = components["schemas"]["JobTemplate"];
+export type ApiScrapeRequest = components["schemas"]["ApiScrapeRequest"];
+
+export interface ClientOptions {
+  // apiKey authenticates the requests to a server running with -auth
+  apiKey?: string;
+  // username and password authenticate as a user instead of an API key
+  username?: string;
+  password?: string;
+  fetch?: typeof globalThis.fetch;
+}
+
+// createScraperClient returns a client of the server at baseUrl, e.g.
+// http://localhost:8080.
+export function createScraperClient(baseUrl: string, opts: ClientOptions = {}) {
+  const client = createClient<paths>({ baseUrl, fetch: opts.fetch });
+
+  const auth: Middleware = {
+    onRequest({ request }) {
+      if (opts.apiKey) {
+        request.headers.set("X-API-Key", opts.apiKey);
+      } else if (opts.username) {
+        request.headers.set("Authorization", "Basic " + btoa(`${opts.username}:${opts.password ?? ""}`));
+      }
+
+      return request;
+    },
+  };
+
+  client.use(auth);
+
+  return client;
+}
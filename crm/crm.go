@@ -0,0 +1,68 @@
+package crm
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// Lead is the subset of an entry that is pushed to a CRM.
+type Lead struct {
+	Name       string
+	Domain     string
+	Website    string
+	Phone      string
+	Emails     []string
+	Address    string
+	City       string
+	PostalCode string
+	State      string
+	Country    string
+}
+
+// Client creates or updates companies and contacts in a CRM.
+type Client interface {
+	// UpsertCompany creates the company or updates the one with the same domain
+	// and returns its id in the CRM.
+	UpsertCompany(ctx context.Context, lead *Lead) (string, error)
+	// UpsertContact creates the contact with the given email if it does not exist
+	// and associates it with the company.
+	UpsertContact(ctx context.Context, companyID, email string, lead *Lead) error
+}
+
+// LeadFromEntry converts an entry to a lead. The domain is only set when the
+// website of the entry is the business own site and not a social profile.
+func LeadFromEntry(entry *gmaps.Entry) Lead {
+	lead := Lead{
+		Name:       entry.Title,
+		Phone:      entry.Phone,
+		Emails:     entry.Emails,
+		Address:    entry.Address,
+		City:       entry.CompleteAddress.City,
+		PostalCode: entry.CompleteAddress.PostalCode,
+		State:      entry.CompleteAddress.State,
+		Country:    entry.CompleteAddress.Country,
+	}
+
+	if entry.IsWebsiteValidForEmail() {
+		lead.Website = entry.WebSite
+		lead.Domain = domainFromURL(entry.WebSite)
+	}
+
+	return lead
+}
+
+func domainFromURL(u string) string {
+	if !strings.Contains(u, "://") {
+		u = "http://" + u
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
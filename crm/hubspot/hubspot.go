@@ -0,0 +1,186 @@
+package hubspot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/crm"
+)
+
+const defaultBaseURL = "https://api.hubapi.com"
+
+var _ crm.Client = (*client)(nil)
+
+type client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+}
+
+// New returns a HubSpot client authenticated with a private app access token.
+// The token needs the crm.objects.companies and crm.objects.contacts write scopes.
+func New(token string) crm.Client {
+	return &client{
+		token:   token,
+		baseURL: defaultBaseURL,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type searchRequest struct {
+	FilterGroups []filterGroup `json:"filterGroups"`
+	Limit        int           `json:"limit"`
+}
+
+type filterGroup struct {
+	Filters []filter `json:"filters"`
+}
+
+type filter struct {
+	PropertyName string `json:"propertyName"`
+	Operator     string `json:"operator"`
+	Value        string `json:"value"`
+}
+
+type searchResponse struct {
+	Results []object `json:"results"`
+}
+
+type object struct {
+	ID         string            `json:"id,omitempty"`
+	Properties map[string]string `json:"properties"`
+}
+
+func (c *client) UpsertCompany(ctx context.Context, lead *crm.Lead) (string, error) {
+	props := map[string]string{
+		"name":    lead.Name,
+		"phone":   lead.Phone,
+		"address": lead.Address,
+		"city":    lead.City,
+		"zip":     lead.PostalCode,
+		"state":   lead.State,
+		"country": lead.Country,
+	}
+
+	if lead.Domain != "" {
+		props["domain"] = lead.Domain
+		props["website"] = lead.Website
+
+		id, err := c.findOne(ctx, "companies", "domain", lead.Domain)
+		if err != nil {
+			return "", err
+		}
+
+		if id != "" {
+			err := c.do(ctx, http.MethodPatch, "/crm/v3/objects/companies/"+id, object{Properties: props}, nil)
+
+			return id, err
+		}
+	}
+
+	var created object
+
+	if err := c.do(ctx, http.MethodPost, "/crm/v3/objects/companies", object{Properties: props}, &created); err != nil {
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+func (c *client) UpsertContact(ctx context.Context, companyID, email string, lead *crm.Lead) error {
+	id, err := c.findOne(ctx, "contacts", "email", email)
+	if err != nil {
+		return err
+	}
+
+	if id == "" {
+		props := map[string]string{
+			"email":   email,
+			"company": lead.Name,
+			"phone":   lead.Phone,
+			"website": lead.Website,
+		}
+
+		var created object
+
+		if err := c.do(ctx, http.MethodPost, "/crm/v3/objects/contacts", object{Properties: props}, &created); err != nil {
+			return err
+		}
+
+		id = created.ID
+	}
+
+	if companyID == "" {
+		return nil
+	}
+
+	path := fmt.Sprintf("/crm/v4/objects/contacts/%s/associations/default/companies/%s", id, companyID)
+
+	return c.do(ctx, http.MethodPut, path, nil, nil)
+}
+
+func (c *client) findOne(ctx context.Context, objectType, property, value string) (string, error) {
+	req := searchRequest{
+		FilterGroups: []filterGroup{
+			{Filters: []filter{{PropertyName: property, Operator: "EQ", Value: value}}},
+		},
+		Limit: 1,
+	}
+
+	var resp searchResponse
+
+	if err := c.do(ctx, http.MethodPost, "/crm/v3/objects/"+objectType+"/search", req, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Results) == 0 {
+		return "", nil
+	}
+
+	return resp.Results[0].ID, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("hubspot %s %s: status %d: %s", method, path, resp.StatusCode, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
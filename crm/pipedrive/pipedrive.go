@@ -0,0 +1,162 @@
+package pipedrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/crm"
+)
+
+const defaultBaseURL = "https://api.pipedrive.com/v1"
+
+var _ crm.Client = (*client)(nil)
+
+type client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+}
+
+// New returns a Pipedrive client authenticated with a personal API token.
+// Pipedrive organizations have no domain field so existing organizations
+// are matched by exact name.
+func New(token string) crm.Client {
+	return &client{
+		token:   token,
+		baseURL: defaultBaseURL,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type searchResponse struct {
+	Data struct {
+		Items []struct {
+			Item struct {
+				ID int `json:"id"`
+			} `json:"item"`
+		} `json:"items"`
+	} `json:"data"`
+}
+
+type createResponse struct {
+	Data struct {
+		ID int `json:"id"`
+	} `json:"data"`
+}
+
+func (c *client) UpsertCompany(ctx context.Context, lead *crm.Lead) (string, error) {
+	params := url.Values{}
+	params.Set("term", lead.Name)
+	params.Set("exact_match", "true")
+	params.Set("limit", "1")
+
+	var found searchResponse
+
+	if err := c.do(ctx, http.MethodGet, "/organizations/search", params, nil, &found); err != nil {
+		return "", err
+	}
+
+	body := map[string]any{
+		"name":    lead.Name,
+		"address": lead.Address,
+	}
+
+	if len(found.Data.Items) > 0 {
+		id := strconv.Itoa(found.Data.Items[0].Item.ID)
+
+		return id, c.do(ctx, http.MethodPut, "/organizations/"+id, nil, body, nil)
+	}
+
+	var created createResponse
+
+	if err := c.do(ctx, http.MethodPost, "/organizations", nil, body, &created); err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(created.Data.ID), nil
+}
+
+func (c *client) UpsertContact(ctx context.Context, companyID, email string, lead *crm.Lead) error {
+	params := url.Values{}
+	params.Set("term", email)
+	params.Set("fields", "email")
+	params.Set("exact_match", "true")
+	params.Set("limit", "1")
+
+	var found searchResponse
+
+	if err := c.do(ctx, http.MethodGet, "/persons/search", params, nil, &found); err != nil {
+		return err
+	}
+
+	if len(found.Data.Items) > 0 {
+		return nil
+	}
+
+	body := map[string]any{
+		"name":  email,
+		"email": []string{email},
+	}
+
+	if lead.Phone != "" {
+		body["phone"] = []string{lead.Phone}
+	}
+
+	if id, err := strconv.Atoi(companyID); err == nil {
+		body["org_id"] = id
+	}
+
+	return c.do(ctx, http.MethodPost, "/persons", nil, body, nil)
+}
+
+func (c *client) do(ctx context.Context, method, path string, params url.Values, body, out any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	params.Set("api_token", c.token)
+
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path+"?"+params.Encode(), reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return fmt.Errorf("pipedrive %s %s: status %d: %s", method, path, resp.StatusCode, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,86 @@
+package crm
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+var _ scrapemate.ResultWriter = (*writer)(nil)
+
+type writer struct {
+	client Client
+	dryRun bool
+	// seen maps the dedupe key of a lead to the company id in the CRM
+	seen map[string]string
+}
+
+// NewResultWriter pushes every entry to the CRM. Leads are deduplicated by
+// domain, or by name and phone when there is no domain.
+// In dry-run mode the leads are only logged.
+// Push failures are logged and do not stop the scraping.
+func NewResultWriter(client Client, dryRun bool) scrapemate.ResultWriter {
+	return &writer{
+		client: client,
+		dryRun: dryRun,
+		seen:   make(map[string]string),
+	}
+}
+
+func (w *writer) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			w.push(ctx, data)
+		case []*gmaps.Entry:
+			for i := range data {
+				w.push(ctx, data[i])
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *writer) push(ctx context.Context, entry *gmaps.Entry) {
+	lead := LeadFromEntry(entry)
+	if lead.Name == "" {
+		return
+	}
+
+	key := lead.Domain
+	if key == "" {
+		key = strings.ToLower(lead.Name) + "|" + lead.Phone
+	}
+
+	if _, ok := w.seen[key]; ok {
+		return
+	}
+
+	if w.dryRun {
+		log.Printf("crm dry-run: company name=%q domain=%q phone=%q emails=%v", lead.Name, lead.Domain, lead.Phone, lead.Emails)
+
+		w.seen[key] = ""
+
+		return
+	}
+
+	companyID, err := w.client.UpsertCompany(ctx, &lead)
+	if err != nil {
+		log.Printf("crm: failed to push company %q: %v", lead.Name, err)
+
+		return
+	}
+
+	w.seen[key] = companyID
+
+	for _, email := range lead.Emails {
+		if err := w.client.UpsertContact(ctx, companyID, email, &lead); err != nil {
+			log.Printf("crm: failed to push contact %q: %v", email, err)
+		}
+	}
+}
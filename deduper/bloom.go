@@ -0,0 +1,313 @@
+package deduper
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	_ Deduper  = (*bloom)(nil)
+	_ Exporter = (*bloom)(nil)
+)
+
+var bloomMagic = [4]byte{'G', 'M', 'B', 'F'}
+
+const bloomVersion uint32 = 1
+
+type bloom struct {
+	mux   sync.Mutex
+	bits  []uint64
+	m     uint64
+	k     uint64
+	dirty bool
+
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBloom returns a memory bounded deduper backed by a bloom filter sized for
+// expected keys with the given false positive rate. A false positive means
+// that a new place is reported as seen and skipped.
+//
+// When path is not empty the filter is loaded from it if the file exists and
+// it is written back every interval and on Close, so a crawl can be resumed.
+func NewBloom(expected uint64, fpRate float64, path string, interval time.Duration) (Deduper, error) {
+	if expected == 0 {
+		return nil, errors.New("expected items must be greater than 0")
+	}
+
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, errors.New("false positive rate must be between 0 and 1")
+	}
+
+	m, k := bloomParams(expected, fpRate)
+
+	ans := bloom{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+		path: path,
+	}
+
+	if path == "" {
+		return &ans, nil
+	}
+
+	if err := ans.load(); err != nil {
+		return nil, err
+	}
+
+	if interval > 0 {
+		ans.stop = make(chan struct{})
+		ans.done = make(chan struct{})
+
+		go ans.snapshotLoop(interval)
+	}
+
+	return &ans, nil
+}
+
+func (d *bloom) AddIfNotExists(_ context.Context, key string) bool {
+	h1, h2 := bloomHash(key)
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	added := false
+
+	for i := uint64(0); i < d.k; i++ {
+		pos := (h1 + i*h2) % d.m
+		word, mask := pos/64, uint64(1)<<(pos%64)
+
+		if d.bits[word]&mask == 0 {
+			d.bits[word] |= mask
+			added = true
+		}
+	}
+
+	if added {
+		d.dirty = true
+	}
+
+	return added
+}
+
+// Close stops the periodic snapshots and writes the final one.
+func (d *bloom) Close() error {
+	if d.stop != nil {
+		close(d.stop)
+		<-d.done
+	}
+
+	if d.path == "" {
+		return nil
+	}
+
+	return d.snapshot()
+}
+
+func (d *bloom) snapshotLoop(interval time.Duration) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			if err := d.snapshot(); err != nil {
+				log.Printf("bloom deduper: %v", err)
+			}
+		}
+	}
+}
+
+func (d *bloom) load() error {
+	f, err := os.Open(d.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to open bloom snapshot: %w", err)
+	}
+
+	defer f.Close()
+
+	words, err := d.read(f)
+	if err != nil {
+		return fmt.Errorf("bloom snapshot %s: %w", d.path, err)
+	}
+
+	d.bits = words
+
+	return nil
+}
+
+// read reads a filter written by write. It must have the same size and
+// number of hash functions as d.
+func (d *bloom) read(r io.Reader) ([]uint64, error) {
+	br := bufio.NewReader(r)
+
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		M       uint64
+		K       uint64
+	}
+
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter: %w", err)
+	}
+
+	if header.Magic != bloomMagic || header.Version != bloomVersion {
+		return nil, errors.New("not a bloom filter")
+	}
+
+	if header.M != d.m || header.K != d.k {
+		return nil, errors.New("bloom filter was created with different size or false positive rate")
+	}
+
+	words := make([]uint64, len(d.bits))
+
+	if err := binary.Read(br, binary.LittleEndian, words); err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter: %w", err)
+	}
+
+	return words, nil
+}
+
+// Export writes the filter in the snapshot format.
+func (d *bloom) Export(w io.Writer) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	return d.write(w)
+}
+
+// Import merges an exported filter of the same size into d.
+func (d *bloom) Import(r io.Reader) error {
+	words, err := d.read(r)
+	if err != nil {
+		return err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	for i := range words {
+		d.bits[i] |= words[i]
+	}
+
+	d.dirty = true
+
+	return nil
+}
+
+// Len estimates the number of keys from the number of set bits.
+func (d *bloom) Len() uint64 {
+	d.mux.Lock()
+
+	set := 0
+	for _, w := range d.bits {
+		set += bits.OnesCount64(w)
+	}
+
+	d.mux.Unlock()
+
+	if uint64(set) >= d.m {
+		return d.m / d.k
+	}
+
+	m, k := float64(d.m), float64(d.k)
+
+	return uint64(math.Round(-m / k * math.Log(1-float64(set)/m)))
+}
+
+func (d *bloom) snapshot() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if !d.dirty {
+		return nil
+	}
+
+	// write to a temporary file first so a crash does not leave a corrupt snapshot
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create bloom snapshot: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := d.write(tmp); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to write bloom snapshot: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write bloom snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		return fmt.Errorf("failed to write bloom snapshot: %w", err)
+	}
+
+	d.dirty = false
+
+	return nil
+}
+
+func (d *bloom) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	header := []any{bloomMagic, bloomVersion, d.m, d.k, d.bits}
+	for _, v := range header {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// bloomParams returns the optimal number of bits and hash functions.
+func bloomParams(n uint64, p float64) (m, k uint64) {
+	m = uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k = uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+
+	if k == 0 {
+		k = 1
+	}
+
+	return m, k
+}
+
+// bloomHash returns the two halves of a 128 bit fnv hash used for double hashing.
+func bloomHash(key string) (h1, h2 uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+
+	sum := h.Sum(nil)
+
+	h1 = binary.BigEndian.Uint64(sum[:8])
+	h2 = binary.BigEndian.Uint64(sum[8:]) | 1
+
+	return h1, h2
+}
@@ -0,0 +1,39 @@
+package deduper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bloom.snapshot")
+
+	d, err := NewBloom(1000, 0.001, path, 0)
+	require.NoError(t, err)
+
+	for i := range 100 {
+		require.True(t, d.AddIfNotExists(ctx, fmt.Sprintf("place-%d", i)))
+	}
+
+	require.False(t, d.AddIfNotExists(ctx, "place-7"))
+	require.NoError(t, d.(*bloom).Close())
+
+	// the next run loads the snapshot
+	d, err = NewBloom(1000, 0.001, path, 0)
+	require.NoError(t, err)
+
+	for i := range 100 {
+		require.False(t, d.AddIfNotExists(ctx, fmt.Sprintf("place-%d", i)))
+	}
+
+	require.True(t, d.AddIfNotExists(ctx, "place-new"))
+
+	// a filter of another size cannot load it
+	_, err = NewBloom(50, 0.1, path, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package deduper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrExportNotSupported is returned when a deduper cannot export or import its keys.
+var ErrExportNotSupported = errors.New("deduper does not support export")
+
+// Exporter is implemented by the dedupers whose keys can be written to a file
+// and loaded on another machine.
+type Exporter interface {
+	Export(io.Writer) error
+	// Import adds the exported keys to the ones already stored.
+	Import(io.Reader) error
+}
+
+// ExportFile writes the keys of d to path.
+// The file is written to a temporary file first and then renamed.
+func ExportFile(d Deduper, path string) error {
+	e, ok := d.(Exporter)
+	if !ok {
+		return ErrExportNotSupported
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create dedup export: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := e.Export(tmp); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to write dedup export: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write dedup export: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// ImportFile adds the keys exported to path to d.
+func ImportFile(d Deduper, path string) error {
+	e, ok := d.(Exporter)
+	if !ok {
+		return ErrExportNotSupported
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	if err := e.Import(f); err != nil {
+		return fmt.Errorf("failed to import %s: %w", path, err)
+	}
+
+	return nil
+}
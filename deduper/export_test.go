@@ -0,0 +1,80 @@
+package deduper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type noExport struct{}
+
+func (noExport) AddIfNotExists(context.Context, string) bool {
+	return true
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	newBloom := func() Deduper {
+		d, err := NewBloom(1000, 0.001, "", 0)
+		require.NoError(t, err)
+
+		return d
+	}
+
+	for name, newDeduper := range map[string]func() Deduper{
+		"hashmap":      New,
+		"bloom":        newBloom,
+		"instrumented": func() Deduper { return WithStats(New()) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := newDeduper()
+
+			for i := range 50 {
+				src.AddIfNotExists(ctx, fmt.Sprintf("place-%d", i))
+			}
+
+			path := filepath.Join(dir, name+".dedup")
+
+			require.NoError(t, ExportFile(src, path))
+
+			dst := newDeduper()
+			require.True(t, dst.AddIfNotExists(ctx, "already-there"))
+
+			require.NoError(t, ImportFile(dst, path))
+
+			for i := range 50 {
+				require.False(t, dst.AddIfNotExists(ctx, fmt.Sprintf("place-%d", i)))
+			}
+
+			// the keys of dst are kept
+			require.False(t, dst.AddIfNotExists(ctx, "already-there"))
+			require.True(t, dst.AddIfNotExists(ctx, "place-new"))
+		})
+	}
+
+	// the formats are not interchangeable
+	path := filepath.Join(dir, "hashmap.dedup")
+	require.Error(t, ImportFile(newBloom(), path))
+
+	require.ErrorIs(t, ExportFile(noExport{}, filepath.Join(dir, "x")), ErrExportNotSupported)
+	require.ErrorIs(t, ImportFile(noExport{}, path), ErrExportNotSupported)
+	require.ErrorIs(t, ExportFile(WithStats(noExport{}), filepath.Join(dir, "x")), ErrExportNotSupported)
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	d := WithStats(New())
+
+	d.AddIfNotExists(ctx, "a")
+	d.AddIfNotExists(ctx, "b")
+	d.AddIfNotExists(ctx, "a")
+
+	s := d.Stats()
+	require.Equal(t, Stats{Keys: 2, Lookups: 3, Hits: 1}, s)
+	require.InDelta(t, 1.0/3, s.HitRate(), 1e-9)
+}
@@ -1,12 +1,20 @@
 package deduper
 
 import (
+	"bufio"
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"hash/fnv"
+	"io"
 	"sync"
 )
 
-var _ Deduper = (*hashmap)(nil)
+var (
+	_ Deduper  = (*hashmap)(nil)
+	_ Exporter = (*hashmap)(nil)
+)
 
 type hashmap struct {
 	mux  *sync.RWMutex
@@ -40,3 +48,76 @@ func (d *hashmap) hash(key string) uint64 {
 
 	return h.Sum64()
 }
+
+var hashmapMagic = [4]byte{'G', 'M', 'D', 'H'}
+
+const hashmapVersion uint32 = 1
+
+func (d *hashmap) Len() uint64 {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	return uint64(len(d.seen))
+}
+
+// Export writes the hashes of the seen keys.
+func (d *hashmap) Export(w io.Writer) error {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	bw := bufio.NewWriter(w)
+
+	header := []any{hashmapMagic, hashmapVersion, uint64(len(d.seen))}
+	for _, v := range header {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	for h := range d.seen {
+		if err := binary.Write(bw, binary.LittleEndian, h); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Import adds the hashes written by Export.
+func (d *hashmap) Import(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Count   uint64
+	}
+
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("failed to read dedup export: %w", err)
+	}
+
+	if header.Magic != hashmapMagic || header.Version != hashmapVersion {
+		return errors.New("not a dedup export")
+	}
+
+	hashes := make([]uint64, 0, min(header.Count, 1<<20))
+
+	for n := uint64(0); n < header.Count; n++ {
+		var h uint64
+		if err := binary.Read(br, binary.LittleEndian, &h); err != nil {
+			return fmt.Errorf("failed to read dedup export: %w", err)
+		}
+
+		hashes = append(hashes, h)
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	for _, h := range hashes {
+		d.seen[h] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package deduper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis stores the keys of SetNX in memory with their expiration.
+type fakeRedis struct {
+	keys map[string]time.Duration
+	err  error
+}
+
+func (f *fakeRedis) SetNX(_ context.Context, key string, _ any, expiration time.Duration) *redis.BoolCmd {
+	if f.err != nil {
+		return redis.NewBoolResult(false, f.err)
+	}
+
+	if _, ok := f.keys[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+
+	f.keys[key] = expiration
+
+	return redis.NewBoolResult(true, nil)
+}
+
+func TestRedisDedupe(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeRedis{keys: map[string]time.Duration{}}
+	d := &redisDeduper{client: client, prefix: "gmaps:"}
+
+	require.True(t, d.AddIfNotExists(ctx, "place-1"))
+	require.False(t, d.AddIfNotExists(ctx, "place-1"))
+	require.True(t, d.AddIfNotExists(ctx, "place-2"))
+
+	// the keys are hashed under the prefix and kept forever without a ttl
+	require.Len(t, client.keys, 2)
+
+	for key, ttl := range client.keys {
+		require.Regexp(t, `^gmaps:[0-9a-f]+$`, key)
+		require.Zero(t, ttl)
+	}
+
+	// another prefix is another set of keys
+	other := &redisDeduper{client: client, prefix: "other:"}
+	require.True(t, other.AddIfNotExists(ctx, "place-1"))
+}
+
+func TestRedisTTL(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeRedis{keys: map[string]time.Duration{}}
+	d := &redisDeduper{client: client, prefix: "gmaps:", ttl: 24 * time.Hour}
+
+	require.True(t, d.AddIfNotExists(ctx, "place-1"))
+	require.Equal(t, 24*time.Hour, client.keys[d.key("place-1")])
+}
+
+func TestRedisFailOpen(t *testing.T) {
+	ctx := context.Background()
+
+	// the keys are new while Redis fails
+	d := &redisDeduper{client: &fakeRedis{err: errors.New("connection refused")}}
+	require.True(t, d.AddIfNotExists(ctx, "place-1"))
+	require.True(t, d.AddIfNotExists(ctx, "place-1"))
+
+	// and while it is down
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	_, err = NewRedis(ctx, "redis://"+addr, "gmaps:", 0)
+	require.ErrorContains(t, err, "failed to connect to redis")
+
+	client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1, DialTimeout: time.Second})
+	t.Cleanup(func() { _ = client.Close() })
+
+	d = &redisDeduper{client: client, prefix: "gmaps:"}
+	require.True(t, d.AddIfNotExists(ctx, "place-1"))
+	require.True(t, d.AddIfNotExists(ctx, "place-1"))
+
+	_, err = NewRedis(ctx, "not a url", "gmaps:", 0)
+	require.ErrorContains(t, err, "invalid redis url")
+}
@@ -0,0 +1,109 @@
+package deduper
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// Stats are the counters of an instrumented deduper.
+type Stats struct {
+	// Keys is the number of keys stored. For dedupers that cannot count
+	// their keys it is the number of keys added by this process.
+	Keys    uint64 `json:"keys"`
+	Lookups uint64 `json:"lookups"`
+	Hits    uint64 `json:"hits"`
+}
+
+// HitRate is the fraction of the lookups that found an existing key.
+func (s Stats) HitRate() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Lookups)
+}
+
+// Add returns the sum of the two stats.
+func (s Stats) Add(o Stats) Stats {
+	return Stats{
+		Keys:    s.Keys + o.Keys,
+		Lookups: s.Lookups + o.Lookups,
+		Hits:    s.Hits + o.Hits,
+	}
+}
+
+type lener interface {
+	Len() uint64
+}
+
+var (
+	_ Deduper  = (*Instrumented)(nil)
+	_ Exporter = (*Instrumented)(nil)
+)
+
+// Instrumented wraps a deduper and counts the lookups and the hits.
+type Instrumented struct {
+	d       Deduper
+	lookups atomic.Uint64
+	hits    atomic.Uint64
+}
+
+// WithStats returns d instrumented with stats.
+func WithStats(d Deduper) *Instrumented {
+	return &Instrumented{d: d}
+}
+
+func (i *Instrumented) AddIfNotExists(ctx context.Context, key string) bool {
+	added := i.d.AddIfNotExists(ctx, key)
+
+	i.lookups.Add(1)
+
+	if !added {
+		i.hits.Add(1)
+	}
+
+	return added
+}
+
+func (i *Instrumented) Stats() Stats {
+	ans := Stats{
+		Lookups: i.lookups.Load(),
+		Hits:    i.hits.Load(),
+	}
+
+	if l, ok := i.d.(lener); ok {
+		ans.Keys = l.Len()
+	} else {
+		ans.Keys = ans.Lookups - ans.Hits
+	}
+
+	return ans
+}
+
+func (i *Instrumented) Export(w io.Writer) error {
+	e, ok := i.d.(Exporter)
+	if !ok {
+		return ErrExportNotSupported
+	}
+
+	return e.Export(w)
+}
+
+func (i *Instrumented) Import(r io.Reader) error {
+	e, ok := i.d.(Exporter)
+	if !ok {
+		return ErrExportNotSupported
+	}
+
+	return e.Import(r)
+}
+
+// Close closes the wrapped deduper if it is an io.Closer.
+func (i *Instrumented) Close() error {
+	if c, ok := i.d.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package emailverify
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+)
+
+// The types of the emails of Classify.
+const (
+	// TypeDisposable is an address of a throwaway email service
+	TypeDisposable = "disposable"
+	// TypeRole is the address of a function rather than a person, e.g. info@ or sales@
+	TypeRole = "role"
+	// TypePersonal is any other address, most likely of a person
+	TypePersonal = "personal"
+)
+
+//go:embed disposable_domains.txt
+var disposableList string
+
+// disposableDomains are the domains of the bundled list of throwaway email services.
+var disposableDomains = parseDomains(disposableList)
+
+// roleLocals are the local parts of the role addresses, without the separators.
+var roleLocals = map[string]bool{
+	"accounting": true, "accounts": true, "admin": true, "administrator": true,
+	"billing": true, "booking": true, "bookings": true, "buero": true, "büro": true,
+	"careers": true, "contact": true, "contacto": true, "contatto": true, "contactus": true,
+	"customercare": true, "customerservice": true, "enquiries": true, "enquiry": true,
+	"events": true, "finance": true, "hello": true, "help": true, "helpdesk": true,
+	"hi": true, "hr": true, "info": true, "information": true, "inquiries": true,
+	"jobs": true, "kontakt": true, "legal": true, "mail": true, "marketing": true,
+	"media": true, "noreply": true, "office": true, "orders": true, "postmaster": true,
+	"press": true, "privacy": true, "reception": true, "reservations": true,
+	"reservation": true, "sales": true, "service": true, "shop": true, "support": true,
+	"team": true, "webmaster": true, "welcome": true,
+}
+
+func parseDomains(list string) map[string]bool {
+	ans := map[string]bool{}
+
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			ans[strings.ToLower(line)] = true
+		}
+	}
+
+	return ans
+}
+
+// IsDisposable reports whether domain, or one of its parent domains, is a
+// throwaway email service of the bundled list.
+func IsDisposable(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	for domain != "" {
+		if disposableDomains[domain] {
+			return true
+		}
+
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+
+		domain = parent
+	}
+
+	return false
+}
+
+// IsRole reports whether the local part of email is a function rather than
+// a person, e.g. info@, sales@ or sales-team@.
+func IsRole(email string) bool {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+
+	// the tag of a sub-address, e.g. info+web@
+	local, _, _ = strings.Cut(local, "+")
+
+	if roleLocals[strings.NewReplacer(".", "", "-", "", "_", "").Replace(local)] {
+		return true
+	}
+
+	// a role followed or preceded by a qualifier, e.g. sales.berlin@ or berlin-info@
+	for _, part := range strings.FieldsFunc(local, func(r rune) bool {
+		return r == '.' || r == '-' || r == '_'
+	}) {
+		if roleLocals[part] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Classify returns the type of email: disposable, role or personal.
+func Classify(email string) string {
+	_, domain, _ := strings.Cut(email, "@")
+
+	switch {
+	case IsDisposable(domain):
+		return TypeDisposable
+	case IsRole(email):
+		return TypeRole
+	default:
+		return TypePersonal
+	}
+}
+
+// ValidTypes reports whether all of types are email types.
+func ValidTypes(types []string) bool {
+	for _, t := range types {
+		switch t {
+		case TypeDisposable, TypeRole, TypePersonal:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,284 @@
+// Package emailverify checks that email addresses are deliverable: their
+// syntax, the MX records of their domain and the answer of the mail server
+// to RCPT TO, without sending an email.
+package emailverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The values of Result.Reachable.
+const (
+	ReachableYes     = "yes"
+	ReachableNo      = "no"
+	ReachableUnknown = "unknown"
+)
+
+// Result is the verification of an email address.
+type Result struct {
+	Email string `json:"email"`
+	// Syntax is true when the address is well formed
+	Syntax bool `json:"syntax"`
+	// MX is true when the domain has mail servers
+	MX bool `json:"mx"`
+	// SMTP is true when the mail server accepted the address as a recipient
+	SMTP bool `json:"smtp"`
+	// CatchAll is true when the mail server accepts any address of the domain
+	CatchAll bool `json:"catch_all"`
+	// Reachable is yes, no or unknown when the server could not be asked or accepts everything
+	Reachable string `json:"reachable"`
+	// Disposable is true when the domain is a throwaway email service
+	Disposable bool `json:"disposable"`
+}
+
+// Option configures a Verifier.
+type Option func(*Verifier)
+
+// WithTimeout limits each connection to a mail server to d.
+func WithTimeout(d time.Duration) Option {
+	return func(v *Verifier) {
+		v.timeout = d
+	}
+}
+
+// WithResolver replaces the lookup of the MX records of a domain.
+func WithResolver(lookupMX func(ctx context.Context, domain string) ([]*net.MX, error)) Option {
+	return func(v *Verifier) {
+		v.lookupMX = lookupMX
+	}
+}
+
+// WithDialer replaces the connection to the mail servers, addr is host:25.
+func WithDialer(dial func(ctx context.Context, addr string) (net.Conn, error)) Option {
+	return func(v *Verifier) {
+		v.dial = dial
+	}
+}
+
+// Verifier verifies email addresses. It is safe to share between jobs.
+type Verifier struct {
+	from     string
+	helo     string
+	timeout  time.Duration
+	lookupMX func(ctx context.Context, domain string) ([]*net.MX, error)
+	dial     func(ctx context.Context, addr string) (net.Conn, error)
+}
+
+// New returns a verifier that introduces itself to the mail servers with
+// from, an address of a domain of the operator: many servers refuse the
+// senders of domains without mail servers.
+func New(from string, opts ...Option) (*Verifier, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+
+	_, domain, _ := strings.Cut(addr.Address, "@")
+
+	v := &Verifier{
+		from:     addr.Address,
+		helo:     domain,
+		timeout:  10 * time.Second,
+		lookupMX: net.DefaultResolver.LookupMX,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	if v.dial == nil {
+		v.dial = func(ctx context.Context, addr string) (net.Conn, error) {
+			d := net.Dialer{Timeout: v.timeout}
+
+			return d.DialContext(ctx, "tcp", addr)
+		}
+	}
+
+	return v, nil
+}
+
+// Verify verifies email.
+func (v *Verifier) Verify(ctx context.Context, email string) Result {
+	local, domain, _ := strings.Cut(email, "@")
+
+	return v.VerifyDomain(ctx, domain, []string{local})[0]
+}
+
+// VerifyDomain verifies the addresses of the local parts locals at domain
+// in a single session with its mail server, in the order of locals.
+func (v *Verifier) VerifyDomain(ctx context.Context, domain string, locals []string) []Result {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+
+	ans := make([]Result, len(locals))
+
+	var valid []int
+
+	disposable := IsDisposable(domain)
+
+	for i, local := range locals {
+		ans[i] = Result{Email: local + "@" + domain, Reachable: ReachableNo, Disposable: disposable}
+
+		if ValidSyntax(ans[i].Email) {
+			ans[i].Syntax = true
+
+			valid = append(valid, i)
+		}
+	}
+
+	if len(valid) == 0 {
+		return ans
+	}
+
+	hosts, err := v.mailServers(ctx, domain)
+	if err != nil {
+		return ans
+	}
+
+	rcpts := make([]string, len(valid))
+	for i, j := range valid {
+		ans[j].MX = true
+		ans[j].Reachable = ReachableUnknown
+		rcpts[i] = ans[j].Email
+	}
+
+	accepted, catchAll, err := v.ask(ctx, hosts, domain, rcpts)
+	if err != nil {
+		return ans
+	}
+
+	for i, j := range valid {
+		ans[j].CatchAll = catchAll
+
+		switch {
+		case accepted[i] == nil:
+			ans[j].SMTP = true
+
+			if !catchAll {
+				ans[j].Reachable = ReachableYes
+			}
+		case isPermanent(accepted[i]):
+			ans[j].Reachable = ReachableNo
+		}
+	}
+
+	return ans
+}
+
+// ValidSyntax reports whether email is a bare address with a dotted domain.
+func ValidSyntax(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return false
+	}
+
+	_, domain, _ := strings.Cut(email, "@")
+
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+func (v *Verifier) mailServers(ctx context.Context, domain string) ([]string, error) {
+	mxs, err := v.lookupMX(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(mxs, func(a, b *net.MX) int {
+		return int(a.Pref) - int(b.Pref)
+	})
+
+	var hosts []string
+
+	for _, mx := range mxs {
+		// a null MX, the domain does not accept email
+		if host := strings.TrimSuffix(mx.Host, "."); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, errors.New("no mail servers")
+	}
+
+	return hosts, nil
+}
+
+// ask asks the first mail server that answers whether it accepts the
+// recipients, and whether it accepts a random address of the domain.
+func (v *Verifier) ask(ctx context.Context, hosts []string, domain string, rcpts []string) ([]error, bool, error) {
+	var err error
+
+	for _, host := range hosts {
+		var (
+			accepted []error
+			catchAll bool
+		)
+
+		accepted, catchAll, err = v.askHost(ctx, host, domain, rcpts)
+		if err == nil {
+			return accepted, catchAll, nil
+		}
+	}
+
+	return nil, false, err
+}
+
+func (v *Verifier) askHost(ctx context.Context, host, domain string, rcpts []string) ([]error, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	conn, err := v.dial(ctx, net.JoinHostPort(host, "25"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer c.Close()
+
+	if err := c.Hello(v.helo); err != nil {
+		return nil, false, err
+	}
+
+	if err := c.Mail(v.from); err != nil {
+		return nil, false, err
+	}
+
+	probe := fmt.Sprintf("no-such-user-%08x@%s", rand.Uint32(), domain)
+	catchAll := c.Rcpt(probe) == nil
+
+	accepted := make([]error, len(rcpts))
+	for i, rcpt := range rcpts {
+		accepted[i] = c.Rcpt(rcpt)
+	}
+
+	_ = c.Quit()
+
+	return accepted, catchAll, nil
+}
+
+// isPermanent reports whether err is a 5xx answer of the server, a mailbox
+// that does not exist rather than a greylisting or a rate limit.
+func isPermanent(err error) bool {
+	var tpErr *textproto.Error
+
+	return errors.As(err, &tpErr) && tpErr.Code >= 500
+}
@@ -0,0 +1,143 @@
+package emailverify_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/emailverify"
+)
+
+// serveSMTP answers the session of a mail server that accepts the mailboxes
+// of users, or any address when users is nil.
+func serveSMTP(conn net.Conn, users map[string]bool) {
+	defer conn.Close()
+
+	w := bufio.NewWriter(conn)
+	reply := func(s string) {
+		_, _ = w.WriteString(s + "\r\n")
+		_ = w.Flush()
+	}
+
+	reply("220 mx.example.com ESMTP")
+
+	scanner := bufio.NewScanner(conn)
+
+	for scanner.Scan() {
+		cmd := strings.ToUpper(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"), strings.HasPrefix(cmd, "MAIL"):
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT"):
+			rcpt := strings.Trim(strings.TrimPrefix(scanner.Text()[len("RCPT TO:"):], " "), "<>")
+			if users == nil || users[rcpt] {
+				reply("250 ok")
+			} else {
+				reply("550 no such user")
+			}
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func newVerifier(t *testing.T, users map[string]bool) *emailverify.Verifier {
+	t.Helper()
+
+	v, err := emailverify.New("verify@operator.com",
+		emailverify.WithResolver(func(_ context.Context, domain string) ([]*net.MX, error) {
+			if domain != "example.com" {
+				return nil, errors.New("no such host")
+			}
+
+			return []*net.MX{{Host: "mx.example.com.", Pref: 10}}, nil
+		}),
+		emailverify.WithDialer(func(context.Context, string) (net.Conn, error) {
+			client, server := net.Pipe()
+
+			go serveSMTP(server, users)
+
+			return client, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	return v
+}
+
+func Test_Verifier(t *testing.T) {
+	t.Parallel()
+
+	_, err := emailverify.New("not an address")
+	require.Error(t, err)
+
+	ctx := context.Background()
+	v := newVerifier(t, map[string]bool{"info@example.com": true})
+
+	results := v.VerifyDomain(ctx, "example.com", []string{"info", "sales", "bad..local"})
+	require.Equal(t, []emailverify.Result{
+		{Email: "info@example.com", Syntax: true, MX: true, SMTP: true, Reachable: emailverify.ReachableYes},
+		{Email: "sales@example.com", Syntax: true, MX: true, Reachable: emailverify.ReachableNo},
+		{Email: "bad..local@example.com", Reachable: emailverify.ReachableNo},
+	}, results)
+
+	require.Equal(t, emailverify.Result{
+		Email:     "info@nomx.com",
+		Syntax:    true,
+		Reachable: emailverify.ReachableNo,
+	}, v.Verify(ctx, "info@nomx.com"))
+
+	catchAll := newVerifier(t, nil)
+	require.Equal(t, emailverify.Result{
+		Email:     "sales@example.com",
+		Syntax:    true,
+		MX:        true,
+		SMTP:      true,
+		CatchAll:  true,
+		Reachable: emailverify.ReachableUnknown,
+	}, catchAll.Verify(ctx, "sales@example.com"))
+}
+
+func Test_ValidSyntax(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, emailverify.ValidSyntax("info@example.com"))
+	require.False(t, emailverify.ValidSyntax("info@localhost"))
+	require.False(t, emailverify.ValidSyntax("Info <info@example.com>"))
+	require.False(t, emailverify.ValidSyntax("info.example.com"))
+}
+
+func Test_IsDisposable(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, emailverify.IsDisposable("mailinator.com"))
+	require.True(t, emailverify.IsDisposable("eu.Mailinator.com."))
+	require.False(t, emailverify.IsDisposable("example.com"))
+	require.False(t, emailverify.IsDisposable("notmailinator.com"))
+}
+
+func Test_Classify(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, emailverify.TypeDisposable, emailverify.Classify("info@yopmail.com"))
+	require.Equal(t, emailverify.TypeRole, emailverify.Classify("info@example.com"))
+	require.Equal(t, emailverify.TypeRole, emailverify.Classify("Sales.Berlin@example.com"))
+	require.Equal(t, emailverify.TypeRole, emailverify.Classify("customer-service@example.com"))
+	require.Equal(t, emailverify.TypeRole, emailverify.Classify("info+web@example.com"))
+	require.Equal(t, emailverify.TypePersonal, emailverify.Classify("maria.rossi@example.com"))
+	require.Equal(t, emailverify.TypePersonal, emailverify.Classify("jsmith@example.com"))
+
+	require.True(t, emailverify.ValidTypes(nil))
+	require.True(t, emailverify.ValidTypes([]string{emailverify.TypeRole, emailverify.TypePersonal}))
+	require.False(t, emailverify.ValidTypes([]string{"spam"}))
+}
@@ -0,0 +1,31 @@
+//go:build plugin
+// +build plugin
+
+package main
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// the enricher is registered when the plugin is opened, it is chosen with
+// -enrichers technologies,contact_form,structured_data,socials,phones,booking
+func init() {
+	gmaps.RegisterEnricher(gmaps.NewEnricher("booking", enrichBooking))
+}
+
+// enrichBooking adds "Online booking" to the technologies of the websites
+// with a booking widget.
+func enrichBooking(_ context.Context, page *gmaps.WebsitePage, e *gmaps.Entry) error {
+	for _, s := range []string{"opentable.com/widget", "resy.com/embed", "calendly.com/"} {
+		if bytes.Contains(page.Body, []byte(s)) {
+			e.Technologies = append(e.Technologies, "Online booking")
+
+			break
+		}
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 
 	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
 	"github.com/gosom/scrapemate"
 )
 
@@ -17,6 +18,64 @@ var _ scrapemate.ResultWriter = (*exampleWriter)(nil)
 
 var DummyPrinter scrapemate.ResultWriter = newWriter("dummy.txt")
 
+// the writers registered when the plugin is opened are chosen by name, with
+// -writer plugins:titles -writer-config '{"file": "titles.txt"}' or by the
+// writers of the web jobs, {"type": "plugin", "name": "titles", "config": {...}}
+func init() {
+	runner.RegisterWriter("titles", func() scrapemate.ResultWriter {
+		return &titlesWriter{}
+	})
+}
+
+var _ runner.ConfigurableWriter = (*titlesWriter)(nil)
+
+// titlesWriter writes the titles of the entries in the file of its config.
+type titlesWriter struct {
+	file string
+}
+
+func (t *titlesWriter) Configure(config map[string]any) error {
+	file, ok := config["file"].(string)
+	if !ok || file == "" {
+		return fmt.Errorf("missing file")
+	}
+
+	t.file = file
+
+	return nil
+}
+
+func (t *titlesWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	fd, err := os.Create(t.file)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	w := bufio.NewWriter(fd)
+	defer w.Flush()
+
+	for result := range in {
+		var items []*gmaps.Entry
+
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			items = append(items, data)
+		case []*gmaps.Entry:
+			items = data
+		}
+
+		for _, item := range items {
+			if _, err := fmt.Fprintln(w, item.Title); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 type exampleWriter struct {
 	w *bufio.Writer
 }
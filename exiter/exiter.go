@@ -8,18 +8,32 @@ import (
 
 type Exiter interface {
 	SetSeedCount(int)
+	IncrSeedCount(int)
 	SetCancelFunc(context.CancelFunc)
 	IncrSeedCompleted(int)
 	IncrPlacesFound(int)
 	IncrPlacesCompleted(int)
+	IncrCroxyUses(int)
+	Progress() Progress
 	Run(context.Context)
 }
 
+// Progress holds the counters of a crawl.
+type Progress struct {
+	SeedCount       int `json:"seed_count"`
+	SeedCompleted   int `json:"seed_completed"`
+	PlacesFound     int `json:"places_found"`
+	PlacesCompleted int `json:"places_completed"`
+	// CroxyUses are the searches loaded through CroxyProxy
+	CroxyUses int `json:"croxy_uses"`
+}
+
 type exiter struct {
 	seedCount       int
 	seedCompleted   int
 	placesFound     int
 	placesCompleted int
+	croxyUses       int
 
 	mu         *sync.Mutex
 	cancelFunc context.CancelFunc
@@ -38,6 +52,14 @@ func (e *exiter) SetSeedCount(val int) {
 	e.seedCount = val
 }
 
+// IncrSeedCount adds seed jobs created while the crawl is running.
+func (e *exiter) IncrSeedCount(val int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.seedCount += val
+}
+
 func (e *exiter) SetCancelFunc(fn context.CancelFunc) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -66,6 +88,28 @@ func (e *exiter) IncrPlacesCompleted(val int) {
 	e.placesCompleted += val
 }
 
+// IncrCroxyUses counts the searches loaded through CroxyProxy.
+func (e *exiter) IncrCroxyUses(val int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.croxyUses += val
+}
+
+// Progress returns the counters of the crawl so far.
+func (e *exiter) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return Progress{
+		SeedCount:       e.seedCount,
+		SeedCompleted:   e.seedCompleted,
+		PlacesFound:     e.placesFound,
+		PlacesCompleted: e.placesCompleted,
+		CroxyUses:       e.croxyUses,
+	}
+}
+
 func (e *exiter) Run(ctx context.Context) {
 	ticker := time.NewTicker(time.Second * 5)
 	defer ticker.Stop()
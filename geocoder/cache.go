@@ -0,0 +1,97 @@
+package geocoder
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheKey identifies a geocoded place in a cache.
+type CacheKey struct {
+	Provider string
+	Place    string
+	Country  string
+}
+
+// Cache stores geocoded places so the same place is not geocoded twice.
+type Cache interface {
+	// GetGeocode returns the cached result of the key and whether it was found.
+	GetGeocode(ctx context.Context, key CacheKey) (Result, bool, error)
+	SaveGeocode(ctx context.Context, key CacheKey, res Result) error
+}
+
+var _ Geocoder = (*cached)(nil)
+
+type cached struct {
+	g        Geocoder
+	provider string
+	cache    Cache
+}
+
+// NewCached returns a geocoder that looks up the places in cache before
+// asking g, and stores the places g found. Places are matched case
+// insensitively. Cache errors are ignored, the place is geocoded instead.
+func NewCached(g Geocoder, provider string, cache Cache) Geocoder {
+	return &cached{g: g, provider: provider, cache: cache}
+}
+
+func (c *cached) Geocode(ctx context.Context, query, country string) (Result, error) {
+	key := CacheKey{
+		Provider: c.provider,
+		Place:    strings.ToLower(strings.Join(strings.Fields(query), " ")),
+		Country:  strings.ToLower(country),
+	}
+
+	if res, ok, err := c.cache.GetGeocode(ctx, key); err == nil && ok {
+		return res, nil
+	}
+
+	res, err := c.g.Geocode(ctx, query, country)
+	if err != nil {
+		return Result{}, err
+	}
+
+	_ = c.cache.SaveGeocode(ctx, key, res)
+
+	return res, nil
+}
+
+var _ Geocoder = (*rateLimited)(nil)
+
+type rateLimited struct {
+	g        Geocoder
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewRateLimited returns a geocoder that sends the requests of g one at a
+// time, at least interval apart.
+func NewRateLimited(g Geocoder, interval time.Duration) Geocoder {
+	return &rateLimited{g: g, interval: interval}
+}
+
+func (r *rateLimited) Geocode(ctx context.Context, query, country string) (Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if wait := time.Until(r.last.Add(r.interval)); wait > 0 {
+		t := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+
+			return Result{}, ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	defer func() {
+		r.last = time.Now()
+	}()
+
+	return r.g.Geocode(ctx, query, country)
+}
@@ -0,0 +1,56 @@
+package geocoder_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+)
+
+type countingGeocoder struct {
+	calls int
+}
+
+func (g *countingGeocoder) Geocode(_ context.Context, query, _ string) (geocoder.Result, error) {
+	g.calls++
+
+	return geocoder.Result{Name: query, Lat: 52.52, Lon: 13.40}, nil
+}
+
+type memoryCache map[geocoder.CacheKey]geocoder.Result
+
+func (c memoryCache) GetGeocode(_ context.Context, key geocoder.CacheKey) (geocoder.Result, bool, error) {
+	res, ok := c[key]
+
+	return res, ok, nil
+}
+
+func (c memoryCache) SaveGeocode(_ context.Context, key geocoder.CacheKey, res geocoder.Result) error {
+	c[key] = res
+
+	return nil
+}
+
+func Test_Cached(t *testing.T) {
+	t.Parallel()
+
+	g := &countingGeocoder{}
+	cache := memoryCache{}
+
+	cached := geocoder.NewCached(g, "test", cache)
+
+	_, err := cached.Geocode(context.Background(), "Berlin, Germany", "DE")
+	require.NoError(t, err)
+
+	res, err := cached.Geocode(context.Background(), "  berlin,  germany ", "de")
+	require.NoError(t, err)
+	require.Equal(t, "Berlin, Germany", res.Name)
+	require.Equal(t, 1, g.calls)
+
+	_, err = cached.Geocode(context.Background(), "Berlin, Germany", "")
+	require.NoError(t, err)
+	require.Equal(t, 2, g.calls)
+	require.Len(t, cache, 2)
+}
@@ -0,0 +1,81 @@
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNotFound is returned when the query does not match any place.
+var ErrNotFound = errors.New("place not found")
+
+// Result is the location of a geocoded place.
+type Result struct {
+	Lat  float64
+	Lon  float64
+	Name string
+	// BoundingBox is south, north, west, east. It is zero when the provider does not return one.
+	BoundingBox [4]float64
+}
+
+// Geocoder resolves a free text place name like "Berlin, Germany" to a location.
+type Geocoder interface {
+	// Geocode returns the best match of the query. country restricts the
+	// results to an ISO 3166-1 alpha-2 country code when it is not empty.
+	Geocode(ctx context.Context, query, country string) (Result, error)
+}
+
+// BBox returns the bounding box in the minlat,minlon,maxlat,maxlon format of
+// tiled crawls, or an empty string when the result has no bounding box.
+func (r *Result) BBox() string {
+	if r.BoundingBox == ([4]float64{}) {
+		return ""
+	}
+
+	return fmt.Sprintf("%f,%f,%f,%f", r.BoundingBox[0], r.BoundingBox[2], r.BoundingBox[1], r.BoundingBox[3])
+}
+
+// Zoom returns the map zoom level that fits the bounding box of the result.
+// fallback is returned when the result has no bounding box.
+func (r *Result) Zoom(fallback int) int {
+	if r.BoundingBox == ([4]float64{}) {
+		return fallback
+	}
+
+	latSpan := math.Abs(r.BoundingBox[1] - r.BoundingBox[0])
+	lonSpan := math.Abs(r.BoundingBox[3] - r.BoundingBox[2])
+
+	span := max(latSpan, lonSpan)
+	if span <= 0 {
+		return fallback
+	}
+
+	zoom := int(math.Floor(math.Log2(360/span))) + 1
+
+	return min(max(zoom, 1), 21)
+}
+
+// Radius returns the distance in meters from the center to the corner of the
+// bounding box, or fallback when the result has no bounding box.
+func (r *Result) Radius(fallback float64) float64 {
+	if r.BoundingBox == ([4]float64{}) {
+		return fallback
+	}
+
+	return haversine(r.Lat, r.Lon, r.BoundingBox[1], r.BoundingBox[3])
+}
+
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const R = 6371e3 // earth radius in meters
+
+	rlat1 := lat1 * math.Pi / 180
+	rlat2 := lat2 * math.Pi / 180
+	dlat := (lat2 - lat1) * math.Pi / 180
+	dlon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(rlat1)*math.Cos(rlat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+
+	return R * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
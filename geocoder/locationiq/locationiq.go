@@ -0,0 +1,133 @@
+package locationiq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+)
+
+const defaultBaseURL = "https://us1.locationiq.com"
+
+var _ geocoder.Geocoder = (*client)(nil)
+
+type client struct {
+	baseURL string
+	key     string
+	http    *http.Client
+}
+
+// New returns a geocoder backed by the LocationIQ search API.
+// key is a LocationIQ access token.
+func New(key string) geocoder.Geocoder {
+	return &client{
+		baseURL: defaultBaseURL,
+		key:     key,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// place is the Nominatim compatible result of LocationIQ.
+type place struct {
+	Lat         string   `json:"lat"`
+	Lon         string   `json:"lon"`
+	DisplayName string   `json:"display_name"`
+	BoundingBox []string `json:"boundingbox"`
+}
+
+func (c *client) Geocode(ctx context.Context, query, country string) (geocoder.Result, error) {
+	params := url.Values{}
+	params.Set("key", c.key)
+	params.Set("q", query)
+	params.Set("format", "json")
+	params.Set("limit", "1")
+
+	if country != "" {
+		params.Set("countrycodes", strings.ToLower(country))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/search?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return geocoder.Result{}, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// the error holds the url, which holds the key
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+
+		return geocoder.Result{}, fmt.Errorf("locationiq: request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return geocoder.Result{}, geocoder.ErrNotFound
+	case http.StatusTooManyRequests:
+		return geocoder.Result{}, fmt.Errorf("locationiq: %w", geocoder.ErrRateLimited)
+	default:
+		return geocoder.Result{}, fmt.Errorf("locationiq: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var places []place
+
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return geocoder.Result{}, fmt.Errorf("locationiq: %w", err)
+	}
+
+	if len(places) == 0 {
+		return geocoder.Result{}, geocoder.ErrNotFound
+	}
+
+	return toResult(&places[0])
+}
+
+func toResult(p *place) (geocoder.Result, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return geocoder.Result{}, fmt.Errorf("locationiq: invalid latitude: %w", err)
+	}
+
+	lon, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return geocoder.Result{}, fmt.Errorf("locationiq: invalid longitude: %w", err)
+	}
+
+	ans := geocoder.Result{
+		Lat:  lat,
+		Lon:  lon,
+		Name: p.DisplayName,
+	}
+
+	if len(p.BoundingBox) != 4 {
+		return ans, nil
+	}
+
+	var bbox [4]float64
+
+	for i, v := range p.BoundingBox {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			// the bounding box is optional, the zoom falls back to the default
+			return ans, nil
+		}
+
+		bbox[i] = f
+	}
+
+	ans.BoundingBox = bbox
+
+	return ans, nil
+}
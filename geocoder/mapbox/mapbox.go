@@ -0,0 +1,108 @@
+package mapbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+)
+
+const defaultBaseURL = "https://api.mapbox.com"
+
+var _ geocoder.Geocoder = (*client)(nil)
+
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// New returns a geocoder backed by the Mapbox geocoding API.
+// token is a Mapbox access token.
+func New(token string) geocoder.Geocoder {
+	return &client{
+		baseURL: defaultBaseURL,
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type response struct {
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	PlaceName string `json:"place_name"`
+	// Center is lon, lat.
+	Center []float64 `json:"center"`
+	// BBox is minlon, minlat, maxlon, maxlat.
+	BBox []float64 `json:"bbox"`
+}
+
+func (c *client) Geocode(ctx context.Context, query, country string) (geocoder.Result, error) {
+	params := url.Values{}
+	params.Set("access_token", c.token)
+	params.Set("limit", "1")
+
+	if country != "" {
+		params.Set("country", strings.ToLower(country))
+	}
+
+	u := c.baseURL + "/geocoding/v5/mapbox.places/" + url.PathEscape(query) + ".json?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return geocoder.Result{}, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// the error holds the url, which holds the token
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+
+		return geocoder.Result{}, fmt.Errorf("mapbox: request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return geocoder.Result{}, fmt.Errorf("mapbox: %w", geocoder.ErrRateLimited)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return geocoder.Result{}, fmt.Errorf("mapbox: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var body response
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return geocoder.Result{}, fmt.Errorf("mapbox: %w", err)
+	}
+
+	if len(body.Features) == 0 || len(body.Features[0].Center) != 2 {
+		return geocoder.Result{}, geocoder.ErrNotFound
+	}
+
+	f := &body.Features[0]
+
+	ans := geocoder.Result{
+		Lat:  f.Center[1],
+		Lon:  f.Center[0],
+		Name: f.PlaceName,
+	}
+
+	if len(f.BBox) == 4 {
+		ans.BoundingBox = [4]float64{f.BBox[1], f.BBox[3], f.BBox[0], f.BBox[2]}
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,143 @@
+package nominatim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+)
+
+const (
+	defaultBaseURL   = "https://nominatim.openstreetmap.org"
+	defaultUserAgent = "google-maps-scraper (https://github.com/gosom/google-maps-scraper)"
+	// publicInterval is the minimum time between requests of the usage policy of the public instance
+	publicInterval = time.Second
+)
+
+var _ geocoder.Geocoder = (*client)(nil)
+
+type client struct {
+	baseURL   string
+	userAgent string
+	http      *http.Client
+}
+
+// New returns a geocoder backed by the OpenStreetMap Nominatim API.
+// baseURL can point to a self hosted instance, the public one is used when it is empty.
+// The requests to the public instance are sent at most once per second, as
+// its usage policy asks.
+func New(baseURL string) geocoder.Geocoder {
+	public := baseURL == ""
+	if public {
+		baseURL = defaultBaseURL
+	}
+
+	c := client{
+		baseURL:   baseURL,
+		userAgent: defaultUserAgent,
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if public {
+		return geocoder.NewRateLimited(&c, publicInterval)
+	}
+
+	return &c
+}
+
+type place struct {
+	Lat         string   `json:"lat"`
+	Lon         string   `json:"lon"`
+	DisplayName string   `json:"display_name"`
+	BoundingBox []string `json:"boundingbox"`
+}
+
+func (c *client) Geocode(ctx context.Context, query, country string) (geocoder.Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "jsonv2")
+	params.Set("limit", "1")
+
+	if country != "" {
+		params.Set("countrycodes", strings.ToLower(country))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/search?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return geocoder.Result{}, err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return geocoder.Result{}, fmt.Errorf("nominatim: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return geocoder.Result{}, fmt.Errorf("nominatim: %w", geocoder.ErrRateLimited)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return geocoder.Result{}, fmt.Errorf("nominatim: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var places []place
+
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return geocoder.Result{}, fmt.Errorf("nominatim: %w", err)
+	}
+
+	if len(places) == 0 {
+		return geocoder.Result{}, geocoder.ErrNotFound
+	}
+
+	return toResult(&places[0])
+}
+
+func toResult(p *place) (geocoder.Result, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return geocoder.Result{}, fmt.Errorf("nominatim: invalid latitude: %w", err)
+	}
+
+	lon, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return geocoder.Result{}, fmt.Errorf("nominatim: invalid longitude: %w", err)
+	}
+
+	ans := geocoder.Result{
+		Lat:  lat,
+		Lon:  lon,
+		Name: p.DisplayName,
+	}
+
+	if len(p.BoundingBox) != 4 {
+		return ans, nil
+	}
+
+	var bbox [4]float64
+
+	for i, v := range p.BoundingBox {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			// the bounding box is optional, the zoom falls back to the default
+			return ans, nil
+		}
+
+		bbox[i] = f
+	}
+
+	ans.BoundingBox = bbox
+
+	return ans, nil
+}
@@ -0,0 +1,144 @@
+package photon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+)
+
+const (
+	defaultBaseURL   = "https://photon.komoot.io"
+	defaultUserAgent = "google-maps-scraper (https://github.com/gosom/google-maps-scraper)"
+	// candidates is the number of results requested when they are filtered by country
+	candidates = 10
+)
+
+var _ geocoder.Geocoder = (*client)(nil)
+
+type client struct {
+	baseURL   string
+	userAgent string
+	http      *http.Client
+}
+
+// New returns a geocoder backed by the Photon API of komoot, which searches
+// OpenStreetMap data and needs no API key. baseURL can point to a self hosted
+// instance, the public one is used when it is empty.
+func New(baseURL string) geocoder.Geocoder {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	return &client{
+		baseURL:   baseURL,
+		userAgent: defaultUserAgent,
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type response struct {
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Geometry struct {
+		// Coordinates are lon, lat.
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Name        string `json:"name"`
+		City        string `json:"city"`
+		State       string `json:"state"`
+		Country     string `json:"country"`
+		CountryCode string `json:"countrycode"`
+		// Extent is minlon, maxlat, maxlon, minlat.
+		Extent []float64 `json:"extent"`
+	} `json:"properties"`
+}
+
+// Geocode searches the query. Photon cannot restrict the search to a country,
+// so the first result in the country is returned.
+func (c *client) Geocode(ctx context.Context, query, country string) (geocoder.Result, error) {
+	limit := 1
+	if country != "" {
+		limit = candidates
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("limit", fmt.Sprint(limit))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return geocoder.Result{}, err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return geocoder.Result{}, fmt.Errorf("photon: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return geocoder.Result{}, fmt.Errorf("photon: %w", geocoder.ErrRateLimited)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return geocoder.Result{}, fmt.Errorf("photon: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var body response
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return geocoder.Result{}, fmt.Errorf("photon: %w", err)
+	}
+
+	for i := range body.Features {
+		f := &body.Features[i]
+
+		if country != "" && !strings.EqualFold(f.Properties.CountryCode, country) {
+			continue
+		}
+
+		if len(f.Geometry.Coordinates) != 2 {
+			continue
+		}
+
+		return toResult(f), nil
+	}
+
+	return geocoder.Result{}, geocoder.ErrNotFound
+}
+
+func toResult(f *feature) geocoder.Result {
+	var parts []string
+
+	for _, p := range []string{f.Properties.Name, f.Properties.City, f.Properties.State, f.Properties.Country} {
+		if p != "" && (len(parts) == 0 || parts[len(parts)-1] != p) {
+			parts = append(parts, p)
+		}
+	}
+
+	ans := geocoder.Result{
+		Lat:  f.Geometry.Coordinates[1],
+		Lon:  f.Geometry.Coordinates[0],
+		Name: strings.Join(parts, ", "),
+	}
+
+	// points like addresses have no extent, the zoom falls back to the default
+	if e := f.Properties.Extent; len(e) == 4 {
+		ans.BoundingBox = [4]float64{e[3], e[1], e[0], e[2]}
+	}
+
+	return ans
+}
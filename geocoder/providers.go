@@ -0,0 +1,119 @@
+package geocoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The supported geocoding providers.
+const (
+	ProviderNominatim  = "nominatim"
+	ProviderPhoton     = "photon"
+	ProviderMapbox     = "mapbox"
+	ProviderLocationIQ = "locationiq"
+)
+
+// ErrRateLimited is returned when the provider refuses the request because
+// too many requests were sent.
+var ErrRateLimited = errors.New("rate limited")
+
+// keyEnv holds the environment variable of the API key of the providers that need one.
+var keyEnv = map[string]string{
+	ProviderNominatim:  "",
+	ProviderPhoton:     "",
+	ProviderMapbox:     "MAPBOX_ACCESS_TOKEN",
+	ProviderLocationIQ: "LOCATIONIQ_API_KEY",
+}
+
+// ParseProviders splits a comma separated list of providers.
+func ParseProviders(s string) []string {
+	var ans []string
+
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			ans = append(ans, p)
+		}
+	}
+
+	return ans
+}
+
+// Key returns the API key of the provider from keys, or from its environment
+// variable when keys does not have it.
+func Key(provider string, keys map[string]string) string {
+	if key := keys[provider]; key != "" {
+		return key
+	}
+
+	if env := keyEnv[provider]; env != "" {
+		return os.Getenv(env)
+	}
+
+	return ""
+}
+
+// ValidateProviders checks that the providers are supported and that the ones
+// that need an API key have one.
+func ValidateProviders(providers []string, keys map[string]string) error {
+	for _, p := range providers {
+		env, ok := keyEnv[p]
+		if !ok {
+			return fmt.Errorf("invalid geocoder: %s", p)
+		}
+
+		if env != "" && Key(p, keys) == "" {
+			return fmt.Errorf("geocoder %s needs an API key, set %s", p, env)
+		}
+	}
+
+	for p := range keys {
+		if _, ok := keyEnv[p]; !ok {
+			return fmt.Errorf("invalid geocoder key: %s", p)
+		}
+	}
+
+	return nil
+}
+
+var _ Geocoder = (fallback)(nil)
+
+type fallback []Geocoder
+
+// NewFallback returns a geocoder that asks the geocoders in order until one
+// finds the place, so a provider that fails or is rate limited is replaced
+// by the next one.
+func NewFallback(gs ...Geocoder) Geocoder {
+	if len(gs) == 1 {
+		return gs[0]
+	}
+
+	return fallback(gs)
+}
+
+func (f fallback) Geocode(ctx context.Context, query, country string) (Result, error) {
+	var errs []error
+
+	for _, g := range f {
+		res, err := g.Geocode(ctx, query, country)
+		if err == nil {
+			return res, nil
+		}
+
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+
+		errs = append(errs, err)
+	}
+
+	for _, err := range errs {
+		if !errors.Is(err, ErrNotFound) {
+			return Result{}, errors.Join(errs...)
+		}
+	}
+
+	return Result{}, ErrNotFound
+}
@@ -0,0 +1,48 @@
+package geocoder_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+)
+
+type failingGeocoder struct {
+	err error
+}
+
+func (g failingGeocoder) Geocode(context.Context, string, string) (geocoder.Result, error) {
+	return geocoder.Result{}, g.err
+}
+
+func Test_Fallback(t *testing.T) {
+	t.Parallel()
+
+	limited := failingGeocoder{err: fmt.Errorf("test: %w", geocoder.ErrRateLimited)}
+	notFound := failingGeocoder{err: geocoder.ErrNotFound}
+
+	g := geocoder.NewFallback(limited, notFound, &countingGeocoder{})
+
+	res, err := g.Geocode(context.Background(), "Berlin", "")
+	require.NoError(t, err)
+	require.Equal(t, "Berlin", res.Name)
+
+	_, err = geocoder.NewFallback(notFound, notFound).Geocode(context.Background(), "Berlin", "")
+	require.ErrorIs(t, err, geocoder.ErrNotFound)
+
+	_, err = geocoder.NewFallback(notFound, limited).Geocode(context.Background(), "Berlin", "")
+	require.ErrorIs(t, err, geocoder.ErrRateLimited)
+}
+
+func Test_ValidateProviders(t *testing.T) {
+	t.Setenv("MAPBOX_ACCESS_TOKEN", "")
+
+	require.NoError(t, geocoder.ValidateProviders([]string{"photon", "nominatim"}, nil))
+	require.NoError(t, geocoder.ValidateProviders([]string{"mapbox"}, map[string]string{"mapbox": "token"}))
+	require.Error(t, geocoder.ValidateProviders([]string{"mapbox"}, nil))
+	require.Error(t, geocoder.ValidateProviders([]string{"google"}, nil))
+	require.Error(t, geocoder.ValidateProviders(nil, map[string]string{"google": "key"}))
+}
@@ -0,0 +1,65 @@
+package gmaps
+
+import (
+	"strings"
+	"unicode"
+)
+
+// AboutFlags flattens the about options of the place into boolean values
+// keyed by about.<section>.<option> in snake_case, e.g.
+// about.service_options.delivery. An option listed more than once is
+// enabled when any of its occurrences is.
+//
+// The option names are in the language of the search, so the keys are only
+// stable across runs that use the same language.
+func (e *Entry) AboutFlags() map[string]bool {
+	if len(e.About) == 0 {
+		return nil
+	}
+
+	flags := make(map[string]bool)
+
+	for _, about := range e.About {
+		section := snakeCase(about.ID)
+		if section == "" {
+			section = snakeCase(about.Name)
+		}
+
+		for _, opt := range about.Options {
+			name := snakeCase(opt.Name)
+			if section == "" || name == "" {
+				continue
+			}
+
+			key := "about." + section + "." + name
+			flags[key] = flags[key] || opt.Enabled
+		}
+	}
+
+	return flags
+}
+
+// snakeCase lowercases s and joins its words with underscores.
+func snakeCase(s string) string {
+	var sb strings.Builder
+
+	sep := false
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			sep = sb.Len() > 0
+
+			continue
+		}
+
+		if sep {
+			sb.WriteByte('_')
+
+			sep = false
+		}
+
+		sb.WriteRune(unicode.ToLower(r))
+	}
+
+	return sb.String()
+}
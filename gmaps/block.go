@@ -0,0 +1,245 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/gosom/google-maps-scraper/captcha"
+)
+
+// ErrBlocked is the error of the pages where Google served a captcha
+// instead of the results. The job is retried.
+var ErrBlocked = errors.New("blocked by google")
+
+// blockSignals are the markers of the Google captcha and "unusual traffic" pages
+var blockSignals = []string{
+	"captcha-form",
+	"g-recaptcha",
+	"our systems have detected unusual traffic",
+}
+
+// IsBlocked reports whether Google redirected to its captcha page.
+func IsBlocked(u string) bool {
+	return strings.Contains(u, "/sorry/")
+}
+
+// containsBlockSignals reports whether the body is a captcha page.
+func containsBlockSignals(body string) bool {
+	body = strings.ToLower(body)
+
+	for _, signal := range blockSignals {
+		if strings.Contains(body, signal) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isBlockedPage reports whether the page is a captcha page, by its url or,
+// when the search or place feed is missing, by its content.
+func isBlockedPage(page playwright.Page) bool {
+	if IsBlocked(page.URL()) {
+		return true
+	}
+
+	title, err := page.Title()
+	if err == nil && strings.Contains(strings.ToLower(title), "google maps") {
+		return false
+	}
+
+	body, err := page.Content()
+
+	return err == nil && containsBlockSignals(body)
+}
+
+// BlockGuard reacts to the captchas served to the jobs. With proxies, the
+// browser that was blocked is closed so that scrapemate starts a new one on
+// the next proxy of the pool. Without, the only IP is blocked and all the
+// jobs pause for the cooldown. With a captcha solver the captchas are solved
+// first, and the guard only acts on the ones the solver failed.
+type BlockGuard struct {
+	cooldown time.Duration
+	rotate   bool
+	onBlock  func()
+
+	solver   captcha.Solver
+	onSolved func()
+
+	blocks atomic.Int64
+	solved atomic.Int64
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// BlockGuardOption configures a BlockGuard.
+type BlockGuardOption func(*BlockGuard)
+
+// WithCaptchaSolver solves the captchas with s. onSolved, when not nil, is
+// called for each solved captcha.
+func WithCaptchaSolver(s captcha.Solver, onSolved func()) BlockGuardOption {
+	return func(g *BlockGuard) {
+		g.solver = s
+		g.onSolved = onSolved
+	}
+}
+
+// NewBlockGuard returns a guard that rotates the proxies when rotate is true
+// and otherwise pauses the jobs for cooldown after a captcha. onBlock, when
+// not nil, is called for each captcha.
+func NewBlockGuard(cooldown time.Duration, rotate bool, onBlock func(), opts ...BlockGuardOption) *BlockGuard {
+	g := BlockGuard{
+		cooldown: cooldown,
+		rotate:   rotate,
+		onBlock:  onBlock,
+	}
+
+	for _, opt := range opts {
+		opt(&g)
+	}
+
+	return &g
+}
+
+// Wait blocks until the cooldown is over or ctx is done.
+func (g *BlockGuard) Wait(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	wait := time.Until(g.until)
+	g.mu.Unlock()
+
+	return pause(ctx, wait)
+}
+
+// Blocked records a captcha served to the page. It returns true when the
+// captcha was solved and the page is back on the url it loaded, otherwise it
+// rotates the proxy or starts the cooldown.
+func (g *BlockGuard) Blocked(ctx context.Context, page playwright.Page) bool {
+	if g == nil {
+		return false
+	}
+
+	g.blocks.Add(1)
+
+	if g.onBlock != nil {
+		g.onBlock()
+	}
+
+	if g.solver != nil {
+		err := solveCaptcha(ctx, page, g.solver)
+		if err == nil {
+			g.solved.Add(1)
+
+			if g.onSolved != nil {
+				g.onSolved()
+			}
+
+			return true
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+
+	if g.rotate {
+		if browser := page.Context().Browser(); browser != nil {
+			_ = browser.Close()
+		}
+
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if until := time.Now().Add(g.cooldown); until.After(g.until) {
+		g.until = until
+	}
+
+	return false
+}
+
+// Blocks returns the number of captchas served so far.
+func (g *BlockGuard) Blocks() int64 {
+	if g == nil {
+		return 0
+	}
+
+	return g.blocks.Load()
+}
+
+// Solved returns the number of captchas solved so far.
+func (g *BlockGuard) Solved() int64 {
+	if g == nil {
+		return 0
+	}
+
+	return g.solved.Load()
+}
+
+// solveCaptcha solves the reCAPTCHA of the Google sorry page and submits it.
+// Google then redirects to the page that was blocked.
+func solveCaptcha(ctx context.Context, page playwright.Page, solver captcha.Solver) error {
+	const (
+		attributeTimeout = 2000
+		redirectTimeout  = 30 * time.Second
+	)
+
+	widget := page.Locator("[data-sitekey]").First()
+
+	siteKey, err := widget.GetAttribute("data-sitekey", playwright.LocatorGetAttributeOptions{
+		Timeout: playwright.Float(attributeTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("captcha site key not found: %w", err)
+	}
+
+	dataS, _ := widget.GetAttribute("data-s", playwright.LocatorGetAttributeOptions{
+		Timeout: playwright.Float(attributeTimeout),
+	})
+
+	token, err := solver.Solve(ctx, captcha.Task{
+		PageURL: page.URL(),
+		SiteKey: siteKey,
+		DataS:   dataS,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = page.Evaluate(`token => {
+		document.getElementById('g-recaptcha-response').value = token;
+		document.getElementById('captcha-form').submit();
+	}`, token)
+	if err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, redirectTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Millisecond * 150)
+	defer ticker.Stop()
+
+	for IsBlocked(page.URL()) {
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("captcha not accepted: %w", waitCtx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	return clickRejectCookiesIfRequired(page)
+}
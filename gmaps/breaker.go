@@ -0,0 +1,215 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+// ErrCircuitOpen is the error of the pages of a host whose circuit is open:
+// they are not loaded until the host recovers.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// The states of the circuit of a host.
+const (
+	// CircuitClosed lets the pages of the host be loaded
+	CircuitClosed = "closed"
+	// CircuitOpen fails the pages of the host at once
+	CircuitOpen = "open"
+	// CircuitHalfOpen lets one page of the host be loaded to probe it
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreakers keep a circuit per host: google, the websites of the
+// places and croxyproxy. After threshold pages of a host failed in a row its
+// circuit opens, and its pages fail at once with ErrCircuitOpen for the
+// cooldown instead of waiting for their timeouts. Then one page at a time
+// probes the host: the circuit closes when it loads and opens again when it
+// fails. The captchas and the canceled pages do not count, the BlockGuard
+// handles the former. It is safe to share between jobs.
+type CircuitBreakers struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(host, state string)
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+type circuit struct {
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreakers opens the circuit of a host after threshold failures in
+// a row, for cooldown. onChange, when not nil, is called with the host and
+// its new state each time a circuit changes. It returns nil, which lets
+// everything through, when threshold is zero.
+func NewCircuitBreakers(threshold int, cooldown time.Duration, onChange func(host, state string)) *CircuitBreakers {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &CircuitBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		onChange:  onChange,
+		hosts:     make(map[string]*circuit),
+	}
+}
+
+// Allow returns ErrCircuitOpen when the circuit of the host of u is open, or
+// half open with a probe in flight. Otherwise the page can be loaded, and
+// Done must be called with how it went.
+func (b *CircuitBreakers) Allow(u string) error {
+	if b == nil {
+		return nil
+	}
+
+	host := limiterHost(u)
+	if host == "" {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.hosts[host]
+	if !ok {
+		return nil
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+
+		b.set(host, c, CircuitHalfOpen)
+	case CircuitHalfOpen:
+		if c.probing {
+			return ErrCircuitOpen
+		}
+	default:
+		return nil
+	}
+
+	c.probing = true
+
+	return nil
+}
+
+// Done records how the page of u let through by Allow went, err being nil
+// when it loaded.
+func (b *CircuitBreakers) Done(u string, err error) {
+	if b == nil || errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+
+	host := limiterHost(u)
+	if host == "" {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.hosts[host]
+
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, ErrBlocked):
+		// the host did not answer for itself, the next page probes it again
+		if ok {
+			c.probing = false
+		}
+	case err == nil:
+		if !ok {
+			return
+		}
+
+		// the closed circuits are forgotten, there is one per website
+		delete(b.hosts, host)
+
+		if c.state != CircuitClosed {
+			b.notify(host, CircuitClosed)
+		}
+	default:
+		if !ok {
+			if len(b.hosts) >= pruneSize {
+				b.prune()
+			}
+
+			c = &circuit{state: CircuitClosed}
+			b.hosts[host] = c
+		}
+
+		c.failures++
+		c.probing = false
+
+		if c.state == CircuitHalfOpen || c.failures >= b.threshold {
+			c.openedAt = time.Now()
+
+			if c.state != CircuitOpen {
+				b.set(host, c, CircuitOpen)
+			}
+		}
+	}
+}
+
+// State returns the state of the circuit of host, e.g. google.com.
+func (b *CircuitBreakers) State(host string) string {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.hosts[host]; ok {
+		return c.state
+	}
+
+	return CircuitClosed
+}
+
+// guard loads the page of u with actions when its circuit allows it and
+// records how it went.
+func (b *CircuitBreakers) guard(u string, actions func() scrapemate.Response) scrapemate.Response {
+	if err := b.Allow(u); err != nil {
+		return scrapemate.Response{Error: err}
+	}
+
+	resp := actions()
+
+	b.Done(u, resp.Error)
+
+	return resp
+}
+
+// prune forgets the closed circuits, whose hosts failed less than threshold
+// times in a row. b.mu must be held.
+func (b *CircuitBreakers) prune() {
+	for host, c := range b.hosts {
+		if c.state == CircuitClosed {
+			delete(b.hosts, host)
+		}
+	}
+}
+
+// set changes the state of the circuit c of host. b.mu must be held.
+func (b *CircuitBreakers) set(host string, c *circuit, state string) {
+	c.state = state
+
+	b.notify(host, state)
+}
+
+func (b *CircuitBreakers) notify(host, state string) {
+	if b.onChange != nil {
+		b.onChange(host, state)
+	}
+}
@@ -0,0 +1,67 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	var changes []string
+
+	b := NewCircuitBreakers(2, time.Hour, func(host, state string) {
+		changes = append(changes, host+" "+state)
+	})
+
+	const u = "https://www.example.com/contact"
+
+	fail := errors.New("timeout")
+
+	require.NoError(t, b.Allow(u))
+	b.Done(u, fail)
+	require.Equal(t, CircuitClosed, b.State("example.com"))
+
+	// the captchas and the canceled pages do not count
+	b.Done(u, ErrBlocked)
+	b.Done(u, context.Canceled)
+	require.Equal(t, CircuitClosed, b.State("example.com"))
+
+	b.Done(u, fail)
+	require.Equal(t, CircuitOpen, b.State("example.com"))
+	require.ErrorIs(t, b.Allow(u), ErrCircuitOpen)
+	require.NoError(t, b.Allow("https://other.org"))
+
+	// the cooldown is over, one page probes the host
+	b.hosts["example.com"].openedAt = time.Now().Add(-2 * time.Hour)
+
+	require.NoError(t, b.Allow(u))
+	require.Equal(t, CircuitHalfOpen, b.State("example.com"))
+	require.ErrorIs(t, b.Allow(u), ErrCircuitOpen)
+
+	b.Done(u, fail)
+	require.Equal(t, CircuitOpen, b.State("example.com"))
+
+	b.hosts["example.com"].openedAt = time.Now().Add(-2 * time.Hour)
+
+	require.NoError(t, b.Allow(u))
+	b.Done(u, nil)
+	require.Equal(t, CircuitClosed, b.State("example.com"))
+
+	require.Equal(t, []string{
+		"example.com open",
+		"example.com half_open",
+		"example.com open",
+		"example.com half_open",
+		"example.com closed",
+	}, changes)
+
+	var disabled *CircuitBreakers
+
+	require.Nil(t, NewCircuitBreakers(0, time.Minute, nil))
+	require.NoError(t, disabled.Allow(u))
+	disabled.Done(u, fail)
+	require.Equal(t, CircuitClosed, disabled.State("example.com"))
+}
@@ -0,0 +1,271 @@
+package gmaps
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/gosom/google-maps-scraper/health"
+)
+
+// openContexts are the browser contexts the jobs ran in that are not closed yet.
+var openContexts sync.Map
+
+// trackPage records the browser context of the page until it is closed.
+func trackPage(page playwright.Page) {
+	bctx := page.Context()
+	if bctx == nil {
+		return
+	}
+
+	if _, loaded := openContexts.LoadOrStore(bctx, struct{}{}); !loaded {
+		bctx.OnClose(func(c playwright.BrowserContext) {
+			openContexts.Delete(c)
+		})
+	}
+}
+
+// BrowserStats returns the number of open browser contexts the jobs ran in
+// and the number of pages open in them.
+func BrowserStats() (contexts, pages int) {
+	openContexts.Range(func(key, _ any) bool {
+		contexts++
+
+		if bctx, ok := key.(playwright.BrowserContext); ok {
+			pages += len(bctx.Pages())
+		}
+
+		return true
+	})
+
+	return contexts, pages
+}
+
+// browserProbeTimeout is the time a browser gets to open a page and run the
+// script of a BrowserCheck.
+const browserProbeTimeout = 30 * time.Second
+
+// BrowserCheck checks that the browsers the jobs run in can still open a page
+// and run javascript. A browser that crashed or hangs makes every job it gets
+// time out; after restartAfter checks in a row where browsers failed, they are
+// closed and the jobs launch new ones. It is a health.Check.
+type BrowserCheck struct {
+	restartAfter int
+	onRestart    func(restarted int, err error)
+
+	// browsers returns the browsers to check, probe checks one of them
+	browsers func() []playwright.Browser
+	probe    func(playwright.Browser) error
+
+	mu       sync.Mutex
+	failures int
+	last     health.Result
+}
+
+// NewBrowserCheck restarts the browsers after restartAfter failed checks in
+// a row, at least one, and calls onRestart, if not nil, with the number of
+// browsers restarted and the error of the last check.
+func NewBrowserCheck(restartAfter int, onRestart func(restarted int, err error)) *BrowserCheck {
+	return &BrowserCheck{
+		restartAfter: max(1, restartAfter),
+		onRestart:    onRestart,
+		browsers:     openBrowsers,
+		probe:        probeBrowser,
+		last:         health.Result{Status: health.StatusOK, Message: "not checked yet"},
+	}
+}
+
+// Name returns browser.
+func (c *BrowserCheck) Name() string {
+	return "browser"
+}
+
+// Check returns the result of the last check of Watch: degraded while
+// browsers fail, down from their restart until they answer again.
+func (c *BrowserCheck) Check(context.Context) health.Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.last
+}
+
+// Watch checks the browsers every interval until ctx is done.
+func (c *BrowserCheck) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.run(ctx)
+		}
+	}
+}
+
+// run checks the open browsers at the same time, and restarts the ones that
+// failed once they failed restartAfter times in a row.
+func (c *BrowserCheck) run(ctx context.Context) {
+	browsers := c.browsers()
+
+	errs := make([]error, len(browsers))
+
+	var wg sync.WaitGroup
+
+	for i, b := range browsers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = c.probeWithTimeout(ctx, b)
+		}()
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	var (
+		failed  []playwright.Browser
+		lastErr error
+	)
+
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, browsers[i])
+			lastErr = err
+		}
+	}
+
+	c.mu.Lock()
+
+	if len(failed) == 0 {
+		c.failures = 0
+		c.last = health.Result{Status: health.StatusOK, Message: fmt.Sprintf("%d browsers answered", len(browsers))}
+
+		if len(browsers) == 0 {
+			c.last.Message = "no browser open"
+		}
+		c.mu.Unlock()
+
+		return
+	}
+
+	c.failures++
+
+	restart := c.failures >= c.restartAfter
+
+	if restart {
+		c.failures = 0
+		c.last = health.Result{
+			Status:  health.StatusDown,
+			Message: fmt.Sprintf("restarted %d of %d browsers: %v", len(failed), len(browsers), lastErr),
+		}
+	} else if c.last.Status != health.StatusDown {
+		c.last = health.Result{
+			Status:  health.StatusDegraded,
+			Message: fmt.Sprintf("%d of %d browsers failed: %v", len(failed), len(browsers), lastErr),
+		}
+	}
+
+	c.mu.Unlock()
+
+	if !restart {
+		return
+	}
+
+	// a hung browser may not answer its close either, the jobs that wait for
+	// it fail and the pool launches a new one
+	for _, b := range failed {
+		go func() {
+			_ = b.Close()
+		}()
+	}
+
+	if c.onRestart != nil {
+		c.onRestart(len(failed), lastErr)
+	}
+}
+
+// probeWithTimeout returns the error of the probe of b, or an error when it
+// does not answer within browserProbeTimeout.
+func (c *BrowserCheck) probeWithTimeout(ctx context.Context, b playwright.Browser) error {
+	ctx, cancel := context.WithTimeout(ctx, browserProbeTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.probe(b)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("the browser did not answer: %w", ctx.Err())
+	}
+}
+
+// openBrowsers returns the connected browsers of the open contexts the jobs
+// ran in. The disconnected ones are replaced by the pool.
+func openBrowsers() []playwright.Browser {
+	var ans []playwright.Browser
+
+	openContexts.Range(func(key, _ any) bool {
+		bctx, ok := key.(playwright.BrowserContext)
+		if !ok {
+			return true
+		}
+
+		if b := bctx.Browser(); b != nil && b.IsConnected() && !slices.Contains(ans, b) {
+			ans = append(ans, b)
+		}
+
+		return true
+	})
+
+	return ans
+}
+
+// probeBrowser opens a page in a new context of b, so that the pages of the
+// jobs are left alone, and runs a script in it.
+func probeBrowser(b playwright.Browser) error {
+	bctx, err := b.NewContext()
+	if err != nil {
+		return fmt.Errorf("failed to open a browser context: %w", err)
+	}
+
+	defer bctx.Close()
+
+	page, err := bctx.NewPage()
+	if err != nil {
+		return fmt.Errorf("failed to open a page: %w", err)
+	}
+
+	v, err := page.Evaluate("() => 1 + 1")
+	if err != nil {
+		return fmt.Errorf("failed to run javascript: %w", err)
+	}
+
+	switch n := v.(type) {
+	case int:
+		if n == 2 {
+			return nil
+		}
+	case float64:
+		if n == 2 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unexpected javascript result %v", v)
+}
@@ -0,0 +1,76 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/health"
+)
+
+type fakeBrowser struct {
+	playwright.Browser
+	closed atomic.Bool
+}
+
+func (b *fakeBrowser) Close(...playwright.BrowserCloseOptions) error {
+	b.closed.Store(true)
+
+	return nil
+}
+
+func TestBrowserCheck(t *testing.T) {
+	ok, hung := &fakeBrowser{}, &fakeBrowser{}
+
+	var restarts []int
+
+	c := NewBrowserCheck(2, func(restarted int, err error) {
+		require.Error(t, err)
+
+		restarts = append(restarts, restarted)
+	})
+
+	failing := true
+
+	c.browsers = func() []playwright.Browser { return []playwright.Browser{ok, hung} }
+	c.probe = func(b playwright.Browser) error {
+		if b == hung && failing {
+			return errors.New("target closed")
+		}
+
+		return nil
+	}
+
+	require.Equal(t, health.StatusOK, c.Check(context.Background()).Status)
+
+	c.run(context.Background())
+	require.Equal(t, health.StatusDegraded, c.Check(context.Background()).Status)
+	require.Empty(t, restarts)
+
+	// the second failure in a row restarts the browser that failed
+	c.run(context.Background())
+	require.Equal(t, health.StatusDown, c.Check(context.Background()).Status)
+	require.Equal(t, []int{1}, restarts)
+	require.Eventually(t, hung.closed.Load, time.Second, 10*time.Millisecond)
+	require.False(t, ok.closed.Load())
+
+	// it is down until the browsers answer again
+	c.run(context.Background())
+	require.Equal(t, health.StatusDown, c.Check(context.Background()).Status)
+
+	failing = false
+
+	c.run(context.Background())
+	require.Equal(t, health.StatusOK, c.Check(context.Background()).Status)
+	require.Equal(t, []int{1}, restarts)
+
+	c.browsers = func() []playwright.Browser { return nil }
+
+	c.run(context.Background())
+	require.Equal(t, "no browser open", c.Check(context.Background()).Message)
+}
@@ -0,0 +1,85 @@
+package gmaps
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// contactFormPlugins are the selectors of the forms of common form plugins
+// and form services, which are contact forms even when their fields are
+// rendered later by a script.
+var contactFormPlugins = []string{
+	// Contact Form 7, WPForms, Gravity Forms, Ninja Forms and Elementor
+	"form.wpcf7-form",
+	"form.wpforms-form",
+	".gform_wrapper form",
+	".nf-form-cont",
+	"form.elementor-form",
+	// HubSpot, Jotform, Typeform, Google Forms and Formspree
+	"form.hs-form",
+	".hbspt-form",
+	"iframe[src*='jotform.com']",
+	"iframe[src*='typeform.com']",
+	"[data-tf-widget]",
+	"iframe[src*='docs.google.com/forms']",
+	"form[action*='formspree.io']",
+}
+
+// HasContactForm reports whether doc has a contact form: a form with an
+// email field and a message field, or the form of a common form plugin.
+// Newsletter, search, login and comment forms are not contact forms.
+func HasContactForm(doc *goquery.Document) bool {
+	for _, selector := range contactFormPlugins {
+		if doc.Find(selector).Length() > 0 {
+			return true
+		}
+	}
+
+	found := false
+
+	doc.Find("form").EachWithBreak(func(_ int, form *goquery.Selection) bool {
+		found = isContactForm(form)
+
+		return !found
+	})
+
+	return found
+}
+
+func isContactForm(form *goquery.Selection) bool {
+	if form.Find("input[type='password']").Length() > 0 {
+		return false
+	}
+
+	// the comment form of the blog posts
+	if strings.Contains(form.AttrOr("action", ""), "wp-comments-post") || form.Is("#commentform") {
+		return false
+	}
+
+	email := form.Find("input[type='email']").Length() > 0 ||
+		form.Find("input").FilterFunction(func(_ int, s *goquery.Selection) bool {
+			return fieldNameContains(s, "mail")
+		}).Length() > 0
+
+	message := form.Find("textarea").Length() > 0 ||
+		form.Find("input").FilterFunction(func(_ int, s *goquery.Selection) bool {
+			return fieldNameContains(s, "message", "nachricht", "messaggio", "mensaje", "mensagem")
+		}).Length() > 0
+
+	return email && message
+}
+
+// fieldNameContains reports whether the name or the id of the field s
+// contains one of words.
+func fieldNameContains(s *goquery.Selection, words ...string) bool {
+	name := strings.ToLower(s.AttrOr("name", "") + " " + s.AttrOr("id", ""))
+
+	for _, w := range words {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+
+	return false
+}
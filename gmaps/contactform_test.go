@@ -0,0 +1,53 @@
+package gmaps_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_HasContactForm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{
+			name: "email and message",
+			html: `<form action="/send"><input type="text" name="name"><input type="email" name="your-email"><textarea name="body"></textarea></form>`,
+			want: true,
+		},
+		{
+			name: "plugin rendered by a script",
+			html: `<div class="hbspt-form"></div>`,
+			want: true,
+		},
+		{
+			name: "newsletter",
+			html: `<form><input type="email" name="email"><button>Subscribe</button></form>`,
+		},
+		{
+			name: "login",
+			html: `<form><input name="email"><input type="password" name="password"><textarea></textarea></form>`,
+		},
+		{
+			name: "comments",
+			html: `<form id="commentform" action="/wp-comments-post.php"><input name="email"><textarea name="comment"></textarea></form>`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.html))
+			require.NoError(t, err)
+
+			require.Equal(t, tc.want, gmaps.HasContactForm(doc))
+		})
+	}
+}
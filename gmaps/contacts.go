@@ -0,0 +1,374 @@
+package gmaps
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSitemapSize is the size of a sitemap or robots.txt above which the rest is ignored
+	maxSitemapSize = 10 << 20
+	// maxChildSitemaps is the number of sitemaps of a sitemap index that are read
+	maxChildSitemaps = 5
+)
+
+// contactPatterns are the path segments of the contact pages, by priority.
+var contactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`contact|kontakt|contatt|contacto|contato`),
+	regexp.MustCompile(`impressum|imprint|mentions-legales|aviso-legal|legal-notice`),
+	regexp.MustCompile(`about|ueber-uns|uber-uns|chi-siamo|quienes-somos|qui-sommes-nous|a-propos|team`),
+}
+
+// ContactFinder finds the contact, imprint and about pages of a website in
+// its sitemap, for the websites whose home page has no email. The pages
+// disallowed by the robots.txt of the website are skipped unless the robots
+// are ignored. It is safe to share between jobs.
+type ContactFinder struct {
+	client       *http.Client
+	maxPages     int
+	ignoreRobots bool
+}
+
+// NewContactFinder returns a finder of up to maxPages pages per website. It
+// returns nil, which finds nothing, when maxPages is zero.
+func NewContactFinder(maxPages int, ignoreRobots bool) *ContactFinder {
+	if maxPages <= 0 {
+		return nil
+	}
+
+	return &ContactFinder{
+		client:       &http.Client{Timeout: 15 * time.Second},
+		maxPages:     maxPages,
+		ignoreRobots: ignoreRobots,
+	}
+}
+
+// Find returns the contact pages of the website of site, the most likely first.
+func (f *ContactFinder) Find(ctx context.Context, site string) []string {
+	if f == nil {
+		return nil
+	}
+
+	home, err := url.Parse(site)
+	if err != nil || home.Host == "" {
+		return nil
+	}
+
+	root := home.Scheme + "://" + home.Host
+
+	var robots *RobotsRules
+
+	if body, err := f.get(ctx, root+"/robots.txt"); err == nil {
+		robots = ParseRobots(body)
+	}
+
+	sitemaps := robots.Sitemaps()
+	if len(sitemaps) == 0 {
+		sitemaps = []string{root + "/sitemap.xml"}
+	}
+
+	var locs []string
+
+	for i := 0; i < len(sitemaps) && i < maxChildSitemaps; i++ {
+		body, err := f.get(ctx, sitemaps[i])
+		if err != nil {
+			continue
+		}
+
+		urls, children := ParseSitemap(body)
+
+		locs = append(locs, urls...)
+
+		// the sitemaps of an index are read after the ones of robots.txt
+		for _, child := range children {
+			if !slices.Contains(sitemaps, child) {
+				sitemaps = append(sitemaps, child)
+			}
+		}
+	}
+
+	if !f.ignoreRobots {
+		locs = slices.DeleteFunc(locs, func(u string) bool {
+			return !robots.Allowed(u)
+		})
+	}
+
+	return ContactPages(home, locs, f.maxPages)
+}
+
+// Fetch returns the body of the page u.
+func (f *ContactFinder) Fetch(ctx context.Context, u string) ([]byte, error) {
+	return f.get(ctx, u)
+}
+
+func (f *ContactFinder) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if err := CheckRateLimit(u, resp.StatusCode, resp.Header); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %d", u, resp.StatusCode)
+	}
+
+	var body io.Reader = io.LimitReader(resp.Body, maxSitemapSize)
+
+	if strings.HasSuffix(strings.ToLower(req.URL.Path), ".gz") {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+
+		defer zr.Close()
+
+		body = io.LimitReader(zr, maxSitemapSize)
+	}
+
+	return io.ReadAll(body)
+}
+
+// ContactPages returns up to limit urls of locs on the host of home that
+// look like contact pages, the contact pages first, then the imprints and
+// the about pages.
+func ContactPages(home *url.URL, locs []string, limit int) []string {
+	host := strings.TrimPrefix(strings.ToLower(home.Hostname()), "www.")
+
+	ranked := make([][]string, len(contactPatterns))
+	seen := map[string]bool{home.String(): true}
+
+	for _, loc := range locs {
+		u, err := url.Parse(strings.TrimSpace(loc))
+		if err != nil || strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.") != host {
+			continue
+		}
+
+		u.Fragment = ""
+		if seen[u.String()] {
+			continue
+		}
+
+		seen[u.String()] = true
+
+		path := strings.ToLower(u.Path)
+
+		for i, re := range contactPatterns {
+			if re.MatchString(path) {
+				ranked[i] = append(ranked[i], u.String())
+
+				break
+			}
+		}
+	}
+
+	var ans []string
+
+	for _, pages := range ranked {
+		// the shortest path is the page of the section, e.g. /contact before /contact/form
+		slices.SortStableFunc(pages, func(a, b string) int {
+			return len(a) - len(b)
+		})
+
+		ans = append(ans, pages...)
+	}
+
+	if len(ans) > limit {
+		ans = ans[:limit]
+	}
+
+	return ans
+}
+
+// ParseSitemap returns the pages and the child sitemaps of a sitemap or a
+// sitemap index.
+func ParseSitemap(body []byte) (pages, sitemaps []string) {
+	var doc struct {
+		XMLName xml.Name
+		URLs    []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, nil
+	}
+
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			pages = append(pages, loc)
+		}
+	}
+
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+
+	return pages, sitemaps
+}
+
+// RobotsRules are the rules of a robots.txt for all the user agents.
+type RobotsRules struct {
+	rules    []robotsRule
+	sitemaps []string
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// ParseRobots parses the rules of the * user agent and the sitemaps of a robots.txt.
+func ParseRobots(body []byte) *RobotsRules {
+	ans := &RobotsRules{}
+
+	// a group is the user agents that follow each other and their rules
+	var (
+		inGroup  bool
+		matches  bool
+		hasRules bool
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(string(body)))
+
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inGroup || hasRules {
+				matches = false
+				hasRules = false
+			}
+
+			inGroup = true
+
+			if value == "*" {
+				matches = true
+			}
+		case "allow", "disallow":
+			hasRules = true
+
+			// an empty disallow allows everything
+			if matches && value != "" {
+				ans.rules = append(ans.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "sitemap":
+			if value != "" {
+				ans.sitemaps = append(ans.sitemaps, value)
+			}
+		}
+	}
+
+	return ans
+}
+
+// Sitemaps returns the sitemaps listed in the robots.txt.
+func (r *RobotsRules) Sitemaps() []string {
+	if r == nil {
+		return nil
+	}
+
+	return r.sitemaps
+}
+
+// Allowed reports whether the page u may be crawled. The longest matching
+// rule wins, allow on a tie.
+func (r *RobotsRules) Allowed(u string) bool {
+	if r == nil {
+		return true
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+
+	allowed, length := true, -1
+
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+
+		if l := len(rule.pattern); l > length || (l == length && rule.allow) {
+			allowed, length = rule.allow, l
+		}
+	}
+
+	return allowed
+}
+
+// robotsMatch matches path against a pattern with the * wildcard and the $
+// end anchor.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+
+	rest := path[len(parts[0]):]
+
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+
+		rest = rest[i+len(part):]
+	}
+
+	if anchored {
+		// the last part must end the path, which the greedy search above may have missed
+		last := parts[len(parts)-1]
+
+		return rest == "" || (len(parts) > 1 && strings.HasSuffix(path, last))
+	}
+
+	return true
+}
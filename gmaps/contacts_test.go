@@ -0,0 +1,93 @@
+package gmaps_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_ParseRobots(t *testing.T) {
+	t.Parallel()
+
+	robots := gmaps.ParseRobots([]byte(`# robots
+User-agent: Googlebot
+Disallow: /
+
+User-agent: bingbot
+User-agent: *
+Disallow: /private/
+Disallow: /*.pdf$
+Allow: /private/contact
+Disallow:
+
+Sitemap: https://example.com/sitemap_index.xml
+`))
+
+	require.Equal(t, []string{"https://example.com/sitemap_index.xml"}, robots.Sitemaps())
+
+	require.True(t, robots.Allowed("https://example.com/contact"))
+	require.False(t, robots.Allowed("https://example.com/private/team"))
+	require.True(t, robots.Allowed("https://example.com/private/contact"))
+	require.False(t, robots.Allowed("https://example.com/docs/imprint.pdf"))
+	require.True(t, robots.Allowed("https://example.com/docs/imprint.pdf.html"))
+
+	require.True(t, (*gmaps.RobotsRules)(nil).Allowed("https://example.com/private/"))
+}
+
+func Test_ContactPages(t *testing.T) {
+	t.Parallel()
+
+	home, err := url.Parse("https://www.example.com/")
+	require.NoError(t, err)
+
+	locs := []string{
+		"https://www.example.com/",
+		"https://example.com/about-us",
+		"https://example.com/products/coffee",
+		"https://www.example.com/contact/form",
+		"https://www.example.com/impressum",
+		"https://other.com/contact",
+		"https://www.example.com/contact",
+		"https://www.example.com/contact#map",
+	}
+
+	require.Equal(t, []string{
+		"https://www.example.com/contact",
+		"https://www.example.com/contact/form",
+		"https://www.example.com/impressum",
+	}, gmaps.ContactPages(home, locs, 3))
+}
+
+func Test_ContactFinder(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, gmaps.NewContactFinder(0, false))
+
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /kontakt\nSitemap: %s/sitemap_index.xml\n", srv.URL)
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/pages.xml</loc></sitemap></sitemapindex>`, srv.URL)
+		case "/pages.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%[1]s/</loc></url><url><loc>%[1]s/kontakt</loc></url><url><loc>%[1]s/about</loc></url></urlset>`, srv.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	require.Equal(t, []string{srv.URL + "/about"}, gmaps.NewContactFinder(3, false).Find(ctx, srv.URL+"/"))
+	require.Equal(t, []string{srv.URL + "/kontakt", srv.URL + "/about"}, gmaps.NewContactFinder(3, true).Find(ctx, srv.URL+"/"))
+}
@@ -8,8 +8,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/tracing"
 	"github.com/gosom/scrapemate"
 	"github.com/playwright-community/playwright-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -23,14 +26,6 @@ const (
 )
 
 var (
-	userAgents = []string{
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Safari/537.36",
-	}
-	
 	// Simple in-memory cache with expiration
 	cache      = make(map[string]cacheEntry)
 	cacheMutex sync.RWMutex
@@ -41,17 +36,47 @@ type cacheEntry struct {
 	expiresAt time.Time
 }
 
+type CroxyProxyJobOptions func(*CroxyProxyJob)
+
 type CroxyProxyJob struct {
 	scrapemate.Job
 	TargetURL string
+
+	trace       string
+	exitMonitor exiter.Exiter
+	breakers    *CircuitBreakers
+	fallback    *fallback
 }
 
-func NewCroxyProxyJob(id, targetURL string) *CroxyProxyJob {
+// WithCroxyExitMonitor counts the job as a seed completed and a croxy use.
+func WithCroxyExitMonitor(exitMonitor exiter.Exiter) CroxyProxyJobOptions {
+	return func(j *CroxyProxyJob) {
+		j.exitMonitor = exitMonitor
+	}
+}
+
+// WithCroxyCircuitBreakers fails the job at once while the circuit of
+// croxyproxy is open in b.
+func WithCroxyCircuitBreakers(b *CircuitBreakers) CroxyProxyJobOptions {
+	return func(j *CroxyProxyJob) {
+		j.breakers = b
+	}
+}
+
+// WithCroxyFallback loads the search with next, the next step of its
+// FallbackChain, when it fails on one of the conditions on.
+func WithCroxyFallback(next scrapemate.IJob, on []string) CroxyProxyJobOptions {
+	return func(j *CroxyProxyJob) {
+		j.fallback = newFallback(next, on)
+	}
+}
+
+func NewCroxyProxyJob(id, targetURL string, opts ...CroxyProxyJobOptions) *CroxyProxyJob {
 	if id == "" {
 		id = fmt.Sprintf("croxy-%d", time.Now().UnixNano())
 	}
 
-	return &CroxyProxyJob{
+	job := CroxyProxyJob{
 		Job: scrapemate.Job{
 			ID:         id,
 			Method:     http.MethodGet,
@@ -61,30 +86,95 @@ func NewCroxyProxyJob(id, targetURL string) *CroxyProxyJob {
 		},
 		TargetURL: targetURL,
 	}
+
+	for _, opt := range opts {
+		opt(&job)
+	}
+
+	return &job
 }
 
 func (j *CroxyProxyJob) UseInResults() bool {
 	return true
 }
 
+// Traceparent returns the traceparent of the span of the proxied page.
+func (j *CroxyProxyJob) Traceparent() string {
+	return j.trace
+}
+
 func (j *CroxyProxyJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+	_, span := tracing.Start(ctx, j.trace, "croxy.process")
+	defer span.End()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	if resp.Error != nil {
+		if next := j.fallback.job(FallbackCondition(resp.Error, 0)); next != nil {
+			log.Info("croxyproxy failed, falling back", "error", resp.Error)
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+
+		return nil, nil, resp.Error
+	}
+
+	if resp.Body == nil {
+		if next := j.fallback.job(FallbackEmpty); next != nil {
+			log.Info("croxyproxy returned no content, falling back")
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+	}
+
+	if j.exitMonitor != nil {
+		j.exitMonitor.IncrCroxyUses(1)
+		j.exitMonitor.IncrSeedCompleted(1)
+	}
+
 	// Return the HTML content as result
 	if resp.Body != nil {
 		return map[string]interface{}{
 			"url":     j.TargetURL,
 			"content": string(resp.Body),
 			"status":  "success",
+			"source":  StrategyCroxy,
 		}, nil, nil
 	}
 	
 	return map[string]interface{}{
 		"url":    j.TargetURL,
 		"status": "failed",
+		"source": StrategyCroxy,
 		"error":  "no content retrieved",
 	}, nil, nil
 }
 
+// ProcessOnFetchError makes a search with a fallback fall back when
+// croxyproxy fails.
+func (j *CroxyProxyJob) ProcessOnFetchError() bool {
+	return j.fallback != nil
+}
+
 func (j *CroxyProxyJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	trackPage(page)
+
+	ctx, span := tracing.Start(ctx, "", "croxy.site",
+		attribute.String("job.id", j.ID),
+		attribute.String("url", j.TargetURL),
+	)
+
+	resp := j.breakers.guard(j.GetURL(), func() scrapemate.Response {
+		return j.browserActions(ctx, page)
+	})
+
+	j.trace = tracing.Parent(ctx)
+	tracing.End(span, resp.Error)
+
+	return resp
+}
+
+func (j *CroxyProxyJob) browserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
 	log := scrapemate.GetLoggerFromContext(ctx)
 	
 	// Check cache first
@@ -0,0 +1,224 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The quoting of the fields of a csv layout.
+const (
+	// CSVQuoteMinimal quotes the fields that contain the delimiter, a quote
+	// or a line break
+	CSVQuoteMinimal = "minimal"
+	// CSVQuoteAll quotes all the fields
+	CSVQuoteAll = "all"
+)
+
+// The cells of the nested fields, e.g. complete_address, of a csv layout.
+const (
+	// CSVNestedJSON writes a nested field as json in one cell
+	CSVNestedJSON = "json"
+	// CSVNestedFlat writes each key of a nested object in its own column,
+	// named after the field and the keys, e.g. complete_address.city. The
+	// lists stay json
+	CSVNestedFlat = "flat"
+)
+
+// CSVLayout is the layout of the csv of the entries. The zero value is the
+// layout of CsvHeaders and CsvRow.
+type CSVLayout struct {
+	// Columns are the columns, in order, all the ones of CsvHeaders when
+	// empty. In the flat layout a column can be a key of a nested field,
+	// e.g. complete_address.city
+	Columns []string `json:"columns,omitempty"`
+	// Delimiter separates the fields: one character, or tab. A comma when
+	// empty
+	Delimiter string `json:"delimiter,omitempty"`
+	// Quote is minimal or all, minimal when empty
+	Quote string `json:"quote,omitempty"`
+	// Nested is json or flat, json when empty
+	Nested string `json:"nested,omitempty"`
+	// Extended adds the columns of ExtendedCsvHeaders to the default ones.
+	// They can be chosen in Columns without it
+	Extended bool `json:"extended,omitempty"`
+}
+
+// Validate checks the columns, delimiter, quoting and nested fields of l.
+func (l *CSVLayout) Validate() error {
+	var e Entry
+
+	headers := append(e.CsvHeaders(), e.ExtendedCsvHeaders()...)
+	seen := make(map[string]bool, len(l.Columns))
+
+	for _, col := range l.Columns {
+		field, key, nested := strings.Cut(col, ".")
+
+		if !slices.Contains(headers, field) || (nested && key == "") {
+			return fmt.Errorf("unknown csv column %q", col)
+		}
+
+		if nested && !l.Flat() {
+			return fmt.Errorf("the csv column %q needs the flat nested fields", col)
+		}
+
+		if seen[col] {
+			return fmt.Errorf("csv column %q given twice", col)
+		}
+
+		seen[col] = true
+	}
+
+	if l.Delimiter != "" && l.Delimiter != "tab" {
+		r, size := utf8.DecodeRuneInString(l.Delimiter)
+		if size != len(l.Delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+			return fmt.Errorf("invalid csv delimiter %q", l.Delimiter)
+		}
+	}
+
+	switch l.Quote {
+	case "", CSVQuoteMinimal, CSVQuoteAll:
+	default:
+		return fmt.Errorf("invalid csv quote %q", l.Quote)
+	}
+
+	switch l.Nested {
+	case "", CSVNestedJSON, CSVNestedFlat:
+	default:
+		return fmt.Errorf("invalid csv nested %q", l.Nested)
+	}
+
+	return nil
+}
+
+// IsDefault reports whether l is the layout of CsvHeaders and CsvRow.
+func (l *CSVLayout) IsDefault() bool {
+	return l == nil || (len(l.Columns) == 0 && l.Comma() == ',' && !l.QuoteAll() && !l.Flat() && !l.Extended)
+}
+
+// Headers returns the columns of l when it has none: the ones of CsvHeaders,
+// and of ExtendedCsvHeaders when extended.
+func (l *CSVLayout) Headers() []string {
+	var e Entry
+
+	if l == nil || !l.Extended {
+		return e.CsvHeaders()
+	}
+
+	return append(e.CsvHeaders(), e.ExtendedCsvHeaders()...)
+}
+
+// Comma returns the delimiter of l.
+func (l *CSVLayout) Comma() rune {
+	switch {
+	case l == nil || l.Delimiter == "":
+		return ','
+	case l.Delimiter == "tab":
+		return '\t'
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.Delimiter)
+
+	return r
+}
+
+// QuoteAll reports whether all the fields are quoted.
+func (l *CSVLayout) QuoteAll() bool {
+	return l != nil && l.Quote == CSVQuoteAll
+}
+
+// Flat reports whether the nested fields are flattened into columns.
+func (l *CSVLayout) Flat() bool {
+	return l != nil && l.Nested == CSVNestedFlat
+}
+
+// Cells returns the cells of e by column: the ones of CsvRow and
+// ExtendedCsvRow, and in the flat layout the keys of its nested objects, e.g.
+// complete_address.city.
+func (l *CSVLayout) Cells(e *Entry) map[string]string {
+	headers := append(e.CsvHeaders(), e.ExtendedCsvHeaders()...)
+	row := append(e.CsvRow(), e.ExtendedCsvRow()...)
+	cells := make(map[string]string, len(headers))
+
+	for i := range headers {
+		cells[headers[i]] = row[i]
+
+		if !l.Flat() || !strings.HasPrefix(row[i], "{") {
+			continue
+		}
+
+		var obj map[string]any
+
+		dec := json.NewDecoder(strings.NewReader(row[i]))
+		dec.UseNumber()
+
+		if err := dec.Decode(&obj); err == nil {
+			flattenCells(headers[i], obj, cells)
+		}
+	}
+
+	return cells
+}
+
+// FlatColumns returns the columns of the flat layout of entries: the ones of
+// Headers, with the nested objects replaced by the sorted columns of their
+// keys.
+func (l *CSVLayout) FlatColumns(entries []*Entry) []string {
+	headers := l.Headers()
+	keys := make(map[string]map[string]bool)
+
+	for _, e := range entries {
+		for col := range l.Cells(e) {
+			field, _, ok := strings.Cut(col, ".")
+			if !ok {
+				continue
+			}
+
+			if keys[field] == nil {
+				keys[field] = make(map[string]bool)
+			}
+
+			keys[field][col] = true
+		}
+	}
+
+	var ans []string
+
+	for _, h := range headers {
+		if len(keys[h]) == 0 {
+			ans = append(ans, h)
+
+			continue
+		}
+
+		ans = append(ans, slices.Sorted(maps.Keys(keys[h]))...)
+	}
+
+	return ans
+}
+
+func flattenCells(prefix string, obj map[string]any, cells map[string]string) {
+	for k, v := range obj {
+		col := prefix + "." + k
+
+		switch val := v.(type) {
+		case map[string]any:
+			flattenCells(col, val, cells)
+		case string:
+			cells[col] = val
+		case json.Number:
+			cells[col] = val.String()
+		case bool:
+			cells[col] = strconv.FormatBool(val)
+		case nil:
+			cells[col] = ""
+		default:
+			d, _ := json.Marshal(val)
+			cells[col] = string(d)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_CSVLayoutValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []gmaps.CSVLayout{
+		{},
+		{Columns: []string{"title", "phone"}, Delimiter: ";", Quote: gmaps.CSVQuoteAll},
+		{Columns: []string{"complete_address.city"}, Delimiter: "tab", Nested: gmaps.CSVNestedFlat},
+		{Columns: []string{"title", "place_id", "domain"}},
+	}
+
+	for i := range valid {
+		require.NoError(t, valid[i].Validate())
+	}
+
+	invalid := []gmaps.CSVLayout{
+		{Columns: []string{"nope"}},
+		{Columns: []string{"title", "title"}},
+		{Columns: []string{"complete_address.city"}},
+		{Columns: []string{"complete_address."}, Nested: gmaps.CSVNestedFlat},
+		{Delimiter: ";;"},
+		{Delimiter: `"`},
+		{Quote: "some"},
+		{Nested: "deep"},
+	}
+
+	for i := range invalid {
+		require.Error(t, invalid[i].Validate(), invalid[i])
+	}
+
+	var layout *gmaps.CSVLayout
+
+	require.True(t, layout.IsDefault())
+	require.True(t, (&gmaps.CSVLayout{Delimiter: ",", Nested: gmaps.CSVNestedJSON}).IsDefault())
+	require.False(t, (&gmaps.CSVLayout{Delimiter: "tab"}).IsDefault())
+	require.False(t, (&gmaps.CSVLayout{Extended: true}).IsDefault())
+	require.Contains(t, (&gmaps.CSVLayout{Extended: true}).Headers(), "kgmid")
+	require.NotContains(t, layout.Headers(), "kgmid")
+	require.Equal(t, '\t', (&gmaps.CSVLayout{Delimiter: "tab"}).Comma())
+}
+
+func Test_CSVLayoutFlat(t *testing.T) {
+	t.Parallel()
+
+	e := &gmaps.Entry{
+		Title:           "Cafe",
+		CompleteAddress: gmaps.Address{City: "Ilion", PostalCode: "13122"},
+		ReviewsPerRating: map[int]int{
+			5: 10,
+		},
+		Emails: []string{"a@example.com"},
+	}
+
+	layout := gmaps.CSVLayout{Nested: gmaps.CSVNestedFlat}
+	cells := layout.Cells(e)
+
+	require.Equal(t, "Cafe", cells["title"])
+	require.Equal(t, "Ilion", cells["complete_address.city"])
+	require.Equal(t, "13122", cells["complete_address.postal_code"])
+	require.Equal(t, "10", cells["reviews_per_rating.5"])
+	require.JSONEq(t, `{"borough":"","street":"","city":"Ilion","postal_code":"13122","state":"","country":""}`, cells["complete_address"])
+
+	columns := layout.FlatColumns([]*gmaps.Entry{e})
+
+	require.Contains(t, columns, "complete_address.city")
+	require.NotContains(t, columns, "complete_address")
+	require.Contains(t, columns, "emails")
+
+	require.NotContains(t, (&gmaps.CSVLayout{}).Cells(e), "complete_address.city")
+}
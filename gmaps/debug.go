@@ -0,0 +1,324 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DebugRecorder records the network requests and the console messages of
+// the pages of the jobs, and writes them to a folder when a job fails, as a
+// HAR file ({job id}.har) and a log ({job id}.console.log), so a page that
+// stopped being parsed can be looked at without running the job again. It is
+// safe to share between jobs.
+type DebugRecorder struct {
+	dir string
+
+	pages sync.Map // playwright.Page -> *pageRecording
+}
+
+// pageRecording is what a page recorded since the job that uses it started.
+type pageRecording struct {
+	mu      sync.Mutex
+	entries []harEntry
+	console []string
+}
+
+// NewDebugRecorder writes the recordings of the failed jobs to dir.
+func NewDebugRecorder(dir string) (*DebugRecorder, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create the debug folder: %w", err)
+	}
+
+	return &DebugRecorder{dir: dir}, nil
+}
+
+// Start starts a new recording of page for a job. It does nothing when d is nil.
+func (d *DebugRecorder) Start(page playwright.Page) {
+	if d == nil {
+		return
+	}
+
+	rec := &pageRecording{}
+
+	// the listeners live as long as the page, which is reused by the next jobs
+	if prev, loaded := d.pages.LoadOrStore(page, rec); loaded {
+		rec = prev.(*pageRecording)
+		rec.reset()
+
+		return
+	}
+
+	page.OnResponse(func(resp playwright.Response) {
+		rec.add(harEntryOfResponse(resp))
+	})
+
+	page.OnRequestFailed(func(req playwright.Request) {
+		rec.add(harEntryOfFailure(req))
+	})
+
+	page.OnConsole(func(msg playwright.ConsoleMessage) {
+		rec.log(msg.Type(), msg.Text())
+	})
+
+	page.OnPageError(func(err error) {
+		rec.log("pageerror", err.Error())
+	})
+
+	page.OnClose(func(p playwright.Page) {
+		d.pages.Delete(p)
+	})
+}
+
+// Finish writes the recording of page when the job failed with jobErr. It
+// does nothing when d is nil or the job did not fail.
+func (d *DebugRecorder) Finish(page playwright.Page, jobID string, jobErr error) error {
+	if d == nil || jobErr == nil {
+		return nil
+	}
+
+	v, ok := d.pages.Load(page)
+	if !ok {
+		return nil
+	}
+
+	entries, console := v.(*pageRecording).snapshot()
+
+	name := safeFileName(jobID)
+
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "google-maps-scraper", Version: "1.0"},
+		Comment: jobErr.Error(),
+		Entries: entries,
+	}}
+
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(d.dir, name+".har"), data, 0o600); err != nil {
+		return fmt.Errorf("failed to save the har file: %w", err)
+	}
+
+	logs := "error: " + jobErr.Error() + "\nurl: " + page.URL() + "\n\n" + strings.Join(console, "\n")
+
+	if err := os.WriteFile(filepath.Join(d.dir, name+".console.log"), []byte(logs), 0o600); err != nil {
+		return fmt.Errorf("failed to save the console log: %w", err)
+	}
+
+	return nil
+}
+
+func (r *pageRecording) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = nil
+	r.console = nil
+}
+
+func (r *pageRecording) add(e harEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, e)
+}
+
+func (r *pageRecording) log(kind, text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.console = append(r.console, time.Now().UTC().Format(time.RFC3339Nano)+" ["+kind+"] "+text)
+}
+
+func (r *pageRecording) snapshot() ([]harEntry, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]harEntry, len(r.entries))
+	copy(entries, r.entries)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime < entries[j].StartedDateTime
+	})
+
+	console := make([]string, len(r.console))
+	copy(console, r.console)
+
+	return entries, console
+}
+
+// The HAR 1.2 format, only what the page events give. The bodies are not
+// recorded.
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Comment string     `json:"comment,omitempty"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func harEntryOfRequest(req playwright.Request) harEntry {
+	e := harEntry{
+		StartedDateTime: time.Now().UTC().Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method(),
+			URL:         req.URL(),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Headers()),
+			QueryString: harQuery(req.URL()),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+
+	if t := req.Timing(); t != nil && t.StartTime > 0 {
+		e.StartedDateTime = time.UnixMilli(int64(t.StartTime)).UTC().Format(time.RFC3339Nano)
+
+		if t.RequestStart >= 0 && t.ResponseStart >= t.RequestStart {
+			e.Timings.Wait = t.ResponseStart - t.RequestStart
+		}
+
+		if t.ResponseStart >= 0 && t.ResponseEnd >= t.ResponseStart {
+			e.Timings.Receive = t.ResponseEnd - t.ResponseStart
+		}
+
+		e.Time = e.Timings.Send + e.Timings.Wait + e.Timings.Receive
+	}
+
+	return e
+}
+
+func harEntryOfResponse(resp playwright.Response) harEntry {
+	e := harEntryOfRequest(resp.Request())
+
+	headers := resp.Headers()
+
+	e.Response.Status = resp.Status()
+	e.Response.StatusText = resp.StatusText()
+	e.Response.Headers = harHeaders(headers)
+	e.Response.Content.MimeType = headers["content-type"]
+	e.Response.RedirectURL = headers["location"]
+
+	return e
+}
+
+func harEntryOfFailure(req playwright.Request) harEntry {
+	e := harEntryOfRequest(req)
+
+	if err := req.Failure(); err != nil {
+		e.Comment = err.Error()
+	}
+
+	return e
+}
+
+func harHeaders(headers map[string]string) []harNameValue {
+	ans := make([]harNameValue, 0, len(headers))
+
+	for k, v := range headers {
+		ans = append(ans, harNameValue{Name: k, Value: v})
+	}
+
+	sort.Slice(ans, func(i, j int) bool {
+		return ans[i].Name < ans[j].Name
+	})
+
+	return ans
+}
+
+func harQuery(u string) []harNameValue {
+	ans := []harNameValue{}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ans
+	}
+
+	for k, values := range parsed.Query() {
+		for _, v := range values {
+			ans = append(ans, harNameValue{Name: k, Value: v})
+		}
+	}
+
+	sort.Slice(ans, func(i, j int) bool {
+		return ans[i].Name < ans[j].Name
+	})
+
+	return ans
+}
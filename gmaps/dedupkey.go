@@ -0,0 +1,42 @@
+package gmaps
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var placeHexIDRegex = regexp.MustCompile(`!1s(0x[0-9a-fA-F]+(?::|%3[aA])0x[0-9a-fA-F]+)`)
+
+// DedupKey returns a canonical key for a place link so the same place found
+// via different languages, tiles or tracking parameters is only scraped once.
+//
+// The key is the CID when it can be extracted from the link, either from the
+// hex place id (0x...:0x<cid>) of /maps/place/ links or from the cid parameter.
+// Otherwise the path of the link is used, so the Google domain and the
+// query string do not matter.
+func DedupKey(link string) string {
+	if m := placeHexIDRegex.FindStringSubmatch(link); len(m) == 2 {
+		hexID := strings.Replace(strings.ToLower(m[1]), "%3a", ":", 1)
+
+		_, cidHex, _ := strings.Cut(hexID, ":")
+
+		if cid, err := strconv.ParseUint(strings.TrimPrefix(cidHex, "0x"), 16, 64); err == nil {
+			return "cid:" + strconv.FormatUint(cid, 10)
+		}
+
+		return "place:" + hexID
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	if cid := u.Query().Get("cid"); cid != "" {
+		return "cid:" + cid
+	}
+
+	return "path:" + u.Path
+}
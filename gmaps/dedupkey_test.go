@@ -0,0 +1,21 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_DedupKey(t *testing.T) {
+	t.Parallel()
+
+	en := "https://www.google.com/maps/place/Kipriakon/data=!4m7!3m6!1s0x14e732fd76f0d90d:0xe5415928d6702b47!8m2!3d35.1!4d33.3!16s%2Fg%2F1tdh6bqw!19sChIJ?authuser=0&hl=en&rclk=1"
+	de := "https://www.google.de/maps/place/Kipriakon/data=!4m7!3m6!1s0x14e732fd76f0d90d%3A0xe5415928d6702b47!8m2!3d35.1!4d33.3?hl=de"
+
+	require.Equal(t, "cid:16519582940102929223", gmaps.DedupKey(en))
+	require.Equal(t, gmaps.DedupKey(en), gmaps.DedupKey(de))
+	require.Equal(t, "cid:123", gmaps.DedupKey("https://maps.google.com/?cid=123&hl=el"))
+	require.Equal(t, "path:/maps/place/Foo", gmaps.DedupKey("https://www.google.com/maps/place/Foo?hl=en"))
+}
@@ -1,14 +1,20 @@
 package gmaps
 
 import (
+	"bytes"
 	"context"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/google/uuid"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/tracing"
 	"github.com/gosom/scrapemate"
 	"github.com/mcnijman/go-emailaddress"
+	"github.com/playwright-community/playwright-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type EmailExtractJobOptions func(*EmailExtractJob)
@@ -18,6 +24,21 @@ type EmailExtractJob struct {
 
 	Entry       *Entry
 	ExitMonitor exiter.Exiter
+	// TraceParent is the traceparent of the span of the place
+	TraceParent string
+
+	trace string
+	// hostLimiter, contacts, guesser, verifier, fingerprints, enrichers, recorder, failures, breakers and retryer are not encoded, the runner that decodes the job sets them again
+	hostLimiter  *HostLimiter
+	contacts     *ContactFinder
+	guesser      *EmailGuesser
+	verifier     *EmailVerifier
+	fingerprints *FingerprintRotator
+	enrichers    []Enricher
+	recorder     *metrics.Recorder
+	failures     *FailureLog
+	breakers     *CircuitBreakers
+	retryer      *Retryer
 }
 
 func NewEmailJob(parentID string, entry *Entry, opts ...EmailExtractJobOptions) *EmailExtractJob {
@@ -52,12 +73,149 @@ func WithEmailJobExitMonitor(exitMonitor exiter.Exiter) EmailExtractJobOptions {
 	}
 }
 
+// WithEmailJobHostLimiter waits for l before the website is loaded.
+func WithEmailJobHostLimiter(l *HostLimiter) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.hostLimiter = l
+	}
+}
+
+// WithEmailJobContactFinder looks for emails on the contact pages found by f
+// when the website has none.
+func WithEmailJobContactFinder(f *ContactFinder) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.contacts = f
+	}
+}
+
+// WithEmailJobGuesser guesses the emails with g when the website has none.
+func WithEmailJobGuesser(g *EmailGuesser) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.guesser = g
+	}
+}
+
+// WithEmailJobVerifier verifies the emails found on the website with v.
+func WithEmailJobVerifier(v *EmailVerifier) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.verifier = v
+	}
+}
+
+// WithEmailJobFingerprints gives the browser contexts of the job a fingerprint of r.
+func WithEmailJobFingerprints(r *FingerprintRotator) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.fingerprints = r
+	}
+}
+
+// WithEmailJobCircuitBreakers fails the job at once while the circuit of the
+// website is open in b.
+func WithEmailJobCircuitBreakers(b *CircuitBreakers) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.breakers = b
+	}
+}
+
+// WithEmailJobRetryer retries the website and its contact pages with r when
+// they are rate limited.
+func WithEmailJobRetryer(r *Retryer) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.retryer = r
+	}
+}
+
+// WithEmailJobFailureLog records the errors of the job in l.
+func WithEmailJobFailureLog(l *FailureLog) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.failures = l
+	}
+}
+
+// WithEmailJobEnrichers runs enrichers over the homepage instead of the
+// built-in ones.
+func WithEmailJobEnrichers(enrichers []Enricher) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.enrichers = enrichers
+	}
+}
+
+// WithEmailJobMetrics records the metrics of the job with r.
+func WithEmailJobMetrics(r *metrics.Recorder) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.recorder = r
+	}
+}
+
+// WithEmailJobTraceParent makes the spans of the job children of the given traceparent.
+func WithEmailJobTraceParent(traceParent string) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.TraceParent = traceParent
+	}
+}
+
+// Traceparent returns the traceparent of the span of the website.
+func (j *EmailExtractJob) Traceparent() string {
+	if j.trace != "" {
+		return j.trace
+	}
+
+	return j.TraceParent
+}
+
+func (j *EmailExtractJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	trackPage(page)
+
+	ctx, span := tracing.Start(ctx, j.TraceParent, "gmaps.email",
+		attribute.String("job.id", j.ID),
+		attribute.String("url", j.GetURL()),
+	)
+
+	if err := j.hostLimiter.Wait(ctx, j.GetURL()); err != nil {
+		tracing.End(span, err)
+
+		return scrapemate.Response{Error: err}
+	}
+
+	if err := j.fingerprints.Apply(page); err != nil {
+		tracing.End(span, err)
+
+		return scrapemate.Response{Error: err}
+	}
+
+	start := time.Now()
+	resp := j.breakers.guard(j.GetURL(), func() scrapemate.Response {
+		var resp scrapemate.Response
+
+		err := j.retryer.Do(ctx, j.GetURL(), func() error {
+			resp = j.gotoWebsite(page)
+
+			return resp.Error
+		})
+		if err != nil && resp.Error == nil {
+			resp.Error = err
+		}
+
+		return resp
+	})
+
+	j.trace = tracing.Parent(ctx)
+	tracing.End(span, resp.Error)
+	j.recorder.Scrape(metrics.JobTypeEmail, time.Since(start), resp.Error)
+	j.failures.RecordJob(Failure{JobID: j.ID, InputID: j.Entry.ID, Type: "email", URL: j.GetURL()}, resp.Error)
+
+	return resp
+}
+
 func (j *EmailExtractJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
 	}()
 
+	ctx, span := tracing.Start(ctx, j.Traceparent(), "gmaps.email.process")
+	defer span.End()
+
 	defer func() {
 		if j.ExitMonitor != nil {
 			j.ExitMonitor.IncrPlacesCompleted(1)
@@ -68,26 +226,97 @@ func (j *EmailExtractJob) Process(ctx context.Context, resp *scrapemate.Response
 
 	log.Info("Processing email job", "url", j.URL)
 
-	// if html fetch failed just return
-	if resp.Error != nil {
-		return j.Entry, nil, nil
+	if health, ok := resp.Meta["health"].(*WebsiteHealth); ok {
+		j.Entry.WebsiteHealth = health
 	}
 
+	// if html fetch failed only the emails are guessed
 	doc, ok := resp.Document.(*goquery.Document)
-	if !ok {
+	if resp.Error != nil || !ok {
+		j.Entry.EmailDetails = j.guesser.Guess(ctx, j.Entry)
+
 		return j.Entry, nil, nil
 	}
 
+	j.runEnrichers(ctx, &WebsitePage{
+		URL:     resp.URL,
+		Headers: resp.Headers,
+		Body:    resp.Body,
+		Doc:     doc,
+	})
+
 	emails := docEmailExtractor(doc)
 	if len(emails) == 0 {
 		emails = regexEmailExtractor(resp.Body)
 	}
 
+	if len(emails) == 0 {
+		emails = j.contactPageEmails(ctx)
+	}
+
 	j.Entry.Emails = emails
+	j.Entry.EmailDetails = WebsiteEmailDetails(emails)
+	j.Entry.EmailStatuses = j.verifier.Verify(ctx, emails)
+
+	if len(emails) == 0 {
+		j.Entry.EmailDetails = j.guesser.Guess(ctx, j.Entry)
+	}
 
 	return j.Entry, nil, nil
 }
 
+// contactPageEmails returns the emails of the first contact page of the
+// website that has some. The first contact form found on the way is kept in
+// ContactFormURL.
+func (j *EmailExtractJob) contactPageEmails(ctx context.Context) []string {
+	if j.contacts == nil {
+		return nil
+	}
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for _, page := range j.contacts.Find(ctx, j.GetURL()) {
+		if err := j.hostLimiter.Wait(ctx, page); err != nil {
+			return nil
+		}
+
+		var body []byte
+
+		err := j.retryer.Do(ctx, page, func() error {
+			var err error
+
+			body, err = j.contacts.Fetch(ctx, page)
+
+			return err
+		})
+		if err != nil {
+			log.Info("cannot fetch contact page", "url", page, "error", err)
+
+			continue
+		}
+
+		var emails []string
+
+		if doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body)); err == nil {
+			emails = docEmailExtractor(doc)
+
+			if j.Entry.ContactFormURL == "" && HasContactForm(doc) {
+				j.Entry.ContactFormURL = page
+			}
+		}
+
+		if len(emails) == 0 {
+			emails = regexEmailExtractor(body)
+		}
+
+		if len(emails) > 0 {
+			return emails
+		}
+	}
+
+	return nil
+}
+
 func (j *EmailExtractJob) ProcessOnFetchError() bool {
 	return true
 }
@@ -0,0 +1,180 @@
+package gmaps
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gosom/scrapemate"
+)
+
+// The names of the built-in enrichers.
+const (
+	EnricherTechnologies   = "technologies"
+	EnricherContactForm    = "contact_form"
+	EnricherStructuredData = "structured_data"
+	EnricherSocials        = "socials"
+	EnricherPhones         = "phones"
+)
+
+// WebsitePage is the homepage of the website of a place.
+type WebsitePage struct {
+	// URL is the url of the page after the redirects
+	URL     string
+	Headers http.Header
+	Body    []byte
+	Doc     *goquery.Document
+}
+
+// Enricher adds data to the entry of a place from the homepage of its
+// website. The email jobs run their enrichers in order after the homepage
+// is loaded, before the emails are extracted.
+type Enricher interface {
+	// Name is the name of the enricher in the registry, see RegisterEnricher
+	Name() string
+	Enrich(ctx context.Context, page *WebsitePage, e *Entry) error
+}
+
+type enricherFunc struct {
+	name string
+	fn   func(ctx context.Context, page *WebsitePage, e *Entry) error
+}
+
+func (f enricherFunc) Name() string {
+	return f.name
+}
+
+func (f enricherFunc) Enrich(ctx context.Context, page *WebsitePage, e *Entry) error {
+	return f.fn(ctx, page, e)
+}
+
+// NewEnricher returns an enricher named name that runs fn.
+func NewEnricher(name string, fn func(ctx context.Context, page *WebsitePage, e *Entry) error) Enricher {
+	return enricherFunc{name: name, fn: fn}
+}
+
+var (
+	enrichersMu sync.RWMutex
+	enrichers   = map[string]Enricher{}
+	// defaultEnrichers are the names of the enrichers run when none are chosen
+	defaultEnrichers []string
+)
+
+func init() {
+	for _, e := range []Enricher{
+		NewEnricher(EnricherTechnologies, func(_ context.Context, page *WebsitePage, e *Entry) error {
+			e.Technologies = DetectTechnologies(page.Headers, page.Body)
+			e.TrackingIDs = DetectTrackingIDs(page.Body)
+
+			return nil
+		}),
+		NewEnricher(EnricherContactForm, func(_ context.Context, page *WebsitePage, e *Entry) error {
+			if HasContactForm(page.Doc) {
+				e.ContactFormURL = page.URL
+			}
+
+			return nil
+		}),
+		NewEnricher(EnricherStructuredData, func(_ context.Context, page *WebsitePage, e *Entry) error {
+			if data := ExtractStructuredData(page.Doc); data != nil {
+				data.CompareWith(e)
+				e.WebsiteStructuredData = data
+			}
+
+			return nil
+		}),
+		NewEnricher(EnricherSocials, func(_ context.Context, page *WebsitePage, e *Entry) error {
+			e.AddSocialProfiles(docLinks(page.Doc, page.URL))
+
+			return nil
+		}),
+		NewEnricher(EnricherPhones, func(_ context.Context, page *WebsitePage, e *Entry) error {
+			e.WebsitePhones = docPhones(page.Doc, e.CompleteAddress.Country)
+
+			return nil
+		}),
+	} {
+		RegisterEnricher(e)
+		defaultEnrichers = append(defaultEnrichers, e.Name())
+	}
+}
+
+// RegisterEnricher adds e to the enrichers that can be chosen by name, the
+// enricher plugins call it in their init function. It panics when the name
+// is empty or already registered.
+func RegisterEnricher(e Enricher) {
+	enrichersMu.Lock()
+	defer enrichersMu.Unlock()
+
+	name := e.Name()
+	if name == "" {
+		panic("gmaps: enricher without a name")
+	}
+
+	if _, ok := enrichers[name]; ok {
+		panic("gmaps: enricher registered twice: " + name)
+	}
+
+	enrichers[name] = e
+}
+
+// EnricherNames returns the names of the registered enrichers, sorted.
+func EnricherNames() []string {
+	enrichersMu.RLock()
+	defer enrichersMu.RUnlock()
+
+	ans := make([]string, 0, len(enrichers))
+	for name := range enrichers {
+		ans = append(ans, name)
+	}
+
+	slices.Sort(ans)
+
+	return ans
+}
+
+// LookupEnrichers returns the registered enrichers named names, in order, or
+// the built-in ones when names is empty.
+func LookupEnrichers(names []string) ([]Enricher, error) {
+	if len(names) == 0 {
+		names = defaultEnrichers
+	}
+
+	enrichersMu.RLock()
+	defer enrichersMu.RUnlock()
+
+	ans := make([]Enricher, 0, len(names))
+
+	for _, name := range names {
+		e, ok := enrichers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown enricher %q", name)
+		}
+
+		ans = append(ans, e)
+	}
+
+	return ans, nil
+}
+
+// runEnrichers runs the enrichers of the job, the built-in ones when none
+// were given, over the homepage. The errors are logged and do not stop the
+// other enrichers.
+func (j *EmailExtractJob) runEnrichers(ctx context.Context, page *WebsitePage) {
+	list := j.enrichers
+	if list == nil {
+		list, _ = LookupEnrichers(nil)
+	}
+
+	page.URL = cmp.Or(page.URL, j.GetURL())
+
+	for _, e := range list {
+		if err := e.Enrich(ctx, page, j.Entry); err != nil {
+			scrapemate.GetLoggerFromContext(ctx).Info("enricher failed", "enricher", e.Name(), "url", page.URL, "error", err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package gmaps_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_Enrichers(t *testing.T) {
+	t.Parallel()
+
+	defaults, err := gmaps.LookupEnrichers(nil)
+	require.NoError(t, err)
+	require.Len(t, defaults, 5)
+	require.Equal(t, gmaps.EnricherTechnologies, defaults[0].Name())
+
+	gmaps.RegisterEnricher(gmaps.NewEnricher("test_title", func(_ context.Context, _ *gmaps.WebsitePage, e *gmaps.Entry) error {
+		e.Title = "enriched"
+
+		return nil
+	}))
+
+	require.Contains(t, gmaps.EnricherNames(), "test_title")
+	require.Panics(t, func() {
+		gmaps.RegisterEnricher(gmaps.NewEnricher(gmaps.EnricherPhones, nil))
+	})
+
+	list, err := gmaps.LookupEnrichers([]string{"test_title", gmaps.EnricherSocials})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	var e gmaps.Entry
+	require.NoError(t, list[0].Enrich(context.Background(), &gmaps.WebsitePage{}, &e))
+	require.Equal(t, "enriched", e.Title)
+
+	_, err = gmaps.LookupEnrichers([]string{"nope"})
+	require.Error(t, err)
+}
@@ -9,6 +9,8 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/gosom/google-maps-scraper/emailverify"
 )
 
 type Image struct {
@@ -54,10 +56,15 @@ type Review struct {
 	Description    string
 	Images         []string
 	When           string
+	// Language is the ISO 639-1 code of the review text, empty when unknown
+	Language string
+	// TranslatedText is the text translated by TranslateReviews, empty when it is not translated
+	TranslatedText string
 }
 
 type Entry struct {
 	ID         string              `json:"input_id"`
+	Query      string              `json:"query"`
 	Link       string              `json:"link"`
 	Cid        string              `json:"cid"`
 	Title      string              `json:"title"`
@@ -105,6 +112,47 @@ type Entry struct {
 	ClosingHours        string                 `json:"closing_hours"`
 	IsOpen24Hours       bool                   `json:"is_open_24_hours"`
 	IsClosed            bool                   `json:"is_closed"`
+	// Computed at scrape time in the timezone of the place
+	OpenNow             bool                   `json:"open_now"`
+	NextOpenTime        string                 `json:"next_open_time"`
+	// Distance in meters from the center of the search, 0 when the job has no center
+	DistanceMeters      float64                `json:"distance_meters,omitempty"`
+	// ReviewsComplete is true when the extracted reviews match the review count, see ReconcileReviews
+	ReviewsComplete     bool                   `json:"reviews_complete"`
+	// DescriptionLanguage is the ISO 639-1 code of the description, empty when unknown
+	DescriptionLanguage string                 `json:"description_language"`
+	// ScreenshotPath is the file, or s3:// url, of the screenshot of the place page
+	ScreenshotPath      string                 `json:"screenshot_path,omitempty"`
+	// ReviewInsights are the statistics of the reviews, nil unless computed, see ComputeReviewInsights
+	ReviewInsights      *ReviewInsights        `json:"review_insights,omitempty"`
+	// LiveOccupancy is the live busyness of the place, nil when Google does not show one
+	LiveOccupancy       *LiveOccupancy         `json:"live_occupancy,omitempty"`
+	// EmailDetails are the emails found on the website, or the guessed ones when it has none
+	EmailDetails        []EmailDetail          `json:"email_details,omitempty"`
+	// EmailStatuses are the verifications of the emails, empty unless verified
+	EmailStatuses       []emailverify.Result   `json:"email_statuses,omitempty"`
+	// PhoneDetails is the phone in the E.164 and national formats, see NormalizePhone
+	PhoneDetails        *PhoneInfo             `json:"phone_details,omitempty"`
+	// WebsitePhones are the valid phones of the tel: links of the website
+	WebsitePhones       []PhoneInfo            `json:"website_phones,omitempty"`
+	// Technologies are the CMS, ecommerce platform, analytics and chat widgets of the website, see DetectTechnologies
+	Technologies        []string               `json:"technologies,omitempty"`
+	// WebsiteHealth is how the website answered, nil unless it was crawled for emails
+	WebsiteHealth       *WebsiteHealth         `json:"website_health,omitempty"`
+	// ContactFormURL is the first page of the website with a contact form, see HasContactForm
+	ContactFormURL      string                 `json:"contact_form_url,omitempty"`
+	// WebsiteStructuredData is the schema.org LocalBusiness of the homepage, see ExtractStructuredData
+	WebsiteStructuredData *StructuredData      `json:"website_structured_data,omitempty"`
+	// SocialProfiles are the canonical handles by platform, see SocialHandle
+	SocialProfiles      map[string]string      `json:"social_profiles,omitempty"`
+	// PlaceID is the id of the place in the Places API, e.g. ChIJDdnwdv0y5xQRRytw1ihZQeU
+	PlaceID             string                 `json:"place_id,omitempty"`
+	// KGMID is the id of the place in the Google knowledge graph, e.g. /g/11c54_9hlz
+	KGMID               string                 `json:"kgmid,omitempty"`
+	// TrackingIDs are the analytics and ads ids of the homepage, see DetectTrackingIDs
+	TrackingIDs         []string               `json:"tracking_ids,omitempty"`
+	// Source is the strategy of the fallback chain that found the place: static or browser
+	Source              string                 `json:"source,omitempty"`
 }
 
 func (e *Entry) haversineDistance(lat, lon float64) float64 {
@@ -128,6 +176,11 @@ func (e *Entry) haversineDistance(lat, lon float64) float64 {
 	return R * c
 }
 
+// SetDistanceFrom sets DistanceMeters to the distance between the entry and the given point.
+func (e *Entry) SetDistanceFrom(lat, lon float64) {
+	e.DistanceMeters = e.haversineDistance(lat, lon)
+}
+
 func (e *Entry) isWithinRadius(lat, lon, radius float64) bool {
 	distance := e.haversineDistance(lat, lon)
 
@@ -211,6 +264,24 @@ func (e *Entry) CsvHeaders() []string {
 		"closing_hours",
 		"is_open_24_hours",
 		"is_closed",
+		"open_now",
+		"next_open_time",
+		"distance_meters",
+		"reviews_complete",
+		"description_language",
+		"screenshot_path",
+		"review_insights",
+		"live_occupancy",
+		"email_details",
+		"email_statuses",
+		"phone_details",
+		"website_phones",
+		"technologies",
+		"website_health",
+		"contact_form_url",
+		"website_structured_data",
+		"social_profiles",
+		"query",
 	}
 }
 
@@ -259,6 +330,24 @@ func (e *Entry) CsvRow() []string {
 		e.ClosingHours,
 		stringify(e.IsOpen24Hours),
 		stringify(e.IsClosed),
+		stringify(e.OpenNow),
+		e.NextOpenTime,
+		formatDistance(e.DistanceMeters),
+		stringify(e.ReviewsComplete),
+		e.DescriptionLanguage,
+		e.ScreenshotPath,
+		reviewInsightsString(e.ReviewInsights),
+		liveOccupancyString(e.LiveOccupancy),
+		emailDetailsString(e.EmailDetails),
+		emailStatusesString(e.EmailStatuses),
+		phoneDetailsString(e.PhoneDetails),
+		websitePhonesString(e.WebsitePhones),
+		stringSliceToString(e.Technologies),
+		websiteHealthString(e.WebsiteHealth),
+		e.ContactFormURL,
+		structuredDataString(e.WebsiteStructuredData),
+		socialProfilesString(e.SocialProfiles),
+		e.Query,
 	}
 }
 
@@ -275,6 +364,22 @@ func (e *Entry) AddExtraReviews(pages [][]byte) {
 	}
 }
 
+// reviewsCompleteRatio is the share of the review count that must be extracted
+// for the reviews to be complete. Google does not return reviews without text
+// and the count is updated with a delay, so a small gap is expected.
+const reviewsCompleteRatio = 0.9
+
+// ReconcileReviews compares the extracted reviews with the review count and sets
+// ReviewsComplete. It returns the number of extracted reviews.
+// A large gap means that the review pagination failed or reviews were filtered.
+func (e *Entry) ReconcileReviews() int {
+	fetched := max(len(e.UserReviews), len(e.UserReviewsExtended))
+
+	e.ReviewsComplete = e.ReviewCount == 0 || float64(fetched) >= reviewsCompleteRatio*float64(e.ReviewCount)
+
+	return fetched
+}
+
 func extractReviews(data []byte) []Review {
 	if len(data) >= 4 && string(data[0:4]) == `)]}'` {
 		data = data[4:] // Skip security prefix
@@ -346,6 +451,7 @@ func EntryFromJSON(raw []byte, reviewCountOnly ...bool) (entry Entry, err error)
 	)
 	entry.OpenHours = getHours(darray)
 	entry.PopularTimes = getPopularTimes(darray)
+	entry.LiveOccupancy = getLiveOccupancy(darray, entry.PopularTimes)
 	entry.WebSite = getNthElementAndCast[string](darray, 7, 0)
 	entry.Phone = getNthElementAndCast[string](darray, 178, 0, 0)
 	entry.PlusCode = getNthElementAndCast[string](darray, 183, 2, 2, 0)
@@ -360,6 +466,8 @@ func EntryFromJSON(raw []byte, reviewCountOnly ...bool) (entry Entry, err error)
 	entry.Timezone = getNthElementAndCast[string](darray, 30)
 	entry.PriceRange = getNthElementAndCast[string](darray, 4, 2)
 	entry.DataID = getNthElementAndCast[string](darray, 10)
+	entry.PlaceID = getNthElementAndCast[string](darray, 78)
+	entry.KGMID = getNthElementAndCast[string](darray, 89)
 
 	items := getLinkSource(getLinkSourceParams{
 		arr:    getNthElementAndCast[[]any](darray, 171, 0),
@@ -417,6 +525,9 @@ func EntryFromJSON(raw []byte, reviewCountOnly ...bool) (entry Entry, err error)
 		Country:    getNthElementAndCast[string](darray, 183, 1, 6),
 	}
 
+	entry.NormalizePhone()
+	entry.AddSocialProfiles([]string{entry.WebSite})
+
 	aboutI := getNthElementAndCast[[]any](darray, 100, 1)
 
 	for i := range aboutI {
@@ -630,7 +741,7 @@ func getNthElementAndCast[T any](arr []any, indexes ...int) T {
 		}
 	}
 
-	if len(indexes) == 0 || len(arr) == 0 {
+	if len(indexes) == 0 || indexes[0] >= len(arr) {
 		return defaultVal
 	}
 
@@ -660,6 +771,14 @@ func stringify(v any) string {
 	}
 }
 
+func formatDistance(d float64) string {
+	if d == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(d, 'f', 1, 64)
+}
+
 func decodeURL(url string) (string, error) {
 	quoted := `"` + strings.ReplaceAll(url, `"`, `\"`) + `"`
 
@@ -681,6 +800,8 @@ func filterAndSortEntriesWithinRadius(entries []*Entry, lat, lon, radius float64
 		for _, entry := range entries {
 			distance := entry.haversineDistance(lat, lon)
 			if distance <= radius {
+				entry.DistanceMeters = distance
+
 				if !yield(EntryWithDistance{Entry: entry, Distance: distance}) {
 					return
 				}
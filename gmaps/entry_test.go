@@ -41,8 +41,15 @@ func Test_EntryFromJSON(t *testing.T) {
 			"Saturday":  {"12:30–10 pm"},
 			"Sunday":    {"12:30–10 pm"},
 		},
-		WebSite:      "",
-		Phone:        "25 101555",
+		WebSite: "",
+		Phone:   "25 101555",
+		PhoneDetails: &gmaps.PhoneInfo{
+			Raw:      "25 101555",
+			E164:     "+35725101555",
+			National: "25 101555",
+			Type:     gmaps.PhoneTypeFixedLine,
+			Valid:    true,
+		},
 		PlusCode:     "M2CR+6X Limassol",
 		ReviewCount:  396,
 		ReviewRating: 4.2,
@@ -55,6 +62,8 @@ func Test_EntryFromJSON(t *testing.T) {
 		Timezone:     "Asia/Nicosia",
 		PriceRange:   "€€",
 		DataID:       "0x14e732fd76f0d90d:0xe5415928d6702b47",
+		PlaceID:      "ChIJDdnwdv0y5xQRRytw1ihZQeU",
+		KGMID:        "/g/11c54_9hlz",
 		Images: []gmaps.Image{
 			{
 				Title: "All",
@@ -200,6 +209,13 @@ func Test_EntryFromJSONRaw2(t *testing.T) {
 
 	require.NoError(t, err)
 	require.Greater(t, len(entry.About), 0)
+
+	require.NotNil(t, entry.LiveOccupancy)
+	require.Equal(t, "Saturday", entry.LiveOccupancy.Day)
+	require.Equal(t, 15, entry.LiveOccupancy.Hour)
+	require.Equal(t, 0, entry.LiveOccupancy.Current)
+	require.Equal(t, entry.PopularTimes["Saturday"][15], entry.LiveOccupancy.Usual)
+	require.NotEmpty(t, entry.LiveOccupancy.Text)
 }
 
 func Test_EntryFromJsonC(t *testing.T) {
@@ -216,3 +232,51 @@ func Test_EntryFromJsonC(t *testing.T) {
 		fmt.Printf("%+v\n", entry)
 	}
 }
+
+func Test_EntryAboutFlags(t *testing.T) {
+	entry := gmaps.Entry{
+		About: []gmaps.About{
+			{
+				ID:   "service_options",
+				Name: "Service options",
+				Options: []gmaps.Option{
+					{Name: "Dine-in", Enabled: true},
+					{Name: "Delivery", Enabled: false},
+				},
+			},
+			{
+				Name: "Payments",
+				Options: []gmaps.Option{
+					{Name: "Credit cards", Enabled: true},
+					{Name: "Credit cards", Enabled: false},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, map[string]bool{
+		"about.service_options.dine_in":  true,
+		"about.service_options.delivery": false,
+		"about.payments.credit_cards":    true,
+	}, entry.AboutFlags())
+}
+
+func Test_EntryNormalize(t *testing.T) {
+	t.Parallel()
+
+	e := gmaps.Entry{
+		Title:       "  Cafe\u0301  Rio 🍕‍🔥 ",
+		Address:     "Main St 1,\n\t 10115 Berlin",
+		Description: "Best ☕ in town ✨",
+	}
+
+	e.Normalize(false)
+
+	require.Equal(t, "Café Rio 🍕‍🔥", e.Title)
+	require.Equal(t, "Main St 1, 10115 Berlin", e.Address)
+
+	e.Normalize(true)
+
+	require.Equal(t, "Café Rio", e.Title)
+	require.Equal(t, "Best in town", e.Description)
+}
@@ -0,0 +1,132 @@
+package gmaps
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ExtendedCsvHeaders are the columns of the extended csv schema, written
+// after the ones of CsvHeaders, see ExtendedCsvRow.
+func (e *Entry) ExtendedCsvHeaders() []string {
+	return []string{
+		"place_id",
+		"kgmid",
+		"domain",
+		"claimed",
+		"phones",
+		"featured_image",
+		"tracking_ids",
+		"social_links",
+		"source",
+	}
+}
+
+// ExtendedCsvRow returns the fields of the extended csv schema, the ones
+// the JSON output has that CsvRow does not and the ones derived from them.
+func (e *Entry) ExtendedCsvRow() []string {
+	return []string{
+		e.PlaceID,
+		e.KGMID,
+		e.Domain(),
+		strconv.FormatBool(e.Claimed()),
+		stringSliceToString(e.Phones()),
+		e.FeaturedImage(),
+		stringSliceToString(e.TrackingIDs),
+		stringSliceToString(e.SocialLinks()),
+		e.Source,
+	}
+}
+
+// Domain returns the host of the website without www, empty without one.
+func (e *Entry) Domain() string {
+	website := e.WebSite
+	if website == "" {
+		return ""
+	}
+
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+
+	u, err := url.Parse(website)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Claimed reports whether the place is managed by its owner, who then has a
+// profile on Google Maps.
+func (e *Entry) Claimed() bool {
+	return e.Owner.ID != ""
+}
+
+// Phones returns the phone of the place and the phones of its website,
+// in the E.164 format when it is known, without duplicates.
+func (e *Entry) Phones() []string {
+	var ans []string
+
+	add := func(phone string) {
+		if phone != "" && !slices.Contains(ans, phone) {
+			ans = append(ans, phone)
+		}
+	}
+
+	switch {
+	case e.PhoneDetails != nil && e.PhoneDetails.E164 != "":
+		add(e.PhoneDetails.E164)
+	default:
+		add(e.Phone)
+	}
+
+	for i := range e.WebsitePhones {
+		if e.WebsitePhones[i].E164 != "" {
+			add(e.WebsitePhones[i].E164)
+		} else {
+			add(e.WebsitePhones[i].Raw)
+		}
+	}
+
+	return ans
+}
+
+// FeaturedImage returns the main image of the place: its thumbnail, or its
+// first image.
+func (e *Entry) FeaturedImage() string {
+	if e.Thumbnail != "" || len(e.Images) == 0 {
+		return e.Thumbnail
+	}
+
+	return e.Images[0].Image
+}
+
+// SocialLinks returns the urls of the social profiles of the place, by
+// platform, and the Facebook, Instagram, LinkedIn and Twitter links of
+// Google Maps that are not among them.
+func (e *Entry) SocialLinks() []string {
+	var ans []string
+
+	for _, platform := range slices.Sorted(maps.Keys(e.SocialProfiles)) {
+		if link := SocialProfileURL(platform, e.SocialProfiles[platform]); link != "" {
+			ans = append(ans, link)
+		}
+	}
+
+	for _, link := range []string{e.Facebook, e.Instagram, e.LinkedIn, e.Twitter} {
+		if link == "" || slices.Contains(ans, link) {
+			continue
+		}
+
+		if platform, handle, ok := SocialHandle(link); ok && e.SocialProfiles[platform] == handle {
+			continue
+		}
+
+		ans = append(ans, link)
+	}
+
+	return ans
+}
@@ -0,0 +1,61 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_EntryExtendedCsvRow(t *testing.T) {
+	t.Parallel()
+
+	e := gmaps.Entry{
+		WebSite:        "https://www.Example.com/about?utm_source=google",
+		Phone:          "210 123 4567",
+		PhoneDetails:   &gmaps.PhoneInfo{Raw: "210 123 4567", E164: "+302101234567"},
+		WebsitePhones:  []gmaps.PhoneInfo{{Raw: "+30 210 123 4567", E164: "+302101234567"}, {Raw: "6900000000"}},
+		Owner:          gmaps.Owner{ID: "102769814432182832009"},
+		Images:         []gmaps.Image{{Title: "All", Image: "https://example.com/1.jpg"}},
+		PlaceID:        "ChIJDdnwdv0y5xQRRytw1ihZQeU",
+		KGMID:          "/g/11c54_9hlz",
+		TrackingIDs:    []string{"G-ABC1234", "GTM-XYZ12"},
+		SocialProfiles: map[string]string{"instagram": "@cafe", "facebook": "cafe"},
+		Facebook:       "https://www.facebook.com/cafe",
+		Twitter:        "https://x.com/cafe",
+		Source:         gmaps.StrategyBrowser,
+	}
+
+	require.Len(t, e.ExtendedCsvRow(), len(e.ExtendedCsvHeaders()))
+	require.Equal(t, []string{
+		"ChIJDdnwdv0y5xQRRytw1ihZQeU",
+		"/g/11c54_9hlz",
+		"example.com",
+		"true",
+		"+302101234567, 6900000000",
+		"https://example.com/1.jpg",
+		"G-ABC1234, GTM-XYZ12",
+		"https://www.facebook.com/cafe, https://www.instagram.com/cafe, https://x.com/cafe",
+		"browser",
+	}, e.ExtendedCsvRow())
+
+	var empty gmaps.Entry
+
+	require.Equal(t, []string{"", "", "", "false", "", "", "", "", ""}, empty.ExtendedCsvRow())
+}
+
+func Test_DetectTrackingIDs(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC1234XYZ"></script>
+<script>gtag('config', 'G-ABC1234XYZ'); gtag('config', 'AW-123456789');</script>
+<script>(function(w,d,s,l,i){})(window,document,'script','dataLayer','GTM-5XK2ZQ');</script>
+<script>fbq('init', '1234567890123456'); ga('create', 'UA-12345678-1', 'auto');</script>`)
+
+	require.Equal(t,
+		[]string{"G-ABC1234XYZ", "AW-123456789", "GTM-5XK2ZQ", "FB-1234567890123456", "UA-12345678-1"},
+		gmaps.DetectTrackingIDs(body),
+	)
+	require.Empty(t, gmaps.DetectTrackingIDs([]byte(`<p>Call G-7 or visit GTM-office</p>`)))
+}
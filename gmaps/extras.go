@@ -0,0 +1,113 @@
+package gmaps
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// RequestExtras are headers and cookies added to the requests sent to Google,
+// e.g. pre-obtained consent or NID cookies.
+// They are never sent to the websites of the places.
+type RequestExtras struct {
+	Headers map[string]string
+	Cookies []*http.Cookie
+}
+
+// ParseHeaders parses headers in the "Name: value" format.
+func ParseHeaders(lines []string) (map[string]string, error) {
+	ans := make(map[string]string, len(lines))
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header: %s", line)
+		}
+
+		ans[http.CanonicalHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	return ans, nil
+}
+
+// ParseCookies parses cookies in the format of the Cookie header: "NID=...; CONSENT=YES+".
+func ParseCookies(s string) ([]*http.Cookie, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	cookies, err := http.ParseCookie(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookies: %w", err)
+	}
+
+	return cookies, nil
+}
+
+func (e *RequestExtras) IsZero() bool {
+	return e == nil || (len(e.Headers) == 0 && len(e.Cookies) == 0)
+}
+
+// httpHeaders returns the headers including the cookies for clients
+// that do not share the browser cookie jar.
+func (e *RequestExtras) httpHeaders() map[string]string {
+	ans := maps.Clone(e.Headers)
+	if ans == nil {
+		ans = make(map[string]string)
+	}
+
+	if len(e.Cookies) > 0 {
+		pairs := make([]string, 0, len(e.Cookies))
+		for _, c := range e.Cookies {
+			pairs = append(pairs, c.Name+"="+c.Value)
+		}
+
+		ans["Cookie"] = strings.Join(pairs, "; ")
+	}
+
+	return ans
+}
+
+// applyToPage sets the headers and cookies on the page before it navigates to u.
+// The cookies are scoped to the Google domain of u.
+func (e *RequestExtras) applyToPage(page playwright.Page, u string) error {
+	if e.IsZero() {
+		return nil
+	}
+
+	if len(e.Headers) > 0 {
+		if err := page.SetExtraHTTPHeaders(e.Headers); err != nil {
+			return fmt.Errorf("failed to set headers: %w", err)
+		}
+	}
+
+	if len(e.Cookies) == 0 {
+		return nil
+	}
+
+	domain := "." + strings.TrimPrefix(googleHostFromURL(u), "www.")
+
+	cookies := make([]playwright.OptionalCookie, 0, len(e.Cookies))
+	for _, c := range e.Cookies {
+		cookies = append(cookies, playwright.OptionalCookie{
+			Name:   c.Name,
+			Value:  c.Value,
+			Domain: playwright.String(domain),
+			Path:   playwright.String("/"),
+		})
+	}
+
+	if err := page.Context().AddCookies(cookies); err != nil {
+		return fmt.Errorf("failed to add cookies: %w", err)
+	}
+
+	return nil
+}
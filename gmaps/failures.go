@@ -0,0 +1,192 @@
+package gmaps
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// The classes of the errors of the failures.
+const (
+	// FailureBlocked is a captcha or a block page served by google
+	FailureBlocked = "blocked"
+	// FailureTimeout is a page or a request that took too long
+	FailureTimeout = "timeout"
+	// FailureNetwork is a connection or proxy error
+	FailureNetwork = "network"
+	// FailureParse is a page whose data could not be read
+	FailureParse = "parse"
+	// FailureCanceled is a job stopped by its run
+	FailureCanceled = "canceled"
+	// FailureCircuitOpen is a page of a host whose circuit was open, see
+	// CircuitBreakers
+	FailureCircuitOpen = "circuit_open"
+	// FailureRateLimited is a website page answered with 429 or 503 after
+	// all its retries, see Retryer
+	FailureRateLimited = "rate_limited"
+	// FailureOther is any other error
+	FailureOther = "other"
+)
+
+// Failure is the last failure of a place or an email job.
+type Failure struct {
+	JobID string `json:"job_id"`
+	// InputID is the id of the input, the input_id of the results
+	InputID string `json:"input_id"`
+	// Type is place or email
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Class string `json:"error_class"`
+	Error string `json:"error"`
+	// Attempts is the number of failed attempts of the job
+	Attempts int `json:"attempts"`
+}
+
+// FailureCsvHeaders are the columns of the csv of the failures.
+func FailureCsvHeaders() []string {
+	return []string{"input_id", "job_id", "type", "url", "error_class", "error", "attempts"}
+}
+
+// CsvRow returns the fields of f in the order of FailureCsvHeaders.
+func (f *Failure) CsvRow() []string {
+	return []string{f.InputID, f.JobID, f.Type, f.URL, f.Class, f.Error, strconv.Itoa(f.Attempts)}
+}
+
+// FailureLog keeps the last error of the place and email jobs, so the runner
+// can tell why the jobs that never completed failed. It is safe to share
+// between jobs.
+type FailureLog struct {
+	mu       sync.Mutex
+	failures map[string]*Failure
+}
+
+// NewFailureLog returns an empty failure log.
+func NewFailureLog() *FailureLog {
+	return &FailureLog{failures: make(map[string]*Failure)}
+}
+
+// Record keeps err as the last error of the job id, or forgets the job when
+// err is nil. It does nothing when l is nil.
+func (l *FailureLog) Record(id string, err error) {
+	l.RecordJob(Failure{JobID: id}, err)
+}
+
+// RecordJob is Record for the job f.JobID, keeping the input, type and url
+// of f for Failures. The class of err is the one of ErrorClass unless f has
+// one.
+func (l *FailureLog) RecordJob(f Failure, err error) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err == nil {
+		delete(l.failures, f.JobID)
+
+		return
+	}
+
+	if f.Class == "" {
+		f.Class = ErrorClass(err)
+	}
+
+	f.Error = err.Error()
+	f.Attempts = 1
+
+	if prev, ok := l.failures[f.JobID]; ok {
+		f.Attempts = prev.Attempts + 1
+	}
+
+	l.failures[f.JobID] = &f
+}
+
+// Error returns the last error of the job id, empty when its last attempt
+// did not fail or when l is nil.
+func (l *FailureLog) Error(id string) string {
+	f, ok := l.Failure(id)
+	if !ok {
+		return ""
+	}
+
+	return f.Error
+}
+
+// Failure returns the failure of the job id, false when its last attempt did
+// not fail or when l is nil.
+func (l *FailureLog) Failure(id string) (Failure, bool) {
+	if l == nil {
+		return Failure{}, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[id]
+	if !ok {
+		return Failure{}, false
+	}
+
+	return *f, true
+}
+
+// Failures returns the jobs whose last attempt failed, by input and url. At
+// the end of a run they are the jobs that failed after all their retries.
+func (l *FailureLog) Failures() []Failure {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ans := make([]Failure, 0, len(l.failures))
+
+	for _, f := range l.failures {
+		ans = append(ans, *f)
+	}
+
+	slices.SortFunc(ans, func(a, b Failure) int {
+		return cmp.Or(
+			cmp.Compare(a.InputID, b.InputID),
+			cmp.Compare(a.URL, b.URL),
+			cmp.Compare(a.JobID, b.JobID),
+		)
+	})
+
+	return ans
+}
+
+// ErrorClass returns the class of err: blocked, circuit_open, rate_limited,
+// timeout, network, canceled or other.
+func ErrorClass(err error) string {
+	var netErr net.Error
+
+	msg := strings.ToLower(err.Error())
+
+	switch {
+	case errors.Is(err, ErrBlocked):
+		return FailureBlocked
+	case errors.Is(err, ErrCircuitOpen):
+		return FailureCircuitOpen
+	case errors.Is(err, ErrRateLimited):
+		return FailureRateLimited
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, playwright.ErrTimeout),
+		errors.As(err, &netErr) && netErr.Timeout(), strings.Contains(msg, "timeout"):
+		return FailureTimeout
+	case errors.Is(err, context.Canceled):
+		return FailureCanceled
+	case errors.As(err, &netErr), strings.Contains(msg, "net::err_"), strings.Contains(msg, "proxy"):
+		return FailureNetwork
+	}
+
+	return FailureOther
+}
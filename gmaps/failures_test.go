@@ -0,0 +1,61 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureLog(t *testing.T) {
+	l := NewFailureLog()
+
+	l.Record("a", errors.New("timeout"))
+	l.Record("b", errors.New("blocked"))
+	require.Equal(t, "timeout", l.Error("a"))
+	require.Equal(t, "blocked", l.Error("b"))
+
+	// the last attempt succeeded
+	l.Record("a", nil)
+	require.Empty(t, l.Error("a"))
+	require.Empty(t, l.Error("c"))
+
+	var nilLog *FailureLog
+
+	nilLog.Record("a", errors.New("timeout"))
+	require.Empty(t, nilLog.Error("a"))
+}
+
+func TestFailureLogFailures(t *testing.T) {
+	l := NewFailureLog()
+
+	l.RecordJob(Failure{JobID: "b", InputID: "2", Type: "place", URL: "https://b"}, ErrBlocked)
+	l.RecordJob(Failure{JobID: "a", InputID: "1", Type: "place", URL: "https://a"}, context.DeadlineExceeded)
+	l.RecordJob(Failure{JobID: "a", InputID: "1", Type: "place", URL: "https://a"}, errors.New("could not convert"))
+	l.RecordJob(Failure{JobID: "c", InputID: "1", Type: "place", URL: "https://c", Class: FailureParse}, errors.New("bad json"))
+	l.RecordJob(Failure{JobID: "c"}, nil)
+
+	failures := l.Failures()
+	require.Len(t, failures, 2)
+
+	require.Equal(t, "a", failures[0].JobID)
+	require.Equal(t, 2, failures[0].Attempts)
+	require.Equal(t, FailureOther, failures[0].Class)
+	require.Equal(t, []string{"1", "a", "place", "https://a", "other", "could not convert", "2"}, failures[0].CsvRow())
+
+	require.Equal(t, "b", failures[1].JobID)
+	require.Equal(t, 1, failures[1].Attempts)
+	require.Equal(t, FailureBlocked, failures[1].Class)
+}
+
+func TestErrorClass(t *testing.T) {
+	require.Equal(t, FailureBlocked, ErrorClass(fmt.Errorf("page: %w", ErrBlocked)))
+	require.Equal(t, FailureCircuitOpen, ErrorClass(ErrCircuitOpen))
+	require.Equal(t, FailureTimeout, ErrorClass(context.DeadlineExceeded))
+	require.Equal(t, FailureTimeout, ErrorClass(errors.New("Timeout 30000ms exceeded")))
+	require.Equal(t, FailureCanceled, ErrorClass(context.Canceled))
+	require.Equal(t, FailureNetwork, ErrorClass(errors.New("net::ERR_PROXY_CONNECTION_FAILED")))
+	require.Equal(t, FailureOther, ErrorClass(errors.New("boom")))
+}
@@ -0,0 +1,265 @@
+package gmaps
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+)
+
+// The strategies of a fallback chain, the ways a search is loaded. They are
+// the Source of the entries found.
+const (
+	// StrategyStatic requests the search results of Google without a
+	// browser, as in fast mode
+	StrategyStatic = "static"
+	// StrategyBrowser loads the search and the places in the browser
+	StrategyBrowser = "browser"
+	// StrategyCroxy loads the search page through CroxyProxy
+	StrategyCroxy = "croxy"
+)
+
+// The conditions on which a search falls back to the next step of its chain.
+const (
+	// FallbackEmpty is a search that found no place
+	FallbackEmpty = "empty"
+	// FallbackBlocked is a search blocked by a captcha or an open circuit
+	FallbackBlocked = "blocked"
+	// FallbackParse is a search page that could not be read
+	FallbackParse = "parse"
+	// FallbackError is a search that failed with any other error
+	FallbackError = "error"
+)
+
+// DefaultFallbackOn are the conditions of a chain that sets none.
+var DefaultFallbackOn = []string{FallbackBlocked, FallbackEmpty, FallbackParse}
+
+// FallbackStep is a step of a fallback chain.
+type FallbackStep struct {
+	Strategy string
+	// Retries are the retries of the step before the next one, the ones of
+	// the job when negative
+	Retries int
+}
+
+// FallbackChain is the chain of the strategies a search is loaded with: the
+// first one, then the next one each time a step fails on one of the
+// conditions of On. The zero value is the strategy of the run alone.
+type FallbackChain struct {
+	Steps []FallbackStep
+	On    []string
+}
+
+// ParseFallbackChain parses the comma separated strategies of chain, each
+// one with its retries after a colon when they are not the ones of the job,
+// e.g. static:1,browser:3,croxy. on are the conditions, the DefaultFallbackOn
+// when empty.
+func ParseFallbackChain(chain string, on []string) (FallbackChain, error) {
+	var c FallbackChain
+
+	if strings.TrimSpace(chain) == "" {
+		if len(on) > 0 {
+			return c, errors.New("the fallback conditions need a fallback chain")
+		}
+
+		return c, nil
+	}
+
+	for _, part := range strings.Split(chain, ",") {
+		strategy, retries, hasRetries := strings.Cut(strings.TrimSpace(part), ":")
+
+		step := FallbackStep{Strategy: strings.ToLower(strategy), Retries: -1}
+
+		switch step.Strategy {
+		case StrategyStatic, StrategyBrowser, StrategyCroxy:
+		default:
+			return c, fmt.Errorf("invalid fallback strategy %q: static, browser or croxy", strategy)
+		}
+
+		if c.Has(step.Strategy) {
+			return c, fmt.Errorf("the fallback strategy %s is repeated", step.Strategy)
+		}
+
+		if hasRetries {
+			n, err := strconv.Atoi(retries)
+			if err != nil || n < 0 {
+				return c, fmt.Errorf("invalid retries of the fallback strategy %s: %q", step.Strategy, retries)
+			}
+
+			step.Retries = n
+		}
+
+		c.Steps = append(c.Steps, step)
+	}
+
+	for _, cond := range on {
+		cond = strings.ToLower(strings.TrimSpace(cond))
+
+		switch cond {
+		case FallbackEmpty, FallbackBlocked, FallbackParse, FallbackError:
+		default:
+			return c, fmt.Errorf("invalid fallback condition %q: empty, blocked, parse or error", cond)
+		}
+
+		if !slices.Contains(c.On, cond) {
+			c.On = append(c.On, cond)
+		}
+	}
+
+	if len(c.On) == 0 {
+		c.On = slices.Clone(DefaultFallbackOn)
+	}
+
+	return c, nil
+}
+
+// Has reports whether strategy is a step of c.
+func (c FallbackChain) Has(strategy string) bool {
+	return slices.ContainsFunc(c.Steps, func(s FallbackStep) bool {
+		return s.Strategy == strategy
+	})
+}
+
+// FallbackCondition returns the condition of a search that failed with err,
+// or found places: blocked, error or empty. It is empty when the search
+// found places.
+func FallbackCondition(err error, places int) string {
+	switch {
+	case errors.Is(err, ErrBlocked), errors.Is(err, ErrCircuitOpen):
+		return FallbackBlocked
+	case err != nil:
+		return FallbackError
+	case places == 0:
+		return FallbackEmpty
+	}
+
+	return ""
+}
+
+// FallbackJob returns the next step of the chain of the search job, nil when
+// it is the last one.
+func FallbackJob(job scrapemate.IJob) scrapemate.IJob {
+	f := fallbackOf(job)
+	if f == nil {
+		return nil
+	}
+
+	return f.next
+}
+
+// FallbackOn returns the conditions on which the search job falls back to
+// the next step of its chain.
+func FallbackOn(job scrapemate.IJob) []string {
+	f := fallbackOf(job)
+	if f == nil {
+		return nil
+	}
+
+	return f.on
+}
+
+// SetFallback makes the search job load the search with next, the next step
+// of its chain, when it fails on one of the conditions on.
+func SetFallback(job, next scrapemate.IJob, on []string) {
+	switch j := job.(type) {
+	case *GmapJob:
+		j.fallback = newFallback(next, on)
+	case *SearchJob:
+		j.fallback = newFallback(next, on)
+	case *CroxyProxyJob:
+		j.fallback = newFallback(next, on)
+	}
+}
+
+func fallbackOf(job scrapemate.IJob) *fallback {
+	switch j := job.(type) {
+	case *GmapJob:
+		return j.fallback
+	case *SearchJob:
+		return j.fallback
+	case *CroxyProxyJob:
+		return j.fallback
+	}
+
+	return nil
+}
+
+// fallbackPayload is the encoding of the next step of a chain.
+type fallbackPayload struct {
+	Type    string
+	Payload []byte
+	On      []string
+}
+
+// EncodeFallback encodes the next step of the chain of job with enc, after
+// the job itself, so the chain is not lost when the job is queued or stored.
+// encode returns the payload type and the encoding of the step, the way the
+// caller encodes its jobs, and encodes the rest of the chain in turn. Nothing
+// is encoded for the last step.
+func EncodeFallback(enc *gob.Encoder, job scrapemate.IJob, encode func(scrapemate.IJob) (string, []byte, error)) error {
+	f := fallbackOf(job)
+	if f == nil {
+		return nil
+	}
+
+	typ, data, err := encode(f.next)
+	if err != nil {
+		return fmt.Errorf("failed to encode the fallback: %w", err)
+	}
+
+	return enc.Encode(fallbackPayload{Type: typ, Payload: data, On: f.on})
+}
+
+// DecodeFallback decodes the step encoded by EncodeFallback after job with
+// decode and links it to job. The jobs encoded without a chain, including the
+// ones stored before the chains were, keep none.
+func DecodeFallback(dec *gob.Decoder, job scrapemate.IJob, decode func(string, []byte) (scrapemate.IJob, error)) error {
+	var p fallbackPayload
+
+	err := dec.Decode(&p)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to decode the fallback: %w", err)
+	}
+
+	next, err := decode(p.Type, p.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to decode the fallback: %w", err)
+	}
+
+	SetFallback(job, next, p.On)
+
+	return nil
+}
+
+// fallback is the next step of the chain of a search.
+type fallback struct {
+	next scrapemate.IJob
+	on   []string
+}
+
+func newFallback(next scrapemate.IJob, on []string) *fallback {
+	if next == nil {
+		return nil
+	}
+
+	return &fallback{next: next, on: on}
+}
+
+// job returns the next step when cond is one of the conditions of f, nil
+// otherwise.
+func (f *fallback) job(cond string) scrapemate.IJob {
+	if f == nil || cond == "" || !slices.Contains(f.on, cond) {
+		return nil
+	}
+
+	return f.next
+}
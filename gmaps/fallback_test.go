@@ -0,0 +1,182 @@
+package gmaps
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/tiling"
+)
+
+func TestParseFallbackChain(t *testing.T) {
+	c, err := ParseFallbackChain("static:1, Browser:3,croxy", nil)
+	require.NoError(t, err)
+	require.Equal(t, []FallbackStep{
+		{Strategy: StrategyStatic, Retries: 1},
+		{Strategy: StrategyBrowser, Retries: 3},
+		{Strategy: StrategyCroxy, Retries: -1},
+	}, c.Steps)
+	require.Equal(t, DefaultFallbackOn, c.On)
+	require.True(t, c.Has(StrategyCroxy))
+
+	c, err = ParseFallbackChain("browser,croxy", []string{"error", "Blocked", "error"})
+	require.NoError(t, err)
+	require.Equal(t, []string{FallbackError, FallbackBlocked}, c.On)
+
+	c, err = ParseFallbackChain("", nil)
+	require.NoError(t, err)
+	require.Empty(t, c.Steps)
+	require.False(t, c.Has(StrategyBrowser))
+
+	for _, tc := range []struct {
+		chain string
+		on    []string
+	}{
+		{chain: "http"},
+		{chain: "browser,browser"},
+		{chain: "browser:x"},
+		{chain: "browser:-1"},
+		{chain: "browser", on: []string{"slow"}},
+		{on: []string{"empty"}},
+	} {
+		_, err := ParseFallbackChain(tc.chain, tc.on)
+		require.Error(t, err, tc.chain)
+	}
+}
+
+func TestFallbackCondition(t *testing.T) {
+	require.Equal(t, FallbackBlocked, FallbackCondition(ErrBlocked, 0))
+	require.Equal(t, FallbackBlocked, FallbackCondition(ErrCircuitOpen, 0))
+	require.Equal(t, FallbackError, FallbackCondition(errors.New("timeout"), 0))
+	require.Equal(t, FallbackEmpty, FallbackCondition(nil, 0))
+	require.Empty(t, FallbackCondition(nil, 3))
+}
+
+func TestSearchJobFallback(t *testing.T) {
+	next := NewGmapJob("seed", "en", "cafe", 1, false, "", 0)
+
+	job := NewSearchJob(&MapSearchParams{Query: "cafe"},
+		WithSearchJobFallback(next, []string{FallbackEmpty, FallbackParse}),
+	)
+
+	require.True(t, job.ProcessOnFetchError())
+	require.Same(t, next, FallbackJob(job))
+	require.Nil(t, FallbackJob(next))
+
+	_, jobs, err := job.Process(context.Background(), &scrapemate.Response{Body: []byte(")]}'\n")})
+	require.NoError(t, err)
+	require.Equal(t, []scrapemate.IJob{next}, jobs)
+
+	_, jobs, err = job.Process(context.Background(), &scrapemate.Response{Body: []byte(")]}'\n{not json")})
+	require.NoError(t, err)
+	require.Equal(t, []scrapemate.IJob{next}, jobs)
+
+	// blocked is not one of the conditions
+	_, jobs, err = job.Process(context.Background(), &scrapemate.Response{Error: ErrBlocked})
+	require.ErrorIs(t, err, ErrBlocked)
+	require.Empty(t, jobs)
+
+	last := NewSearchJob(&MapSearchParams{Query: "cafe"})
+
+	require.False(t, last.ProcessOnFetchError())
+
+	_, _, err = last.Process(context.Background(), &scrapemate.Response{Body: []byte(")]}'\n")})
+	require.Error(t, err)
+}
+
+func TestFallbackGob(t *testing.T) {
+	var encode func(scrapemate.IJob) (string, []byte, error)
+
+	encode = func(job scrapemate.IJob) (string, []byte, error) {
+		var buf bytes.Buffer
+
+		enc := gob.NewEncoder(&buf)
+
+		if err := enc.Encode(job); err != nil {
+			return "", nil, err
+		}
+
+		if err := EncodeFallback(enc, job, encode); err != nil {
+			return "", nil, err
+		}
+
+		return fmt.Sprintf("%T", job), buf.Bytes(), nil
+	}
+
+	var decode func(string, []byte) (scrapemate.IJob, error)
+
+	decode = func(typ string, data []byte) (scrapemate.IJob, error) {
+		var job scrapemate.IJob
+
+		switch typ {
+		case "*gmaps.GmapJob":
+			job = new(GmapJob)
+		case "*gmaps.SearchJob":
+			job = new(SearchJob)
+		case "*gmaps.CroxyProxyJob":
+			job = new(CroxyProxyJob)
+		default:
+			return nil, fmt.Errorf("invalid job type: %s", typ)
+		}
+
+		dec := gob.NewDecoder(bytes.NewReader(data))
+
+		if err := dec.Decode(job); err != nil {
+			return nil, err
+		}
+
+		if err := DecodeFallback(dec, job, decode); err != nil {
+			return nil, err
+		}
+
+		return job, nil
+	}
+
+	croxy := NewCroxyProxyJob("seed", "https://www.google.com/maps/search/cafe")
+	browser := NewGmapJob("seed", "en", "cafe", 1, false, "", 0, WithFallback(croxy, DefaultFallbackOn))
+	static := NewSearchJob(&MapSearchParams{Query: "cafe", Hl: "en", Location: MapLocation{Lat: 1, Lon: 2, ZoomLvl: 15}},
+		WithSearchJobFallback(browser, []string{FallbackEmpty}),
+	)
+
+	typ, data, err := encode(static)
+	require.NoError(t, err)
+
+	job, err := decode(typ, data)
+	require.NoError(t, err)
+
+	got, ok := job.(*SearchJob)
+	require.True(t, ok)
+	require.Equal(t, static.params, got.params)
+	require.Equal(t, static.URLParams, got.URLParams)
+	require.Equal(t, []string{FallbackEmpty}, FallbackOn(got))
+
+	gotBrowser, ok := FallbackJob(got).(*GmapJob)
+	require.True(t, ok)
+	require.Equal(t, browser.URL, gotBrowser.URL)
+	require.Equal(t, DefaultFallbackOn, FallbackOn(gotBrowser))
+
+	gotCroxy, ok := FallbackJob(gotBrowser).(*CroxyProxyJob)
+	require.True(t, ok)
+	require.Equal(t, croxy.TargetURL, gotCroxy.TargetURL)
+	require.Nil(t, FallbackJob(gotCroxy))
+
+	// a job without a chain decodes without one
+	_, data, err = encode(croxy)
+	require.NoError(t, err)
+
+	job, err = decode("*gmaps.CroxyProxyJob", data)
+	require.NoError(t, err)
+	require.Nil(t, FallbackJob(job))
+
+	// the searches of the tiles cannot be split once decoded
+	tiled := NewSearchJob(&MapSearchParams{Query: "cafe"}, WithSearchJobTile(tiling.Tile{}, nil))
+
+	_, _, err = encode(tiled)
+	require.Error(t, err)
+}
@@ -0,0 +1,269 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Fingerprint is what a browser context tells the sites about itself.
+type Fingerprint struct {
+	UserAgent string
+	// Platform is navigator.platform, it matches the operating system of the user agent
+	Platform            string
+	Width               int
+	Height              int
+	Locale              string
+	Timezone            string
+	WebGLVendor         string
+	WebGLRenderer       string
+	HardwareConcurrency int
+	DeviceMemory        int
+}
+
+type userAgent struct {
+	ua       string
+	platform string
+	// webgl are the vendor and renderer pairs of the operating system
+	webgl [][2]string
+}
+
+var (
+	windowsWebGL = [][2]string{
+		{"Google Inc. (NVIDIA)", "ANGLE (NVIDIA, NVIDIA GeForce GTX 1660 SUPER Direct3D11 vs_5_0 ps_5_0, D3D11)"},
+		{"Google Inc. (NVIDIA)", "ANGLE (NVIDIA, NVIDIA GeForce RTX 3060 Direct3D11 vs_5_0 ps_5_0, D3D11)"},
+		{"Google Inc. (Intel)", "ANGLE (Intel, Intel(R) UHD Graphics 630 Direct3D11 vs_5_0 ps_5_0, D3D11)"},
+		{"Google Inc. (AMD)", "ANGLE (AMD, AMD Radeon RX 580 Series Direct3D11 vs_5_0 ps_5_0, D3D11)"},
+	}
+
+	macWebGL = [][2]string{
+		{"Google Inc. (Apple)", "ANGLE (Apple, Apple M1, OpenGL 4.1)"},
+		{"Google Inc. (Apple)", "ANGLE (Apple, Apple M2, OpenGL 4.1)"},
+		{"Google Inc. (Intel Inc.)", "ANGLE (Intel Inc., Intel(R) Iris(TM) Plus Graphics 655, OpenGL 4.1)"},
+	}
+
+	userAgents = []userAgent{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36", "Win32", windowsWebGL},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Safari/537.36", "Win32", windowsWebGL},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/134.0.0.0 Safari/537.36", "Win32", windowsWebGL},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36", "MacIntel", macWebGL},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Safari/537.36", "MacIntel", macWebGL},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/134.0.0.0 Safari/537.36", "MacIntel", macWebGL},
+	}
+
+	viewports = [][2]int{
+		{1920, 1080},
+		{1536, 864},
+		{1440, 900},
+		{1366, 768},
+		{1680, 1050},
+		{2560, 1440},
+	}
+
+	hardwareConcurrency = []int{4, 8, 12, 16}
+	deviceMemory        = []int{4, 8, 16}
+)
+
+// FingerprintRotator gives every browser context a random fingerprint and
+// applies it to the pages of the context, so all the pages of a context
+// look like the same browser. It is safe to share between jobs.
+type FingerprintRotator struct {
+	locale    string
+	timezones []string
+
+	mu       sync.Mutex
+	contexts map[playwright.BrowserContext]*Fingerprint
+	pages    map[playwright.Page]struct{}
+}
+
+// NewFingerprintRotator returns a rotator of fingerprints with the locale,
+// e.g. en or de-DE. The timezones, IANA names like Europe/Berlin, are picked
+// at random; the timezone of the browser is kept when there are none.
+func NewFingerprintRotator(locale string, timezones []string) *FingerprintRotator {
+	return &FingerprintRotator{
+		locale:    locale,
+		timezones: timezones,
+		contexts:  make(map[playwright.BrowserContext]*Fingerprint),
+		pages:     make(map[playwright.Page]struct{}),
+	}
+}
+
+// Random returns a new fingerprint.
+func (r *FingerprintRotator) Random() Fingerprint {
+	ua := userAgents[rand.IntN(len(userAgents))]
+	webgl := ua.webgl[rand.IntN(len(ua.webgl))]
+	viewport := viewports[rand.IntN(len(viewports))]
+
+	ans := Fingerprint{
+		UserAgent:           ua.ua,
+		Platform:            ua.platform,
+		Width:               viewport[0],
+		Height:              viewport[1],
+		Locale:              r.locale,
+		WebGLVendor:         webgl[0],
+		WebGLRenderer:       webgl[1],
+		HardwareConcurrency: hardwareConcurrency[rand.IntN(len(hardwareConcurrency))],
+		DeviceMemory:        deviceMemory[rand.IntN(len(deviceMemory))],
+	}
+
+	if len(r.timezones) > 0 {
+		ans.Timezone = r.timezones[rand.IntN(len(r.timezones))]
+	}
+
+	return ans
+}
+
+// Of returns the fingerprint of the browser context, false when the context
+// has none or r is nil.
+func (r *FingerprintRotator) Of(bctx playwright.BrowserContext) (Fingerprint, bool) {
+	if r == nil {
+		return Fingerprint{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fp, ok := r.contexts[bctx]
+	if !ok {
+		return Fingerprint{}, false
+	}
+
+	return *fp, true
+}
+
+// Apply gives the page the fingerprint of its browser context. Only the
+// first call for a page changes it. It does nothing when r is nil.
+func (r *FingerprintRotator) Apply(page playwright.Page) error {
+	if r == nil {
+		return nil
+	}
+
+	bctx := page.Context()
+	if bctx == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+
+	if _, ok := r.pages[page]; ok {
+		r.mu.Unlock()
+
+		return nil
+	}
+
+	fp, ok := r.contexts[bctx]
+	if !ok {
+		random := r.Random()
+		fp = &random
+		r.contexts[bctx] = fp
+
+		bctx.OnClose(func(c playwright.BrowserContext) {
+			r.mu.Lock()
+			delete(r.contexts, c)
+			r.mu.Unlock()
+		})
+	}
+
+	r.pages[page] = struct{}{}
+
+	r.mu.Unlock()
+
+	page.OnClose(func(p playwright.Page) {
+		r.mu.Lock()
+		delete(r.pages, p)
+		r.mu.Unlock()
+	})
+
+	return applyFingerprint(page, fp)
+}
+
+func applyFingerprint(page playwright.Page, fp *Fingerprint) error {
+	if err := page.SetViewportSize(fp.Width, fp.Height); err != nil {
+		return fmt.Errorf("failed to set the viewport: %w", err)
+	}
+
+	script, err := fingerprintScript(fp)
+	if err != nil {
+		return err
+	}
+
+	if err := page.AddInitScript(playwright.Script{Content: &script}); err != nil {
+		return fmt.Errorf("failed to add the fingerprint script: %w", err)
+	}
+
+	// the user agent, the locale and the timezone are set for the requests
+	// too, which only chromium can change once the context exists
+	cdp, err := page.Context().NewCDPSession(page)
+	if err != nil {
+		return page.SetExtraHTTPHeaders(map[string]string{
+			"User-Agent":      fp.UserAgent,
+			"Accept-Language": fp.Locale,
+		})
+	}
+
+	defer func() {
+		_ = cdp.Detach()
+	}()
+
+	_, err = cdp.Send("Emulation.setUserAgentOverride", map[string]any{
+		"userAgent":      fp.UserAgent,
+		"acceptLanguage": fp.Locale,
+		"platform":       fp.Platform,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set the user agent: %w", err)
+	}
+
+	if fp.Locale != "" {
+		if _, err := cdp.Send("Emulation.setLocaleOverride", map[string]any{"locale": fp.Locale}); err != nil {
+			return fmt.Errorf("failed to set the locale: %w", err)
+		}
+	}
+
+	if fp.Timezone != "" {
+		if _, err := cdp.Send("Emulation.setTimezoneOverride", map[string]any{"timezoneId": fp.Timezone}); err != nil {
+			return fmt.Errorf("failed to set the timezone: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// fingerprintScript overrides the navigator and WebGL properties that the
+// user agent override does not change.
+func fingerprintScript(fp *Fingerprint) (string, error) {
+	params, err := json.Marshal(map[string]any{
+		"platform":            fp.Platform,
+		"hardwareConcurrency": fp.HardwareConcurrency,
+		"deviceMemory":        fp.DeviceMemory,
+		"vendor":              fp.WebGLVendor,
+		"renderer":            fp.WebGLRenderer,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return `(() => {
+	const fp = ` + string(params) + `;
+	const define = (obj, prop, value) => Object.defineProperty(obj, prop, { get: () => value, configurable: true });
+
+	define(Navigator.prototype, 'platform', fp.platform);
+	define(Navigator.prototype, 'hardwareConcurrency', fp.hardwareConcurrency);
+	define(Navigator.prototype, 'deviceMemory', fp.deviceMemory);
+	define(Navigator.prototype, 'webdriver', false);
+
+	// UNMASKED_VENDOR_WEBGL and UNMASKED_RENDERER_WEBGL of WEBGL_debug_renderer_info
+	for (const ctx of [WebGLRenderingContext, typeof WebGL2RenderingContext === 'undefined' ? null : WebGL2RenderingContext]) {
+		if (!ctx) continue;
+		const getParameter = ctx.prototype.getParameter;
+		ctx.prototype.getParameter = function (p) {
+			if (p === 37445) return fp.vendor;
+			if (p === 37446) return fp.renderer;
+			return getParameter.call(this, p);
+		};
+	}
+})();`, nil
+}
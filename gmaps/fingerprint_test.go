@@ -0,0 +1,34 @@
+package gmaps_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_FingerprintRotatorRandom(t *testing.T) {
+	r := gmaps.NewFingerprintRotator("de", []string{"Europe/Berlin"})
+
+	for range 50 {
+		fp := r.Random()
+
+		require.Equal(t, "de", fp.Locale)
+		require.Equal(t, "Europe/Berlin", fp.Timezone)
+		require.Positive(t, fp.Width)
+		require.Positive(t, fp.Height)
+
+		// the platform and the WebGL renderer match the operating system of the user agent
+		if strings.Contains(fp.UserAgent, "Windows") {
+			require.Equal(t, "Win32", fp.Platform)
+			require.Contains(t, fp.WebGLRenderer, "Direct3D11")
+		} else {
+			require.Equal(t, "MacIntel", fp.Platform)
+			require.Contains(t, fp.WebGLRenderer, "OpenGL")
+		}
+	}
+
+	require.Empty(t, gmaps.NewFingerprintRotator("en", nil).Random().Timezone)
+}
@@ -0,0 +1,40 @@
+package gmaps
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes the point as a geohash of the given precision.
+// A precision of 7 is a cell of about 150x150 meters.
+func Geohash(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	ans := make([]byte, 0, precision)
+	even := true
+	bit, ch := 0, 0
+
+	for len(ans) < precision {
+		rng, v := &latRange, lat
+		if even {
+			rng, v = &lonRange, lon
+		}
+
+		mid := (rng[0] + rng[1]) / 2
+		if v >= mid {
+			ch |= 1 << (4 - bit)
+			rng[0] = mid
+		} else {
+			rng[1] = mid
+		}
+
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			ans = append(ans, geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return string(ans)
+}
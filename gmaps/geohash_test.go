@@ -0,0 +1,19 @@
+package gmaps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeohash(t *testing.T) {
+	require.Equal(t, "u4pruydqqvj", Geohash(57.64911, 10.40744, 11))
+	require.Equal(t, "u4pruyd", Geohash(57.64911, 10.40744, 7))
+	require.Equal(t, "s0000", Geohash(0, 0, 5))
+	require.Equal(t, "pbpbp", Geohash(-90, 180, 5))
+	require.Empty(t, Geohash(10, 10, 0))
+
+	// close points share the cell, distant ones do not
+	require.Equal(t, Geohash(37.98381, 23.72754, 7), Geohash(37.98385, 23.72750, 7))
+	require.NotEqual(t, Geohash(37.98381, 23.72754, 7), Geohash(38.2466, 21.7346, 7))
+}
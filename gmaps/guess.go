@@ -0,0 +1,209 @@
+package gmaps
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/gosom/google-maps-scraper/emailverify"
+)
+
+// The sources of the emails of EmailDetail.
+const (
+	// EmailSourceWebsite is an email found on the website of the place
+	EmailSourceWebsite = "website"
+	// EmailSourceGuessed is an email made of a common pattern and accepted by the mail server of the domain
+	EmailSourceGuessed = "guessed"
+)
+
+// The confidence of the guessed emails.
+const (
+	// guessedConfidence is the confidence of a guessed email the mail server accepted
+	guessedConfidence = 0.8
+	// catchAllConfidence is the confidence of the first guess on a domain that accepts any address
+	catchAllConfidence = 0.3
+)
+
+// guessPatterns are the local parts of the guessed emails, the most common first.
+var guessPatterns = []string{"info", "contact", "hello", "office", "mail"}
+
+// EmailDetail is an email of a place and where it comes from.
+type EmailDetail struct {
+	Email  string `json:"email"`
+	Source string `json:"source"`
+	// Confidence is 1 for the emails found on the website, between 0 and 1 for the guessed ones
+	Confidence float64 `json:"confidence"`
+	// Reachable is the answer of the mail server of a guessed email, see emailverify.Result
+	Reachable string `json:"reachable,omitempty"`
+	// Type is disposable, role or personal, see emailverify.Classify
+	Type string `json:"type"`
+}
+
+// WebsiteEmailDetails returns the details of emails found on the website.
+func WebsiteEmailDetails(emails []string) []EmailDetail {
+	var ans []EmailDetail
+
+	for _, email := range emails {
+		ans = append(ans, EmailDetail{
+			Email:      email,
+			Source:     EmailSourceWebsite,
+			Confidence: 1,
+			Type:       emailverify.Classify(email),
+		})
+	}
+
+	return ans
+}
+
+func emailDetailsString(details []EmailDetail) string {
+	if len(details) == 0 {
+		return ""
+	}
+
+	d, _ := json.Marshal(details)
+
+	return string(d)
+}
+
+// FilterEmails keeps the emails of e whose type is one of types, see
+// emailverify.Classify. It does nothing when types is empty.
+func (e *Entry) FilterEmails(types []string) {
+	if len(types) == 0 {
+		return
+	}
+
+	keep := func(email string) bool {
+		return slices.Contains(types, emailverify.Classify(email))
+	}
+
+	e.Emails = slices.DeleteFunc(e.Emails, func(email string) bool {
+		return !keep(email)
+	})
+
+	e.EmailDetails = slices.DeleteFunc(e.EmailDetails, func(d EmailDetail) bool {
+		return !keep(d.Email)
+	})
+
+	e.EmailStatuses = slices.DeleteFunc(e.EmailStatuses, func(r emailverify.Result) bool {
+		return !keep(r.Email)
+	})
+}
+
+// EmailGuesser guesses the emails of the places whose website has none from
+// common patterns, info@ or first.last@ of the owner, and keeps the ones the
+// mail server of the domain of the website accepts. It is safe to share
+// between jobs.
+type EmailGuesser struct {
+	verifier *emailverify.Verifier
+}
+
+// NewEmailGuesser returns a guesser that verifies the guesses with v. It
+// returns nil, which guesses nothing, when v is nil.
+func NewEmailGuesser(v *emailverify.Verifier) *EmailGuesser {
+	if v == nil {
+		return nil
+	}
+
+	return &EmailGuesser{verifier: v}
+}
+
+// Guess returns the guessed emails of e the mail server accepted. When the
+// server accepts any address only the first pattern is returned, with a low
+// confidence.
+func (g *EmailGuesser) Guess(ctx context.Context, e *Entry) []EmailDetail {
+	if g == nil {
+		return nil
+	}
+
+	domain := emailDomain(e.WebSite)
+	if domain == "" {
+		return nil
+	}
+
+	locals := GuessLocalParts(e.Owner.Name, e.Title)
+
+	var ans []EmailDetail
+
+	for _, r := range g.verifier.VerifyDomain(ctx, domain, locals) {
+		switch {
+		case r.Reachable == emailverify.ReachableYes:
+			ans = append(ans, EmailDetail{
+				Email:      r.Email,
+				Source:     EmailSourceGuessed,
+				Confidence: guessedConfidence,
+				Reachable:  r.Reachable,
+				Type:       emailverify.Classify(r.Email),
+			})
+		case r.CatchAll && len(ans) == 0:
+			return []EmailDetail{{
+				Email:      r.Email,
+				Source:     EmailSourceGuessed,
+				Confidence: catchAllConfidence,
+				Reachable:  r.Reachable,
+				Type:       emailverify.Classify(r.Email),
+			}}
+		}
+	}
+
+	return ans
+}
+
+// GuessLocalParts returns the local parts guessed for a place: the common
+// patterns, then first.last and first of the owner when the owner looks like
+// a person rather than the business itself.
+func GuessLocalParts(owner, title string) []string {
+	locals := append([]string(nil), guessPatterns...)
+
+	if strings.EqualFold(strings.TrimSpace(owner), strings.TrimSpace(title)) {
+		return locals
+	}
+
+	names := strings.Fields(owner)
+	if len(names) < 2 || len(names) > 3 {
+		return locals
+	}
+
+	for i := range names {
+		names[i] = asciiName(names[i])
+		if names[i] == "" {
+			return locals
+		}
+	}
+
+	first, last := names[0], names[len(names)-1]
+
+	return append(locals, first+"."+last, first)
+}
+
+// asciiName returns name in lower case without accents, empty when it has
+// other characters than letters.
+func asciiName(name string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r)
+		default:
+			return ""
+		}
+	}
+
+	return b.String()
+}
+
+// emailDomain returns the domain of the emails of website, its host without www.
+func emailDomain(website string) string {
+	u, err := url.Parse(website)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,51 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/emailverify"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_GuessLocalParts(t *testing.T) {
+	t.Parallel()
+
+	common := []string{"info", "contact", "hello", "office", "mail"}
+
+	require.Equal(t, append(common, "jose.garcia", "jose"), gmaps.GuessLocalParts("José García", "Café Central"))
+	require.Equal(t, append(common, "anna.berg", "anna"), gmaps.GuessLocalParts("Anna Maria Berg", "Berg Bakery"))
+	require.Equal(t, common, gmaps.GuessLocalParts("Café Central", "Café Central"))
+	require.Equal(t, common, gmaps.GuessLocalParts("Central", "Café Central"))
+	require.Equal(t, common, gmaps.GuessLocalParts("Bakery & Co", "Berg Bakery"))
+	require.Equal(t, common, gmaps.GuessLocalParts("", "Berg Bakery"))
+}
+
+func Test_WebsiteEmailDetails(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, gmaps.WebsiteEmailDetails(nil))
+	require.Equal(t, []gmaps.EmailDetail{
+		{Email: "info@example.com", Source: gmaps.EmailSourceWebsite, Confidence: 1, Type: emailverify.TypeRole},
+	}, gmaps.WebsiteEmailDetails([]string{"info@example.com"}))
+}
+
+func Test_FilterEmails(t *testing.T) {
+	t.Parallel()
+
+	emails := []string{"info@example.com", "maria.rossi@example.com", "deals@mailinator.com"}
+
+	e := gmaps.Entry{
+		Emails:       emails,
+		EmailDetails: gmaps.WebsiteEmailDetails(emails),
+	}
+
+	e.FilterEmails(nil)
+	require.Len(t, e.Emails, 3)
+
+	e.FilterEmails([]string{emailverify.TypePersonal, emailverify.TypeRole})
+	require.Equal(t, []string{"info@example.com", "maria.rossi@example.com"}, e.Emails)
+	require.Len(t, e.EmailDetails, 2)
+	require.Equal(t, emailverify.TypePersonal, e.EmailDetails[1].Type)
+}
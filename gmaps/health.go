@@ -0,0 +1,177 @@
+package gmaps
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+)
+
+// WebsiteHealth is how the website of a place answered, to spot the dead,
+// redirected, expiring or parked domains.
+type WebsiteHealth struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	FinalURL   string `json:"final_url,omitempty"`
+	// Redirects are the urls redirected to the final url, the website first
+	Redirects []string `json:"redirects,omitempty"`
+	// TLSIssuer and TLSExpiresAt are empty for the websites without https
+	TLSIssuer    string `json:"tls_issuer,omitempty"`
+	TLSExpiresAt string `json:"tls_expires_at,omitempty"`
+	// PageSize is the size of the homepage in bytes
+	PageSize int `json:"page_size"`
+	// Parked is true when the domain shows the page of a registrar or a parking service
+	Parked bool `json:"parked"`
+	// Error is why the website could not be loaded, e.g. a domain that does not resolve
+	Error string `json:"error,omitempty"`
+}
+
+// parkingHosts are the hosts parked domains redirect to or load their page from.
+var parkingHosts = []string{
+	"sedoparking.com",
+	"parkingcrew.net",
+	"bodis.com",
+	"above.com",
+	"dan.com",
+	"afternic.com",
+	"hugedomains.com",
+	"parklogic.com",
+	"domainmarket.com",
+	"undeveloped.com",
+}
+
+// parkingTexts are the lowercase texts of the pages of parked domains.
+var parkingTexts = []string{
+	"this domain is for sale",
+	"this domain may be for sale",
+	"buy this domain",
+	"domain is parked",
+	"this domain has been registered",
+	"domain name is for sale",
+	"future home of something quite cool",
+}
+
+// IsParked reports whether a website whose homepage, at finalURL, is body
+// is a parked domain.
+func IsParked(finalURL string, body []byte) bool {
+	if u, err := url.Parse(finalURL); err == nil {
+		host := strings.ToLower(u.Hostname())
+
+		if slices.ContainsFunc(parkingHosts, func(h string) bool {
+			return host == h || strings.HasSuffix(host, "."+h)
+		}) {
+			return true
+		}
+	}
+
+	page := bytes.ToLower(body)
+
+	for _, s := range parkingHosts {
+		if bytes.Contains(page, []byte("//"+s)) || bytes.Contains(page, []byte("."+s+"/")) {
+			return true
+		}
+	}
+
+	for _, s := range parkingTexts {
+		if bytes.Contains(page, []byte(s)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// gotoWebsite loads the website like scrapemate.Job.BrowserActions and keeps
+// its health in the meta of the response.
+func (j *EmailExtractJob) gotoWebsite(page playwright.Page) scrapemate.Response {
+	resp := scrapemate.Response{Meta: make(map[string]any)}
+
+	pageResponse, err := page.Goto(j.GetFullURL(), playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateNetworkidle,
+	})
+	if err != nil {
+		resp.Error = err
+		resp.Meta["health"] = &WebsiteHealth{Error: err.Error()}
+
+		return resp
+	}
+
+	if pageResponse == nil {
+		return resp
+	}
+
+	resp.URL = pageResponse.URL()
+	resp.StatusCode = pageResponse.Status()
+	resp.Headers = make(http.Header, len(pageResponse.Headers()))
+
+	for k, v := range pageResponse.Headers() {
+		resp.Headers.Add(k, v)
+	}
+
+	health := &WebsiteHealth{
+		StatusCode: resp.StatusCode,
+		FinalURL:   resp.URL,
+		Redirects:  redirectChain(pageResponse.Request()),
+	}
+
+	resp.Meta["health"] = health
+
+	if details, err := pageResponse.SecurityDetails(); err == nil && details != nil {
+		if details.Issuer != nil {
+			health.TLSIssuer = *details.Issuer
+		}
+
+		if details.ValidTo != nil {
+			health.TLSExpiresAt = time.Unix(int64(*details.ValidTo), 0).UTC().Format(time.RFC3339)
+		}
+	}
+
+	if err := CheckRateLimit(j.GetURL(), resp.StatusCode, resp.Headers); err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	body, err := pageResponse.Body()
+	if err != nil {
+		resp.Error = err
+		health.Error = err.Error()
+
+		return resp
+	}
+
+	resp.Body = body
+
+	health.PageSize = len(body)
+	health.Parked = IsParked(resp.URL, body)
+
+	return resp
+}
+
+// redirectChain returns the urls redirected to the url of req, the first one first.
+func redirectChain(req playwright.Request) []string {
+	var ans []string
+
+	for r := req.RedirectedFrom(); r != nil; r = r.RedirectedFrom() {
+		ans = append(ans, r.URL())
+	}
+
+	slices.Reverse(ans)
+
+	return ans
+}
+
+func websiteHealthString(h *WebsiteHealth) string {
+	if h == nil {
+		return ""
+	}
+
+	d, _ := json.Marshal(h)
+
+	return string(d)
+}
@@ -0,0 +1,19 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_IsParked(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, gmaps.IsParked("https://www.hugedomains.com/domain_profile.cfm?d=example.com", nil))
+	require.True(t, gmaps.IsParked("https://example.com/", []byte(`<h1>This domain is for sale!</h1>`)))
+	require.True(t, gmaps.IsParked("https://example.com/", []byte(`<script src="https://www.sedoparking.com/frmpark/example.com/IONOSParkingUS/park.js"></script>`)))
+	require.False(t, gmaps.IsParked("https://example.com/", []byte(`<h1>Kipriakon tavern</h1>`)))
+	require.False(t, gmaps.IsParked("https://notdan.com/", nil))
+}
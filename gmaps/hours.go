@@ -0,0 +1,236 @@
+package gmaps
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // the docker image does not ship zoneinfo
+)
+
+const minutesPerDay = 24 * 60
+
+type hoursRange struct {
+	start int // minutes since midnight
+	end   int // minutes since midnight, may exceed minutesPerDay when overnight
+}
+
+// ComputeOpenNow sets OpenNow and NextOpenTime using the entry's timezone
+// and structured opening hours. The reference time is converted to the
+// local time of the place before the comparison.
+// When the timezone or the hours cannot be parsed the fields are left untouched.
+func (e *Entry) ComputeOpenNow(now time.Time) {
+	if e.Timezone == "" || len(e.OpenHours) == 0 {
+		return
+	}
+
+	loc, err := time.LoadLocation(e.Timezone)
+	if err != nil {
+		return
+	}
+
+	week, ok := parseWeeklyHours(e.OpenHours)
+	if !ok {
+		return
+	}
+
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	nowMin := local.Hour()*60 + local.Minute()
+
+	// ranges of the previous day may spill over midnight
+	prev := week[(local.Weekday()+6)%7]
+	for _, r := range prev {
+		if r.end > minutesPerDay && nowMin < r.end-minutesPerDay {
+			e.OpenNow = true
+			e.NextOpenTime = ""
+
+			return
+		}
+	}
+
+	for _, r := range week[local.Weekday()] {
+		if nowMin >= r.start && nowMin < r.end {
+			e.OpenNow = true
+			e.NextOpenTime = ""
+
+			return
+		}
+	}
+
+	e.OpenNow = false
+
+	for offset := 0; offset <= 7; offset++ {
+		day := (int(local.Weekday()) + offset) % 7
+
+		for _, r := range week[day] {
+			if offset == 0 && r.start <= nowMin {
+				continue
+			}
+
+			next := midnight.AddDate(0, 0, offset).Add(time.Duration(r.start) * time.Minute)
+			e.NextOpenTime = next.Format(time.RFC3339)
+
+			return
+		}
+	}
+}
+
+// parseWeeklyHours converts the open hours map into ranges indexed by time.Weekday.
+// It returns false when none of the days could be understood.
+func parseWeeklyHours(openHours map[string][]string) ([7][]hoursRange, bool) {
+	var (
+		week   [7][]hoursRange
+		parsed bool
+	)
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		values, ok := openHours[day.String()]
+		if !ok {
+			continue
+		}
+
+		for _, v := range values {
+			ranges, ok := parseHoursRange(v)
+			if !ok {
+				continue
+			}
+
+			parsed = true
+
+			week[day] = append(week[day], ranges...)
+		}
+	}
+
+	return week, parsed
+}
+
+// parseHoursRange parses values like "9 am–5 pm", "12:30–10 pm", "09:00–17:00",
+// "Open 24 hours" or "Closed". A closed day is reported as parsed with no ranges.
+func parseHoursRange(s string) ([]hoursRange, bool) {
+	s = normalizeHoursString(s)
+
+	switch {
+	case s == "":
+		return nil, false
+	case strings.Contains(s, "closed"):
+		return nil, true
+	case strings.Contains(s, "24 hours"):
+		return []hoursRange{{start: 0, end: minutesPerDay}}, true
+	}
+
+	var ans []hoursRange
+
+	for _, part := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, false
+		}
+
+		start, startSuffix, ok := parseClock(from)
+		if !ok {
+			return nil, false
+		}
+
+		end, endSuffix, ok := parseClock(to)
+		if !ok {
+			return nil, false
+		}
+
+		end = applyMeridiem(end, endSuffix)
+
+		switch {
+		case startSuffix != "":
+			start = applyMeridiem(start, startSuffix)
+		case endSuffix != "":
+			// "12:30–10 pm" shares the suffix unless that makes the range inverted
+			start = applyMeridiem(start, endSuffix)
+			if start >= end {
+				start = applyMeridiem(start%(12*60), oppositeMeridiem(endSuffix))
+			}
+		}
+
+		if end <= start {
+			end += minutesPerDay
+		}
+
+		ans = append(ans, hoursRange{start: start, end: end})
+	}
+
+	return ans, true
+}
+
+func normalizeHoursString(s string) string {
+	s = strings.ToLower(s)
+
+	r := strings.NewReplacer(
+		"\u202f", " ",
+		"\u00a0", " ",
+		"\u2013", "-",
+		"\u2014", "-",
+		"a.m.", "am",
+		"p.m.", "pm",
+	)
+
+	return strings.TrimSpace(r.Replace(s))
+}
+
+// parseClock parses "9", "9 am", "12:30", "17:00" returning the minutes
+// since midnight (without meridiem applied) and the meridiem suffix if any.
+func parseClock(s string) (int, string, bool) {
+	s = strings.TrimSpace(s)
+
+	var suffix string
+
+	for _, m := range []string{"am", "pm"} {
+		if strings.HasSuffix(s, m) {
+			suffix = m
+			s = strings.TrimSpace(strings.TrimSuffix(s, m))
+
+			break
+		}
+	}
+
+	hh, mm, _ := strings.Cut(s, ":")
+
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 24 {
+		return 0, "", false
+	}
+
+	var m int
+
+	if mm != "" {
+		m, err = strconv.Atoi(mm)
+		if err != nil || m < 0 || m > 59 {
+			return 0, "", false
+		}
+	}
+
+	return h*60 + m, suffix, true
+}
+
+func applyMeridiem(minutes int, suffix string) int {
+	h := minutes / 60
+	m := minutes % 60
+
+	switch suffix {
+	case "am":
+		if h == 12 {
+			h = 0
+		}
+	case "pm":
+		if h < 12 {
+			h += 12
+		}
+	}
+
+	return h*60 + m
+}
+
+func oppositeMeridiem(suffix string) string {
+	if suffix == "pm" {
+		return "am"
+	}
+
+	return "pm"
+}
@@ -0,0 +1,135 @@
+package gmaps_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_ComputeOpenNow(t *testing.T) {
+	hours := map[string][]string{
+		"Monday":    {"Closed"},
+		"Tuesday":   {"12:30 –10 pm"},
+		"Wednesday": {"9 am–5 pm"},
+		"Thursday":  {"6 pm–2 am"},
+		"Friday":    {"Open 24 hours"},
+		"Saturday":  {"Closed"},
+		"Sunday":    {"Closed"},
+	}
+
+	testCases := []struct {
+		name     string
+		now      string
+		openNow  bool
+		nextOpen string
+	}{
+		{
+			name:     "closed day",
+			now:      "2024-01-08T10:00:00+02:00", // Monday
+			openNow:  false,
+			nextOpen: "2024-01-09T12:30:00+02:00",
+		},
+		{
+			name:    "shared meridiem",
+			now:     "2024-01-09T21:59:00+02:00", // Tuesday
+			openNow: true,
+		},
+		{
+			name:     "before opening",
+			now:      "2024-01-10T08:00:00+02:00", // Wednesday
+			openNow:  false,
+			nextOpen: "2024-01-10T09:00:00+02:00",
+		},
+		{
+			name:    "overnight range",
+			now:     "2024-01-12T01:30:00+02:00", // Friday, Thursday's range spills over
+			openNow: true,
+		},
+		{
+			name:     "wraps to next week",
+			now:      "2024-01-13T12:00:00+02:00", // Saturday
+			openNow:  false,
+			nextOpen: "2024-01-16T12:30:00+02:00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tc.now)
+			require.NoError(t, err)
+
+			entry := gmaps.Entry{
+				Timezone:  "Asia/Nicosia",
+				OpenHours: hours,
+			}
+
+			entry.ComputeOpenNow(now.UTC())
+
+			require.Equal(t, tc.openNow, entry.OpenNow)
+			require.Equal(t, tc.nextOpen, entry.NextOpenTime)
+		})
+	}
+}
+
+func Test_ComputeOpenNow_OvernightBeforeClosedDay(t *testing.T) {
+	hours := map[string][]string{
+		"Monday":    {"Closed"},
+		"Tuesday":   {"Closed"},
+		"Wednesday": {"Closed"},
+		"Thursday":  {"Closed"},
+		"Friday":    {"Closed"},
+		"Saturday":  {"10 pm–3 am"},
+		"Sunday":    {"Closed"},
+	}
+
+	testCases := []struct {
+		name     string
+		now      string
+		openNow  bool
+		nextOpen string
+	}{
+		{
+			name:    "spillover into a closed day",
+			now:     "2024-01-14T02:00:00+02:00", // Sunday, only Saturday's range is open
+			openNow: true,
+		},
+		{
+			name:     "after the spillover",
+			now:      "2024-01-14T03:30:00+02:00", // Sunday
+			openNow:  false,
+			nextOpen: "2024-01-20T22:00:00+02:00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tc.now)
+			require.NoError(t, err)
+
+			entry := gmaps.Entry{
+				Timezone:  "Asia/Nicosia",
+				OpenHours: hours,
+			}
+
+			entry.ComputeOpenNow(now.UTC())
+
+			require.Equal(t, tc.openNow, entry.OpenNow)
+			require.Equal(t, tc.nextOpen, entry.NextOpenTime)
+		})
+	}
+}
+
+func Test_ComputeOpenNow_UnknownTimezone(t *testing.T) {
+	entry := gmaps.Entry{
+		Timezone:  "Not/AZone",
+		OpenHours: map[string][]string{"Monday": {"9 am–5 pm"}},
+	}
+
+	entry.ComputeOpenNow(time.Now())
+
+	require.False(t, entry.OpenNow)
+	require.Empty(t, entry.NextOpenTime)
+}
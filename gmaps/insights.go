@@ -0,0 +1,217 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// reviewKeywords is the number of keywords kept in the review insights
+const reviewKeywords = 10
+
+// ReviewInsights are statistics of the reviews of a place, see
+// Entry.ComputeReviewInsights.
+type ReviewInsights struct {
+	// Reviews is the number of reviews the insights are computed from
+	Reviews   int             `json:"reviews"`
+	Sentiment ReviewSentiment `json:"sentiment"`
+	Keywords  []KeywordCount  `json:"keywords"`
+	// RatingByYear is the average rating of the reviews of each year
+	RatingByYear map[string]float64 `json:"rating_by_year"`
+}
+
+// ReviewSentiment is the number of positive, neutral and negative reviews.
+type ReviewSentiment struct {
+	Positive int `json:"positive"`
+	Neutral  int `json:"neutral"`
+	Negative int `json:"negative"`
+}
+
+// KeywordCount is the number of reviews a keyword appears in.
+type KeywordCount struct {
+	Keyword string `json:"keyword"`
+	Count   int    `json:"count"`
+}
+
+// ComputeReviewInsights sets ReviewInsights from the extended reviews, or
+// the reviews of the place page without them. It leaves it nil when the
+// place has no reviews.
+func (e *Entry) ComputeReviewInsights() {
+	reviews := e.UserReviewsExtended
+	if len(reviews) == 0 {
+		reviews = e.UserReviews
+	}
+
+	if len(reviews) == 0 {
+		e.ReviewInsights = nil
+
+		return
+	}
+
+	ans := ReviewInsights{
+		Reviews:      len(reviews),
+		Keywords:     []KeywordCount{},
+		RatingByYear: map[string]float64{},
+	}
+
+	keywords := map[string]int{}
+	ratings := map[string][]int{}
+
+	for i := range reviews {
+		words := reviewWords(reviews[i].Description)
+
+		switch reviewSentiment(words, reviews[i].Rating) {
+		case 1:
+			ans.Sentiment.Positive++
+		case -1:
+			ans.Sentiment.Negative++
+		default:
+			ans.Sentiment.Neutral++
+		}
+
+		seen := map[string]bool{}
+
+		for _, w := range words {
+			if seen[w] || len([]rune(w)) < 3 || stopWords[w] {
+				continue
+			}
+
+			seen[w] = true
+			keywords[w]++
+		}
+
+		// When is year-month-day
+		if year, _, ok := strings.Cut(reviews[i].When, "-"); ok && reviews[i].Rating > 0 {
+			ratings[year] = append(ratings[year], reviews[i].Rating)
+		}
+	}
+
+	for w, n := range keywords {
+		// a word of a single review is not a topic
+		if n > 1 || len(reviews) == 1 {
+			ans.Keywords = append(ans.Keywords, KeywordCount{Keyword: w, Count: n})
+		}
+	}
+
+	sort.Slice(ans.Keywords, func(i, j int) bool {
+		if ans.Keywords[i].Count != ans.Keywords[j].Count {
+			return ans.Keywords[i].Count > ans.Keywords[j].Count
+		}
+
+		return ans.Keywords[i].Keyword < ans.Keywords[j].Keyword
+	})
+
+	if len(ans.Keywords) > reviewKeywords {
+		ans.Keywords = ans.Keywords[:reviewKeywords]
+	}
+
+	for year, rs := range ratings {
+		sum := 0
+		for _, r := range rs {
+			sum += r
+		}
+
+		ans.RatingByYear[year] = math.Round(float64(sum)/float64(len(rs))*100) / 100
+	}
+
+	e.ReviewInsights = &ans
+}
+
+// reviewSentiment returns 1 for a positive review, -1 for a negative one
+// and 0 for a neutral one. The words of its text are scored with an english
+// lexicon. Reviews without lexicon words, most reviews in other languages
+// among them, are scored by their rating from 1 to 5.
+func reviewSentiment(words []string, rating int) int {
+	score, hits := 0, 0
+
+	for i, w := range words {
+		v, ok := sentimentWords[w]
+		if !ok {
+			continue
+		}
+
+		// "not good", "never friendly"
+		if i > 0 && negations[words[i-1]] {
+			v = -v
+		}
+
+		score += v
+		hits++
+	}
+
+	if hits == 0 {
+		switch {
+		case rating >= 4:
+			return 1
+		case rating > 0 && rating <= 2:
+			return -1
+		default:
+			return 0
+		}
+	}
+
+	switch {
+	case score > 0:
+		return 1
+	case score < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// reviewWords returns the lowercase words of text.
+func reviewWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
+
+func reviewInsightsString(r *ReviewInsights) string {
+	if r == nil {
+		return ""
+	}
+
+	d, _ := json.Marshal(r)
+
+	return string(d)
+}
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "isn't": true, "wasn't": true, "don't": true,
+	"didn't": true, "aren't": true, "weren't": true, "hardly": true,
+}
+
+var sentimentWords = map[string]int{
+	"good": 1, "great": 2, "excellent": 2, "amazing": 2, "awesome": 2, "fantastic": 2,
+	"wonderful": 2, "perfect": 2, "best": 2, "love": 2, "loved": 2, "lovely": 1,
+	"nice": 1, "friendly": 1, "helpful": 1, "delicious": 2, "tasty": 1, "fresh": 1,
+	"clean": 1, "recommend": 1, "recommended": 1, "fast": 1, "quick": 1, "professional": 1,
+	"beautiful": 1, "pleasant": 1, "cozy": 1, "attentive": 1, "happy": 1, "polite": 1,
+	"bad": -1, "terrible": -2, "awful": -2, "horrible": -2, "worst": -2, "poor": -1,
+	"rude": -2, "dirty": -2, "slow": -1, "cold": -1, "expensive": -1, "overpriced": -1,
+	"disappointing": -2, "disappointed": -2, "avoid": -2, "waste": -2,
+	"unfriendly": -1, "unprofessional": -2, "bland": -1, "noisy": -1, "wrong": -1,
+	"hate": -2, "mediocre": -1, "broken": -1, "scam": -2, "late": -1,
+}
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "had": true, "her": true,
+	"was": true, "one": true, "our": true, "out": true, "has": true, "have": true,
+	"his": true, "how": true, "its": true, "may": true, "new": true, "now": true,
+	"own": true, "see": true, "two": true, "way": true, "who": true, "did": true,
+	"get": true, "got": true, "this": true, "that": true, "with": true, "they": true,
+	"from": true, "were": true, "there": true, "their": true, "what": true, "when": true,
+	"which": true, "will": true, "would": true, "been": true, "them": true, "than": true,
+	"then": true, "very": true, "just": true, "also": true, "here": true, "some": true,
+	"more": true, "much": true, "only": true, "about": true, "after": true, "again": true,
+	"place": true, "really": true, "even": true, "because": true, "could": true, "well": true,
+	"time": true, "went": true, "back": true, "come": true, "came": true, "there's": true,
+	"it's": true, "i'm": true, "don't": true, "didn't": true, "we're": true, "they're": true,
+	"your": true, "into": true, "over": true, "other": true, "such": true, "these": true,
+	"those": true, "where": true, "while": true, "being": true, "does": true, "she": true,
+	"him": true, "let": true, "too": true, "off": true, "ever": true, "never": true,
+}
@@ -0,0 +1,41 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_ComputeReviewInsights(t *testing.T) {
+	entry := gmaps.Entry{
+		UserReviews: []gmaps.Review{
+			{Rating: 5, When: "2023-5-1", Description: "Great pizza and friendly staff"},
+			{Rating: 4, When: "2023-8-12", Description: "The pizza was good, the staff was slow"},
+			{Rating: 2, When: "2024-1-3", Description: "Not good, rude staff"},
+			{Rating: 3, When: "2024-2-9", Description: "Sehr lecker"},
+		},
+	}
+
+	entry.ComputeReviewInsights()
+
+	insights := entry.ReviewInsights
+	require.NotNil(t, insights)
+	require.Equal(t, 4, insights.Reviews)
+	require.Equal(t, gmaps.ReviewSentiment{Positive: 1, Neutral: 2, Negative: 1}, insights.Sentiment)
+	require.Equal(t, []gmaps.KeywordCount{
+		{Keyword: "staff", Count: 3},
+		{Keyword: "good", Count: 2},
+		{Keyword: "pizza", Count: 2},
+	}, insights.Keywords)
+	require.Equal(t, map[string]float64{"2023": 4.5, "2024": 2.5}, insights.RatingByYear)
+}
+
+func Test_ComputeReviewInsightsWithoutReviews(t *testing.T) {
+	entry := gmaps.Entry{}
+
+	entry.ComputeReviewInsights()
+
+	require.Nil(t, entry.ReviewInsights)
+}
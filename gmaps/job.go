@@ -12,8 +12,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/tracing"
 	"github.com/gosom/scrapemate"
 	"github.com/playwright-community/playwright-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type GmapJobOptions func(*GmapJob)
@@ -24,10 +27,38 @@ type GmapJob struct {
 	MaxDepth     int
 	LangCode     string
 	ExtractEmail bool
+	Query        string
 
 	Deduper             deduper.Deduper
 	ExitMonitor         exiter.Exiter
 	ExtractExtraReviews bool
+	Center              *MapLocation
+	Region              string
+	Extras              *RequestExtras
+	NoiseFilter         *NoiseFilter
+	Delay               time.Duration
+	// TraceParent is the traceparent of the span the job belongs to, empty for a seed
+	TraceParent string
+	Throttle    Throttle
+
+	trace string
+	// hostLimiter, contacts, guesser, verifier, enrichers, blockGuard, fingerprints, sessions, screenshots, debug, videos, recorder, failures, breakers and retryer are not encoded, the runner that decodes the job sets them again. The fallback is encoded after the job, see EncodeFallback
+	hostLimiter  *HostLimiter
+	contacts     *ContactFinder
+	guesser      *EmailGuesser
+	verifier     *EmailVerifier
+	enrichers    []Enricher
+	blockGuard   *BlockGuard
+	fingerprints *FingerprintRotator
+	sessions     *SessionStore
+	screenshots  *ScreenshotStore
+	debug        *DebugRecorder
+	videos       *VideoRecorder
+	recorder     *metrics.Recorder
+	failures     *FailureLog
+	breakers     *CircuitBreakers
+	retryer      *Retryer
+	fallback     *fallback
 }
 
 func NewGmapJob(
@@ -38,6 +69,7 @@ func NewGmapJob(
 	zoom int,
 	opts ...GmapJobOptions,
 ) *GmapJob {
+	rawQuery := query
 	query = url.QueryEscape(query)
 
 	const (
@@ -49,19 +81,10 @@ func NewGmapJob(
 		id = uuid.New().String()
 	}
 
-	mapURL := ""
-	if geoCoordinates != "" && zoom > 0 {
-		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s/@%s,%dz", query, strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
-	} else {
-		//Warning: geo and zoom MUST be both set or not
-		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s", query)
-	}
-
 	job := GmapJob{
 		Job: scrapemate.Job{
 			ID:         id,
 			Method:     http.MethodGet,
-			URL:        mapURL,
 			URLParams:  map[string]string{"hl": langCode},
 			MaxRetries: maxRetries,
 			Priority:   prio,
@@ -69,12 +92,26 @@ func NewGmapJob(
 		MaxDepth:     maxDepth,
 		LangCode:     langCode,
 		ExtractEmail: extractEmail,
+		Query:        rawQuery,
 	}
 
 	for _, opt := range opts {
 		opt(&job)
 	}
 
+	host := GoogleHost(job.Region)
+
+	if geoCoordinates != "" && zoom > 0 {
+		job.URL = fmt.Sprintf("https://%s/maps/search/%s/@%s,%dz", host, query, strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
+	} else {
+		//Warning: geo and zoom MUST be both set or not
+		job.URL = fmt.Sprintf("https://%s/maps/search/%s", host, query)
+	}
+
+	if job.Region != "" {
+		job.URLParams["gl"] = job.Region
+	}
+
 	return &job
 }
 
@@ -96,51 +133,257 @@ func WithExtraReviews() GmapJobOptions {
 	}
 }
 
+// WithCenter sets the point used to compute the distance of each place.
+func WithCenter(lat, lon float64) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Center = &MapLocation{Lat: lat, Lon: lon}
+	}
+}
+
+// WithRegion targets the Google domain of the given country code
+// and sets the gl parameter, since localized results may differ from google.com.
+func WithRegion(region string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Region = strings.ToLower(region)
+	}
+}
+
+// WithRequestExtras adds headers and cookies to the search and place pages.
+func WithRequestExtras(e *RequestExtras) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Extras = e
+	}
+}
+
+// WithNoiseFilter drops the places that are not businesses before they are enriched.
+func WithNoiseFilter(f *NoiseFilter) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.NoiseFilter = f
+	}
+}
+
+// WithDelay pauses before the search page and the place pages are loaded,
+// to send fewer requests to Google.
+func WithDelay(d time.Duration) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Delay = d
+	}
+}
+
+// WithThrottle paces the search page and the place pages with t.
+func WithThrottle(t Throttle) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Throttle = t
+	}
+}
+
+// WithHostLimiter paces the email crawl of the places with l.
+func WithHostLimiter(l *HostLimiter) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.hostLimiter = l
+	}
+}
+
+// WithContactFinder looks for the emails of the places without one on the
+// contact pages found by f.
+func WithContactFinder(f *ContactFinder) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.contacts = f
+	}
+}
+
+// WithEmailGuesser guesses the emails of the places without one with g.
+func WithEmailGuesser(g *EmailGuesser) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.guesser = g
+	}
+}
+
+// WithEmailVerifier verifies the emails of the places with v.
+func WithEmailVerifier(v *EmailVerifier) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.verifier = v
+	}
+}
+
+// WithEnrichers runs enrichers over the homepages of the places instead of
+// the built-in ones.
+func WithEnrichers(enrichers []Enricher) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.enrichers = enrichers
+	}
+}
+
+// WithMetrics records the metrics of the search and of the jobs it creates with r.
+func WithMetrics(r *metrics.Recorder) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.recorder = r
+	}
+}
+
+// WithCircuitBreakers fails the search at once while the circuit of google
+// is open in b, and the places it finds and their websites while theirs are.
+func WithCircuitBreakers(b *CircuitBreakers) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.breakers = b
+	}
+}
+
+// WithRetryer retries the websites of the places the search finds with r
+// when they are rate limited.
+func WithRetryer(r *Retryer) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.retryer = r
+	}
+}
+
+// WithFallback loads the search with next, the next step of its
+// FallbackChain, when it fails on one of the conditions on.
+func WithFallback(next scrapemate.IJob, on []string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.fallback = newFallback(next, on)
+	}
+}
+
+// WithFailureLog records the errors of the places the search finds, and of
+// their email jobs, in l.
+func WithFailureLog(l *FailureLog) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.failures = l
+	}
+}
+
+// WithBlockGuard reports the captchas of the search and place pages to g.
+func WithBlockGuard(g *BlockGuard) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.blockGuard = g
+	}
+}
+
+// WithFingerprints gives the browser contexts of the job, and of the jobs it
+// creates, a fingerprint of r.
+func WithFingerprints(r *FingerprintRotator) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.fingerprints = r
+	}
+}
+
+// WithSessions restores and saves the cookies of the browsers of the job, and
+// of the jobs it creates, in s.
+func WithSessions(s *SessionStore) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.sessions = s
+	}
+}
+
+// WithScreenshots keeps a screenshot of the pages of the places the job
+// finds in s.
+func WithScreenshots(s *ScreenshotStore) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.screenshots = s
+	}
+}
+
+// WithDebugRecorder records the pages of the job, and of the jobs it creates,
+// in d and keeps the recordings of the failed ones.
+func WithDebugRecorder(d *DebugRecorder) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.debug = d
+	}
+}
+
+// WithVideoRecorder keeps or deletes the videos of the pages of the job, and
+// of the jobs it creates, with v.
+func WithVideoRecorder(v *VideoRecorder) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.videos = v
+	}
+}
+
+// WithTraceParent makes the spans of the job children of the given traceparent.
+func WithTraceParent(traceParent string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.TraceParent = traceParent
+	}
+}
+
 func (j *GmapJob) UseInResults() bool {
 	return false
 }
 
+// Traceparent returns the traceparent of the span of the search page.
+func (j *GmapJob) Traceparent() string {
+	if j.trace != "" {
+		return j.trace
+	}
+
+	return j.TraceParent
+}
+
 func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
 	}()
 
+	ctx, span := tracing.Start(ctx, j.Traceparent(), "gmaps.search.process")
+	defer span.End()
+
 	log := scrapemate.GetLoggerFromContext(ctx)
 
+	if resp.Error != nil {
+		if next := j.fallback.job(FallbackCondition(resp.Error, 0)); next != nil {
+			log.Info("search failed, falling back", "error", resp.Error)
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+
+		return nil, nil, resp.Error
+	}
+
 	doc, ok := resp.Document.(*goquery.Document)
 	if !ok {
+		if next := j.fallback.job(FallbackParse); next != nil {
+			log.Info("could not read the search, falling back")
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+
 		return nil, nil, fmt.Errorf("could not convert to goquery document")
 	}
 
-	var next []scrapemate.IJob
+	var (
+		next  []scrapemate.IJob
+		found int
+	)
 
 	if strings.Contains(resp.URL, "/maps/place/") {
-		jopts := []PlaceJobOptions{}
-		if j.ExitMonitor != nil {
-			jopts = append(jopts, WithPlaceJobExitMonitor(j.ExitMonitor))
-		}
-
-		placeJob := NewPlaceJob(j.ID, j.LangCode, resp.URL, j.ExtractEmail, j.ExtractExtraReviews, jopts...)
+		placeJob := NewPlaceJob(j.ID, j.LangCode, resp.URL, j.ExtractEmail, j.ExtractExtraReviews, j.placeJobOptions()...)
 
 		next = append(next, placeJob)
+		found++
 	} else {
 		doc.Find(`div[role=feed] div[jsaction]>a`).Each(func(_ int, s *goquery.Selection) {
 			if href := s.AttrOr("href", ""); href != "" {
-				jopts := []PlaceJobOptions{}
-				if j.ExitMonitor != nil {
-					jopts = append(jopts, WithPlaceJobExitMonitor(j.ExitMonitor))
-				}
+				found++
 
-				nextJob := NewPlaceJob(j.ID, j.LangCode, href, j.ExtractEmail, j.ExtractExtraReviews, jopts...)
+				nextJob := NewPlaceJob(j.ID, j.LangCode, href, j.ExtractEmail, j.ExtractExtraReviews, j.placeJobOptions()...)
 
-				if j.Deduper == nil || j.Deduper.AddIfNotExists(ctx, href) {
+				if j.Deduper == nil || j.Deduper.AddIfNotExists(ctx, DedupKey(href)) {
 					next = append(next, nextJob)
 				}
 			}
 		})
 	}
 
+	if found == 0 {
+		if fb := j.fallback.job(FallbackEmpty); fb != nil {
+			log.Info("no places found, falling back")
+
+			return nil, []scrapemate.IJob{fb}, nil
+		}
+	}
+
 	if j.ExitMonitor != nil {
 		j.ExitMonitor.IncrPlacesFound(len(next))
 		j.ExitMonitor.IncrSeedCompleted(1)
@@ -151,38 +394,191 @@ func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any,
 	return nil, next, nil
 }
 
+// ProcessOnFetchError makes a search with a fallback fall back when its page
+// fails to load.
+func (j *GmapJob) ProcessOnFetchError() bool {
+	return j.fallback != nil
+}
+
+func (j *GmapJob) placeJobOptions() []PlaceJobOptions {
+	jopts := []PlaceJobOptions{
+		WithPlaceJobQuery(j.Query),
+	}
+
+	if j.ExitMonitor != nil {
+		jopts = append(jopts, WithPlaceJobExitMonitor(j.ExitMonitor))
+	}
+
+	if j.Center != nil {
+		jopts = append(jopts, WithPlaceJobCenter(j.Center.Lat, j.Center.Lon))
+	}
+
+	if j.Region != "" {
+		jopts = append(jopts, WithPlaceJobRegion(j.Region))
+	}
+
+	if !j.Extras.IsZero() {
+		jopts = append(jopts, WithPlaceJobRequestExtras(j.Extras))
+	}
+
+	if j.NoiseFilter != nil {
+		jopts = append(jopts, WithPlaceJobNoiseFilter(j.NoiseFilter))
+	}
+
+	if j.Delay > 0 {
+		jopts = append(jopts, WithPlaceJobDelay(j.Delay))
+	}
+
+	if trace := j.Traceparent(); trace != "" {
+		jopts = append(jopts, WithPlaceJobTraceParent(trace))
+	}
+
+	if j.Throttle != nil {
+		jopts = append(jopts, WithPlaceJobThrottle(j.Throttle))
+	}
+
+	if j.hostLimiter != nil {
+		jopts = append(jopts, WithPlaceJobHostLimiter(j.hostLimiter))
+	}
+
+	if j.contacts != nil {
+		jopts = append(jopts, WithPlaceJobContactFinder(j.contacts))
+	}
+
+	if j.guesser != nil {
+		jopts = append(jopts, WithPlaceJobEmailGuesser(j.guesser))
+	}
+
+	if j.verifier != nil {
+		jopts = append(jopts, WithPlaceJobEmailVerifier(j.verifier))
+	}
+
+	if j.enrichers != nil {
+		jopts = append(jopts, WithPlaceJobEnrichers(j.enrichers))
+	}
+
+	if j.blockGuard != nil {
+		jopts = append(jopts, WithPlaceJobBlockGuard(j.blockGuard))
+	}
+
+	if j.fingerprints != nil {
+		jopts = append(jopts, WithPlaceJobFingerprints(j.fingerprints))
+	}
+
+	if j.sessions != nil {
+		jopts = append(jopts, WithPlaceJobSessions(j.sessions))
+	}
+
+	if j.screenshots != nil {
+		jopts = append(jopts, WithPlaceJobScreenshots(j.screenshots))
+	}
+
+	if j.debug != nil {
+		jopts = append(jopts, WithPlaceJobDebugRecorder(j.debug))
+	}
+
+	if j.videos != nil {
+		jopts = append(jopts, WithPlaceJobVideoRecorder(j.videos))
+	}
+
+	if j.breakers != nil {
+		jopts = append(jopts, WithPlaceJobCircuitBreakers(j.breakers))
+	}
+
+	if j.retryer != nil {
+		jopts = append(jopts, WithPlaceJobRetryer(j.retryer))
+	}
+
+	if j.recorder != nil {
+		jopts = append(jopts, WithPlaceJobMetrics(j.recorder))
+	}
+
+	if j.failures != nil {
+		jopts = append(jopts, WithPlaceJobFailureLog(j.failures))
+	}
+
+	return jopts
+}
+
 func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
-	var resp scrapemate.Response
+	trackPage(page)
 
-	pageResponse, err := page.Goto(j.GetFullURL(), playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+	start := time.Now()
+
+	ctx, span := tracing.Start(ctx, j.TraceParent, "gmaps.search",
+		attribute.String("job.id", j.ID),
+		attribute.String("query", j.Query),
+	)
+
+	j.debug.Start(page)
+
+	resp := j.breakers.guard(j.GetURL(), func() scrapemate.Response {
+		return throttle(ctx, j.Throttle, page, func() scrapemate.Response {
+			return j.browserActions(ctx, page)
+		})
 	})
 
-	if err != nil {
+	if err := j.debug.Finish(page, j.ID, resp.Error); err != nil {
+		scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to save the debug recording: %v", err))
+	}
+
+	j.videos.Finish(ctx, page, j.ID, resp.Error)
+
+	j.trace = tracing.Parent(ctx)
+	tracing.End(span, resp.Error)
+	j.recorder.Scrape(metrics.JobTypeSearch, time.Since(start), resp.Error)
+
+	return resp
+}
+
+func (j *GmapJob) browserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	var resp scrapemate.Response
+
+	if err := pause(ctx, j.Delay); err != nil {
 		resp.Error = err
 
 		return resp
 	}
 
-	if err = clickRejectCookiesIfRequired(page); err != nil {
+	if err := j.blockGuard.Wait(ctx); err != nil {
 		resp.Error = err
 
 		return resp
 	}
 
-	const defaultTimeout = 5000
+	if err := j.fingerprints.Apply(page); err != nil {
+		resp.Error = err
 
-	err = page.WaitForURL(page.URL(), playwright.PageWaitForURLOptions{
-		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
-		Timeout:   playwright.Float(defaultTimeout),
-	})
+		return resp
+	}
+
+	if err := j.sessions.Restore(page); err != nil {
+		scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to restore the session: %v", err))
+	}
 
+	if err := j.Extras.applyToPage(page, j.GetFullURL()); err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	pageResponse, err := navigate(ctx, page, j.GetFullURL())
 	if err != nil {
 		resp.Error = err
 
 		return resp
 	}
 
+	if isBlockedPage(page) && !j.blockGuard.Blocked(ctx, page) {
+		resp.Error = ErrBlocked
+
+		return resp
+	}
+
+	if err := j.sessions.Save(page); err != nil {
+		scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to save the session: %v", err))
+	}
+
 	resp.URL = pageResponse.URL()
 	resp.StatusCode = pageResponse.Status()
 	resp.Headers = make(http.Header, len(pageResponse.Headers()))
@@ -229,7 +625,12 @@ func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scra
 
 	scrollSelector := `div[role='feed']`
 
-	_, err = scroll(ctx, page, j.MaxDepth, scrollSelector)
+	scrollCtx, span := tracing.Start(ctx, "", "scroll")
+
+	_, err = scroll(scrollCtx, page, j.MaxDepth, scrollSelector)
+
+	tracing.End(span, err)
+
 	if err != nil {
 		resp.Error = err
 
@@ -355,3 +756,51 @@ func scroll(ctx context.Context,
 
 	return cnt, nil
 }
+
+// navigate loads u in the page, rejects the cookies if asked and waits for
+// the redirects to settle.
+func navigate(ctx context.Context, page playwright.Page, u string) (playwright.Response, error) {
+	_, span := tracing.Start(ctx, "", "navigation", attribute.String("url", u))
+
+	pageResponse, err := page.Goto(u, playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+	})
+
+	if err == nil {
+		err = clickRejectCookiesIfRequired(page)
+	}
+
+	if err == nil {
+		const defaultTimeout = 5000
+
+		err = page.WaitForURL(page.URL(), playwright.PageWaitForURLOptions{
+			WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+			Timeout:   playwright.Float(defaultTimeout),
+		})
+	}
+
+	tracing.End(span, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pageResponse, nil
+}
+
+// pause waits for d or until ctx is done.
+func pause(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package gmaps
+
+import (
+	"context"
+
+	"github.com/abadojack/whatlanggo"
+
+	"github.com/gosom/google-maps-scraper/translate"
+)
+
+// translateBatch is the number of reviews translated in a request
+const translateBatch = 50
+
+// DetectLanguage returns the ISO 639-1 code of the language of the text, or
+// the ISO 639-3 code for languages without one. It returns an empty string
+// when the text is too short or mixed to tell the language reliably.
+func DetectLanguage(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	info := whatlanggo.Detect(text)
+	if !info.IsReliable() {
+		return ""
+	}
+
+	if code := info.Lang.Iso6391(); code != "" {
+		return code
+	}
+
+	return info.Lang.Iso6393()
+}
+
+// DetectLanguages sets the language of the description and of every review.
+func (e *Entry) DetectLanguages() {
+	e.DescriptionLanguage = DetectLanguage(e.Description)
+
+	for i := range e.UserReviews {
+		e.UserReviews[i].Language = DetectLanguage(e.UserReviews[i].Description)
+	}
+
+	for i := range e.UserReviewsExtended {
+		e.UserReviewsExtended[i].Language = DetectLanguage(e.UserReviewsExtended[i].Description)
+	}
+}
+
+// TranslateReviews sets the TranslatedText of the reviews that are not in the
+// target language with t. The language of the reviews must be detected
+// first, see DetectLanguages; reviews of an unknown language are translated
+// too.
+func (e *Entry) TranslateReviews(ctx context.Context, t translate.Translator, target string) error {
+	var pending []*Review
+
+	for _, reviews := range [][]Review{e.UserReviews, e.UserReviewsExtended} {
+		for i := range reviews {
+			if reviews[i].Description != "" && reviews[i].Language != target {
+				pending = append(pending, &reviews[i])
+			}
+		}
+	}
+
+	for start := 0; start < len(pending); start += translateBatch {
+		batch := pending[start:min(start+translateBatch, len(pending))]
+
+		texts := make([]string, len(batch))
+		for i := range batch {
+			texts[i] = batch[i].Description
+		}
+
+		translated, err := t.Translate(ctx, texts, target)
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			batch[i].TranslatedText = translated[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package gmaps_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+type upperTranslator struct {
+	calls int
+}
+
+func (u *upperTranslator) Translate(_ context.Context, texts []string, _ string) ([]string, error) {
+	u.calls++
+
+	ans := make([]string, len(texts))
+	for i := range texts {
+		ans[i] = strings.ToUpper(texts[i])
+	}
+
+	return ans, nil
+}
+
+func Test_TranslateReviews(t *testing.T) {
+	entry := gmaps.Entry{
+		UserReviews: []gmaps.Review{
+			{Description: "Sehr lecker", Language: "de"},
+			{Description: "Very tasty", Language: "en"},
+			{Description: ""},
+		},
+		UserReviewsExtended: []gmaps.Review{
+			{Description: "Muy rico", Language: "es"},
+		},
+	}
+
+	tr := &upperTranslator{}
+
+	require.NoError(t, entry.TranslateReviews(context.Background(), tr, "en"))
+
+	require.Equal(t, 1, tr.calls)
+	require.Equal(t, "SEHR LECKER", entry.UserReviews[0].TranslatedText)
+	require.Empty(t, entry.UserReviews[1].TranslatedText)
+	require.Empty(t, entry.UserReviews[2].TranslatedText)
+	require.Equal(t, "MUY RICO", entry.UserReviewsExtended[0].TranslatedText)
+}
@@ -0,0 +1,201 @@
+package gmaps
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultNoiseCategories are the categories of places that are not businesses,
+// such as transit stops, cash machines and public spaces.
+// Categories are in the language of the search, these are the English ones.
+var DefaultNoiseCategories = []string{
+	"ATM",
+	"Bench",
+	"Bridge",
+	"Bus stop",
+	"Bus station",
+	"City park",
+	"Dog park",
+	"EV charging station",
+	"Fountain",
+	"Garden",
+	"Historical landmark",
+	"Lake",
+	"Monument",
+	"Mountain peak",
+	"National park",
+	"Park",
+	"Parking garage",
+	"Parking lot",
+	"Picnic ground",
+	"Playground",
+	"Post box",
+	"Public bathroom",
+	"Public parking space",
+	"River",
+	"Scenic spot",
+	"State park",
+	"Subway station",
+	"Taxi stand",
+	"Train station",
+	"Tram stop",
+	"Transit station",
+	"Transit stop",
+}
+
+// NoiseFilter drops the places whose main category is not a business one
+// and counts them by category.
+type NoiseFilter struct {
+	categories map[string]struct{}
+
+	mu      sync.Mutex
+	dropped map[string]int
+}
+
+// NewNoiseFilter returns a filter of the given categories, compared case
+// insensitively. DefaultNoiseCategories are used when none are given.
+func NewNoiseFilter(categories []string) *NoiseFilter {
+	if len(categories) == 0 {
+		categories = DefaultNoiseCategories
+	}
+
+	f := NoiseFilter{
+		categories: make(map[string]struct{}, len(categories)),
+		dropped:    make(map[string]int),
+	}
+
+	for _, c := range categories {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			f.categories[c] = struct{}{}
+		}
+	}
+
+	return &f
+}
+
+// GobEncode encodes the categories of the filter, so the jobs that carry it
+// can be stored by the database provider. The counts are not encoded.
+func (f *NoiseFilter) GobEncode() ([]byte, error) {
+	categories := make([]string, 0, len(f.categories))
+	for c := range f.categories {
+		categories = append(categories, c)
+	}
+
+	sort.Strings(categories)
+
+	return []byte(strings.Join(categories, "\n")), nil
+}
+
+// GobDecode decodes the categories encoded by GobEncode.
+func (f *NoiseFilter) GobDecode(data []byte) error {
+	n := NewNoiseFilter(strings.Split(string(data), "\n"))
+
+	f.categories = n.categories
+	f.dropped = n.dropped
+
+	return nil
+}
+
+// ParseNoiseCategories splits a comma separated list of categories.
+func ParseNoiseCategories(s string) []string {
+	var ans []string
+
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			ans = append(ans, c)
+		}
+	}
+
+	return ans
+}
+
+// Drop reports whether the entry is noise and counts it when it is.
+// Entries without a category are kept.
+func (f *NoiseFilter) Drop(e *Entry) bool {
+	if f == nil || e.Category == "" {
+		return false
+	}
+
+	if _, ok := f.categories[strings.ToLower(e.Category)]; !ok {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.dropped[e.Category]++
+
+	return true
+}
+
+// Filter returns the entries that are not noise.
+func (f *NoiseFilter) Filter(entries []*Entry) []*Entry {
+	if f == nil {
+		return entries
+	}
+
+	ans := entries[:0]
+
+	for i := range entries {
+		if !f.Drop(entries[i]) {
+			ans = append(ans, entries[i])
+		}
+	}
+
+	return ans
+}
+
+// Dropped returns the number of dropped entries by category.
+func (f *NoiseFilter) Dropped() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ans := make(map[string]int, len(f.dropped))
+
+	for k, v := range f.dropped {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+// Total returns the number of dropped entries.
+func (f *NoiseFilter) Total() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	total := 0
+
+	for _, v := range f.dropped {
+		total += v
+	}
+
+	return total
+}
+
+// Summary describes the dropped entries, e.g. "Bus stop: 3, ATM: 1".
+func (f *NoiseFilter) Summary() string {
+	dropped := f.Dropped()
+
+	categories := make([]string, 0, len(dropped))
+	for c := range dropped {
+		categories = append(categories, c)
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		if dropped[categories[i]] != dropped[categories[j]] {
+			return dropped[categories[i]] > dropped[categories[j]]
+		}
+
+		return categories[i] < categories[j]
+	})
+
+	parts := make([]string, len(categories))
+	for i, c := range categories {
+		parts[i] = c + ": " + strconv.Itoa(dropped[c])
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,86 @@
+package gmaps_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_NoiseFilter(t *testing.T) {
+	t.Parallel()
+
+	f := gmaps.NewNoiseFilter(nil)
+
+	entries := []*gmaps.Entry{
+		{Title: "Cafe", Category: "Coffee shop"},
+		{Title: "Main St", Category: "Bus stop"},
+		{Title: "Bank ATM", Category: "atm"},
+		{Title: "Unknown"},
+		{Title: "Central", Category: "Bus stop"},
+	}
+
+	kept := f.Filter(entries)
+
+	require.Len(t, kept, 2)
+	require.Equal(t, "Cafe", kept[0].Title)
+	require.Equal(t, "Unknown", kept[1].Title)
+	require.Equal(t, 3, f.Total())
+	require.Equal(t, "Bus stop: 2, atm: 1", f.Summary())
+
+	custom := gmaps.NewNoiseFilter(gmaps.ParseNoiseCategories("Haltestelle, Geldautomat"))
+
+	require.True(t, custom.Drop(&gmaps.Entry{Category: "Haltestelle"}))
+	require.False(t, custom.Drop(&gmaps.Entry{Category: "Bus stop"}))
+}
+
+func Test_NoiseFilterGob(t *testing.T) {
+	t.Parallel()
+
+	job := gmaps.NewGmapJob("", "en", "cafe", 1, false, "", 15,
+		gmaps.WithNoiseFilter(gmaps.NewNoiseFilter(gmaps.ParseNoiseCategories("Haltestelle"))),
+	)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, gob.NewEncoder(&buf).Encode(job))
+
+	var decoded gmaps.GmapJob
+
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	require.True(t, decoded.NoiseFilter.Drop(&gmaps.Entry{Category: "haltestelle"}))
+	require.False(t, decoded.NoiseFilter.Drop(&gmaps.Entry{Category: "Bus stop"}))
+}
+
+func Test_PlaceJobNoiseFilterGob(t *testing.T) {
+	t.Parallel()
+
+	job := gmaps.NewPlaceJob("parent", "en", "https://www.google.com/maps/place/x", false, false,
+		gmaps.WithPlaceJobNoiseFilter(gmaps.NewNoiseFilter(nil)),
+	)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, gob.NewEncoder(&buf).Encode(job))
+
+	var decoded gmaps.PlaceJob
+
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	require.Equal(t, job.URL, decoded.URL)
+	require.True(t, decoded.NoiseFilter.Drop(&gmaps.Entry{Category: "Bus stop"}))
+	require.False(t, decoded.NoiseFilter.Drop(&gmaps.Entry{Category: "Coffee shop"}))
+
+	// a job without a filter keeps none
+	buf.Reset()
+
+	require.NoError(t, gob.NewEncoder(&buf).Encode(gmaps.NewPlaceJob("parent", "en", "https://www.google.com/maps/place/y", false, false)))
+
+	decoded = gmaps.PlaceJob{}
+
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	require.Nil(t, decoded.NoiseFilter)
+	require.False(t, decoded.NoiseFilter.Drop(&gmaps.Entry{Category: "Bus stop"}))
+}
@@ -0,0 +1,59 @@
+package gmaps
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeText puts s in unicode NFC form, collapses runs of whitespace,
+// including new lines, to a single space and trims it. Emojis are removed
+// when stripEmojis is true.
+func NormalizeText(s string, stripEmojis bool) string {
+	if s == "" {
+		return s
+	}
+
+	s = norm.NFC.String(s)
+
+	if stripEmojis {
+		s = strings.Map(func(r rune) rune {
+			if isEmoji(r) {
+				return -1
+			}
+
+			return r
+		}, s)
+	}
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Normalize normalizes the title, address and description of the entry, see NormalizeText.
+func (e *Entry) Normalize(stripEmojis bool) {
+	e.Title = NormalizeText(e.Title, stripEmojis)
+	e.Address = NormalizeText(e.Address, stripEmojis)
+	e.Description = NormalizeText(e.Description, stripEmojis)
+}
+
+// isEmoji reports whether r is an emoji or one of the characters that join
+// and modify emojis, such as the zero width joiner and variation selectors.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, flags and skin tones
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and stars
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tags of subdivision flags
+		return true
+	case r == 0x200D, r == 0x20E3, r == 0xFE0E, r == 0xFE0F:
+		return true
+	case r >= 0x2300 && r <= 0x23FF:
+		return unicode.Is(unicode.So, r)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,63 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LiveOccupancy is the live busyness Google shows for a place when it has
+// enough visits, e.g. "Live: busier than usual".
+type LiveOccupancy struct {
+	// Day and Hour are when the busyness was measured, in the timezone of the place
+	Day  string `json:"day"`
+	Hour int    `json:"hour"`
+	// Current is the busyness now, on the scale of PopularTimes
+	Current int `json:"current"`
+	// Usual is the busyness of PopularTimes at this day and hour, 0 when unknown
+	Usual int `json:"usual"`
+	// Text is the description of Google in the language of the job, e.g. "Busier than usual"
+	Text string `json:"text,omitempty"`
+}
+
+// getLiveOccupancy returns the live busyness of the place, nil when Google
+// does not show one.
+func getLiveOccupancy(darray []any, popularTimes map[string]map[int]int) *LiveOccupancy {
+	live := getNthElementAndCast[[]any](darray, 84, 7)
+	if len(live) < 2 {
+		return nil
+	}
+
+	hour, ok := live[0].(float64)
+	if !ok {
+		return nil
+	}
+
+	current, ok := live[1].(float64)
+	if !ok {
+		return nil
+	}
+
+	ans := LiveOccupancy{
+		Hour:    int(hour),
+		Current: int(current),
+		Text:    getNthElementAndCast[string](darray, 84, 6),
+	}
+
+	// the days are numbered from 1, Monday, to 7, Sunday
+	if day := int(getNthElementAndCast[float64](darray, 84, 1)); day >= 1 && day <= 7 {
+		ans.Day = time.Weekday(day % 7).String()
+		ans.Usual = popularTimes[ans.Day][ans.Hour]
+	}
+
+	return &ans
+}
+
+func liveOccupancyString(o *LiveOccupancy) string {
+	if o == nil {
+		return ""
+	}
+
+	d, _ := json.Marshal(o)
+
+	return string(d)
+}
@@ -0,0 +1,135 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/ttacon/libphonenumber"
+)
+
+// The types of the phone numbers of PhoneInfo.
+const (
+	PhoneTypeMobile    = "mobile"
+	PhoneTypeFixedLine = "fixed_line"
+	// PhoneTypeUnknown is a number whose type cannot be told, e.g. in the US
+	// where mobile and fixed numbers look alike, or that is neither, e.g. a
+	// toll free number
+	PhoneTypeUnknown = "unknown"
+)
+
+// PhoneInfo is a phone number in the E.164 and national formats.
+type PhoneInfo struct {
+	Raw      string `json:"raw"`
+	E164     string `json:"e164,omitempty"`
+	National string `json:"national,omitempty"`
+	Type     string `json:"type,omitempty"`
+	// Valid is true when the number is a valid number of its country
+	Valid bool `json:"valid"`
+}
+
+// NormalizePhone parses raw, a phone number in the international format or
+// in the national format of region, an ISO 3166-1 alpha-2 country code,
+// with the numbering plans of libphonenumber.
+func NormalizePhone(raw, region string) PhoneInfo {
+	ans := PhoneInfo{Raw: raw}
+
+	// the trunk prefix written in an international number, e.g. +44 (0)20
+	number := strings.Replace(strings.TrimSpace(raw), "(0)", "", 1)
+
+	// the international prefix 00 of the countries whose prefix differs,
+	// e.g. a number copied from a European site for a US place
+	if strings.HasPrefix(number, "00") {
+		number = "+" + number[2:]
+	}
+
+	region = strings.ToUpper(region)
+	if region == "" {
+		region = unknownPhoneRegion
+	}
+
+	num, err := libphonenumber.Parse(number, region)
+	if err != nil || !libphonenumber.IsValidNumber(num) {
+		return ans
+	}
+
+	ans.Valid = true
+	ans.E164 = libphonenumber.Format(num, libphonenumber.E164)
+	ans.National = libphonenumber.Format(num, libphonenumber.NATIONAL)
+
+	switch libphonenumber.GetNumberType(num) {
+	case libphonenumber.MOBILE:
+		ans.Type = PhoneTypeMobile
+	case libphonenumber.FIXED_LINE:
+		ans.Type = PhoneTypeFixedLine
+	default:
+		ans.Type = PhoneTypeUnknown
+	}
+
+	return ans
+}
+
+// unknownPhoneRegion is the region of libphonenumber for the numbers that
+// must have a calling code.
+const unknownPhoneRegion = "ZZ"
+
+// NormalizePhone sets PhoneDetails from the phone and the country of the place.
+func (e *Entry) NormalizePhone() {
+	if e.Phone == "" {
+		e.PhoneDetails = nil
+
+		return
+	}
+
+	region := e.CompleteAddress.Country
+	if region == "" {
+		region = e.CountryCode
+	}
+
+	info := NormalizePhone(e.Phone, region)
+	e.PhoneDetails = &info
+}
+
+// docPhones returns the valid phones of the tel: links of doc, in the
+// national format of region when they have no calling code.
+func docPhones(doc *goquery.Document, region string) []PhoneInfo {
+	var ans []PhoneInfo
+
+	doc.Find("a[href^='tel:']").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+
+		info := NormalizePhone(strings.TrimPrefix(href, "tel:"), region)
+		if !info.Valid {
+			return
+		}
+
+		if slices.ContainsFunc(ans, func(p PhoneInfo) bool { return p.E164 == info.E164 }) {
+			return
+		}
+
+		ans = append(ans, info)
+	})
+
+	return ans
+}
+
+func phoneDetailsString(info *PhoneInfo) string {
+	if info == nil {
+		return ""
+	}
+
+	d, _ := json.Marshal(info)
+
+	return string(d)
+}
+
+func websitePhonesString(phones []PhoneInfo) string {
+	if len(phones) == 0 {
+		return ""
+	}
+
+	d, _ := json.Marshal(phones)
+
+	return string(d)
+}
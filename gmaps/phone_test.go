@@ -0,0 +1,107 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_NormalizePhone(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   gmaps.PhoneInfo
+	}{
+		{
+			name:   "national fixed line",
+			raw:    "030 1234567",
+			region: "DE",
+			want:   gmaps.PhoneInfo{Raw: "030 1234567", E164: "+49301234567", National: "030 1234567", Type: gmaps.PhoneTypeFixedLine, Valid: true},
+		},
+		{
+			name:   "international mobile with the trunk prefix",
+			raw:    "+44 (0)7911 123456",
+			region: "",
+			want:   gmaps.PhoneInfo{Raw: "+44 (0)7911 123456", E164: "+447911123456", National: "07911 123456", Type: gmaps.PhoneTypeMobile, Valid: true},
+		},
+		{
+			name:   "north american number",
+			raw:    "1-212-555-0100",
+			region: "us",
+			want:   gmaps.PhoneInfo{Raw: "1-212-555-0100", E164: "+12125550100", National: "(212) 555-0100", Type: gmaps.PhoneTypeUnknown, Valid: true},
+		},
+		{
+			name:   "no trunk prefix",
+			raw:    "210 123 4567",
+			region: "GR",
+			want:   gmaps.PhoneInfo{Raw: "210 123 4567", E164: "+302101234567", National: "21 0123 4567", Type: gmaps.PhoneTypeFixedLine, Valid: true},
+		},
+		{
+			name:   "international prefix 00",
+			raw:    "0039 06 1234 5678",
+			region: "GR",
+			want:   gmaps.PhoneInfo{Raw: "0039 06 1234 5678", E164: "+390612345678", National: "06 1234 5678", Type: gmaps.PhoneTypeFixedLine, Valid: true},
+		},
+		{
+			name:   "kenyan mobile",
+			raw:    "0712 345678",
+			region: "KE",
+			want:   gmaps.PhoneInfo{Raw: "0712 345678", E164: "+254712345678", National: "0712 345678", Type: gmaps.PhoneTypeMobile, Valid: true},
+		},
+		{
+			name:   "argentinian fixed line",
+			raw:    "011 4123-4567",
+			region: "AR",
+			want:   gmaps.PhoneInfo{Raw: "011 4123-4567", E164: "+541141234567", National: "011 4123-4567", Type: gmaps.PhoneTypeFixedLine, Valid: true},
+		},
+		{
+			name:   "vietnamese mobile",
+			raw:    "0912 345 678",
+			region: "vn",
+			want:   gmaps.PhoneInfo{Raw: "0912 345 678", E164: "+84912345678", National: "091 234 56 78", Type: gmaps.PhoneTypeMobile, Valid: true},
+		},
+		{
+			name:   "international nigerian number",
+			raw:    "+234 1 271 0000",
+			region: "",
+			want:   gmaps.PhoneInfo{Raw: "+234 1 271 0000", E164: "+23412710000", National: "01 271 0000", Type: gmaps.PhoneTypeFixedLine, Valid: true},
+		},
+		{
+			name:   "international russian number",
+			raw:    "+7 495 123-45-67",
+			region: "DE",
+			want:   gmaps.PhoneInfo{Raw: "+7 495 123-45-67", E164: "+74951234567", National: "8 (495) 123-45-67", Type: gmaps.PhoneTypeFixedLine, Valid: true},
+		},
+		{
+			name:   "toll free",
+			raw:    "1-800-225-5288",
+			region: "US",
+			want:   gmaps.PhoneInfo{Raw: "1-800-225-5288", E164: "+18002255288", National: "(800) 225-5288", Type: gmaps.PhoneTypeUnknown, Valid: true},
+		},
+		{
+			name:   "too short",
+			raw:    "12345",
+			region: "FR",
+			want:   gmaps.PhoneInfo{Raw: "12345"},
+		},
+		{
+			name:   "unknown country",
+			raw:    "555 1234",
+			region: "",
+			want:   gmaps.PhoneInfo{Raw: "555 1234"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, gmaps.NormalizePhone(tc.raw, tc.region))
+		})
+	}
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/playwright-community/playwright-go"
 
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type PlaceJobOptions func(*PlaceJob)
@@ -23,6 +26,33 @@ type PlaceJob struct {
 	ExtractEmail        bool
 	ExitMonitor         exiter.Exiter
 	ExtractExtraReviews bool
+	Center              *MapLocation
+	Query               string
+	Region              string
+	Extras              *RequestExtras
+	NoiseFilter         *NoiseFilter
+	Delay               time.Duration
+	// TraceParent is the traceparent of the span of the search that found the place
+	TraceParent string
+	Throttle    Throttle
+
+	trace string
+	// hostLimiter, contacts, guesser, verifier, enrichers, blockGuard, fingerprints, sessions, screenshots, debug, videos, recorder, failures, breakers and retryer are not encoded, the runner that decodes the job sets them again
+	hostLimiter  *HostLimiter
+	contacts     *ContactFinder
+	guesser      *EmailGuesser
+	verifier     *EmailVerifier
+	enrichers    []Enricher
+	blockGuard   *BlockGuard
+	fingerprints *FingerprintRotator
+	sessions     *SessionStore
+	screenshots  *ScreenshotStore
+	debug        *DebugRecorder
+	videos       *VideoRecorder
+	recorder     *metrics.Recorder
+	failures     *FailureLog
+	breakers     *CircuitBreakers
+	retryer      *Retryer
 }
 
 func NewPlaceJob(parentID, langCode, u string, extractEmail, extraExtraReviews bool, opts ...PlaceJobOptions) *PlaceJob {
@@ -60,40 +90,311 @@ func WithPlaceJobExitMonitor(exitMonitor exiter.Exiter) PlaceJobOptions {
 	}
 }
 
-func (j *PlaceJob) Process(_ context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+func WithPlaceJobCenter(lat, lon float64) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Center = &MapLocation{Lat: lat, Lon: lon}
+	}
+}
+
+func WithPlaceJobQuery(query string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Query = query
+	}
+}
+
+// WithPlaceJobRegion sets the gl parameter of the place and review requests.
+func WithPlaceJobRegion(region string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Region = region
+		j.URLParams["gl"] = region
+	}
+}
+
+// WithPlaceJobRequestExtras adds headers and cookies to the place page and the review requests.
+func WithPlaceJobRequestExtras(e *RequestExtras) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Extras = e
+	}
+}
+
+// WithPlaceJobDelay pauses before the place page is loaded.
+func WithPlaceJobDelay(d time.Duration) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Delay = d
+	}
+}
+
+// WithPlaceJobNoiseFilter drops the place when it is not a business.
+func WithPlaceJobNoiseFilter(f *NoiseFilter) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.NoiseFilter = f
+	}
+}
+
+// WithPlaceJobThrottle paces the place page with t.
+func WithPlaceJobThrottle(t Throttle) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Throttle = t
+	}
+}
+
+// WithPlaceJobHostLimiter paces the email crawl of the place with l.
+func WithPlaceJobHostLimiter(l *HostLimiter) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.hostLimiter = l
+	}
+}
+
+// WithPlaceJobContactFinder looks for the emails of the place on the contact
+// pages found by f when its website has none.
+func WithPlaceJobContactFinder(f *ContactFinder) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.contacts = f
+	}
+}
+
+// WithPlaceJobEmailGuesser guesses the emails of the place with g when its
+// website has none.
+func WithPlaceJobEmailGuesser(g *EmailGuesser) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.guesser = g
+	}
+}
+
+// WithPlaceJobEmailVerifier verifies the emails of the place with v.
+func WithPlaceJobEmailVerifier(v *EmailVerifier) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.verifier = v
+	}
+}
+
+// WithPlaceJobEnrichers runs enrichers over the homepage of the place
+// instead of the built-in ones.
+func WithPlaceJobEnrichers(enrichers []Enricher) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.enrichers = enrichers
+	}
+}
+
+// WithPlaceJobMetrics records the metrics of the place and of its email job with r.
+func WithPlaceJobMetrics(r *metrics.Recorder) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.recorder = r
+	}
+}
+
+// WithPlaceJobBlockGuard reports the captchas of the place page to g.
+func WithPlaceJobBlockGuard(g *BlockGuard) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.blockGuard = g
+	}
+}
+
+// WithPlaceJobFingerprints gives the browser contexts of the job, and of its
+// email job, a fingerprint of r.
+func WithPlaceJobFingerprints(r *FingerprintRotator) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.fingerprints = r
+	}
+}
+
+// WithPlaceJobSessions restores and saves the cookies of the browsers of the job in s.
+func WithPlaceJobSessions(s *SessionStore) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.sessions = s
+	}
+}
+
+// WithPlaceJobScreenshots keeps a screenshot of the place page in s.
+func WithPlaceJobScreenshots(s *ScreenshotStore) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.screenshots = s
+	}
+}
+
+// WithPlaceJobCircuitBreakers fails the place at once while the circuit of
+// google is open in b, and its website while its circuit is.
+func WithPlaceJobCircuitBreakers(b *CircuitBreakers) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.breakers = b
+	}
+}
+
+// WithPlaceJobRetryer retries the website of the place with r when it is
+// rate limited.
+func WithPlaceJobRetryer(r *Retryer) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.retryer = r
+	}
+}
+
+// WithPlaceJobFailureLog records the errors of the job, and of its email
+// job, in l.
+func WithPlaceJobFailureLog(l *FailureLog) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.failures = l
+	}
+}
+
+// WithPlaceJobDebugRecorder records the page of the job in d and keeps the
+// recording when the job fails.
+func WithPlaceJobDebugRecorder(d *DebugRecorder) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.debug = d
+	}
+}
+
+// WithPlaceJobVideoRecorder keeps or deletes the video of the page of the
+// job with v.
+func WithPlaceJobVideoRecorder(v *VideoRecorder) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.videos = v
+	}
+}
+
+// WithPlaceJobTraceParent makes the spans of the job children of the given traceparent.
+func WithPlaceJobTraceParent(traceParent string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.TraceParent = traceParent
+	}
+}
+
+// Traceparent returns the traceparent of the span of the place page.
+func (j *PlaceJob) Traceparent() string {
+	if j.trace != "" {
+		return j.trace
+	}
+
+	return j.TraceParent
+}
+
+func (j *PlaceJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
 		resp.Meta = nil
 	}()
 
+	ctx, span := tracing.Start(ctx, j.Traceparent(), "gmaps.place.process")
+	defer span.End()
+
 	raw, ok := resp.Meta["json"].([]byte)
 	if !ok {
-		return nil, nil, fmt.Errorf("could not convert to []byte")
+		err := fmt.Errorf("could not convert to []byte")
+		j.failures.RecordJob(j.failure(FailureParse), err)
+
+		return nil, nil, err
 	}
 
 	entry, err := EntryFromJSON(raw)
 	if err != nil {
+		j.failures.RecordJob(j.failure(FailureParse), err)
+
 		return nil, nil, err
 	}
 
 	entry.ID = j.ParentID
+	entry.Query = j.Query
+	entry.Source = StrategyBrowser
 
 	if entry.Link == "" {
 		entry.Link = j.GetURL()
 	}
 
+	entry.ComputeOpenNow(time.Now().UTC())
+
+	if j.Center != nil {
+		entry.SetDistanceFrom(j.Center.Lat, j.Center.Lon)
+	}
+
 	allReviewsRaw, ok := resp.Meta["reviews_raw"].(fetchReviewsResponse)
 	if ok && len(allReviewsRaw.pages) > 0 {
 		entry.AddExtraReviews(allReviewsRaw.pages)
 	}
 
+	// noise is dropped before the website is visited for emails
+	if j.NoiseFilter.Drop(&entry) {
+		j.UsageInResultststs = false
+
+		if j.ExitMonitor != nil {
+			j.ExitMonitor.IncrPlacesCompleted(1)
+		}
+
+		return nil, nil, nil
+	}
+
+	if png, ok := resp.Meta["screenshot"].([]byte); ok {
+		name := entry.Cid
+		if name == "" {
+			name = j.ID
+		}
+
+		entry.ScreenshotPath, err = j.screenshots.Save(ctx, name, png)
+		if err != nil {
+			scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to save the screenshot of %s: %v", entry.Link, err))
+
+			err = nil
+		}
+	}
+
+	fetched := entry.ReconcileReviews()
+
+	entry.DetectLanguages()
+	if j.ExtractExtraReviews && !entry.ReviewsComplete {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("extracted %d of %d reviews for %s", fetched, entry.ReviewCount, entry.Link))
+	}
+
 	if j.ExtractEmail && entry.IsWebsiteValidForEmail() {
 		opts := []EmailExtractJobOptions{}
 		if j.ExitMonitor != nil {
 			opts = append(opts, WithEmailJobExitMonitor(j.ExitMonitor))
 		}
 
+		if trace := j.Traceparent(); trace != "" {
+			opts = append(opts, WithEmailJobTraceParent(trace))
+		}
+
+		if j.hostLimiter != nil {
+			opts = append(opts, WithEmailJobHostLimiter(j.hostLimiter))
+		}
+
+		if j.contacts != nil {
+			opts = append(opts, WithEmailJobContactFinder(j.contacts))
+		}
+
+		if j.guesser != nil {
+			opts = append(opts, WithEmailJobGuesser(j.guesser))
+		}
+
+		if j.verifier != nil {
+			opts = append(opts, WithEmailJobVerifier(j.verifier))
+		}
+
+		if j.enrichers != nil {
+			opts = append(opts, WithEmailJobEnrichers(j.enrichers))
+		}
+
+		if j.breakers != nil {
+			opts = append(opts, WithEmailJobCircuitBreakers(j.breakers))
+		}
+
+		if j.retryer != nil {
+			opts = append(opts, WithEmailJobRetryer(j.retryer))
+		}
+
+		if j.recorder != nil {
+			opts = append(opts, WithEmailJobMetrics(j.recorder))
+		}
+
+		if j.failures != nil {
+			opts = append(opts, WithEmailJobFailureLog(j.failures))
+		}
+
+		if j.fingerprints != nil {
+			opts = append(opts, WithEmailJobFingerprints(j.fingerprints))
+		}
+
 		emailJob := NewEmailJob(j.ID, &entry, opts...)
 
 		j.UsageInResultststs = false
@@ -107,35 +408,91 @@ func (j *PlaceJob) Process(_ context.Context, resp *scrapemate.Response) (any, [
 }
 
 func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
-	var resp scrapemate.Response
+	trackPage(page)
+
+	start := time.Now()
+
+	ctx, span := tracing.Start(ctx, j.TraceParent, "gmaps.place",
+		attribute.String("job.id", j.ID),
+		attribute.String("url", j.GetURL()),
+		attribute.String("query", j.Query),
+	)
+
+	j.debug.Start(page)
 
-	pageResponse, err := page.Goto(j.GetURL(), playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+	resp := j.breakers.guard(j.GetURL(), func() scrapemate.Response {
+		return throttle(ctx, j.Throttle, page, func() scrapemate.Response {
+			return j.browserActions(ctx, page)
+		})
 	})
-	if err != nil {
+
+	if err := j.debug.Finish(page, j.ID, resp.Error); err != nil {
+		scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to save the debug recording: %v", err))
+	}
+
+	j.videos.Finish(ctx, page, j.ID, resp.Error)
+
+	j.trace = tracing.Parent(ctx)
+	tracing.End(span, resp.Error)
+	j.recorder.Scrape(metrics.JobTypePlace, time.Since(start), resp.Error)
+	j.failures.RecordJob(j.failure(""), resp.Error)
+
+	return resp
+}
+
+// failure is the failure of the job, of class when it is known.
+func (j *PlaceJob) failure(class string) Failure {
+	return Failure{JobID: j.ID, InputID: j.ParentID, Type: "place", URL: j.GetURL(), Class: class}
+}
+
+func (j *PlaceJob) browserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	var resp scrapemate.Response
+
+	if err := pause(ctx, j.Delay); err != nil {
 		resp.Error = err
 
 		return resp
 	}
 
-	if err = clickRejectCookiesIfRequired(page); err != nil {
+	if err := j.blockGuard.Wait(ctx); err != nil {
 		resp.Error = err
 
 		return resp
 	}
 
-	const defaultTimeout = 5000
+	if err := j.fingerprints.Apply(page); err != nil {
+		resp.Error = err
 
-	err = page.WaitForURL(page.URL(), playwright.PageWaitForURLOptions{
-		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
-		Timeout:   playwright.Float(defaultTimeout),
-	})
+		return resp
+	}
+
+	if err := j.sessions.Restore(page); err != nil {
+		scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to restore the session: %v", err))
+	}
+
+	if err := j.Extras.applyToPage(page, j.GetURL()); err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	pageResponse, err := navigate(ctx, page, j.GetURL())
 	if err != nil {
 		resp.Error = err
 
 		return resp
 	}
 
+	if isBlockedPage(page) && !j.blockGuard.Blocked(ctx, page) {
+		resp.Error = ErrBlocked
+
+		return resp
+	}
+
+	if err := j.sessions.Save(page); err != nil {
+		scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to save the session: %v", err))
+	}
+
 	resp.URL = pageResponse.URL()
 	resp.StatusCode = pageResponse.Status()
 	resp.Headers = make(http.Header, len(pageResponse.Headers()))
@@ -144,7 +501,12 @@ func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scr
 		resp.Headers.Add(k, v)
 	}
 
+	_, span := tracing.Start(ctx, "", "extract_json")
+
 	raw, err := j.extractJSON(page)
+
+	tracing.End(span, err)
+
 	if err != nil {
 		resp.Error = err
 
@@ -157,6 +519,14 @@ func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scr
 
 	resp.Meta["json"] = raw
 
+	// the screenshot is taken before the reviews are scrolled
+	png, err := j.screenshots.capture(page)
+	if err != nil {
+		scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("failed to take the screenshot of %s: %v", j.GetURL(), err))
+	} else if png != nil {
+		resp.Meta["screenshot"] = png
+	}
+
 	if j.ExtractExtraReviews {
 		reviewCount := j.getReviewCount(raw)
 		if reviewCount > 8 { // we have more reviews
@@ -164,11 +534,19 @@ func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scr
 				page:        page,
 				mapURL:      page.URL(),
 				reviewCount: reviewCount,
+				langCode:    j.URLParams["hl"],
+				region:      j.Region,
+				extras:      j.Extras,
 			}
 
 			reviewFetcher := newReviewFetcher(params)
 
-			reviewData, err := reviewFetcher.fetch(ctx)
+			reviewCtx, span := tracing.Start(ctx, "", "reviews", attribute.Int("reviews", reviewCount))
+
+			reviewData, err := reviewFetcher.fetch(reviewCtx)
+
+			tracing.End(span, err)
+
 			if err != nil {
 				return resp
 			}
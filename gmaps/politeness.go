@@ -0,0 +1,101 @@
+package gmaps
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pruneSize is the number of hosts above which the idle ones are forgotten
+const pruneSize = 1024
+
+// HostLimiter spaces the requests to each website host with a token bucket,
+// so the email crawl of many places on the same site does not hammer it.
+// It is safe to share between jobs.
+type HostLimiter struct {
+	rate  float64
+	burst float64
+
+	mu    sync.Mutex
+	hosts map[string]*hostBucket
+}
+
+type hostBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewHostLimiter allows perSecond requests to each host, with bursts of up to
+// burst requests. It returns nil, which allows everything, when perSecond is zero.
+func NewHostLimiter(perSecond float64, burst int) *HostLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &HostLimiter{
+		rate:  perSecond,
+		burst: float64(max(1, burst)),
+		hosts: make(map[string]*hostBucket),
+	}
+}
+
+// Wait blocks until a request to the host of u is allowed or ctx is done.
+func (l *HostLimiter) Wait(ctx context.Context, u string) error {
+	if l == nil {
+		return nil
+	}
+
+	host := limiterHost(u)
+	if host == "" {
+		return nil
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &hostBucket{tokens: l.burst, last: now}
+		l.hosts[host] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	// the token is reserved now, a negative balance is the wait of the queued requests
+	b.tokens--
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / l.rate * float64(time.Second))
+	}
+
+	if len(l.hosts) > pruneSize {
+		l.prune(now)
+	}
+
+	l.mu.Unlock()
+
+	return pause(ctx, wait)
+}
+
+// prune forgets the hosts whose bucket is full again. l.mu must be held.
+func (l *HostLimiter) prune(now time.Time) {
+	for host, b := range l.hosts {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.hosts, host)
+		}
+	}
+}
+
+func limiterHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
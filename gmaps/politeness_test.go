@@ -0,0 +1,39 @@
+package gmaps_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_HostLimiter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	require.Nil(t, gmaps.NewHostLimiter(0, 1))
+	require.NoError(t, (*gmaps.HostLimiter)(nil).Wait(ctx, "https://example.com"))
+
+	l := gmaps.NewHostLimiter(10, 2)
+
+	start := time.Now()
+
+	// the burst, another host and the www. prefix
+	require.NoError(t, l.Wait(ctx, "https://example.com/contact"))
+	require.NoError(t, l.Wait(ctx, "https://www.example.com/about"))
+	require.NoError(t, l.Wait(ctx, "https://example.org"))
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+
+	// the third request to example.com waits for a token
+	require.NoError(t, l.Wait(ctx, "https://example.com/team"))
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	require.ErrorIs(t, l.Wait(cancelled, "https://example.com"), context.Canceled)
+}
@@ -0,0 +1,98 @@
+package gmaps
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const defaultGoogleHost = "www.google.com"
+
+// googleDomains maps a country code to its Google domain.
+// Countries that are not listed use google.com and rely only on the gl parameter.
+var googleDomains = map[string]string{
+	"ae": "google.ae",
+	"ar": "google.com.ar",
+	"at": "google.at",
+	"au": "google.com.au",
+	"be": "google.be",
+	"br": "google.com.br",
+	"ca": "google.ca",
+	"ch": "google.ch",
+	"cl": "google.cl",
+	"co": "google.com.co",
+	"cz": "google.cz",
+	"de": "google.de",
+	"dk": "google.dk",
+	"eg": "google.com.eg",
+	"es": "google.es",
+	"fi": "google.fi",
+	"fr": "google.fr",
+	"gb": "google.co.uk",
+	"gr": "google.gr",
+	"hk": "google.com.hk",
+	"hu": "google.hu",
+	"id": "google.co.id",
+	"ie": "google.ie",
+	"il": "google.co.il",
+	"in": "google.co.in",
+	"it": "google.it",
+	"jp": "google.co.jp",
+	"kr": "google.co.kr",
+	"mx": "google.com.mx",
+	"my": "google.com.my",
+	"ng": "google.com.ng",
+	"nl": "google.nl",
+	"no": "google.no",
+	"nz": "google.co.nz",
+	"pe": "google.com.pe",
+	"ph": "google.com.ph",
+	"pk": "google.com.pk",
+	"pl": "google.pl",
+	"pt": "google.pt",
+	"ro": "google.ro",
+	"ru": "google.ru",
+	"sa": "google.com.sa",
+	"se": "google.se",
+	"sg": "google.com.sg",
+	"th": "google.co.th",
+	"tr": "google.com.tr",
+	"tw": "google.com.tw",
+	"ua": "google.com.ua",
+	"us": "google.com",
+	"vn": "google.com.vn",
+	"za": "google.co.za",
+}
+
+// ValidateRegion checks that region is empty or a two letter country code.
+func ValidateRegion(region string) error {
+	if region == "" {
+		return nil
+	}
+
+	if len(region) != 2 || strings.Trim(strings.ToLower(region), "abcdefghijklmnopqrstuvwxyz") != "" {
+		return fmt.Errorf("invalid region: %s", region)
+	}
+
+	return nil
+}
+
+// GoogleHost returns the Google host to use for the given country code.
+func GoogleHost(region string) string {
+	if domain, ok := googleDomains[strings.ToLower(region)]; ok {
+		return "www." + domain
+	}
+
+	return defaultGoogleHost
+}
+
+// googleHostFromURL returns the host of u when it is a Google host.
+// It is used to keep follow up requests on the domain the place was found on.
+func googleHostFromURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || !strings.Contains(parsed.Hostname(), "google.") {
+		return defaultGoogleHost
+	}
+
+	return parsed.Hostname()
+}
@@ -0,0 +1,185 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is the error of the website pages answered with 429 Too Many
+// Requests or 503 Service Unavailable, see RateLimitError.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitError is the error of a page answered with StatusCode 429 or 503,
+// with the wait asked by its Retry-After header, zero when it has none.
+type RateLimitError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s: rate limited with status %d, retry after %s", e.URL, e.StatusCode, e.RetryAfter)
+	}
+
+	return fmt.Sprintf("%s: rate limited with status %d", e.URL, e.StatusCode)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// CheckRateLimit returns a RateLimitError when the answer to u with status and
+// header is a 429 or a 503, nil otherwise.
+func CheckRateLimit(u string, status int, header http.Header) error {
+	if status != http.StatusTooManyRequests && status != http.StatusServiceUnavailable {
+		return nil
+	}
+
+	return &RateLimitError{
+		URL:        u,
+		StatusCode: status,
+		RetryAfter: ParseRetryAfter(header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// ParseRetryAfter returns the wait of a Retry-After header, in seconds or an
+// HTTP date, from now. It is zero when the header is empty, invalid or past.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(0, seconds)) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
+// Retryer retries the website pages that were rate limited, see
+// RateLimitError, after the Retry-After of the answer or, without one, a
+// backoff that doubles from one second. The wait is capped at maxWait. Until
+// it is over the other pages of the host wait too, the cooldown of the host.
+// It is safe to share between jobs.
+type Retryer struct {
+	retries int
+	maxWait time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]time.Time
+}
+
+// NewRetryer retries a rate limited page up to retries times, waiting up to
+// maxWait each time. It returns nil, which loads each page once, when retries
+// is zero.
+func NewRetryer(retries int, maxWait time.Duration) *Retryer {
+	if retries <= 0 {
+		return nil
+	}
+
+	return &Retryer{
+		retries: retries,
+		maxWait: maxWait,
+		hosts:   make(map[string]time.Time),
+	}
+}
+
+// Do waits for the cooldown of the host of u and calls fetch, again while it
+// returns a RateLimitError and retries are left. It returns the error of the
+// last call, or of ctx when it is done while waiting.
+func (r *Retryer) Do(ctx context.Context, u string, fetch func() error) error {
+	if r == nil {
+		return fetch()
+	}
+
+	host := limiterHost(u)
+
+	for attempt := 0; ; attempt++ {
+		if err := pause(ctx, r.Cooldown(host)); err != nil {
+			return err
+		}
+
+		err := fetch()
+
+		var limited *RateLimitError
+		if !errors.As(err, &limited) {
+			return err
+		}
+
+		wait := limited.RetryAfter
+		if wait <= 0 {
+			wait = time.Second << min(attempt, 10)
+		}
+
+		r.cool(host, min(wait, r.maxWait))
+
+		if attempt >= r.retries {
+			return err
+		}
+	}
+}
+
+// Cooldown returns the time the pages of host, e.g. example.com, still have
+// to wait.
+func (r *Retryer) Cooldown(host string) time.Duration {
+	if r == nil || host == "" {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	until, ok := r.hosts[host]
+	if !ok {
+		return 0
+	}
+
+	wait := time.Until(until)
+	if wait <= 0 {
+		delete(r.hosts, host)
+
+		return 0
+	}
+
+	return wait
+}
+
+// cool holds the pages of host back for d, unless they already wait longer.
+func (r *Retryer) cool(host string, d time.Duration) {
+	if host == "" || d <= 0 {
+		return
+	}
+
+	until := time.Now().Add(d)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if until.Before(r.hosts[host]) {
+		return
+	}
+
+	if len(r.hosts) >= pruneSize {
+		now := time.Now()
+
+		for h, t := range r.hosts {
+			if !t.After(now) {
+				delete(r.hosts, h)
+			}
+		}
+	}
+
+	r.hosts[host] = until
+}
@@ -0,0 +1,99 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.Equal(t, 30*time.Second, ParseRetryAfter("30", now))
+	require.Equal(t, 2*time.Minute, ParseRetryAfter("Wed, 01 May 2024 12:02:00 GMT", now))
+	require.Zero(t, ParseRetryAfter("Wed, 01 May 2024 11:00:00 GMT", now))
+	require.Zero(t, ParseRetryAfter("", now))
+	require.Zero(t, ParseRetryAfter("soon", now))
+	require.Zero(t, ParseRetryAfter("-5", now))
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "5")
+
+	err := CheckRateLimit("https://example.com", http.StatusTooManyRequests, header)
+
+	var limited *RateLimitError
+
+	require.ErrorAs(t, err, &limited)
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, 5*time.Second, limited.RetryAfter)
+	require.Equal(t, FailureRateLimited, ErrorClass(err))
+
+	require.ErrorIs(t, CheckRateLimit("https://example.com", http.StatusServiceUnavailable, http.Header{}), ErrRateLimited)
+	require.NoError(t, CheckRateLimit("https://example.com", http.StatusOK, header))
+	require.NoError(t, CheckRateLimit("https://example.com", http.StatusNotFound, header))
+}
+
+func TestRetryer(t *testing.T) {
+	r := NewRetryer(2, 10*time.Millisecond)
+
+	const u = "https://www.example.com/contact"
+
+	calls := 0
+	err := r.Do(context.Background(), u, func() error {
+		calls++
+
+		if calls < 3 {
+			return &RateLimitError{URL: u, StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	// the retries are exhausted and the host cools down
+	calls = 0
+	err = r.Do(context.Background(), u, func() error {
+		calls++
+
+		return &RateLimitError{URL: u, StatusCode: http.StatusServiceUnavailable}
+	})
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, 3, calls)
+	require.Positive(t, r.Cooldown("example.com"))
+	require.Zero(t, r.Cooldown("other.org"))
+
+	// the other errors are not retried
+	calls = 0
+	err = r.Do(context.Background(), "https://other.org", func() error {
+		calls++
+
+		return errors.New("timeout")
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, r.Do(ctx, u, func() error { return nil }), context.Canceled)
+
+	var disabled *Retryer
+
+	require.Nil(t, NewRetryer(0, time.Minute))
+
+	calls = 0
+	err = disabled.Do(context.Background(), u, func() error {
+		calls++
+
+		return &RateLimitError{URL: u, StatusCode: http.StatusTooManyRequests}
+	})
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, 1, calls)
+}
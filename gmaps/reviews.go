@@ -1,6 +1,7 @@
 package gmaps
 
 import (
+	"cmp"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
@@ -20,6 +21,9 @@ type fetchReviewsParams struct {
 	page        playwright.Page
 	mapURL      string
 	reviewCount int
+	langCode    string
+	region      string
+	extras      *RequestExtras
 }
 
 type fetchReviewsResponse struct {
@@ -109,8 +113,19 @@ func (f *fetcher) generateURL(mapURL, pageToken string, pageSize int, requestID
 		"!12m4!1b1!2b1!4m1!1e1!11m0!13m1!1e1",
 	}
 
+	query := url.Values{}
+	query.Set("authuser", "0")
+	query.Set("hl", cmp.Or(f.params.langCode, "en"))
+
+	if f.params.region != "" {
+		query.Set("gl", f.params.region)
+	}
+
+	// the pb components must not be escaped
 	fullURL := fmt.Sprintf(
-		"https://www.google.com/maps/rpc/listugcposts?authuser=0&hl=el&pb=%s",
+		"https://%s/maps/rpc/listugcposts?%s&pb=%s",
+		googleHostFromURL(mapURL),
+		query.Encode(),
 		strings.Join(pbComponents, ""),
 	)
 
@@ -118,6 +133,12 @@ func (f *fetcher) generateURL(mapURL, pageToken string, pageSize int, requestID
 }
 
 func (f *fetcher) fetchReviewPage(ctx context.Context, u string) ([]byte, error) {
+	// the stealth client sends a fixed set of headers so when extra headers or
+	// cookies are configured the request goes through the browser context instead
+	if !f.params.extras.IsZero() && f.params.page != nil {
+		return f.fetchReviewPageWithBrowser(u)
+	}
+
 	job := scrapemate.Job{
 		Method: "GET",
 		URL:    u,
@@ -135,6 +156,25 @@ func (f *fetcher) fetchReviewPage(ctx context.Context, u string) ([]byte, error)
 	return resp.Body, nil
 }
 
+func (f *fetcher) fetchReviewPageWithBrowser(u string) ([]byte, error) {
+	resp, err := f.params.page.Request().Get(u, playwright.APIRequestContextGetOptions{
+		Headers: f.params.extras.Headers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch error for %s: %w", u, err)
+	}
+
+	defer func() {
+		_ = resp.Dispose()
+	}()
+
+	if resp.Status() != 200 {
+		return nil, fmt.Errorf("%s: unexpected status code: %d", u, resp.Status())
+	}
+
+	return resp.Body()
+}
+
 func extractNextPageToken(data []byte) string {
 	text := string(data)
 	prefix := ")]}'\n"
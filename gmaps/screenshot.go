@@ -0,0 +1,106 @@
+package gmaps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// ScreenshotUploader copies the screenshots to an S3-compatible storage.
+type ScreenshotUploader interface {
+	Upload(ctx context.Context, bucketName, key string, body io.Reader) error
+}
+
+type ScreenshotStoreOption func(*ScreenshotStore)
+
+// WithScreenshotUploader uploads the screenshots to bucket under prefix
+// instead of writing them to the folder.
+func WithScreenshotUploader(u ScreenshotUploader, bucket, prefix string) ScreenshotStoreOption {
+	return func(s *ScreenshotStore) {
+		s.uploader = u
+		s.bucket = bucket
+		s.prefix = prefix
+	}
+}
+
+// ScreenshotStore keeps a screenshot of the page of each place, named after
+// its cid. It is safe to share between jobs.
+type ScreenshotStore struct {
+	dir string
+
+	uploader ScreenshotUploader
+	bucket   string
+	prefix   string
+}
+
+// NewScreenshotStore stores the screenshots in dir.
+func NewScreenshotStore(dir string, opts ...ScreenshotStoreOption) (*ScreenshotStore, error) {
+	ans := ScreenshotStore{dir: dir}
+
+	for _, opt := range opts {
+		opt(&ans)
+	}
+
+	if ans.uploader == nil {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create the screenshot folder: %w", err)
+		}
+	}
+
+	return &ans, nil
+}
+
+// capture returns a full page screenshot of page in png, nil when s is nil.
+func (s *ScreenshotStore) capture(page playwright.Page) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	return page.Screenshot(playwright.PageScreenshotOptions{
+		FullPage: playwright.Bool(true),
+		Type:     playwright.ScreenshotTypePng,
+	})
+}
+
+// Save stores the png of the place called name and returns its path, or its
+// s3:// url when the screenshots are uploaded.
+func (s *ScreenshotStore) Save(ctx context.Context, name string, png []byte) (string, error) {
+	name = safeFileName(name) + ".png"
+
+	if s.uploader != nil {
+		key := path.Join(s.prefix, name)
+
+		if err := s.uploader.Upload(ctx, s.bucket, key, bytes.NewReader(png)); err != nil {
+			return "", fmt.Errorf("failed to upload the screenshot: %w", err)
+		}
+
+		return "s3://" + s.bucket + "/" + key, nil
+	}
+
+	fpath := filepath.Join(s.dir, name)
+
+	if err := os.WriteFile(fpath, png, 0o600); err != nil {
+		return "", fmt.Errorf("failed to save the screenshot: %w", err)
+	}
+
+	return fpath, nil
+}
+
+// safeFileName keeps the characters of name that are safe in a file name.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,29 @@
+package gmaps_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_ScreenshotStoreSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "screenshots")
+
+	s, err := gmaps.NewScreenshotStore(dir)
+	require.NoError(t, err)
+
+	fpath, err := s.Save(context.Background(), "0x1:../0x2", []byte("png"))
+	require.NoError(t, err)
+
+	// the name cannot leave the folder
+	require.Equal(t, filepath.Join(dir, "0x1____0x2.png"), fpath)
+
+	data, err := os.ReadFile(fpath)
+	require.NoError(t, err)
+	require.Equal(t, "png", string(data))
+}
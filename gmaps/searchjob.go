@@ -3,11 +3,15 @@ package gmaps
 import (
 	"bytes"
 	"context"
+	"encoding/gob"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/tiling"
 	"github.com/gosom/scrapemate"
 )
 
@@ -26,6 +30,13 @@ type MapSearchParams struct {
 	ViewportW int
 	ViewportH int
 	Hl        string
+	// Gl is the country code of the Google domain to search on
+	Gl string
+	// Exclude are the zones whose tiles are not searched when a tile is split
+	Exclude tiling.Exclusions
+	// Circle is the area of a circle coverage. The places outside it are
+	// dropped and the distance is measured from its center.
+	Circle *MapLocation
 }
 
 type SearchJob struct {
@@ -33,15 +44,25 @@ type SearchJob struct {
 
 	params      *MapSearchParams
 	ExitMonitor exiter.Exiter
+
+	tile     *tiling.Tile
+	density  *tiling.Density
+	noise    *NoiseFilter
+	delay    time.Duration
+	fallback *fallback
 }
 
 func NewSearchJob(params *MapSearchParams, opts ...SearchJobOptions) *SearchJob {
 	const (
 		defaultPrio       = scrapemate.PriorityMedium
 		defaultMaxRetries = 3
-		baseURL           = "https://maps.google.com/search"
 	)
 
+	baseURL := "https://maps.google.com/search"
+	if params.Gl != "" {
+		baseURL = fmt.Sprintf("https://%s/search", GoogleHost(params.Gl))
+	}
+
 	job := SearchJob{
 		Job: scrapemate.Job{
 			ID:         uuid.New().String(),
@@ -68,36 +89,235 @@ func WithSearchJobExitMonitor(exitMonitor exiter.Exiter) SearchJobOptions {
 	}
 }
 
-func (j *SearchJob) Process(_ context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+// WithSearchJobRequestExtras sends the headers and cookies with the search request.
+func WithSearchJobRequestExtras(e *RequestExtras) SearchJobOptions {
+	return func(j *SearchJob) {
+		if !e.IsZero() {
+			j.Headers = e.httpHeaders()
+		}
+	}
+}
+
+// WithSearchJobDelay pauses after the search, before the worker takes the next job.
+// The search is fetched before the job is processed, so this is the only place to wait.
+func WithSearchJobDelay(d time.Duration) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.delay = d
+	}
+}
+
+// WithSearchJobNoiseFilter drops the places that are not businesses from the results.
+func WithSearchJobNoiseFilter(f *NoiseFilter) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.noise = f
+	}
+}
+
+// WithSearchJobExclusions skips the smaller tiles that overlap the zones when the tile of the job is split.
+func WithSearchJobExclusions(ex tiling.Exclusions) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.params.Exclude = ex
+	}
+}
+
+// WithSearchJobCircle drops the places farther than radius meters from lat,lon,
+// when the job searches one of the tiles of a circle.
+func WithSearchJobCircle(lat, lon, radius float64) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.params.Circle = &MapLocation{Lat: lat, Lon: lon, Radius: radius}
+	}
+}
+
+// WithSearchJobTile marks the job as the search of a tile. When density is
+// not nil, the tile is split in smaller tiles that are searched next when the
+// search is saturated.
+func WithSearchJobTile(tile tiling.Tile, density *tiling.Density) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.tile = &tile
+		j.density = density
+	}
+}
+
+// WithSearchJobFallback loads the search with next, the next step of its
+// FallbackChain, when it fails on one of the conditions on.
+func WithSearchJobFallback(next scrapemate.IJob, on []string) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.fallback = newFallback(next, on)
+	}
+}
+
+// searchJob is the encoding of a SearchJob.
+type searchJob struct {
+	Job    scrapemate.Job
+	Params *MapSearchParams
+}
+
+// GobEncode encodes the request and the parameters of the search, so the
+// static steps of a fallback chain can be queued. The exit monitor, the
+// noise filter, the delay and the fallback are not encoded, the runner that
+// decodes the job sets them again. The searches of the tiles are not
+// supported, they could not be split.
+func (j *SearchJob) GobEncode() ([]byte, error) {
+	if j.tile != nil {
+		return nil, errors.New("the search of a tile cannot be encoded")
+	}
+
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(searchJob{Job: j.Job, Params: j.params}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GobDecode decodes the search encoded by GobEncode.
+func (j *SearchJob) GobDecode(data []byte) error {
+	var s searchJob
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return err
+	}
+
+	j.Job, j.params = s.Job, s.Params
+
+	return nil
+}
+
+// ProcessOnFetchError makes a search with a fallback fall back when its
+// request fails.
+func (j *SearchJob) ProcessOnFetchError() bool {
+	return j.fallback != nil
+}
+
+func (j *SearchJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
 		resp.Meta = nil
 	}()
 
+	if err := pause(ctx, j.delay); err != nil {
+		return nil, nil, err
+	}
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	if resp.Error != nil {
+		if next := j.fallback.job(FallbackCondition(resp.Error, 0)); next != nil {
+			log.Info("search failed, falling back", "error", resp.Error)
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+
+		return nil, nil, resp.Error
+	}
+
 	body := removeFirstLine(resp.Body)
 	if len(body) == 0 {
+		if next := j.fallback.job(FallbackEmpty); next != nil {
+			log.Info("empty search response, falling back")
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+
 		return nil, nil, fmt.Errorf("empty response body")
 	}
 
 	entries, err := ParseSearchResults(body)
 	if err != nil {
+		if next := j.fallback.job(FallbackParse); next != nil {
+			log.Info("could not parse the search results, falling back", "error", err)
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+
 		return nil, nil, fmt.Errorf("failed to parse search results: %w", err)
 	}
 
+	if len(entries) == 0 {
+		if next := j.fallback.job(FallbackEmpty); next != nil {
+			log.Info("no places found, falling back")
+
+			return nil, []scrapemate.IJob{next}, nil
+		}
+	}
+
+	next, err := j.split(len(entries))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+
+	for i := range entries {
+		entries[i].Query = j.params.Query
+		entries[i].Source = StrategyStatic
+		entries[i].ComputeOpenNow(now)
+		entries[i].DetectLanguages()
+	}
+
 	entries = filterAndSortEntriesWithinRadius(entries,
 		j.params.Location.Lat,
 		j.params.Location.Lon,
 		j.params.Location.Radius,
 	)
 
+	if c := j.params.Circle; c != nil {
+		entries = filterAndSortEntriesWithinRadius(entries, c.Lat, c.Lon, c.Radius)
+	}
+
+	entries = j.noise.Filter(entries)
+
 	if j.ExitMonitor != nil {
+		// the new seeds are counted before this one completes so the crawl does not stop in between
+		j.ExitMonitor.IncrSeedCount(len(next))
 		j.ExitMonitor.IncrSeedCompleted(1)
 		j.ExitMonitor.IncrPlacesFound(len(entries))
 		j.ExitMonitor.IncrPlacesCompleted(len(entries))
 	}
 
-	return entries, nil, nil
+	return entries, next, nil
+}
+
+// split returns the searches of the smaller tiles the tile of the job is split in.
+// The places of the tile are still written, the ones found again are removed by the deduper.
+func (j *SearchJob) split(results int) ([]scrapemate.IJob, error) {
+	if j.tile == nil || j.density == nil {
+		return nil, nil
+	}
+
+	tiles, err := j.density.Observe(*j.tile, results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split tile %s: %w", j.tile.ID, err)
+	}
+
+	tiles = j.params.Exclude.Filter(tiles)
+
+	next := make([]scrapemate.IJob, 0, len(tiles))
+
+	for i := range tiles {
+		params := *j.params
+		params.Location = MapLocation{
+			Lat:     tiles[i].Lat,
+			Lon:     tiles[i].Lon,
+			ZoomLvl: float64(tiles[i].Zoom()),
+			Radius:  tiles[i].RadiusMeters,
+		}
+
+		child := NewSearchJob(&params,
+			WithSearchJobExitMonitor(j.ExitMonitor),
+			WithSearchJobTile(tiles[i], j.density),
+			WithSearchJobNoiseFilter(j.noise),
+			WithSearchJobDelay(j.delay),
+		)
+		child.ParentID = j.ID
+		child.Headers = j.Headers
+
+		next = append(next, child)
+	}
+
+	return next, nil
 }
 
 func removeFirstLine(data []byte) []byte {
@@ -124,6 +344,10 @@ func buildGoogleMapsParams(params *MapSearchParams) map[string]string {
 		"q":        params.Query,
 	}
 
+	if params.Gl != "" {
+		ans["gl"] = params.Gl
+	}
+
 	pb := fmt.Sprintf("!4m12!1m3!1d3826.902183192154!2d%.4f!3d%.4f!2m3!1f0!2f0!3f0!3m2!1i%d!2i%d!4f%.1f!7i20!8i0"+
 		"!10b1!12m22!1m3!18b1!30b1!34e1!2m3!5m1!6e2!20e3!4b0!10b1!12b1!13b1!16b1!17m1!3e1!20m3!5e2!6b1!14b1!46m1!1b0"+
 		"!96b1!19m4!2m3!1i360!2i120!4i8",
@@ -0,0 +1,182 @@
+package gmaps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// sessionSaveInterval is how often the cookies of a browser context are saved
+const sessionSaveInterval = time.Minute
+
+// SessionStore keeps the Google cookies of the browsers on disk, the consent
+// choice among them, and gives them to the next browsers that use the same
+// proxies and fingerprint, so they do not go through the consent dialog
+// again. It is safe to share between jobs.
+type SessionStore struct {
+	dir          string
+	proxy        string
+	fingerprints *FingerprintRotator
+
+	mu       sync.Mutex
+	contexts map[playwright.BrowserContext]*browserSession
+}
+
+type browserSession struct {
+	file  string
+	saved time.Time
+}
+
+// NewSessionStore stores the sessions in dir. proxy identifies the proxies
+// the browsers use, empty without proxies. fingerprints may be nil.
+func NewSessionStore(dir, proxy string, fingerprints *FingerprintRotator) (*SessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the session folder: %w", err)
+	}
+
+	return &SessionStore{
+		dir:          dir,
+		proxy:        proxy,
+		fingerprints: fingerprints,
+		contexts:     make(map[playwright.BrowserContext]*browserSession),
+	}, nil
+}
+
+// Restore adds the saved cookies to the browser context of the page the
+// first time the context is seen. It does nothing when s is nil.
+func (s *SessionStore) Restore(page playwright.Page) error {
+	if s == nil {
+		return nil
+	}
+
+	bctx := page.Context()
+	if bctx == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+
+	if _, ok := s.contexts[bctx]; ok {
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	session := &browserSession{file: s.file(bctx)}
+	s.contexts[bctx] = session
+
+	s.mu.Unlock()
+
+	bctx.OnClose(func(c playwright.BrowserContext) {
+		s.mu.Lock()
+		delete(s.contexts, c)
+		s.mu.Unlock()
+	})
+
+	data, err := os.ReadFile(session.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read the session: %w", err)
+	}
+
+	var saved []playwright.Cookie
+
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to decode the session: %w", err)
+	}
+
+	now := float64(time.Now().Unix())
+
+	cookies := make([]playwright.OptionalCookie, 0, len(saved))
+
+	for i := range saved {
+		// -1 is a session cookie
+		if saved[i].Expires > 0 && saved[i].Expires < now {
+			continue
+		}
+
+		cookies = append(cookies, saved[i].ToOptionalCookie())
+	}
+
+	if len(cookies) == 0 {
+		return nil
+	}
+
+	return bctx.AddCookies(cookies)
+}
+
+// Save writes the Google cookies of the browser context of the page, at most
+// once a minute per context. It does nothing when s is nil or the context was
+// not restored.
+func (s *SessionStore) Save(page playwright.Page) error {
+	if s == nil {
+		return nil
+	}
+
+	bctx := page.Context()
+
+	s.mu.Lock()
+
+	session, ok := s.contexts[bctx]
+	if !ok || time.Since(session.saved) < sessionSaveInterval {
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	session.saved = time.Now()
+
+	s.mu.Unlock()
+
+	cookies, err := bctx.Cookies()
+	if err != nil {
+		return fmt.Errorf("failed to get the cookies: %w", err)
+	}
+
+	google := make([]playwright.Cookie, 0, len(cookies))
+
+	for i := range cookies {
+		if strings.Contains(cookies[i].Domain, "google.") {
+			google = append(google, cookies[i])
+		}
+	}
+
+	data, err := json.Marshal(google)
+	if err != nil {
+		return err
+	}
+
+	tmp := session.file + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save the session: %w", err)
+	}
+
+	return os.Rename(tmp, session.file)
+}
+
+// file returns the session file of the proxies and the fingerprint of the
+// context. The name is a hash, the proxies may hold credentials.
+func (s *SessionStore) file(bctx playwright.BrowserContext) string {
+	key := s.proxy
+
+	if fp, ok := s.fingerprints.Of(bctx); ok {
+		key += "\n" + fp.UserAgent + "\n" + fp.Platform
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8])+".json")
+}
@@ -0,0 +1,231 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The platforms of Entry.SocialProfiles.
+const (
+	SocialFacebook  = "facebook"
+	SocialInstagram = "instagram"
+	SocialTwitter   = "twitter"
+	SocialLinkedIn  = "linkedin"
+	SocialYouTube   = "youtube"
+	SocialTikTok    = "tiktok"
+)
+
+// socialHosts are the platforms by host, without www. and m.
+var socialHosts = map[string]string{
+	"facebook.com":  SocialFacebook,
+	"fb.com":        SocialFacebook,
+	"instagram.com": SocialInstagram,
+	"twitter.com":   SocialTwitter,
+	"x.com":         SocialTwitter,
+	"linkedin.com":  SocialLinkedIn,
+	"youtube.com":   SocialYouTube,
+	"tiktok.com":    SocialTikTok,
+}
+
+// socialReserved are the first path segments of the share, login and content
+// pages of the platforms, which are not profiles.
+var socialReserved = map[string][]string{
+	SocialFacebook: {
+		"sharer", "sharer.php", "share", "share.php", "dialog", "login", "login.php", "plugins", "tr",
+		"events", "watch", "photo", "photo.php", "story.php", "permalink.php", "hashtag", "help", "policies",
+	},
+	SocialInstagram: {"p", "reel", "reels", "explore", "accounts", "about", "developer", "legal", "tv"},
+	SocialTwitter:   {"intent", "share", "home", "search", "hashtag", "i", "login", "signup", "tos", "privacy"},
+	SocialYouTube:   {"watch", "embed", "results", "playlist", "shorts", "feed", "redirect"},
+}
+
+var socialHandleRe = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// SocialHandle returns the platform and the canonical handle of the profile
+// link: the page name on Facebook, @handle on Instagram, Twitter, YouTube
+// and TikTok, company/name or in/name on LinkedIn. It returns false for the
+// links that are not profiles, such as share and login links or posts.
+func SocialHandle(link string) (platform, handle string, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "mobile.", "web."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+
+	// the localized subdomains of linkedin, e.g. de.linkedin.com
+	if strings.HasSuffix(host, ".linkedin.com") {
+		host = "linkedin.com"
+	}
+
+	platform, ok = socialHosts[host]
+	if !ok {
+		return "", "", false
+	}
+
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	if len(segments) == 0 {
+		return "", "", false
+	}
+
+	first := strings.ToLower(segments[0])
+	if slices.Contains(socialReserved[platform], first) {
+		return "", "", false
+	}
+
+	switch platform {
+	case SocialFacebook:
+		handle = facebookHandle(u, segments)
+	case SocialLinkedIn:
+		if len(segments) >= 2 && slices.Contains([]string{"company", "in", "school", "showcase"}, first) {
+			handle = first + "/" + segments[1]
+		}
+	case SocialYouTube:
+		switch {
+		case strings.HasPrefix(segments[0], "@"):
+			handle = segments[0]
+		case len(segments) >= 2 && slices.Contains([]string{"channel", "c", "user"}, first):
+			handle = first + "/" + segments[1]
+		}
+	case SocialTikTok:
+		if strings.HasPrefix(segments[0], "@") {
+			handle = segments[0]
+		}
+	default:
+		handle = "@" + strings.TrimPrefix(segments[0], "@")
+	}
+
+	if !socialHandleRe.MatchString(strings.NewReplacer("@", "", "/", "").Replace(handle)) {
+		return "", "", false
+	}
+
+	return platform, handle, true
+}
+
+// facebookHandle returns the page name, or the id of profile.php?id= and of
+// pages/name/id, of a Facebook link.
+func facebookHandle(u *url.URL, segments []string) string {
+	switch strings.ToLower(segments[0]) {
+	case "profile.php":
+		return u.Query().Get("id")
+	case "pages", "pg":
+		if len(segments) >= 3 {
+			return segments[2]
+		}
+
+		if len(segments) == 2 {
+			return segments[1]
+		}
+
+		return ""
+	case "people", "groups":
+		if len(segments) >= 2 {
+			return strings.ToLower(segments[0]) + "/" + segments[len(segments)-1]
+		}
+
+		return ""
+	}
+
+	return segments[0]
+}
+
+// SocialProfileURL returns the url of the profile handle on platform.
+func SocialProfileURL(platform, handle string) string {
+	switch platform {
+	case SocialFacebook:
+		if !strings.Contains(handle, "/") && strings.Trim(handle, "0123456789") == "" {
+			return "https://www.facebook.com/profile.php?id=" + handle
+		}
+
+		return "https://www.facebook.com/" + handle
+	case SocialInstagram:
+		return "https://www.instagram.com/" + strings.TrimPrefix(handle, "@")
+	case SocialTwitter:
+		return "https://x.com/" + strings.TrimPrefix(handle, "@")
+	case SocialLinkedIn:
+		return "https://www.linkedin.com/" + handle
+	case SocialYouTube:
+		return "https://www.youtube.com/" + handle
+	case SocialTikTok:
+		return "https://www.tiktok.com/" + handle
+	}
+
+	return ""
+}
+
+// AddSocialProfiles adds the profiles of links to SocialProfiles, the first
+// profile of each platform only, and fills the Facebook, Instagram, LinkedIn
+// and Twitter fields that are empty with their url.
+func (e *Entry) AddSocialProfiles(links []string) {
+	for _, link := range links {
+		platform, handle, ok := SocialHandle(link)
+		if !ok {
+			continue
+		}
+
+		if _, ok := e.SocialProfiles[platform]; ok {
+			continue
+		}
+
+		if e.SocialProfiles == nil {
+			e.SocialProfiles = map[string]string{}
+		}
+
+		e.SocialProfiles[platform] = handle
+	}
+
+	fields := map[string]*string{
+		SocialFacebook:  &e.Facebook,
+		SocialInstagram: &e.Instagram,
+		SocialLinkedIn:  &e.LinkedIn,
+		SocialTwitter:   &e.Twitter,
+	}
+
+	for platform, field := range fields {
+		if handle, ok := e.SocialProfiles[platform]; ok && *field == "" {
+			*field = SocialProfileURL(platform, handle)
+		}
+	}
+}
+
+// docLinks returns the absolute urls of the links of doc, a page at base.
+func docLinks(doc *goquery.Document, base string) []string {
+	baseURL, _ := url.Parse(base)
+
+	var ans []string
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+
+		if baseURL != nil {
+			u = baseURL.ResolveReference(u)
+		}
+
+		ans = append(ans, u.String())
+	})
+
+	return ans
+}
+
+func socialProfilesString(profiles map[string]string) string {
+	if len(profiles) == 0 {
+		return ""
+	}
+
+	d, _ := json.Marshal(profiles)
+
+	return string(d)
+}
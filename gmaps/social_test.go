@@ -0,0 +1,62 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_SocialHandle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		link     string
+		platform string
+		handle   string
+	}{
+		{link: "https://www.facebook.com/kipriakon/?ref=page_internal&utm_source=site", platform: gmaps.SocialFacebook, handle: "kipriakon"},
+		{link: "https://m.facebook.com/profile.php?id=100063606218394", platform: gmaps.SocialFacebook, handle: "100063606218394"},
+		{link: "https://www.facebook.com/pages/Kipriakon/123456789", platform: gmaps.SocialFacebook, handle: "123456789"},
+		{link: "https://instagram.com/kipriakon_tavern?igshid=abc", platform: gmaps.SocialInstagram, handle: "@kipriakon_tavern"},
+		{link: "https://x.com/kipriakon", platform: gmaps.SocialTwitter, handle: "@kipriakon"},
+		{link: "https://de.linkedin.com/company/kipriakon-ltd/about/", platform: gmaps.SocialLinkedIn, handle: "company/kipriakon-ltd"},
+		{link: "https://www.youtube.com/@kipriakon", platform: gmaps.SocialYouTube, handle: "@kipriakon"},
+		{link: "https://www.tiktok.com/@kipriakon?lang=en", platform: gmaps.SocialTikTok, handle: "@kipriakon"},
+		{link: "https://www.facebook.com/sharer/sharer.php?u=https://kipriakon.com"},
+		{link: "https://twitter.com/intent/tweet?text=hello"},
+		{link: "https://www.instagram.com/p/C1a2b3c4/"},
+		{link: "https://www.youtube.com/watch?v=abc"},
+		{link: "https://www.linkedin.com/shareArticle?url=x"},
+		{link: "https://kipriakon.com/facebook"},
+		{link: "https://www.facebook.com/"},
+	}
+
+	for _, tc := range tests {
+		platform, handle, ok := gmaps.SocialHandle(tc.link)
+		require.Equal(t, tc.platform != "", ok, tc.link)
+		require.Equal(t, tc.platform, platform, tc.link)
+		require.Equal(t, tc.handle, handle, tc.link)
+	}
+}
+
+func Test_AddSocialProfiles(t *testing.T) {
+	t.Parallel()
+
+	entry := gmaps.Entry{Twitter: "https://twitter.com/old"}
+	entry.AddSocialProfiles([]string{
+		"https://www.facebook.com/sharer.php?u=x",
+		"https://www.facebook.com/kipriakon?utm_source=a",
+		"https://facebook.com/kipriakon?utm_source=b",
+		"https://www.facebook.com/other",
+		"https://twitter.com/kipriakon",
+	})
+
+	require.Equal(t, map[string]string{
+		gmaps.SocialFacebook: "kipriakon",
+		gmaps.SocialTwitter:  "@kipriakon",
+	}, entry.SocialProfiles)
+	require.Equal(t, "https://www.facebook.com/kipriakon", entry.Facebook)
+	require.Equal(t, "https://twitter.com/old", entry.Twitter)
+}
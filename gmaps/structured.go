@@ -0,0 +1,446 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// structuredGeoTolerance is the distance in meters between the location of
+// the website and the one of Google Maps above which they disagree.
+const structuredGeoTolerance = 500
+
+// The fields of StructuredData.Mismatches.
+const (
+	MismatchPhone        = "phone"
+	MismatchGeo          = "geo"
+	MismatchPostalCode   = "postal_code"
+	MismatchOpeningHours = "opening_hours"
+)
+
+// localBusinessTypes are the schema.org LocalBusiness type and its most
+// common subtypes.
+var localBusinessTypes = []string{
+	"LocalBusiness", "Restaurant", "FoodEstablishment", "CafeOrCoffeeShop", "BarOrPub", "Bakery",
+	"Store", "Hotel", "LodgingBusiness", "Dentist", "MedicalBusiness", "Physician", "AutoRepair",
+	"AutomotiveBusiness", "BeautySalon", "HairSalon", "HealthAndBeautyBusiness", "DaySpa",
+	"ProfessionalService", "LegalService", "Attorney", "RealEstateAgent", "HomeAndConstructionBusiness",
+	"Plumber", "Electrician", "SportsActivityLocation", "ExerciseGym", "EntertainmentBusiness",
+	"FinancialService", "TravelAgency",
+}
+
+// schemaDays are the days of the openingHours of schema.org, by time.Weekday.
+var schemaDays = []string{"su", "mo", "tu", "we", "th", "fr", "sa"}
+
+var openingHoursRe = regexp.MustCompile(`(?i)((?:mo|tu|we|th|fr|sa|su)(?:\s*[-,]\s*(?:mo|tu|we|th|fr|sa|su))*)\s+(\d{1,2}:\d{2})\s*-\s*(\d{1,2}:\d{2})`)
+
+// StructuredData is the schema.org LocalBusiness of the website of a place,
+// in JSON-LD or microdata.
+type StructuredData struct {
+	Type      string  `json:"type"`
+	Name      string  `json:"name,omitempty"`
+	Telephone string  `json:"telephone,omitempty"`
+	Address   Address `json:"address"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	// OpenHours are the opening hours by day, like the ones of Entry.OpenHours
+	OpenHours map[string][]string `json:"open_hours,omitempty"`
+	// Mismatch is true when the website and Google Maps disagree on one of the Mismatches
+	Mismatch   bool     `json:"mismatch"`
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// ExtractStructuredData returns the LocalBusiness of doc, nil when it has
+// none. The nodes of a LocalBusiness type are preferred to the other ones
+// with an address and a phone, a location or opening hours.
+func ExtractStructuredData(doc *goquery.Document) *StructuredData {
+	var nodes []map[string]any
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var v any
+		if err := json.Unmarshal([]byte(s.Text()), &v); err == nil {
+			nodes = append(nodes, jsonLDNodes(v)...)
+		}
+	})
+
+	doc.Find(`[itemscope][itemtype*="schema.org/"]`).Each(func(_ int, s *goquery.Selection) {
+		if s.ParentsFiltered("[itemscope]").Length() == 0 {
+			nodes = append(nodes, microdataItem(s))
+		}
+	})
+
+	var fallback map[string]any
+
+	for _, node := range nodes {
+		types := schemaTypes(node)
+
+		if slices.ContainsFunc(types, func(t string) bool { return slices.Contains(localBusinessTypes, t) }) {
+			return newStructuredData(node)
+		}
+
+		if fallback == nil && len(types) > 0 && node["address"] != nil &&
+			(node["telephone"] != nil || node["geo"] != nil || node["openingHours"] != nil || node["openingHoursSpecification"] != nil) {
+			fallback = node
+		}
+	}
+
+	if fallback == nil {
+		return nil
+	}
+
+	return newStructuredData(fallback)
+}
+
+// CompareWith sets the Mismatches of d with the data of e on Google Maps.
+// The fields missing on either side are not compared.
+func (d *StructuredData) CompareWith(e *Entry) {
+	d.Mismatches = nil
+
+	region := e.CompleteAddress.Country
+	if region == "" {
+		region = e.CountryCode
+	}
+
+	website, maps := NormalizePhone(d.Telephone, region), NormalizePhone(e.Phone, region)
+	if website.Valid && maps.Valid && website.E164 != maps.E164 {
+		d.Mismatches = append(d.Mismatches, MismatchPhone)
+	}
+
+	if d.Latitude != 0 && d.Longitude != 0 && e.Latitude != 0 && e.Longtitude != 0 &&
+		e.haversineDistance(d.Latitude, d.Longitude) > structuredGeoTolerance {
+		d.Mismatches = append(d.Mismatches, MismatchGeo)
+	}
+
+	postalCode := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	}
+
+	if d.Address.PostalCode != "" && e.CompleteAddress.PostalCode != "" &&
+		postalCode(d.Address.PostalCode) != postalCode(e.CompleteAddress.PostalCode) {
+		d.Mismatches = append(d.Mismatches, MismatchPostalCode)
+	}
+
+	if !sameOpenHours(d.OpenHours, e.OpenHours) {
+		d.Mismatches = append(d.Mismatches, MismatchOpeningHours)
+	}
+
+	d.Mismatch = len(d.Mismatches) > 0
+}
+
+// sameOpenHours reports whether a and b have the same hours on the days they
+// both have and can parse.
+func sameOpenHours(a, b map[string][]string) bool {
+	parse := func(values []string) ([]hoursRange, bool) {
+		var ans []hoursRange
+
+		for _, v := range values {
+			ranges, ok := parseHoursRange(v)
+			if !ok {
+				return nil, false
+			}
+
+			ans = append(ans, ranges...)
+		}
+
+		slices.SortFunc(ans, func(x, y hoursRange) int { return x.start - y.start })
+
+		return ans, true
+	}
+
+	for day, values := range a {
+		other, ok := b[day]
+		if !ok {
+			continue
+		}
+
+		x, ok := parse(values)
+		if !ok {
+			continue
+		}
+
+		y, ok := parse(other)
+		if !ok {
+			continue
+		}
+
+		if !slices.Equal(x, y) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// jsonLDNodes returns the objects of a JSON-LD document and of its @graph.
+func jsonLDNodes(v any) []map[string]any {
+	switch val := v.(type) {
+	case []any:
+		var ans []map[string]any
+
+		for _, item := range val {
+			ans = append(ans, jsonLDNodes(item)...)
+		}
+
+		return ans
+	case map[string]any:
+		ans := []map[string]any{val}
+
+		if graph, ok := val["@graph"]; ok {
+			ans = append(ans, jsonLDNodes(graph)...)
+		}
+
+		return ans
+	}
+
+	return nil
+}
+
+// microdataItem returns the properties of the microdata item s as a JSON-LD
+// node, the properties with several values as arrays.
+func microdataItem(s *goquery.Selection) map[string]any {
+	node := map[string]any{}
+
+	if t := s.AttrOr("itemtype", ""); t != "" {
+		node["@type"] = t[strings.LastIndex(t, "/")+1:]
+	}
+
+	s.Find("[itemprop]").Each(func(_ int, prop *goquery.Selection) {
+		// the properties of the nested items belong to them
+		if !prop.ParentsFiltered("[itemscope]").First().IsSelection(s) {
+			return
+		}
+
+		var value any
+
+		if _, ok := prop.Attr("itemscope"); ok {
+			value = microdataItem(prop)
+		} else {
+			value = microdataValue(prop)
+		}
+
+		for _, name := range strings.Fields(prop.AttrOr("itemprop", "")) {
+			switch prev := node[name].(type) {
+			case nil:
+				node[name] = value
+			case []any:
+				node[name] = append(prev, value)
+			default:
+				node[name] = []any{prev, value}
+			}
+		}
+	})
+
+	return node
+}
+
+func microdataValue(s *goquery.Selection) string {
+	for _, attr := range []string{"content", "href", "src", "datetime"} {
+		if v, ok := s.Attr(attr); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+func newStructuredData(node map[string]any) *StructuredData {
+	d := StructuredData{
+		Name:      schemaString(node["name"]),
+		Telephone: strings.TrimPrefix(schemaString(node["telephone"]), "tel:"),
+		Address:   schemaAddress(node["address"]),
+	}
+
+	if types := schemaTypes(node); len(types) > 0 {
+		d.Type = types[0]
+	}
+
+	if geo, ok := firstValue(node["geo"]).(map[string]any); ok {
+		d.Latitude = schemaFloat(geo["latitude"])
+		d.Longitude = schemaFloat(geo["longitude"])
+	}
+
+	d.OpenHours = schemaOpenHours(node)
+
+	return &d
+}
+
+func schemaTypes(node map[string]any) []string {
+	var ans []string
+
+	for _, v := range schemaValues(node["@type"]) {
+		if s, ok := v.(string); ok {
+			ans = append(ans, s[strings.LastIndex(s, "/")+1:])
+		}
+	}
+
+	return ans
+}
+
+// schemaValues returns the values of v, a value or an array of values.
+func schemaValues(v any) []any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return val
+	}
+
+	return []any{v}
+}
+
+func firstValue(v any) any {
+	if values := schemaValues(v); len(values) > 0 {
+		return values[0]
+	}
+
+	return nil
+}
+
+func schemaString(v any) string {
+	switch val := firstValue(v).(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case map[string]any:
+		return schemaString(val["name"])
+	}
+
+	return ""
+}
+
+func schemaFloat(v any) float64 {
+	switch val := firstValue(v).(type) {
+	case float64:
+		return val
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(val), 64)
+
+		return f
+	}
+
+	return 0
+}
+
+func schemaAddress(v any) Address {
+	switch val := firstValue(v).(type) {
+	case string:
+		return Address{Street: strings.TrimSpace(val)}
+	case map[string]any:
+		return Address{
+			Street:     schemaString(val["streetAddress"]),
+			City:       schemaString(val["addressLocality"]),
+			PostalCode: schemaString(val["postalCode"]),
+			State:      schemaString(val["addressRegion"]),
+			Country:    schemaString(val["addressCountry"]),
+		}
+	}
+
+	return Address{}
+}
+
+// schemaOpenHours returns the openingHours, "Mo-Fr 09:00-18:00", and the
+// openingHoursSpecification of node by day.
+func schemaOpenHours(node map[string]any) map[string][]string {
+	ans := map[string][]string{}
+
+	for _, v := range schemaValues(node["openingHours"]) {
+		s, _ := v.(string)
+
+		for _, m := range openingHoursRe.FindAllStringSubmatch(s, -1) {
+			for _, day := range schemaDaysOf(m[1]) {
+				ans[day.String()] = append(ans[day.String()], m[2]+"-"+m[3])
+			}
+		}
+	}
+
+	for _, v := range schemaValues(node["openingHoursSpecification"]) {
+		spec, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		opens, closes := schemaClock(spec["opens"]), schemaClock(spec["closes"])
+		if opens == "" || closes == "" {
+			continue
+		}
+
+		hours := opens + "-" + closes
+		if opens == "00:00" && closes == "00:00" {
+			hours = "Closed"
+		}
+
+		for _, d := range schemaValues(spec["dayOfWeek"]) {
+			name := schemaString(d)
+			name = name[strings.LastIndex(name, "/")+1:]
+
+			for day := time.Sunday; day <= time.Saturday; day++ {
+				if strings.EqualFold(name, day.String()) {
+					ans[day.String()] = append(ans[day.String()], hours)
+				}
+			}
+		}
+	}
+
+	if len(ans) == 0 {
+		return nil
+	}
+
+	return ans
+}
+
+// schemaDaysOf returns the days of "Mo-Fr", "Mo,We,Fr" or "Sa".
+func schemaDaysOf(s string) []time.Weekday {
+	var ans []time.Weekday
+
+	for _, part := range strings.Split(strings.ToLower(strings.ReplaceAll(s, " ", "")), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+
+		start := slices.Index(schemaDays, from)
+		if start < 0 {
+			continue
+		}
+
+		end := start
+		if isRange {
+			if end = slices.Index(schemaDays, to); end < 0 {
+				continue
+			}
+		}
+
+		for i := start; ; i = (i + 1) % 7 {
+			ans = append(ans, time.Weekday(i))
+
+			if i == end {
+				break
+			}
+		}
+	}
+
+	return ans
+}
+
+// schemaClock returns the hh:mm of "09:00" or "09:00:00".
+func schemaClock(v any) string {
+	s := schemaString(v)
+	if len(s) < 5 || s[2] != ':' {
+		return ""
+	}
+
+	return s[:5]
+}
+
+func structuredDataString(d *StructuredData) string {
+	if d == nil {
+		return ""
+	}
+
+	b, _ := json.Marshal(d)
+
+	return string(b)
+}
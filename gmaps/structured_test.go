@@ -0,0 +1,90 @@
+package gmaps_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func newDocument(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	require.NoError(t, err)
+
+	return doc
+}
+
+func Test_ExtractStructuredData_JSONLD(t *testing.T) {
+	t.Parallel()
+
+	doc := newDocument(t, `<html><head>
+<script type="application/ld+json">{"@context":"https://schema.org","@graph":[
+	{"@type":"WebSite","name":"Kipriakon"},
+	{"@type":"Restaurant","name":"Kipriakon","telephone":"+357 25 101555",
+	 "address":{"@type":"PostalAddress","streetAddress":"Anexartisias 1","addressLocality":"Limassol","postalCode":"3036","addressCountry":"CY"},
+	 "geo":{"@type":"GeoCoordinates","latitude":"34.6705954","longitude":33.0424567},
+	 "openingHours":["Mo-Fr 12:30-22:00","Sa,Su 11:00-23:00"]}
+]}</script></head></html>`)
+
+	data := gmaps.ExtractStructuredData(doc)
+	require.NotNil(t, data)
+	require.Equal(t, "Restaurant", data.Type)
+	require.Equal(t, "+357 25 101555", data.Telephone)
+	require.Equal(t, gmaps.Address{Street: "Anexartisias 1", City: "Limassol", PostalCode: "3036", Country: "CY"}, data.Address)
+	require.InDelta(t, 34.6705954, data.Latitude, 1e-9)
+	require.Equal(t, []string{"12:30-22:00"}, data.OpenHours["Monday"])
+	require.Equal(t, []string{"11:00-23:00"}, data.OpenHours["Sunday"])
+
+	entry := gmaps.Entry{
+		Phone:           "25 101555",
+		Latitude:        34.670595399999996,
+		Longtitude:      33.042456699999995,
+		CompleteAddress: gmaps.Address{PostalCode: "3036", Country: "CY"},
+		OpenHours: map[string][]string{
+			"Monday": {"12:30–10 pm"},
+			"Sunday": {"12:30–10 pm"},
+		},
+	}
+
+	data.CompareWith(&entry)
+	require.True(t, data.Mismatch)
+	require.Equal(t, []string{gmaps.MismatchOpeningHours}, data.Mismatches)
+
+	entry.OpenHours["Sunday"] = []string{"11 am–11 pm"}
+	data.CompareWith(&entry)
+	require.False(t, data.Mismatch)
+}
+
+func Test_ExtractStructuredData_Microdata(t *testing.T) {
+	t.Parallel()
+
+	doc := newDocument(t, `<div itemscope itemtype="https://schema.org/Dentist">
+	<span itemprop="name">Smile</span>
+	<a itemprop="telephone" href="tel:+4930123456">030 123456</a>
+	<div itemprop="address" itemscope itemtype="https://schema.org/PostalAddress">
+		<span itemprop="streetAddress">Hauptstr. 1</span>
+		<span itemprop="postalCode">10115</span> <span itemprop="addressLocality">Berlin</span>
+	</div>
+	<meta itemprop="openingHours" content="Mo-Th 08:00-18:00">
+</div>`)
+
+	data := gmaps.ExtractStructuredData(doc)
+	require.NotNil(t, data)
+	require.Equal(t, "Dentist", data.Type)
+	require.Equal(t, "Smile", data.Name)
+	require.Equal(t, "+4930123456", data.Telephone)
+	require.Equal(t, gmaps.Address{Street: "Hauptstr. 1", City: "Berlin", PostalCode: "10115"}, data.Address)
+	require.Len(t, data.OpenHours, 4)
+
+	entry := gmaps.Entry{Phone: "030 654321", CompleteAddress: gmaps.Address{PostalCode: "10115", Country: "DE"}}
+
+	data.CompareWith(&entry)
+	require.Equal(t, []string{gmaps.MismatchPhone}, data.Mismatches)
+
+	require.Nil(t, gmaps.ExtractStructuredData(newDocument(t, `<script type="application/ld+json">{"@type":"WebSite"}</script>`)))
+}
@@ -0,0 +1,167 @@
+package gmaps
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// technology is the fingerprint of a technology of a website, any of its
+// signatures is enough to detect it.
+type technology struct {
+	name string
+	// html are lowercase substrings of the page, mostly the hosts and paths of its scripts
+	html []string
+	// generator is the lowercase prefix of the content of the generator meta tag
+	generator string
+	// headers are lowercase substrings of the response headers, an empty one only needs the header
+	headers map[string]string
+}
+
+// technologies are the detected technologies, in the order of Entry.Technologies.
+var technologies = []technology{
+	// cms and site builders
+	{name: "WordPress", html: []string{"/wp-content/", "/wp-includes/"}, generator: "wordpress"},
+	{name: "Joomla", html: []string{"/media/jui/", "/media/system/js/"}, generator: "joomla"},
+	{name: "Drupal", html: []string{"/sites/default/files/", "drupal-settings-json"}, generator: "drupal", headers: map[string]string{"X-Drupal-Cache": "", "X-Generator": "drupal"}},
+	{name: "Wix", html: []string{"static.wixstatic.com", "static.parastorage.com"}, generator: "wix.com", headers: map[string]string{"X-Wix-Request-Id": ""}},
+	{name: "Squarespace", html: []string{"static1.squarespace.com", "assets.squarespace.com"}, headers: map[string]string{"Server": "squarespace"}},
+	{name: "Webflow", html: []string{"assets.website-files.com", "assets-global.website-files.com"}, generator: "webflow"},
+	{name: "Ghost", generator: "ghost"},
+	{name: "Jimdo", html: []string{"assets.jimstatic.com"}},
+	{name: "Weebly", html: []string{"editmysite.com"}},
+	{name: "GoDaddy Website Builder", html: []string{"img1.wsimg.com"}, generator: "starfield technologies"},
+	// ecommerce
+	{name: "Shopify", html: []string{"cdn.shopify.com", "shopify.theme"}, headers: map[string]string{"X-Shopid": "", "Powered-By": "shopify"}},
+	{name: "WooCommerce", html: []string{"/wp-content/plugins/woocommerce/", "woocommerce-no-js"}, generator: "woocommerce"},
+	{name: "Magento", html: []string{"mage/cookies", "/static/version", "magento_"}, generator: "magento"},
+	{name: "PrestaShop", html: []string{"/modules/ps_", "prestashop"}, generator: "prestashop", headers: map[string]string{"Powered-By": "prestashop"}},
+	{name: "BigCommerce", html: []string{"cdn11.bigcommerce.com"}},
+	{name: "OpenCart", html: []string{"catalog/view/theme/"}},
+	{name: "Ecwid", html: []string{"app.ecwid.com"}},
+	// analytics
+	{name: "Google Analytics", html: []string{"google-analytics.com/analytics.js", "googletagmanager.com/gtag/js", "google-analytics.com/ga.js"}},
+	{name: "Google Tag Manager", html: []string{"googletagmanager.com/gtm.js", "googletagmanager.com/ns.html"}},
+	{name: "Facebook Pixel", html: []string{"connect.facebook.net/en_us/fbevents.js", "/fbevents.js"}},
+	{name: "Hotjar", html: []string{"static.hotjar.com"}},
+	{name: "Matomo", html: []string{"matomo.js", "piwik.js"}},
+	{name: "Plausible", html: []string{"plausible.io/js/"}},
+	{name: "Microsoft Clarity", html: []string{"clarity.ms/tag/"}},
+	{name: "Yandex Metrica", html: []string{"mc.yandex.ru/metrika"}},
+	// chat widgets
+	{name: "Intercom", html: []string{"widget.intercom.io", "js.intercomcdn.com"}},
+	{name: "Drift", html: []string{"js.driftt.com"}},
+	{name: "Zendesk Chat", html: []string{"static.zdassets.com", "v2.zopim.com"}},
+	{name: "Tawk.to", html: []string{"embed.tawk.to"}},
+	{name: "LiveChat", html: []string{"cdn.livechatinc.com"}},
+	{name: "Crisp", html: []string{"client.crisp.chat"}},
+	{name: "HubSpot", html: []string{"js.hs-scripts.com", "js.hs-analytics.net"}},
+	{name: "Tidio", html: []string{"code.tidio.co"}},
+}
+
+var (
+	metaTagRe   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaGenRe   = regexp.MustCompile(`(?i)name\s*=\s*["']?generator["'\s/>]`)
+	metaValueRe = regexp.MustCompile(`(?is)content\s*=\s*["']([^"']*)["']`)
+)
+
+// DetectTechnologies returns the technologies of a website, such as its CMS,
+// ecommerce platform, analytics and chat widgets, from the headers and the
+// body of its homepage.
+func DetectTechnologies(headers http.Header, body []byte) []string {
+	page := string(bytes.ToLower(body))
+	generator := strings.ToLower(metaGenerator(body))
+
+	var ans []string
+
+	for i := range technologies {
+		if technologies[i].match(headers, page, generator) {
+			ans = append(ans, technologies[i].name)
+		}
+	}
+
+	return ans
+}
+
+func (t *technology) match(headers http.Header, page, generator string) bool {
+	if t.generator != "" && strings.HasPrefix(generator, t.generator) {
+		return true
+	}
+
+	for _, s := range t.html {
+		if strings.Contains(page, s) {
+			return true
+		}
+	}
+
+	for name, s := range t.headers {
+		for _, v := range headers.Values(name) {
+			if strings.Contains(strings.ToLower(v), s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// metaGenerator returns the content of the generator meta tag of body.
+func metaGenerator(body []byte) string {
+	for _, tag := range metaTagRe.FindAll(body, -1) {
+		if !metaGenRe.Match(tag) {
+			continue
+		}
+
+		if m := metaValueRe.FindSubmatch(tag); m != nil {
+			return strings.TrimSpace(string(m[1]))
+		}
+	}
+
+	return ""
+}
+
+// trackingIDRes match the ids of Google Analytics, Google Tag Manager,
+// Google Ads and the Facebook pixel.
+var trackingIDRes = []*regexp.Regexp{
+	regexp.MustCompile(`\b(G-[A-Z0-9]{6,12}|UA-\d{4,10}-\d{1,4}|GTM-[A-Z0-9]{4,9}|AW-\d{6,12})\b`),
+	regexp.MustCompile(`fbq\(\s*['"]init['"]\s*,\s*['"](\d{10,20})['"]`),
+}
+
+// DetectTrackingIDs returns the analytics and ads ids of a page, in order of
+// appearance: the ids of Google Analytics (G-, UA-), Google Tag Manager
+// (GTM-), Google Ads (AW-) and the Facebook pixel, prefixed with FB-.
+func DetectTrackingIDs(body []byte) []string {
+	type match struct {
+		at int
+		id string
+	}
+
+	var matches []match
+
+	for i, re := range trackingIDRes {
+		for _, m := range re.FindAllSubmatchIndex(body, -1) {
+			id := string(body[m[2]:m[3]])
+			if i == 1 {
+				id = "FB-" + id
+			}
+
+			matches = append(matches, match{at: m[2], id: id})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return a.at - b.at
+	})
+
+	var ans []string
+
+	for _, m := range matches {
+		if !slices.Contains(ans, m.id) {
+			ans = append(ans, m.id)
+		}
+	}
+
+	return ans
+}
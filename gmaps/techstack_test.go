@@ -0,0 +1,31 @@
+package gmaps_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_DetectTechnologies(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`<html><head>
+<meta content="WordPress 6.4.2" name="generator">
+<link rel="stylesheet" href="https://example.com/wp-content/plugins/woocommerce/assets/css/woocommerce.css">
+<script async src="https://www.googletagmanager.com/gtag/js?id=G-XXXX"></script>
+</head><body><script src="https://embed.tawk.to/123/default"></script></body></html>`)
+
+	require.Equal(t,
+		[]string{"WordPress", "WooCommerce", "Google Analytics", "Tawk.to"},
+		gmaps.DetectTechnologies(nil, body),
+	)
+
+	headers := http.Header{}
+	headers.Set("X-ShopId", "123")
+
+	require.Equal(t, []string{"Shopify"}, gmaps.DetectTechnologies(headers, []byte(`<html></html>`)))
+	require.Empty(t, gmaps.DetectTechnologies(nil, []byte(`<meta name="description" content="wordpress tips">`)))
+}
@@ -0,0 +1,39 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Throttle paces the pages loaded from Google. Acquire is called before a
+// page is loaded and, when it returns nil, Release after.
+type Throttle interface {
+	Acquire(ctx context.Context) error
+	Release(latency time.Duration, err error, blocked bool)
+}
+
+// throttle runs the browser actions when t allows it and reports to t how
+// they went. It only runs them when t is nil.
+func throttle(ctx context.Context, t Throttle, page playwright.Page, actions func() scrapemate.Response) scrapemate.Response {
+	if t == nil {
+		return actions()
+	}
+
+	if err := t.Acquire(ctx); err != nil {
+		return scrapemate.Response{Error: err}
+	}
+
+	start := time.Now()
+
+	resp := actions()
+
+	blocked := errors.Is(resp.Error, ErrBlocked)
+
+	t.Release(time.Since(start), resp.Error, blocked)
+
+	return resp
+}
@@ -0,0 +1,96 @@
+package gmaps
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/gosom/google-maps-scraper/emailverify"
+)
+
+func emailStatusesString(statuses []emailverify.Result) string {
+	if len(statuses) == 0 {
+		return ""
+	}
+
+	d, _ := json.Marshal(statuses)
+
+	return string(d)
+}
+
+// EmailVerifier verifies the emails of the places, the domains of all the
+// places up to concurrency at once. It is safe to share between jobs.
+type EmailVerifier struct {
+	verifier *emailverify.Verifier
+	sem      chan struct{}
+}
+
+// NewEmailVerifier returns a verifier of the emails with v. It returns nil,
+// which verifies nothing, when v is nil.
+func NewEmailVerifier(v *emailverify.Verifier, concurrency int) *EmailVerifier {
+	if v == nil {
+		return nil
+	}
+
+	return &EmailVerifier{
+		verifier: v,
+		sem:      make(chan struct{}, max(1, concurrency)),
+	}
+}
+
+// Verify returns the status of each of emails, in the same order. The
+// emails of a domain are verified in one session with its mail server.
+func (v *EmailVerifier) Verify(ctx context.Context, emails []string) []emailverify.Result {
+	if v == nil || len(emails) == 0 {
+		return nil
+	}
+
+	// the positions of the emails of each domain
+	domains := map[string][]int{}
+
+	for i, email := range emails {
+		_, domain, _ := strings.Cut(email, "@")
+		domain = strings.ToLower(domain)
+		domains[domain] = append(domains[domain], i)
+	}
+
+	ans := make([]emailverify.Result, len(emails))
+
+	var wg sync.WaitGroup
+
+	for domain, positions := range domains {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			locals := make([]string, len(positions))
+			for i, p := range positions {
+				locals[i], _, _ = strings.Cut(emails[p], "@")
+			}
+
+			select {
+			case v.sem <- struct{}{}:
+			case <-ctx.Done():
+				for i, p := range positions {
+					ans[p] = emailverify.Result{Email: locals[i] + "@" + domain, Reachable: emailverify.ReachableUnknown}
+				}
+
+				return
+			}
+
+			results := v.verifier.VerifyDomain(ctx, domain, locals)
+
+			<-v.sem
+
+			for i, p := range positions {
+				ans[p] = results[i]
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return ans
+}
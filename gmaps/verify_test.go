@@ -0,0 +1,44 @@
+package gmaps_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/emailverify"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_EmailVerifier(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, gmaps.NewEmailVerifier(nil, 4))
+	require.Nil(t, (*gmaps.EmailVerifier)(nil).Verify(context.Background(), []string{"info@example.com"}))
+
+	// no domain has mail servers, the emails are verified without a connection
+	v, err := emailverify.New("verify@operator.com",
+		emailverify.WithResolver(func(context.Context, string) ([]*net.MX, error) {
+			return nil, errors.New("no such host")
+		}),
+	)
+	require.NoError(t, err)
+
+	results := gmaps.NewEmailVerifier(v, 1).Verify(context.Background(), []string{
+		"info@example.com",
+		"sales@mailinator.com",
+		"bad@@example.com",
+		"team@example.com",
+	})
+
+	require.Len(t, results, 4)
+	require.Equal(t, "info@example.com", results[0].Email)
+	require.True(t, results[0].Syntax)
+	require.False(t, results[0].MX)
+	require.Equal(t, emailverify.ReachableNo, results[0].Reachable)
+	require.True(t, results[1].Disposable)
+	require.False(t, results[2].Syntax)
+	require.Equal(t, "team@example.com", results[3].Email)
+}
@@ -0,0 +1,105 @@
+package gmaps
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+)
+
+// videoFailureStreak is the number of failed jobs in a row from which the
+// videos of the failed jobs are all kept
+const videoFailureStreak = 2
+
+// VideoRecorder keeps the videos the browsers record of the pages of a
+// sample of the jobs, and of all the failed jobs once several jobs failed in
+// a row, and deletes the others. The browser contexts must record videos and
+// close the page after each job. It is safe to share between jobs.
+type VideoRecorder struct {
+	dir    string
+	sample float64
+
+	mu     sync.Mutex
+	streak int
+}
+
+// NewVideoRecorder keeps the videos in dir. sample is the fraction of the
+// jobs, between 0 and 1, whose video is kept whatever their result.
+func NewVideoRecorder(dir string, sample float64) (*VideoRecorder, error) {
+	if sample < 0 || sample > 1 {
+		return nil, fmt.Errorf("invalid video sample: %v", sample)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create the video folder: %w", err)
+	}
+
+	return &VideoRecorder{dir: dir, sample: sample}, nil
+}
+
+// Finish keeps or deletes the video of page once the page is closed, after
+// the job that failed with jobErr, or nil. It does nothing when v is nil.
+func (v *VideoRecorder) Finish(ctx context.Context, page playwright.Page, jobID string, jobErr error) {
+	if v == nil {
+		return
+	}
+
+	video := page.Video()
+	if video == nil {
+		return
+	}
+
+	keep := v.keep(jobErr)
+	name := filepath.Join(v.dir, safeFileName(jobID)+"-"+time.Now().UTC().Format("20060102T150405")+".webm")
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	closed := make(chan struct{})
+
+	var once sync.Once
+
+	page.OnClose(func(playwright.Page) {
+		once.Do(func() { close(closed) })
+	})
+
+	if page.IsClosed() {
+		once.Do(func() { close(closed) })
+	}
+
+	go func() {
+		<-closed
+
+		if keep {
+			if err := video.SaveAs(name); err != nil {
+				log.Error(fmt.Sprintf("failed to save the video of job %s: %v", jobID, err))
+			}
+		}
+
+		if err := video.Delete(); err != nil {
+			log.Error(fmt.Sprintf("failed to delete the video of job %s: %v", jobID, err))
+		}
+	}()
+}
+
+// keep reports whether the video of a job that failed with jobErr is kept.
+func (v *VideoRecorder) keep(jobErr error) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if jobErr == nil {
+		v.streak = 0
+	} else {
+		v.streak++
+
+		if v.streak >= videoFailureStreak {
+			return true
+		}
+	}
+
+	return rand.Float64() < v.sample
+}
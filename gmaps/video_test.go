@@ -0,0 +1,25 @@
+package gmaps_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_NewVideoRecorder(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "videos")
+
+	_, err := gmaps.NewVideoRecorder(dir, 1.5)
+	require.Error(t, err)
+
+	_, err = gmaps.NewVideoRecorder(dir, 0.01)
+	require.NoError(t, err)
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+}
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+// DiskSpaceCheck checks the free space of the disk the scraper writes its
+// results to.
+type DiskSpaceCheck struct {
+	path    string
+	minFree uint64
+
+	// usage returns the space of the disk of a path available to the
+	// scraper and its size
+	usage func(path string) (free, total uint64, err error)
+}
+
+// NewDiskSpaceCheck is down when the disk of path has less than minFree bytes
+// available, and degraded below twice as much.
+func NewDiskSpaceCheck(path string, minFree uint64) *DiskSpaceCheck {
+	return &DiskSpaceCheck{
+		path:    path,
+		minFree: minFree,
+		usage:   diskUsage,
+	}
+}
+
+// Name returns disk.
+func (d *DiskSpaceCheck) Name() string {
+	return "disk"
+}
+
+// Check reports the free space of the disk, down when it cannot be read.
+func (d *DiskSpaceCheck) Check(context.Context) Result {
+	free, total, err := d.usage(d.path)
+	if err != nil {
+		return Result{Status: StatusDown, Message: fmt.Sprintf("%s: %v", d.path, err)}
+	}
+
+	ans := Result{
+		Status:  StatusOK,
+		Message: fmt.Sprintf("%s free of %s at %s", formatBytes(free), formatBytes(total), d.path),
+	}
+
+	switch {
+	case free < d.minFree:
+		ans.Status = StatusDown
+	case free < 2*d.minFree:
+		ans.Status = StatusDegraded
+	}
+
+	return ans
+}
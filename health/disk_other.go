@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package health
+
+import "github.com/shirou/gopsutil/v4/disk"
+
+func diskUsage(path string) (free, total uint64, err error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return usage.Free, usage.Total, nil
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package health
+
+import "syscall"
+
+func diskUsage(path string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	// the blocks reserved to root are not available
+	bsize := uint64(st.Bsize) //nolint:gosec // the block size is positive
+
+	return st.Bavail * bsize, st.Blocks * bsize, nil
+}
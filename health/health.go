@@ -0,0 +1,241 @@
+// Package health checks the resources the scraper needs to keep running, the
+// memory, the disk space and the browsers, and serves the overall status to
+// the probes of Docker and Kubernetes. The runners register their checks with
+// the default checker, see Register, which is served at /api/health.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The statuses of a check and of a report, from the best to the worst.
+const (
+	StatusOK = "ok"
+	// StatusDegraded is a scraper that still works but needs attention
+	StatusDegraded = "degraded"
+	// StatusDown is a scraper that should be restarted
+	StatusDown = "down"
+)
+
+// checkTimeout is the time a check gets before it is reported down.
+const checkTimeout = 5 * time.Second
+
+// Check checks one resource of the scraper.
+type Check interface {
+	// Name identifies the check in the report, e.g. memory
+	Name() string
+	// Check returns the status of the resource, its Name is filled by the
+	// checker
+	Check(ctx context.Context) Result
+}
+
+// Result is the status of a check, with a message for the operators.
+type Result struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the status of all the checks, the worst of them.
+type Report struct {
+	Status    string    `json:"status"`
+	Checks    []Result  `json:"checks"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Checker runs the registered checks. It is safe for concurrent use.
+type Checker struct {
+	mu     sync.Mutex
+	checks []Check
+}
+
+// NewChecker returns a checker without checks, which reports ok.
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Register adds the checks, replacing the ones registered with the same name.
+func (c *Checker) Register(checks ...Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, check := range checks {
+		replaced := false
+
+		for i := range c.checks {
+			if c.checks[i].Name() == check.Name() {
+				c.checks[i] = check
+				replaced = true
+
+				break
+			}
+		}
+
+		if !replaced {
+			c.checks = append(c.checks, check)
+		}
+	}
+}
+
+// Run runs the checks at the same time, each one for up to 5 seconds, and
+// returns their report in the order they were registered.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]Check(nil), c.checks...)
+	c.mu.Unlock()
+
+	ans := Report{
+		Status:    StatusOK,
+		Checks:    make([]Result, len(checks)),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	var wg sync.WaitGroup
+
+	for i, check := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ans.Checks[i] = run(ctx, check)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, r := range ans.Checks {
+		ans.Status = Worst(ans.Status, r.Status)
+	}
+
+	return ans
+}
+
+func run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	done := make(chan Result, 1)
+
+	go func() {
+		done <- check.Check(ctx)
+	}()
+
+	var ans Result
+
+	select {
+	case ans = <-done:
+	case <-ctx.Done():
+		ans = Result{Status: StatusDown, Message: fmt.Sprintf("no answer: %v", ctx.Err())}
+	}
+
+	ans.Name = check.Name()
+
+	if ans.Status == "" {
+		ans.Status = StatusOK
+	}
+
+	return ans
+}
+
+// Handler serves the report of c as json, with a 503 when it is down so the
+// probes restart the scraper, and a 200 when it is ok or degraded.
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		report := c.Run(r.Context())
+
+		code := http.StatusOK
+		if report.Status == StatusDown {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Worst returns the worst of the statuses a and b.
+func Worst(a, b string) string {
+	if rank(b) > rank(a) {
+		return b
+	}
+
+	return a
+}
+
+func rank(status string) int {
+	switch status {
+	case StatusDegraded:
+		return 1
+	case StatusDown:
+		return 2
+	default:
+		return 0
+	}
+}
+
+var std = NewChecker()
+
+// Register adds the checks to the default checker, see Checker.Register.
+func Register(checks ...Check) {
+	std.Register(checks...)
+}
+
+// Run runs the checks of the default checker.
+func Run(ctx context.Context) Report {
+	return std.Run(ctx)
+}
+
+// Handler serves the report of the default checker.
+func Handler() http.Handler {
+	return std.Handler()
+}
+
+// threshold returns down when used is at least limit, degraded when it is
+// within margin of it, and ok otherwise.
+func threshold(used, limit, margin float64) string {
+	switch {
+	case used >= limit:
+		return StatusDown
+	case used >= limit-margin:
+		return StatusDegraded
+	default:
+		return StatusOK
+	}
+}
+
+// formatBytes formats n in binary units, e.g. 1.5 GiB.
+func formatBytes(n uint64) string {
+	const unit = 1024
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := uint64(unit), 0
+
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
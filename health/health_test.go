@@ -0,0 +1,152 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCheck struct {
+	name   string
+	status string
+	// block holds the check until it is closed
+	block chan struct{}
+}
+
+func (f *fakeCheck) Name() string {
+	return f.name
+}
+
+func (f *fakeCheck) Check(context.Context) Result {
+	if f.block != nil {
+		<-f.block
+	}
+
+	return Result{Status: f.status, Message: f.name}
+}
+
+func TestChecker(t *testing.T) {
+	c := NewChecker()
+
+	report := c.Run(context.Background())
+	require.Equal(t, StatusOK, report.Status)
+	require.Empty(t, report.Checks)
+
+	c.Register(&fakeCheck{name: "a", status: StatusOK}, &fakeCheck{name: "b", status: StatusDegraded})
+
+	report = c.Run(context.Background())
+	require.Equal(t, StatusDegraded, report.Status)
+	require.Equal(t, []Result{
+		{Name: "a", Status: StatusOK, Message: "a"},
+		{Name: "b", Status: StatusDegraded, Message: "b"},
+	}, report.Checks)
+
+	// a check registered again replaces the previous one
+	c.Register(&fakeCheck{name: "a", status: StatusDown})
+
+	report = c.Run(context.Background())
+	require.Equal(t, StatusDown, report.Status)
+	require.Len(t, report.Checks, 2)
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", http.NoBody))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var got Report
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, StatusDown, got.Status)
+
+	c.Register(&fakeCheck{name: "a", status: StatusOK})
+
+	rec = httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/health", http.NoBody))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+
+	c := NewChecker()
+	c.Register(&fakeCheck{name: "stuck", block: block})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := c.Run(ctx)
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, "stuck", report.Checks[0].Name)
+}
+
+func TestMemoryCheck(t *testing.T) {
+	const gib = 1 << 30
+
+	m := NewMemoryCheck(90)
+
+	for _, tc := range []struct {
+		used, limit uint64
+		system      float64
+		systemErr   error
+		want        string
+	}{
+		{used: gib, limit: 4 * gib, system: 50, want: StatusOK},
+		{used: gib, system: 50, want: StatusOK},
+		{used: 3.5 * gib, limit: 4 * gib, system: 50, want: StatusDegraded},
+		{used: 4 * gib, limit: 4 * gib, system: 50, want: StatusDown},
+		{used: gib, limit: 4 * gib, system: 85, want: StatusDegraded},
+		{used: gib, system: 97.5, want: StatusDown},
+		{used: gib, systemErr: errors.New("no /proc"), want: StatusOK},
+	} {
+		m.runtime = func() (uint64, uint64) { return tc.used, tc.limit }
+		m.system = func(context.Context) (float64, error) { return tc.system, tc.systemErr }
+
+		r := m.Check(context.Background())
+		require.Equal(t, tc.want, r.Status, r.Message)
+	}
+
+	// the real sources
+	r := NewMemoryCheck(100).Check(context.Background())
+	require.Contains(t, r.Message, "runtime")
+}
+
+func TestDiskSpaceCheck(t *testing.T) {
+	const mib = 1 << 20
+
+	d := NewDiskSpaceCheck("/data", 512*mib)
+
+	for _, tc := range []struct {
+		free uint64
+		err  error
+		want string
+	}{
+		{free: 10 * 1024 * mib, want: StatusOK},
+		{free: 800 * mib, want: StatusDegraded},
+		{free: 100 * mib, want: StatusDown},
+		{err: errors.New("no such file or directory"), want: StatusDown},
+	} {
+		d.usage = func(string) (uint64, uint64, error) { return tc.free, 20 * 1024 * mib, tc.err }
+
+		r := d.Check(context.Background())
+		require.Equal(t, tc.want, r.Status, r.Message)
+	}
+
+	r := NewDiskSpaceCheck(t.TempDir(), 0).Check(context.Background())
+	require.Equal(t, StatusOK, r.Status, r.Message)
+	require.Contains(t, r.Message, "free of")
+}
+
+func TestFormatBytes(t *testing.T) {
+	require.Equal(t, "512 B", formatBytes(512))
+	require.Equal(t, "1.5 KiB", formatBytes(1536))
+	require.Equal(t, "2.0 GiB", formatBytes(2<<30))
+}
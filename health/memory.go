@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// memoryMargin are the percentage points below the limit of a MemoryCheck
+// where the memory is degraded.
+const memoryMargin = 10
+
+// MemoryCheck checks the memory of the scraper, the one the Go runtime holds
+// against GOMEMLIMIT when it is set, and the memory used on the machine, the
+// browsers among it.
+type MemoryCheck struct {
+	maxPercent float64
+
+	// runtime returns the memory the runtime holds and its limit, zero
+	// without GOMEMLIMIT
+	runtime func() (used, limit uint64)
+	// system returns the percentage of the memory used on the machine
+	system func(ctx context.Context) (float64, error)
+}
+
+// NewMemoryCheck is down when the memory used, by the runtime or on the
+// machine, is maxPercent or more, and degraded within 10 points of it.
+func NewMemoryCheck(maxPercent float64) *MemoryCheck {
+	return &MemoryCheck{
+		maxPercent: maxPercent,
+		runtime:    runtimeMemory,
+		system:     systemMemory,
+	}
+}
+
+// Name returns memory.
+func (m *MemoryCheck) Name() string {
+	return "memory"
+}
+
+// Check reports the memory used.
+func (m *MemoryCheck) Check(ctx context.Context) Result {
+	used, limit := m.runtime()
+
+	ans := Result{Status: StatusOK}
+
+	if limit > 0 {
+		percent := float64(used) / float64(limit) * 100 //nolint:gomnd // percentage
+
+		ans.Status = threshold(percent, m.maxPercent, memoryMargin)
+		ans.Message = fmt.Sprintf("runtime %s of %s (%.1f%%)", formatBytes(used), formatBytes(limit), percent)
+	} else {
+		ans.Message = fmt.Sprintf("runtime %s", formatBytes(used))
+	}
+
+	percent, err := m.system(ctx)
+	if err != nil {
+		ans.Message += fmt.Sprintf(", system unknown: %v", err)
+
+		return ans
+	}
+
+	ans.Status = Worst(ans.Status, threshold(percent, m.maxPercent, memoryMargin))
+	ans.Message += fmt.Sprintf(", system %.1f%% used", percent)
+
+	return ans
+}
+
+func runtimeMemory() (used, limit uint64) {
+	var stats runtime.MemStats
+
+	runtime.ReadMemStats(&stats)
+
+	used = stats.Sys - stats.HeapReleased
+
+	// a negative input reads the limit, math.MaxInt64 when there is none
+	if l := debug.SetMemoryLimit(-1); l > 0 && l < math.MaxInt64 {
+		limit = uint64(l)
+	}
+
+	return used, limit
+}
+
+func systemMemory(ctx context.Context) (float64, error) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return vm.UsedPercent, nil
+}
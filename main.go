@@ -9,12 +9,18 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/gosom/google-maps-scraper/metrics"
 	"github.com/gosom/google-maps-scraper/runner"
 	"github.com/gosom/google-maps-scraper/runner/databaserunner"
+	"github.com/gosom/google-maps-scraper/runner/distributed"
+	"github.com/gosom/google-maps-scraper/runner/enrichrunner"
 	"github.com/gosom/google-maps-scraper/runner/filerunner"
 	"github.com/gosom/google-maps-scraper/runner/installplaywright"
 	"github.com/gosom/google-maps-scraper/runner/lambdaaws"
+	"github.com/gosom/google-maps-scraper/runner/monitorrunner"
+	"github.com/gosom/google-maps-scraper/runner/occupancyrunner"
 	"github.com/gosom/google-maps-scraper/runner/webrunner"
+	"github.com/gosom/google-maps-scraper/tracing"
 )
 
 func main() {
@@ -35,6 +41,30 @@ func main() {
 
 	cfg := runner.ParseConfig()
 
+	shutdownTracing := func(context.Context) error { return nil }
+
+	if cfg.OtelEndpoint != "" {
+		var err error
+
+		shutdownTracing, err = tracing.Setup(ctx, cfg.OtelEndpoint)
+		if err != nil {
+			cancel()
+			os.Stderr.WriteString(err.Error() + "\n")
+
+			runner.Telemetry().Close()
+
+			os.Exit(1)
+		}
+	}
+
+	if cfg.MetricsAddr != "" {
+		go func() {
+			if err := metrics.Serve(ctx, cfg.MetricsAddr); err != nil {
+				log.Printf("metrics server failed: %v", err)
+			}
+		}()
+	}
+
 	runnerInstance, err := runnerFactory(cfg)
 	if err != nil {
 		cancel()
@@ -49,6 +79,7 @@ func main() {
 		os.Stderr.WriteString(err.Error() + "\n")
 
 		_ = runnerInstance.Close(ctx)
+		_ = shutdownTracing(context.Background())
 		runner.Telemetry().Close()
 
 		cancel()
@@ -57,6 +88,7 @@ func main() {
 	}
 
 	_ = runnerInstance.Close(ctx)
+	_ = shutdownTracing(context.Background())
 	runner.Telemetry().Close()
 
 	cancel()
@@ -78,6 +110,16 @@ func runnerFactory(cfg *runner.Config) (runner.Runner, error) {
 		return lambdaaws.New(cfg)
 	case runner.RunModeAwsLambdaInvoker:
 		return lambdaaws.NewInvoker(cfg)
+	case runner.RunModeCoordinator:
+		return distributed.NewCoordinator(cfg)
+	case runner.RunModeWorker:
+		return distributed.NewWorker(cfg)
+	case runner.RunModeOccupancy:
+		return occupancyrunner.New(cfg)
+	case runner.RunModeMonitor:
+		return monitorrunner.New(cfg)
+	case runner.RunModeEnrich:
+		return enrichrunner.New(cfg)
 	default:
 		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
 	}
@@ -0,0 +1,267 @@
+// Package metrics exports Prometheus metrics of the jobs: the scrapes by job
+// type and status with their durations, the blocks served by Google, the
+// proxy errors, the latency of the writers and the results waiting for
+// them. They are labeled by the
+// runner and the id of the web job, empty for the other runners.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/gosom/google-maps-scraper/health"
+)
+
+// The types of the jobs of the scrapes.
+const (
+	JobTypeSearch = "search"
+	JobTypePlace  = "place"
+	JobTypeEmail  = "email"
+	// JobTypeWeb is a job of the web runner, which runs the search, place and email jobs
+	JobTypeWeb = "web"
+)
+
+// The statuses of the scrapes.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+const namespace = "gmaps"
+
+var (
+	registry = prometheus.NewRegistry()
+	handler  http.Handler
+
+	jobs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_total",
+		Help:      "Jobs finished by type and status.",
+	}, []string{"runner", "job_id", "type", "status"})
+
+	scrapeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scrape_duration_seconds",
+		Help:      "Time spent loading the pages of the search, place and email jobs.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 40, 80, 160},
+	}, []string{"runner", "job_id", "type"})
+
+	blocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_total",
+		Help:      "Captchas served by Google.",
+	}, []string{"runner", "job_id"})
+
+	proxyErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proxy_errors_total",
+		Help:      "Pages that could not be loaded because of the proxy.",
+	}, []string{"runner", "job_id"})
+
+	writerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "writer_duration_seconds",
+		Help:      "Time the writers took to accept a result.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 9),
+	}, []string{"runner", "job_id", "writer"})
+
+	writerQueue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "writer_queue_depth",
+		Help:      "Results waiting for a writer slower than the scraping, in memory and on disk.",
+	}, []string{"runner", "job_id", "writer"})
+
+	writerSpilled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "writer_spilled_total",
+		Help:      "Results written to disk because their writer was too far behind.",
+	}, []string{"runner", "job_id", "writer"})
+
+	circuits = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_state",
+		Help:      "Hosts whose pages fail at once after failing in a row: 1 open, 0.5 half open while a page probes the host.",
+	}, []string{"runner", "host"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		jobs,
+		scrapeDuration,
+		blocks,
+		proxyErrors,
+		writerDuration,
+		writerQueue,
+		writerSpilled,
+		circuits,
+	)
+
+	handler = promhttp.InstrumentMetricHandler(registry, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return handler
+}
+
+// Serve serves the metrics at addr/metrics, and the health checks of the
+// runner at addr/api/health for the probes, until ctx is done.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	mux.Handle("/api/health", health.Handler())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Recorder records the metrics of the jobs of a runner, or of a web job. A
+// nil recorder records nothing. It is safe to share between jobs.
+type Recorder struct {
+	runner string
+	jobID  string
+}
+
+// NewRecorder returns the recorder of the jobs of runner, e.g. web or file,
+// and of the web job jobID, empty for the other runners.
+func NewRecorder(runner, jobID string) *Recorder {
+	return &Recorder{runner: runner, jobID: jobID}
+}
+
+// Scrape records a job of type jobType that took d and failed with err, nil
+// when it succeeded.
+func (r *Recorder) Scrape(jobType string, d time.Duration, err error) {
+	if r == nil {
+		return
+	}
+
+	status := StatusOK
+	if err != nil {
+		status = StatusError
+	}
+
+	jobs.WithLabelValues(r.runner, r.jobID, jobType, status).Inc()
+	scrapeDuration.WithLabelValues(r.runner, r.jobID, jobType).Observe(d.Seconds())
+
+	if IsProxyError(err) {
+		proxyErrors.WithLabelValues(r.runner, r.jobID).Inc()
+	}
+}
+
+// JobFinished records a web job that finished with status.
+func (r *Recorder) JobFinished(status string) {
+	if r == nil {
+		return
+	}
+
+	jobs.WithLabelValues(r.runner, r.jobID, JobTypeWeb, status).Inc()
+}
+
+// Block records a captcha served by Google.
+func (r *Recorder) Block() {
+	if r == nil {
+		return
+	}
+
+	blocks.WithLabelValues(r.runner, r.jobID).Inc()
+}
+
+// Write records a result the writer named writer took d to accept.
+func (r *Recorder) Write(writer string, d time.Duration) {
+	if r == nil {
+		return
+	}
+
+	writerDuration.WithLabelValues(r.runner, r.jobID, writer).Observe(d.Seconds())
+}
+
+// Queue records the number of results waiting for the writer named writer.
+func (r *Recorder) Queue(writer string, n int) {
+	if r == nil {
+		return
+	}
+
+	writerQueue.WithLabelValues(r.runner, r.jobID, writer).Set(float64(n))
+}
+
+// Spill records a result of the writer named writer written to disk.
+func (r *Recorder) Spill(writer string) {
+	if r == nil {
+		return
+	}
+
+	writerSpilled.WithLabelValues(r.runner, r.jobID, writer).Inc()
+}
+
+// Circuit records the state of the circuit of host: open, half_open or
+// closed, which removes the host.
+func (r *Recorder) Circuit(host, state string) {
+	if r == nil {
+		return
+	}
+
+	switch state {
+	case "open":
+		circuits.WithLabelValues(r.runner, host).Set(1)
+	case "half_open":
+		circuits.WithLabelValues(r.runner, host).Set(0.5)
+	default:
+		circuits.DeleteLabelValues(r.runner, host)
+	}
+}
+
+// proxyErrorTexts are the errors of the browser and of Go when the proxy
+// refuses the connection or the tunnel.
+var proxyErrorTexts = []string{
+	"err_proxy_connection_failed",
+	"err_tunnel_connection_failed",
+	"err_proxy_auth",
+	"err_socks_connection_failed",
+	"proxyconnect",
+	"proxy authentication required",
+}
+
+// IsProxyError reports whether err comes from the proxy rather than the
+// website.
+func IsProxyError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	for _, s := range proxyErrorTexts {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
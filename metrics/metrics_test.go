@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+func TestIsProxyError(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, metrics.IsProxyError(errors.New("page.goto: net::ERR_PROXY_CONNECTION_FAILED at https://example.com")))
+	require.True(t, metrics.IsProxyError(errors.New("proxyconnect tcp: dial tcp 10.0.0.1:8080: connection refused")))
+	require.False(t, metrics.IsProxyError(errors.New("page.goto: net::ERR_NAME_NOT_RESOLVED")))
+	require.False(t, metrics.IsProxyError(nil))
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	rec := metrics.NewRecorder("test", "job-1")
+	rec.Scrape(metrics.JobTypePlace, 2*time.Second, nil)
+	rec.Scrape(metrics.JobTypeEmail, time.Second, errors.New("net::ERR_TUNNEL_CONNECTION_FAILED"))
+	rec.Block()
+	rec.Write("csv", time.Millisecond)
+	rec.Queue("webhook", 3)
+	rec.Spill("webhook")
+	rec.JobFinished(metrics.StatusOK)
+
+	// a nil recorder records nothing
+	var none *metrics.Recorder
+	none.Scrape(metrics.JobTypePlace, time.Second, nil)
+	none.Block()
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+	body := w.Body.String()
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, body, `gmaps_jobs_total{job_id="job-1",runner="test",status="ok",type="place"} 1`)
+	require.Contains(t, body, `gmaps_jobs_total{job_id="job-1",runner="test",status="error",type="email"} 1`)
+	require.Contains(t, body, `gmaps_jobs_total{job_id="job-1",runner="test",status="ok",type="web"} 1`)
+	require.Contains(t, body, `gmaps_blocks_total{job_id="job-1",runner="test"} 1`)
+	require.Contains(t, body, `gmaps_proxy_errors_total{job_id="job-1",runner="test"} 1`)
+	require.Contains(t, body, `gmaps_scrape_duration_seconds_count{job_id="job-1",runner="test",type="place"} 1`)
+	require.Contains(t, body, `gmaps_writer_duration_seconds_count{job_id="job-1",runner="test",writer="csv"} 1`)
+	require.Contains(t, body, `gmaps_writer_queue_depth{job_id="job-1",runner="test",writer="webhook"} 3`)
+	require.Contains(t, body, `gmaps_writer_spilled_total{job_id="job-1",runner="test",writer="webhook"} 1`)
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gosom/scrapemate"
 
 	"github.com/gosom/google-maps-scraper/gmaps"
@@ -110,36 +111,13 @@ func (p *provider) Push(ctx context.Context, job scrapemate.IJob) error {
 		VALUES
 		($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-
-	var payloadType string
-
-	switch j := job.(type) {
-	case *gmaps.GmapJob:
-		payloadType = "search"
-
-		if err := enc.Encode(j); err != nil {
-			return err
-		}
-	case *gmaps.PlaceJob:
-		payloadType = "place"
-
-		if err := enc.Encode(j); err != nil {
-			return err
-		}
-	case *gmaps.EmailExtractJob:
-		payloadType = "email"
-
-		if err := enc.Encode(j); err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("invalid job type %T", job)
+	payloadType, payload, err := encodeJob(job)
+	if err != nil {
+		return err
 	}
 
-	_, err := p.db.ExecContext(ctx, q,
-		job.GetID(), job.GetPriority(), payloadType, buf.Bytes(), time.Now().UTC(), statusNew,
+	_, err = p.db.ExecContext(ctx, q,
+		rowID(job, payloadType), job.GetPriority(), payloadType, payload, time.Now().UTC(), statusNew,
 	)
 
 	return err
@@ -248,33 +226,84 @@ type encjob struct {
 	Data scrapemate.IJob
 }
 
+// encodeJob encodes the job and the next steps of its fallback chain.
+func encodeJob(job scrapemate.IJob) (string, []byte, error) {
+	var payloadType string
+
+	switch job.(type) {
+	case *gmaps.GmapJob:
+		payloadType = "search"
+	case *gmaps.SearchJob:
+		payloadType = "static"
+	case *gmaps.CroxyProxyJob:
+		payloadType = "croxy"
+	case *gmaps.PlaceJob:
+		payloadType = "place"
+	case *gmaps.EmailExtractJob:
+		payloadType = "email"
+	default:
+		return "", nil, fmt.Errorf("invalid job type %T", job)
+	}
+
+	var buf bytes.Buffer
+
+	enc := gob.NewEncoder(&buf)
+
+	if err := enc.Encode(job); err != nil {
+		return "", nil, err
+	}
+
+	if err := gmaps.EncodeFallback(enc, job, encodeJob); err != nil {
+		return "", nil, err
+	}
+
+	return payloadType, buf.Bytes(), nil
+}
+
 func decodeJob(payloadType string, payload []byte) (scrapemate.IJob, error) {
-	buf := bytes.NewBuffer(payload)
-	dec := gob.NewDecoder(buf)
+	var job scrapemate.IJob
 
 	switch payloadType {
 	case "search":
-		j := new(gmaps.GmapJob)
-		if err := dec.Decode(j); err != nil {
-			return nil, fmt.Errorf("failed to decode search job: %w", err)
-		}
-
-		return j, nil
+		job = new(gmaps.GmapJob)
+	case "static":
+		job = new(gmaps.SearchJob)
+	case "croxy":
+		job = new(gmaps.CroxyProxyJob)
 	case "place":
-		j := new(gmaps.PlaceJob)
-		if err := dec.Decode(j); err != nil {
-			return nil, fmt.Errorf("failed to decode place job: %w", err)
-		}
-
-		return j, nil
+		job = new(gmaps.PlaceJob)
 	case "email":
-		j := new(gmaps.EmailExtractJob)
-		if err := dec.Decode(j); err != nil {
-			return nil, fmt.Errorf("failed to decode email job: %w", err)
-		}
-
-		return j, nil
+		job = new(gmaps.EmailExtractJob)
 	default:
 		return nil, fmt.Errorf("invalid payload type: %s", payloadType)
 	}
+
+	dec := gob.NewDecoder(bytes.NewReader(payload))
+
+	if err := dec.Decode(job); err != nil {
+		return nil, fmt.Errorf("failed to decode %s job: %w", payloadType, err)
+	}
+
+	if err := gmaps.DecodeFallback(dec, job, decodeJob); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// rowID is the id of the row of the job. The steps of a fallback chain share
+// the id of the chain, which is also the parent id of the steps after the
+// first one, so these are stored under an id derived from it and their
+// payload type.
+func rowID(job scrapemate.IJob, payloadType string) string {
+	if job.GetParentID() != job.GetID() {
+		return job.GetID()
+	}
+
+	chain, err := uuid.Parse(job.GetID())
+	if err != nil {
+		return job.GetID()
+	}
+
+	return uuid.NewSHA1(chain, []byte(payloadType)).String()
 }
@@ -0,0 +1,106 @@
+// Package profile holds the presets of the crawl settings. The conservative
+// profile is meant for first runs: it is slow, but unlikely to get the IP
+// blocked by Google.
+package profile
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Default leaves the settings as they are.
+	Default = "default"
+	// Conservative crawls with one worker, a pause before every page, a
+	// small scroll depth and no enrichment.
+	Conservative = "conservative"
+)
+
+// Settings are the crawl settings a profile sets.
+type Settings struct {
+	Concurrency  int
+	Depth        int
+	Email        bool
+	ExtraReviews bool
+	// Delay is the pause of a worker before each page load or after each search
+	Delay time.Duration
+}
+
+// Recommended are the most aggressive settings that are not reported by Warnings.
+var Recommended = Settings{
+	Concurrency: 4,
+	Depth:       20,
+}
+
+var profiles = map[string]Settings{
+	Conservative: {
+		Concurrency: 1,
+		Depth:       5,
+		Delay:       3 * time.Second,
+	},
+}
+
+// Validate returns an error when the profile is not known.
+// An empty name is the default profile.
+func Validate(name string) error {
+	if name == "" || name == Default {
+		return nil
+	}
+
+	if _, ok := profiles[name]; !ok {
+		return fmt.Errorf("invalid profile %q, supported values: %s, %s", name, Default, Conservative)
+	}
+
+	return nil
+}
+
+// Get returns the settings of the profile and false for the default profile.
+func Get(name string) (Settings, bool) {
+	s, ok := profiles[name]
+
+	return s, ok
+}
+
+// Limit returns s capped to the settings of the profile: the concurrency and
+// depth are lowered, the enrichment disabled and the delay raised. Zero
+// values are left as they are, they stand for settings that do not apply.
+func Limit(name string, s Settings) Settings {
+	p, ok := profiles[name]
+	if !ok {
+		return s
+	}
+
+	if s.Concurrency > 0 {
+		s.Concurrency = min(s.Concurrency, p.Concurrency)
+	}
+
+	if s.Depth > 0 {
+		s.Depth = min(s.Depth, p.Depth)
+	}
+
+	s.Email = s.Email && p.Email
+	s.ExtraReviews = s.ExtraReviews && p.ExtraReviews
+	s.Delay = max(s.Delay, p.Delay)
+
+	return s
+}
+
+// Warnings returns a message for each setting that is more aggressive than
+// Recommended. A zero concurrency or depth is not checked.
+func Warnings(s Settings) []string {
+	var ans []string
+
+	if s.Concurrency > Recommended.Concurrency {
+		ans = append(ans, fmt.Sprintf("concurrency %d is above the recommended %d, Google may block the IP", s.Concurrency, Recommended.Concurrency))
+	}
+
+	if s.Depth > Recommended.Depth {
+		ans = append(ans, fmt.Sprintf("depth %d is above the recommended %d, long scrolls are likely to be blocked", s.Depth, Recommended.Depth))
+	}
+
+	if s.ExtraReviews && s.Delay == 0 {
+		ans = append(ans, "extra reviews without a delay send many requests per place")
+	}
+
+	return ans
+}
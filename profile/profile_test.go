@@ -0,0 +1,38 @@
+package profile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/profile"
+)
+
+func Test_Limit(t *testing.T) {
+	t.Parallel()
+
+	s := profile.Settings{
+		Concurrency:  8,
+		Depth:        2,
+		Email:        true,
+		ExtraReviews: true,
+	}
+
+	require.Equal(t, s, profile.Limit(profile.Default, s))
+
+	got := profile.Limit(profile.Conservative, s)
+	require.Equal(t, profile.Settings{Concurrency: 1, Depth: 2, Delay: 3 * time.Second}, got)
+	require.Empty(t, profile.Warnings(got))
+}
+
+func Test_Warnings(t *testing.T) {
+	t.Parallel()
+
+	require.Len(t, profile.Warnings(profile.Settings{Concurrency: 8, Depth: 50, ExtraReviews: true}), 3)
+	require.Empty(t, profile.Warnings(profile.Settings{Concurrency: 4, Depth: 20, ExtraReviews: true, Delay: time.Second}))
+
+	require.NoError(t, profile.Validate(""))
+	require.NoError(t, profile.Validate(profile.Conservative))
+	require.Error(t, profile.Validate("aggressive"))
+}
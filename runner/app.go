@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/scrapemateapp"
+
+	"github.com/gosom/google-maps-scraper/browser"
+)
+
+// App runs the jobs, it is a scrapemateapp.ScrapemateApp or, with a remote
+// browser, another engine than chromium or videos, a browser.App.
+type App interface {
+	Start(ctx context.Context, seedJobs ...scrapemate.IJob) error
+	Close() error
+}
+
+// NewApp returns the app of the config of scrapemate. The browser pages run in
+// the engine browser, chromium when it is empty, and in the remote browser of
+// -browser-ws-endpoint when it is set. The browsers record videos with
+// -video-dir. Fast mode does not use a browser.
+func (c *Config) NewApp(matecfg *scrapemateapp.Config, engine string) (App, error) {
+	if engine == "" {
+		engine = browser.EngineChromium
+	}
+
+	if !matecfg.UseJS || (c.BrowserWSEndpoint == "" && engine == browser.EngineChromium && c.VideoDir == "") {
+		return scrapemateapp.NewScrapeMateApp(matecfg)
+	}
+
+	var videoDir string
+
+	// the recordings are kept or deleted by the jobs, see NewVideoRecorder
+	if c.VideoDir != "" {
+		videoDir = filepath.Join(os.TempDir(), "google-maps-scraper-videos")
+	}
+
+	return browser.NewApp(matecfg, browser.Options{
+		Engine:   engine,
+		VideoDir: videoDir,
+		Endpoint: c.BrowserWSEndpoint,
+		Protocol: c.BrowserProtocol,
+	}), nil
+}
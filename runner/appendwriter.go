@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// RepairCSV makes the csv results at path, with the delimiter comma, safe to
+// append to after the run that wrote them stopped: the last row is removed
+// when it was not fully written. It returns the data_id of the places in the
+// file, none when it has no data_id column.
+func RepairCSV(path string, comma rune) (map[string]bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(f))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	var (
+		seen   = make(map[string]bool)
+		header = true
+		column = -1
+		end    int64
+		last   = make([]byte, 1)
+	)
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// a row cut in a quoted field
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		offset := r.InputOffset()
+
+		if _, err := f.ReadAt(last, offset-1); err != nil || last[0] != '\n' {
+			// a row cut before its line break
+			break
+		}
+
+		end = offset
+
+		if header {
+			header = false
+			column = slices.Index(record, "data_id")
+
+			continue
+		}
+
+		if column >= 0 && column < len(record) && record[column] != "" {
+			seen[record[column]] = true
+		}
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() > end {
+		if err := f.Truncate(end); err != nil {
+			return nil, err
+		}
+	}
+
+	return seen, nil
+}
+
+var _ scrapemate.ResultWriter = (*appendWriter)(nil)
+
+type appendWriter struct {
+	w    scrapemate.ResultWriter
+	seen map[string]bool
+}
+
+// NewAppendWriter drops the entries whose data_id is in seen, the places of
+// the results a resumed run appends to, see RepairCSV, and the ones it
+// already forwarded, so a place scraped again after a restart is written
+// once. It returns w when seen is nil.
+func NewAppendWriter(w scrapemate.ResultWriter, seen map[string]bool) scrapemate.ResultWriter {
+	if seen == nil {
+		return w
+	}
+
+	return &appendWriter{w: w, seen: seen}
+}
+
+func (a *appendWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := Forward(ctx, a.w)
+
+	for result := range in {
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			if !a.add(data) {
+				continue
+			}
+		case []*gmaps.Entry:
+			entries := make([]*gmaps.Entry, 0, len(data))
+
+			for _, e := range data {
+				if a.add(e) {
+					entries = append(entries, e)
+				}
+			}
+
+			if len(entries) == 0 {
+				continue
+			}
+
+			result.Data = entries
+		}
+
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
+
+// add reports whether e was not seen yet and marks it seen.
+func (a *appendWriter) add(e *gmaps.Entry) bool {
+	if e.DataID == "" {
+		return true
+	}
+
+	if a.seen[e.DataID] {
+		return false
+	}
+
+	a.seen[e.DataID] = true
+
+	return true
+}
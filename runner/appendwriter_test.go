@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func TestRepairCSV(t *testing.T) {
+	const rows = "title,data_id\n" +
+		"a,1\n" +
+		"\"b, the pub\",2\n"
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  map[string]bool
+	}{
+		{name: "complete", input: rows, want: map[string]bool{"1": true, "2": true}},
+		// a row cut before its line break
+		{name: "cut row", input: rows + "c,3", want: map[string]bool{"1": true, "2": true}},
+		// a row cut in a quoted field
+		{name: "cut quote", input: rows + "\"d, the", want: map[string]bool{"1": true, "2": true}},
+		{name: "cut header", input: "title,da", want: map[string]bool{}},
+	} {
+		path := filepath.Join(t.TempDir(), "results.csv")
+		require.NoError(t, os.WriteFile(path, []byte(tc.input), 0o600))
+
+		seen, err := RepairCSV(path, ',')
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.want, seen, tc.name)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, tc.name)
+
+		if tc.name == "cut header" {
+			require.Empty(t, data, tc.name)
+		} else {
+			require.Equal(t, rows, string(data), tc.name)
+		}
+	}
+}
+
+func TestRepairCSVNoDataID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	require.NoError(t, os.WriteFile(path, []byte("title;cid\na;1\nb;2"), 0o600))
+
+	seen, err := RepairCSV(path, ';')
+	require.NoError(t, err)
+	require.Empty(t, seen)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "title;cid\na;1\n", string(data))
+
+	// a file that does not exist has nothing to repair
+	seen, err = RepairCSV(filepath.Join(t.TempDir(), "missing.csv"), ',')
+	require.NoError(t, err)
+	require.Nil(t, seen)
+}
+
+func TestAppendWriter(t *testing.T) {
+	c := &collectWriter{}
+
+	writeAll(t, NewAppendWriter(c, map[string]bool{"1": true}),
+		scrapemate.Result{Data: &gmaps.Entry{Title: "a", DataID: "1"}},
+		scrapemate.Result{Data: []*gmaps.Entry{
+			{Title: "b", DataID: "2"},
+			{Title: "b again", DataID: "2"},
+		}},
+		scrapemate.Result{Data: "not an entry"},
+	)
+
+	require.Equal(t, []string{"other", "other"}, c.titles())
+	require.Equal(t, []*gmaps.Entry{{Title: "b", DataID: "2"}}, c.results[0].Data)
+}
@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/autotune"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// NewTuner returns the tuner of the crawl, starting at the concurrency and
+// the delay of the config. It returns nil when autotune is not set.
+// The tuner must be run with Run for the jobs to adapt.
+func (c *Config) NewTuner() *autotune.Tuner {
+	if !c.AutoTune {
+		return nil
+	}
+
+	return autotune.New(c.Concurrency, autotune.WithDelay(c.Delay))
+}
+
+// ApplyTuner makes the jobs load the pages of Google at the pace of t.
+// It does nothing when t is nil.
+func ApplyTuner(jobs []scrapemate.IJob, t *autotune.Tuner) {
+	if t == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithThrottle(t)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobThrottle(t)(j)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/crm"
+	"github.com/gosom/google-maps-scraper/crm/hubspot"
+	"github.com/gosom/google-maps-scraper/crm/pipedrive"
+)
+
+const (
+	CRMHubSpot   = "hubspot"
+	CRMPipedrive = "pipedrive"
+)
+
+// NewCRMWriter returns a writer that pushes the entries to the configured CRM.
+func NewCRMWriter(cfg *Config) (scrapemate.ResultWriter, error) {
+	var client crm.Client
+
+	switch cfg.CRM {
+	case CRMHubSpot:
+		client = hubspot.New(cfg.CRMToken)
+	case CRMPipedrive:
+		client = pipedrive.New(cfg.CRMToken)
+	default:
+		return nil, fmt.Errorf("unsupported crm: %s", cfg.CRM)
+	}
+
+	return crm.NewResultWriter(client, cfg.CRMDryRun), nil
+}
@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+var _ scrapemate.ResultWriter = (*layoutCSVWriter)(nil)
+
+type layoutCSVWriter struct {
+	w      *bufio.Writer
+	csv    *csv.Writer
+	layout *gmaps.CSVLayout
+}
+
+// NewCSVWriter writes the entries as csv in layout, which must be valid.
+// The flat layout without columns buffers the entries and writes them when
+// the input is closed, since its columns are only known once all the places
+// are scraped.
+func NewCSVWriter(w io.Writer, layout *gmaps.CSVLayout) scrapemate.ResultWriter {
+	bw := bufio.NewWriter(w)
+
+	cw := csv.NewWriter(bw)
+	cw.Comma = layout.Comma()
+
+	return &layoutCSVWriter{w: bw, csv: cw, layout: layout}
+}
+
+func (c *layoutCSVWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	columns := c.layout.Columns
+	buffered := len(columns) == 0 && c.layout.Flat()
+
+	if len(columns) == 0 && !buffered {
+		columns = c.layout.Headers()
+	}
+
+	var (
+		entries []*gmaps.Entry
+		header  bool
+	)
+
+	for result := range in {
+		var batch []*gmaps.Entry
+
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			batch = append(batch, data)
+		case []*gmaps.Entry:
+			batch = data
+		}
+
+		if buffered {
+			entries = append(entries, batch...)
+
+			continue
+		}
+
+		if len(batch) == 0 {
+			continue
+		}
+
+		if !header {
+			if err := c.write(columns); err != nil {
+				return err
+			}
+
+			header = true
+		}
+
+		if err := c.writeEntries(columns, batch); err != nil {
+			return err
+		}
+
+		if err := c.flush(); err != nil {
+			return err
+		}
+	}
+
+	if !buffered || len(entries) == 0 {
+		return c.flush()
+	}
+
+	columns = c.layout.FlatColumns(entries)
+
+	if err := c.write(columns); err != nil {
+		return err
+	}
+
+	if err := c.writeEntries(columns, entries); err != nil {
+		return err
+	}
+
+	return c.flush()
+}
+
+func (c *layoutCSVWriter) writeEntries(columns []string, entries []*gmaps.Entry) error {
+	row := make([]string, len(columns))
+
+	for _, e := range entries {
+		cells := c.layout.Cells(e)
+
+		for i, col := range columns {
+			row[i] = cells[col]
+		}
+
+		if err := c.write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write writes row, with all its fields quoted when the layout says so,
+// which encoding/csv does not do.
+func (c *layoutCSVWriter) write(row []string) error {
+	if !c.layout.QuoteAll() {
+		return c.csv.Write(row)
+	}
+
+	for i, field := range row {
+		if i > 0 {
+			if _, err := c.w.WriteRune(c.csv.Comma); err != nil {
+				return err
+			}
+		}
+
+		if _, err := c.w.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`); err != nil {
+			return err
+		}
+	}
+
+	return c.w.WriteByte('\n')
+}
+
+func (c *layoutCSVWriter) flush() error {
+	c.csv.Flush()
+
+	if err := c.csv.Error(); err != nil {
+		return err
+	}
+
+	return c.w.Flush()
+}
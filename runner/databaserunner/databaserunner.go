@@ -21,7 +21,7 @@ type dbrunner struct {
 	cfg      *runner.Config
 	provider scrapemate.JobProvider
 	produce  bool
-	app      *scrapemateapp.ScrapemateApp
+	app      runner.App
 	conn     *sql.DB
 }
 
@@ -30,6 +30,8 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
 	}
 
+	cfg.RegisterHealthChecks()
+
 	conn, err := openPsqlConn(cfg.Dsn)
 	if err != nil {
 		return nil, err
@@ -52,6 +54,10 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		psqlWriter,
 	}
 
+	if cfg.OtelEndpoint != "" {
+		writers[0] = runner.NewTracingWriter(writers[0])
+	}
+
 	opts := []func(*scrapemateapp.Config) error{
 		// scrapemateapp.WithCache("leveldb", "cache"),
 		scrapemateapp.WithConcurrency(cfg.Concurrency),
@@ -93,7 +99,7 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		return nil, err
 	}
 
-	ans.app, err = scrapemateapp.NewScrapeMateApp(matecfg)
+	ans.app, err = cfg.NewApp(matecfg, cfg.BrowserEngine)
 	if err != nil {
 		return nil, err
 	}
@@ -108,6 +114,8 @@ func (d *dbrunner) Run(ctx context.Context) error {
 		return d.produceSeedJobs(ctx)
 	}
 
+	d.cfg.WatchBrowsers(ctx, nil)
+
 	return d.app.Start(ctx)
 }
 
@@ -140,20 +148,61 @@ func (d *dbrunner) produceSeedJobs(ctx context.Context) error {
 		input = f
 	}
 
-	jobs, err := runner.CreateSeedJobs(
-		d.cfg.FastMode,
-		d.cfg.LangCode,
-		input,
-		d.cfg.MaxDepth,
-		d.cfg.Email,
-		d.cfg.GeoCoordinates,
-		d.cfg.Zoom,
-		d.cfg.Radius,
-		nil,
-		nil,
-		d.cfg.ExtraReviews,
-		d.cfg.UseCroxy,
-	)
+	tiles, err := d.cfg.Tiles()
+	if err != nil {
+		return err
+	}
+
+	var jobs []scrapemate.IJob
+
+	if tiles != nil {
+		jobs, err = runner.CreateTiledSeedJobs(
+			d.cfg.FastMode,
+			d.cfg.LangCode,
+			d.cfg.Region,
+			input,
+			d.cfg.MaxDepth,
+			d.cfg.Email,
+			tiles,
+			nil,
+			nil,
+			nil,
+			nil,
+			d.cfg.ExtraReviews,
+			d.cfg.UseCroxy,
+			d.cfg.RequestExtras,
+		)
+	} else {
+		var (
+			coords string
+			zoom   int
+			radius float64
+		)
+
+		coords, zoom, radius, err = d.cfg.SearchArea(ctx)
+		if err != nil {
+			return err
+		}
+
+		jobs, err = runner.CreateSeedJobs(
+			d.cfg.FastMode,
+			d.cfg.LangCode,
+			d.cfg.Region,
+			input,
+			d.cfg.MaxDepth,
+			d.cfg.Email,
+			coords,
+			zoom,
+			radius,
+			nil,
+			nil,
+			d.cfg.ExtraReviews,
+			d.cfg.UseCroxy,
+			d.cfg.RequestExtras,
+			d.cfg.Fallback,
+		)
+	}
+
 	if err != nil {
 		return err
 	}
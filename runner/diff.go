@@ -0,0 +1,383 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// The types of the changes.
+const (
+	// ChangeOwnership is emitted when a place has a new owner
+	ChangeOwnership = "ownership_changed"
+	// ChangeOpenHours is emitted when the opening hours of a place changed
+	ChangeOpenHours = "open_hours_changed"
+	ChangePhone     = "phone_changed"
+	ChangeRating    = "rating_changed"
+	ChangeWebsite   = "website_changed"
+	// ChangeStatus is emitted when a place closed temporarily or opened again
+	ChangeStatus = "status_changed"
+	// ChangeClosed is emitted when a place is permanently closed
+	ChangeClosed = "permanently_closed"
+)
+
+// The business status of a place, see BusinessStatus.
+const (
+	StatusOperational       = "operational"
+	StatusTemporarilyClosed = "temporarily_closed"
+	StatusPermanentlyClosed = "permanently_closed"
+)
+
+// Change is a difference found between a place of a previous run and the
+// same place scraped again.
+type Change struct {
+	Type       string    `json:"type"`
+	Key        string    `json:"key"`
+	Title      string    `json:"title"`
+	Link       string    `json:"link"`
+	Old        any       `json:"old"`
+	New        any       `json:"new"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// Snapshot holds the places of a previous run by their place key.
+type Snapshot map[string]*gmaps.Entry
+
+// PlaceKey returns the key used to match a place between runs.
+func PlaceKey(e *gmaps.Entry) string {
+	if e.Cid != "" {
+		return "cid:" + e.Cid
+	}
+
+	return gmaps.DedupKey(e.Link)
+}
+
+// LoadSnapshot reads the results of a previous run. Files ending in .csv are
+// read as csv results, anything else as json results (one entry per line).
+func LoadSnapshot(path string) (Snapshot, error) {
+	entries, err := ReadEntries(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+
+	s := make(Snapshot, len(entries))
+
+	for i := range entries {
+		s[PlaceKey(entries[i])] = entries[i]
+	}
+
+	return s, nil
+}
+
+// ReadEntries reads the places of a results file. Files ending in .csv are
+// read as csv results, anything else as json results (one entry per line).
+// Only the columns used to compare and enrich the places are read from csv.
+func ReadEntries(path string) ([]*gmaps.Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readCSVEntries(f)
+	}
+
+	return readJSONEntries(f)
+}
+
+func readJSONEntries(r io.Reader) ([]*gmaps.Entry, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var entries []*gmaps.Entry
+
+	for {
+		var e gmaps.Entry
+
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &e)
+	}
+}
+
+func readCSVEntries(r io.Reader) ([]*gmaps.Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[h] = i
+	}
+
+	get := func(row []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return row[i]
+		}
+
+		return ""
+	}
+
+	var entries []*gmaps.Entry
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		e := gmaps.Entry{
+			Query:    get(row, "query"),
+			Link:     get(row, "link"),
+			Cid:      get(row, "cid"),
+			Title:    get(row, "title"),
+			Category: get(row, "category"),
+			Address:  get(row, "address"),
+			DataID:   get(row, "data_id"),
+			Phone:    get(row, "phone"),
+			WebSite:  get(row, "website"),
+			Status:   get(row, "status"),
+		}
+
+		if owner := get(row, "owner"); owner != "" {
+			if err := json.Unmarshal([]byte(owner), &e.Owner); err != nil {
+				return nil, fmt.Errorf("invalid owner of %s: %w", e.Link, err)
+			}
+		}
+
+		if hours := get(row, "open_hours"); hours != "" {
+			if err := json.Unmarshal([]byte(hours), &e.OpenHours); err != nil {
+				return nil, fmt.Errorf("invalid open hours of %s: %w", e.Link, err)
+			}
+		}
+
+		if rating := get(row, "review_rating"); rating != "" {
+			e.ReviewRating, err = strconv.ParseFloat(rating, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid review rating of %s: %w", e.Link, err)
+			}
+		}
+
+		entries = append(entries, &e)
+	}
+}
+
+// Diff compares a place with its previous version and returns the changes.
+// A value missing on either side is not a change, since the values are not
+// always extracted.
+func Diff(prev, cur *gmaps.Entry) []Change {
+	var changes []Change
+
+	add := func(typ string, before, after any) {
+		changes = append(changes, Change{
+			Type:  typ,
+			Key:   PlaceKey(cur),
+			Title: cur.Title,
+			Link:  cur.Link,
+			Old:   before,
+			New:   after,
+		})
+	}
+
+	if ownerChanged(prev.Owner, cur.Owner) {
+		add(ChangeOwnership, prev.Owner, cur.Owner)
+	}
+
+	if len(prev.OpenHours) > 0 && len(cur.OpenHours) > 0 && !reflect.DeepEqual(prev.OpenHours, cur.OpenHours) {
+		add(ChangeOpenHours, prev.OpenHours, cur.OpenHours)
+	}
+
+	if valueChanged(phoneDigits(prev.Phone), phoneDigits(cur.Phone)) {
+		add(ChangePhone, prev.Phone, cur.Phone)
+	}
+
+	if prev.ReviewRating > 0 && cur.ReviewRating > 0 && prev.ReviewRating != cur.ReviewRating {
+		add(ChangeRating, prev.ReviewRating, cur.ReviewRating)
+	}
+
+	if valueChanged(websiteKey(prev.WebSite), websiteKey(cur.WebSite)) {
+		add(ChangeWebsite, prev.WebSite, cur.WebSite)
+	}
+
+	if before, after := BusinessStatus(prev.Status), BusinessStatus(cur.Status); prev.Status != "" && cur.Status != "" && before != after {
+		if after == StatusPermanentlyClosed {
+			add(ChangeClosed, before, after)
+		} else {
+			add(ChangeStatus, before, after)
+		}
+	}
+
+	return changes
+}
+
+// BusinessStatus returns whether the place of the status Google shows is
+// operational, temporarily or permanently closed. The opening times of the
+// status, like "Open ⋅ Closes 8 PM", are ignored.
+func BusinessStatus(status string) string {
+	s := strings.ToLower(status)
+
+	switch {
+	case strings.Contains(s, "permanently closed"):
+		return StatusPermanentlyClosed
+	case strings.Contains(s, "temporarily closed"):
+		return StatusTemporarilyClosed
+	default:
+		return StatusOperational
+	}
+}
+
+func valueChanged(prev, cur string) bool {
+	return prev != "" && cur != "" && prev != cur
+}
+
+// phoneDigits keeps the digits of a phone number, so a number written with
+// other spaces is not a change.
+func phoneDigits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+
+		return -1
+	}, phone)
+}
+
+// websiteKey returns the host and path of a website without the scheme, www.
+// and the query, so tracking parameters are not a change.
+func websiteKey(website string) string {
+	u, err := url.Parse(strings.TrimSpace(website))
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSpace(website))
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.Path, "/")
+}
+
+// ownerChanged reports whether the owner is different. A missing owner on
+// either side is not a change, since the owner is not always extracted.
+func ownerChanged(prev, cur gmaps.Owner) bool {
+	if prev.ID == "" && prev.Name == "" || cur.ID == "" && cur.Name == "" {
+		return false
+	}
+
+	if prev.ID != "" && cur.ID != "" {
+		return prev.ID != cur.ID
+	}
+
+	return !strings.EqualFold(strings.TrimSpace(prev.Name), strings.TrimSpace(cur.Name))
+}
+
+var _ scrapemate.ResultWriter = (*DiffWriter)(nil)
+
+// DiffWriter compares the entries with a previous snapshot and writes the
+// changes as json lines to the report before passing the results to the
+// wrapped writer.
+type DiffWriter struct {
+	w        scrapemate.ResultWriter
+	previous Snapshot
+
+	mu      sync.Mutex
+	enc     *json.Encoder
+	changes int
+}
+
+func NewDiffWriter(w scrapemate.ResultWriter, previous Snapshot, report io.Writer) *DiffWriter {
+	return &DiffWriter{
+		w:        w,
+		previous: previous,
+		enc:      json.NewEncoder(report),
+	}
+}
+
+func (d *DiffWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := Forward(ctx, d.w)
+
+	for result := range in {
+		for _, e := range Entries(result.Data) {
+			if err := d.compare(e); err != nil {
+				_ = fw.Close()
+
+				return fmt.Errorf("failed to write diff report: %w", err)
+			}
+		}
+
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
+
+// Changes returns the number of changes written to the report.
+func (d *DiffWriter) Changes() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.changes
+}
+
+func (d *DiffWriter) compare(e *gmaps.Entry) error {
+	prev, ok := d.previous[PlaceKey(e)]
+	if !ok {
+		return nil
+	}
+
+	changes := Diff(prev, e)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	for i := range changes {
+		changes[i].DetectedAt = now
+
+		if err := d.enc.Encode(changes[i]); err != nil {
+			return err
+		}
+
+		d.changes++
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package distributed
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
+	"github.com/gosom/scrapemate/adapters/writers/jsonwriter"
+
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/tlmt"
+)
+
+// defaultIdle is how long the coordinator waits for results when
+// -exit-on-inactivity is not set.
+const defaultIdle = 5 * time.Minute
+
+// results are the places the workers pushed to the queue.
+type results interface {
+	PopResult(ctx context.Context) (*TakenResult, error)
+	DoneResult(ctx context.Context, r *TakenResult) error
+	Pending(ctx context.Context) (int64, error)
+}
+
+type coordinator struct {
+	cfg     *runner.Config
+	queue   *Queue
+	results results
+}
+
+// NewCoordinator returns a runner that pushes the seed jobs of the input to
+// the queue and writes the places the workers find to the results file. It
+// stops when no place arrived for -exit-on-inactivity and the queue is empty.
+func NewCoordinator(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeCoordinator {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	cfg.RegisterHealthChecks()
+
+	queue, err := NewQueue(context.Background(), cfg.DistributedRedisURL, cfg.DistributedQueue)
+	if err != nil {
+		return nil, err
+	}
+
+	return &coordinator{cfg: cfg, queue: queue, results: queue}, nil
+}
+
+func (c *coordinator) Run(ctx context.Context) error {
+	_ = runner.Telemetry().Send(ctx, tlmt.NewEvent("distributed.coordinator.Run", nil))
+
+	// the places taken by a coordinator that crashed before it wrote them
+	restored, err := c.queue.RestoreResults(ctx)
+	if err != nil {
+		return err
+	}
+
+	if restored > 0 {
+		log.Printf("restored %d places not written by a previous run", restored)
+	}
+
+	seedJobs, err := c.seedJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range seedJobs {
+		if err := c.queue.Push(ctx, seedJobs[i]); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("pushed %d seed jobs to queue %s", len(seedJobs), c.cfg.DistributedQueue)
+
+	out := io.Writer(os.Stdout)
+
+	if c.cfg.ResultsFile != "stdout" {
+		f, err := os.Create(c.cfg.ResultsFile)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		out = f
+	}
+
+	var writer scrapemate.ResultWriter
+
+	if c.cfg.JSON {
+		writer = jsonwriter.NewJSONWriter(out)
+	} else {
+		writer = csvwriter.NewCsvWriter(csv.NewWriter(out))
+	}
+
+	in := make(chan scrapemate.Result)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- writer.Run(ctx, in)
+	}()
+
+	err = c.collect(ctx, in)
+
+	close(in)
+
+	return errors.Join(err, <-errc)
+}
+
+// collect sends the places of the queue to in until the workers are idle.
+// A place leaves the queue once the writer took it.
+func (c *coordinator) collect(ctx context.Context, in chan<- scrapemate.Result) error {
+	idle := c.cfg.ExitOnInactivityDuration
+	if idle <= 0 {
+		idle = defaultIdle
+	}
+
+	var (
+		lastResult = time.Now()
+		count      int
+	)
+
+	for {
+		taken, err := c.results.PopResult(ctx)
+
+		switch {
+		case ctx.Err() != nil:
+			log.Printf("collected %d places", count)
+
+			return nil
+		case err != nil:
+			return err
+		case taken != nil:
+			count++
+			lastResult = time.Now()
+
+			select {
+			case in <- scrapemate.Result{Data: taken.Entry}:
+			case <-ctx.Done():
+				return nil
+			}
+
+			if err := c.results.DoneResult(ctx, taken); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if time.Since(lastResult) < idle {
+			continue
+		}
+
+		pending, err := c.results.Pending(ctx)
+		if err != nil {
+			return err
+		}
+
+		if pending == 0 {
+			log.Printf("no places for %s and the queue is empty, collected %d places", idle, count)
+
+			return nil
+		}
+
+		log.Printf("no places for %s but %d jobs are queued, are the workers running?", idle, pending)
+
+		lastResult = time.Now()
+	}
+}
+
+func (c *coordinator) seedJobs(ctx context.Context) ([]scrapemate.IJob, error) {
+	var input io.Reader
+
+	switch c.cfg.InputFile {
+	case "stdin":
+		input = os.Stdin
+	default:
+		f, err := os.Open(c.cfg.InputFile)
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		input = f
+	}
+
+	tiles, err := c.cfg.Tiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if tiles != nil {
+		return runner.CreateTiledSeedJobs(
+			c.cfg.FastMode,
+			c.cfg.LangCode,
+			c.cfg.Region,
+			input,
+			c.cfg.MaxDepth,
+			c.cfg.Email,
+			tiles,
+			nil,
+			nil,
+			nil,
+			nil,
+			c.cfg.ExtraReviews,
+			c.cfg.UseCroxy,
+			c.cfg.RequestExtras,
+		)
+	}
+
+	coords, zoom, radius, err := c.cfg.SearchArea(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.CreateSeedJobs(
+		c.cfg.FastMode,
+		c.cfg.LangCode,
+		c.cfg.Region,
+		input,
+		c.cfg.MaxDepth,
+		c.cfg.Email,
+		coords,
+		zoom,
+		radius,
+		nil,
+		nil,
+		c.cfg.ExtraReviews,
+		c.cfg.UseCroxy,
+		c.cfg.RequestExtras,
+		c.cfg.Fallback,
+	)
+}
+
+func (c *coordinator) Close(context.Context) error {
+	return c.queue.Close()
+}
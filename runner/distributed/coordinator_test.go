@@ -0,0 +1,117 @@
+package distributed
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+// fakeResults are the results of a queue with pending jobs and no worker.
+type fakeResults struct {
+	mu      sync.Mutex
+	entries []*gmaps.Entry
+	done    int
+	pending []int64
+	checks  int
+}
+
+func (f *fakeResults) PopResult(ctx context.Context) (*TakenResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.entries) == 0 {
+		// the pop waits for a place
+		select {
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+		}
+
+		return nil, nil
+	}
+
+	e := f.entries[0]
+	f.entries = f.entries[1:]
+
+	return &TakenResult{Entry: e}, nil
+}
+
+func (f *fakeResults) DoneResult(context.Context, *TakenResult) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.done++
+
+	return nil
+}
+
+func (f *fakeResults) Pending(context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.checks++
+
+	ans := f.pending[0]
+	if len(f.pending) > 1 {
+		f.pending = f.pending[1:]
+	}
+
+	return ans, nil
+}
+
+func TestCoordinatorCollect(t *testing.T) {
+	results := &fakeResults{
+		entries: []*gmaps.Entry{{Title: "a"}, {Title: "b"}},
+		// the workers still run a job when the places stop first
+		pending: []int64{1, 0},
+	}
+
+	c := &coordinator{
+		cfg:     &runner.Config{ExitOnInactivityDuration: 20 * time.Millisecond},
+		results: results,
+	}
+
+	in := make(chan scrapemate.Result)
+
+	var titles []string
+
+	written := make(chan struct{})
+
+	go func() {
+		defer close(written)
+
+		for r := range in {
+			titles = append(titles, r.Data.(*gmaps.Entry).Title)
+		}
+	}()
+
+	require.NoError(t, c.collect(context.Background(), in))
+	close(in)
+	<-written
+
+	require.Equal(t, []string{"a", "b"}, titles)
+	require.Equal(t, 2, results.done)
+	// it waited again while a job was pending
+	require.Equal(t, 2, results.checks)
+}
+
+func TestCoordinatorCollectCancel(t *testing.T) {
+	results := &fakeResults{pending: []int64{1}}
+
+	c := &coordinator{
+		cfg:     &runner.Config{ExitOnInactivityDuration: time.Hour},
+		results: results,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, c.collect(ctx, make(chan scrapemate.Result)))
+	require.Zero(t, results.checks)
+}
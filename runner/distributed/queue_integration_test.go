@@ -0,0 +1,128 @@
+//go:build integration
+
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// newTestQueue returns an empty queue on the redis of DISTRIBUTED_TEST_REDIS.
+// Run with: DISTRIBUTED_TEST_REDIS=redis://... go test -tags integration ./runner/distributed/
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+
+	redisURL := os.Getenv("DISTRIBUTED_TEST_REDIS")
+	if redisURL == "" {
+		t.Skip("DISTRIBUTED_TEST_REDIS is not set")
+	}
+
+	name := fmt.Sprintf("gmaps-test-%d", time.Now().UnixNano())
+
+	q, err := NewQueue(context.Background(), redisURL, name)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = q.client.Del(context.Background(), q.jobs, q.taken, q.scores, q.results, q.written).Err()
+		_ = q.Close()
+	})
+
+	return q
+}
+
+func newPlaceJob(u string) scrapemate.IJob {
+	return gmaps.NewPlaceJob("search", "en", u, false, false)
+}
+
+func TestQueueJobsLease(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+
+	for _, u := range []string{"https://example.com/a", "https://example.com/b"} {
+		require.NoError(t, q.Push(ctx, newPlaceJob(u)))
+	}
+
+	wctx, cancel := context.WithCancel(ctx)
+
+	jobc, _ := q.Jobs(wctx)
+
+	first := <-jobc
+
+	// the job sent stays in the queue until the worker takes the next one
+	pending, err := q.Pending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), pending)
+
+	<-jobc
+
+	// the worker stops: the job it ran is queued again
+	cancel()
+
+	for range jobc {
+	}
+
+	pending, err = q.Pending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pending)
+
+	payload, err := q.take(ctx)
+	require.NoError(t, err)
+
+	job, err := decodeJob(payload)
+	require.NoError(t, err)
+	require.NotEqual(t, first.GetURL(), job.GetURL())
+
+	// a job whose lease expired is taken again
+	require.NoError(t, q.client.ZAdd(ctx, q.taken, redis.Z{Score: 0, Member: payload}).Err())
+
+	again, err := q.take(ctx)
+	require.NoError(t, err)
+	require.Equal(t, payload, again)
+
+	q.done(ctx, again)
+
+	pending, err = q.Pending(ctx)
+	require.NoError(t, err)
+	require.Zero(t, pending)
+}
+
+func TestQueueResults(t *testing.T) {
+	q := newTestQueue(t)
+	ctx := context.Background()
+
+	require.NoError(t, q.PushResult(ctx, &gmaps.Entry{Title: "a"}))
+	require.NoError(t, q.PushResult(ctx, &gmaps.Entry{Title: "b"}))
+
+	taken, err := q.PopResult(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", taken.Entry.Title)
+
+	// the coordinator crashed before it wrote "a"
+	restored, err := q.RestoreResults(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, restored)
+
+	for _, want := range []string{"a", "b"} {
+		taken, err = q.PopResult(ctx)
+		require.NoError(t, err)
+		require.Equal(t, want, taken.Entry.Title)
+		require.NoError(t, q.DoneResult(ctx, taken))
+	}
+
+	restored, err = q.RestoreResults(ctx)
+	require.NoError(t, err)
+	require.Zero(t, restored)
+
+	taken, err = q.PopResult(ctx)
+	require.NoError(t, err)
+	require.Nil(t, taken)
+}
@@ -0,0 +1,97 @@
+package distributed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func TestEncodeJobFallback(t *testing.T) {
+	croxy := gmaps.NewCroxyProxyJob("seed", "https://www.google.com/maps/search/cafe")
+	static := gmaps.NewSearchJob(&gmaps.MapSearchParams{Query: "cafe"},
+		gmaps.WithSearchJobFallback(croxy, []string{gmaps.FallbackBlocked}),
+	)
+	browser := gmaps.NewGmapJob("seed", "en", "cafe", 1, false, "", 0,
+		gmaps.WithFallback(static, gmaps.DefaultFallbackOn),
+	)
+
+	payload, err := encodeJob(browser)
+	require.NoError(t, err)
+
+	job, err := decodeJob(payload)
+	require.NoError(t, err)
+	require.Equal(t, browser.URL, job.GetURL())
+	require.Equal(t, gmaps.DefaultFallbackOn, gmaps.FallbackOn(job))
+
+	next := gmaps.FallbackJob(job)
+	require.IsType(t, &gmaps.SearchJob{}, next)
+	require.Equal(t, static.URLParams, next.GetURLParams())
+	require.Equal(t, []string{gmaps.FallbackBlocked}, gmaps.FallbackOn(next))
+
+	last := gmaps.FallbackJob(next)
+	require.IsType(t, &gmaps.CroxyProxyJob{}, last)
+	require.Equal(t, croxy.TargetURL, last.(*gmaps.CroxyProxyJob).TargetURL)
+	require.Nil(t, gmaps.FallbackJob(last))
+
+	// the steps pushed when a search falls back are queued on their own
+	payload, err = encodeJob(croxy)
+	require.NoError(t, err)
+
+	job, err = decodeJob(payload)
+	require.NoError(t, err)
+	require.Equal(t, croxy.GetID(), job.GetID())
+}
+
+// noThrottle is a throttle that never waits.
+type noThrottle struct{}
+
+func (noThrottle) Acquire(context.Context) error      { return nil }
+func (noThrottle) Release(time.Duration, error, bool) {}
+
+func TestEncodeJob(t *testing.T) {
+	place := gmaps.NewPlaceJob("search", "de", "https://www.google.com/maps/place/cafe", true, false,
+		gmaps.WithPlaceJobThrottle(noThrottle{}),
+	)
+
+	payload, err := encodeJob(place)
+	require.NoError(t, err)
+	require.NotNil(t, place.Throttle, "the job pushed is not changed")
+
+	job, err := decodeJob(payload)
+	require.NoError(t, err)
+	require.IsType(t, &gmaps.PlaceJob{}, job)
+
+	got := job.(*gmaps.PlaceJob)
+	require.Equal(t, place.GetID(), got.GetID())
+	require.Equal(t, place.GetURL(), got.GetURL())
+	require.Equal(t, place.ParentID, got.ParentID)
+	require.Equal(t, place.ExtractEmail, got.ExtractEmail)
+	// the worker that takes the job sets its own throttle
+	require.Nil(t, got.Throttle)
+
+	email := gmaps.NewEmailJob("place", &gmaps.Entry{Title: "Cafe", WebSite: "https://cafe.example"})
+
+	payload, err = encodeJob(email)
+	require.NoError(t, err)
+
+	job, err = decodeJob(payload)
+	require.NoError(t, err)
+	require.IsType(t, &gmaps.EmailExtractJob{}, job)
+	require.Equal(t, email.GetURL(), job.GetURL())
+	require.Equal(t, "Cafe", job.(*gmaps.EmailExtractJob).Entry.Title)
+}
+
+func TestDecodeJobInvalid(t *testing.T) {
+	_, err := decodeJob("unknown:data")
+	require.ErrorContains(t, err, "invalid payload type")
+
+	_, err = decodeJob(payloadPlace + ":not gob")
+	require.Error(t, err)
+
+	_, err = encodeJob(nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,234 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/scrapemateapp"
+
+	"github.com/gosom/google-maps-scraper/autotune"
+	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/tlmt"
+)
+
+type worker struct {
+	cfg   *runner.Config
+	queue *Queue
+	app   runner.App
+	tuner *autotune.Tuner
+}
+
+// NewWorker returns a runner that runs the jobs of the queue and pushes the
+// places it finds to the results of the queue. It stops when the queue has
+// been empty for -exit-on-inactivity.
+//
+// The workers share nothing but the queue and the redis deduper. The noise
+// filter, the delay or the tuner, the email host limiter and the deduper of
+// the worker are set on the jobs it takes.
+func NewWorker(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeWorker {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	cfg.RegisterHealthChecks()
+
+	ctx := context.Background()
+
+	dedup, err := deduper.NewRedis(ctx, cfg.DedupRedisURL, "gmaps:dedup:", cfg.DedupTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	noise := cfg.NewNoiseFilter()
+	tuner := cfg.NewTuner()
+	hostLimiter := cfg.NewHostLimiter()
+	retryer := cfg.NewRetryer()
+	contacts := cfg.NewContactFinder()
+	guesser := cfg.NewEmailGuesser()
+	verifier := cfg.NewEmailVerifier()
+	enrichers := cfg.NewEnrichers()
+	fingerprints := cfg.NewFingerprintRotator(cfg.LangCode)
+
+	sessions, err := cfg.NewSessionStore(cfg.ProxyKey(), fingerprints)
+	if err != nil {
+		return nil, err
+	}
+
+	var screenshots *gmaps.ScreenshotStore
+
+	if cfg.Screenshots {
+		screenshots, err = cfg.NewScreenshotStore(cfg.ScreenshotDir, "screenshots")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	debug, err := cfg.NewDebugRecorder(filepath.Join(cfg.DataFolder, "debug"))
+	if err != nil {
+		return nil, err
+	}
+
+	videos, err := cfg.NewVideoRecorder(cfg.VideoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockOpts []gmaps.BlockGuardOption
+
+	if solver := cfg.NewCaptchaSolver(); solver != nil {
+		blockOpts = append(blockOpts, gmaps.WithCaptchaSolver(solver, nil))
+	}
+
+	recorder := cfg.NewMetricsRecorder("worker")
+
+	blocks := cfg.NewBlockGuard(len(cfg.Proxies) > 0, func() {
+		log.Printf("warning: google served a captcha")
+		recorder.Block()
+	}, blockOpts...)
+
+	breakers := cfg.NewCircuitBreakers(func(host, state string) {
+		log.Printf("the circuit of %s is %s", host, state)
+		recorder.Circuit(host, state)
+	})
+
+	prepare := func(job scrapemate.IJob) {
+		jobs := []scrapemate.IJob{job}
+
+		runner.ApplyNoiseFilter(jobs, noise)
+		runner.ApplyHostLimiter(jobs, hostLimiter)
+		runner.ApplyRetryer(jobs, retryer)
+		runner.ApplyCircuitBreakers(jobs, breakers)
+		runner.ApplyContactFinder(jobs, contacts)
+		runner.ApplyEmailGuesser(jobs, guesser)
+		runner.ApplyEmailVerifier(jobs, verifier)
+		runner.ApplyEnrichers(jobs, enrichers)
+		runner.ApplyMetrics(jobs, recorder)
+		runner.ApplyBlockGuard(jobs, blocks)
+		runner.ApplyFingerprints(jobs, fingerprints)
+		runner.ApplySessions(jobs, sessions)
+		runner.ApplyScreenshots(jobs, screenshots)
+		runner.ApplyDebugRecorder(jobs, debug)
+		runner.ApplyVideoRecorder(jobs, videos)
+
+		if tuner != nil {
+			runner.ApplyTuner(jobs, tuner)
+		} else {
+			runner.ApplyDelay(jobs, cfg.Delay)
+		}
+
+		// the browser steps of a fallback chain share the deduper
+		for step := job; step != nil; step = gmaps.FallbackJob(step) {
+			if j, ok := step.(*gmaps.GmapJob); ok {
+				j.Deduper = dedup
+			}
+		}
+	}
+
+	queue, err := NewQueue(ctx, cfg.DistributedRedisURL, cfg.DistributedQueue, WithPrepare(prepare))
+	if err != nil {
+		return nil, err
+	}
+
+	ans := worker{
+		cfg:   cfg,
+		queue: queue,
+		tuner: tuner,
+	}
+
+	opts := []func(*scrapemateapp.Config) error{
+		scrapemateapp.WithConcurrency(cfg.Concurrency),
+		scrapemateapp.WithProvider(queue),
+		scrapemateapp.WithExitOnInactivity(cfg.ExitOnInactivityDuration),
+	}
+
+	if len(cfg.Proxies) > 0 {
+		opts = append(opts, scrapemateapp.WithProxies(cfg.Proxies))
+	}
+
+	if cfg.Debug {
+		opts = append(opts, scrapemateapp.WithJS(
+			scrapemateapp.Headfull(),
+			scrapemateapp.DisableImages(),
+		))
+	} else {
+		opts = append(opts, scrapemateapp.WithJS(scrapemateapp.DisableImages()))
+	}
+
+	if !cfg.DisablePageReuse {
+		opts = append(opts,
+			scrapemateapp.WithPageReuseLimit(2),
+			scrapemateapp.WithPageReuseLimit(200),
+		)
+	}
+
+	var writer scrapemate.ResultWriter = runner.NewMetricsWriter(&resultWriter{queue: queue}, recorder, "queue")
+
+	if cfg.OtelEndpoint != "" {
+		writer = runner.NewTracingWriter(writer)
+	}
+
+	matecfg, err := scrapemateapp.NewConfig(
+		[]scrapemate.ResultWriter{writer},
+		opts...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ans.app, err = cfg.NewApp(matecfg, cfg.BrowserEngine)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ans, nil
+}
+
+func (w *worker) Run(ctx context.Context) error {
+	_ = runner.Telemetry().Send(ctx, tlmt.NewEvent("distributed.worker.Run", nil))
+
+	log.Printf("worker pulling jobs from queue %s", w.cfg.DistributedQueue)
+
+	if w.tuner != nil {
+		go w.tuner.Run(ctx)
+	}
+
+	w.cfg.WatchBrowsers(ctx, nil)
+
+	return w.app.Start(ctx)
+}
+
+func (w *worker) Close(context.Context) error {
+	var err error
+
+	if w.app != nil {
+		err = w.app.Close()
+	}
+
+	return errors.Join(err, w.queue.Close())
+}
+
+// resultWriter pushes the places to the results of the queue.
+type resultWriter struct {
+	queue *Queue
+}
+
+func (r *resultWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		entry, ok := result.Data.(*gmaps.Entry)
+		if !ok {
+			return errors.New("invalid data type")
+		}
+
+		if err := r.queue.PushResult(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// NewEmailTypesWriter keeps the emails of the entries whose type is one of
+// types before passing them to w, see gmaps.Entry.FilterEmails.
+func NewEmailTypesWriter(w scrapemate.ResultWriter, types []string) scrapemate.ResultWriter {
+	return MapEntries(w, func(_ context.Context, e *gmaps.Entry) {
+		e.FilterEmails(types)
+	})
+}
@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// ReadEnrichInput reads the places whose websites are crawled for emails:
+// the results of a previous run, csv or json (files ending in .json or
+// .jsonl), or a list of websites, one per line. A csv file is recognized by
+// its website column. name is stdin to read from the standard input.
+func ReadEnrichInput(name string) ([]*gmaps.Entry, error) {
+	if ext := strings.ToLower(filepath.Ext(name)); ext == ".json" || ext == ".jsonl" {
+		return ReadEntries(name)
+	}
+
+	var in io.Reader = os.Stdin
+
+	if name != "stdin" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		in = f
+	}
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseEnrichInput(data)
+}
+
+// ParseEnrichInput parses the results csv or the list of websites of
+// ReadEnrichInput.
+func ParseEnrichInput(data []byte) ([]*gmaps.Entry, error) {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+
+	header, err := csv.NewReader(bytes.NewReader(first)).Read()
+	if err == nil && slices.Contains(header, "website") {
+		return readCSVEntries(bytes.NewReader(data))
+	}
+
+	var entries []*gmaps.Entry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.Contains(line, "://") {
+			line = "https://" + line
+		}
+
+		entries = append(entries, &gmaps.Entry{WebSite: line})
+	}
+
+	return entries, scanner.Err()
+}
+
+// EnrichEmails crawls the websites of entries for emails, without searching
+// or scraping the places, and passes them to w. The places without a website
+// are skipped.
+func (c *Config) EnrichEmails(ctx context.Context, entries []*gmaps.Entry, w scrapemate.ResultWriter) error {
+	exitMonitor := exiter.New()
+
+	jobs := CreateEmailJobs(entries, exitMonitor)
+
+	log.Printf("crawling the websites of %d of %d places for emails", len(jobs), len(entries))
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	recorder := c.NewMetricsRecorder("enrich")
+
+	ApplyHostLimiter(jobs, c.NewHostLimiter())
+	ApplyRetryer(jobs, c.NewRetryer())
+	ApplyCircuitBreakers(jobs, c.NewCircuitBreakers(func(host, state string) {
+		log.Printf("the circuit of %s is %s", host, state)
+		recorder.Circuit(host, state)
+	}))
+	ApplyContactFinder(jobs, c.NewContactFinder())
+	ApplyEmailGuesser(jobs, c.NewEmailGuesser())
+	ApplyEmailVerifier(jobs, c.NewEmailVerifier())
+	ApplyEnrichers(jobs, c.NewEnrichers())
+	ApplyMetrics(jobs, recorder)
+	ApplyFingerprints(jobs, c.NewFingerprintRotator(c.LangCode))
+
+	return c.runJobs(ctx, jobs, exitMonitor, w, 0)
+}
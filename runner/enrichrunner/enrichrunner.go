@@ -0,0 +1,116 @@
+package enrichrunner
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
+	"github.com/gosom/scrapemate/adapters/writers/jsonwriter"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+// enrichRunner crawls the websites of the places of a previous run, or of a
+// list of websites, for emails and writes the enriched places to -results,
+// without searching Google Maps again.
+type enrichRunner struct {
+	cfg     *runner.Config
+	entries []*gmaps.Entry
+	writer  scrapemate.ResultWriter
+	outfile *os.File
+}
+
+func New(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeEnrich {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	cfg.RegisterHealthChecks()
+
+	entries, err := runner.ReadEnrichInput(cfg.InputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no places or websites in %s", cfg.InputFile)
+	}
+
+	ans := &enrichRunner{
+		cfg:     cfg,
+		entries: entries,
+	}
+
+	var out io.Writer = os.Stdout
+
+	if cfg.ResultsFile != "stdout" {
+		ans.outfile, err = os.Create(cfg.ResultsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		out = ans.outfile
+	}
+
+	if cfg.JSON {
+		ans.writer = jsonwriter.NewJSONWriter(out)
+	} else {
+		ans.writer = csvwriter.NewCsvWriter(csv.NewWriter(out))
+	}
+
+	return ans, nil
+}
+
+func (r *enrichRunner) Run(ctx context.Context) error {
+	counter := &emailCounter{w: r.writer}
+
+	if err := r.cfg.EnrichEmails(ctx, r.entries, counter); err != nil {
+		return err
+	}
+
+	log.Printf("found emails for %d of %d places", counter.emails, counter.places)
+
+	return nil
+}
+
+func (r *enrichRunner) Close(context.Context) error {
+	if r.outfile != nil {
+		return r.outfile.Close()
+	}
+
+	return nil
+}
+
+// emailCounter counts the places written and the ones with emails.
+type emailCounter struct {
+	w scrapemate.ResultWriter
+
+	places int
+	emails int
+}
+
+func (c *emailCounter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := runner.Forward(ctx, c.w)
+
+	for result := range in {
+		if entry, ok := result.Data.(*gmaps.Entry); ok {
+			c.places++
+
+			if len(entry.Emails) > 0 {
+				c.emails++
+			}
+		}
+
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
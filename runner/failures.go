@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// FailuresPath returns the path of the failures of the results file
+// results: next to it, with its name and errors.csv, or errors.jsonl when
+// jsonl, e.g. results.errors.csv. It is empty for stdout.
+func FailuresPath(results string, jsonl bool) string {
+	if results == "" || results == "stdout" {
+		return ""
+	}
+
+	ext := ".errors.csv"
+	if jsonl {
+		ext = ".errors.jsonl"
+	}
+
+	return strings.TrimSuffix(results, filepath.Ext(results)) + ext
+}
+
+// WriteFailures writes failures to path, as json lines when the path ends in
+// .jsonl and as csv otherwise. Without failures the file of a previous run
+// is removed.
+func WriteFailures(path string, failures []gmaps.Failure) error {
+	if len(failures) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if filepath.Ext(path) == ".jsonl" {
+		err = writeFailuresJSON(f, failures)
+	} else {
+		err = writeFailuresCSV(f, failures)
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func writeFailuresJSON(f *os.File, failures []gmaps.Failure) error {
+	enc := json.NewEncoder(f)
+
+	for i := range failures {
+		if err := enc.Encode(&failures[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeFailuresCSV(f *os.File, failures []gmaps.Failure) error {
+	w := csv.NewWriter(f)
+
+	if err := w.Write(gmaps.FailureCsvHeaders()); err != nil {
+		return err
+	}
+
+	for i := range failures {
+		if err := w.Write(failures[i].CsvRow()); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
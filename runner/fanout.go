@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/gosom/scrapemate"
+	"golang.org/x/sync/errgroup"
+)
+
+var _ scrapemate.ResultWriter = (*fanOutWriter)(nil)
+
+type fanOutWriter struct {
+	writers []scrapemate.ResultWriter
+}
+
+// NewFanOutWriter sends every result to all the writers.
+// scrapemate shares a single results channel between its writers, so without
+// this each result would only reach one of them.
+func NewFanOutWriter(writers ...scrapemate.ResultWriter) scrapemate.ResultWriter {
+	return &fanOutWriter{writers: writers}
+}
+
+func (f *fanOutWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	chans := make([]chan scrapemate.Result, len(f.writers))
+
+	for i := range f.writers {
+		chans[i] = make(chan scrapemate.Result)
+
+		writer, ch := f.writers[i], chans[i]
+
+		g.Go(func() error {
+			return writer.Run(ctx, ch)
+		})
+	}
+
+	g.Go(func() error {
+		defer func() {
+			for i := range chans {
+				close(chans[i])
+			}
+		}()
+
+		for result := range in {
+			for i := range chans {
+				select {
+				case chans[i] <- result:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
@@ -5,13 +5,18 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/metrics"
 	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/tiling"
 	"github.com/gosom/google-maps-scraper/tlmt"
 	"github.com/gosom/scrapemate"
 	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
@@ -20,11 +25,15 @@ import (
 )
 
 type fileRunner struct {
-	cfg     *runner.Config
-	input   io.Reader
-	writers []scrapemate.ResultWriter
-	app     *scrapemateapp.ScrapemateApp
-	outfile *os.File
+	cfg         *runner.Config
+	input       io.Reader
+	writers     []scrapemate.ResultWriter
+	reviewStats *runner.ReviewStatsWriter
+	diff        *runner.DiffWriter
+	difffile    *os.File
+	app         runner.App
+	outfile     *os.File
+	recorder    *metrics.Recorder
 }
 
 func New(cfg *runner.Config) (runner.Runner, error) {
@@ -32,8 +41,11 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
 	}
 
+	cfg.RegisterHealthChecks()
+
 	ans := &fileRunner{
-		cfg: cfg,
+		cfg:      cfg,
+		recorder: cfg.NewMetricsRecorder("file"),
 	}
 
 	if err := ans.setInput(); err != nil {
@@ -73,26 +85,199 @@ func (r *fileRunner) Run(ctx context.Context) (err error) {
 	}()
 
 	dedup := deduper.New()
+
+	switch {
+	case r.cfg.DedupRedisURL != "":
+		dedup, err = deduper.NewRedis(ctx, r.cfg.DedupRedisURL, "gmaps:dedup:", r.cfg.DedupTTL)
+		if err != nil {
+			return err
+		}
+	case r.cfg.DedupBloomSize > 0:
+		dedup, err = deduper.NewBloom(
+			r.cfg.DedupBloomSize,
+			r.cfg.DedupBloomFPRate,
+			r.cfg.DedupBloomSnapshot,
+			r.cfg.DedupBloomSnapshotEvery,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if closer, ok := dedup.(io.Closer); ok {
+		defer func() {
+			if cerr := closer.Close(); cerr != nil {
+				log.Printf("failed to close deduper: %v", cerr)
+			}
+		}()
+	}
+
+	if r.cfg.DedupImport != "" {
+		if err = deduper.ImportFile(dedup, r.cfg.DedupImport); err != nil {
+			return err
+		}
+	}
+
+	stats := deduper.WithStats(dedup)
+	dedup = stats
+
+	defer func() {
+		s := stats.Stats()
+		log.Printf("deduper: %d keys, %d lookups, %.1f%% hit rate", s.Keys, s.Lookups, s.HitRate()*100)
+
+		if r.cfg.DedupExport != "" {
+			if eerr := deduper.ExportFile(dedup, r.cfg.DedupExport); eerr != nil {
+				log.Printf("failed to export deduper: %v", eerr)
+			}
+		}
+	}()
+
 	exitMonitor := exiter.New()
 
-	seedJobs, err = runner.CreateSeedJobs(
-		r.cfg.FastMode,
-		r.cfg.LangCode,
-		r.input,
-		r.cfg.MaxDepth,
-		r.cfg.Email,
-		r.cfg.GeoCoordinates,
-		r.cfg.Zoom,
-		r.cfg.Radius,
-		dedup,
-		exitMonitor,
-		r.cfg.ExtraReviews,
-		r.cfg.UseCroxy,
-	)
+	tiles, err := r.cfg.Tiles()
+	if err != nil {
+		return err
+	}
+
+	if tiles != nil {
+		log.Printf("searching %d %s tiles of level %d", len(tiles), r.cfg.TileSystem, r.cfg.TileLevel)
+
+		var density *tiling.Density
+
+		density, err = r.cfg.Density()
+		if err != nil {
+			return err
+		}
+
+		seedJobs, err = runner.CreateTiledSeedJobs(
+			r.cfg.FastMode,
+			r.cfg.LangCode,
+			r.cfg.Region,
+			r.input,
+			r.cfg.MaxDepth,
+			r.cfg.Email,
+			tiles,
+			density,
+			r.cfg.Exclusions(),
+			dedup,
+			exitMonitor,
+			r.cfg.ExtraReviews,
+			r.cfg.UseCroxy,
+			r.cfg.RequestExtras,
+		)
+	} else {
+		var (
+			coords string
+			zoom   int
+			radius float64
+		)
+
+		coords, zoom, radius, err = r.cfg.SearchArea(ctx)
+		if err != nil {
+			return err
+		}
+
+		if r.cfg.TileRadius > 0 {
+			seedJobs, err = r.circleSeedJobs(coords, radius, dedup, exitMonitor)
+		} else {
+			seedJobs, err = runner.CreateSeedJobs(
+				r.cfg.FastMode,
+				r.cfg.LangCode,
+				r.cfg.Region,
+				r.input,
+				r.cfg.MaxDepth,
+				r.cfg.Email,
+				coords,
+				zoom,
+				radius,
+				dedup,
+				exitMonitor,
+				r.cfg.ExtraReviews,
+				r.cfg.UseCroxy,
+				r.cfg.RequestExtras,
+				r.cfg.Fallback,
+			)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	noise := r.cfg.NewNoiseFilter()
+	runner.ApplyNoiseFilter(seedJobs, noise)
+	runner.ApplyHostLimiter(seedJobs, r.cfg.NewHostLimiter())
+	runner.ApplyRetryer(seedJobs, r.cfg.NewRetryer())
+	runner.ApplyCircuitBreakers(seedJobs, r.cfg.NewCircuitBreakers(func(host, state string) {
+		log.Printf("the circuit of %s is %s", host, state)
+		r.recorder.Circuit(host, state)
+	}))
+	runner.ApplyContactFinder(seedJobs, r.cfg.NewContactFinder())
+	runner.ApplyEmailGuesser(seedJobs, r.cfg.NewEmailGuesser())
+	runner.ApplyEmailVerifier(seedJobs, r.cfg.NewEmailVerifier())
+	runner.ApplyEnrichers(seedJobs, r.cfg.NewEnrichers())
+	runner.ApplyMetrics(seedJobs, r.recorder)
+
+	failures := gmaps.NewFailureLog()
+	runner.ApplyFailureLog(seedJobs, failures)
+
+	var blockOpts []gmaps.BlockGuardOption
+
+	if solver := r.cfg.NewCaptchaSolver(); solver != nil {
+		blockOpts = append(blockOpts, gmaps.WithCaptchaSolver(solver, nil))
+	}
+
+	blocks := r.cfg.NewBlockGuard(len(r.cfg.Proxies) > 0, func() {
+		log.Printf("warning: google served a captcha")
+		r.recorder.Block()
+	}, blockOpts...)
+	runner.ApplyBlockGuard(seedJobs, blocks)
+
+	fingerprints := r.cfg.NewFingerprintRotator(r.cfg.LangCode)
+	runner.ApplyFingerprints(seedJobs, fingerprints)
+
+	sessions, err := r.cfg.NewSessionStore(r.cfg.ProxyKey(), fingerprints)
+	if err != nil {
+		return err
+	}
+
+	runner.ApplySessions(seedJobs, sessions)
+
+	if r.cfg.Screenshots {
+		screenshots, err := r.cfg.NewScreenshotStore(r.cfg.ScreenshotDir, "screenshots")
+		if err != nil {
+			return err
+		}
+
+		runner.ApplyScreenshots(seedJobs, screenshots)
+	}
+
+	debug, err := r.cfg.NewDebugRecorder(filepath.Join(r.cfg.DataFolder, "debug"))
 	if err != nil {
 		return err
 	}
 
+	runner.ApplyDebugRecorder(seedJobs, debug)
+
+	videos, err := r.cfg.NewVideoRecorder(r.cfg.VideoDir)
+	if err != nil {
+		return err
+	}
+
+	runner.ApplyVideoRecorder(seedJobs, videos)
+
+	// the tuner starts at the delay and adapts it
+	tuner := r.cfg.NewTuner()
+	if tuner != nil {
+		runner.ApplyTuner(seedJobs, tuner)
+	} else {
+		runner.ApplyDelay(seedJobs, r.cfg.Delay)
+	}
+
+	for _, warning := range r.cfg.Warnings() {
+		log.Printf("warning: %s", warning)
+	}
+
 	exitMonitor.SetSeedCount(len(seedJobs))
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -102,12 +287,110 @@ func (r *fileRunner) Run(ctx context.Context) (err error) {
 
 	go exitMonitor.Run(ctx)
 
+	if tuner != nil {
+		go tuner.Run(ctx)
+	}
+
+	r.cfg.WatchBrowsers(ctx, nil)
+
 	err = r.app.Start(ctx, seedJobs...)
 
+	if entries, complete := r.reviewStats.Stats(); entries > 0 {
+		log.Printf("reviews complete for %d of %d places (%.1f%%)", complete, entries, float64(complete)/float64(entries)*100)
+	}
+
+	if n := blocks.Blocks(); n > 0 {
+		log.Printf("google served %d captchas, consider -proxies, -delay or -autotune", n)
+	}
+
+	if n := blocks.Solved(); n > 0 {
+		log.Printf("solved %d captchas for %.2f", n, float64(n)*r.cfg.CaptchaCost)
+	}
+
+	if noise != nil {
+		log.Printf("dropped %d places that are not businesses (%s)", noise.Total(), noise.Summary())
+	}
+
+	if r.diff != nil {
+		log.Printf("found %d changes against %s", r.diff.Changes(), r.cfg.DiffPrevious)
+	}
+
+	r.writeFailures(failures.Failures())
+
 	return err
 }
 
+// writeFailures writes the places that failed after all their retries to
+// -errors-file, next to the results by default.
+func (r *fileRunner) writeFailures(failures []gmaps.Failure) {
+	path := r.cfg.ErrorsFile
+	if path == "" {
+		path = runner.FailuresPath(r.cfg.ResultsFile, r.cfg.JSON)
+	}
+
+	if path == "" {
+		if len(failures) > 0 {
+			log.Printf("%d places failed after all their retries, set -errors-file to keep them", len(failures))
+		}
+
+		return
+	}
+
+	if err := runner.WriteFailures(path, failures); err != nil {
+		log.Printf("failed to write the failed places to %s: %v", path, err)
+
+		return
+	}
+
+	if len(failures) > 0 {
+		log.Printf("%d places failed after all their retries, see %s", len(failures), path)
+	}
+}
+
+// circleSeedJobs creates the searches of the hexagonal tiles that cover the
+// circle of radius meters around coords. The places outside the circle are dropped.
+func (r *fileRunner) circleSeedJobs(coords string, radius float64, dedup deduper.Deduper, exitMonitor exiter.Exiter) ([]scrapemate.IJob, error) {
+	tiles, err := runner.CoverCircle(coords, radius, r.cfg.TileRadius)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("searching %d hexagonal tiles of %.0fm covering %.0fm around %s", len(tiles), r.cfg.TileRadius, radius, coords)
+
+	jobs, err := runner.CreateTiledSeedJobs(
+		r.cfg.FastMode,
+		r.cfg.LangCode,
+		r.cfg.Region,
+		r.input,
+		r.cfg.MaxDepth,
+		r.cfg.Email,
+		tiles,
+		nil,
+		nil,
+		dedup,
+		exitMonitor,
+		r.cfg.ExtraReviews,
+		r.cfg.UseCroxy,
+		r.cfg.RequestExtras,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := runner.ApplyCircle(jobs, coords, radius); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
 func (r *fileRunner) Close(context.Context) error {
+	if r.difffile != nil {
+		if err := r.difffile.Close(); err != nil {
+			log.Printf("failed to close diff report: %v", err)
+		}
+	}
+
 	if r.app != nil {
 		return r.app.Close()
 	}
@@ -150,12 +433,14 @@ func (r *fileRunner) setWriters() error {
 
 		dir, pluginName := parts[0], parts[1]
 
-		customWriter, err := runner.LoadCustomWriter(dir, pluginName)
+		customWriter, err := runner.LoadCustomWriter(dir, pluginName, r.cfg.CustomWriterConfig)
 		if err != nil {
 			return err
 		}
 
-		r.writers = append(r.writers, customWriter)
+		writer := runner.NewMetricsWriter(customWriter, r.recorder, pluginName)
+
+		r.writers = append(r.writers, runner.NewSpillWriter(writer, r.cfg.WriterBuffer, "", r.recorder, pluginName))
 	} else {
 		var resultsWriter io.Writer
 
@@ -173,13 +458,90 @@ func (r *fileRunner) setWriters() error {
 			resultsWriter = r.outfile
 		}
 
-		csvWriter := csvwriter.NewCsvWriter(csv.NewWriter(resultsWriter))
+		switch {
+		case r.cfg.JSON:
+			r.writers = append(r.writers, runner.NewMetricsWriter(jsonwriter.NewJSONWriter(resultsWriter), r.recorder, "json"))
+		case !r.cfg.CSVLayout.IsDefault():
+			r.writers = append(r.writers, runner.NewMetricsWriter(runner.NewCSVWriter(resultsWriter, &r.cfg.CSVLayout), r.recorder, "csv"))
+		case r.cfg.FlattenAbout:
+			r.writers = append(r.writers, runner.NewMetricsWriter(runner.NewFlatAboutCSVWriter(csv.NewWriter(resultsWriter)), r.recorder, "csv"))
+		default:
+			r.writers = append(r.writers, runner.NewMetricsWriter(csvwriter.NewCsvWriter(csv.NewWriter(resultsWriter)), r.recorder, "csv"))
+		}
+	}
 
-		if r.cfg.JSON {
-			r.writers = append(r.writers, jsonwriter.NewJSONWriter(resultsWriter))
-		} else {
-			r.writers = append(r.writers, csvWriter)
+	if r.cfg.CRM != "" {
+		crmWriter, err := runner.NewCRMWriter(r.cfg)
+		if err != nil {
+			return err
 		}
+
+		writer := runner.NewMetricsWriter(crmWriter, r.recorder, "crm")
+
+		r.writers = append(r.writers, runner.NewSpillWriter(writer, r.cfg.WriterBuffer, "", r.recorder, "crm"))
+	}
+
+	if r.cfg.SortBy != "" {
+		for i := range r.writers {
+			r.writers[i] = runner.NewSortedWriter(r.writers[i], r.cfg.SortBy)
+		}
+	}
+
+	if r.cfg.StableOutput {
+		for i := range r.writers {
+			r.writers[i] = runner.NewStableWriter(r.writers[i], "", nil)
+		}
+	}
+
+	if r.cfg.FuzzyDedup {
+		for i := range r.writers {
+			r.writers[i] = runner.NewFuzzyDedupWriter(r.writers[i])
+		}
+	}
+
+	if len(r.writers) > 1 {
+		r.writers = []scrapemate.ResultWriter{runner.NewFanOutWriter(r.writers...)}
+	}
+
+	r.reviewStats = runner.NewReviewStatsWriter(r.writers[0])
+	r.writers[0] = r.reviewStats
+
+	if r.cfg.DiffPrevious != "" {
+		previous, err := runner.LoadSnapshot(r.cfg.DiffPrevious)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Create(r.cfg.DiffReport)
+		if err != nil {
+			return err
+		}
+
+		r.difffile = f
+
+		r.diff = runner.NewDiffWriter(r.writers[0], previous, f)
+		r.writers[0] = r.diff
+	}
+
+	if t := r.cfg.NewTranslator(); t != nil {
+		r.writers[0] = runner.NewTranslateWriter(r.writers[0], t, r.cfg.TranslateTo)
+	}
+
+	if r.cfg.ReviewInsights {
+		r.writers[0] = runner.NewReviewInsightsWriter(r.writers[0])
+	}
+
+	if len(r.cfg.EmailTypes) > 0 {
+		r.writers[0] = runner.NewEmailTypesWriter(r.writers[0], r.cfg.EmailTypes)
+	}
+
+	// normalized first so the diff and the review stats see the written values
+	if r.cfg.Normalize {
+		r.writers[0] = runner.NewNormalizeWriter(r.writers[0], r.cfg.StripEmojis)
+	}
+
+	if r.cfg.OtelEndpoint != "" {
+		r.writers[0] = runner.NewTracingWriter(r.writers[0])
 	}
 
 	return nil
@@ -227,7 +589,7 @@ func (r *fileRunner) setApp() error {
 		return err
 	}
 
-	r.app, err = scrapemateapp.NewScrapeMateApp(matecfg)
+	r.app, err = r.cfg.NewApp(matecfg, r.cfg.BrowserEngine)
 	if err != nil {
 		return err
 	}
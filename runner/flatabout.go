@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"context"
+	"encoding/csv"
+	"slices"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+var _ scrapemate.ResultWriter = (*flatAboutCSVWriter)(nil)
+
+type flatAboutCSVWriter struct {
+	w *csv.Writer
+}
+
+// NewFlatAboutCSVWriter writes the entries as csv with one extra boolean
+// column per about option found in any of them. Since the columns are only
+// known once all the places are scraped, the entries are buffered and
+// written when the input is closed. A place that does not list an option
+// has an empty value in its column.
+func NewFlatAboutCSVWriter(w *csv.Writer) scrapemate.ResultWriter {
+	return &flatAboutCSVWriter{w: w}
+}
+
+func (f *flatAboutCSVWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	var entries []*gmaps.Entry
+
+	for result := range in {
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			entries = append(entries, data)
+		case []*gmaps.Entry:
+			entries = append(entries, data...)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	flags := make([]map[string]bool, len(entries))
+	seen := map[string]bool{}
+
+	var keys []string
+
+	for i := range entries {
+		flags[i] = entries[i].AboutFlags()
+
+		for k := range flags[i] {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	slices.Sort(keys)
+
+	if err := f.w.Write(append(entries[0].CsvHeaders(), keys...)); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		row := entries[i].CsvRow()
+
+		for _, k := range keys {
+			v, ok := flags[i][k]
+
+			switch {
+			case !ok:
+				row = append(row, "")
+			case v:
+				row = append(row, "true")
+			default:
+				row = append(row, "false")
+			}
+		}
+
+		if err := f.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	f.w.Flush()
+
+	return f.w.Error()
+}
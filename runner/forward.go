@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// Forwarder passes results to a writer that runs on its own, for the writers
+// that wrap another one.
+type Forwarder struct {
+	out     chan scrapemate.Result
+	errc    chan error
+	stopped bool
+	err     error
+}
+
+// Forward runs w on the results sent to the forwarder until it is closed.
+func Forward(ctx context.Context, w scrapemate.ResultWriter) *Forwarder {
+	f := &Forwarder{
+		out:  make(chan scrapemate.Result),
+		errc: make(chan error, 1),
+	}
+
+	go func() {
+		f.errc <- w.Run(ctx, f.out)
+	}()
+
+	return f
+}
+
+// Send passes result to the writer. It returns false when the writer stopped
+// before taking it, Close returns its error.
+func (f *Forwarder) Send(result scrapemate.Result) bool {
+	if f.stopped {
+		return false
+	}
+
+	select {
+	case f.out <- result:
+		return true
+	case err := <-f.errc:
+		f.stopped, f.err = true, err
+
+		return false
+	}
+}
+
+// Close tells the writer there are no more results and returns its error
+// once it stopped.
+func (f *Forwarder) Close() error {
+	if !f.stopped {
+		close(f.out)
+
+		f.stopped, f.err = true, <-f.errc
+	}
+
+	return f.err
+}
+
+// Entries returns the entries of the data of a result, none when it is not
+// an entry or a list of entries.
+func Entries(data any) []*gmaps.Entry {
+	switch data := data.(type) {
+	case *gmaps.Entry:
+		return []*gmaps.Entry{data}
+	case []*gmaps.Entry:
+		return data
+	default:
+		return nil
+	}
+}
+
+var _ scrapemate.ResultWriter = (*mapWriter)(nil)
+
+type mapWriter struct {
+	w  scrapemate.ResultWriter
+	fn func(context.Context, *gmaps.Entry)
+}
+
+// MapEntries calls fn with each entry of the results before passing them to w.
+func MapEntries(w scrapemate.ResultWriter, fn func(context.Context, *gmaps.Entry)) scrapemate.ResultWriter {
+	return &mapWriter{w: w, fn: fn}
+}
+
+func (m *mapWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := Forward(ctx, m.w)
+
+	for result := range in {
+		for _, e := range Entries(result.Data) {
+			m.fn(ctx, e)
+		}
+
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
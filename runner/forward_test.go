@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// failWriter takes n results and stops with err.
+type failWriter struct {
+	n   int
+	err error
+}
+
+func (f *failWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	for range f.n {
+		<-in
+	}
+
+	return f.err
+}
+
+func TestMapEntries(t *testing.T) {
+	c := &collectWriter{}
+
+	w := MapEntries(c, func(_ context.Context, e *gmaps.Entry) {
+		e.Title += "!"
+	})
+
+	writeAll(t, w,
+		scrapemate.Result{Data: &gmaps.Entry{Title: "a"}},
+		scrapemate.Result{Data: "not an entry"},
+		scrapemate.Result{Data: []*gmaps.Entry{{Title: "b"}, {Title: "c"}}},
+	)
+
+	require.Len(t, c.results, 3)
+	require.Equal(t, "a!", c.results[0].Data.(*gmaps.Entry).Title)
+	require.Equal(t, "not an entry", c.results[1].Data)
+	require.Equal(t, []string{"b!", "c!"}, []string{
+		c.results[2].Data.([]*gmaps.Entry)[0].Title,
+		c.results[2].Data.([]*gmaps.Entry)[1].Title,
+	})
+}
+
+func TestForwarder(t *testing.T) {
+	fw := Forward(context.Background(), &failWriter{n: 1, err: errors.New("disk full")})
+
+	require.True(t, fw.Send(scrapemate.Result{Data: "a"}))
+	require.False(t, fw.Send(scrapemate.Result{Data: "b"}))
+	require.False(t, fw.Send(scrapemate.Result{Data: "c"}))
+	require.EqualError(t, fw.Close(), "disk full")
+	require.EqualError(t, fw.Close(), "disk full")
+
+	// the writer stops when there are no more results
+	c := &collectWriter{}
+
+	fw = Forward(context.Background(), c)
+
+	require.True(t, fw.Send(scrapemate.Result{Data: "a"}))
+	require.NoError(t, fw.Close())
+	require.Len(t, c.results, 1)
+
+	require.Nil(t, Entries("not an entry"))
+	require.Len(t, Entries(&gmaps.Entry{}), 1)
+}
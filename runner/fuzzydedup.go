@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const fuzzyDedupGeohashPrecision = 7
+
+var _ scrapemate.ResultWriter = (*fuzzyDedupWriter)(nil)
+
+type fuzzyDedupWriter struct {
+	w scrapemate.ResultWriter
+}
+
+// NewFuzzyDedupWriter buffers all the results and merges the entries that have
+// the same normalized title and geohash-7 cell, e.g. the same business found
+// via different links. The record with the most data is kept.
+// Entries without coordinates and results that are not entries are forwarded as they are.
+func NewFuzzyDedupWriter(w scrapemate.ResultWriter) scrapemate.ResultWriter {
+	return &fuzzyDedupWriter{w: w}
+}
+
+func (f *fuzzyDedupWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	var (
+		results []scrapemate.Result
+		index   = make(map[string]int)
+	)
+
+	add := func(result scrapemate.Result, entry *gmaps.Entry) {
+		key, ok := fuzzyDedupKey(entry)
+		if !ok {
+			results = append(results, result)
+
+			return
+		}
+
+		i, seen := index[key]
+		if !seen {
+			index[key] = len(results)
+			results = append(results, result)
+
+			return
+		}
+
+		//nolint:errcheck // only entries are indexed
+		if entryRichness(entry) > entryRichness(results[i].Data.(*gmaps.Entry)) {
+			results[i] = result
+		}
+	}
+
+	for result := range in {
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			add(result, data)
+		case []*gmaps.Entry:
+			for i := range data {
+				add(scrapemate.Result{Job: result.Job, Data: data[i]}, data[i])
+			}
+		default:
+			results = append(results, result)
+		}
+	}
+
+	fw := Forward(ctx, f.w)
+
+	for _, result := range results {
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
+
+func fuzzyDedupKey(e *gmaps.Entry) (string, bool) {
+	title := normalizeTitle(e.Title)
+	if title == "" || (e.Latitude == 0 && e.Longtitude == 0) {
+		return "", false
+	}
+
+	return title + "|" + gmaps.Geohash(e.Latitude, e.Longtitude, fuzzyDedupGeohashPrecision), true
+}
+
+// normalizeTitle lowercases the title and keeps only letters and digits
+// separated by single spaces.
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// entryRichness counts the populated fields of the entry.
+func entryRichness(e *gmaps.Entry) int {
+	ans := 0
+
+	for _, v := range []string{
+		e.Cid, e.Category, e.Address, e.WebSite, e.Phone, e.PlusCode, e.Status,
+		e.Description, e.Thumbnail, e.Timezone, e.PriceRange, e.DataID,
+	} {
+		if v != "" {
+			ans++
+		}
+	}
+
+	for _, n := range []int{
+		len(e.Categories), len(e.OpenHours), len(e.PopularTimes), len(e.ReviewsPerRating),
+		len(e.Images), len(e.Reservations), len(e.OrderOnline), len(e.About),
+		len(e.UserReviews), len(e.UserReviewsExtended), len(e.Emails), e.ReviewCount,
+	} {
+		if n > 0 {
+			ans++
+		}
+	}
+
+	return ans
+}
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+	"github.com/gosom/google-maps-scraper/geocoder/locationiq"
+	"github.com/gosom/google-maps-scraper/geocoder/mapbox"
+	"github.com/gosom/google-maps-scraper/geocoder/nominatim"
+	"github.com/gosom/google-maps-scraper/geocoder/photon"
+)
+
+const (
+	defaultZoom   = 15
+	defaultRadius = 10000
+)
+
+// GeocodePlace resolves the center of a fast mode search from a place name.
+// It returns the center in the lat,lon format of -geo. When zoom or radius
+// are not positive they are computed so the search covers the place.
+func GeocodePlace(ctx context.Context, g geocoder.Geocoder, place string, zoom int, radius float64) (string, int, float64, error) {
+	res, err := g.Geocode(ctx, place, "")
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("failed to geocode %q: %w", place, err)
+	}
+
+	if zoom <= 0 {
+		zoom = res.Zoom(defaultZoom)
+	}
+
+	if radius <= 0 {
+		radius = res.Radius(defaultRadius)
+	}
+
+	return fmt.Sprintf("%f,%f", res.Lat, res.Lon), zoom, radius, nil
+}
+
+// SearchArea returns the center, zoom and radius of the search, geocoding
+// GeoPlace when no coordinates are set.
+func (c *Config) SearchArea(ctx context.Context) (string, int, float64, error) {
+	if c.GeoCoordinates != "" || c.GeoPlace == "" {
+		return c.GeoCoordinates, c.Zoom, c.Radius, nil
+	}
+
+	coords, zoom, radius, err := GeocodePlace(ctx, c.Geocoder, c.GeoPlace, c.Zoom, c.Radius)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	log.Printf("geocoded %q to %s (zoom %d, radius %.0fm)", c.GeoPlace, coords, zoom, radius)
+
+	return coords, zoom, radius, nil
+}
+
+// GeocoderProviders returns the geocoding providers of the config in order.
+func (c *Config) GeocoderProviders() []string {
+	return geocoder.ParseProviders(c.Geocoders)
+}
+
+// NewGeocoder returns a geocoder that asks the providers in order, falling
+// back to the next one when a provider fails or is rate limited. Nominatim is
+// used when no provider is given. The API keys are taken from keys by
+// provider name or from the environment, see geocoder.Key. When cache is not
+// nil the places found by each provider are cached.
+func NewGeocoder(providers []string, keys map[string]string, nominatimURL string, cache geocoder.Cache) (geocoder.Geocoder, error) {
+	if len(providers) == 0 {
+		providers = []string{geocoder.ProviderNominatim}
+	}
+
+	if err := geocoder.ValidateProviders(providers, keys); err != nil {
+		return nil, err
+	}
+
+	gs := make([]geocoder.Geocoder, len(providers))
+
+	for i, p := range providers {
+		var g geocoder.Geocoder
+
+		switch p {
+		case geocoder.ProviderNominatim:
+			g = nominatim.New(nominatimURL)
+		case geocoder.ProviderPhoton:
+			g = photon.New("")
+		case geocoder.ProviderMapbox:
+			g = mapbox.New(geocoder.Key(p, keys))
+		case geocoder.ProviderLocationIQ:
+			g = locationiq.New(geocoder.Key(p, keys))
+		}
+
+		if cache != nil {
+			g = geocoder.NewCached(g, p, cache)
+		}
+
+		gs[i] = g
+	}
+
+	return geocoder.NewFallback(gs...), nil
+}
@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/alerting"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/health"
+)
+
+const megabyte = 1 << 20
+
+// RegisterHealthChecks registers the memory and disk space checks of the
+// config with the checker served at /api/health. The disk is the one of
+// -data-folder for the web runner, and of the results file, or the working
+// directory, for the others.
+func (c *Config) RegisterHealthChecks() {
+	dir := "."
+
+	switch {
+	case c.RunMode == RunModeWeb:
+		dir = c.DataFolder
+	case c.ResultsFile != "" && c.ResultsFile != "stdout":
+		dir = filepath.Dir(c.ResultsFile)
+	}
+
+	health.Register(
+		health.NewMemoryCheck(c.HealthMaxMemory),
+		health.NewDiskSpaceCheck(dir, c.HealthMinFreeDisk*megabyte),
+	)
+}
+
+// WatchBrowsers checks the browsers of the jobs every -browser-check-interval
+// until ctx is done, with the result at /api/health. The browsers that failed
+// -browser-restart-after checks in a row are restarted, which is logged and
+// sent to notify as a critical alert, to the channels of -alerts-config when
+// notify is nil. It does nothing in fast mode, which does not use a browser.
+func (c *Config) WatchBrowsers(ctx context.Context, notify func(context.Context, *alerting.Alert) error) {
+	if c.BrowserCheckInterval <= 0 || c.FastMode {
+		return
+	}
+
+	if notify == nil {
+		router, err := c.NewAlertRouter()
+		if err != nil {
+			log.Printf("the browser restarts are not alerted: %v", err)
+		}
+
+		notify = router.Notify
+	}
+
+	check := gmaps.NewBrowserCheck(c.BrowserRestartAfter, func(restarted int, err error) {
+		log.Printf("restarted %d browsers that failed %d checks in a row: %v", restarted, c.BrowserRestartAfter, err)
+
+		alert := alerting.Alert{
+			Severity: alerting.SeverityCritical,
+			Title:    "browsers restarted",
+			Text: fmt.Sprintf("%d browsers could not open a page or run javascript in %d checks in a row and were restarted: %v",
+				restarted, c.BrowserRestartAfter, err),
+		}
+
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+
+		if err := notify(notifyCtx, &alert); err != nil {
+			log.Printf("failed to send the alert of the browser restart: %v", err)
+		}
+	})
+
+	health.Register(check)
+
+	go check.Watch(ctx, c.BrowserCheckInterval)
+}
+
+// NewAlertRouter returns the router of the channels of -alerts-config, for
+// the runners without alert rules, nil when it is not set.
+func (c *Config) NewAlertRouter() (*alerting.Router, error) {
+	if c.AlertsConfig == "" {
+		return nil, nil
+	}
+
+	ac, err := alerting.LoadConfig(c.AlertsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return ac.NewRouter()
+}
@@ -0,0 +1,17 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// NewReviewInsightsWriter computes the review insights of the entries before
+// passing them to w, see gmaps.Entry.ComputeReviewInsights.
+func NewReviewInsightsWriter(w scrapemate.ResultWriter) scrapemate.ResultWriter {
+	return MapEntries(w, func(_ context.Context, e *gmaps.Entry) {
+		e.ComputeReviewInsights()
+	})
+}
@@ -5,20 +5,37 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"plugin"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/alerting"
+	"github.com/gosom/google-maps-scraper/captcha"
+	"github.com/gosom/google-maps-scraper/captcha/capsolver"
+	"github.com/gosom/google-maps-scraper/captcha/twocaptcha"
 	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/emailverify"
 	"github.com/gosom/google-maps-scraper/exiter"
 	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/translate"
+	"github.com/gosom/google-maps-scraper/translate/deepl"
+	"github.com/gosom/google-maps-scraper/translate/google"
 	"github.com/gosom/scrapemate"
 )
 
+// CreateSeedJobs creates the searches of the queries of r, one per line. A
+// query can end with #!# and its id. Each search is loaded with the steps of
+// fallback in turn, or, when it has none, in fast mode, through CroxyProxy
+// or in the browser.
 func CreateSeedJobs(
 	fastmode bool,
 	langCode string,
+	region string,
 	r io.Reader,
 	maxDepth int,
 	email bool,
@@ -29,35 +46,34 @@ func CreateSeedJobs(
 	exitMonitor exiter.Exiter,
 	extraReviews bool,
 	useCroxy bool,
+	extras *gmaps.RequestExtras,
+	fallback gmaps.FallbackChain,
 ) (jobs []scrapemate.IJob, err error) {
-	var lat, lon float64
+	steps := fallback.Steps
 
-	if fastmode {
-		if geoCoordinates == "" {
-			return nil, fmt.Errorf("geo coordinates are required in fast mode")
-		}
+	if len(steps) == 0 {
+		strategy := gmaps.StrategyBrowser
 
-		parts := strings.Split(geoCoordinates, ",")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid geo coordinates: %s", geoCoordinates)
+		switch {
+		case useCroxy:
+			strategy = gmaps.StrategyCroxy
+		case fastmode:
+			strategy = gmaps.StrategyStatic
 		}
 
-		lat, err = strconv.ParseFloat(parts[0], 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid latitude: %w", err)
-		}
+		steps = []gmaps.FallbackStep{{Strategy: strategy, Retries: -1}}
+	}
 
-		lon, err = strconv.ParseFloat(parts[1], 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid longitude: %w", err)
-		}
+	var lat, lon float64
 
-		if lat < -90 || lat > 90 {
-			return nil, fmt.Errorf("invalid latitude: %f", lat)
+	if fastmode || fallback.Has(gmaps.StrategyStatic) {
+		if geoCoordinates == "" {
+			return nil, fmt.Errorf("geo coordinates are required in fast mode")
 		}
 
-		if lon < -180 || lon > 180 {
-			return nil, fmt.Errorf("invalid longitude: %f", lon)
+		lat, lon, err = ParseGeoCoordinates(geoCoordinates)
+		if err != nil {
+			return nil, err
 		}
 
 		if zoom < 1 || zoom > 21 {
@@ -69,31 +85,23 @@ func CreateSeedJobs(
 		}
 	}
 
-	scanner := bufio.NewScanner(r)
-
-	for scanner.Scan() {
-		query := strings.TrimSpace(scanner.Text())
-		if query == "" {
-			continue
-		}
-
-		var id string
-
-		if before, after, ok := strings.Cut(query, "#!#"); ok {
-			query = strings.TrimSpace(before)
-			id = strings.TrimSpace(after)
-		}
-
-		var job scrapemate.IJob
-
-		if useCroxy {
+	seedJob := func(strategy, id, query string) scrapemate.IJob {
+		switch strategy {
+		case gmaps.StrategyCroxy:
 			// Create CroxyProxy job for the target URL
-			targetURL := fmt.Sprintf("https://www.google.com/maps/search/%s", query)
+			host := gmaps.GoogleHost(region)
+
+			targetURL := fmt.Sprintf("https://%s/maps/search/%s", host, query)
 			if geoCoordinates != "" && zoom > 0 {
-				targetURL = fmt.Sprintf("https://www.google.com/maps/search/%s/@%s,%dz", query, strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
+				targetURL = fmt.Sprintf("https://%s/maps/search/%s/@%s,%dz", host, query, strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
 			}
-			job = gmaps.NewCroxyProxyJob(id, targetURL)
-		} else if !fastmode {
+			var copts []gmaps.CroxyProxyJobOptions
+			if exitMonitor != nil {
+				copts = append(copts, gmaps.WithCroxyExitMonitor(exitMonitor))
+			}
+
+			return gmaps.NewCroxyProxyJob(id, targetURL, copts...)
+		case gmaps.StrategyBrowser:
 			opts := []gmaps.GmapJobOptions{}
 
 			if dedup != nil {
@@ -108,8 +116,21 @@ func CreateSeedJobs(
 				opts = append(opts, gmaps.WithExtraReviews())
 			}
 
-			job = gmaps.NewGmapJob(id, langCode, query, maxDepth, email, geoCoordinates, zoom, opts...)
-		} else {
+			if region != "" {
+				opts = append(opts, gmaps.WithRegion(region))
+			}
+
+			if !extras.IsZero() {
+				opts = append(opts, gmaps.WithRequestExtras(extras))
+			}
+
+			// the web UI defaults to 0,0 when no center is given
+			if clat, clon, err := ParseGeoCoordinates(geoCoordinates); err == nil && (clat != 0 || clon != 0) {
+				opts = append(opts, gmaps.WithCenter(clat, clon))
+			}
+
+			return gmaps.NewGmapJob(id, langCode, query, maxDepth, email, geoCoordinates, zoom, opts...)
+		default:
 			jparams := gmaps.MapSearchParams{
 				Location: gmaps.MapLocation{
 					Lat:     lat,
@@ -121,6 +142,7 @@ func CreateSeedJobs(
 				ViewportW: 1920,
 				ViewportH: 450,
 				Hl:        langCode,
+				Gl:        strings.ToLower(region),
 			}
 
 			opts := []gmaps.SearchJobOptions{}
@@ -129,49 +151,753 @@ func CreateSeedJobs(
 				opts = append(opts, gmaps.WithSearchJobExitMonitor(exitMonitor))
 			}
 
-			job = gmaps.NewSearchJob(&jparams, opts...)
+			if !extras.IsZero() {
+				opts = append(opts, gmaps.WithSearchJobRequestExtras(extras))
+			}
+
+			return gmaps.NewSearchJob(&jparams, opts...)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		query := strings.TrimSpace(scanner.Text())
+		if query == "" {
+			continue
 		}
 
-		jobs = append(jobs, job)
+		var id string
+
+		if before, after, ok := strings.Cut(query, "#!#"); ok {
+			query = strings.TrimSpace(before)
+			id = strings.TrimSpace(after)
+		}
+
+		if len(steps) > 1 && id == "" {
+			// the steps share the id, the input_id of the places
+			id = uuid.New().String()
+		}
+
+		var next scrapemate.IJob
+
+		for i := len(steps) - 1; i >= 0; i-- {
+			job := seedJob(steps[i].Strategy, id, query)
+
+			linkFallback(job, steps[i].Retries, next, fallback.On)
+
+			if len(steps) > 1 {
+				// a step is the child of the previous one, which is done once it falls back
+				parentID := id
+				if i == 0 {
+					parentID = ""
+				}
+
+				setSeedID(job, id, parentID)
+			}
+
+			next = job
+		}
+
+		jobs = append(jobs, next)
 	}
 
 	return jobs, scanner.Err()
 }
 
-func LoadCustomWriter(pluginDir, pluginName string) (scrapemate.ResultWriter, error) {
-	files, err := os.ReadDir(pluginDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+// linkFallback makes job load the search with next when it fails on one of
+// the conditions on, after retries retries unless they are negative.
+func linkFallback(job scrapemate.IJob, retries int, next scrapemate.IJob, on []string) {
+	switch j := job.(type) {
+	case *gmaps.GmapJob:
+		if retries >= 0 {
+			j.MaxRetries = retries
+		}
+
+		gmaps.WithFallback(next, on)(j)
+	case *gmaps.SearchJob:
+		if retries >= 0 {
+			j.MaxRetries = retries
+		}
+
+		gmaps.WithSearchJobFallback(next, on)(j)
+	case *gmaps.CroxyProxyJob:
+		if retries >= 0 {
+			j.MaxRetries = retries
+		}
+
+		gmaps.WithCroxyFallback(next, on)(j)
 	}
+}
 
-	for _, file := range files {
-		if file.IsDir() {
+// setSeedID gives the search job, a step of a fallback chain, the id of the
+// chain and the parent id of its previous step.
+func setSeedID(job scrapemate.IJob, id, parentID string) {
+	switch j := job.(type) {
+	case *gmaps.GmapJob:
+		j.ID, j.ParentID = id, parentID
+	case *gmaps.SearchJob:
+		j.ID, j.ParentID = id, parentID
+	case *gmaps.CroxyProxyJob:
+		j.ID, j.ParentID = id, parentID
+	}
+}
+
+// withFallbacks returns the jobs and the next steps of their fallback chains.
+func withFallbacks(jobs []scrapemate.IJob) []scrapemate.IJob {
+	var ans []scrapemate.IJob
+
+	for _, job := range jobs {
+		for next := gmaps.FallbackJob(job); next != nil; next = gmaps.FallbackJob(next) {
+			if ans == nil {
+				ans = slices.Clone(jobs)
+			}
+
+			ans = append(ans, next)
+		}
+	}
+
+	if ans == nil {
+		return jobs
+	}
+
+	return ans
+}
+
+// CreateEmailJobs creates the jobs that crawl the websites of the places for
+// emails, without scraping the places again. The places without a website
+// and the duplicates are skipped.
+func CreateEmailJobs(entries []*gmaps.Entry, exitMonitor exiter.Exiter) []scrapemate.IJob {
+	var opts []gmaps.EmailExtractJobOptions
+
+	if exitMonitor != nil {
+		opts = append(opts, gmaps.WithEmailJobExitMonitor(exitMonitor))
+	}
+
+	seen := make(map[string]bool, len(entries))
+
+	var jobs []scrapemate.IJob
+
+	for _, e := range entries {
+		if seen[PlaceKey(e)] || !e.IsWebsiteValidForEmail() {
 			continue
 		}
 
-		if filepath.Ext(file.Name()) != ".so" && filepath.Ext(file.Name()) != ".dll" {
+		seen[PlaceKey(e)] = true
+
+		jobs = append(jobs, gmaps.NewEmailJob("", e, opts...))
+	}
+
+	return jobs
+}
+
+// CreatePlaceJobs creates the jobs that scrape the places again from their
+// links. The duplicates are skipped.
+func CreatePlaceJobs(
+	entries []*gmaps.Entry,
+	langCode string,
+	region string,
+	email bool,
+	extraReviews bool,
+	exitMonitor exiter.Exiter,
+	extras *gmaps.RequestExtras,
+) []scrapemate.IJob {
+	var opts []gmaps.PlaceJobOptions
+
+	if exitMonitor != nil {
+		opts = append(opts, gmaps.WithPlaceJobExitMonitor(exitMonitor))
+	}
+
+	if region != "" {
+		opts = append(opts, gmaps.WithPlaceJobRegion(region))
+	}
+
+	if !extras.IsZero() {
+		opts = append(opts, gmaps.WithPlaceJobRequestExtras(extras))
+	}
+
+	seen := make(map[string]bool, len(entries))
+
+	var jobs []scrapemate.IJob
+
+	for _, e := range entries {
+		if seen[PlaceKey(e)] || e.Link == "" {
 			continue
 		}
 
-		pluginPath := filepath.Join(pluginDir, file.Name())
+		seen[PlaceKey(e)] = true
 
-		p, err := plugin.Open(pluginPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open plugin %s: %w", file.Name(), err)
+		jopts := append([]gmaps.PlaceJobOptions{gmaps.WithPlaceJobQuery(e.Query)}, opts...)
+
+		jobs = append(jobs, gmaps.NewPlaceJob("", langCode, e.Link, email, extraReviews, jopts...))
+	}
+
+	return jobs
+}
+
+// NewNoiseFilter returns the noise filter of the config, nil when it is disabled.
+func (c *Config) NewNoiseFilter() *gmaps.NoiseFilter {
+	if !c.NoiseFilter {
+		return nil
+	}
+
+	return gmaps.NewNoiseFilter(gmaps.ParseNoiseCategories(c.NoiseCategories))
+}
+
+// NewHostLimiter returns the limiter of the email crawl, nil when there is no limit.
+func (c *Config) NewHostLimiter() *gmaps.HostLimiter {
+	return gmaps.NewHostLimiter(c.EmailHostRate, c.EmailHostBurst)
+}
+
+// NewContactFinder returns the finder of the contact pages of the email
+// crawl, nil when it is disabled.
+func (c *Config) NewContactFinder() *gmaps.ContactFinder {
+	return gmaps.NewContactFinder(c.EmailContactPages, c.EmailIgnoreRobots)
+}
+
+// NewEmailGuesser returns the guesser of the emails of the websites without
+// one, nil unless -email-guess is set.
+func (c *Config) NewEmailGuesser() *gmaps.EmailGuesser {
+	if !c.EmailGuess {
+		return nil
+	}
+
+	return gmaps.NewEmailGuesser(c.newMailVerifier())
+}
+
+// NewEmailVerifier returns the verifier of the emails found, nil unless
+// -email-verify is set.
+func (c *Config) NewEmailVerifier() *gmaps.EmailVerifier {
+	if !c.EmailVerify {
+		return nil
+	}
+
+	return gmaps.NewEmailVerifier(c.newMailVerifier(), c.EmailVerifyConcurrency)
+}
+
+// NewEnrichers returns the enrichers of -enrichers, nil for the built-in
+// ones.
+func (c *Config) NewEnrichers() []gmaps.Enricher {
+	if len(c.Enrichers) == 0 {
+		return nil
+	}
+
+	enrichers, err := gmaps.LookupEnrichers(c.Enrichers)
+	if err != nil {
+		return nil
+	}
+
+	return enrichers
+}
+
+// newMailVerifier returns the verifier of the email addresses, nil when the
+// sender is invalid.
+func (c *Config) newMailVerifier() *emailverify.Verifier {
+	v, err := emailverify.New(c.EmailVerifyFrom)
+	if err != nil {
+		return nil
+	}
+
+	return v
+}
+
+// NewBlockGuard returns the guard of the captchas. The proxies are rotated
+// when the crawl uses proxies, otherwise the workers pause for the block
+// cooldown. onBlock may be nil.
+func (c *Config) NewBlockGuard(proxies bool, onBlock func(), opts ...gmaps.BlockGuardOption) *gmaps.BlockGuard {
+	return gmaps.NewBlockGuard(c.BlockCooldown, proxies, onBlock, opts...)
+}
+
+// NewCircuitBreakers returns the circuit breakers of the hosts, nil when
+// -circuit-threshold is 0. onChange may be nil.
+func (c *Config) NewCircuitBreakers(onChange func(host, state string)) *gmaps.CircuitBreakers {
+	return gmaps.NewCircuitBreakers(c.CircuitThreshold, c.CircuitCooldown, onChange)
+}
+
+// NewRetryer returns the retryer of the rate limited website pages of the
+// email crawl, nil when -email-retries is zero.
+func (c *Config) NewRetryer() *gmaps.Retryer {
+	return gmaps.NewRetryer(c.EmailRetries, c.EmailRetryMaxWait)
+}
+
+// NewCaptchaSolver returns the captcha solver of the config, nil when there is none.
+func (c *Config) NewCaptchaSolver() captcha.Solver {
+	switch c.CaptchaSolver {
+	case captcha.ProviderTwoCaptcha:
+		return twocaptcha.New(captcha.Key(c.CaptchaSolver))
+	case captcha.ProviderCapSolver:
+		return capsolver.New(captcha.Key(c.CaptchaSolver))
+	default:
+		return nil
+	}
+}
+
+// NewTranslator returns the translator of the reviews of the config, nil when
+// there is none.
+func (c *Config) NewTranslator() translate.Translator {
+	switch c.TranslateReviews {
+	case translate.ProviderGoogle:
+		return google.New(translate.Key(c.TranslateReviews))
+	case translate.ProviderDeepL:
+		return deepl.New(translate.Key(c.TranslateReviews))
+	default:
+		return nil
+	}
+}
+
+// ApplyBlockGuard makes the search and place jobs, and the ones the jobs
+// create, report the captchas to g. It does nothing when g is nil.
+func ApplyBlockGuard(jobs []scrapemate.IJob, g *gmaps.BlockGuard) {
+	if g == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithBlockGuard(g)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobBlockGuard(g)(j)
+		}
+	}
+}
+
+// NewFingerprintRotator returns the fingerprint rotator of the jobs in lang,
+// nil unless -rotate-fingerprint is set.
+func (c *Config) NewFingerprintRotator(lang string) *gmaps.FingerprintRotator {
+	if !c.RotateFingerprint {
+		return nil
+	}
+
+	return gmaps.NewFingerprintRotator(lang, c.FingerprintTimezones)
+}
+
+// ApplyFingerprints makes the jobs, and the ones the jobs create, give their
+// browser contexts a fingerprint of r. It does nothing when r is nil.
+func ApplyFingerprints(jobs []scrapemate.IJob, r *gmaps.FingerprintRotator) {
+	if r == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithFingerprints(r)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobFingerprints(r)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobFingerprints(r)(j)
+		}
+	}
+}
+
+// NewSessionStore returns the store of the browser sessions of the proxies
+// identified by proxyKey, nil unless -session-dir is set.
+func (c *Config) NewSessionStore(proxyKey string, fingerprints *gmaps.FingerprintRotator) (*gmaps.SessionStore, error) {
+	if c.SessionDir == "" {
+		return nil, nil
+	}
+
+	return gmaps.NewSessionStore(c.SessionDir, proxyKey, fingerprints)
+}
+
+// ApplySessions makes the search and place jobs, and the ones the jobs
+// create, reuse the browser sessions of s. It does nothing when s is nil.
+func ApplySessions(jobs []scrapemate.IJob, s *gmaps.SessionStore) {
+	if s == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithSessions(s)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobSessions(s)(j)
+		}
+	}
+}
+
+// NewScreenshotStore returns the store of the screenshots of the places, dir
+// or, when an S3 bucket is set, prefix under the -s3-prefix of the bucket.
+func (c *Config) NewScreenshotStore(dir, prefix string) (*gmaps.ScreenshotStore, error) {
+	var opts []gmaps.ScreenshotStoreOption
+
+	if c.S3Uploader != nil && c.S3Bucket != "" {
+		opts = append(opts, gmaps.WithScreenshotUploader(c.S3Uploader, c.S3Bucket, path.Join(c.S3Prefix, prefix)))
+	}
+
+	return gmaps.NewScreenshotStore(dir, opts...)
+}
+
+// ApplyScreenshots makes the search and place jobs, and the ones the jobs
+// create, keep a screenshot of the place pages in s. It does nothing when s
+// is nil.
+func ApplyScreenshots(jobs []scrapemate.IJob, s *gmaps.ScreenshotStore) {
+	if s == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithScreenshots(s)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobScreenshots(s)(j)
+		}
+	}
+}
+
+// NewDebugRecorder returns the recorder of the failed pages, that saves them
+// in dir, nil unless -debug-har is set.
+func (c *Config) NewDebugRecorder(dir string) (*gmaps.DebugRecorder, error) {
+	if !c.DebugHAR {
+		return nil, nil
+	}
+
+	return gmaps.NewDebugRecorder(dir)
+}
+
+// ApplyDebugRecorder makes the search and place jobs, and the ones the jobs
+// create, record their pages in d. It does nothing when d is nil.
+func ApplyDebugRecorder(jobs []scrapemate.IJob, d *gmaps.DebugRecorder) {
+	if d == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithDebugRecorder(d)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobDebugRecorder(d)(j)
+		}
+	}
+}
+
+// ApplyFailureLog makes the place and email jobs, and the ones the jobs
+// create, record their errors in l. It does nothing when l is nil.
+func ApplyFailureLog(jobs []scrapemate.IJob, l *gmaps.FailureLog) {
+	if l == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithFailureLog(l)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobFailureLog(l)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobFailureLog(l)(j)
+		}
+	}
+}
+
+// NewVideoRecorder returns the recorder that keeps the videos of the pages in
+// dir, nil unless -video-dir is set.
+func (c *Config) NewVideoRecorder(dir string) (*gmaps.VideoRecorder, error) {
+	if c.VideoDir == "" {
+		return nil, nil
+	}
+
+	return gmaps.NewVideoRecorder(dir, c.VideoSample)
+}
+
+// ApplyVideoRecorder makes the search and place jobs, and the ones the jobs
+// create, keep or delete the videos of their pages with v. It does nothing
+// when v is nil.
+func ApplyVideoRecorder(jobs []scrapemate.IJob, v *gmaps.VideoRecorder) {
+	if v == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithVideoRecorder(v)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobVideoRecorder(v)(j)
+		}
+	}
+}
+
+// ApplyHostLimiter makes the email jobs, and the ones the jobs create, wait
+// for l before they load a website. It does nothing when l is nil.
+func ApplyHostLimiter(jobs []scrapemate.IJob, l *gmaps.HostLimiter) {
+	if l == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithHostLimiter(l)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobHostLimiter(l)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobHostLimiter(l)(j)
+		}
+	}
+}
+
+// ApplyCircuitBreakers makes the jobs, and the ones the jobs create, fail at
+// once while the circuit of their host is open in b. It does nothing when b
+// is nil.
+func ApplyCircuitBreakers(jobs []scrapemate.IJob, b *gmaps.CircuitBreakers) {
+	if b == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithCircuitBreakers(b)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobCircuitBreakers(b)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobCircuitBreakers(b)(j)
+		case *gmaps.CroxyProxyJob:
+			gmaps.WithCroxyCircuitBreakers(b)(j)
+		}
+	}
+}
+
+// ApplyRetryer makes the email jobs, and the ones the jobs create, retry the
+// rate limited pages with r. It does nothing when r is nil.
+func ApplyRetryer(jobs []scrapemate.IJob, r *gmaps.Retryer) {
+	if r == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithRetryer(r)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobRetryer(r)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobRetryer(r)(j)
+		}
+	}
+}
+
+// ApplyContactFinder makes the email jobs, and the ones the jobs create, look
+// for emails on the contact pages found by f. It does nothing when f is nil.
+func ApplyContactFinder(jobs []scrapemate.IJob, f *gmaps.ContactFinder) {
+	if f == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithContactFinder(f)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobContactFinder(f)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobContactFinder(f)(j)
+		}
+	}
+}
+
+// ApplyEmailGuesser makes the email jobs, and the ones the jobs create, guess
+// the emails of the websites without one with g. It does nothing when g is nil.
+func ApplyEmailGuesser(jobs []scrapemate.IJob, g *gmaps.EmailGuesser) {
+	if g == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithEmailGuesser(g)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobEmailGuesser(g)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobGuesser(g)(j)
+		}
+	}
+}
+
+// ApplyEmailVerifier makes the email jobs, and the ones the jobs create,
+// verify the emails found with v. It does nothing when v is nil.
+func ApplyEmailVerifier(jobs []scrapemate.IJob, v *gmaps.EmailVerifier) {
+	if v == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithEmailVerifier(v)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobEmailVerifier(v)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobVerifier(v)(j)
+		}
+	}
+}
+
+// ApplyEnrichers makes the email jobs, and the ones the jobs create, run
+// enrichers over the homepages. It does nothing when enrichers is nil.
+func ApplyEnrichers(jobs []scrapemate.IJob, enrichers []gmaps.Enricher) {
+	if enrichers == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithEnrichers(enrichers)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobEnrichers(enrichers)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobEnrichers(enrichers)(j)
 		}
+	}
+}
+
+// NewAlerter returns the alerter of the channels and rules of
+// -alerts-config, whose rules watch the metrics named metrics, nil when it
+// is not set.
+func (c *Config) NewAlerter(metrics []string) (*alerting.Alerter, error) {
+	if c.AlertsConfig == "" {
+		return nil, nil
+	}
+
+	ac, err := alerting.LoadConfig(c.AlertsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return alerting.NewAlerter(ac, metrics)
+}
+
+// NewMetricsRecorder returns the recorder of the metrics of the jobs of
+// runner, nil unless -metrics-addr is set.
+func (c *Config) NewMetricsRecorder(runner string) *metrics.Recorder {
+	if c.MetricsAddr == "" {
+		return nil
+	}
+
+	return metrics.NewRecorder(runner, "")
+}
 
+// ApplyMetrics makes the jobs, and the ones the jobs create, record their
+// metrics with r. It does nothing when r is nil.
+func ApplyMetrics(jobs []scrapemate.IJob, r *metrics.Recorder) {
+	if r == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithMetrics(r)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobMetrics(r)(j)
+		case *gmaps.EmailExtractJob:
+			gmaps.WithEmailJobMetrics(r)(j)
+		}
+	}
+}
+
+// ApplyNoiseFilter makes the jobs drop the places that are not businesses.
+// It does nothing when f is nil.
+func ApplyNoiseFilter(jobs []scrapemate.IJob, f *gmaps.NoiseFilter) {
+	if f == nil {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithNoiseFilter(f)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobNoiseFilter(f)(j)
+		case *gmaps.SearchJob:
+			gmaps.WithSearchJobNoiseFilter(f)(j)
+		}
+	}
+}
+
+// ParseGeoCoordinates parses a "lat,lon" string and validates the ranges.
+func ParseGeoCoordinates(geoCoordinates string) (lat, lon float64, err error) {
+	parts := strings.Split(strings.ReplaceAll(geoCoordinates, " ", ""), ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid geo coordinates: %s", geoCoordinates)
+	}
+
+	lat, err = strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid latitude: %w", err)
+	}
+
+	lon, err = strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid longitude: %w", err)
+	}
+
+	if lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude: %f", lat)
+	}
+
+	if lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude: %f", lon)
+	}
+
+	return lat, lon, nil
+}
+
+// LoadEnricherPlugins opens the Go plugins of dir, whose init functions
+// register their enrichers with gmaps.RegisterEnricher.
+func LoadEnricherPlugins(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read enricher plugin directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || (filepath.Ext(file.Name()) != ".so" && filepath.Ext(file.Name()) != ".dll") {
+			continue
+		}
+
+		if _, err := plugin.Open(filepath.Join(dir, file.Name())); err != nil {
+			return fmt.Errorf("failed to open enricher plugin %s: %w", file.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// LoadCustomWriter opens the Go plugins of pluginDir and returns the writer
+// named pluginName, configured with config: a writer registered with
+// RegisterWriter, or else a *scrapemate.ResultWriter the plugins export
+// under that name.
+func LoadCustomWriter(pluginDir, pluginName string, config map[string]any) (scrapemate.ResultWriter, error) {
+	plugins, err := LoadWriterPlugins(pluginDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(WriterNames(), pluginName) {
+		return NewPluginWriter(pluginName, config)
+	}
+
+	for _, p := range plugins {
 		symWriter, err := p.Lookup(pluginName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to lookup symbol %s: %w", pluginName, err)
+			continue
 		}
 
 		writer, ok := symWriter.(*scrapemate.ResultWriter)
 		if !ok {
-			return nil, fmt.Errorf("unexpected type %T from writer symbol in plugin %s", symWriter, file.Name())
+			return nil, fmt.Errorf("unexpected type %T from writer symbol %s", symWriter, pluginName)
 		}
 
-		return *writer, nil
+		return configureWriter(pluginName, *writer, config)
 	}
 
-	return nil, fmt.Errorf("no plugin found in %s", pluginDir)
+	return nil, fmt.Errorf("no writer %s in the plugins of %s", pluginName, pluginDir)
 }
@@ -0,0 +1,24 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGeoCoordinates(t *testing.T) {
+	lat, lon, err := ParseGeoCoordinates("37.9838, 23.7275")
+	require.NoError(t, err)
+	require.InDelta(t, 37.9838, lat, 1e-9)
+	require.InDelta(t, 23.7275, lon, 1e-9)
+
+	lat, lon, err = ParseGeoCoordinates("-90,180")
+	require.NoError(t, err)
+	require.InDelta(t, -90.0, lat, 1e-9)
+	require.InDelta(t, 180.0, lon, 1e-9)
+
+	for _, s := range []string{"", "37.9", "37.9,23.7,1", "north,23.7", "37.9,east", "90.5,0", "0,-180.5"} {
+		_, _, err := ParseGeoCoordinates(s)
+		require.Error(t, err, s)
+	}
+}
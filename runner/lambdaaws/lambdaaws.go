@@ -16,6 +16,7 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
 	"github.com/gosom/google-maps-scraper/runner"
 	"github.com/gosom/scrapemate"
 	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
@@ -81,6 +82,7 @@ func (l *lambdaAwsRunner) handler(ctx context.Context, input lInput) error {
 	seedJobs, err = runner.CreateSeedJobs(
 		false, // TODO supoort fast mode
 		input.Language,
+		input.Region,
 		in,
 		input.Depth,
 		false,
@@ -91,6 +93,8 @@ func (l *lambdaAwsRunner) handler(ctx context.Context, input lInput) error {
 		exitMonitor,
 		input.ExtraReviews,
 		false, // CroxyProxy not supported in Lambda
+		nil,
+		gmaps.FallbackChain{},
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+var _ scrapemate.ResultWriter = (*metricsWriter)(nil)
+
+type metricsWriter struct {
+	w    scrapemate.ResultWriter
+	rec  *metrics.Recorder
+	name string
+}
+
+// NewMetricsWriter records with rec how long w, named name in the metrics,
+// takes to accept each result. The writers take one result at a time, so a
+// slow writer makes the next result wait. It returns w when rec is nil.
+func NewMetricsWriter(w scrapemate.ResultWriter, rec *metrics.Recorder, name string) scrapemate.ResultWriter {
+	if rec == nil {
+		return w
+	}
+
+	return &metricsWriter{w: w, rec: rec, name: name}
+}
+
+func (m *metricsWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := Forward(ctx, m.w)
+
+	for result := range in {
+		start := time.Now()
+
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+
+		m.rec.Write(m.name, time.Since(start))
+	}
+
+	return fw.Close()
+}
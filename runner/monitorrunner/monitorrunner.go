@@ -0,0 +1,147 @@
+package monitorrunner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+// monitorRunner scrapes a fixed list of places every -monitor-interval,
+// compares each place with its previous snapshot and records the changes in
+// the change log of -monitor-db, notifying -monitor-webhook of them.
+type monitorRunner struct {
+	cfg    *runner.Config
+	urls   []string
+	store  *store
+	notify *notifier
+}
+
+func New(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeMonitor {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	cfg.RegisterHealthChecks()
+
+	urls, err := runner.ReadPlaceURLs(cfg.InputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no place urls in %s", cfg.InputFile)
+	}
+
+	s, err := openStore(cfg.MonitorDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the monitor database: %w", err)
+	}
+
+	return &monitorRunner{
+		cfg:    cfg,
+		urls:   urls,
+		store:  s,
+		notify: newNotifier(cfg.MonitorWebhook, cfg.MonitorNotify),
+	}, nil
+}
+
+func (r *monitorRunner) Run(ctx context.Context) error {
+	log.Printf("monitoring %d places every %s", len(r.urls), r.cfg.MonitorInterval)
+
+	r.cfg.WatchBrowsers(ctx, nil)
+
+	for {
+		t0 := time.Now()
+		w := &changeWriter{store: r.store, notify: r.notify}
+
+		// a round stops at the latest when the next one is due
+		err := r.cfg.ScrapePlaces(ctx, r.urls, w, r.cfg.MonitorInterval)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Printf("failed to scrape the monitored places: %v", err)
+		}
+
+		log.Printf("scraped %d of %d monitored places, found %d changes", w.places, len(r.urls), w.changes)
+
+		// the interval is between the starts of the rounds
+		wait := r.cfg.MonitorInterval - time.Since(t0)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *monitorRunner) Close(context.Context) error {
+	return r.store.close()
+}
+
+// changeWriter compares the places of a round with their snapshots.
+type changeWriter struct {
+	store  *store
+	notify *notifier
+
+	places  int
+	changes int
+}
+
+func (w *changeWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		entry, ok := result.Data.(*gmaps.Entry)
+		if !ok {
+			continue
+		}
+
+		if err := w.compare(ctx, entry); err != nil {
+			return fmt.Errorf("failed to record the changes of %s: %w", entry.Link, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *changeWriter) compare(ctx context.Context, e *gmaps.Entry) error {
+	prev, err := w.store.previous(ctx, runner.PlaceKey(e))
+	if err != nil {
+		return err
+	}
+
+	var changes []runner.Change
+
+	if prev != nil {
+		changes = runner.Diff(prev, e)
+	}
+
+	now := time.Now().UTC()
+
+	for i := range changes {
+		changes[i].DetectedAt = now
+	}
+
+	if err := w.store.save(ctx, e, changes); err != nil {
+		return err
+	}
+
+	w.places++
+	w.changes += len(changes)
+
+	// a webhook that is down does not stop the monitoring, the changes are in the log
+	for i := range changes {
+		if err := w.notify.notify(ctx, &changes[i]); err != nil {
+			log.Printf("failed to notify %s of %s: %v", changes[i].Type, e.Link, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package monitorrunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+// notifier posts the changes to a webhook, as the json of the change, or as
+// a message to a Slack incoming webhook (https://hooks.slack.com/...).
+type notifier struct {
+	url   string
+	slack bool
+	types map[string]bool
+	http  *http.Client
+}
+
+// newNotifier notifies the changes of types, comma separated, or of all
+// types when types is empty. It returns nil when webhook is empty.
+func newNotifier(webhook, types string) *notifier {
+	if webhook == "" {
+		return nil
+	}
+
+	ans := notifier{
+		url:   webhook,
+		types: map[string]bool{},
+		http:  &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if u, err := url.Parse(webhook); err == nil {
+		ans.slack = u.Host == "hooks.slack.com"
+	}
+
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			ans.types[t] = true
+		}
+	}
+
+	return &ans
+}
+
+// notify posts c when its type is notified. It does nothing when n is nil.
+func (n *notifier) notify(ctx context.Context, c *runner.Change) error {
+	if n == nil || (len(n.types) > 0 && !n.types[c.Type]) {
+		return nil
+	}
+
+	var payload any = c
+
+	if n.slack {
+		payload = map[string]string{"text": changeText(c)}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// changeText describes c in a line, e.g. "Cafe: phone_changed from "+30 210" to "+30 211" (link)".
+func changeText(c *runner.Change) string {
+	before, _ := json.Marshal(c.Old)
+	after, _ := json.Marshal(c.New)
+
+	return fmt.Sprintf("%s: %s from %s to %s (%s)", c.Title, c.Type, before, after, c.Link)
+}
@@ -0,0 +1,133 @@
+package monitorrunner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	_ "modernc.org/sqlite" // sqlite driver
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+// store keeps the last snapshot of each monitored place and the change log
+// in a sqlite database.
+type store struct {
+	db *sql.DB
+}
+
+func openStore(path string) (*store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		PRAGMA busy_timeout = 5000;
+		PRAGMA journal_mode=WAL;
+		CREATE TABLE IF NOT EXISTS monitor_places (
+			key TEXT PRIMARY KEY,
+			data TEXT NOT NULL,
+			updated_at INT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS monitor_changes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key TEXT NOT NULL,
+			type TEXT NOT NULL,
+			title TEXT NOT NULL,
+			link TEXT NOT NULL,
+			old TEXT NOT NULL,
+			new TEXT NOT NULL,
+			detected_at INT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS monitor_changes_key ON monitor_changes (key, detected_at)
+	`)
+	if err != nil {
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	return &store{db: db}, nil
+}
+
+// previous returns the last snapshot of the place of key, nil when the place
+// was never scraped.
+func (s *store) previous(ctx context.Context, key string) (*gmaps.Entry, error) {
+	const q = `SELECT data FROM monitor_places WHERE key = ?`
+
+	var data string
+
+	err := s.db.QueryRowContext(ctx, q, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var e gmaps.Entry
+
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// save replaces the snapshot of the place and appends its changes to the
+// change log.
+func (s *store) save(ctx context.Context, e *gmaps.Entry, changes []runner.Change) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const q = `INSERT INTO monitor_places (key, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`
+
+	if _, err := tx.ExecContext(ctx, q, runner.PlaceKey(e), string(data), time.Now().UTC().Unix()); err != nil {
+		return err
+	}
+
+	const qc = `INSERT INTO monitor_changes (key, type, title, link, old, new, detected_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	for i := range changes {
+		before, err := json.Marshal(changes[i].Old)
+		if err != nil {
+			return err
+		}
+
+		after, err := json.Marshal(changes[i].New)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, qc, changes[i].Key, changes[i].Type, changes[i].Title, changes[i].Link,
+			string(before), string(after), changes[i].DetectedAt.Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,17 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// NewNormalizeWriter normalizes the title, address and description of the
+// entries before passing them to w, see gmaps.NormalizeText.
+func NewNormalizeWriter(w scrapemate.ResultWriter, stripEmojis bool) scrapemate.ResultWriter {
+	return MapEntries(w, func(_ context.Context, e *gmaps.Entry) {
+		e.Normalize(stripEmojis)
+	})
+}
@@ -0,0 +1,144 @@
+package occupancyrunner
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/scrapemate"
+)
+
+// occupancyHeaders are the columns of the occupancy file
+var occupancyHeaders = []string{"sampled_at", "cid", "title", "link", "day", "hour", "current", "usual", "text"}
+
+// occupancyRunner samples the live busyness of a list of places every
+// -occupancy-interval and appends it to -occupancy-file, building a time
+// series of the occupancy of each place.
+type occupancyRunner struct {
+	cfg     *runner.Config
+	urls    []string
+	outfile *os.File
+	out     *csv.Writer
+}
+
+func New(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeOccupancy {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	cfg.RegisterHealthChecks()
+
+	urls, err := runner.ReadPlaceURLs(cfg.InputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no place urls in %s", cfg.InputFile)
+	}
+
+	f, err := os.OpenFile(cfg.OccupancyFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &occupancyRunner{
+		cfg:     cfg,
+		urls:    urls,
+		outfile: f,
+		out:     csv.NewWriter(f),
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		if err := ans.out.Write(occupancyHeaders); err != nil {
+			_ = f.Close()
+
+			return nil, err
+		}
+
+		ans.out.Flush()
+	}
+
+	return ans, nil
+}
+
+func (r *occupancyRunner) Run(ctx context.Context) error {
+	log.Printf("sampling the occupancy of %d places every %s", len(r.urls), r.cfg.OccupancyInterval)
+
+	r.cfg.WatchBrowsers(ctx, nil)
+
+	for {
+		t0 := time.Now()
+
+		// a round stops at the latest when the next one is due
+		err := r.cfg.ScrapePlaces(ctx, r.urls, &occupancyWriter{out: r.out}, r.cfg.OccupancyInterval)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Printf("failed to sample the occupancy: %v", err)
+		}
+
+		// the interval is between the starts of the rounds
+		wait := r.cfg.OccupancyInterval - time.Since(t0)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *occupancyRunner) Close(context.Context) error {
+	r.out.Flush()
+
+	return r.outfile.Close()
+}
+
+// occupancyWriter writes a row of the occupancy file per place. The places
+// without live busyness get empty day, hour and values.
+type occupancyWriter struct {
+	out *csv.Writer
+}
+
+func (w *occupancyWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		entry, ok := result.Data.(*gmaps.Entry)
+		if !ok {
+			continue
+		}
+
+		row := []string{time.Now().UTC().Format(time.RFC3339), entry.Cid, entry.Title, entry.Link, "", "", "", "", ""}
+
+		if o := entry.LiveOccupancy; o != nil {
+			row[4] = o.Day
+			row[5] = strconv.Itoa(o.Hour)
+			row[6] = strconv.Itoa(o.Current)
+			row[7] = strconv.Itoa(o.Usual)
+			row[8] = o.Text
+		}
+
+		if err := w.out.Write(row); err != nil {
+			return err
+		}
+
+		w.out.Flush()
+	}
+
+	return w.out.Error()
+}
@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/scrapemateapp"
+
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// ReadPlaceURLs returns the place urls of the input file, or stdin, one per
+// line. Empty lines and lines starting with # are skipped.
+func ReadPlaceURLs(name string) ([]string, error) {
+	var in io.Reader
+
+	if name == "stdin" {
+		in = os.Stdin
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		in = f
+	}
+
+	var urls []string
+
+	scanner := bufio.NewScanner(in)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+// ScrapePlaces scrapes the places of urls again, without their emails, and
+// passes them to w. It stops after timeout at the latest, since the places
+// that fail are never completed.
+func (c *Config) ScrapePlaces(ctx context.Context, urls []string, w scrapemate.ResultWriter, timeout time.Duration) error {
+	exitMonitor := exiter.New()
+
+	jopts := []gmaps.PlaceJobOptions{gmaps.WithPlaceJobExitMonitor(exitMonitor)}
+
+	if c.Region != "" {
+		jopts = append(jopts, gmaps.WithPlaceJobRegion(c.Region))
+	}
+
+	if !c.RequestExtras.IsZero() {
+		jopts = append(jopts, gmaps.WithPlaceJobRequestExtras(c.RequestExtras))
+	}
+
+	jobs := make([]scrapemate.IJob, 0, len(urls))
+
+	for _, u := range urls {
+		jobs = append(jobs, gmaps.NewPlaceJob("", c.LangCode, u, false, false, jopts...))
+	}
+
+	recorder := c.NewMetricsRecorder("places")
+
+	blocks := c.NewBlockGuard(len(c.Proxies) > 0, func() {
+		log.Printf("warning: google served a captcha")
+		recorder.Block()
+	})
+	ApplyBlockGuard(jobs, blocks)
+	ApplyCircuitBreakers(jobs, c.NewCircuitBreakers(func(host, state string) {
+		log.Printf("the circuit of %s is %s", host, state)
+		recorder.Circuit(host, state)
+	}))
+	ApplyMetrics(jobs, recorder)
+	ApplyFingerprints(jobs, c.NewFingerprintRotator(c.LangCode))
+	ApplyDelay(jobs, c.Delay)
+
+	return c.runJobs(ctx, jobs, exitMonitor, w, timeout)
+}
+
+// runJobs runs jobs, that are places or websites counted by exitMonitor, and
+// passes their results to w. The browsers are started for the call and
+// closed when it returns. A zero timeout waits until all the jobs completed.
+func (c *Config) runJobs(ctx context.Context, jobs []scrapemate.IJob, exitMonitor exiter.Exiter, w scrapemate.ResultWriter, timeout time.Duration) error {
+	opts := []func(*scrapemateapp.Config) error{
+		scrapemateapp.WithConcurrency(c.Concurrency),
+		scrapemateapp.WithExitOnInactivity(c.ExitOnInactivityDuration),
+	}
+
+	if len(c.Proxies) > 0 {
+		opts = append(opts, scrapemateapp.WithProxies(c.Proxies))
+	}
+
+	if c.Debug {
+		opts = append(opts, scrapemateapp.WithJS(
+			scrapemateapp.Headfull(),
+			scrapemateapp.DisableImages(),
+		))
+	} else {
+		opts = append(opts, scrapemateapp.WithJS(scrapemateapp.DisableImages()))
+	}
+
+	matecfg, err := scrapemateapp.NewConfig([]scrapemate.ResultWriter{w}, opts...)
+	if err != nil {
+		return err
+	}
+
+	app, err := c.NewApp(matecfg, c.BrowserEngine)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if cerr := app.Close(); cerr != nil {
+			log.Printf("failed to close the browsers: %v", cerr)
+		}
+	}()
+
+	exitMonitor.IncrPlacesFound(len(jobs))
+
+	var cancel context.CancelFunc
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	defer cancel()
+
+	exitMonitor.SetCancelFunc(cancel)
+
+	go exitMonitor.Run(ctx)
+
+	err = app.Start(ctx, jobs...)
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+
+	return nil
+}
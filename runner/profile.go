@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"flag"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/profile"
+)
+
+// applyProfile sets the settings of the profile of the config, except the
+// ones given as flags. In the web runner the profile is the default of the
+// jobs instead.
+func (c *Config) applyProfile() {
+	s, ok := profile.Get(c.Profile)
+	if !ok || c.RunMode == RunModeWeb {
+		return
+	}
+
+	set := make(map[string]bool)
+
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if !set["c"] {
+		c.Concurrency = s.Concurrency
+	}
+
+	if !set["depth"] {
+		c.MaxDepth = s.Depth
+	}
+
+	if !set["email"] {
+		c.Email = s.Email
+	}
+
+	if !set["extra-reviews"] {
+		c.ExtraReviews = s.ExtraReviews
+	}
+
+	if !set["delay"] {
+		c.Delay = s.Delay
+	}
+}
+
+// Warnings returns the settings of the config that are more aggressive than recommended.
+func (c *Config) Warnings() []string {
+	return profile.Warnings(profile.Settings{
+		Concurrency:  c.Concurrency,
+		Depth:        c.MaxDepth,
+		Email:        c.Email,
+		ExtraReviews: c.ExtraReviews,
+		Delay:        c.Delay,
+	})
+}
+
+// ApplyDelay makes the workers pause before each page of Google they load.
+// It does nothing when d is zero.
+func ApplyDelay(jobs []scrapemate.IJob, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	for _, job := range withFallbacks(jobs) {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			gmaps.WithDelay(d)(j)
+		case *gmaps.PlaceJob:
+			gmaps.WithPlaceJobDelay(d)(j)
+		case *gmaps.SearchJob:
+			gmaps.WithSearchJobDelay(d)(j)
+		}
+	}
+}
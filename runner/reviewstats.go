@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+var _ scrapemate.ResultWriter = (*ReviewStatsWriter)(nil)
+
+// ReviewStatsWriter counts the entries, the ones with complete reviews and
+// the ones with emails before passing the results to the wrapped writer.
+type ReviewStatsWriter struct {
+	w        scrapemate.ResultWriter
+	entries  atomic.Int64
+	complete atomic.Int64
+	emails   atomic.Int64
+}
+
+func NewReviewStatsWriter(w scrapemate.ResultWriter) *ReviewStatsWriter {
+	return &ReviewStatsWriter{w: w}
+}
+
+func (r *ReviewStatsWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	return MapEntries(r.w, r.add).Run(ctx, in)
+}
+
+// Stats returns the number of entries written and how many of them have complete reviews.
+func (r *ReviewStatsWriter) Stats() (entries, complete int) {
+	return int(r.entries.Load()), int(r.complete.Load())
+}
+
+// Emails returns the number of entries written with at least one email.
+func (r *ReviewStatsWriter) Emails() int {
+	return int(r.emails.Load())
+}
+
+func (r *ReviewStatsWriter) add(_ context.Context, e *gmaps.Entry) {
+	r.entries.Add(1)
+
+	if e.ReviewsComplete {
+		r.complete.Add(1)
+	}
+
+	if len(e.Emails) > 0 {
+		r.emails.Add(1)
+	}
+}
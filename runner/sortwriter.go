@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const (
+	SortByDistance = "distance"
+	SortByRating   = "rating"
+	SortByTitle    = "title"
+	SortByQuery    = "query"
+)
+
+// ValidateSortBy returns an error when the sort key is not supported.
+// An empty value means that results are written as they arrive.
+func ValidateSortBy(sortBy string) error {
+	switch sortBy {
+	case "", SortByDistance, SortByRating, SortByTitle, SortByQuery:
+		return nil
+	default:
+		return fmt.Errorf("invalid sort option: %s", sortBy)
+	}
+}
+
+var _ scrapemate.ResultWriter = (*sortedWriter)(nil)
+
+type sortedWriter struct {
+	w      scrapemate.ResultWriter
+	sortBy string
+}
+
+// NewSortedWriter buffers all the results, sorts the entries by the given key
+// and then forwards them to w. Ties are broken by cid, title and link so the
+// output is the same across runs that found the same places.
+// Results that are not entries are forwarded after the entries in the order they arrived.
+func NewSortedWriter(w scrapemate.ResultWriter, sortBy string) scrapemate.ResultWriter {
+	return &sortedWriter{w: w, sortBy: sortBy}
+}
+
+func (s *sortedWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	var (
+		entries []scrapemate.Result
+		others  []scrapemate.Result
+	)
+
+	for result := range in {
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			entries = append(entries, result)
+		case []*gmaps.Entry:
+			for i := range data {
+				entries = append(entries, scrapemate.Result{Job: result.Job, Data: data[i]})
+			}
+		default:
+			others = append(others, result)
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b scrapemate.Result) int {
+		//nolint:errcheck // only entries are in the slice
+		return compareEntries(a.Data.(*gmaps.Entry), b.Data.(*gmaps.Entry), s.sortBy)
+	})
+
+	fw := Forward(ctx, s.w)
+
+	for _, result := range append(entries, others...) {
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
+
+func compareEntries(a, b *gmaps.Entry, sortBy string) int {
+	if c := compareByKey(a, b, sortBy); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(a.Cid, b.Cid); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(a.Title, b.Title); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(a.Link, b.Link)
+}
+
+func compareByKey(a, b *gmaps.Entry, sortBy string) int {
+	switch sortBy {
+	case SortByDistance:
+		// entries without a distance go last
+		switch {
+		case a.DistanceMeters == 0 && b.DistanceMeters != 0:
+			return 1
+		case a.DistanceMeters != 0 && b.DistanceMeters == 0:
+			return -1
+		}
+
+		return cmp.Compare(a.DistanceMeters, b.DistanceMeters)
+	case SortByRating:
+		if c := cmp.Compare(b.ReviewRating, a.ReviewRating); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(b.ReviewCount, a.ReviewCount)
+	case SortByTitle:
+		return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case SortByQuery:
+		return cmp.Compare(a.Query, b.Query)
+	default:
+		return 0
+	}
+}
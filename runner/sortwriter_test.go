@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// collectWriter keeps the results it is given.
+type collectWriter struct {
+	results []scrapemate.Result
+}
+
+func (c *collectWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		c.results = append(c.results, result)
+	}
+
+	return nil
+}
+
+func (c *collectWriter) titles() []string {
+	var ans []string
+
+	for _, r := range c.results {
+		if e, ok := r.Data.(*gmaps.Entry); ok {
+			ans = append(ans, e.Title)
+		} else {
+			ans = append(ans, "other")
+		}
+	}
+
+	return ans
+}
+
+// writeAll runs w on results and returns when it is done.
+func writeAll(t *testing.T, w scrapemate.ResultWriter, results ...scrapemate.Result) {
+	t.Helper()
+
+	in := make(chan scrapemate.Result, len(results))
+
+	for _, r := range results {
+		in <- r
+	}
+
+	close(in)
+
+	require.NoError(t, w.Run(context.Background(), in))
+}
+
+func TestSortedWriter(t *testing.T) {
+	entries := func() []scrapemate.Result {
+		return []scrapemate.Result{
+			{Data: &gmaps.Entry{Title: "b", Cid: "2", DistanceMeters: 300, ReviewRating: 4.5, ReviewCount: 10, Query: "pub"}},
+			{Data: "not an entry"},
+			{Data: []*gmaps.Entry{
+				{Title: "A", Cid: "1", ReviewRating: 4.5, ReviewCount: 90, Query: "cafe"},
+				{Title: "c", Cid: "3", DistanceMeters: 100, ReviewRating: 3, Query: "bar"},
+			}},
+		}
+	}
+
+	for _, tc := range []struct {
+		sortBy string
+		want   []string
+	}{
+		// the entries without a distance go last
+		{sortBy: SortByDistance, want: []string{"c", "b", "A", "other"}},
+		// the ties of the rating are broken by the review count
+		{sortBy: SortByRating, want: []string{"A", "b", "c", "other"}},
+		{sortBy: SortByTitle, want: []string{"A", "b", "c", "other"}},
+		{sortBy: SortByQuery, want: []string{"c", "A", "b", "other"}},
+		// unsorted keys keep the order of the cids
+		{sortBy: "", want: []string{"A", "b", "c", "other"}},
+	} {
+		c := &collectWriter{}
+
+		writeAll(t, NewSortedWriter(c, tc.sortBy), entries()...)
+
+		require.Equal(t, tc.want, c.titles(), tc.sortBy)
+	}
+}
+
+func TestValidateSortBy(t *testing.T) {
+	for _, key := range []string{"", SortByDistance, SortByRating, SortByTitle, SortByQuery} {
+		require.NoError(t, ValidateSortBy(key))
+	}
+
+	require.Error(t, ValidateSortBy("reviews"))
+}
@@ -0,0 +1,327 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+// spillSegmentSize is the number of results of a file of a spill writer.
+const spillSegmentSize = 1000
+
+func init() {
+	gob.Register(&gmaps.Entry{})
+	gob.Register([]*gmaps.Entry{})
+}
+
+type spillWriter struct {
+	w      scrapemate.ResultWriter
+	memory int
+	dir    string
+	rec    *metrics.Recorder
+	name   string
+}
+
+// NewSpillWriter lets the scraping go on when w, named name in the metrics,
+// is slower than it, e.g. a webhook or a remote database. Up to memory
+// results wait for w in memory, the next ones are written to files in a
+// folder of dir, the temporary folder when it is empty, and read back in
+// order when w catches up. The results read from disk have no job. A result
+// whose data cannot be written to disk waits for w. It returns w when memory
+// is 0.
+func NewSpillWriter(w scrapemate.ResultWriter, memory int, dir string, rec *metrics.Recorder, name string) scrapemate.ResultWriter {
+	if memory <= 0 {
+		return w
+	}
+
+	return &spillWriter{w: w, memory: memory, dir: dir, rec: rec, name: name}
+}
+
+func (s *spillWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := Forward(ctx, s.w)
+
+	q := &spillQueue{memory: s.memory, dir: s.dir, name: s.name}
+	defer q.close()
+
+	for in != nil || q.len() > 0 {
+		var (
+			send chan<- scrapemate.Result
+			next scrapemate.Result
+		)
+
+		if q.len() > 0 {
+			var err error
+
+			next, err = q.peek()
+			if err != nil {
+				return fmt.Errorf("failed to read the spilled results of %s: %w", s.name, err)
+			}
+
+			// the writer takes the next result while the input is read
+			send = fw.out
+		}
+
+		select {
+		case result, ok := <-in:
+			if !ok {
+				in = nil
+
+				continue
+			}
+
+			spilled, err := q.push(result)
+
+			switch {
+			case errors.Is(err, errNotSpillable):
+				// the results before it go first
+				if err := s.drain(q, fw); err != nil {
+					return err
+				}
+
+				if !fw.Send(result) {
+					return fw.Close()
+				}
+			case err != nil:
+				return fmt.Errorf("failed to spill the results of %s: %w", s.name, err)
+			case spilled:
+				s.rec.Spill(s.name)
+			}
+		case send <- next:
+			q.pop()
+		case err := <-fw.errc:
+			return err
+		}
+
+		s.rec.Queue(s.name, q.len())
+	}
+
+	return fw.Close()
+}
+
+// drain sends all the results of q to fw.
+func (s *spillWriter) drain(q *spillQueue, fw *Forwarder) error {
+	for q.len() > 0 {
+		next, err := q.peek()
+		if err != nil {
+			return fmt.Errorf("failed to read the spilled results of %s: %w", s.name, err)
+		}
+
+		if !fw.Send(next) {
+			return fw.Close()
+		}
+
+		q.pop()
+	}
+
+	return nil
+}
+
+// errNotSpillable is returned for a result whose data cannot be encoded.
+var errNotSpillable = errors.New("result cannot be spilled")
+
+// spilledResult is a result written to disk.
+type spilledResult struct {
+	Data any
+}
+
+// spillSegment is a file of spilled results.
+type spillSegment struct {
+	path  string
+	file  *os.File
+	buf   *bufio.Writer
+	enc   *gob.Encoder
+	count int
+}
+
+// seal flushes and closes the file of a segment so it can be read.
+func (seg *spillSegment) seal() error {
+	if seg.file == nil {
+		return nil
+	}
+
+	err := seg.buf.Flush()
+	if cerr := seg.file.Close(); err == nil {
+		err = cerr
+	}
+
+	seg.file, seg.buf, seg.enc = nil, nil, nil
+
+	return err
+}
+
+// spillQueue is a queue of results kept in memory up to memory results,
+// then in files. The results in memory are always older than the ones on
+// disk.
+type spillQueue struct {
+	memory int
+	dir    string
+	name   string
+
+	mem []scrapemate.Result
+
+	tmpDir   string
+	segments []*spillSegment
+	spilled  int
+	// reader reads the first segment, head is the next result it read
+	reader *os.File
+	dec    *gob.Decoder
+	head   *scrapemate.Result
+}
+
+func (q *spillQueue) len() int {
+	return len(q.mem) + q.spilled
+}
+
+// push adds result to the queue and reports whether it was written to disk.
+func (q *spillQueue) push(result scrapemate.Result) (bool, error) {
+	if q.spilled == 0 && len(q.mem) < q.memory {
+		q.mem = append(q.mem, result)
+
+		return false, nil
+	}
+
+	seg, err := q.writingSegment()
+	if err != nil {
+		return false, err
+	}
+
+	if err := seg.enc.Encode(spilledResult{Data: result.Data}); err != nil {
+		// the encoder cannot be used after a failure
+		if err := q.sealLast(); err != nil {
+			return false, err
+		}
+
+		return false, fmt.Errorf("%w: %v", errNotSpillable, err)
+	}
+
+	seg.count++
+	q.spilled++
+
+	if seg.count >= spillSegmentSize {
+		if err := seg.seal(); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// writingSegment returns the segment the results are written to, a new one
+// when the last one is sealed.
+func (q *spillQueue) writingSegment() (*spillSegment, error) {
+	if n := len(q.segments); n > 0 && q.segments[n-1].file != nil {
+		return q.segments[n-1], nil
+	}
+
+	if q.tmpDir == "" {
+		dir, err := os.MkdirTemp(q.dir, "spill-"+q.name+"-*")
+		if err != nil {
+			return nil, err
+		}
+
+		q.tmpDir = dir
+	}
+
+	f, err := os.CreateTemp(q.tmpDir, "segment-*")
+	if err != nil {
+		return nil, err
+	}
+
+	seg := spillSegment{path: f.Name(), file: f, buf: bufio.NewWriter(f)}
+	seg.enc = gob.NewEncoder(seg.buf)
+
+	q.segments = append(q.segments, &seg)
+
+	return &seg, nil
+}
+
+// sealLast seals the last segment, the next results go to a new one.
+func (q *spillQueue) sealLast() error {
+	if len(q.segments) == 0 {
+		return nil
+	}
+
+	return q.segments[len(q.segments)-1].seal()
+}
+
+// peek returns the oldest result of the queue, which must not be empty.
+func (q *spillQueue) peek() (scrapemate.Result, error) {
+	if len(q.mem) > 0 {
+		return q.mem[0], nil
+	}
+
+	for q.head == nil {
+		if q.dec == nil {
+			seg := q.segments[0]
+
+			// a segment is read once complete
+			if err := seg.seal(); err != nil {
+				return scrapemate.Result{}, err
+			}
+
+			f, err := os.Open(seg.path)
+			if err != nil {
+				return scrapemate.Result{}, err
+			}
+
+			q.reader, q.dec = f, gob.NewDecoder(bufio.NewReader(f))
+		}
+
+		var spilled spilledResult
+
+		err := q.dec.Decode(&spilled)
+
+		switch {
+		case errors.Is(err, io.EOF):
+			_ = q.reader.Close()
+			_ = os.Remove(q.segments[0].path)
+
+			q.reader, q.dec = nil, nil
+			q.segments = q.segments[1:]
+		case err != nil:
+			return scrapemate.Result{}, err
+		default:
+			q.head = &scrapemate.Result{Data: spilled.Data}
+		}
+	}
+
+	return *q.head, nil
+}
+
+// pop removes the result returned by peek.
+func (q *spillQueue) pop() {
+	if len(q.mem) > 0 {
+		q.mem[0] = scrapemate.Result{}
+		q.mem = q.mem[1:]
+
+		return
+	}
+
+	q.head = nil
+	q.spilled--
+}
+
+// close removes the files of the queue.
+func (q *spillQueue) close() {
+	if q.reader != nil {
+		_ = q.reader.Close()
+	}
+
+	for _, seg := range q.segments {
+		_ = seg.seal()
+	}
+
+	if q.tmpDir != "" {
+		_ = os.RemoveAll(filepath.Clean(q.tmpDir))
+	}
+}
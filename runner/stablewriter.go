@@ -0,0 +1,295 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+var _ scrapemate.ResultWriter = (*stableWriter)(nil)
+
+type stableWriter struct {
+	w    scrapemate.ResultWriter
+	path string
+	keep func() bool
+}
+
+// NewStableWriter keeps the entries by data_id in a store at path, the
+// latest one of each place, and forwards them to w sorted by data_id when
+// the input is closed, so the output is the same across runs that found the
+// same places, whatever the order they were scraped in. The store is read
+// again when it exists, e.g. after a crash, and removed once w got the
+// entries, unless keep reports the run is not over, e.g. paused, when it is
+// kept for the next run. path is a temporary file when it is empty. The
+// entries without data_id are keyed by cid or link, the ones without any
+// and the results that are not entries are forwarded after the others in the
+// order they arrived.
+func NewStableWriter(w scrapemate.ResultWriter, path string, keep func() bool) scrapemate.ResultWriter {
+	return &stableWriter{w: w, path: path, keep: keep}
+}
+
+func (s *stableWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	store, err := openStableStore(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open the store of the entries: %w", err)
+	}
+
+	var others []scrapemate.Result
+
+	for result := range in {
+		entries := Entries(result.Data)
+		if entries == nil {
+			others = append(others, result)
+
+			continue
+		}
+
+		for _, e := range entries {
+			key, ok := stableKey(e)
+			if !ok {
+				others = append(others, scrapemate.Result{Job: result.Job, Data: e})
+
+				continue
+			}
+
+			if err := store.put(key, e); err != nil {
+				_ = store.close()
+
+				return fmt.Errorf("failed to store the entry %s: %w", key, err)
+			}
+		}
+	}
+
+	if s.keep != nil && s.keep() {
+		return store.close()
+	}
+
+	if err := s.forward(ctx, store, others); err != nil {
+		_ = store.close()
+
+		return err
+	}
+
+	return store.remove()
+}
+
+func (s *stableWriter) forward(ctx context.Context, store *stableStore, others []scrapemate.Result) error {
+	fw := Forward(ctx, s.w)
+
+	send := func(result scrapemate.Result) error {
+		if fw.Send(result) {
+			return nil
+		}
+
+		if err := fw.Close(); err != nil {
+			return err
+		}
+
+		return errors.New("the writer stopped before the end of the results")
+	}
+
+	for _, key := range store.keys() {
+		e, err := store.get(key)
+		if err != nil {
+			_ = fw.Close()
+
+			return fmt.Errorf("failed to read the stored entry %s: %w", key, err)
+		}
+
+		if err := send(scrapemate.Result{Data: e}); err != nil {
+			return err
+		}
+	}
+
+	for _, result := range others {
+		if err := send(result); err != nil {
+			return err
+		}
+	}
+
+	return fw.Close()
+}
+
+// stableKey returns the key of e in the store: its data_id, or its cid or
+// link when it has none.
+func stableKey(e *gmaps.Entry) (string, bool) {
+	switch {
+	case e.DataID != "":
+		return e.DataID, true
+	case e.Cid != "":
+		return "cid:" + e.Cid, true
+	case e.Link != "":
+		return "link:" + e.Link, true
+	}
+
+	return "", false
+}
+
+// stableStore is an append only file of gob encoded entries, each one after
+// its length, with the offset of the latest entry of each key.
+type stableStore struct {
+	f      *os.File
+	w      *bufio.Writer
+	size   int64
+	offset map[string]int64
+}
+
+func openStableStore(path string) (*stableStore, error) {
+	var (
+		f   *os.File
+		err error
+	)
+
+	if path == "" {
+		f, err = os.CreateTemp("", "stable-*.store")
+	} else {
+		f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &stableStore{f: f, offset: make(map[string]int64)}
+
+	if err := s.load(); err != nil {
+		_ = f.Close()
+
+		return nil, err
+	}
+
+	s.w = bufio.NewWriter(f)
+
+	return s, nil
+}
+
+// load indexes the entries of the store and drops the last one when it was
+// not fully written.
+func (s *stableStore) load() error {
+	r := bufio.NewReader(s.f)
+
+	for {
+		e, n, err := readStableEntry(r)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if key, ok := stableKey(e); ok {
+			s.offset[key] = s.size
+		}
+
+		s.size += n
+	}
+
+	if err := s.f.Truncate(s.size); err != nil {
+		return err
+	}
+
+	_, err := s.f.Seek(s.size, io.SeekStart)
+
+	return err
+}
+
+func (s *stableStore) put(key string, e *gmaps.Entry) error {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		return err
+	}
+
+	var head [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(head[:], uint64(buf.Len()))
+
+	if _, err := s.w.Write(head[:n]); err != nil {
+		return err
+	}
+
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	s.offset[key] = s.size
+	s.size += int64(n + buf.Len())
+
+	return nil
+}
+
+// keys returns the keys of the store, sorted.
+func (s *stableStore) keys() []string {
+	keys := make([]string, 0, len(s.offset))
+
+	for key := range s.offset {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+func (s *stableStore) get(key string) (*gmaps.Entry, error) {
+	if err := s.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.f, s.offset[key], s.size-s.offset[key]))
+
+	e, _, err := readStableEntry(r)
+
+	return e, err
+}
+
+func (s *stableStore) close() error {
+	if err := s.w.Flush(); err != nil {
+		_ = s.f.Close()
+
+		return err
+	}
+
+	return s.f.Close()
+}
+
+func (s *stableStore) remove() error {
+	_ = s.f.Close()
+
+	return os.Remove(s.f.Name())
+}
+
+// readStableEntry reads an entry of a store and returns it with the number
+// of bytes it took.
+func readStableEntry(r *bufio.Reader) (*gmaps.Entry, int64, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data := make([]byte, size)
+
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, 0, err
+	}
+
+	var e gmaps.Entry
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return nil, 0, err
+	}
+
+	return &e, int64(binary.PutUvarint(make([]byte, binary.MaxVarintLen64), size)) + int64(size), nil
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func TestStableStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.store")
+
+	store, err := openStableStore(path)
+	require.NoError(t, err)
+
+	require.NoError(t, store.put("2", &gmaps.Entry{Title: "b", DataID: "2"}))
+	require.NoError(t, store.put("1", &gmaps.Entry{Title: "a", DataID: "1"}))
+	require.NoError(t, store.put("2", &gmaps.Entry{Title: "b updated", DataID: "2"}))
+	require.NoError(t, store.close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// an entry cut while it was written is dropped when the store is read
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0x40, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	store, err = openStableStore(path)
+	require.NoError(t, err)
+	require.Equal(t, info.Size(), store.size)
+	require.Equal(t, []string{"1", "2"}, store.keys())
+
+	e, err := store.get("2")
+	require.NoError(t, err)
+	require.Equal(t, "b updated", e.Title)
+
+	// the entries put after the reload follow the ones that were kept
+	require.NoError(t, store.put("3", &gmaps.Entry{Title: "c", DataID: "3"}))
+
+	e, err = store.get("3")
+	require.NoError(t, err)
+	require.Equal(t, "c", e.Title)
+
+	e, err = store.get("1")
+	require.NoError(t, err)
+	require.Equal(t, "a", e.Title)
+
+	require.NoError(t, store.remove())
+
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStableWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.store")
+	paused := true
+
+	results := []scrapemate.Result{
+		{Data: &gmaps.Entry{Title: "c", DataID: "3"}},
+		{Data: "not an entry"},
+		{Data: []*gmaps.Entry{
+			{Title: "a", DataID: "1"},
+			{Title: "no key"},
+		}},
+	}
+
+	// a paused run keeps the store for the next one
+	c := &collectWriter{}
+
+	writeAll(t, NewStableWriter(c, path, func() bool { return paused }), results...)
+	require.Empty(t, c.results)
+
+	paused = false
+
+	writeAll(t, NewStableWriter(c, path, func() bool { return paused }),
+		scrapemate.Result{Data: &gmaps.Entry{Title: "b", Cid: "9"}},
+		scrapemate.Result{Data: &gmaps.Entry{Title: "a again", DataID: "1"}},
+	)
+
+	require.Equal(t, []string{"a again", "c", "b"}, c.titles())
+
+	_, err := os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
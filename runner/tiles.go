@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/tiling"
+)
+
+// Tiles splits the bounding box of the config in tiles.
+// It returns nil when no bounding box is set.
+func (c *Config) Tiles() ([]tiling.Tile, error) {
+	if c.BBox == "" {
+		return nil, nil
+	}
+
+	return CoverBBox(c.BBox, c.TileSystem, c.TileLevel, c.Exclusions())
+}
+
+// Exclusions returns the zones of the bounding box of the config that are not searched.
+func (c *Config) Exclusions() tiling.Exclusions {
+	// validated in ParseConfig
+	ex, _ := tiling.ParseExclusions(c.BBoxExclude)
+
+	return ex
+}
+
+// Density returns the model that splits the saturated tiles of the config.
+// It returns nil when tiles are not split.
+func (c *Config) Density() (*tiling.Density, error) {
+	return NewDensity(c.TileSystem, c.TileMaxLevel)
+}
+
+// NewDensity returns the model that splits saturated tiles of the system down to maxLevel.
+// It returns nil when maxLevel is zero.
+func NewDensity(system string, maxLevel int) (*tiling.Density, error) {
+	if maxLevel == 0 {
+		return nil, nil
+	}
+
+	sys, err := tiling.New(system)
+	if err != nil {
+		return nil, err
+	}
+
+	return tiling.NewDensity(sys, maxLevel), nil
+}
+
+// CoverCircle returns the hexagonal tiles of tileRadius meters that cover the
+// circle of radius meters around coords, see tiling.CoverCircle.
+func CoverCircle(coords string, radius, tileRadius float64) ([]tiling.Tile, error) {
+	lat, lon, err := ParseGeoCoordinates(coords)
+	if err != nil {
+		return nil, err
+	}
+
+	return tiling.CoverCircle(lat, lon, radius, tileRadius)
+}
+
+// ApplyCircle drops the places outside the circle of radius meters around
+// coords from the results of the fast mode searches, which search the circle
+// around each tile.
+func ApplyCircle(jobs []scrapemate.IJob, coords string, radius float64) error {
+	lat, lon, err := ParseGeoCoordinates(coords)
+	if err != nil {
+		return err
+	}
+
+	for i := range jobs {
+		if job, ok := jobs[i].(*gmaps.SearchJob); ok {
+			gmaps.WithSearchJobCircle(lat, lon, radius)(job)
+		}
+	}
+
+	return nil
+}
+
+// ErrAllTilesExcluded is returned when every tile of a bounding box overlaps an excluded zone.
+var ErrAllTilesExcluded = errors.New("all the tiles of the bounding box are excluded")
+
+// CoverBBox returns the tiles of the given system and level that cover the
+// bounding box, without the ones that overlap the excluded zones.
+func CoverBBox(bbox, system string, level int, exclude tiling.Exclusions) ([]tiling.Tile, error) {
+	box, err := tiling.ParseBBox(bbox)
+	if err != nil {
+		return nil, err
+	}
+
+	sys, err := tiling.New(system)
+	if err != nil {
+		return nil, err
+	}
+
+	tiles, err := sys.Cover(box, level)
+	if err != nil {
+		return nil, err
+	}
+
+	tiles = exclude.Filter(tiles)
+	if len(tiles) == 0 {
+		return nil, ErrAllTilesExcluded
+	}
+
+	return tiles, nil
+}
+
+// CreateTiledSeedJobs creates the seed jobs of each query for every tile.
+// The places found in more than one tile are removed by the deduper.
+// In fast mode, the saturated tiles are split when density is not nil,
+// and the smaller tiles that overlap the excluded zones are skipped.
+func CreateTiledSeedJobs(
+	fastmode bool,
+	langCode string,
+	region string,
+	r io.Reader,
+	maxDepth int,
+	email bool,
+	tiles []tiling.Tile,
+	density *tiling.Density,
+	exclude tiling.Exclusions,
+	dedup deduper.Deduper,
+	exitMonitor exiter.Exiter,
+	extraReviews bool,
+	useCroxy bool,
+	extras *gmaps.RequestExtras,
+) ([]scrapemate.IJob, error) {
+	queries, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []scrapemate.IJob
+
+	for i := range tiles {
+		tileJobs, err := CreateTileSeedJobs(
+			fastmode,
+			langCode,
+			region,
+			string(queries),
+			maxDepth,
+			email,
+			tiles[i],
+			density,
+			exclude,
+			dedup,
+			exitMonitor,
+			extraReviews,
+			useCroxy,
+			extras,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, tileJobs...)
+	}
+
+	return jobs, nil
+}
+
+// CreateTileSeedJobs creates the seed jobs of each query for one tile.
+// The tile is searched around its center with a zoom that fits it.
+func CreateTileSeedJobs(
+	fastmode bool,
+	langCode string,
+	region string,
+	queries string,
+	maxDepth int,
+	email bool,
+	tile tiling.Tile,
+	density *tiling.Density,
+	exclude tiling.Exclusions,
+	dedup deduper.Deduper,
+	exitMonitor exiter.Exiter,
+	extraReviews bool,
+	useCroxy bool,
+	extras *gmaps.RequestExtras,
+) ([]scrapemate.IJob, error) {
+	jobs, err := CreateSeedJobs(
+		fastmode,
+		langCode,
+		region,
+		strings.NewReader(queries),
+		maxDepth,
+		email,
+		tile.Coordinates(),
+		tile.Zoom(),
+		tile.RadiusMeters,
+		dedup,
+		exitMonitor,
+		extraReviews,
+		useCroxy,
+		extras,
+		gmaps.FallbackChain{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tile %s: %w", tile.ID, err)
+	}
+
+	for i := range jobs {
+		if job, ok := jobs[i].(*gmaps.SearchJob); ok {
+			gmaps.WithSearchJobTile(tile, density)(job)
+			gmaps.WithSearchJobExclusions(exclude)(job)
+		}
+	}
+
+	return jobs, nil
+}
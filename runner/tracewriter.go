@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gosom/scrapemate"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gosom/google-maps-scraper/tracing"
+)
+
+var _ scrapemate.ResultWriter = (*traceWriter)(nil)
+
+type traceWriter struct {
+	w scrapemate.ResultWriter
+}
+
+// NewTracingWriter records a span for each result written by w, a child of
+// the span of the job that produced it. The writers take one result at a
+// time, so a span ends when w takes the next result or returns.
+func NewTracingWriter(w scrapemate.ResultWriter) scrapemate.ResultWriter {
+	return &traceWriter{w: w}
+}
+
+func (t *traceWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := Forward(ctx, t.w)
+
+	var span trace.Span
+
+	endSpan := func() {
+		if span != nil {
+			span.End()
+			span = nil
+		}
+	}
+
+	for result := range in {
+		var parent string
+
+		if job, ok := result.Job.(interface{ Traceparent() string }); ok {
+			parent = job.Traceparent()
+		}
+
+		_, next := tracing.Start(ctx, parent, "write", attribute.String("data.type", fmt.Sprintf("%T", result.Data)))
+
+		if !fw.Send(result) {
+			next.End()
+			endSpan()
+
+			return fw.Close()
+		}
+
+		endSpan()
+
+		span = next
+	}
+
+	err := fw.Close()
+
+	endSpan()
+
+	return err
+}
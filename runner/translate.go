@@ -0,0 +1,23 @@
+package runner
+
+import (
+	"context"
+	"log"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/translate"
+)
+
+// NewTranslateWriter translates the reviews of the entries to the target
+// language with t before passing them to w, see gmaps.Entry.TranslateReviews.
+// The entries whose reviews could not be translated are written without
+// translations.
+func NewTranslateWriter(w scrapemate.ResultWriter, t translate.Translator, target string) scrapemate.ResultWriter {
+	return MapEntries(w, func(ctx context.Context, e *gmaps.Entry) {
+		if err := e.TranslateReviews(ctx, t, target); err != nil {
+			log.Printf("failed to translate the reviews of %s: %v", e.Link, err)
+		}
+	})
+}
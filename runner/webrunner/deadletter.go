@@ -0,0 +1,124 @@
+package webrunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"path/filepath"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/web"
+	"github.com/gosom/scrapemate"
+)
+
+// deadLetter keeps the place and email jobs of pending that failed after all
+// their retries as dead letters of the job jobID, and writes them next to its
+// results, then deletes the requeued dead letters the run consumed.
+func (w *webrunner) deadLetter(ctx context.Context, jobID string, pending []scrapemate.IJob, failures *gmaps.FailureLog, requeued []web.DeadLetter) {
+	var letters []web.DeadLetter
+
+	dead := make(map[string]bool)
+
+	for _, job := range pending {
+		var typ string
+
+		switch job.(type) {
+		case *gmaps.PlaceJob:
+			typ = "place"
+		case *gmaps.EmailExtractJob:
+			typ = "email"
+		default:
+			continue
+		}
+
+		failure, ok := failures.Failure(job.GetID())
+		if !ok {
+			continue
+		}
+
+		dead[job.GetID()] = true
+
+		payload, err := encodeFrontier([]scrapemate.IJob{job})
+		if err != nil {
+			w.svc.Logf(jobID, "failed to encode the failed %s job %s of job %s: %v", typ, job.GetID(), jobID, err)
+
+			continue
+		}
+
+		letters = append(letters, web.DeadLetter{
+			JobID:   jobID,
+			Type:    typ,
+			URL:     job.GetURL(),
+			Error:   failure.Error,
+			Payload: payload,
+		})
+	}
+
+	var failed []gmaps.Failure
+
+	for _, f := range failures.Failures() {
+		if dead[f.JobID] {
+			failed = append(failed, f)
+		}
+	}
+
+	errorsPath := runner.FailuresPath(filepath.Join(w.cfg.DataFolder, jobID+".csv"), false)
+
+	if err := runner.WriteFailures(errorsPath, failed); err != nil {
+		w.svc.Logf(jobID, "failed to write the failed places of job %s: %v", jobID, err)
+	}
+
+	if err := w.svc.AddDeadLetters(ctx, letters); err != nil {
+		w.svc.Logf(jobID, "failed to save the %d failed places of job %s: %v", len(letters), jobID, err)
+
+		return
+	}
+
+	if len(letters) > 0 {
+		w.svc.Logf(jobID, "job %s has %d failed places, they can be requeued", jobID, len(letters))
+	}
+
+	if len(requeued) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(requeued))
+	for i := range requeued {
+		ids = append(ids, requeued[i].ID)
+	}
+
+	if err := w.svc.DeleteDeadLetters(ctx, jobID, ids); err != nil {
+		w.svc.Logf(jobID, "failed to delete the requeued places of job %s: %v", jobID, err)
+	}
+}
+
+// appendDeadLetters appends the jobs of the dead letters to the jobs encoded
+// in data by encodeFrontier. data may be empty.
+func appendDeadLetters(data []byte, letters []web.DeadLetter) ([]byte, error) {
+	var saved []savedJob
+
+	if len(data) > 0 {
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&saved); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range letters {
+		var jobs []savedJob
+
+		if err := gob.NewDecoder(bytes.NewReader(letters[i].Payload)).Decode(&jobs); err != nil {
+			return nil, err
+		}
+
+		saved = append(saved, jobs...)
+	}
+
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(saved); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,439 @@
+package webrunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+var _ scrapemate.JobProvider = (*frontier)(nil)
+
+// frontier is an in memory job provider that can be paused.
+// It keeps track of the jobs that were handed out and are not known to be
+// completed yet, so the pending work of a paused job can be saved and resumed.
+// A job is completed when it produces a result or pushes a child job, and
+// finished when the worker that ran it takes its next job, see Jobs. The
+// finished jobs whose last attempt failed are kept for the dead letters.
+type frontier struct {
+	mu       sync.Mutex
+	queues   [3][]scrapemate.IJob
+	inflight map[string]scrapemate.IJob
+	failed   map[string]scrapemate.IJob
+	// failures has the errors of the jobs, nil when they are not recorded
+	failures *gmaps.FailureLog
+	paused   bool
+	// wake is closed and replaced when jobs are pushed to wake up all the waiting workers
+	wake chan struct{}
+	// tiles follows the progress of the tiles of bbox jobs, it is nil for other jobs
+	tiles *tileTracker
+}
+
+func newFrontier() *frontier {
+	return &frontier{
+		inflight: make(map[string]scrapemate.IJob),
+		failed:   make(map[string]scrapemate.IJob),
+		wake:     make(chan struct{}),
+	}
+}
+
+// Jobs hands out the jobs. Each worker of scrapemate takes its jobs from
+// its own channel and finishes a job before it takes the next one, so the
+// previous job of the channel is finished once a job is sent.
+//
+//nolint:gocritic // we need to return a read only channel
+func (f *frontier) Jobs(ctx context.Context) (<-chan scrapemate.IJob, <-chan error) {
+	out := make(chan scrapemate.IJob)
+	errc := make(chan error, 1)
+
+	go func() {
+		var last string
+
+		for {
+			job, wake := f.next()
+			if job == nil {
+				select {
+				case <-ctx.Done():
+					errc <- ctx.Err()
+
+					return
+				case <-wake:
+					continue
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				errc <- ctx.Err()
+
+				return
+			case out <- job:
+				if last != "" {
+					f.finished(last)
+				}
+
+				last = job.GetID()
+			}
+		}
+	}()
+
+	return out, errc
+}
+
+func (f *frontier) Push(_ context.Context, job scrapemate.IJob) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.inflight, job.GetParentID())
+	f.tiles.pushed(job)
+
+	i := queueIndex(job.GetPriority())
+	f.queues[i] = append(f.queues[i], job)
+
+	close(f.wake)
+	f.wake = make(chan struct{})
+
+	return nil
+}
+
+func (f *frontier) pause() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.paused = true
+}
+
+func (f *frontier) isPaused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.paused
+}
+
+func (f *frontier) done(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.inflight, id)
+	f.tiles.completed(id)
+}
+
+// finished forgets the job id, which the worker that ran it is done with.
+// It is kept for the dead letters when its last attempt failed.
+func (f *frontier) finished(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job, ok := f.inflight[id]
+	if !ok {
+		return
+	}
+
+	delete(f.inflight, id)
+
+	if _, failed := f.failures.Failure(id); failed {
+		f.failed[id] = job
+	}
+
+	f.tiles.completed(id)
+}
+
+func (f *frontier) inflightCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.inflight)
+}
+
+// pending returns the jobs that were not completed, including the ones that
+// were in flight and the ones that failed, which run again on resume.
+func (f *frontier) pending() []scrapemate.IJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ans := make([]scrapemate.IJob, 0, len(f.inflight)+len(f.failed))
+
+	for _, job := range f.inflight {
+		ans = append(ans, job)
+	}
+
+	for _, job := range f.failed {
+		ans = append(ans, job)
+	}
+
+	for i := range f.queues {
+		ans = append(ans, f.queues[i]...)
+	}
+
+	return ans
+}
+
+// next returns the next job to hand out. When there is none it returns
+// a channel that is closed when new jobs are pushed.
+func (f *frontier) next() (scrapemate.IJob, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.paused {
+		return nil, f.wake
+	}
+
+	for i := range f.queues {
+		if len(f.queues[i]) == 0 {
+			continue
+		}
+
+		job := f.queues[i][0]
+		f.queues[i] = f.queues[i][1:]
+		f.inflight[job.GetID()] = job
+
+		return job, nil
+	}
+
+	return nil, f.wake
+}
+
+func queueIndex(priority int) int {
+	switch priority {
+	case scrapemate.PriorityMedium:
+		return 1
+	case scrapemate.PriorityLow:
+		return 2
+	default:
+		return 0
+	}
+}
+
+var _ scrapemate.ResultWriter = (*frontierWriter)(nil)
+
+// frontierWriter marks the jobs that produced a result as completed.
+type frontierWriter struct {
+	w scrapemate.ResultWriter
+	f *frontier
+}
+
+func (fw *frontierWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	w := runner.Forward(ctx, fw.w)
+
+	for result := range in {
+		if result.Job != nil {
+			fw.f.tiles.addEntries(result.Job.GetID(), countEntries(result.Data))
+			fw.f.done(result.Job.GetID())
+		}
+
+		if !w.Send(result) {
+			return w.Close()
+		}
+	}
+
+	return w.Close()
+}
+
+type savedJob struct {
+	Type    string
+	Payload []byte
+}
+
+// saveFrontier writes the jobs to path so they can be resumed later.
+func saveFrontier(path string, jobs []scrapemate.IJob) error {
+	data, err := encodeFrontier(jobs)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// encodeFrontier encodes the jobs so they can be resumed later.
+// The deduper, exit monitor and noise filter are not saved, they are set again on load.
+func encodeFrontier(jobs []scrapemate.IJob) ([]byte, error) {
+	saved := make([]savedJob, 0, len(jobs))
+
+	for _, job := range jobs {
+		var (
+			buf bytes.Buffer
+			typ string
+			err error
+		)
+
+		enc := gob.NewEncoder(&buf)
+
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			var data []byte
+
+			typ, data, err = encodeStep(j)
+			buf.Write(data)
+		case *gmaps.SearchJob:
+			// fast mode jobs have unexported parameters and cannot be saved
+			return nil, errors.New("fast mode jobs cannot be paused")
+		case *gmaps.PlaceJob:
+			c := *j
+			c.ExitMonitor, c.NoiseFilter, c.Throttle = nil, nil, nil
+			typ, err = "place", enc.Encode(&c)
+		case *gmaps.EmailExtractJob:
+			c := *j
+			c.ExitMonitor = nil
+			typ, err = "email", enc.Encode(&c)
+		default:
+			return nil, fmt.Errorf("invalid job type %T", job)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		saved = append(saved, savedJob{Type: typ, Payload: buf.Bytes()})
+	}
+
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(saved); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeFrontier decodes the jobs encoded by encodeFrontier and attaches the
+// deduper and the exit monitor. The exit monitor counts are set for the jobs.
+func decodeFrontier(data []byte, dedup deduper.Deduper, exitMonitor exiter.Exiter) ([]scrapemate.IJob, error) {
+	var saved []savedJob
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&saved); err != nil {
+		return nil, err
+	}
+
+	var (
+		jobs        []scrapemate.IJob
+		seeds       int
+		placesFound int
+	)
+
+	for _, s := range saved {
+		dec := gob.NewDecoder(bytes.NewReader(s.Payload))
+
+		switch s.Type {
+		case "search":
+			j, err := decodeStep(s.Type, s.Payload)
+			if err != nil {
+				return nil, err
+			}
+
+			// the next steps of the chain are set up as the search
+			for step := j; step != nil; step = gmaps.FallbackJob(step) {
+				switch st := step.(type) {
+				case *gmaps.GmapJob:
+					st.Deduper, st.ExitMonitor = dedup, exitMonitor
+				case *gmaps.SearchJob:
+					st.ExitMonitor = exitMonitor
+				case *gmaps.CroxyProxyJob:
+					gmaps.WithCroxyExitMonitor(exitMonitor)(st)
+				}
+			}
+
+			seeds++
+
+			jobs = append(jobs, j)
+		case "place":
+			j := new(gmaps.PlaceJob)
+			if err := dec.Decode(j); err != nil {
+				return nil, fmt.Errorf("failed to decode place job: %w", err)
+			}
+
+			j.ExitMonitor = exitMonitor
+			placesFound++
+
+			jobs = append(jobs, j)
+		case "email":
+			j := new(gmaps.EmailExtractJob)
+			if err := dec.Decode(j); err != nil {
+				return nil, fmt.Errorf("failed to decode email job: %w", err)
+			}
+
+			j.ExitMonitor = exitMonitor
+			placesFound++
+
+			jobs = append(jobs, j)
+		default:
+			return nil, fmt.Errorf("invalid job type: %s", s.Type)
+		}
+	}
+
+	exitMonitor.SetSeedCount(seeds)
+	exitMonitor.IncrPlacesFound(placesFound)
+
+	return jobs, nil
+}
+
+// encodeStep encodes a search, a step of a fallback chain, and the steps
+// after it. The deduper, exit monitor, noise filter and throttle are not
+// saved, they are set again on load.
+func encodeStep(job scrapemate.IJob) (string, []byte, error) {
+	var typ string
+
+	switch j := job.(type) {
+	case *gmaps.GmapJob:
+		c := *j
+		c.Deduper, c.ExitMonitor, c.NoiseFilter, c.Throttle = nil, nil, nil, nil
+		typ, job = "search", &c
+	case *gmaps.SearchJob:
+		typ = "static"
+	case *gmaps.CroxyProxyJob:
+		typ = "croxy"
+	default:
+		return "", nil, fmt.Errorf("invalid job type %T", job)
+	}
+
+	var buf bytes.Buffer
+
+	enc := gob.NewEncoder(&buf)
+
+	if err := enc.Encode(job); err != nil {
+		return "", nil, err
+	}
+
+	if err := gmaps.EncodeFallback(enc, job, encodeStep); err != nil {
+		return "", nil, err
+	}
+
+	return typ, buf.Bytes(), nil
+}
+
+// decodeStep decodes a search encoded by encodeStep with its fallback chain.
+func decodeStep(typ string, data []byte) (scrapemate.IJob, error) {
+	var job scrapemate.IJob
+
+	switch typ {
+	case "search":
+		job = new(gmaps.GmapJob)
+	case "static":
+		job = new(gmaps.SearchJob)
+	case "croxy":
+		job = new(gmaps.CroxyProxyJob)
+	default:
+		return nil, fmt.Errorf("invalid job type: %s", typ)
+	}
+
+	dec := gob.NewDecoder(bytes.NewReader(data))
+
+	if err := dec.Decode(job); err != nil {
+		return nil, fmt.Errorf("failed to decode %s job: %w", typ, err)
+	}
+
+	if err := gmaps.DecodeFallback(dec, job, decodeStep); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
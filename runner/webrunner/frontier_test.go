@@ -0,0 +1,105 @@
+package webrunner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func TestFrontierSaveLoad(t *testing.T) {
+	search := gmaps.NewGmapJob("", "en", "cafes in athens", 10, false, "", 0)
+	croxy := gmaps.NewCroxyProxyJob("", "https://www.google.com/maps/search/cafes+in+athens")
+	gmaps.SetFallback(search, croxy, []string{"error"})
+
+	place := gmaps.NewPlaceJob(search.ID, "en", "https://www.google.com/maps/place/1", true, false)
+
+	data, err := encodeFrontier([]scrapemate.IJob{search, place})
+	require.NoError(t, err)
+
+	dedup, exitMonitor := deduper.New(), exiter.New()
+
+	jobs, err := decodeFrontier(data, dedup, exitMonitor)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	gotSearch, ok := jobs[0].(*gmaps.GmapJob)
+	require.True(t, ok)
+	require.Equal(t, search.ID, gotSearch.ID)
+	require.Equal(t, search.URL, gotSearch.URL)
+	require.Equal(t, dedup, gotSearch.Deduper)
+	require.Equal(t, exitMonitor, gotSearch.ExitMonitor)
+
+	gotCroxy, ok := gmaps.FallbackJob(gotSearch).(*gmaps.CroxyProxyJob)
+	require.True(t, ok)
+	require.Equal(t, croxy.ID, gotCroxy.ID)
+	require.Equal(t, []string{"error"}, gmaps.FallbackOn(gotSearch))
+
+	gotPlace, ok := jobs[1].(*gmaps.PlaceJob)
+	require.True(t, ok)
+	require.Equal(t, place.ID, gotPlace.ID)
+	require.Equal(t, place.URL, gotPlace.URL)
+	require.True(t, gotPlace.ExtractEmail)
+	require.Equal(t, exitMonitor, gotPlace.ExitMonitor)
+
+	progress := exitMonitor.Progress()
+	require.Equal(t, 1, progress.SeedCount)
+	require.Equal(t, 1, progress.PlacesFound)
+}
+
+func TestFrontierFinished(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := newFrontier()
+	f.failures = gmaps.NewFailureLog()
+
+	a := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/a", false, false)
+	b := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/b", false, false)
+	c := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/c", false, false)
+
+	for _, job := range []scrapemate.IJob{a, b, c} {
+		require.NoError(t, f.Push(ctx, job))
+	}
+
+	jobs, _ := f.Jobs(ctx)
+
+	receive := func() scrapemate.IJob {
+		select {
+		case job := <-jobs:
+			return job
+		case <-time.After(time.Second):
+			t.Fatal("no job")
+
+			return nil
+		}
+	}
+
+	first := receive()
+
+	f.failures.Record(first.GetID(), errors.New("timeout"))
+
+	// the worker takes its next job once it is done with the first one
+	second := receive()
+	receive()
+
+	// only the last job is in flight, the queue is empty
+	require.Eventually(t, func() bool { return f.inflightCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	// the failed job runs again on resume, the completed one does not
+	ids := make([]string, 0, 2)
+	for _, job := range f.pending() {
+		ids = append(ids, job.GetID())
+	}
+
+	require.Contains(t, ids, first.GetID())
+	require.NotContains(t, ids, second.GetID())
+	require.Len(t, ids, 2)
+}
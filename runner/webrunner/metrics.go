@@ -0,0 +1,50 @@
+package webrunner
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+var _ scrapemate.ResultWriter = (*metricsWriter)(nil)
+
+// metricsWriter counts the places written by a job and records them in the metrics.
+type metricsWriter struct {
+	w       scrapemate.ResultWriter
+	metrics *web.MetricsCollector
+	// recorder records the Prometheus metrics of the job
+	recorder *metrics.Recorder
+	// onPlaces, when set, is called with the number of places written so far
+	onPlaces func(total int64)
+	places   atomic.Int64
+}
+
+func (mw *metricsWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := runner.Forward(ctx, mw.w)
+
+	for result := range in {
+		n := 1
+		if entries, ok := result.Data.([]*gmaps.Entry); ok {
+			n = len(entries)
+		}
+
+		total := mw.places.Add(int64(n))
+		mw.metrics.AddPlaces(n)
+
+		if mw.onPlaces != nil {
+			mw.onPlaces(total)
+		}
+
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
@@ -0,0 +1,37 @@
+package webrunner
+
+import (
+	"context"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+// resultsWriter keeps the entries written by w in the results of the job,
+// so they can be previewed while it runs.
+type resultsWriter struct {
+	w     scrapemate.ResultWriter
+	svc   *web.Service
+	jobID string
+}
+
+func (rw *resultsWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := runner.Forward(ctx, rw.w)
+
+	for result := range in {
+		entries := runner.Entries(result.Data)
+
+		// the csv is the results of record, a failure only shows in the preview
+		if err := rw.svc.StoreResults(ctx, rw.jobID, entries); err != nil {
+			rw.svc.Logf(rw.jobID, "failed to store the results of job %s: %v", rw.jobID, err)
+		}
+
+		if !fw.Send(result) {
+			return fw.Close()
+		}
+	}
+
+	return fw.Close()
+}
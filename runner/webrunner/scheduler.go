@@ -0,0 +1,284 @@
+package webrunner
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+// scheduler runs up to max jobs at a time. The running jobs share the
+// concurrency of the server by their shares, and a pending job preempts the
+// running job of the lowest priority when there is no free slot.
+//
+// The concurrency of a job is set when it starts and cannot change while it
+// runs. The jobs that got more than their share when other jobs start are
+// preempted to resume with their new share, and the ones that got less when
+// jobs stop keep it until they are paused or resumed.
+type scheduler struct {
+	mu      sync.Mutex
+	max     int
+	running map[string]*slot
+	wg      sync.WaitGroup
+	// stopping is set when the server shuts down
+	stopping bool
+}
+
+// slot is a running job.
+type slot struct {
+	priority int
+	share    int
+	// fraction is the part of the concurrency of the server the job got when
+	// it started
+	fraction float64
+	started  time.Time
+	// preemptible jobs can be paused and resumed later, fast mode jobs cannot
+	preemptible bool
+	// preempt is closed when the job must give its slot to a job of a higher
+	// priority, or stop because the server shuts down
+	preempt   chan struct{}
+	preempted bool
+}
+
+func newScheduler(maxJobs int) *scheduler {
+	return &scheduler{
+		max:     max(1, maxJobs),
+		running: make(map[string]*slot),
+	}
+}
+
+// free returns the number of jobs that can be started.
+func (s *scheduler) free() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.max - len(s.running)
+}
+
+func (s *scheduler) isRunning(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.running[id]
+
+	return ok
+}
+
+// start runs fn for each of jobs in the background in a slot. The jobs get
+// the part of the concurrency of their share over the shares of the running
+// jobs and of the jobs started with them. It returns the running jobs that
+// got more than their new part, which are preempted to resume with it, see
+// the scheduler.
+func (s *scheduler) start(jobs []*web.Job, fn func(*web.Job)) []string {
+	s.mu.Lock()
+
+	total := 0
+
+	for _, sl := range s.running {
+		total += sl.share
+	}
+
+	for _, job := range jobs {
+		total += max(1, job.Data.Share)
+	}
+
+	var rebalanced []string
+
+	for id, sl := range s.running {
+		if sl.preemptible && !sl.preempted && sl.fraction > float64(sl.share)/float64(total)+1e-9 {
+			sl.preempted = true
+			close(sl.preempt)
+
+			rebalanced = append(rebalanced, id)
+		}
+	}
+
+	for _, job := range jobs {
+		share := max(1, job.Data.Share)
+
+		s.running[job.ID] = &slot{
+			priority:    job.Data.Priority,
+			share:       share,
+			fraction:    float64(share) / float64(total),
+			started:     time.Now(),
+			preemptible: !job.Data.FastMode,
+			preempt:     make(chan struct{}),
+		}
+	}
+
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+
+			defer func() {
+				s.mu.Lock()
+				delete(s.running, job.ID)
+				s.mu.Unlock()
+			}()
+
+			fn(job)
+		}()
+	}
+
+	slices.Sort(rebalanced)
+
+	return rebalanced
+}
+
+// ids returns the ids of the running jobs.
+func (s *scheduler) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ans := make([]string, 0, len(s.running))
+
+	for id := range s.running {
+		ans = append(ans, id)
+	}
+
+	slices.Sort(ans)
+
+	return ans
+}
+
+// wait waits for the running jobs to stop.
+func (s *scheduler) wait() {
+	s.wg.Wait()
+}
+
+// waitTimeout waits for the running jobs to stop for d at most and reports
+// whether they did.
+func (s *scheduler) waitTimeout(d time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// fraction returns the part of the concurrency of the server of the job id,
+// all of it when it is not run by the scheduler.
+func (s *scheduler) fraction(id string) float64 {
+	if s == nil {
+		return 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sl, ok := s.running[id]; ok {
+		return sl.fraction
+	}
+
+	return 1
+}
+
+// preemption returns the channel closed when the job id is preempted, nil
+// when it is not run by the scheduler.
+func (s *scheduler) preemption(id string) <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sl, ok := s.running[id]; ok {
+		return sl.preempt
+	}
+
+	return nil
+}
+
+// wasPreempted reports whether the job id was preempted, by a job of a
+// higher priority or by the shutdown.
+func (s *scheduler) wasPreempted(id string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sl, ok := s.running[id]
+
+	return ok && sl.preempted
+}
+
+// preemptFor preempts the running job of the lowest priority below priority,
+// the last started of them, and returns its id. Nothing is preempted while a
+// preempted job has not given its slot back.
+func (s *scheduler) preemptFor(priority int) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		victimID string
+		victim   *slot
+	)
+
+	for id, sl := range s.running {
+		if sl.preempted {
+			return "", false
+		}
+
+		if !sl.preemptible || sl.priority >= priority {
+			continue
+		}
+
+		if victim == nil || sl.priority < victim.priority ||
+			(sl.priority == victim.priority && sl.started.After(victim.started)) {
+			victimID, victim = id, sl
+		}
+	}
+
+	if victim == nil {
+		return "", false
+	}
+
+	victim.preempted = true
+	close(victim.preempt)
+
+	return victimID, true
+}
+
+// shutdown preempts all the running jobs, so they save their pending work
+// and are resumed when the server starts again.
+func (s *scheduler) shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopping = true
+
+	for _, sl := range s.running {
+		if !sl.preempted {
+			sl.preempted = true
+			close(sl.preempt)
+		}
+	}
+}
+
+// isStopping reports whether the server shuts down.
+func (s *scheduler) isStopping() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopping
+}
@@ -0,0 +1,121 @@
+package webrunner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+// run starts jobs on s that run until the test ends.
+func run(t *testing.T, s *scheduler, jobs ...*web.Job) []string {
+	t.Helper()
+
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	return s.start(jobs, func(*web.Job) { <-release })
+}
+
+func newSchedJob(id string, priority, share int) *web.Job {
+	return &web.Job{ID: id, Data: web.JobData{Priority: priority, Share: share}}
+}
+
+func TestSchedulerFraction(t *testing.T) {
+	var none *scheduler
+
+	require.InDelta(t, 1, none.fraction("a"), 1e-9)
+
+	s := newScheduler(4)
+
+	// a job alone gets all the concurrency
+	rebalanced := run(t, s, newSchedJob("a", 0, 1))
+	require.Empty(t, rebalanced)
+	require.InDelta(t, 1, s.fraction("a"), 1e-9)
+	require.Equal(t, 3, s.free())
+
+	// the jobs started later split it by their shares, the first job resumes
+	// with its new share
+	rebalanced = run(t, s, newSchedJob("b", 0, 2), newSchedJob("c", 0, 1))
+	require.Equal(t, []string{"a"}, rebalanced)
+	require.True(t, s.wasPreempted("a"))
+	require.InDelta(t, 0.5, s.fraction("b"), 1e-9)
+	require.InDelta(t, 0.25, s.fraction("c"), 1e-9)
+
+	require.InDelta(t, 1, s.fraction("unknown"), 1e-9)
+}
+
+func TestSchedulerFastModeKeepsShare(t *testing.T) {
+	s := newScheduler(2)
+
+	fast := newSchedJob("fast", 0, 1)
+	fast.Data.FastMode = true
+
+	run(t, s, fast)
+
+	rebalanced := run(t, s, newSchedJob("b", 0, 1))
+	require.Empty(t, rebalanced)
+	require.False(t, s.wasPreempted("fast"))
+	require.InDelta(t, 1, s.fraction("fast"), 1e-9)
+	require.InDelta(t, 0.5, s.fraction("b"), 1e-9)
+}
+
+func TestSchedulerPreemptFor(t *testing.T) {
+	s := newScheduler(3)
+
+	fast := newSchedJob("fast", 0, 1)
+	fast.Data.FastMode = true
+
+	run(t, s, fast, newSchedJob("low", 1, 1), newSchedJob("high", 5, 1))
+	require.Equal(t, 0, s.free())
+
+	// nothing runs below priority 1, and fast mode jobs are never preempted
+	_, ok := s.preemptFor(1)
+	require.False(t, ok)
+
+	id, ok := s.preemptFor(3)
+	require.True(t, ok)
+	require.Equal(t, "low", id)
+	require.True(t, s.wasPreempted("low"))
+
+	select {
+	case <-s.preemption("low"):
+	default:
+		t.Fatal("the preempted job was not told")
+	}
+
+	// nothing else is preempted until the preempted job gives its slot back
+	_, ok = s.preemptFor(10)
+	require.False(t, ok)
+
+	require.Nil(t, s.preemption("unknown"))
+}
+
+func TestSchedulerPreemptLastStarted(t *testing.T) {
+	s := newScheduler(2)
+
+	run(t, s, newSchedJob("first", 1, 1), newSchedJob("second", 1, 1))
+
+	s.mu.Lock()
+	s.running["first"].started = s.running["second"].started.Add(-time.Minute)
+	s.mu.Unlock()
+
+	id, ok := s.preemptFor(5)
+	require.True(t, ok)
+	require.Equal(t, "second", id)
+	require.False(t, s.wasPreempted("first"))
+}
+
+func TestSchedulerShutdown(t *testing.T) {
+	s := newScheduler(2)
+
+	run(t, s, newSchedJob("a", 0, 1), newSchedJob("b", 0, 1))
+
+	s.shutdown()
+
+	require.True(t, s.isStopping())
+	require.True(t, s.wasPreempted("a"))
+	require.True(t, s.wasPreempted("b"))
+}
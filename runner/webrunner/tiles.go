@@ -0,0 +1,219 @@
+package webrunner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+// tileTracker follows the jobs of every tile of a bbox job. A tile is done
+// when all the jobs started from its seed jobs are completed, in the sense
+// of the frontier, including the searches of the smaller tiles it was split in. Jobs that fail or searches without results never
+// complete, so the tiles left are marked done when the crawl finishes.
+//
+// All the methods can be called on a nil tracker and do nothing.
+type tileTracker struct {
+	mu          sync.Mutex
+	owner       map[string]string
+	open        map[string]struct{}
+	outstanding map[string]int
+	tiles       map[string]*web.TileProgress
+	dirty       map[string]struct{}
+}
+
+func newTileTracker(tiles []web.TileProgress) *tileTracker {
+	t := tileTracker{
+		owner:       make(map[string]string),
+		open:        make(map[string]struct{}),
+		outstanding: make(map[string]int),
+		tiles:       make(map[string]*web.TileProgress, len(tiles)),
+		dirty:       make(map[string]struct{}),
+	}
+
+	for i := range tiles {
+		tile := tiles[i]
+		t.tiles[tile.ID] = &tile
+	}
+
+	return &t
+}
+
+// addSeeds registers the seed jobs of a tile.
+func (t *tileTracker) addSeeds(tileID string, jobs []scrapemate.IJob) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, job := range jobs {
+		t.owner[job.GetID()] = tileID
+		t.open[job.GetID()] = struct{}{}
+		t.outstanding[tileID]++
+	}
+}
+
+// pushed registers a job pushed by a job of a tile and completes its parent.
+func (t *tileTracker) pushed(job scrapemate.IJob) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tileID, ok := t.owner[job.GetParentID()]
+	if !ok {
+		return
+	}
+
+	t.owner[job.GetID()] = tileID
+	t.open[job.GetID()] = struct{}{}
+	t.outstanding[tileID]++
+
+	// the result of the parent may be written before its jobs are pushed,
+	// and a search that pushes searches split its tile in smaller ones
+	if tile, ok := t.tiles[tileID]; ok {
+		state := tile.State
+
+		if _, split := job.(*gmaps.SearchJob); split {
+			state = web.TileStateSubdivided
+		} else if state == web.TileStateDone {
+			state = web.TileStatePending
+		}
+
+		if state != tile.State {
+			tile.State = state
+			t.touch(tile)
+		}
+	}
+
+	t.complete(job.GetParentID())
+}
+
+// completed marks a job as completed.
+func (t *tileTracker) completed(id string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.complete(id)
+}
+
+// addEntries adds the entries of a result to the tile of the job.
+func (t *tileTracker) addEntries(id string, n int) {
+	if t == nil || n == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tile, ok := t.tiles[t.owner[id]]
+	if !ok {
+		return
+	}
+
+	tile.Entries += n
+	t.touch(tile)
+}
+
+// finish marks all the tiles that are not done as done.
+func (t *tileTracker) finish() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tile := range t.tiles {
+		if tile.State != web.TileStateDone {
+			tile.State = web.TileStateDone
+			t.touch(tile)
+		}
+	}
+}
+
+// changes returns the tiles changed since the last call.
+func (t *tileTracker) changes() []web.TileProgress {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ans := make([]web.TileProgress, 0, len(t.dirty))
+
+	for id := range t.dirty {
+		ans = append(ans, *t.tiles[id])
+	}
+
+	clear(t.dirty)
+
+	return ans
+}
+
+// flush saves the changed tiles. They are saved again on the next flush when it fails.
+func (t *tileTracker) flush(ctx context.Context, svc *web.Service, jobID string) error {
+	changed := t.changes()
+	if len(changed) == 0 {
+		return nil
+	}
+
+	err := svc.SaveTiles(ctx, jobID, changed)
+	if err != nil {
+		t.mu.Lock()
+		for i := range changed {
+			t.dirty[changed[i].ID] = struct{}{}
+		}
+		t.mu.Unlock()
+	}
+
+	return err
+}
+
+func (t *tileTracker) complete(id string) {
+	if _, ok := t.open[id]; !ok {
+		return
+	}
+
+	delete(t.open, id)
+
+	tileID := t.owner[id]
+
+	t.outstanding[tileID]--
+
+	tile, ok := t.tiles[tileID]
+	if ok && t.outstanding[tileID] == 0 && tile.State != web.TileStateDone {
+		tile.State = web.TileStateDone
+		t.touch(tile)
+	}
+}
+
+func (t *tileTracker) touch(tile *web.TileProgress) {
+	tile.UpdatedAt = time.Now().UTC()
+	t.dirty[tile.ID] = struct{}{}
+}
+
+// countEntries returns the number of entries of a result.
+func countEntries(data any) int {
+	switch v := data.(type) {
+	case *gmaps.Entry:
+		return 1
+	case []*gmaps.Entry:
+		return len(v)
+	default:
+		return 0
+	}
+}
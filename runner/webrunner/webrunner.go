@@ -1,33 +1,74 @@
 package webrunner
 
 import (
+	"bytes"
+	"cmp"
 	"context"
-	"encoding/csv"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/gosom/google-maps-scraper/alerting"
+	"github.com/gosom/google-maps-scraper/autotune"
+	"github.com/gosom/google-maps-scraper/captcha"
 	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/geocoder"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/profile"
 	"github.com/gosom/google-maps-scraper/runner"
 	"github.com/gosom/google-maps-scraper/tlmt"
+	"github.com/gosom/google-maps-scraper/translate"
 	"github.com/gosom/google-maps-scraper/web"
+	"github.com/gosom/google-maps-scraper/web/postgres"
 	"github.com/gosom/google-maps-scraper/web/sqlite"
 	"github.com/gosom/scrapemate"
-	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
 	"github.com/gosom/scrapemate/scrapemateapp"
 	"golang.org/x/sync/errgroup"
 )
 
+// shutdownSaveTime is the time the jobs get to save their pending work on
+// shutdown, after -shutdown-timeout.
+const shutdownSaveTime = 15 * time.Second
+
 type webrunner struct {
 	srv *web.Server
 	svc *web.Service
 	cfg *runner.Config
+
+	geocodeCache geocoder.Cache
+	// hostLimiter, retryer, contacts, guesser and verifier are shared by the email crawl of all the jobs
+	hostLimiter *gmaps.HostLimiter
+	retryer     *gmaps.Retryer
+	contacts    *gmaps.ContactFinder
+	guesser     *gmaps.EmailGuesser
+	verifier    *gmaps.EmailVerifier
+	// breakers are shared by all the jobs, a host that fails for one fails for all
+	breakers *gmaps.CircuitBreakers
+	// captchaSolver is used by the jobs that enable solve_captchas, nil without -captcha-solver
+	captchaSolver captcha.Solver
+	// translator is used by the jobs that set translate_to, nil without -translate-reviews
+	translator translate.Translator
+	// alerts notifies the failed and blocked jobs and raises the alerts of
+	// the rules over the metrics, nil without -alerts-config
+	alerts *alerting.Alerter
+
+	// sched runs the jobs, up to -max-jobs at a time
+	sched *scheduler
+
+	// db is the database of -dsn the postgres writer of the jobs writes to,
+	// opened by the first of them
+	dbMu sync.Mutex
+	db   *sql.DB
 }
 
 func New(cfg *runner.Config) (runner.Runner, error) {
@@ -39,34 +80,252 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		return nil, err
 	}
 
+	cfg.RegisterHealthChecks()
+
 	const dbfname = "jobs.db"
 
-	dbpath := filepath.Join(cfg.DataFolder, dbfname)
+	var (
+		repo web.JobRepository
+		err  error
+	)
+
+	if cfg.WebDsn != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		repo, err = postgres.New(ctx, cfg.WebDsn)
+
+		cancel()
+	} else {
+		repo, err = sqlite.New(filepath.Join(cfg.DataFolder, dbfname))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var svcOpts []web.ServiceOption
+
+	if store, ok := cfg.S3Uploader.(web.ObjectStore); ok && cfg.S3Bucket != "" {
+		svcOpts = append(svcOpts, web.WithObjectStore(store, cfg.S3Bucket, cfg.S3Prefix))
+	}
+
+	if metricsRepo, ok := repo.(web.MetricsRepository); ok {
+		svcOpts = append(svcOpts, web.WithMetrics(metricsRepo, cfg.MetricsInterval, cfg.MetricsRetention))
+	}
+
+	if tilesRepo, ok := repo.(web.TileRepository); ok {
+		svcOpts = append(svcOpts, web.WithTiles(tilesRepo))
+	}
+
+	if checkpointRepo, ok := repo.(web.CheckpointRepository); ok {
+		svcOpts = append(svcOpts, web.WithCheckpoints(checkpointRepo, cfg.Resume))
+	}
+
+	if scheduleRepo, ok := repo.(web.ScheduleRepository); ok {
+		svcOpts = append(svcOpts, web.WithSchedules(scheduleRepo))
+	}
+
+	if templateRepo, ok := repo.(web.TemplateRepository); ok {
+		svcOpts = append(svcOpts, web.WithTemplates(templateRepo))
+	}
+
+	if deadLetterRepo, ok := repo.(web.DeadLetterRepository); ok {
+		svcOpts = append(svcOpts, web.WithDeadLetters(deadLetterRepo))
+	}
+
+	if userRepo, ok := repo.(web.UserRepository); ok {
+		svcOpts = append(svcOpts, web.WithUsers(userRepo))
+	}
+
+	if resultRepo, ok := repo.(web.ResultRepository); ok {
+		svcOpts = append(svcOpts, web.WithResults(resultRepo))
+	}
+
+	if types := writerTypes(cfg); len(types) > 0 {
+		svcOpts = append(svcOpts, web.WithWriters(types))
+	}
+
+	if names := runner.WriterNames(); len(names) > 0 {
+		svcOpts = append(svcOpts, web.WithPluginWriters(names))
+	}
+
+	if cfg.PublicURL != "" {
+		svcOpts = append(svcOpts, web.WithPublicURL(cfg.PublicURL))
+	}
+
+	web.AllowPrivateWebhooks(cfg.WebhookAllowPrivate)
+
+	svcOpts = append(svcOpts, web.WithRetention(web.RetentionPolicy{
+		MaxAge:       time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+		MaxDataBytes: cfg.MaxDataSizeMB << 20,
+	}))
+
+	if placeRepo, ok := repo.(web.PlaceRepository); ok && cfg.GlobalPlaces {
+		svcOpts = append(svcOpts, web.WithPlaces(placeRepo))
+	}
+
+	if auditRepo, ok := repo.(web.AuditRepository); ok {
+		svcOpts = append(svcOpts, web.WithAudit(auditRepo))
+	}
+
+	if keyRepo, ok := repo.(web.APIKeyRepository); ok {
+		svcOpts = append(svcOpts, web.WithAPIKeys(keyRepo, web.APIKeyLimits{
+			RateLimit:     cfg.APIKeyRateLimit,
+			MonthlyPlaces: cfg.APIKeyMonthlyPlaces,
+		}))
+	}
+
+	// the places of jobs and of the geocode endpoint are geocoded once
+	cache, _ := repo.(geocoder.Cache)
+	if cache != nil {
+		cfg.Geocoder, err = runner.NewGeocoder(cfg.GeocoderProviders(), nil, cfg.GeocoderURL, cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Geocoder != nil {
+		svcOpts = append(svcOpts, web.WithGeocoder(cfg.Geocoder))
+	}
+
+	if len(cfg.ProxyGroups) > 0 {
+		svcOpts = append(svcOpts, web.WithProxyGroups(cfg.ProxyGroupNames()))
+	}
+
+	if cfg.CaptchaSolver != "" {
+		svcOpts = append(svcOpts, web.WithCaptchaSolving())
+	}
+
+	if cfg.TranslateReviews != "" {
+		svcOpts = append(svcOpts, web.WithTranslation())
+	}
 
-	repo, err := sqlite.New(dbpath)
+	alerts, err := cfg.NewAlerter(alertMetrics)
 	if err != nil {
 		return nil, err
 	}
 
-	svc := web.NewService(repo, cfg.DataFolder)
+	if alerts != nil {
+		interval := cmp.Or(cfg.MetricsInterval, time.Minute)
+
+		svcOpts = append(svcOpts, web.WithMetricsObserver(func(p web.MetricsPoint) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			if err := alerts.Evaluate(ctx, p.Time, alertSample(&p, interval)); err != nil {
+				log.Printf("failed to send alerts: %v", err)
+			}
+		}))
+	}
+
+	svc := web.NewService(repo, cfg.DataFolder, svcOpts...)
+
+	var srvOpts []web.ServerOption
+
+	if cfg.SlackSigningSecret != "" {
+		srvOpts = append(srvOpts, web.WithSlackSigningSecret(cfg.SlackSigningSecret))
+	}
+
+	if cfg.DebugToken != "" {
+		srvOpts = append(srvOpts, web.WithDebug(cfg.DebugToken, gmaps.BrowserStats))
+	}
+
+	if cfg.Auth {
+		authOpts, err := setupAuth(svc, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		srvOpts = append(srvOpts, authOpts...)
+	}
 
-	srv, err := web.New(svc, cfg.Addr)
+	srv, err := web.New(svc, cfg.Addr, srvOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	ans := webrunner{
-		srv: srv,
-		svc: svc,
-		cfg: cfg,
+		alerts:        alerts,
+		srv:           srv,
+		svc:           svc,
+		cfg:           cfg,
+		geocodeCache:  cache,
+		sched:         newScheduler(cfg.MaxJobs),
+		hostLimiter:   cfg.NewHostLimiter(),
+		retryer:       cfg.NewRetryer(),
+		contacts:      cfg.NewContactFinder(),
+		guesser:       cfg.NewEmailGuesser(),
+		verifier:      cfg.NewEmailVerifier(),
+		captchaSolver: cfg.NewCaptchaSolver(),
+		translator:    cfg.NewTranslator(),
 	}
 
+	recorder := metrics.NewRecorder("web", "")
+
+	ans.breakers = cfg.NewCircuitBreakers(func(host, state string) {
+		log.Printf("the circuit of %s is %s", host, state)
+		svc.Metrics().CircuitChanged(host, state)
+		recorder.Circuit(host, state)
+	})
+
 	return &ans, nil
 }
 
+// setupAuth creates or resets the admin of -admin-password and returns the
+// server options of -auth.
+func setupAuth(svc *web.Service, cfg *runner.Config) ([]web.ServerOption, error) {
+	ctx := context.Background()
+
+	if cfg.AdminPassword != "" {
+		if err := svc.EnsureAdmin(ctx, cfg.AdminUser, cfg.AdminPassword); err != nil {
+			return nil, fmt.Errorf("failed to create the admin %s: %w", cfg.AdminUser, err)
+		}
+	}
+
+	users, err := svc.Users(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 && cfg.OIDCIssuer == "" {
+		return nil, errors.New("-auth needs -admin-password or -oidc-issuer to sign in the first user")
+	}
+
+	ans := []web.ServerOption{web.WithAuth()}
+
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "" {
+			return nil, errors.New("-oidc-issuer needs -oidc-client-id and -oidc-redirect-url")
+		}
+
+		var admins []string
+
+		for _, email := range strings.Split(cfg.OIDCAdmins, ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				admins = append(admins, email)
+			}
+		}
+
+		ans = append(ans, web.WithOIDC(web.OIDCConfig{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Admins:       admins,
+		}))
+	}
+
+	return ans, nil
+}
+
 func (w *webrunner) Run(ctx context.Context) error {
+	if err := w.svc.RequeueInterrupted(ctx); err != nil {
+		return err
+	}
+
 	egroup, ctx := errgroup.WithContext(ctx)
 
+	w.cfg.WatchBrowsers(ctx, w.alerts.Notify)
+
 	egroup.Go(func() error {
 		return w.work(ctx)
 	})
@@ -75,10 +334,35 @@ func (w *webrunner) Run(ctx context.Context) error {
 		return w.srv.Start(ctx)
 	})
 
+	egroup.Go(func() error {
+		return w.svc.RunMetrics(ctx)
+	})
+
+	egroup.Go(func() error {
+		return w.svc.RunSchedules(ctx)
+	})
+
+	egroup.Go(func() error {
+		return w.svc.RunRetention(ctx)
+	})
+
+	if w.alerts != nil {
+		egroup.Go(func() error {
+			return w.alerts.Watch(ctx, w.cfg.AlertsConfig, alertsReloadInterval)
+		})
+	}
+
 	return egroup.Wait()
 }
 
 func (w *webrunner) Close(context.Context) error {
+	w.dbMu.Lock()
+	defer w.dbMu.Unlock()
+
+	if w.db != nil {
+		return w.db.Close()
+	}
+
 	return nil
 }
 
@@ -86,218 +370,1392 @@ func (w *webrunner) work(ctx context.Context) error {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
+	renew := time.NewTicker(web.ClaimLease / 5)
+	defer renew.Stop()
+
+	// the jobs outlive ctx, they are stopped by shutdown
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	defer w.shutdown(cancelJobs)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			jobs, err := w.svc.SelectPending(ctx)
-			if err != nil {
+			if err := w.schedule(jobCtx); err != nil {
 				return err
 			}
-
-			for i := range jobs {
-				select {
-				case <-ctx.Done():
-					return nil
-				default:
-					t0 := time.Now().UTC()
-					if err := w.scrapeJob(ctx, &jobs[i]); err != nil {
-						params := map[string]any{
-							"job_count": len(jobs[i].Data.Keywords),
-							"duration":  time.Now().UTC().Sub(t0).String(),
-							"error":     err.Error(),
-						}
-
-						evt := tlmt.NewEvent("web_runner", params)
-
-						_ = runner.Telemetry().Send(ctx, evt)
-
-						log.Printf("error scraping job %s: %v", jobs[i].ID, err)
-					} else {
-						params := map[string]any{
-							"job_count": len(jobs[i].Data.Keywords),
-							"duration":  time.Now().UTC().Sub(t0).String(),
-						}
-
-						_ = runner.Telemetry().Send(ctx, tlmt.NewEvent("web_runner", params))
-
-						log.Printf("job %s scraped successfully", jobs[i].ID)
-					}
-				}
+		case <-renew.C:
+			// the other servers claim the jobs whose claim is not renewed
+			if err := w.svc.RenewClaims(ctx, w.sched.ids()); err != nil {
+				log.Printf("failed to renew the claims of the running jobs: %v", err)
 			}
 		}
 	}
 }
 
-func (w *webrunner) scrapeJob(ctx context.Context, job *web.Job) error {
-	job.Status = web.StatusWorking
+// shutdown stops the running jobs: they finish the place and email jobs in
+// flight for -shutdown-timeout at most, flush their results, save their
+// pending work, dedup set and tiles, and are pending again so they resume
+// when the server starts. The jobs that still run shutdownSaveTime later
+// are canceled, they resume from their checkpoint when they have one.
+func (w *webrunner) shutdown(cancelJobs context.CancelFunc) {
+	w.sched.shutdown()
 
-	err := w.svc.Update(ctx, job)
-	if err != nil {
-		return err
+	timeout := w.cfg.ShutdownTimeout + shutdownSaveTime
+
+	if w.sched.waitTimeout(timeout) {
+		return
 	}
 
-	if len(job.Data.Keywords) == 0 {
-		job.Status = web.StatusFailed
+	log.Printf("the jobs did not stop within %s, canceling them", timeout)
 
-		return w.svc.Update(ctx, job)
-	}
+	cancelJobs()
+
+	w.sched.wait()
+}
 
-	outpath := filepath.Join(w.cfg.DataFolder, job.ID+".csv")
+// schedule starts the pending jobs, the highest priority first, while there
+// are free slots. When there are none, the most urgent pending job preempts
+// a running job of a lower priority, which is pending again once it saved
+// its pending work.
+func (w *webrunner) schedule(ctx context.Context) error {
+	if w.sched.free() == 0 {
+		jobs, err := w.svc.Pending(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := range jobs {
+			if w.sched.isRunning(jobs[i].ID) {
+				continue
+			}
+
+			if id, ok := w.sched.preemptFor(jobs[i].Data.Priority); ok {
+				w.svc.Logf(id, "job %s is preempted by job %s of priority %d", id, jobs[i].ID, jobs[i].Data.Priority)
+			}
+
+			break
+		}
 
-	outfile, err := os.Create(outpath)
+		return nil
+	}
+
+	jobs, err := w.svc.SelectPending(ctx)
 	if err != nil {
 		return err
 	}
 
-	defer func() {
-		_ = outfile.Close()
-	}()
+	var starting []*web.Job
 
-	mate, err := w.setupMate(ctx, outfile, job)
-	if err != nil {
-		job.Status = web.StatusFailed
+	for i := range jobs {
+		if len(starting) == w.sched.free() {
+			break
+		}
 
-		err2 := w.svc.Update(ctx, job)
-		if err2 != nil {
-			log.Printf("failed to update job status: %v", err2)
+		if w.sched.isRunning(jobs[i].ID) {
+			continue
 		}
 
-		return err
+		starting = append(starting, &jobs[i])
 	}
 
-	defer mate.Close()
+	if len(starting) == 0 {
+		return nil
+	}
 
-	var coords string
-	if job.Data.Lat != "" && job.Data.Lon != "" {
-		coords = job.Data.Lat + "," + job.Data.Lon
+	rebalanced := w.sched.start(starting, func(job *web.Job) {
+		w.runJob(ctx, job)
+	})
+
+	for _, id := range rebalanced {
+		w.svc.Logf(id, "job %s is paused to resume with its share of the concurrency with the %d jobs started", id, len(starting))
 	}
 
-	dedup := deduper.New()
-	exitMonitor := exiter.New()
+	return nil
+}
 
-	seedJobs, err := runner.CreateSeedJobs(
-		job.Data.FastMode,
-		job.Data.Lang,
-		strings.NewReader(strings.Join(job.Data.Keywords, "\n")),
-		job.Data.Depth,
-		job.Data.Email,
-		coords,
-		job.Data.Zoom,
-		func() float64 {
-			if job.Data.Radius <= 0 {
-				return 10000 // 10 km
-			}
+// runJob scrapes job and releases the jobs waiting for it.
+func (w *webrunner) runJob(ctx context.Context, job *web.Job) {
+	t0 := time.Now().UTC()
+	if err := w.scrapeJob(ctx, job); err != nil {
+		params := map[string]any{
+			"job_count": len(job.Data.Keywords),
+			"duration":  time.Now().UTC().Sub(t0).String(),
+			"error":     err.Error(),
+		}
 
-			return float64(job.Data.Radius)
-		}(),
-		dedup,
-		exitMonitor,
-		w.cfg.ExtraReviews,
-		w.cfg.UseCroxy,
-	)
-	if err != nil {
-		err2 := w.svc.Update(ctx, job)
-		if err2 != nil {
-			log.Printf("failed to update job status: %v", err2)
+		evt := tlmt.NewEvent("web_runner", params)
+
+		_ = runner.Telemetry().Send(ctx, evt)
+
+		w.svc.Logf(job.ID, "error scraping job %s: %v", job.ID, err)
+	} else {
+		params := map[string]any{
+			"job_count": len(job.Data.Keywords),
+			"duration":  time.Now().UTC().Sub(t0).String(),
 		}
 
-		return err
+		_ = runner.Telemetry().Send(ctx, tlmt.NewEvent("web_runner", params))
+
+		w.svc.Logf(job.ID, "job %s scraped successfully", job.ID)
 	}
 
-	if len(seedJobs) > 0 {
-		exitMonitor.SetSeedCount(len(seedJobs))
+	if err := w.svc.ReleaseDependents(ctx, job); err != nil {
+		w.svc.Logf(job.ID, "failed to release the jobs waiting for job %s: %v", job.ID, err)
+	}
+}
 
-		allowedSeconds := max(60, len(seedJobs)*10*job.Data.Depth/50+120)
+// jobRun is the state a run of a job starts from.
+type jobRun struct {
+	outpath      string
+	frontierPath string
+	storePath    string
+	dedupPath    string
+	// paused is true when the job was paused with its pending work saved
+	paused bool
+	// resuming is true when the job continues from its pending work, its
+	// checkpoint or the places requeued from its dead letters
+	resuming bool
+	// continuing is true when a bbox job that was interrupted continues with
+	// the tiles that are not done
+	continuing bool
+	checkpoint []byte
+	requeued   []web.DeadLetter
+	tiles      []web.TileProgress
+	// coords, zoom and radius are the center of the search
+	coords string
+	zoom   int
+	radius float64
+}
 
-		if job.Data.MaxTime > 0 {
-			if job.Data.MaxTime.Seconds() < 180 {
-				allowedSeconds = 180
-			} else {
-				allowedSeconds = int(job.Data.MaxTime.Seconds())
-			}
-		}
+func (w *webrunner) scrapeJob(ctx context.Context, job *web.Job) error {
+	counter := &metricsWriter{
+		metrics:  w.svc.Metrics(),
+		recorder: metrics.NewRecorder("web", job.ID),
+		onPlaces: func(total int64) {
+			w.svc.Publish(web.JobEvent{JobID: job.ID, Type: web.EventEntries, Data: total})
+		},
+	}
 
-		log.Printf("running job %s with %d seed jobs and %d allowed seconds", job.ID, len(seedJobs), allowedSeconds)
+	defer func() {
+		w.svc.Metrics().JobFinished(job.Status, int(counter.places.Load()))
+		counter.recorder.JobFinished(job.Status)
+		w.alertJob(job, int(counter.places.Load()))
+	}()
 
-		mateCtx, cancel := context.WithTimeout(ctx, time.Duration(allowedSeconds)*time.Second)
-		defer cancel()
+	job.Status = web.StatusWorking
 
-		exitMonitor.SetCancelFunc(cancel)
+	// the limits of the profile are saved with the job
+	settings := w.settings(job)
+	job.Data.Depth = settings.Depth
+	job.Data.Email = settings.Email
 
-		go exitMonitor.Run(mateCtx)
+	for _, warning := range profile.Warnings(settings) {
+		w.svc.Logf(job.ID, "job %s: warning: %s", job.ID, warning)
+	}
 
-		err = mate.Start(mateCtx, seedJobs...)
-		if err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
-			cancel()
+	if err := w.svc.Update(ctx, job); err != nil {
+		return err
+	}
 
-			err2 := w.svc.Update(ctx, job)
-			if err2 != nil {
-				log.Printf("failed to update job status: %v", err2)
-			}
+	// from now on the job is working, it fails when it cannot run
 
-			return err
-		}
+	if len(job.Data.Keywords) == 0 && job.Data.Transform == "" {
+		return w.failJob(ctx, job, fmt.Errorf("job %s has no keywords", job.ID))
+	}
 
-		cancel()
+	run, err := w.prepareRun(ctx, job)
+	if err != nil {
+		return w.failJob(ctx, job, err)
 	}
 
-	mate.Close()
+	outfile, writer, seen, err := w.openResults(ctx, job, run)
+	if err != nil {
+		return w.failJob(ctx, job, err)
+	}
 
-	job.Status = web.StatusOK
+	defer func() {
+		_ = outfile.Close()
+	}()
 
-	return w.svc.Update(ctx, job)
-}
+	fr := newFrontier()
 
-func (w *webrunner) setupMate(_ context.Context, writer io.Writer, job *web.Job) (*scrapemateapp.ScrapemateApp, error) {
-	opts := []func(*scrapemateapp.Config) error{
-		scrapemateapp.WithConcurrency(w.cfg.Concurrency),
-		scrapemateapp.WithExitOnInactivity(time.Minute * 3),
+	mate, reviewStats, err := w.setupMate(ctx, writer, job, fr, counter, seen)
+	if err != nil {
+		return w.failJob(ctx, job, err)
 	}
 
-	if !job.Data.FastMode {
-		opts = append(opts,
-			scrapemateapp.WithJS(scrapemateapp.DisableImages()),
-		)
-	} else {
-		opts = append(opts,
-			scrapemateapp.WithStealth("firefox"),
-		)
+	defer mate.Close()
+
+	if err := w.locate(ctx, job, run); err != nil {
+		return w.failJob(ctx, job, err)
 	}
 
-	hasProxy := false
+	dedup, err := w.loadDedup(job, run)
+	if err != nil {
+		return w.failJob(ctx, job, err)
+	}
 
-	if len(w.cfg.Proxies) > 0 {
-		opts = append(opts, scrapemateapp.WithProxies(w.cfg.Proxies))
-		hasProxy = true
-	} else if len(job.Data.Proxies) > 0 {
-		opts = append(opts,
-			scrapemateapp.WithProxies(job.Data.Proxies),
-		)
-		hasProxy = true
+	untrack := w.svc.TrackDedup(dedup)
+	defer untrack()
+
+	exitMonitor := exiter.New()
+
+	defer w.svc.TrackProgress(job.ID, exitMonitor)()
+
+	extras, err := requestExtras(job, w.cfg.RequestExtras)
+	if err != nil {
+		return w.failJob(ctx, job, err)
 	}
 
-	if !w.cfg.DisablePageReuse {
-		opts = append(opts,
-			scrapemateapp.WithPageReuseLimit(2),
-			scrapemateapp.WithPageReuseLimit(200),
-		)
+	seedJobs, err := w.createSeedJobs(ctx, job, run, fr, settings, dedup, exitMonitor, extras)
+	if err != nil {
+		return w.failJob(ctx, job, err)
 	}
 
-	log.Printf("job %s has proxy: %v", job.ID, hasProxy)
+	noise, tuner, err := w.configureSeedJobs(job, seedJobs, settings, fr, counter)
+	if err != nil {
+		return w.failJob(ctx, job, err)
+	}
 
-	csvWriter := csvwriter.NewCsvWriter(csv.NewWriter(writer))
+	if len(seedJobs) > 0 {
+		// it can be resumed from its last checkpoint via the API
+		if err := w.startMate(ctx, job, run, mate, seedJobs, fr, exitMonitor, tuner); err != nil {
+			return w.failJob(ctx, job, err)
+		}
+	}
 
-	writers := []scrapemate.ResultWriter{csvWriter}
+	mate.Close()
 
-	matecfg, err := scrapemateapp.NewConfig(
-		writers,
-		opts...,
-	)
+	return w.finishJob(ctx, job, run, fr, dedup, reviewStats, noise)
+}
+
+// failJob saves job as failed and returns err. A job that stops after it was
+// saved as working fails through it, so it does not stay working.
+func (w *webrunner) failJob(ctx context.Context, job *web.Job, err error) error {
+	job.Status = web.StatusFailed
+
+	// the status is saved even when the shutdown cancels the job meanwhile
+	if err2 := w.svc.Update(context.WithoutCancel(ctx), job); err2 != nil {
+		log.Printf("failed to update job status: %v", err2)
+	}
+
+	return err
+}
+
+// prepareRun reads what the run of job continues from: its pending work, its
+// checkpoint, the places requeued from its dead letters and its tiles.
+func (w *webrunner) prepareRun(ctx context.Context, job *web.Job) (*jobRun, error) {
+	run := &jobRun{
+		outpath:      filepath.Join(w.cfg.DataFolder, job.ID+".csv"),
+		frontierPath: filepath.Join(w.cfg.DataFolder, job.ID+".frontier"),
+		storePath:    filepath.Join(w.cfg.DataFolder, job.ID+".store"),
+	}
+
+	_, err := os.Stat(run.frontierPath)
+	run.paused = err == nil
+	run.resuming = run.paused
+
+	// a job that was interrupted or failed continues from its last checkpoint
+	if !run.resuming {
+		run.checkpoint, err = w.svc.Checkpoint(ctx, job.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		run.resuming = run.checkpoint != nil
+	}
+
+	// the places requeued from the dead letters of the job run again, their
+	// results are added to the ones of the job
+	run.requeued, err = w.svc.RequeuedDeadLetters(ctx, job.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return scrapemateapp.NewScrapeMateApp(matecfg)
+	run.resuming = run.resuming || len(run.requeued) > 0
+
+	if job.Data.BBox != "" {
+		run.tiles, err = w.svc.Tiles(ctx, job.ID)
+		if err != nil && !errors.Is(err, web.ErrTilesNotSupported) {
+			return nil, err
+		}
+	}
+
+	// a bbox job that was interrupted continues with the tiles that are not done
+	run.continuing = !run.resuming && len(run.tiles) > 0 && hasData(run.outpath)
+
+	return run, nil
+}
+
+// openResults opens the csv of the results of job, appended to when the run
+// continues, and returns it with the writer of the results and the places
+// already written.
+func (w *webrunner) openResults(ctx context.Context, job *web.Job, run *jobRun) (*os.File, io.Writer, map[string]bool, error) {
+	var (
+		outfile *os.File
+		// seen are the places of the results the run appends to
+		seen map[string]bool
+		err  error
+	)
+
+	if run.resuming || run.continuing {
+		// the run that wrote the results may have stopped in the middle of a row
+		seen, err = runner.RepairCSV(run.outpath, job.Data.CSV.Comma())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to repair the results of job %s: %w", job.ID, err)
+		}
+
+		outfile, err = os.OpenFile(run.outpath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	} else {
+		// the job starts over, so do its results
+		if err := w.svc.ResetResults(ctx, job.ID); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if err := os.Remove(run.storePath); err != nil && !os.IsNotExist(err) {
+			return nil, nil, nil, err
+		}
+
+		outfile, err = os.Create(run.outpath)
+	}
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var writer io.Writer = outfile
+	if (run.resuming || run.continuing) && hasData(run.outpath) {
+		// the header was written before the job was paused or interrupted
+		writer = &skipFirstLineWriter{w: outfile}
+	}
+
+	return outfile, writer, seen, nil
+}
+
+// locate sets the center of the search of job, geocoding its place when it
+// has no coordinates.
+func (w *webrunner) locate(ctx context.Context, job *web.Job, run *jobRun) error {
+	if job.Data.Lat != "" && job.Data.Lon != "" {
+		run.coords = job.Data.Lat + "," + job.Data.Lon
+	}
+
+	run.zoom = job.Data.Zoom
+
+	run.radius = float64(job.Data.Radius)
+	if run.radius <= 0 {
+		run.radius = 10000 // 10 km
+	}
+
+	// the web UI defaults to 0,0 when no center is given
+	if run.resuming || job.Data.Place == "" || (run.coords != "" && run.coords != "0,0") {
+		return nil
+	}
+
+	g, err := w.geocoder(job)
+	if err != nil {
+		return err
+	}
+
+	run.coords, run.zoom, run.radius, err = runner.GeocodePlace(ctx, g, job.Data.Place, run.zoom, float64(job.Data.Radius))
+
+	return err
+}
+
+// loadDedup returns the dedup set of job, with the places of its previous
+// runs when it was saved.
+func (w *webrunner) loadDedup(job *web.Job, run *jobRun) (*deduper.Instrumented, error) {
+	var err error
+
+	run.dedupPath, err = w.svc.DedupPath(job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dedup := deduper.WithStats(deduper.New())
+
+	if _, err := os.Stat(run.dedupPath); err == nil {
+		if err := deduper.ImportFile(dedup, run.dedupPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return dedup, nil
+}
+
+// createSeedJobs returns the jobs job starts with: its pending work when it
+// resumes, else the jobs of its transform, its tiles, its circle or its
+// keywords.
+func (w *webrunner) createSeedJobs(
+	ctx context.Context,
+	job *web.Job,
+	run *jobRun,
+	fr *frontier,
+	settings profile.Settings,
+	dedup deduper.Deduper,
+	exitMonitor exiter.Exiter,
+	extras *gmaps.RequestExtras,
+) ([]scrapemate.IJob, error) {
+	var (
+		seedJobs []scrapemate.IJob
+		err      error
+	)
+
+	switch {
+	case run.resuming:
+		if job.Data.BBox != "" && len(run.tiles) > 0 {
+			// the pending jobs are not linked to their tiles, the tiles are done when the job is
+			fr.tiles = newTileTracker(run.tiles)
+		}
+
+		var data []byte
+
+		switch {
+		case run.checkpoint != nil:
+			w.svc.Logf(job.ID, "job %s resumes from its checkpoint", job.ID)
+
+			data = run.checkpoint
+		case run.paused:
+			data, err = os.ReadFile(run.frontierPath)
+		}
+
+		if err == nil && len(run.requeued) > 0 {
+			w.svc.Logf(job.ID, "job %s runs %d requeued places", job.ID, len(run.requeued))
+
+			data, err = appendDeadLetters(data, run.requeued)
+		}
+
+		if err == nil {
+			seedJobs, err = decodeFrontier(data, dedup, exitMonitor)
+		}
+	case job.Data.Transform != "":
+		seedJobs, err = w.transformSeedJobs(ctx, job, settings, exitMonitor, extras)
+	case job.Data.BBox != "":
+		seedJobs, fr.tiles, err = w.tiledSeedJobs(ctx, job, run.tiles, dedup, exitMonitor, extras)
+	case job.Data.TileRadius > 0:
+		seedJobs, err = w.circleSeedJobs(job, run.coords, run.radius, dedup, exitMonitor, extras)
+	default:
+		fallback := w.cfg.Fallback
+
+		if chain, _ := job.Data.FallbackChain(); len(chain.Steps) > 0 {
+			fallback = chain
+		}
+
+		seedJobs, err = runner.CreateSeedJobs(
+			job.Data.FastMode,
+			job.Data.Lang,
+			job.Data.Region,
+			strings.NewReader(strings.Join(job.Data.Keywords, "\n")),
+			job.Data.Depth,
+			job.Data.Email,
+			run.coords,
+			run.zoom,
+			run.radius,
+			dedup,
+			exitMonitor,
+			settings.ExtraReviews,
+			w.cfg.UseCroxy,
+			extras,
+			fallback,
+		)
+	}
+
+	return seedJobs, err
+}
+
+// configureSeedJobs applies the filters, the enrichments, the stores, the
+// recorders and the guards of job to its seed jobs. It returns the noise
+// filter of the places and the tuner of the delay, nil when they are off.
+func (w *webrunner) configureSeedJobs(
+	job *web.Job,
+	seedJobs []scrapemate.IJob,
+	settings profile.Settings,
+	fr *frontier,
+	counter *metricsWriter,
+) (*gmaps.NoiseFilter, *autotune.Tuner, error) {
+	var noise *gmaps.NoiseFilter
+	if job.Data.NoiseFilter {
+		noise = gmaps.NewNoiseFilter(job.Data.NoiseCategories)
+	}
+
+	runner.ApplyNoiseFilter(seedJobs, noise)
+	runner.ApplyHostLimiter(seedJobs, w.hostLimiter)
+	runner.ApplyRetryer(seedJobs, w.retryer)
+	runner.ApplyCircuitBreakers(seedJobs, w.breakers)
+	runner.ApplyContactFinder(seedJobs, w.contacts)
+	runner.ApplyEmailGuesser(seedJobs, w.guesser)
+	runner.ApplyEmailVerifier(seedJobs, w.verifier)
+
+	enrichers := w.cfg.NewEnrichers()
+	if len(job.Data.Enrichers) > 0 {
+		enrichers, _ = gmaps.LookupEnrichers(job.Data.Enrichers)
+	}
+
+	runner.ApplyEnrichers(seedJobs, enrichers)
+	runner.ApplyMetrics(seedJobs, counter.recorder)
+
+	failures := gmaps.NewFailureLog()
+	runner.ApplyFailureLog(seedJobs, failures)
+
+	fr.failures = failures
+
+	var fingerprints *gmaps.FingerprintRotator
+
+	if job.Data.RotateFingerprint || w.cfg.RotateFingerprint {
+		fingerprints = gmaps.NewFingerprintRotator(job.Data.Lang, w.cfg.FingerprintTimezones)
+		runner.ApplyFingerprints(seedJobs, fingerprints)
+	}
+
+	if err := w.applyStores(job, seedJobs, fingerprints); err != nil {
+		return nil, nil, err
+	}
+
+	var blockOpts []gmaps.BlockGuardOption
+
+	if job.Data.SolveCaptchas && w.captchaSolver != nil {
+		blockOpts = append(blockOpts, gmaps.WithCaptchaSolver(w.captchaSolver, func() {
+			w.svc.Metrics().CaptchaSolved(w.cfg.CaptchaCost)
+		}))
+	}
+
+	hasProxies := job.Data.ProxyGroup != "" || len(w.cfg.Proxies) > 0 || len(job.Data.Proxies) > 0
+
+	runner.ApplyBlockGuard(seedJobs, w.cfg.NewBlockGuard(hasProxies, func() {
+		w.svc.Logf(job.ID, "warning: google served a captcha to job %s", job.ID)
+		w.svc.Metrics().CaptchaServed()
+		counter.recorder.Block()
+	}, blockOpts...))
+
+	// the tuner starts at the delay and adapts it, fast mode jobs load no pages
+	var tuner *autotune.Tuner
+
+	if w.cfg.AutoTune && !job.Data.FastMode {
+		tuner = autotune.New(settings.Concurrency, autotune.WithDelay(settings.Delay))
+		runner.ApplyTuner(seedJobs, tuner)
+	} else {
+		runner.ApplyDelay(seedJobs, settings.Delay)
+	}
+
+	return noise, tuner, nil
+}
+
+// applyStores gives the seed jobs of job the stores of the browser sessions
+// and of the screenshots, and the recorders of the failed pages and of the
+// videos.
+func (w *webrunner) applyStores(job *web.Job, seedJobs []scrapemate.IJob, fingerprints *gmaps.FingerprintRotator) error {
+	// the sessions of the proxies are shared by the jobs, whatever their sticky session
+	proxyKey := runner.ProxyKey(job.Data.ProxyGroup, job.Data.Proxies)
+	if job.Data.ProxyGroup == "" && len(w.cfg.Proxies) > 0 {
+		proxyKey = runner.ProxyKey("", w.cfg.Proxies)
+	}
+
+	sessions, err := w.cfg.NewSessionStore(proxyKey, fingerprints)
+	if err != nil {
+		return err
+	}
+
+	runner.ApplySessions(seedJobs, sessions)
+
+	if job.Data.Screenshots || w.cfg.Screenshots {
+		screenshots, err := w.cfg.NewScreenshotStore(
+			filepath.Join(w.cfg.DataFolder, job.ID, "screenshots"),
+			path.Join(job.Data.OutputPrefix, job.ID, "screenshots"),
+		)
+		if err != nil {
+			return err
+		}
+
+		runner.ApplyScreenshots(seedJobs, screenshots)
+	}
+
+	debug, err := w.cfg.NewDebugRecorder(filepath.Join(w.cfg.DataFolder, job.ID, "debug"))
+	if err != nil {
+		return err
+	}
+
+	runner.ApplyDebugRecorder(seedJobs, debug)
+
+	videos, err := w.cfg.NewVideoRecorder(filepath.Join(w.cfg.DataFolder, job.ID, "videos"))
+	if err != nil {
+		return err
+	}
+
+	runner.ApplyVideoRecorder(seedJobs, videos)
+
+	return nil
+}
+
+// startMate runs the seed jobs of job until they are done, the job is paused
+// or its time is up. It returns the errors of the scraping only.
+func (w *webrunner) startMate(
+	ctx context.Context,
+	job *web.Job,
+	run *jobRun,
+	mate runner.App,
+	seedJobs []scrapemate.IJob,
+	fr *frontier,
+	exitMonitor exiter.Exiter,
+	tuner *autotune.Tuner,
+) error {
+	// the jobs of a transform are places, they are counted when created
+	if !run.resuming && job.Data.Transform == "" {
+		exitMonitor.SetSeedCount(len(seedJobs))
+	}
+
+	allowedSeconds := max(60, len(seedJobs)*10*job.Data.Depth/50+120)
+
+	if job.Data.MaxTime > 0 {
+		if job.Data.MaxTime.Seconds() < 180 {
+			allowedSeconds = 180
+		} else {
+			allowedSeconds = int(job.Data.MaxTime.Seconds())
+		}
+	}
+
+	w.svc.Logf(job.ID, "running job %s with %d seed jobs and %d allowed seconds", job.ID, len(seedJobs), allowedSeconds)
+
+	mateCtx, cancel := context.WithTimeout(ctx, time.Duration(allowedSeconds)*time.Second)
+	defer cancel()
+
+	exitMonitor.SetCancelFunc(cancel)
+
+	go exitMonitor.Run(mateCtx)
+
+	go w.watchPause(mateCtx, cancel, job.ID, fr, w.sched.preemption(job.ID))
+
+	if tuner != nil {
+		go tuner.Run(mateCtx)
+	}
+
+	if fr.tiles != nil {
+		go w.saveTiles(mateCtx, job.ID, fr.tiles)
+	}
+
+	// fast mode jobs cannot be saved
+	if !job.Data.FastMode {
+		go w.saveCheckpoints(mateCtx, job.ID, fr)
+	}
+
+	err := mate.Start(mateCtx, seedJobs...)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
+
+// finishJob saves the outcome of the run of job: its pending work when it is
+// paused or the process is stopping, else its results, its dead letters and
+// its stats.
+func (w *webrunner) finishJob(
+	ctx context.Context,
+	job *web.Job,
+	run *jobRun,
+	fr *frontier,
+	dedup deduper.Deduper,
+	reviewStats *runner.ReviewStatsWriter,
+	noise *gmaps.NoiseFilter,
+) error {
+	checkpointing := w.cfg.Resume && !job.Data.FastMode
+
+	if ctx.Err() != nil && (fr.tiles != nil || checkpointing) {
+		// the process is stopping, the job continues with the pending tiles or
+		// from its checkpoint after a restart.
+		// The dedup set is not saved since it has the places of the pending work,
+		// which would be skipped when they are searched again.
+		if err := fr.tiles.flush(context.Background(), w.svc, job.ID); err != nil {
+			w.svc.Logf(job.ID, "failed to save the tiles of job %s: %v", job.ID, err)
+		}
+
+		if checkpointing {
+			if err := w.checkpoint(context.Background(), job.ID, fr); err != nil && !errors.Is(err, web.ErrCheckpointsNotSupported) {
+				w.svc.Logf(job.ID, "failed to save the checkpoint of job %s: %v", job.ID, err)
+			}
+		}
+
+		return ctx.Err()
+	}
+
+	// the results, the status and the pending work are saved even when the
+	// shutdown cancels the job meanwhile
+	ctx = context.WithoutCancel(ctx)
+
+	// keep the dedup set so the job can be resumed or moved to another machine
+	if err := deduper.ExportFile(dedup, run.dedupPath); err != nil {
+		w.svc.Logf(job.ID, "failed to save the dedup set of job %s: %v", job.ID, err)
+	}
+
+	if err := fr.tiles.flush(ctx, w.svc, job.ID); err != nil {
+		w.svc.Logf(job.ID, "failed to save the tiles of job %s: %v", job.ID, err)
+	}
+
+	if fr.isPaused() && job.Data.FastMode && w.sched.isStopping() {
+		// fast mode jobs cannot be saved, the job starts over
+		w.svc.Logf(job.ID, "job %s is stopped by the shutdown and runs again when the server starts", job.ID)
+
+		job.Status = web.StatusPending
+
+		return w.svc.Update(ctx, job)
+	}
+
+	if fr.isPaused() {
+		if pending := fr.pending(); len(pending) > 0 {
+			if err := saveFrontier(run.frontierPath, pending); err != nil {
+				return w.failJob(ctx, job, fmt.Errorf("failed to save the pending work: %w", err))
+			}
+
+			w.svc.Logf(job.ID, "job %s paused with %d pending jobs", job.ID, len(pending))
+
+			job.Status = web.StatusPaused
+
+			// a preempted job resumes when a slot is free, or when the
+			// server starts again after a shutdown
+			if w.sched.wasPreempted(job.ID) {
+				job.Status = web.StatusPending
+			}
+
+			addJobStats(job, reviewStats, noise, run.outpath)
+
+			return w.svc.Update(ctx, job)
+		}
+	}
+
+	w.deadLetter(ctx, job.ID, fr.pending(), fr.failures, run.requeued)
+
+	if err := os.Remove(run.frontierPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove %s: %v", run.frontierPath, err)
+	}
+
+	if err := w.svc.DeleteCheckpoint(ctx, job.ID); err != nil {
+		w.svc.Logf(job.ID, "failed to remove the checkpoint of job %s: %v", job.ID, err)
+	}
+
+	fr.tiles.finish()
+
+	if err := fr.tiles.flush(ctx, w.svc, job.ID); err != nil {
+		w.svc.Logf(job.ID, "failed to save the tiles of job %s: %v", job.ID, err)
+	}
+
+	job.Status = web.StatusOK
+
+	addJobStats(job, reviewStats, noise, run.outpath)
+
+	// the results are served by the server when they could not be uploaded
+	if err := w.svc.UploadResults(ctx, job); err != nil {
+		w.svc.Logf(job.ID, "failed to upload results of job %s: %v", job.ID, err)
+	}
+
+	return w.svc.Update(ctx, job)
+}
+
+func (w *webrunner) setupMate(ctx context.Context, writer io.Writer, job *web.Job, fr *frontier, counter *metricsWriter, seen map[string]bool) (runner.App, *runner.ReviewStatsWriter, error) {
+	opts := []func(*scrapemateapp.Config) error{
+		scrapemateapp.WithConcurrency(w.settings(job).Concurrency),
+		scrapemateapp.WithExitOnInactivity(time.Minute * 3),
+		scrapemateapp.WithProvider(fr),
+	}
+
+	switch {
+	case job.Data.FastMode:
+		opts = append(opts,
+			scrapemateapp.WithStealth("firefox"),
+		)
+	case job.Data.Headful:
+		opts = append(opts,
+			scrapemateapp.WithJS(scrapemateapp.Headfull(), scrapemateapp.DisableImages()),
+		)
+	default:
+		opts = append(opts,
+			scrapemateapp.WithJS(scrapemateapp.DisableImages()),
+		)
+	}
+
+	hasProxy := false
+
+	// the session of the proxies is the job, all its requests leave from the same IP
+	switch {
+	case job.Data.ProxyGroup != "":
+		proxies, err := w.cfg.GroupProxies(job.Data.ProxyGroup, job.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		opts = append(opts, scrapemateapp.WithProxies(proxies))
+		hasProxy = true
+	case len(w.cfg.Proxies) > 0:
+		opts = append(opts, scrapemateapp.WithProxies(runner.StickyProxies(w.cfg.Proxies, job.ID)))
+		hasProxy = true
+	case len(job.Data.Proxies) > 0:
+		opts = append(opts,
+			scrapemateapp.WithProxies(runner.StickyProxies(job.Data.Proxies, job.ID)),
+		)
+		hasProxy = true
+	}
+
+	if !w.cfg.DisablePageReuse {
+		opts = append(opts,
+			scrapemateapp.WithPageReuseLimit(2),
+			scrapemateapp.WithPageReuseLimit(200),
+		)
+	}
+
+	w.svc.Logf(job.ID, "job %s has proxy: %v", job.ID, hasProxy)
+
+	outputs, err := w.jobWriters(job, writer, counter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the places go through the filters and the enrichments once, then to
+	// every output
+	output := outputs[0]
+	if len(outputs) > 1 {
+		output = runner.NewFanOutWriter(outputs...)
+	}
+
+	if w.svc.StoresResults() {
+		output = &resultsWriter{w: output, svc: w.svc, jobID: job.ID}
+	}
+
+	writers := []scrapemate.ResultWriter{output}
+
+	if job.Data.SortBy != "" {
+		writers[0] = runner.NewSortedWriter(writers[0], job.Data.SortBy)
+	}
+
+	if job.Data.FuzzyDedup {
+		writers[0] = runner.NewFuzzyDedupWriter(writers[0])
+	}
+
+	// the sorted and merged outputs need all the places of the job, not the
+	// ones of each run, so the places of a paused or interrupted job are kept
+	// until it completes instead of being appended to its CSV
+	if job.Data.StableOutput || job.Data.SortBy != "" || job.Data.FuzzyDedup {
+		keep := func() bool {
+			return fr.isPaused() || ctx.Err() != nil
+		}
+
+		writers[0] = runner.NewStableWriter(writers[0], filepath.Join(w.cfg.DataFolder, job.ID+".store"), keep)
+	}
+
+	writers[0] = runner.NewAppendWriter(writers[0], seen)
+
+	if job.Data.TranslateTo != "" && w.translator != nil {
+		writers[0] = runner.NewTranslateWriter(writers[0], w.translator, job.Data.TranslateTo)
+	}
+
+	if job.Data.ReviewInsights {
+		writers[0] = runner.NewReviewInsightsWriter(writers[0])
+	}
+
+	if len(job.Data.EmailTypes) > 0 {
+		writers[0] = runner.NewEmailTypesWriter(writers[0], job.Data.EmailTypes)
+	}
+
+	if job.Data.Normalize {
+		writers[0] = runner.NewNormalizeWriter(writers[0], job.Data.StripEmojis)
+	}
+
+	reviewStats := runner.NewReviewStatsWriter(writers[0])
+
+	counter.w = reviewStats
+	writers[0] = &frontierWriter{w: counter, f: fr}
+
+	if w.cfg.OtelEndpoint != "" {
+		writers[0] = runner.NewTracingWriter(writers[0])
+	}
+
+	matecfg, err := scrapemateapp.NewConfig(
+		writers,
+		opts...,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	engine := job.Data.BrowserEngine
+	if engine == "" {
+		engine = w.cfg.BrowserEngine
+	}
+
+	mate, err := w.cfg.NewApp(matecfg, engine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mate, reviewStats, nil
+}
+
+// settings returns the settings of the server limited by the profile of the
+// job, or by the default profile of the server when the job sets none. The
+// concurrency is the part of the job of the one of the server.
+func (w *webrunner) settings(job *web.Job) profile.Settings {
+	name := job.Data.Profile
+	if name == "" {
+		name = w.cfg.Profile
+	}
+
+	return profile.Limit(name, profile.Settings{
+		Concurrency:  max(1, int(float64(w.cfg.Concurrency)*w.sched.fraction(job.ID))),
+		Depth:        job.Data.Depth,
+		Email:        job.Data.Email,
+		ExtraReviews: w.cfg.ExtraReviews,
+		Delay:        w.cfg.Delay,
+	})
+}
+
+// geocoder returns the geocoder of the providers and keys of the job,
+// or the one of the server when the job sets none.
+func (w *webrunner) geocoder(job *web.Job) (geocoder.Geocoder, error) {
+	if len(job.Data.Geocoders) == 0 && len(job.Data.GeocoderKeys) == 0 {
+		return w.cfg.Geocoder, nil
+	}
+
+	providers := job.Data.Geocoders
+	if len(providers) == 0 {
+		providers = w.cfg.GeocoderProviders()
+	}
+
+	return runner.NewGeocoder(providers, job.Data.GeocoderKeys, w.cfg.GeocoderURL, w.geocodeCache)
+}
+
+// tiledSeedJobs creates the seed jobs of the tiles of a bbox job that are not done yet.
+// The tiles are saved as pending the first time the job runs. The returned
+// tracker is nil when the repository does not store tile progress.
+func (w *webrunner) tiledSeedJobs(
+	ctx context.Context,
+	job *web.Job,
+	progress []web.TileProgress,
+	dedup deduper.Deduper,
+	exitMonitor exiter.Exiter,
+	extras *gmaps.RequestExtras,
+) ([]scrapemate.IJob, *tileTracker, error) {
+	exclude, err := job.Data.Exclusions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tiles, err := runner.CoverBBox(job.Data.BBox, job.Data.TileSystem, job.Data.TileLevel, exclude)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	density, err := runner.NewDensity(job.Data.TileSystem, job.Data.TileMaxLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(progress) == 0 {
+		now := time.Now().UTC()
+
+		progress = make([]web.TileProgress, len(tiles))
+
+		for i := range tiles {
+			progress[i] = web.TileProgress{
+				ID:        tiles[i].ID,
+				Level:     tiles[i].Level,
+				State:     web.TileStatePending,
+				UpdatedAt: now,
+			}
+		}
+
+		err = w.svc.SaveTiles(ctx, job.ID, progress)
+		if err != nil && !errors.Is(err, web.ErrTilesNotSupported) {
+			return nil, nil, err
+		}
+	}
+
+	var tracker *tileTracker
+
+	if err == nil {
+		tracker = newTileTracker(progress)
+	}
+
+	pending := make(map[string]bool, len(progress))
+
+	for i := range progress {
+		// the smaller tiles of a subdivided tile are not saved, it is searched again
+		pending[progress[i].ID] = progress[i].State != web.TileStateDone
+	}
+
+	queries := strings.Join(job.Data.Keywords, "\n")
+
+	var (
+		seedJobs []scrapemate.IJob
+		searched int
+	)
+
+	for i := range tiles {
+		if !pending[tiles[i].ID] {
+			continue
+		}
+
+		tileJobs, err := runner.CreateTileSeedJobs(
+			job.Data.FastMode,
+			job.Data.Lang,
+			job.Data.Region,
+			queries,
+			job.Data.Depth,
+			job.Data.Email,
+			tiles[i],
+			density,
+			exclude,
+			dedup,
+			exitMonitor,
+			w.settings(job).ExtraReviews,
+			w.cfg.UseCroxy,
+			extras,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tracker.addSeeds(tiles[i].ID, tileJobs)
+
+		seedJobs = append(seedJobs, tileJobs...)
+		searched++
+	}
+
+	w.svc.Logf(job.ID, "job %s searches %d of %d tiles", job.ID, searched, len(tiles))
+
+	return seedJobs, tracker, nil
+}
+
+// transformSeedJobs creates the jobs of the transform of a job over the
+// results of the job it depends on, or over its uploaded input.
+func (w *webrunner) transformSeedJobs(
+	ctx context.Context,
+	job *web.Job,
+	settings profile.Settings,
+	exitMonitor exiter.Exiter,
+	extras *gmaps.RequestExtras,
+) ([]scrapemate.IJob, error) {
+	inpath := w.svc.InputPath(job.ID)
+
+	if job.Data.DependsOn != "" {
+		var err error
+
+		inpath, err = w.svc.GetCSV(ctx, job.Data.DependsOn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := runner.ReadEnrichInput(inpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var seedJobs []scrapemate.IJob
+
+	switch job.Data.Transform {
+	case web.TransformEmails:
+		if !settings.Email {
+			return nil, errors.New("the profile of the job does not allow the email crawl")
+		}
+
+		seedJobs = runner.CreateEmailJobs(entries, exitMonitor)
+	case web.TransformPlaces:
+		seedJobs = runner.CreatePlaceJobs(
+			entries,
+			job.Data.Lang,
+			job.Data.Region,
+			settings.Email,
+			settings.ExtraReviews,
+			exitMonitor,
+			extras,
+		)
+	default:
+		return nil, fmt.Errorf("invalid transform: %s", job.Data.Transform)
+	}
+
+	exitMonitor.IncrPlacesFound(len(seedJobs))
+
+	w.svc.Logf(job.ID, "job %s transforms %d places into %d %s jobs", job.ID, len(entries), len(seedJobs), job.Data.Transform)
+
+	return seedJobs, nil
+}
+
+// circleSeedJobs creates the searches of the hexagonal tiles that cover the
+// circle of radius meters around coords. The places outside the circle are dropped.
+func (w *webrunner) circleSeedJobs(
+	job *web.Job,
+	coords string,
+	radius float64,
+	dedup deduper.Deduper,
+	exitMonitor exiter.Exiter,
+	extras *gmaps.RequestExtras,
+) ([]scrapemate.IJob, error) {
+	tiles, err := runner.CoverCircle(coords, radius, float64(job.Data.TileRadius))
+	if err != nil {
+		return nil, err
+	}
+
+	seedJobs, err := runner.CreateTiledSeedJobs(
+		job.Data.FastMode,
+		job.Data.Lang,
+		job.Data.Region,
+		strings.NewReader(strings.Join(job.Data.Keywords, "\n")),
+		job.Data.Depth,
+		job.Data.Email,
+		tiles,
+		nil,
+		nil,
+		dedup,
+		exitMonitor,
+		w.settings(job).ExtraReviews,
+		w.cfg.UseCroxy,
+		extras,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := runner.ApplyCircle(seedJobs, coords, radius); err != nil {
+		return nil, err
+	}
+
+	w.svc.Logf(job.ID, "job %s searches %d hexagonal tiles", job.ID, len(tiles))
+
+	return seedJobs, nil
+}
+
+// saveCheckpoints saves the pending work of the job every 30 seconds until
+// ctx is done, so the job resumes from it after a crash or a restart.
+func (w *webrunner) saveCheckpoints(ctx context.Context, jobID string, fr *frontier) {
+	const interval = 30 * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// the pending work of a paused job is saved when it stops
+			if fr.isPaused() {
+				continue
+			}
+
+			err := w.checkpoint(ctx, jobID, fr)
+			if errors.Is(err, web.ErrCheckpointsNotSupported) {
+				return
+			}
+
+			if err != nil {
+				w.svc.Logf(jobID, "failed to save the checkpoint of job %s: %v", jobID, err)
+			}
+		}
+	}
+}
+
+// checkpoint saves the jobs of the frontier that are not completed.
+func (w *webrunner) checkpoint(ctx context.Context, jobID string, fr *frontier) error {
+	data, err := encodeFrontier(fr.pending())
+	if err != nil {
+		return err
+	}
+
+	return w.svc.SaveCheckpoint(ctx, jobID, data)
+}
+
+// saveTiles saves the progress of the tiles every few seconds until ctx is done,
+// so an interrupted job continues where it stopped.
+func (w *webrunner) saveTiles(ctx context.Context, jobID string, tracker *tileTracker) {
+	const interval = 5 * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := tracker.flush(ctx, w.svc, jobID); err != nil {
+			w.svc.Logf(jobID, "failed to save the tiles of job %s: %v", jobID, err)
+		}
+	}
+}
+
+// hasData reports whether the file exists and is not empty.
+func hasData(path string) bool {
+	info, err := os.Stat(path)
+
+	return err == nil && info.Size() > 0
+}
+
+// addJobStats adds the results written in this run to the job stats,
+// which already contain the ones of the runs before a pause.
+func addJobStats(job *web.Job, reviewStats *runner.ReviewStatsWriter, noise *gmaps.NoiseFilter, outpath string) {
+	places, complete := reviewStats.Stats()
+
+	job.Stats.Places += places
+	job.Stats.ReviewsComplete += complete
+	job.Stats.Emails += reviewStats.Emails()
+
+	// the results of every run are appended to the same file
+	if fi, err := os.Stat(outpath); err == nil {
+		job.Stats.ResultBytes = fi.Size()
+	}
+
+	if noise != nil {
+		job.Stats.Dropped += noise.Total()
+	}
+}
+
+// requestExtras returns the headers and cookies of the job.
+// Jobs without any use the ones of the server.
+func requestExtras(job *web.Job, fallback *gmaps.RequestExtras) (*gmaps.RequestExtras, error) {
+	if len(job.Data.Headers) == 0 && job.Data.Cookies == "" {
+		return fallback, nil
+	}
+
+	cookies, err := gmaps.ParseCookies(job.Data.Cookies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gmaps.RequestExtras{
+		Headers: job.Data.Headers,
+		Cookies: cookies,
+	}, nil
+}
+
+// watchPause pauses the frontier when the job is paused via the API or
+// preempted by the scheduler.
+// The jobs in flight get some time to complete before the job is stopped,
+// the ones that do not are saved with the rest of the pending work.
+func (w *webrunner) watchPause(ctx context.Context, cancel context.CancelFunc, id string, fr *frontier, preempt <-chan struct{}) {
+	const pollInterval = 2 * time.Second
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var pausedAt time.Time
+
+	for {
+		preempted := false
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-preempt:
+			// a closed channel is ready on each poll
+			preempt, preempted = nil, true
+		case <-ticker.C:
+		}
+
+		if pausedAt.IsZero() {
+			if !preempted {
+				job, err := w.svc.Get(ctx, id)
+				if err != nil || job.Status != web.StatusPaused {
+					continue
+				}
+			}
+
+			w.svc.Logf(id, "pausing job %s", id)
+
+			fr.pause()
+
+			pausedAt = time.Now()
+		}
+
+		// the jobs in flight of a stopping server get the time it has, the
+		// server may start stopping after the job was paused
+		drainTimeout := time.Minute
+		if w.sched.isStopping() {
+			drainTimeout = w.cfg.ShutdownTimeout
+		}
+
+		if fr.inflightCount() == 0 || time.Since(pausedAt) > drainTimeout {
+			cancel()
+
+			return
+		}
+	}
+}
+
+// skipFirstLineWriter drops everything up to and including the first new line.
+type skipFirstLineWriter struct {
+	w       io.Writer
+	skipped bool
+}
+
+func (s *skipFirstLineWriter) Write(p []byte) (int, error) {
+	if s.skipped {
+		return s.w.Write(p)
+	}
+
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	s.skipped = true
+
+	if _, err := s.w.Write(p[i+1:]); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// alertsReloadInterval is how often the alerting config is checked for changes.
+const alertsReloadInterval = 10 * time.Second
+
+// alertMetrics are the metrics the alert rules can watch.
+var alertMetrics = []string{"jobs_per_minute", "error_rate", "block_rate", "places_per_minute", "captchas_per_minute"}
+
+// alertSample returns the alertMetrics of the metrics snapshot p, which
+// covers interval.
+func alertSample(p *web.MetricsPoint, interval time.Duration) map[string]float64 {
+	return map[string]float64{
+		"jobs_per_minute":     p.JobsPerMinute,
+		"error_rate":          p.ErrorRate,
+		"block_rate":          p.BlockRate,
+		"places_per_minute":   float64(p.Places) / interval.Minutes(),
+		"captchas_per_minute": float64(p.Captchas) / interval.Minutes(),
+	}
+}
+
+// alertJob notifies the alert channels of a job that failed, or that
+// completed without places, which usually means that Google blocked it.
+func (w *webrunner) alertJob(job *web.Job, places int) {
+	if w.alerts == nil {
+		return
+	}
+
+	var alert alerting.Alert
+
+	switch {
+	case job.Status == web.StatusFailed:
+		alert = alerting.Alert{
+			Severity: alerting.SeverityWarning,
+			Title:    fmt.Sprintf("job %s failed", job.Name),
+			Text:     fmt.Sprintf("The job %s (%s) failed.", job.Name, job.ID),
+		}
+	case job.Status == web.StatusOK && places == 0:
+		alert = alerting.Alert{
+			Severity: alerting.SeverityWarning,
+			Title:    fmt.Sprintf("job %s found no places", job.Name),
+			Text:     fmt.Sprintf("The job %s (%s) completed without places, Google may be blocking the scraper.", job.Name, job.ID),
+		}
+	default:
+		return
+	}
+
+	// the job is done, the alert must not wait for it
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := w.alerts.Notify(ctx, &alert); err != nil {
+		w.svc.Logf(job.ID, "failed to send the alert of job %s: %v", job.ID, err)
+	}
 }
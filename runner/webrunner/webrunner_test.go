@@ -0,0 +1,114 @@
+package webrunner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/web"
+	"github.com/gosom/google-maps-scraper/web/sqlite"
+)
+
+func TestWatchPauseShutdown(t *testing.T) {
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "jobs.db"))
+	require.NoError(t, err)
+
+	w := &webrunner{
+		svc:   web.NewService(repo, t.TempDir()),
+		cfg:   &runner.Config{ShutdownTimeout: 100 * time.Millisecond},
+		sched: newScheduler(1),
+	}
+
+	job := web.Job{ID: "1"}
+
+	release := make(chan struct{})
+
+	w.sched.start([]*web.Job{&job}, func(*web.Job) { <-release })
+
+	t.Cleanup(func() {
+		close(release)
+		w.sched.wait()
+	})
+
+	// a place job that never completes
+	fr := newFrontier()
+	fr.inflight["place"] = gmaps.NewGmapJob("place", "en", "cafe", 1, false, "", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		w.watchPause(ctx, cancel, job.ID, fr, w.sched.preemption(job.ID))
+	}()
+
+	// the server starts stopping after the job started, the job in flight
+	// gets the shutdown timeout and not the minute of a paused job
+	time.Sleep(100 * time.Millisecond)
+	w.sched.shutdown()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the job was not stopped after the shutdown timeout")
+	}
+
+	require.Error(t, ctx.Err())
+	require.True(t, fr.isPaused())
+	require.True(t, w.sched.wasPreempted(job.ID))
+}
+
+func TestScrapeJobFailsWhenAStoreFails(t *testing.T) {
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "jobs.db"))
+	require.NoError(t, err)
+
+	dataFolder := t.TempDir()
+
+	// the session folder cannot be created under a file
+	sessionDir := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(sessionDir, nil, 0o600))
+
+	w := &webrunner{
+		svc: web.NewService(repo, dataFolder),
+		cfg: &runner.Config{
+			DataFolder: dataFolder,
+			SessionDir: filepath.Join(sessionDir, "sessions"),
+		},
+		sched: newScheduler(1),
+	}
+
+	job := web.Job{
+		ID:     "1",
+		Name:   "cafes",
+		Date:   time.Now().UTC(),
+		Status: web.StatusPending,
+		Data: web.JobData{
+			Keywords: []string{"cafe"},
+			Lang:     "en",
+			Depth:    1,
+			MaxTime:  time.Minute,
+			FastMode: true,
+			Lat:      "52.52",
+			Lon:      "13.40",
+			Zoom:     15,
+		},
+	}
+
+	require.NoError(t, repo.Create(context.Background(), &job))
+
+	err = w.scrapeJob(context.Background(), &job)
+	require.ErrorContains(t, err, "failed to create the session folder")
+
+	saved, err := repo.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, web.StatusFailed, saved.Status)
+}
@@ -0,0 +1,249 @@
+package webrunner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/web"
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
+)
+
+const (
+	webhookWriterBatch    = 50
+	webhookWriterInterval = time.Minute
+)
+
+// writerFactory builds a writer of the places of a job.
+type writerFactory struct {
+	// enabled reports whether the server has what the writer needs
+	enabled func(cfg *runner.Config) bool
+	build   func(w *webrunner, job *web.Job, spec *web.JobWriter) (scrapemate.ResultWriter, error)
+}
+
+// writerRegistry are the writers the jobs can use besides their csv file,
+// by type.
+var writerRegistry = map[string]writerFactory{
+	web.WriterPostgres: {
+		enabled: func(cfg *runner.Config) bool { return cfg.Dsn != "" },
+		build:   newPostgresWriter,
+	},
+	web.WriterWebhook: {
+		enabled: func(*runner.Config) bool { return true },
+		build:   newWebhookWriter,
+	},
+	web.WriterPlugin: {
+		enabled: func(*runner.Config) bool { return len(runner.WriterNames()) > 0 },
+		build:   newPluginWriter,
+	},
+}
+
+// writerTypes returns the types of the writers of the registry the server
+// can build, sorted.
+func writerTypes(cfg *runner.Config) []string {
+	var ans []string
+
+	for name, f := range writerRegistry {
+		if f.enabled(cfg) {
+			ans = append(ans, name)
+		}
+	}
+
+	slices.Sort(ans)
+
+	return ans
+}
+
+// jobWriters returns the writers of the places of job: its csv file, written
+// to out in the layout of the job, then the writers of its request.
+func (w *webrunner) jobWriters(job *web.Job, out io.Writer, counter *metricsWriter) ([]scrapemate.ResultWriter, error) {
+	csvWriter := csvwriter.NewCsvWriter(csv.NewWriter(out))
+	if !job.Data.CSV.IsDefault() {
+		csvWriter = runner.NewCSVWriter(out, job.Data.CSV)
+	}
+
+	ans := []scrapemate.ResultWriter{
+		runner.NewMetricsWriter(csvWriter, counter.recorder, web.WriterCSV),
+	}
+
+	for i := range job.Data.Writers {
+		spec := &job.Data.Writers[i]
+
+		// the csv file is always written
+		if spec.Type == web.WriterCSV {
+			continue
+		}
+
+		f, ok := writerRegistry[spec.Type]
+		if !ok || !f.enabled(w.cfg) {
+			return nil, fmt.Errorf("the %s writer is not available on the server", spec.Type)
+		}
+
+		writer, err := f.build(w, job, spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s writer: %w", spec.Type, err)
+		}
+
+		name := spec.Type
+		if spec.Type == web.WriterPlugin {
+			name = spec.Name
+		}
+
+		// a slow writer does not hold the scraping back
+		writer = runner.NewMetricsWriter(writer, counter.recorder, name)
+		writer = runner.NewSpillWriter(writer, w.cfg.WriterBuffer, w.cfg.DataFolder, counter.recorder, name)
+
+		ans = append(ans, writer)
+	}
+
+	return ans, nil
+}
+
+// resultsDB returns the connection to the database of -dsn, opened the
+// first time a job writes to it.
+func (w *webrunner) resultsDB() (*sql.DB, error) {
+	w.dbMu.Lock()
+	defer w.dbMu.Unlock()
+
+	if w.db != nil {
+		return w.db, nil
+	}
+
+	db, err := sql.Open("pgx", w.cfg.Dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(10)
+
+	w.db = db
+
+	return db, nil
+}
+
+func newPostgresWriter(w *webrunner, _ *web.Job, _ *web.JobWriter) (scrapemate.ResultWriter, error) {
+	db, err := w.resultsDB()
+	if err != nil {
+		return nil, err
+	}
+
+	return &entryWriter{w: postgres.NewResultWriter(db)}, nil
+}
+
+func newPluginWriter(_ *webrunner, _ *web.Job, spec *web.JobWriter) (scrapemate.ResultWriter, error) {
+	return runner.NewPluginWriter(spec.Name, spec.Config)
+}
+
+// entryWriter sends the entries of the results one by one to w, which
+// only takes a single entry per result.
+type entryWriter struct {
+	w scrapemate.ResultWriter
+}
+
+func (ew *entryWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	fw := runner.Forward(ctx, ew.w)
+
+	for result := range in {
+		results := []scrapemate.Result{result}
+
+		if entries, ok := result.Data.([]*gmaps.Entry); ok {
+			results = results[:0]
+
+			for _, e := range entries {
+				results = append(results, scrapemate.Result{Job: result.Job, Data: e})
+			}
+		}
+
+		for i := range results {
+			if !fw.Send(results[i]) {
+				return fw.Close()
+			}
+		}
+	}
+
+	return fw.Close()
+}
+
+// webhookWriter posts the places of a job to a url in batches.
+type webhookWriter struct {
+	svc   *web.Service
+	jobID string
+	hook  web.Webhook
+}
+
+// webhookWriterPayload is the json posted by the webhook writer.
+type webhookWriterPayload struct {
+	ID     string         `json:"id"`
+	JobID  string         `json:"job_id"`
+	Places []*gmaps.Entry `json:"places"`
+}
+
+func newWebhookWriter(w *webrunner, job *web.Job, spec *web.JobWriter) (scrapemate.ResultWriter, error) {
+	return &webhookWriter{
+		svc:   w.svc,
+		jobID: job.ID,
+		hook:  web.Webhook{URL: spec.URL, Secret: spec.Secret},
+	}, nil
+}
+
+func (ww *webhookWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	batch := make([]*gmaps.Entry, 0, webhookWriterBatch)
+	lastPost := time.Now()
+
+	for result := range in {
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			batch = append(batch, data)
+		case []*gmaps.Entry:
+			batch = append(batch, data...)
+		}
+
+		if len(batch) >= webhookWriterBatch || time.Since(lastPost) >= webhookWriterInterval {
+			ww.post(batch)
+
+			batch = batch[:0]
+			lastPost = time.Now()
+		}
+	}
+
+	if len(batch) > 0 {
+		ww.post(batch)
+	}
+
+	return nil
+}
+
+// post posts the places of batch. The csv is the results of record, a
+// batch that cannot be posted is logged and dropped.
+func (ww *webhookWriter) post(batch []*gmaps.Entry) {
+	payload := webhookWriterPayload{
+		ID:     uuid.New().String(),
+		JobID:  ww.jobID,
+		Places: batch,
+	}
+
+	body, err := json.Marshal(payload)
+	if err == nil {
+		err = web.DeliverWebhook(&ww.hook, payload.ID, web.WriterWebhookEvent, body)
+	}
+
+	if err != nil {
+		ww.svc.Logf(ww.jobID, "failed to post %d places of job %s to %s: %v", len(batch), ww.jobID, ww.hook.URL, err)
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"slices"
+	"sync"
+
+	"github.com/gosom/scrapemate"
+)
+
+// ConfigurableWriter is a writer of a plugin that takes a configuration,
+// e.g. the spreadsheet and the credentials of a Google Sheets writer. The
+// configuration is the one of -writer-config or of the writer of the web
+// job.
+type ConfigurableWriter interface {
+	scrapemate.ResultWriter
+	Configure(config map[string]any) error
+}
+
+var (
+	pluginWritersMu sync.RWMutex
+	pluginWriters   = map[string]func() scrapemate.ResultWriter{}
+)
+
+// RegisterWriter adds a writer named name that can be chosen with -writer
+// or by the web jobs, the writer plugins call it in their init function, once
+// per writer. newWriter returns a new writer for each run or job, configured
+// after it is built when it is a ConfigurableWriter. It panics when the name
+// is empty or already registered.
+func RegisterWriter(name string, newWriter func() scrapemate.ResultWriter) {
+	pluginWritersMu.Lock()
+	defer pluginWritersMu.Unlock()
+
+	if name == "" {
+		panic("runner: writer without a name")
+	}
+
+	if _, ok := pluginWriters[name]; ok {
+		panic("runner: writer registered twice: " + name)
+	}
+
+	pluginWriters[name] = newWriter
+}
+
+// WriterNames returns the names of the registered writers, sorted.
+func WriterNames() []string {
+	pluginWritersMu.RLock()
+	defer pluginWritersMu.RUnlock()
+
+	ans := make([]string, 0, len(pluginWriters))
+	for name := range pluginWriters {
+		ans = append(ans, name)
+	}
+
+	slices.Sort(ans)
+
+	return ans
+}
+
+// NewPluginWriter returns a new writer of the registered writer named name,
+// configured with config.
+func NewPluginWriter(name string, config map[string]any) (scrapemate.ResultWriter, error) {
+	pluginWritersMu.RLock()
+	newWriter, ok := pluginWriters[name]
+	pluginWritersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown writer %q", name)
+	}
+
+	return configureWriter(name, newWriter(), config)
+}
+
+// configureWriter passes config to w when it takes one. A configuration
+// given to a writer that takes none is an error, it would be ignored.
+func configureWriter(name string, w scrapemate.ResultWriter, config map[string]any) (scrapemate.ResultWriter, error) {
+	cw, ok := w.(ConfigurableWriter)
+	if !ok {
+		if len(config) > 0 {
+			return nil, fmt.Errorf("the writer %s takes no configuration", name)
+		}
+
+		return w, nil
+	}
+
+	if config == nil {
+		config = map[string]any{}
+	}
+
+	if err := cw.Configure(config); err != nil {
+		return nil, fmt.Errorf("failed to configure the writer %s: %w", name, err)
+	}
+
+	return cw, nil
+}
+
+// LoadWriterPlugins opens the Go plugins of dir, whose init functions
+// register their writers with RegisterWriter, and returns them.
+func LoadWriterPlugins(dir string) ([]*plugin.Plugin, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	var ans []*plugin.Plugin
+
+	for _, file := range files {
+		if file.IsDir() || (filepath.Ext(file.Name()) != ".so" && filepath.Ext(file.Name()) != ".dll") {
+			continue
+		}
+
+		p, err := plugin.Open(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open plugin %s: %w", file.Name(), err)
+		}
+
+		ans = append(ans, p)
+	}
+
+	if len(ans) == 0 {
+		return nil, fmt.Errorf("no plugin found in %s", dir)
+	}
+
+	return ans, nil
+}
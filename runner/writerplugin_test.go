@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+)
+
+// configWriter is a writer of a plugin that takes a configuration.
+type configWriter struct {
+	collectWriter
+	config map[string]any
+}
+
+func (c *configWriter) Configure(config map[string]any) error {
+	if _, ok := config["sheet"]; !ok {
+		return errors.New("missing sheet")
+	}
+
+	c.config = config
+
+	return nil
+}
+
+func TestPluginWriters(t *testing.T) {
+	RegisterWriter("test-plain", func() scrapemate.ResultWriter { return &collectWriter{} })
+	RegisterWriter("test-config", func() scrapemate.ResultWriter { return &configWriter{} })
+
+	t.Cleanup(func() {
+		pluginWritersMu.Lock()
+		defer pluginWritersMu.Unlock()
+
+		delete(pluginWriters, "test-plain")
+		delete(pluginWriters, "test-config")
+	})
+
+	require.Subset(t, WriterNames(), []string{"test-config", "test-plain"})
+
+	require.Panics(t, func() {
+		RegisterWriter("test-plain", func() scrapemate.ResultWriter { return &collectWriter{} })
+	})
+	require.Panics(t, func() {
+		RegisterWriter("", func() scrapemate.ResultWriter { return &collectWriter{} })
+	})
+
+	w, err := NewPluginWriter("test-plain", nil)
+	require.NoError(t, err)
+	require.IsType(t, &collectWriter{}, w)
+
+	// a writer without configuration does not ignore one
+	_, err = NewPluginWriter("test-plain", map[string]any{"sheet": "leads"})
+	require.ErrorContains(t, err, "takes no configuration")
+
+	w, err = NewPluginWriter("test-config", map[string]any{"sheet": "leads"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"sheet": "leads"}, w.(*configWriter).config)
+
+	// each run gets its own writer
+	other, err := NewPluginWriter("test-config", map[string]any{"sheet": "other"})
+	require.NoError(t, err)
+	require.NotSame(t, w, other)
+
+	_, err = NewPluginWriter("test-config", nil)
+	require.ErrorContains(t, err, "failed to configure the writer test-config: missing sheet")
+
+	_, err = NewPluginWriter("missing", nil)
+	require.ErrorContains(t, err, `unknown writer "missing"`)
+
+	writeAll(t, other, scrapemate.Result{Data: "row"})
+	require.Len(t, other.(*configWriter).results, 1)
+}
@@ -3,6 +3,7 @@ package s3uploader
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -14,7 +15,24 @@ type Uploader struct {
 	client *s3.Client
 }
 
-func New(accessKey, secretKey, region string) *Uploader {
+// Option configures the underlying s3 client.
+type Option func(*s3.Options)
+
+// WithEndpoint points the client to an S3-compatible service
+// like MinIO or Google Cloud Storage (https://storage.googleapis.com).
+// Path style addressing is used since most of them require it.
+func WithEndpoint(endpoint string) Option {
+	return func(o *s3.Options) {
+		if endpoint == "" {
+			return
+		}
+
+		o.BaseEndpoint = aws.String(endpoint)
+		o.UsePathStyle = true
+	}
+}
+
+func New(accessKey, secretKey, region string, opts ...Option) *Uploader {
 	creds := credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")
 
 	cfg, err := config.LoadDefaultConfig(context.Background(),
@@ -25,7 +43,12 @@ func New(accessKey, secretKey, region string) *Uploader {
 		return nil
 	}
 
-	client := s3.NewFromConfig(cfg)
+	clientOpts := make([]func(*s3.Options), 0, len(opts))
+	for _, opt := range opts {
+		clientOpts = append(clientOpts, opt)
+	}
+
+	client := s3.NewFromConfig(cfg, clientOpts...)
 
 	return &Uploader{
 		client: client,
@@ -46,3 +69,29 @@ func (u *Uploader) Upload(ctx context.Context, bucketName, key string, body io.R
 
 	return nil
 }
+
+// PresignGet returns a URL that allows downloading the object without credentials until it expires.
+func (u *Uploader) PresignGet(ctx context.Context, bucketName, key string, expires time.Duration) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+
+	req, err := s3.NewPresignClient(u.client).PresignGetObject(ctx, input, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+
+	return req.URL, nil
+}
+
+func (u *Uploader) Delete(ctx context.Context, bucketName, key string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+
+	_, err := u.client.DeleteObject(ctx, input)
+
+	return err
+}
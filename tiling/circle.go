@@ -0,0 +1,100 @@
+package tiling
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// CoverCircle returns the tiles of tileRadius meters that cover the circle of
+// radius meters around lat,lon. The tiles are the hexagons of a hexagonal
+// grid centered on the circle, which is the packing of equal circles that
+// covers an area with the fewest of them. Each tile is searched in the circle
+// around the hexagon, so RadiusMeters is tileRadius.
+//
+// The tiles are not part of a tile system, they cannot be split.
+func CoverCircle(lat, lon, radius, tileRadius float64) ([]Tile, error) {
+	if radius <= 0 || tileRadius <= 0 {
+		return nil, errors.New("radius and tile radius must be positive")
+	}
+
+	if tileRadius >= radius {
+		return []Tile{{ID: "hex:0:0", Lat: lat, Lon: lon, RadiusMeters: radius}}, nil
+	}
+
+	const metersPerDegree = 111320
+
+	// pointy top hexagons whose circumradius is the tile radius
+	width := math.Sqrt(3) * tileRadius
+	height := 1.5 * tileRadius
+
+	rows := int(math.Ceil((radius+tileRadius)/height)) + 1
+
+	lonScale := metersPerDegree * math.Cos(lat*math.Pi/180)
+
+	var ans []Tile
+
+	for row := -rows; row <= rows; row++ {
+		y := float64(row) * height
+
+		offset := 0.0
+		if row%2 != 0 {
+			offset = width / 2
+		}
+
+		cols := int(math.Ceil((radius+tileRadius)/width)) + 1
+
+		for col := -cols; col <= cols; col++ {
+			x := float64(col)*width + offset
+
+			if hexDistance(x, y, tileRadius) > radius {
+				continue
+			}
+
+			if len(ans) == MaxTiles {
+				return nil, fmt.Errorf("circle covers more than %d tiles, use a larger tile radius", MaxTiles)
+			}
+
+			ans = append(ans, Tile{
+				ID:           fmt.Sprintf("hex:%d:%d", col, row),
+				Lat:          lat + y/metersPerDegree,
+				Lon:          lon + x/lonScale,
+				RadiusMeters: tileRadius,
+			})
+		}
+	}
+
+	return ans, nil
+}
+
+// hexDistance returns the distance from the origin to the pointy top hexagon
+// of circumradius r centered at x,y. It is zero when the origin is inside.
+func hexDistance(x, y, r float64) float64 {
+	var vx, vy [6]float64
+
+	for i := range 6 {
+		angle := math.Pi/6 + float64(i)*math.Pi/3
+		vx[i] = x + r*math.Cos(angle)
+		vy[i] = y + r*math.Sin(angle)
+	}
+
+	inside := true
+	ans := math.Inf(1)
+
+	for i := range 6 {
+		j := (i + 1) % 6
+
+		// the vertices go counterclockwise, the origin is inside when it is left of every edge
+		if (vx[j]-vx[i])*(-vy[i])-(vy[j]-vy[i])*(-vx[i]) < 0 {
+			inside = false
+		}
+
+		ans = min(ans, distanceToSegment(vx[i], vy[i], vx[j], vy[j]))
+	}
+
+	if inside {
+		return 0
+	}
+
+	return ans
+}
@@ -0,0 +1,100 @@
+package tiling
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultSaturated is the number of results of a fast mode search page.
+	// A search that returns that many places probably missed some.
+	DefaultSaturated = 20
+	// DefaultPresplit is the number of saturated tiles in an area after which
+	// the other tiles of the area are split two levels at once.
+	DefaultPresplit = 2
+)
+
+// Density learns which areas are dense from the number of results of the
+// searched tiles, so a bounding box is searched with small tiles in cities
+// and large ones in the countryside.
+//
+// A tile whose search is saturated is split in its children. When the
+// siblings of a tile saturated too, the area is dense and the tile is split
+// in its grandchildren right away, which skips a level of searches that
+// would saturate as well. Nearly empty tiles are not split, and they lower
+// the density of their area so their siblings go back to one level at a time.
+type Density struct {
+	sys      System
+	maxLevel int
+
+	// Saturated is the number of results from which a tile is split.
+	Saturated int
+	// Sparse is the number of results below which a tile is nearly empty.
+	Sparse int
+	// Presplit is the density of an area from which its tiles are split two levels at once.
+	Presplit int
+
+	mu sync.Mutex
+	// areas holds the number of saturated minus nearly empty tiles by parent tile id.
+	areas map[string]int
+}
+
+// NewDensity returns a density model that splits the tiles of sys down to maxLevel.
+func NewDensity(sys System, maxLevel int) *Density {
+	return &Density{
+		sys:       sys,
+		maxLevel:  min(maxLevel, sys.MaxLevel()),
+		Saturated: DefaultSaturated,
+		Sparse:    DefaultSaturated / 5,
+		Presplit:  DefaultPresplit,
+		areas:     make(map[string]int),
+	}
+}
+
+// Observe records the number of results of a searched tile and returns the
+// tiles to search instead of it. It returns nil when the tile does not need
+// to be split or is at the max level.
+func (d *Density) Observe(t Tile, results int) ([]Tile, error) {
+	area := ""
+	if parent, err := d.sys.Parent(t); err == nil {
+		area = parent.ID
+	}
+
+	d.mu.Lock()
+
+	switch {
+	case results >= d.Saturated:
+		d.areas[area]++
+	case results < d.Sparse:
+		d.areas[area]--
+	}
+
+	dense := d.areas[area] >= d.Presplit
+
+	d.mu.Unlock()
+
+	if results < d.Saturated || t.Level >= d.maxLevel {
+		return nil, nil
+	}
+
+	children, err := d.sys.Children(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dense || t.Level+2 > d.maxLevel {
+		return children, nil
+	}
+
+	var ans []Tile
+
+	for i := range children {
+		grandchildren, err := d.sys.Children(children[i])
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, grandchildren...)
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,189 @@
+package tiling
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Zone is an area whose tiles are not searched.
+type Zone interface {
+	// Overlaps reports whether the tile covers part of the zone.
+	Overlaps(t Tile) bool
+}
+
+// Point is a vertex of a polygon in degrees.
+type Point struct {
+	Lat, Lon float64
+}
+
+// Polygon is a zone given by its vertices. It is closed automatically.
+type Polygon []Point
+
+// Exclusions are the zones left out of a bounding box, e.g. a neighboring
+// country or a lake inside it.
+type Exclusions []Zone
+
+// ParseExclusions parses a semicolon separated list of zones. A zone of four
+// numbers is a bounding box in the format minlat,minlon,maxlat,maxlon and a
+// zone of three or more lat,lon pairs is a polygon, e.g.
+// "52.3,13.0,52.4,13.2;52.5,13.3,52.6,13.3,52.55,13.4".
+func ParseExclusions(s string) (Exclusions, error) {
+	var ans Exclusions
+
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		zone, err := ParseZone(part)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, zone)
+	}
+
+	return ans, nil
+}
+
+// ParseZone parses a bounding box or a polygon, see ParseExclusions.
+func ParseZone(s string) (Zone, error) {
+	parts := strings.Split(strings.ReplaceAll(s, " ", ""), ",")
+
+	if len(parts) == 4 {
+		return ParseBBox(s)
+	}
+
+	if len(parts) < 6 || len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid exclusion zone: %s", s)
+	}
+
+	p := make(Polygon, len(parts)/2)
+
+	for i := range p {
+		lat, err := strconv.ParseFloat(parts[2*i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclusion zone: %w", err)
+		}
+
+		lon, err := strconv.ParseFloat(parts[2*i+1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclusion zone: %w", err)
+		}
+
+		p[i] = Point{Lat: lat, Lon: lon}
+	}
+
+	return p, p.Validate()
+}
+
+// Excludes reports whether the tile overlaps one of the zones.
+func (e Exclusions) Excludes(t Tile) bool {
+	for _, zone := range e {
+		if zone.Overlaps(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns the tiles that do not overlap any zone.
+func (e Exclusions) Filter(tiles []Tile) []Tile {
+	if len(e) == 0 {
+		return tiles
+	}
+
+	ans := make([]Tile, 0, len(tiles))
+
+	for i := range tiles {
+		if !e.Excludes(tiles[i]) {
+			ans = append(ans, tiles[i])
+		}
+	}
+
+	return ans
+}
+
+// Overlaps reports whether the tile, as the circle around its center, covers
+// part of the bounding box.
+func (b BBox) Overlaps(t Tile) bool {
+	lat := min(max(t.Lat, b.MinLat), b.MaxLat)
+	lon := min(max(t.Lon, b.MinLon), b.MaxLon)
+
+	return haversine(t.Lat, t.Lon, lat, lon) <= t.RadiusMeters
+}
+
+func (p Polygon) Validate() error {
+	if len(p) < 3 {
+		return errors.New("a polygon needs at least 3 vertices")
+	}
+
+	for _, v := range p {
+		if v.Lat < -90 || v.Lat > 90 || v.Lon < -180 || v.Lon > 180 {
+			return errors.New("invalid polygon coordinates")
+		}
+	}
+
+	return nil
+}
+
+// Contains reports whether the point is inside the polygon.
+func (p Polygon) Contains(lat, lon float64) bool {
+	inside := false
+
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lon < (b.Lon-a.Lon)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// Overlaps reports whether the tile, as the circle around its center, covers
+// part of the polygon.
+func (p Polygon) Overlaps(t Tile) bool {
+	if p.Contains(t.Lat, t.Lon) {
+		return true
+	}
+
+	// the edges are measured on a plane around the center of the tile,
+	// which is accurate enough at the size of a tile
+	const metersPerDegree = 111320
+
+	scale := math.Cos(t.Lat * math.Pi / 180)
+
+	project := func(v Point) (float64, float64) {
+		return (v.Lon - t.Lon) * scale * metersPerDegree, (v.Lat - t.Lat) * metersPerDegree
+	}
+
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		ax, ay := project(p[i])
+		bx, by := project(p[j])
+
+		if distanceToSegment(ax, ay, bx, by) <= t.RadiusMeters {
+			return true
+		}
+	}
+
+	return false
+}
+
+// distanceToSegment returns the distance from the origin to the segment ab.
+func distanceToSegment(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+
+	f := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		f = min(max(-(ax*dx+ay*dy)/l, 0), 1)
+	}
+
+	return math.Hypot(ax+f*dx, ay+f*dy)
+}
@@ -0,0 +1,104 @@
+package tiling
+
+import (
+	"fmt"
+	"math"
+)
+
+// grid splits the world in squares of 360/2^level degrees.
+// The tiles get narrower towards the poles.
+type grid struct{}
+
+const gridMaxLevel = 20
+
+func (grid) MaxLevel() int {
+	return gridMaxLevel
+}
+
+func (g grid) Cover(bbox BBox, level int) ([]Tile, error) {
+	if err := bbox.Validate(); err != nil {
+		return nil, err
+	}
+
+	size := gridSize(level)
+
+	x0, x1 := int(math.Floor((bbox.MinLon+180)/size)), int(math.Floor((bbox.MaxLon+180)/size))
+	y0, y1 := int(math.Floor((bbox.MinLat+90)/size)), int(math.Floor((bbox.MaxLat+90)/size))
+
+	if (x1-x0+1)*(y1-y0+1) > MaxTiles {
+		return nil, ErrTooManyTiles
+	}
+
+	var ans []Tile
+
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			ans = append(ans, gridTile(level, x, y))
+		}
+	}
+
+	return ans, nil
+}
+
+func (grid) Children(t Tile) ([]Tile, error) {
+	if t.Level >= gridMaxLevel {
+		return nil, fmt.Errorf("tile %s is at the max level", t.ID)
+	}
+
+	var x, y, level int
+
+	if _, err := fmt.Sscanf(t.ID, "grid/%d/%d/%d", &level, &x, &y); err != nil {
+		return nil, fmt.Errorf("invalid grid tile id %s: %w", t.ID, err)
+	}
+
+	return []Tile{
+		gridTile(level+1, 2*x, 2*y),
+		gridTile(level+1, 2*x+1, 2*y),
+		gridTile(level+1, 2*x, 2*y+1),
+		gridTile(level+1, 2*x+1, 2*y+1),
+	}, nil
+}
+
+func (grid) Parent(t Tile) (Tile, error) {
+	var x, y, level int
+
+	if _, err := fmt.Sscanf(t.ID, "grid/%d/%d/%d", &level, &x, &y); err != nil {
+		return Tile{}, fmt.Errorf("invalid grid tile id %s: %w", t.ID, err)
+	}
+
+	if level == 0 {
+		return Tile{}, fmt.Errorf("tile %s has no parent", t.ID)
+	}
+
+	return gridTile(level-1, x/2, y/2), nil
+}
+
+func gridSize(level int) float64 {
+	return 360 / math.Pow(2, float64(level))
+}
+
+func gridTile(level, x, y int) Tile {
+	size := gridSize(level)
+
+	minLat := float64(y)*size - 90
+	minLon := float64(x)*size - 180
+
+	maxLat := min(minLat+size, 90)
+	maxLon := min(minLon+size, 180)
+
+	lat, lon := (minLat+maxLat)/2, (minLon+maxLon)/2
+
+	// the corner closest to the equator is the farthest from the center
+	cornerLat := minLat
+	if math.Abs(maxLat) < math.Abs(minLat) {
+		cornerLat = maxLat
+	}
+
+	return Tile{
+		ID:           fmt.Sprintf("grid/%d/%d/%d", level, x, y),
+		Level:        level,
+		Lat:          lat,
+		Lon:          lon,
+		RadiusMeters: haversine(lat, lon, cornerLat, maxLon),
+	}
+}
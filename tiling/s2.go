@@ -0,0 +1,381 @@
+package tiling
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// s2 uses the cells of the S2 geometry library. The sphere is projected on the
+// six faces of a cube and each face is split in a quadtree, so the cells of a
+// level have about the same area everywhere, including the poles.
+// Level 12 cells are about 2km wide and level 14 cells about 500m.
+//
+// See https://s2geometry.io/devguide/s2cell_hierarchy for the details.
+type s2 struct{}
+
+const (
+	s2MaxLevel   = 30
+	s2LookupBits = 4
+	s2SwapMask   = 1
+	s2InvertMask = 2
+	s2MaxSize    = 1 << s2MaxLevel
+)
+
+var (
+	s2LookupPos [1 << (2*s2LookupBits + 2)]uint64
+	s2LookupIJ  [1 << (2*s2LookupBits + 2)]uint64
+
+	s2IJToPos = [4][4]uint64{{0, 1, 3, 2}, {0, 3, 1, 2}, {2, 3, 1, 0}, {2, 1, 3, 0}}
+	s2PosToIJ = [4][4]uint64{{0, 1, 3, 2}, {0, 2, 3, 1}, {3, 2, 0, 1}, {3, 1, 0, 2}}
+
+	s2PosToOrientation = [4]uint64{s2SwapMask, 0, 0, s2InvertMask | s2SwapMask}
+)
+
+func init() {
+	for o := uint64(0); o < 4; o++ {
+		s2InitLookupCell(0, 0, 0, o, 0, o)
+	}
+}
+
+func s2InitLookupCell(level int, i, j, origOrientation, pos, orientation uint64) {
+	if level == s2LookupBits {
+		ij := (i << s2LookupBits) + j
+		s2LookupPos[(ij<<2)+origOrientation] = (pos << 2) + orientation
+		s2LookupIJ[(pos<<2)+origOrientation] = (ij << 2) + orientation
+
+		return
+	}
+
+	level++
+	i <<= 1
+	j <<= 1
+	pos <<= 2
+
+	r := s2PosToIJ[orientation]
+
+	for idx := uint64(0); idx < 4; idx++ {
+		s2InitLookupCell(level, i+(r[idx]>>1), j+(r[idx]&1), origOrientation, pos+idx, orientation^s2PosToOrientation[idx])
+	}
+}
+
+// s2Cell is a cell given by its face, level and position on the face.
+// i and j are in the range [0, 2^level).
+type s2Cell struct {
+	face  int
+	level int
+	i, j  uint64
+}
+
+func (s2) MaxLevel() int {
+	return s2MaxLevel
+}
+
+// Cover samples the bounding box with a step smaller than the cells, so every
+// cell that intersects the box is found.
+func (s s2) Cover(bbox BBox, level int) ([]Tile, error) {
+	if err := bbox.Validate(); err != nil {
+		return nil, err
+	}
+
+	if level < 0 || level > s2MaxLevel {
+		return nil, fmt.Errorf("invalid s2 level: %d", level)
+	}
+
+	const (
+		earthRadius     = 6371e3
+		metersPerDegree = 111320
+		// the narrowest cells are about 0.94 times the average width
+		minWidthFactor = 0.94
+	)
+
+	avgWidth := math.Pi / 2 / math.Pow(2, float64(level)) * earthRadius
+	step := avgWidth * minWidthFactor / 2 / metersPerDegree
+
+	seen := make(map[s2Cell]struct{})
+
+	var ans []Tile
+
+	add := func(lat, lon float64) error {
+		c := s2CellFromLatLon(lat, lon, level)
+		if _, ok := seen[c]; ok {
+			return nil
+		}
+
+		if len(ans) >= MaxTiles {
+			return ErrTooManyTiles
+		}
+
+		seen[c] = struct{}{}
+		ans = append(ans, c.tile())
+
+		return nil
+	}
+
+	for lat := bbox.MinLat; ; lat += step {
+		lat = min(lat, bbox.MaxLat)
+
+		// longitude degrees get shorter towards the poles
+		cos := math.Max(math.Cos(math.Max(math.Abs(lat), math.Abs(lat+step))*math.Pi/180), 1e-6)
+		lonStep := step / cos
+
+		for lon := bbox.MinLon; ; lon += lonStep {
+			lon = min(lon, bbox.MaxLon)
+
+			if err := add(lat, lon); err != nil {
+				return nil, err
+			}
+
+			if lon >= bbox.MaxLon {
+				break
+			}
+		}
+
+		if lat >= bbox.MaxLat {
+			break
+		}
+	}
+
+	return ans, nil
+}
+
+func (s2) Children(t Tile) ([]Tile, error) {
+	c, err := s2CellFromToken(t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.level >= s2MaxLevel {
+		return nil, fmt.Errorf("tile %s is at the max level", t.ID)
+	}
+
+	ans := make([]Tile, 0, 4)
+
+	for _, d := range [4][2]uint64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		child := s2Cell{face: c.face, level: c.level + 1, i: 2*c.i + d[0], j: 2*c.j + d[1]}
+		ans = append(ans, child.tile())
+	}
+
+	return ans, nil
+}
+
+func (s2) Parent(t Tile) (Tile, error) {
+	c, err := s2CellFromToken(t.ID)
+	if err != nil {
+		return Tile{}, err
+	}
+
+	if c.level == 0 {
+		return Tile{}, fmt.Errorf("tile %s has no parent", t.ID)
+	}
+
+	parent := s2Cell{face: c.face, level: c.level - 1, i: c.i >> 1, j: c.j >> 1}
+
+	return parent.tile(), nil
+}
+
+func s2CellFromLatLon(lat, lon float64, level int) s2Cell {
+	rlat, rlon := lat*math.Pi/180, lon*math.Pi/180
+
+	x := math.Cos(rlat) * math.Cos(rlon)
+	y := math.Cos(rlat) * math.Sin(rlon)
+	z := math.Sin(rlat)
+
+	face, u, v := s2XYZToFaceUV(x, y, z)
+
+	shift := uint(s2MaxLevel - level)
+
+	return s2Cell{
+		face:  face,
+		level: level,
+		i:     s2STToIJ(s2UVToST(u)) >> shift,
+		j:     s2STToIJ(s2UVToST(v)) >> shift,
+	}
+}
+
+// id returns the S2 cell id, which orders the cells along a Hilbert curve.
+func (c s2Cell) id() uint64 {
+	shift := uint(s2MaxLevel - c.level)
+
+	// the leaf cell at the lower left corner of the cell
+	i, j := c.i<<shift, c.j<<shift
+
+	n := uint64(c.face) << (2 * s2MaxLevel)
+	bits := uint64(c.face) & s2SwapMask
+
+	const mask = 1<<s2LookupBits - 1
+
+	for k := 7; k >= 0; k-- {
+		bits += ((i >> (uint(k) * s2LookupBits)) & mask) << (s2LookupBits + 2)
+		bits += ((j >> (uint(k) * s2LookupBits)) & mask) << 2
+		bits = s2LookupPos[bits]
+		n |= (bits >> 2) << (uint(k) * 2 * s2LookupBits)
+		bits &= s2SwapMask | s2InvertMask
+	}
+
+	leaf := n<<1 | 1
+
+	lsb := uint64(1) << (2 * shift)
+
+	return leaf&-lsb | lsb
+}
+
+// token is the hex cell id without trailing zeros, as used by the S2 libraries.
+func (c s2Cell) token() string {
+	return strings.TrimRight(fmt.Sprintf("%016x", c.id()), "0")
+}
+
+func s2CellFromToken(token string) (s2Cell, error) {
+	if len(token) == 0 || len(token) > 16 {
+		return s2Cell{}, fmt.Errorf("invalid s2 token: %s", token)
+	}
+
+	id, err := strconv.ParseUint(token+strings.Repeat("0", 16-len(token)), 16, 64)
+	if err != nil || id == 0 {
+		return s2Cell{}, fmt.Errorf("invalid s2 token: %s", token)
+	}
+
+	face := int(id >> (2*s2MaxLevel + 1))
+	if face > 5 {
+		return s2Cell{}, fmt.Errorf("invalid s2 token: %s", token)
+	}
+
+	// the level is given by the position of the lowest set bit
+	trailing := 0
+	for id>>uint(trailing)&1 == 0 {
+		trailing++
+	}
+
+	level := s2MaxLevel - trailing/2
+
+	var i, j uint64
+
+	bits := uint64(face) & s2SwapMask
+
+	for k := 7; k >= 0; k-- {
+		nbits := s2LookupBits
+		if k == 7 {
+			nbits = s2MaxLevel - 7*s2LookupBits
+		}
+
+		bits += (id >> (uint(k)*2*s2LookupBits + 1) & (1<<(2*uint(nbits)) - 1)) << 2
+		bits = s2LookupIJ[bits]
+		i += (bits >> (s2LookupBits + 2)) << (uint(k) * s2LookupBits)
+		j += ((bits >> 2) & (1<<s2LookupBits - 1)) << (uint(k) * s2LookupBits)
+		bits &= s2SwapMask | s2InvertMask
+	}
+
+	shift := uint(s2MaxLevel - level)
+
+	return s2Cell{face: face, level: level, i: i >> shift, j: j >> shift}, nil
+}
+
+func (c s2Cell) tile() Tile {
+	size := 1 / math.Pow(2, float64(c.level))
+
+	s0, t0 := float64(c.i)*size, float64(c.j)*size
+
+	lat, lon := s2FaceSTToLatLon(c.face, s0+size/2, t0+size/2)
+
+	radius := 0.0
+
+	for _, d := range [4][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		vlat, vlon := s2FaceSTToLatLon(c.face, s0+d[0]*size, t0+d[1]*size)
+		radius = max(radius, haversine(lat, lon, vlat, vlon))
+	}
+
+	return Tile{
+		ID:           c.token(),
+		Level:        c.level,
+		Lat:          lat,
+		Lon:          lon,
+		RadiusMeters: radius,
+	}
+}
+
+func s2XYZToFaceUV(x, y, z float64) (face int, u, v float64) {
+	ax, ay, az := math.Abs(x), math.Abs(y), math.Abs(z)
+
+	switch {
+	case ax >= ay && ax >= az:
+		face = 0
+		if x < 0 {
+			face = 3
+		}
+	case ay >= az:
+		face = 1
+		if y < 0 {
+			face = 4
+		}
+	default:
+		face = 2
+		if z < 0 {
+			face = 5
+		}
+	}
+
+	switch face {
+	case 0:
+		u, v = y/x, z/x
+	case 1:
+		u, v = -x/y, z/y
+	case 2:
+		u, v = -x/z, -y/z
+	case 3:
+		u, v = z/x, y/x
+	case 4:
+		u, v = z/y, -x/y
+	default:
+		u, v = -y/z, -x/z
+	}
+
+	return face, u, v
+}
+
+func s2FaceUVToXYZ(face int, u, v float64) (x, y, z float64) {
+	switch face {
+	case 0:
+		return 1, u, v
+	case 1:
+		return -u, 1, v
+	case 2:
+		return -u, -v, 1
+	case 3:
+		return -1, -v, -u
+	case 4:
+		return v, -1, -u
+	default:
+		return v, u, -1
+	}
+}
+
+func s2FaceSTToLatLon(face int, s, t float64) (lat, lon float64) {
+	x, y, z := s2FaceUVToXYZ(face, s2STToUV(s), s2STToUV(t))
+
+	lat = math.Atan2(z, math.Sqrt(x*x+y*y)) * 180 / math.Pi
+	lon = math.Atan2(y, x) * 180 / math.Pi
+
+	return lat, lon
+}
+
+// s2UVToST is the quadratic transform that makes the cells of a level about the same size.
+func s2UVToST(u float64) float64 {
+	if u >= 0 {
+		return 0.5 * math.Sqrt(1+3*u)
+	}
+
+	return 1 - 0.5*math.Sqrt(1-3*u)
+}
+
+func s2STToUV(s float64) float64 {
+	if s >= 0.5 {
+		return (1.0 / 3.0) * (4*s*s - 1)
+	}
+
+	return (1.0 / 3.0) * (1 - 4*(1-s)*(1-s))
+}
+
+func s2STToIJ(s float64) uint64 {
+	return uint64(max(0, min(s2MaxSize-1, math.Floor(s2MaxSize*s))))
+}
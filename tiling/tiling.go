@@ -0,0 +1,171 @@
+// Package tiling splits a bounding box into tiles that are searched one by one,
+// so crawls of large areas are not limited by the results of a single search.
+package tiling
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	SystemGrid = "grid"
+	SystemS2   = "s2"
+	SystemH3   = "h3"
+)
+
+// MaxTiles is the maximum number of tiles a bounding box can be split into.
+const MaxTiles = 10000
+
+var ErrTooManyTiles = fmt.Errorf("bounding box covers more than %d tiles, use a lower tile level", MaxTiles)
+
+// BBox is a bounding box in degrees. Boxes that cross the antimeridian are not supported.
+type BBox struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// ParseBBox parses a bounding box in the format minlat,minlon,maxlat,maxlon.
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(strings.ReplaceAll(s, " ", ""), ",")
+	if len(parts) != 4 {
+		return BBox{}, fmt.Errorf("invalid bounding box: %s", s)
+	}
+
+	var v [4]float64
+
+	for i := range parts {
+		f, err := strconv.ParseFloat(parts[i], 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("invalid bounding box: %w", err)
+		}
+
+		v[i] = f
+	}
+
+	ans := BBox{MinLat: v[0], MinLon: v[1], MaxLat: v[2], MaxLon: v[3]}
+
+	return ans, ans.Validate()
+}
+
+func (b BBox) Validate() error {
+	if b.MinLat < -90 || b.MaxLat > 90 || b.MinLat >= b.MaxLat {
+		return errors.New("invalid bounding box latitudes")
+	}
+
+	if b.MinLon < -180 || b.MaxLon > 180 || b.MinLon >= b.MaxLon {
+		return errors.New("invalid bounding box longitudes")
+	}
+
+	return nil
+}
+
+func (b BBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// Tile is an area that is searched with a single seed job.
+type Tile struct {
+	// ID identifies the tile in its tile system, e.g. the S2 cell token.
+	ID    string
+	Level int
+	// Lat and Lon are the center of the tile.
+	Lat float64
+	Lon float64
+	// RadiusMeters is the distance from the center to the farthest vertex.
+	RadiusMeters float64
+}
+
+// Coordinates returns the center in the lat,lon format of the geo coordinates flag.
+func (t *Tile) Coordinates() string {
+	return strconv.FormatFloat(t.Lat, 'f', 6, 64) + "," + strconv.FormatFloat(t.Lon, 'f', 6, 64)
+}
+
+// Zoom returns the map zoom level that fits the tile.
+func (t *Tile) Zoom() int {
+	const metersPerDegree = 111320
+
+	span := 2 * t.RadiusMeters / metersPerDegree
+	if span <= 0 {
+		return 21
+	}
+
+	zoom := int(math.Floor(math.Log2(360/span))) + 1
+
+	return min(max(zoom, 1), 21)
+}
+
+// System splits bounding boxes into tiles.
+type System interface {
+	// Cover returns the tiles of the given level that intersect the bounding box.
+	Cover(bbox BBox, level int) ([]Tile, error)
+	// Children returns the tiles of the next level that make up the tile.
+	Children(t Tile) ([]Tile, error)
+	// Parent returns the tile of the previous level that contains the tile.
+	Parent(t Tile) (Tile, error)
+	// MaxLevel is the highest level supported.
+	MaxLevel() int
+}
+
+// New returns the tile system with the given name. The default is s2.
+func New(name string) (System, error) {
+	switch name {
+	case SystemGrid:
+		return grid{}, nil
+	case SystemS2, "":
+		return s2{}, nil
+	case SystemH3:
+		return nil, errors.New("the h3 tile system is not supported yet, use s2")
+	default:
+		return nil, fmt.Errorf("invalid tile system: %s", name)
+	}
+}
+
+// ValidateSystem checks the tile system name and level.
+func ValidateSystem(name string, level int) error {
+	sys, err := New(name)
+	if err != nil {
+		return err
+	}
+
+	if level < 0 || level > sys.MaxLevel() {
+		return fmt.Errorf("tile level must be between 0 and %d", sys.MaxLevel())
+	}
+
+	return nil
+}
+
+// ValidateMaxLevel checks the level down to which saturated tiles are split.
+// Zero means that tiles are not split.
+func ValidateMaxLevel(name string, level, maxLevel int) error {
+	if maxLevel == 0 {
+		return nil
+	}
+
+	sys, err := New(name)
+	if err != nil {
+		return err
+	}
+
+	if maxLevel <= level || maxLevel > sys.MaxLevel() {
+		return fmt.Errorf("tile max level must be between %d and %d", level+1, sys.MaxLevel())
+	}
+
+	return nil
+}
+
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const R = 6371e3 // earth radius in meters
+
+	rlat1 := lat1 * math.Pi / 180
+	rlat2 := lat2 * math.Pi / 180
+	dlat := (lat2 - lat1) * math.Pi / 180
+	dlon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(rlat1)*math.Cos(rlat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+
+	return R * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
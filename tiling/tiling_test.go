@@ -0,0 +1,170 @@
+package tiling_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/tiling"
+)
+
+func Test_S2Cover(t *testing.T) {
+	t.Parallel()
+
+	sys, err := tiling.New(tiling.SystemS2)
+	require.NoError(t, err)
+
+	// San Francisco
+	bbox := tiling.BBox{MinLat: 37.70, MinLon: -122.52, MaxLat: 37.82, MaxLon: -122.35}
+
+	tiles, err := sys.Cover(bbox, 12)
+	require.NoError(t, err)
+	require.NotEmpty(t, tiles)
+
+	seen := make(map[string]bool)
+
+	for i := range tiles {
+		require.Equal(t, 12, tiles[i].Level)
+		require.Equal(t, "808", tiles[i].ID[:3])
+		require.False(t, seen[tiles[i].ID])
+		require.InDelta(t, 1700, tiles[i].RadiusMeters, 500)
+
+		seen[tiles[i].ID] = true
+	}
+
+	children, err := sys.Children(tiles[0])
+	require.NoError(t, err)
+	require.Len(t, children, 4)
+
+	for i := range children {
+		require.Equal(t, 13, children[i].Level)
+		require.Less(t, children[i].RadiusMeters, tiles[0].RadiusMeters)
+	}
+}
+
+func Test_S2CoverPole(t *testing.T) {
+	t.Parallel()
+
+	sys, err := tiling.New(tiling.SystemS2)
+	require.NoError(t, err)
+
+	tiles, err := sys.Cover(tiling.BBox{MinLat: 89, MinLon: -180, MaxLat: 90, MaxLon: 180}, 10)
+	require.NoError(t, err)
+
+	// the cells near the pole have about the same size as everywhere else
+	for i := range tiles {
+		require.InDelta(t, 7000, tiles[i].RadiusMeters, 3000)
+	}
+}
+
+func Test_DensityObserve(t *testing.T) {
+	t.Parallel()
+
+	sys, err := tiling.New(tiling.SystemGrid)
+	require.NoError(t, err)
+
+	tiles, err := sys.Cover(tiling.BBox{MinLat: 10, MinLon: 10, MaxLat: 10.5, MaxLon: 10.5}, 12)
+	require.NoError(t, err)
+	require.Greater(t, len(tiles), 3)
+
+	parent, err := sys.Parent(tiles[0])
+	require.NoError(t, err)
+
+	siblings, err := sys.Children(parent)
+	require.NoError(t, err)
+
+	d := tiling.NewDensity(sys, 14)
+
+	next, err := d.Observe(siblings[0], 3)
+	require.NoError(t, err)
+	require.Empty(t, next)
+
+	next, err = d.Observe(siblings[1], 20)
+	require.NoError(t, err)
+	require.Len(t, next, 4)
+
+	next, err = d.Observe(siblings[2], 20)
+	require.NoError(t, err)
+	require.Len(t, next, 4)
+
+	// the area saturated twice more than it was empty, the last sibling is split two levels
+	next, err = d.Observe(siblings[3], 20)
+	require.NoError(t, err)
+	require.Len(t, next, 16)
+
+	for i := range next {
+		require.Equal(t, 14, next[i].Level)
+	}
+
+	// no split past the max level
+	next, err = d.Observe(next[0], 20)
+	require.NoError(t, err)
+	require.Empty(t, next)
+}
+
+func Test_ExclusionsFilter(t *testing.T) {
+	t.Parallel()
+
+	ex, err := tiling.ParseExclusions("52.50,13.30,52.55,13.40; 52.40,13.60,52.45,13.60,52.40,13.70")
+	require.NoError(t, err)
+	require.Len(t, ex, 2)
+
+	tiles := []tiling.Tile{
+		{ID: "inside-bbox", Lat: 52.52, Lon: 13.35, RadiusMeters: 100},
+		{ID: "near-bbox", Lat: 52.52, Lon: 13.401, RadiusMeters: 1000},
+		{ID: "inside-polygon", Lat: 52.41, Lon: 13.63, RadiusMeters: 100},
+		{ID: "far", Lat: 52.30, Lon: 13.00, RadiusMeters: 1000},
+	}
+
+	kept := ex.Filter(tiles)
+	require.Len(t, kept, 1)
+	require.Equal(t, "far", kept[0].ID)
+
+	_, err = tiling.ParseExclusions("52.50,13.30,52.55")
+	require.Error(t, err)
+}
+
+func Test_CoverCircle(t *testing.T) {
+	t.Parallel()
+
+	tiles, err := tiling.CoverCircle(52.52, 13.40, 5000, 1000)
+	require.NoError(t, err)
+	require.Greater(t, len(tiles), 20)
+	require.Less(t, len(tiles), 50)
+
+	// every point of the circle is in the search radius of a tile
+	for _, p := range [][2]float64{{0, 0}, {4999, 0}, {0, -4999}, {3500, 3500}, {-2000, 4500}} {
+		lat := 52.52 + p[1]/111320
+		lon := 13.40 + p[0]/(111320*0.6088)
+
+		covered := false
+
+		for i := range tiles {
+			if distance(lat, lon, tiles[i].Lat, tiles[i].Lon) <= tiles[i].RadiusMeters {
+				covered = true
+
+				break
+			}
+		}
+
+		require.True(t, covered, "point %v is not covered", p)
+	}
+
+	tiles, err = tiling.CoverCircle(52.52, 13.40, 500, 1000)
+	require.NoError(t, err)
+	require.Len(t, tiles, 1)
+	require.InDelta(t, 500, tiles[0].RadiusMeters, 0.1)
+}
+
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const R = 6371e3
+
+	dlat := (lat2 - lat1) * math.Pi / 180
+	dlon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dlon/2)*math.Sin(dlon/2)
+
+	return R * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
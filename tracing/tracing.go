@@ -0,0 +1,88 @@
+// Package tracing exports OpenTelemetry spans of the jobs to an OTLP
+// collector. Until Setup is called the spans are not recorded.
+//
+// The jobs run on different goroutines, and with the distributed mode on
+// different machines, so a job does not get the context of the job that
+// created it. Each job keeps instead the W3C traceparent of the span of its
+// parent, which makes all the spans of a seed one trace.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "google-maps-scraper"
+	tracerName  = "github.com/gosom/google-maps-scraper"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Setup exports the spans to the OTLP/HTTP endpoint, for example
+// http://localhost:4318. The returned function flushes the pending spans
+// and must be called before the process exits.
+func Setup(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the otlp exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the otel resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span. parent is the traceparent of the parent span, when it
+// is empty the span is a child of the span in ctx or the root of a new trace.
+func Start(ctx context.Context, parent, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if parent != "" {
+		ctx = propagator.Extract(ctx, propagation.MapCarrier{"traceparent": parent})
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Parent returns the traceparent of the span in ctx, to be kept by the jobs
+// it creates. It is empty when the span is not recorded.
+func Parent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+
+	propagator.Inject(ctx, carrier)
+
+	return carrier.Get("traceparent")
+}
+
+// End records err on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,34 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gosom/google-maps-scraper/tracing"
+)
+
+func Test_StartParent(t *testing.T) {
+	require.Empty(t, tracing.Parent(context.Background()))
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	seedCtx, seed := tracing.Start(context.Background(), "", "seed")
+	defer seed.End()
+
+	parent := tracing.Parent(seedCtx)
+	require.NotEmpty(t, parent)
+
+	childCtx, child := tracing.Start(context.Background(), parent, "child")
+	defer child.End()
+
+	seedSpan := trace.SpanContextFromContext(seedCtx)
+	childSpan := trace.SpanContextFromContext(childCtx)
+
+	require.Equal(t, seedSpan.TraceID(), childSpan.TraceID())
+	require.NotEqual(t, seedSpan.SpanID(), childSpan.SpanID())
+}
@@ -0,0 +1,96 @@
+package deepl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/translate"
+)
+
+const (
+	defaultBaseURL = "https://api.deepl.com"
+	freeBaseURL    = "https://api-free.deepl.com"
+)
+
+var _ translate.Translator = (*client)(nil)
+
+type client struct {
+	baseURL string
+	key     string
+	http    *http.Client
+}
+
+// New returns a translator backed by the DeepL API. key is a DeepL API key,
+// the keys of the free plan, that end with :fx, use the free API.
+func New(key string) translate.Translator {
+	baseURL := defaultBaseURL
+	if strings.HasSuffix(key, ":fx") {
+		baseURL = freeBaseURL
+	}
+
+	return &client{
+		baseURL: baseURL,
+		key:     key,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type request struct {
+	Text       []string `json:"text"`
+	TargetLang string   `json:"target_lang"`
+}
+
+type response struct {
+	Translations []struct {
+		Text string `json:"text"`
+	} `json:"translations"`
+}
+
+func (c *client) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	body, err := json.Marshal(request{Text: texts, TargetLang: strings.ToUpper(target)})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v2/translate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+c.key)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("deepl: request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("deepl: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result response
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("deepl: %w", err)
+	}
+
+	if len(result.Translations) != len(texts) {
+		return nil, fmt.Errorf("deepl: got %d translations for %d texts", len(result.Translations), len(texts))
+	}
+
+	ans := make([]string, len(texts))
+
+	for i := range result.Translations {
+		ans[i] = result.Translations[i].Text
+	}
+
+	return ans, nil
+}
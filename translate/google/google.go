@@ -0,0 +1,97 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/translate"
+)
+
+const defaultBaseURL = "https://translation.googleapis.com"
+
+var _ translate.Translator = (*client)(nil)
+
+type client struct {
+	baseURL string
+	key     string
+	http    *http.Client
+}
+
+// New returns a translator backed by the Google Cloud Translation API (v2).
+// key is a Google Cloud API key.
+func New(key string) translate.Translator {
+	return &client{
+		baseURL: defaultBaseURL,
+		key:     key,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type request struct {
+	Q      []string `json:"q"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+}
+
+type response struct {
+	Data struct {
+		Translations []struct {
+			TranslatedText string `json:"translatedText"`
+		} `json:"translations"`
+	} `json:"data"`
+}
+
+func (c *client) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	body, err := json.Marshal(request{Q: texts, Target: target, Format: "text"})
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.baseURL + "/language/translate/v2?" + url.Values{"key": {c.key}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// the error holds the url, which holds the key
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+
+		return nil, fmt.Errorf("google translate: request failed: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("google translate: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result response
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("google translate: %w", err)
+	}
+
+	if len(result.Data.Translations) != len(texts) {
+		return nil, fmt.Errorf("google translate: got %d translations for %d texts", len(result.Data.Translations), len(texts))
+	}
+
+	ans := make([]string, len(texts))
+
+	for i := range result.Data.Translations {
+		ans[i] = result.Data.Translations[i].TranslatedText
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,51 @@
+// Package translate translates the text of the reviews with a translation
+// service.
+package translate
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// The supported translation services.
+const (
+	ProviderGoogle = "google"
+	ProviderDeepL  = "deepl"
+)
+
+// keyEnv holds the environment variable of the API key of the providers.
+var keyEnv = map[string]string{
+	ProviderGoogle: "GOOGLE_TRANSLATE_API_KEY",
+	ProviderDeepL:  "DEEPL_API_KEY",
+}
+
+// Translator translates texts.
+type Translator interface {
+	// Translate returns the texts translated to the target language, an ISO
+	// 639-1 code, in the same order.
+	Translate(ctx context.Context, texts []string, target string) ([]string, error)
+}
+
+// Key returns the API key of the provider from its environment variable.
+func Key(provider string) string {
+	if env := keyEnv[provider]; env != "" {
+		return os.Getenv(env)
+	}
+
+	return ""
+}
+
+// ValidateProvider checks that the provider is supported and has an API key.
+func ValidateProvider(provider string) error {
+	env, ok := keyEnv[provider]
+	if !ok {
+		return fmt.Errorf("invalid translator: %s", provider)
+	}
+
+	if Key(provider) == "" {
+		return fmt.Errorf("translator %s needs an API key, set %s", provider, env)
+	}
+
+	return nil
+}
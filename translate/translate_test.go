@@ -0,0 +1,19 @@
+package translate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/translate"
+)
+
+func Test_ValidateProvider(t *testing.T) {
+	t.Setenv("GOOGLE_TRANSLATE_API_KEY", "")
+	t.Setenv("DEEPL_API_KEY", "key:fx")
+
+	require.Error(t, translate.ValidateProvider("bing"))
+	require.Error(t, translate.ValidateProvider(translate.ProviderGoogle))
+	require.NoError(t, translate.ValidateProvider(translate.ProviderDeepL))
+	require.Equal(t, "key:fx", translate.Key(translate.ProviderDeepL))
+}
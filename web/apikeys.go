@@ -0,0 +1,546 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix starts the API keys, so they are easy to tell from the
+// session tokens and to find in leaked code.
+const apiKeyPrefix = "gms_"
+
+// APIKey lets a program use the API as its user, within limits.
+type APIKey struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix is the start of the key, to tell the keys apart
+	Prefix  string `json:"prefix"`
+	KeyHash string `json:"-"`
+	// RateLimit is the number of requests per minute, 0 for no limit
+	RateLimit int `json:"rate_limit"`
+	// MonthlyPlaces is the number of places the jobs created with the keys
+	// of the user may scrape in a calendar month, 0 for no limit
+	MonthlyPlaces int       `json:"monthly_places"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// APIKeyUsage is what an API key used in a month.
+type APIKeyUsage struct {
+	KeyID    string `json:"key_id"`
+	Month    string `json:"month"`
+	Requests int    `json:"requests"`
+	Jobs     int    `json:"jobs"`
+	Places   int    `json:"places"`
+	// RemainingPlaces is what the jobs of all the keys of the user left of
+	// the quota of the key, nil when the key has no monthly quota
+	RemainingPlaces *int `json:"remaining_places,omitempty"`
+}
+
+// APIKeyLimits are the limits of the keys created by the users who are not
+// admins. The admins choose the limits of the keys they create.
+type APIKeyLimits struct {
+	RateLimit     int
+	MonthlyPlaces int
+}
+
+// APIKeyRepository stores the API keys and the requests they made.
+type APIKeyRepository interface {
+	CreateAPIKey(context.Context, *APIKey) error
+	GetAPIKey(context.Context, string) (APIKey, error)
+	GetAPIKeyByHash(context.Context, string) (APIKey, error)
+	// SelectAPIKeys returns the keys of the user userID, all of them when it is empty.
+	SelectAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	DeleteAPIKey(context.Context, string) error
+	// IncrAPIKeyRequests counts a request of the key keyID in month, as YYYY-MM.
+	IncrAPIKeyRequests(ctx context.Context, keyID, month string) error
+	APIKeyRequests(ctx context.Context, keyID, month string) (int, error)
+}
+
+var (
+	// ErrAPIKeysNotSupported is returned when the repository does not store API keys.
+	ErrAPIKeysNotSupported = errors.New("api keys are not supported")
+	// ErrQuotaExceeded is returned when a job is created with an API key
+	// whose user's jobs scraped its monthly places.
+	ErrQuotaExceeded = errors.New("monthly place quota exceeded")
+	// ErrAPIKeyCreatesKey is returned when a request made with an API key
+	// creates a key, which would not be limited by the key it was made with.
+	ErrAPIKeyCreatesKey = errors.New("api keys cannot be created with an api key")
+)
+
+// WithAPIKeys keeps the API keys in repo. The keys of the users who are not
+// admins get limits.
+func WithAPIKeys(repo APIKeyRepository, limits APIKeyLimits) ServiceOption {
+	return func(s *Service) {
+		s.apiKeyRepo = repo
+		s.apiKeyLimits = limits
+	}
+}
+
+const apiKeyCtxKey ctxKey = "api_key"
+
+// ContextWithAPIKey returns ctx for the requests made with key. The jobs
+// created with it count towards its quota.
+func ContextWithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyCtxKey, key)
+}
+
+// APIKeyFromContext returns the API key of ctx, false when the request was
+// not made with one.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCtxKey).(*APIKey)
+
+	return key, ok
+}
+
+// setAPIKey sets the key of ctx as the key d was created with, and clears
+// the one the client gave.
+func setAPIKey(ctx context.Context, d *JobData) {
+	d.APIKeyID = ""
+
+	if key, ok := APIKeyFromContext(ctx); ok {
+		d.APIKeyID = key.ID
+	}
+}
+
+func monthLabel(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// CreateAPIKey stores a new key of the user of ctx, or of key.UserID when
+// an admin creates it, and returns the key. Only its hash is stored, so it
+// cannot be shown again.
+func (s *Service) CreateAPIKey(ctx context.Context, key *APIKey) (string, error) {
+	if s.apiKeyRepo == nil || s.userRepo == nil {
+		return "", ErrAPIKeysNotSupported
+	}
+
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return "", errors.New("api keys need a signed in user")
+	}
+
+	if _, ok := APIKeyFromContext(ctx); ok {
+		return "", ErrAPIKeyCreatesKey
+	}
+
+	key.Name = strings.TrimSpace(key.Name)
+
+	switch {
+	case key.Name == "":
+		return "", errors.New("missing name")
+	case len(key.Name) > 100:
+		return "", errors.New("name must be at most 100 characters")
+	case key.RateLimit < 0 || key.MonthlyPlaces < 0:
+		return "", errors.New("limits cannot be negative")
+	}
+
+	if !user.IsAdmin() {
+		key.UserID = user.ID
+		key.RateLimit = s.apiKeyLimits.RateLimit
+		key.MonthlyPlaces = s.apiKeyLimits.MonthlyPlaces
+	}
+
+	if key.UserID == "" {
+		key.UserID = user.ID
+	}
+
+	if _, err := s.userRepo.GetUser(ctx, key.UserID); err != nil {
+		return "", fmt.Errorf("user %s: %w", key.UserID, err)
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	raw := apiKeyPrefix + token
+
+	key.ID = uuid.New().String()
+	key.Prefix = raw[:len(apiKeyPrefix)+6]
+	key.KeyHash = hashToken(raw)
+	key.CreatedAt = time.Now().UTC()
+
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+// APIKeys returns the keys of the user of ctx, all of them for an admin.
+func (s *Service) APIKeys(ctx context.Context) ([]APIKey, error) {
+	if s.apiKeyRepo == nil {
+		return nil, ErrAPIKeysNotSupported
+	}
+
+	var userID string
+
+	if user, ok := UserFromContext(ctx); ok && !user.IsAdmin() {
+		userID = user.ID
+	}
+
+	return s.apiKeyRepo.SelectAPIKeys(ctx, userID)
+}
+
+// GetAPIKey returns the key id, ErrNotFound when the user of ctx may not
+// see it.
+func (s *Service) GetAPIKey(ctx context.Context, id string) (APIKey, error) {
+	if s.apiKeyRepo == nil {
+		return APIKey{}, ErrAPIKeysNotSupported
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	if user, ok := UserFromContext(ctx); ok && !user.IsAdmin() && key.UserID != user.ID {
+		return APIKey{}, ErrNotFound
+	}
+
+	return key, nil
+}
+
+// DeleteAPIKey revokes the key id.
+func (s *Service) DeleteAPIKey(ctx context.Context, id string) error {
+	if _, err := s.GetAPIKey(ctx, id); err != nil {
+		return err
+	}
+
+	return s.apiKeyRepo.DeleteAPIKey(ctx, id)
+}
+
+// APIKeyUser returns the key raw and its user.
+func (s *Service) APIKeyUser(ctx context.Context, raw string) (User, APIKey, error) {
+	if s.apiKeyRepo == nil || s.userRepo == nil {
+		return User{}, APIKey{}, ErrAPIKeysNotSupported
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, hashToken(raw))
+	if errors.Is(err, ErrNotFound) {
+		return User{}, APIKey{}, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return User{}, APIKey{}, err
+	}
+
+	user, err := s.userRepo.GetUser(ctx, key.UserID)
+	if err != nil {
+		return User{}, APIKey{}, err
+	}
+
+	return user, key, nil
+}
+
+// countAPIKeyRequest counts a request made with key.
+func (s *Service) countAPIKeyRequest(ctx context.Context, key *APIKey) error {
+	return s.apiKeyRepo.IncrAPIKeyRequests(ctx, key.ID, monthLabel(time.Now()))
+}
+
+// APIKeyUsage returns the requests, jobs and places of the key id in the
+// month of the given time.
+func (s *Service) APIKeyUsage(ctx context.Context, id string, month time.Time) (APIKeyUsage, error) {
+	key, err := s.GetAPIKey(ctx, id)
+	if err != nil {
+		return APIKeyUsage{}, err
+	}
+
+	ans := APIKeyUsage{
+		KeyID: key.ID,
+		Month: monthLabel(month),
+	}
+
+	ans.Requests, err = s.apiKeyRepo.APIKeyRequests(ctx, key.ID, ans.Month)
+	if err != nil {
+		return APIKeyUsage{}, err
+	}
+
+	all, err := s.apiKeyJobs(ctx, month)
+	if err != nil {
+		return APIKeyUsage{}, err
+	}
+
+	userPlaces := 0
+
+	for i := range all {
+		if all[i].Data.Owner == key.UserID {
+			userPlaces += all[i].Stats.Places
+		}
+
+		if all[i].Data.APIKeyID == key.ID {
+			ans.Jobs++
+			ans.Places += all[i].Stats.Places
+		}
+	}
+
+	if key.MonthlyPlaces > 0 {
+		remaining := max(0, key.MonthlyPlaces-userPlaces)
+		ans.RemainingPlaces = &remaining
+	}
+
+	return ans, nil
+}
+
+// apiKeyJobs returns the jobs created with an API key in the month of the
+// given time.
+func (s *Service) apiKeyJobs(ctx context.Context, month time.Time) ([]Job, error) {
+	all, err := s.repo.Select(ctx, SelectParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	label := monthLabel(month)
+
+	ans := all[:0]
+
+	for i := range all {
+		if all[i].Data.APIKeyID != "" && monthLabel(all[i].Date) == label {
+			ans = append(ans, all[i])
+		}
+	}
+
+	return ans, nil
+}
+
+// checkQuota fails when the jobs created with the keys of the user of key
+// scraped the monthly places of key, so the user does not get a new quota
+// with each new key. The running jobs are not stopped, so the last job of a
+// month can go over.
+func (s *Service) checkQuota(ctx context.Context, key *APIKey) error {
+	if key.MonthlyPlaces <= 0 {
+		return nil
+	}
+
+	jobs, err := s.apiKeyJobs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	places := 0
+
+	for i := range jobs {
+		if jobs[i].Data.Owner == key.UserID {
+			places += jobs[i].Stats.Places
+		}
+	}
+
+	if places >= key.MonthlyPlaces {
+		return fmt.Errorf("%w: %d of %d places", ErrQuotaExceeded, places, key.MonthlyPlaces)
+	}
+
+	return nil
+}
+
+// keyLimiter limits the requests made with the API keys to the rate of the
+// key with a token bucket that holds a minute of requests. The keys of a user
+// share a bucket, so the user does not get a new one with each new key.
+type keyLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*keyBucket
+}
+
+type keyBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newKeyLimiter() *keyLimiter {
+	return &keyLimiter{buckets: make(map[string]*keyBucket)}
+}
+
+// allow takes a token of key, or returns how long until there is one.
+func (l *keyLimiter) allow(key *APIKey, now time.Time) (bool, time.Duration) {
+	if key.RateLimit <= 0 {
+		return true, 0
+	}
+
+	perSecond := float64(key.RateLimit) / 60
+	burst := float64(key.RateLimit)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key.UserID]
+	if !ok {
+		b = &keyBucket{tokens: burst, last: now}
+		l.buckets[key.UserID] = b
+	}
+
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration(math.Ceil((1-b.tokens)/perSecond)) * time.Second
+
+		return false, wait
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+type apiCreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// UserID, RateLimit and MonthlyPlaces are set by the admins only
+	UserID        string `json:"user_id"`
+	RateLimit     int    `json:"rate_limit"`
+	MonthlyPlaces int    `json:"monthly_places"`
+}
+
+type apiCreateAPIKeyResponse struct {
+	APIKey
+	// Key is shown once, only its hash is kept
+	Key string `json:"key"`
+}
+
+func (s *Server) apiCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apiCreateAPIKeyRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	key := APIKey{
+		Name:          req.Name,
+		UserID:        req.UserID,
+		RateLimit:     req.RateLimit,
+		MonthlyPlaces: req.MonthlyPlaces,
+	}
+
+	raw, err := s.svc.CreateAPIKey(r.Context(), &key)
+	if errors.Is(err, ErrAPIKeyCreatesKey) {
+		apiError := apiError{
+			Code:    http.StatusForbidden,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusForbidden, apiError)
+
+		return
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, apiCreateAPIKeyResponse{APIKey: key, Key: raw})
+}
+
+func (s *Server) apiGetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.svc.APIKeys(r.Context())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	if keys == nil {
+		keys = []APIKey{}
+	}
+
+	renderJSON(w, http.StatusOK, keys)
+}
+
+func (s *Server) apiDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if err := s.svc.DeleteAPIKey(r.Context(), id.String()); err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiGetAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	month := time.Now().UTC()
+
+	if v := r.URL.Query().Get("month"); v != "" {
+		var err error
+
+		month, err = time.Parse("2006-01", v)
+		if err != nil {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "month must be in the YYYY-MM format",
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+	}
+
+	usage, err := s.svc.APIKeyUsage(r.Context(), id.String(), month)
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, usage)
+}
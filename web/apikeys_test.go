@@ -0,0 +1,494 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memUsers is a UserRepository and an APIKeyRepository in memory.
+type memUsers struct {
+	users    map[string]User
+	sessions map[string]Session
+	keys     map[string]APIKey
+	requests map[string]int
+}
+
+func newMemUsers(users ...User) *memUsers {
+	ans := memUsers{
+		users:    map[string]User{},
+		sessions: map[string]Session{},
+		keys:     map[string]APIKey{},
+		requests: map[string]int{},
+	}
+
+	for i := range users {
+		ans.users[users[i].ID] = users[i]
+	}
+
+	return &ans
+}
+
+func (m *memUsers) CreateUser(_ context.Context, u *User) error {
+	m.users[u.ID] = *u
+
+	return nil
+}
+
+func (m *memUsers) GetUser(_ context.Context, id string) (User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+
+	return u, nil
+}
+
+func (m *memUsers) GetUserByUsername(_ context.Context, username string) (User, error) {
+	for _, u := range m.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+
+	return User{}, ErrNotFound
+}
+
+func (m *memUsers) GetUserByExternal(_ context.Context, external string) (User, error) {
+	for _, u := range m.users {
+		if external != "" && u.External == external {
+			return u, nil
+		}
+	}
+
+	return User{}, ErrNotFound
+}
+
+func (m *memUsers) SelectUsers(context.Context) ([]User, error) {
+	var ans []User
+
+	for _, u := range m.users {
+		ans = append(ans, u)
+	}
+
+	return ans, nil
+}
+
+func (m *memUsers) UpdateUser(_ context.Context, u *User) error {
+	m.users[u.ID] = *u
+
+	return nil
+}
+
+func (m *memUsers) DeleteUser(_ context.Context, id string) error {
+	delete(m.users, id)
+
+	return nil
+}
+
+func (m *memUsers) CreateSession(_ context.Context, s *Session) error {
+	m.sessions[s.TokenHash] = *s
+
+	return nil
+}
+
+func (m *memUsers) GetSession(_ context.Context, tokenHash string) (Session, error) {
+	s, ok := m.sessions[tokenHash]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+
+	return s, nil
+}
+
+func (m *memUsers) DeleteSession(_ context.Context, tokenHash string) error {
+	delete(m.sessions, tokenHash)
+
+	return nil
+}
+
+func (m *memUsers) DeleteExpiredSessions(_ context.Context, now time.Time) error {
+	for k, s := range m.sessions {
+		if s.ExpiresAt.Before(now) {
+			delete(m.sessions, k)
+		}
+	}
+
+	return nil
+}
+
+func (m *memUsers) CreateAPIKey(_ context.Context, key *APIKey) error {
+	m.keys[key.ID] = *key
+
+	return nil
+}
+
+func (m *memUsers) GetAPIKey(_ context.Context, id string) (APIKey, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return APIKey{}, ErrNotFound
+	}
+
+	return key, nil
+}
+
+func (m *memUsers) GetAPIKeyByHash(_ context.Context, keyHash string) (APIKey, error) {
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+
+	return APIKey{}, ErrNotFound
+}
+
+func (m *memUsers) SelectAPIKeys(_ context.Context, userID string) ([]APIKey, error) {
+	var ans []APIKey
+
+	for _, key := range m.keys {
+		if userID == "" || key.UserID == userID {
+			ans = append(ans, key)
+		}
+	}
+
+	return ans, nil
+}
+
+func (m *memUsers) DeleteAPIKey(_ context.Context, id string) error {
+	delete(m.keys, id)
+
+	return nil
+}
+
+func (m *memUsers) IncrAPIKeyRequests(_ context.Context, keyID, month string) error {
+	m.requests[keyID+month]++
+
+	return nil
+}
+
+func (m *memUsers) APIKeyRequests(_ context.Context, keyID, month string) (int, error) {
+	return m.requests[keyID+month], nil
+}
+
+// memSchedules is a ScheduleRepository in memory.
+type memSchedules struct {
+	schedules map[string]Schedule
+	runs      []ScheduleRun
+}
+
+func (m *memSchedules) CreateSchedule(_ context.Context, sc *Schedule) error {
+	m.schedules[sc.ID] = *sc
+
+	return nil
+}
+
+func (m *memSchedules) GetSchedule(_ context.Context, id string) (Schedule, error) {
+	sc, ok := m.schedules[id]
+	if !ok {
+		return Schedule{}, ErrNotFound
+	}
+
+	return sc, nil
+}
+
+func (m *memSchedules) SelectSchedules(context.Context) ([]Schedule, error) {
+	var ans []Schedule
+
+	for _, sc := range m.schedules {
+		ans = append(ans, sc)
+	}
+
+	return ans, nil
+}
+
+func (m *memSchedules) UpdateSchedule(_ context.Context, sc *Schedule) error {
+	m.schedules[sc.ID] = *sc
+
+	return nil
+}
+
+func (m *memSchedules) ClaimScheduleRun(_ context.Context, id string, prev, next time.Time) (bool, error) {
+	sc, ok := m.schedules[id]
+	if !ok || !sc.NextRun.Equal(prev) {
+		return false, nil
+	}
+
+	sc.NextRun = next
+	m.schedules[id] = sc
+
+	return true, nil
+}
+
+func (m *memSchedules) DeleteSchedule(_ context.Context, id string) error {
+	delete(m.schedules, id)
+
+	return nil
+}
+
+func (m *memSchedules) InsertScheduleRun(_ context.Context, run *ScheduleRun) error {
+	m.runs = append(m.runs, *run)
+
+	return nil
+}
+
+func (m *memSchedules) SelectScheduleRuns(context.Context, string, int) ([]ScheduleRun, error) {
+	return m.runs, nil
+}
+
+var (
+	testAdmin = User{ID: "admin", Username: "admin", Role: RoleAdmin}
+	testAlice = User{ID: "alice", Username: "alice", Role: RoleUser}
+	testBob   = User{ID: "bob", Username: "bob", Role: RoleUser}
+)
+
+func newKeyService(repo *memRepo) (*Service, *memUsers) {
+	users := newMemUsers(testAdmin, testAlice, testBob)
+
+	svc := NewService(repo, "",
+		WithUsers(users),
+		WithAPIKeys(users, APIKeyLimits{RateLimit: 2, MonthlyPlaces: 100}),
+	)
+
+	return svc, users
+}
+
+func userContext(u User) context.Context {
+	return ContextWithUser(context.Background(), &u)
+}
+
+func testJobData() JobData {
+	return JobData{Keywords: []string{"cafe"}, Lang: "en", Depth: 1, MaxTime: time.Minute}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	svc, _ := newKeyService(newMemRepo())
+
+	// a user gets the default limits whatever it asks for
+	key := APIKey{Name: "crm", UserID: "bob", RateLimit: 1000}
+
+	raw, err := svc.CreateAPIKey(userContext(testAlice), &key)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(raw, apiKeyPrefix))
+	require.Equal(t, "alice", key.UserID)
+	require.Equal(t, 2, key.RateLimit)
+	require.Equal(t, 100, key.MonthlyPlaces)
+
+	user, got, err := svc.APIKeyUser(context.Background(), raw)
+	require.NoError(t, err)
+	require.Equal(t, "alice", user.ID)
+	require.Equal(t, key.ID, got.ID)
+
+	_, _, err = svc.APIKeyUser(context.Background(), apiKeyPrefix+"unknown")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// a request made with a key cannot create another one
+	ctx := ContextWithAPIKey(userContext(testAlice), &got)
+
+	_, err = svc.CreateAPIKey(ctx, &APIKey{Name: "more"})
+	require.ErrorIs(t, err, ErrAPIKeyCreatesKey)
+
+	// the admins choose the limits and the user
+	key = APIKey{Name: "etl", UserID: "bob", RateLimit: 1000}
+
+	_, err = svc.CreateAPIKey(userContext(testAdmin), &key)
+	require.NoError(t, err)
+	require.Equal(t, "bob", key.UserID)
+	require.Equal(t, 1000, key.RateLimit)
+	require.Zero(t, key.MonthlyPlaces)
+
+	// a user sees its keys only
+	_, err = svc.GetAPIKey(userContext(testAlice), key.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	keys, err := svc.APIKeys(userContext(testAlice))
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+}
+
+func TestAPIKeyQuotaPerUser(t *testing.T) {
+	now := time.Now().UTC()
+
+	repo := newMemRepo(
+		// the places of the jobs of the other keys of alice count
+		Job{ID: "1", Date: now, Data: JobData{Owner: "alice", APIKeyID: "old"}, Stats: JobStats{Places: 60}},
+		Job{ID: "2", Date: now, Data: JobData{Owner: "alice", APIKeyID: "revoked"}, Stats: JobStats{Places: 40}},
+		// the ones of bob, of the jobs of alice without a key and of last month do not
+		Job{ID: "3", Date: now, Data: JobData{Owner: "bob", APIKeyID: "bob"}, Stats: JobStats{Places: 500}},
+		Job{ID: "4", Date: now, Data: JobData{Owner: "alice"}, Stats: JobStats{Places: 500}},
+		Job{ID: "5", Date: now.AddDate(0, -1, 0), Data: JobData{Owner: "alice", APIKeyID: "old"}, Stats: JobStats{Places: 500}},
+	)
+
+	svc, _ := newKeyService(repo)
+
+	key := APIKey{Name: "new"}
+
+	_, err := svc.CreateAPIKey(userContext(testAlice), &key)
+	require.NoError(t, err)
+
+	ctx := ContextWithAPIKey(userContext(testAlice), &key)
+
+	job := Job{ID: "6", Name: "cafes", Date: now, Status: StatusPending, Data: testJobData()}
+
+	err = svc.Create(ctx, &job)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	err = svc.CreateJobs(ctx, []Job{job})
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	usage, err := svc.APIKeyUsage(userContext(testAlice), key.ID, now)
+	require.NoError(t, err)
+	require.Zero(t, usage.Jobs)
+	require.Zero(t, usage.Places)
+	require.Equal(t, 0, *usage.RemainingPlaces)
+
+	// bob has places left
+	bobKey := APIKey{Name: "bob"}
+
+	_, err = svc.CreateAPIKey(userContext(testBob), &bobKey)
+	require.NoError(t, err)
+
+	repo.jobs["3"] = Job{ID: "3", Date: now, Data: JobData{Owner: "bob", APIKeyID: bobKey.ID}, Stats: JobStats{Places: 30}}
+
+	require.NoError(t, svc.Create(ContextWithAPIKey(userContext(testBob), &bobKey), &job))
+	require.Equal(t, bobKey.ID, repo.jobs["6"].Data.APIKeyID)
+
+	usage, err = svc.APIKeyUsage(userContext(testBob), bobKey.ID, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, usage.Jobs)
+	require.Equal(t, 30, usage.Places)
+	require.Equal(t, 70, *usage.RemainingPlaces)
+}
+
+func TestCreateClearsAPIKeyID(t *testing.T) {
+	repo := newMemRepo()
+	svc, _ := newKeyService(repo)
+
+	data := testJobData()
+	data.APIKeyID = "someone-else"
+
+	job := Job{ID: "1", Name: "cafes", Date: time.Now().UTC(), Status: StatusPending, Data: data}
+
+	require.NoError(t, svc.Create(userContext(testAlice), &job))
+	require.Empty(t, repo.jobs["1"].Data.APIKeyID)
+
+	job.ID = "2"
+
+	require.NoError(t, svc.CreateJobs(userContext(testAlice), []Job{job}))
+	require.Empty(t, repo.jobs["2"].Data.APIKeyID)
+}
+
+func TestScheduleQuota(t *testing.T) {
+	repo := newMemRepo()
+	schedules := &memSchedules{schedules: map[string]Schedule{}}
+
+	users := newMemUsers(testAlice)
+
+	svc := NewService(repo, "",
+		WithUsers(users),
+		WithAPIKeys(users, APIKeyLimits{MonthlyPlaces: 100}),
+		WithSchedules(schedules),
+	)
+
+	key := APIKey{Name: "cron"}
+
+	_, err := svc.CreateAPIKey(userContext(testAlice), &key)
+	require.NoError(t, err)
+
+	sc := Schedule{Name: "daily", Cron: "@daily", Data: testJobData()}
+
+	require.NoError(t, svc.CreateSchedule(ContextWithAPIKey(userContext(testAlice), &key), &sc))
+	require.Equal(t, key.ID, sc.Data.APIKeyID)
+
+	now := time.Now().UTC()
+
+	// the first run creates a job of the key
+	require.NoError(t, svc.runSchedule(context.Background(), &sc, now))
+	require.Equal(t, ScheduleRunCreated, schedules.runs[0].Status)
+
+	first := repo.jobs[schedules.runs[0].JobID]
+	require.Equal(t, key.ID, first.Data.APIKeyID)
+
+	// it used up the quota, the next run fails
+	first.Status = StatusOK
+	first.Stats.Places = 100
+	repo.jobs[first.ID] = first
+
+	require.NoError(t, svc.runSchedule(context.Background(), &sc, now))
+	require.Equal(t, ScheduleRunFailed, schedules.runs[1].Status)
+	require.Contains(t, schedules.runs[1].Reason, ErrQuotaExceeded.Error())
+
+	// and so do the runs of a revoked key
+	require.NoError(t, users.DeleteAPIKey(context.Background(), key.ID))
+
+	first.Stats.Places = 0
+	repo.jobs[first.ID] = first
+
+	require.NoError(t, svc.runSchedule(context.Background(), &sc, now))
+	require.Equal(t, ScheduleRunFailed, schedules.runs[2].Status)
+	require.Len(t, repo.jobs, 1)
+}
+
+func TestKeyLimiterPerUser(t *testing.T) {
+	l := newKeyLimiter()
+	now := time.Now()
+
+	first := &APIKey{ID: "1", UserID: "alice", RateLimit: 2}
+	second := &APIKey{ID: "2", UserID: "alice", RateLimit: 2}
+
+	ok, _ := l.allow(first, now)
+	require.True(t, ok)
+
+	ok, _ = l.allow(second, now)
+	require.True(t, ok)
+
+	// a new key of the user does not get a new bucket
+	ok, wait := l.allow(second, now)
+	require.False(t, ok)
+	require.Equal(t, 30*time.Second, wait)
+
+	ok, _ = l.allow(&APIKey{ID: "3", UserID: "bob", RateLimit: 2}, now)
+	require.True(t, ok)
+
+	ok, _ = l.allow(first, now.Add(30*time.Second))
+	require.True(t, ok)
+}
+
+func TestAPICreateAPIKeyWithKey(t *testing.T) {
+	svc, _ := newKeyService(newMemRepo())
+	s := &Server{svc: svc}
+
+	key := APIKey{ID: "1", UserID: "alice"}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/keys", strings.NewReader(`{"name": "more"}`))
+	req = req.WithContext(ContextWithAPIKey(userContext(testAlice), &key))
+
+	w := httptest.NewRecorder()
+	s.apiCreateAPIKey(w, req)
+
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUsageByUser(t *testing.T) {
+	now := time.Now().UTC()
+
+	svc := NewService(newMemRepo(
+		Job{ID: "1", Date: now, Status: StatusOK, Data: JobData{Owner: "alice", Tenant: "sales"}, Stats: JobStats{Places: 10}},
+		// bob cannot bill alice's tenant to her
+		Job{ID: "2", Date: now, Status: StatusFailed, Data: JobData{Owner: "bob", Tenant: "sales"}, Stats: JobStats{Places: 5}},
+		Job{ID: "3", Date: now, Status: StatusOK, Data: JobData{Owner: "alice"}, Stats: JobStats{Places: 1}},
+	), "")
+
+	usage, err := svc.Usage(context.Background(), now)
+	require.NoError(t, err)
+	require.Len(t, usage, 3)
+
+	require.Equal(t, []string{"alice", "alice", "bob"}, []string{usage[0].User, usage[1].User, usage[2].User})
+	require.Equal(t, []string{DefaultTenant, "sales", "sales"}, []string{usage[0].Tenant, usage[1].Tenant, usage[2].Tenant})
+	require.Equal(t, 10, usage[1].Places)
+	require.Equal(t, 1, usage[2].Failed)
+}
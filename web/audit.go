@@ -0,0 +1,176 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The actions recorded in the audit log.
+const (
+	AuditJobCreate   = "job.create"
+	AuditJobPause    = "job.pause"
+	AuditJobResume   = "job.resume"
+	AuditJobDelete   = "job.delete"
+	AuditJobDownload = "job.download"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditEntry records who did an action on a job.
+type AuditEntry struct {
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"`
+	// UserID and Username are empty for the actions of the runner, the
+	// schedules and the servers without -auth
+	UserID   string `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	APIKeyID string `json:"api_key_id,omitempty"`
+	Action   string `json:"action"`
+	JobID    string `json:"job_id"`
+	// Details is e.g. the file of a download
+	Details string `json:"details,omitempty"`
+}
+
+// AuditParams filters the audit log. The zero values match everything.
+type AuditParams struct {
+	JobID  string
+	UserID string
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// AuditRepository stores the audit log. The entries outlive their jobs.
+type AuditRepository interface {
+	CreateAuditEntry(context.Context, *AuditEntry) error
+	// SelectAuditEntries returns the entries matching params, the newest first.
+	SelectAuditEntries(context.Context, AuditParams) ([]AuditEntry, error)
+}
+
+// WithAudit records the creation, pause, resume, deletion and downloads of
+// the jobs in repo.
+func WithAudit(repo AuditRepository) ServiceOption {
+	return func(s *Service) {
+		s.auditRepo = repo
+	}
+}
+
+// audit records action on the job jobID by the user of ctx. A failure is
+// logged only, the action is already done.
+func (s *Service) audit(ctx context.Context, action, jobID, details string) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:    time.Now().UTC(),
+		Action:  action,
+		JobID:   jobID,
+		Details: details,
+	}
+
+	if user, ok := UserFromContext(ctx); ok {
+		entry.UserID = user.ID
+		entry.Username = user.Username
+	}
+
+	if key, ok := APIKeyFromContext(ctx); ok {
+		entry.APIKeyID = key.ID
+	}
+
+	if err := s.auditRepo.CreateAuditEntry(ctx, &entry); err != nil {
+		log.Printf("failed to record %s of job %s in the audit log: %v", action, jobID, err)
+	}
+}
+
+// AuditLog returns the entries of the audit log matching params.
+func (s *Service) AuditLog(ctx context.Context, params AuditParams) ([]AuditEntry, error) {
+	if s.auditRepo == nil {
+		return nil, nil
+	}
+
+	if params.Limit <= 0 {
+		params.Limit = defaultAuditLimit
+	}
+
+	params.Limit = min(params.Limit, maxAuditLimit)
+
+	return s.auditRepo.SelectAuditEntries(ctx, params)
+}
+
+// apiGetAudit serves the audit log, to the admins only with -auth.
+func (s *Server) apiGetAudit(w http.ResponseWriter, r *http.Request) {
+	if _, ok := UserFromContext(r.Context()); ok && !requireAdmin(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+
+	params := AuditParams{
+		JobID:  q.Get("job_id"),
+		UserID: q.Get("user_id"),
+		Action: q.Get("action"),
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &params.Since, "until": &params.Until} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: name + " must be an RFC 3339 time",
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+
+		*dst = t
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "limit must be a positive number",
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+
+		params.Limit = limit
+	}
+
+	entries, err := s.svc.AuditLog(r.Context(), params)
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+
+	renderJSON(w, http.StatusOK, entries)
+}
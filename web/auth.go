@@ -0,0 +1,436 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The roles of the users.
+const (
+	// RoleAdmin sees and manages all the jobs and the users
+	RoleAdmin = "admin"
+	// RoleUser sees and manages the jobs it created
+	RoleUser = "user"
+)
+
+const (
+	sessionTTL        = 7 * 24 * time.Hour
+	minPasswordLength = 8
+
+	// passwordCacheTTL is how long a checked password is not checked again,
+	// so the API calls with basic auth do not all run bcrypt
+	passwordCacheTTL  = 5 * time.Minute
+	passwordCacheSize = 1000
+)
+
+// User is an account of the dashboard and the API.
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	// PasswordHash is the bcrypt hash of the password, empty for the users
+	// that sign in with OIDC only
+	PasswordHash string `json:"-"`
+	// External is the issuer and the subject of the users signed in by an
+	// identity provider, empty for the users with a password
+	External  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsAdmin reports whether the user sees all the jobs.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// Session is a signed in user. Only the hash of its token is stored.
+type Session struct {
+	TokenHash string
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// UserRepository stores the users and their sessions.
+type UserRepository interface {
+	CreateUser(context.Context, *User) error
+	GetUser(context.Context, string) (User, error)
+	GetUserByUsername(context.Context, string) (User, error)
+	// GetUserByExternal returns the user of an identity provider by its issuer
+	// and subject.
+	GetUserByExternal(context.Context, string) (User, error)
+	SelectUsers(context.Context) ([]User, error)
+	UpdateUser(context.Context, *User) error
+	// DeleteUser deletes the user, its sessions and its API keys, not its jobs.
+	DeleteUser(context.Context, string) error
+	CreateSession(context.Context, *Session) error
+	GetSession(context.Context, string) (Session, error)
+	DeleteSession(context.Context, string) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) error
+}
+
+var (
+	// ErrUsersNotSupported is returned when the repository does not store users.
+	ErrUsersNotSupported = errors.New("users are not supported")
+	// ErrInvalidCredentials is returned when the username or the password is wrong.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// WithUsers keeps the users and their sessions in repo.
+func WithUsers(repo UserRepository) ServiceOption {
+	return func(s *Service) {
+		s.userRepo = repo
+		s.passwords = newPasswordCache()
+	}
+}
+
+const userCtxKey ctxKey = "user"
+
+// ContextWithUser returns ctx for the requests of user. The jobs of the
+// service are then limited to the ones of user, unless it is an admin.
+func ContextWithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userCtxKey, user)
+}
+
+// UserFromContext returns the user of ctx, false when the request is not
+// authenticated, e.g. the ones of the runner or without -auth.
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userCtxKey).(*User)
+
+	return user, ok
+}
+
+// canAccess reports whether the user of ctx may see the jobs and schedules
+// of the data d.
+func canAccess(ctx context.Context, d *JobData) bool {
+	user, ok := UserFromContext(ctx)
+	if !ok || user.IsAdmin() {
+		return true
+	}
+
+	return d.Owner == user.ID
+}
+
+// setOwner makes the user of ctx the owner of the job or schedule of d.
+func setOwner(ctx context.Context, d *JobData) {
+	if user, ok := UserFromContext(ctx); ok {
+		d.Owner = user.ID
+	}
+}
+
+func validateUser(u *User, password string) error {
+	switch {
+	case u.Username == "":
+		return errors.New("missing username")
+	case len(u.Username) > 100:
+		return errors.New("username must be at most 100 characters")
+	case u.Role != RoleAdmin && u.Role != RoleUser:
+		return fmt.Errorf("invalid role %q", u.Role)
+	case len(password) < minPasswordLength:
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	return nil
+}
+
+// CreateUser validates and stores a new user with password.
+func (s *Service) CreateUser(ctx context.Context, user *User, password string) error {
+	if s.userRepo == nil {
+		return ErrUsersNotSupported
+	}
+
+	user.Username = strings.TrimSpace(user.Username)
+
+	if err := validateUser(user, password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.ID = uuid.New().String()
+	user.PasswordHash = string(hash)
+	user.CreatedAt = time.Now().UTC()
+
+	return s.userRepo.CreateUser(ctx, user)
+}
+
+func (s *Service) Users(ctx context.Context) ([]User, error) {
+	if s.userRepo == nil {
+		return nil, ErrUsersNotSupported
+	}
+
+	return s.userRepo.SelectUsers(ctx)
+}
+
+func (s *Service) DeleteUser(ctx context.Context, id string) error {
+	if s.userRepo == nil {
+		return ErrUsersNotSupported
+	}
+
+	return s.userRepo.DeleteUser(ctx, id)
+}
+
+// EnsureAdmin creates the admin username with password, or resets its
+// password and makes it an admin when it exists.
+func (s *Service) EnsureAdmin(ctx context.Context, username, password string) error {
+	if s.userRepo == nil {
+		return ErrUsersNotSupported
+	}
+
+	user, err := s.userRepo.GetUserByUsername(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		err = s.CreateUser(ctx, &User{Username: username, Role: RoleAdmin}, password)
+		// another server sharing the users created it meanwhile
+		if !errors.Is(err, ErrAlreadyExists) {
+			return err
+		}
+
+		user, err = s.userRepo.GetUserByUsername(ctx, username)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	user.Role = RoleAdmin
+
+	if err := validateUser(&user, password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = string(hash)
+
+	return s.userRepo.UpdateUser(ctx, &user)
+}
+
+// Authenticate returns the user of username when password is its password.
+func (s *Service) Authenticate(ctx context.Context, username, password string) (User, error) {
+	if s.userRepo == nil {
+		return User{}, ErrUsersNotSupported
+	}
+
+	user, err := s.userRepo.GetUserByUsername(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return User{}, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		return User{}, err
+	}
+
+	if user.PasswordHash == "" {
+		return User{}, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	key := s.passwords.key(username, password)
+
+	if s.passwords.checked(key, user.PasswordHash, now) {
+		return user, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return User{}, ErrInvalidCredentials
+	}
+
+	s.passwords.add(key, user.PasswordHash, now)
+
+	return user, nil
+}
+
+// externalUser returns the user signed in by an identity provider with the
+// issuer and subject external, which is created the first time as username.
+// It is never merged with another user: when username is taken, it gets a
+// suffix. admin makes it an admin, it is never demoted.
+func (s *Service) externalUser(ctx context.Context, external, username string, admin bool) (User, error) {
+	if s.userRepo == nil {
+		return User{}, ErrUsersNotSupported
+	}
+
+	user, err := s.userRepo.GetUserByExternal(ctx, external)
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return User{}, err
+	case admin && !user.IsAdmin():
+		user.Role = RoleAdmin
+
+		return user, s.userRepo.UpdateUser(ctx, &user)
+	default:
+		return user, nil
+	}
+
+	user = User{
+		ID:        uuid.New().String(),
+		Username:  username,
+		Role:      RoleUser,
+		External:  external,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if admin {
+		user.Role = RoleAdmin
+	}
+
+	_, err = s.userRepo.GetUserByUsername(ctx, username)
+
+	switch {
+	case err == nil:
+		sum := sha256.Sum256([]byte(external))
+		user.Username += "-" + hex.EncodeToString(sum[:3])
+	case !errors.Is(err, ErrNotFound):
+		return User{}, err
+	}
+
+	return user, s.userRepo.CreateUser(ctx, &user)
+}
+
+// passwordCache keeps the passwords checked recently, as an HMAC with a key
+// of the process, with the hash they were checked against. A password is
+// checked again when the hash changed.
+type passwordCache struct {
+	secret []byte
+
+	mu      sync.Mutex
+	entries map[string]passwordCacheEntry
+}
+
+type passwordCacheEntry struct {
+	hash    string
+	expires time.Time
+}
+
+func newPasswordCache() *passwordCache {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+
+	return &passwordCache{secret: secret, entries: make(map[string]passwordCacheEntry)}
+}
+
+func (c *passwordCache) key(username, password string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+
+	return string(mac.Sum(nil))
+}
+
+// checked reports whether the password of key was checked against hash
+// less than passwordCacheTTL ago.
+func (c *passwordCache) checked(key, hash string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+
+	return ok && e.hash == hash && now.Before(e.expires)
+}
+
+func (c *passwordCache) add(key, hash string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= passwordCacheSize {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+
+	if len(c.entries) >= passwordCacheSize {
+		clear(c.entries)
+	}
+
+	c.entries[key] = passwordCacheEntry{hash: hash, expires: now.Add(passwordCacheTTL)}
+}
+
+// NewSession signs user in and returns the token of its session.
+func (s *Service) NewSession(ctx context.Context, user *User) (string, error) {
+	if s.userRepo == nil {
+		return "", ErrUsersNotSupported
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+
+	// a good time to forget the old sessions
+	if err := s.userRepo.DeleteExpiredSessions(ctx, now); err != nil {
+		return "", err
+	}
+
+	session := Session{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(sessionTTL),
+	}
+
+	return token, s.userRepo.CreateSession(ctx, &session)
+}
+
+// SessionUser returns the user of the session token.
+func (s *Service) SessionUser(ctx context.Context, token string) (User, error) {
+	if s.userRepo == nil {
+		return User{}, ErrUsersNotSupported
+	}
+
+	session, err := s.userRepo.GetSession(ctx, hashToken(token))
+	if err != nil {
+		return User{}, err
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return User{}, ErrNotFound
+	}
+
+	return s.userRepo.GetUser(ctx, session.UserID)
+}
+
+// DeleteSession signs the session token out.
+func (s *Service) DeleteSession(ctx context.Context, token string) error {
+	if s.userRepo == nil {
+		return ErrUsersNotSupported
+	}
+
+	return s.userRepo.DeleteSession(ctx, hashToken(token))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns 32 random bytes in base64.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,461 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newUserService(jobs ...Job) (*Service, *memUsers) {
+	users := newMemUsers()
+
+	return NewService(newMemRepo(jobs...), "", WithUsers(users)), users
+}
+
+func TestSession(t *testing.T) {
+	svc, users := newUserService()
+	ctx := context.Background()
+
+	alice := User{Username: "alice", Role: RoleUser}
+	require.NoError(t, svc.CreateUser(ctx, &alice, "a long password"))
+
+	token, err := svc.NewSession(ctx, &alice)
+	require.NoError(t, err)
+
+	got, err := svc.SessionUser(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, alice.ID, got.ID)
+
+	// only the hash of the token is stored
+	_, ok := users.sessions[token]
+	require.False(t, ok)
+
+	_, err = svc.SessionUser(ctx, "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, svc.DeleteSession(ctx, token))
+
+	_, err = svc.SessionUser(ctx, token)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// an expired session signs nobody in
+	token, err = svc.NewSession(ctx, &alice)
+	require.NoError(t, err)
+
+	session := users.sessions[hashToken(token)]
+	session.ExpiresAt = time.Now().Add(-time.Minute)
+	users.sessions[hashToken(token)] = session
+
+	_, err = svc.SessionUser(ctx, token)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAuthenticate(t *testing.T) {
+	svc, users := newUserService()
+	ctx := context.Background()
+
+	alice := User{Username: "alice", Role: RoleUser}
+	require.NoError(t, svc.CreateUser(ctx, &alice, "a long password"))
+
+	_, err := svc.Authenticate(ctx, "alice", "wrong password")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = svc.Authenticate(ctx, "bob", "a long password")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	got, err := svc.Authenticate(ctx, "alice", "a long password")
+	require.NoError(t, err)
+	require.Equal(t, alice.ID, got.ID)
+
+	// the password checked is cached
+	got, err = svc.Authenticate(ctx, "alice", "a long password")
+	require.NoError(t, err)
+	require.Equal(t, alice.ID, got.ID)
+
+	// until the password changes
+	hash, err := bcrypt.GenerateFromPassword([]byte("another password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	alice.PasswordHash = string(hash)
+	require.NoError(t, users.UpdateUser(ctx, &alice))
+
+	_, err = svc.Authenticate(ctx, "alice", "a long password")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = svc.Authenticate(ctx, "alice", "another password")
+	require.NoError(t, err)
+
+	// the users of an identity provider have no password
+	external, err := svc.externalUser(ctx, "https://idp.example.com#1", "carol", false)
+	require.NoError(t, err)
+
+	_, err = svc.Authenticate(ctx, external.Username, "")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestPasswordCache(t *testing.T) {
+	c := newPasswordCache()
+	now := time.Now()
+
+	key := c.key("alice", "secret")
+	require.NotEqual(t, key, c.key("alic", "esecret"))
+	require.NotEqual(t, key, newPasswordCache().key("alice", "secret"))
+
+	require.False(t, c.checked(key, "hash", now))
+
+	c.add(key, "hash", now)
+	require.True(t, c.checked(key, "hash", now))
+	require.False(t, c.checked(key, "other hash", now))
+	require.False(t, c.checked(key, "hash", now.Add(passwordCacheTTL)))
+}
+
+func TestOwnership(t *testing.T) {
+	now := time.Now().UTC()
+
+	svc, _ := newUserService(
+		Job{ID: "a", Date: now, Data: JobData{Owner: "alice"}},
+		Job{ID: "b", Date: now, Data: JobData{Owner: "bob"}},
+	)
+
+	jobs, err := svc.All(userContext(testAlice))
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "a", jobs[0].ID)
+
+	_, err = svc.Get(userContext(testAlice), "b")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = svc.Delete(userContext(testAlice), "b")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	jobs, err = svc.All(userContext(testAdmin))
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	// the owner is the user that creates the job, whatever the job says
+	data := testJobData()
+	data.Owner = "bob"
+
+	job := Job{ID: "c", Name: "cafes", Date: now, Status: StatusPending, Data: data}
+	require.NoError(t, svc.Create(userContext(testAlice), &job))
+
+	got, err := svc.Get(userContext(testAlice), "c")
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Data.Owner)
+}
+
+// racingUsers is a user store where another server creates the admin between
+// the lookup and the creation of EnsureAdmin.
+type racingUsers struct {
+	*memUsers
+	raced bool
+}
+
+func (r *racingUsers) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	if !r.raced {
+		return User{}, ErrNotFound
+	}
+
+	return r.memUsers.GetUserByUsername(ctx, username)
+}
+
+func (r *racingUsers) CreateUser(ctx context.Context, u *User) error {
+	r.raced = true
+
+	other := User{ID: "other", Username: u.Username, Role: RoleAdmin, PasswordHash: "other"}
+	if err := r.memUsers.CreateUser(ctx, &other); err != nil {
+		return err
+	}
+
+	return ErrAlreadyExists
+}
+
+func TestEnsureAdminConcurrent(t *testing.T) {
+	users := &racingUsers{memUsers: newMemUsers()}
+	svc := NewService(newMemRepo(), "", WithUsers(users))
+	ctx := context.Background()
+
+	require.NoError(t, svc.EnsureAdmin(ctx, "admin", "a long password"))
+
+	// the admin the other server created gets the password
+	admin, err := svc.Authenticate(ctx, "admin", "a long password")
+	require.NoError(t, err)
+	require.Equal(t, "other", admin.ID)
+}
+
+func TestExternalUser(t *testing.T) {
+	svc, users := newUserService()
+	ctx := context.Background()
+
+	require.NoError(t, svc.EnsureAdmin(ctx, "admin", "a long password"))
+
+	local, err := users.GetUserByUsername(ctx, "admin")
+	require.NoError(t, err)
+
+	// an identity named like the admin is another user
+	user, err := svc.externalUser(ctx, "https://idp.example.com#1", "admin", false)
+	require.NoError(t, err)
+	require.NotEqual(t, local.ID, user.ID)
+	require.True(t, strings.HasPrefix(user.Username, "admin-"))
+	require.False(t, user.IsAdmin())
+	require.Empty(t, user.PasswordHash)
+
+	// it is the same user the next time
+	again, err := svc.externalUser(ctx, "https://idp.example.com#1", "renamed", false)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, again.ID)
+
+	// and it is made an admin, never demoted
+	again, err = svc.externalUser(ctx, "https://idp.example.com#1", "admin", true)
+	require.NoError(t, err)
+	require.True(t, again.IsAdmin())
+
+	again, err = svc.externalUser(ctx, "https://idp.example.com#1", "admin", false)
+	require.NoError(t, err)
+	require.True(t, again.IsAdmin())
+
+	// the same subject of another issuer is another user
+	other, err := svc.externalUser(ctx, "https://other.example.com#1", "dave", false)
+	require.NoError(t, err)
+	require.NotEqual(t, user.ID, other.ID)
+	require.Equal(t, "dave", other.Username)
+
+	local, err = users.GetUser(ctx, local.ID)
+	require.NoError(t, err)
+	require.Empty(t, local.External)
+}
+
+func TestOIDCClaims(t *testing.T) {
+	p := &oidcProvider{cfg: OIDCConfig{Admins: []string{"Ops@example.com"}}}
+
+	unverified := oidcClaims{Sub: "1", Email: "ops@example.com", PreferredUsername: "ops"}
+	require.Equal(t, "ops", unverified.username())
+	require.False(t, p.isAdmin(&unverified))
+
+	verified := oidcClaims{Sub: "1", Email: "OPS@example.com", EmailVerified: true}
+	require.Equal(t, "ops@example.com", verified.username())
+	require.True(t, p.isAdmin(&verified))
+
+	// some providers send the flag as a string
+	verified.EmailVerified = "true"
+	require.True(t, p.isAdmin(&verified))
+
+	verified.EmailVerified = "false"
+	require.False(t, p.isAdmin(&verified))
+
+	require.Equal(t, "1", (&oidcClaims{Sub: "1"}).username())
+	require.Equal(t, "https://idp.example.com#1", verified.external("https://idp.example.com/"))
+}
+
+// newOIDCProvider serves the discovery, token and userinfo endpoints of a
+// provider that signs in the user of claims.
+func newOIDCProvider(t *testing.T, claims map[string]any) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"userinfo_endpoint":      srv.URL + "/userinfo",
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "code", r.Form.Get("code"))
+		require.Equal(t, "verifier", r.Form.Get("code_verifier"))
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "token"})
+	})
+
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		_ = json.NewEncoder(w).Encode(claims)
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func oidcCallback(t *testing.T, s *Server) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+url.Values{
+		"state": {"state"},
+		"code":  {"code"},
+	}.Encode(), http.NoBody)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "state.verifier"})
+
+	w := httptest.NewRecorder()
+	s.oidcCallback(w, req)
+
+	return w
+}
+
+func TestOIDCCallback(t *testing.T) {
+	svc, users := newUserService()
+	ctx := context.Background()
+
+	require.NoError(t, svc.EnsureAdmin(ctx, "admin@example.com", "a long password"))
+
+	// the provider did not verify the email of the admin
+	idp := newOIDCProvider(t, map[string]any{
+		"sub":            "42",
+		"email":          "admin@example.com",
+		"email_verified": false,
+	})
+
+	s := &Server{svc: svc}
+	WithOIDC(OIDCConfig{Issuer: idp.URL, Admins: []string{"admin@example.com"}})(s)
+
+	w := oidcCallback(t, s)
+	require.Equal(t, http.StatusSeeOther, w.Code)
+
+	var token string
+
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookie {
+			token = c.Value
+		}
+	}
+
+	require.NotEmpty(t, token)
+
+	user, err := svc.SessionUser(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, idp.URL+"#42", user.External)
+	require.Equal(t, "42", user.Username)
+	require.False(t, user.IsAdmin())
+
+	admin, err := users.GetUserByUsername(ctx, "admin@example.com")
+	require.NoError(t, err)
+	require.NotEqual(t, admin.ID, user.ID)
+
+	// a wrong state is refused
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?state=other&code=code", http.NoBody)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "state.verifier"})
+
+	w = httptest.NewRecorder()
+	s.oidcCallback(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLoginLimiter(t *testing.T) {
+	l := newLoginLimiter()
+	now := time.Now()
+
+	for range maxUserFailures - 1 {
+		l.fail("10.0.0.1", "alice", now)
+	}
+
+	require.Zero(t, l.wait("10.0.0.1", "alice", now))
+
+	l.fail("10.0.0.2", "alice", now)
+
+	// the username is throttled from any address, the other users are not
+	require.Equal(t, loginWindow, l.wait("10.0.0.3", "alice", now))
+	require.Zero(t, l.wait("10.0.0.1", "bob", now))
+	require.Equal(t, time.Minute, l.wait("10.0.0.3", "alice", now.Add(loginWindow-time.Minute)))
+	require.Zero(t, l.wait("10.0.0.3", "alice", now.Add(loginWindow)))
+
+	// an address is throttled for all the usernames
+	l = newLoginLimiter()
+
+	for i := range maxIPFailures {
+		l.fail("10.0.0.1", "user"+strings.Repeat("x", i), now)
+	}
+
+	require.Positive(t, l.wait("10.0.0.1", "bob", now))
+	require.Zero(t, l.wait("10.0.0.2", "bob", now))
+
+	// a sign in forgets the failures of the username
+	l = newLoginLimiter()
+
+	for range maxUserFailures {
+		l.fail("10.0.0.1", "alice", now)
+	}
+
+	l.succeed("alice")
+	require.Zero(t, l.wait("10.0.0.1", "alice", now))
+}
+
+func TestBasicAuthThrottled(t *testing.T) {
+	svc, _ := newUserService()
+
+	alice := User{Username: "alice", Role: RoleUser}
+	require.NoError(t, svc.CreateUser(context.Background(), &alice, "a long password"))
+
+	s := &Server{svc: svc}
+	WithAuth()(s)
+
+	h := s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	get := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", http.NoBody)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.SetBasicAuth("alice", password)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		return w
+	}
+
+	require.Equal(t, http.StatusNoContent, get("a long password").Code)
+	require.Equal(t, http.StatusUnauthorized, get("wrong password").Code)
+
+	for range maxUserFailures {
+		s.logins.fail("10.0.0.1", "alice", time.Now())
+	}
+
+	// even the right password is refused for a while
+	w := get("a long password")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestStatusPublic(t *testing.T) {
+	svc, _ := newUserService()
+
+	s := &Server{svc: svc}
+	WithAuth()(s)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.status)
+	mux.HandleFunc("/api/v1/jobs", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	h := s.authenticate(mux)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+
+		return w
+	}
+
+	// the status page is served without signing in, the API is not
+	w := get("/status")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"health"`)
+
+	require.Equal(t, http.StatusUnauthorized, get("/api/v1/jobs").Code)
+}
@@ -0,0 +1,398 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxBulkInput is the maximum size of the csv uploaded to /api/v1/jobs/bulk
+	maxBulkInput = 10 << 20
+	maxBulkRows  = 1000
+)
+
+// bulkColumns are the columns of the csv of a bulk upload, only keyword is
+// required.
+var bulkColumns = []string{"keyword", "place", "bbox", "lang", "depth", "name", "max_time"}
+
+// BulkJobCreator is a JobRepository that creates many jobs at once, all of
+// them or none.
+type BulkJobCreator interface {
+	CreateJobs(context.Context, []Job) error
+}
+
+// BulkRowError is why a row of a bulk upload is invalid.
+type BulkRowError struct {
+	// Row is the line of the row in the csv, the header is line 1
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// BulkJob is a job created from a row of a bulk upload.
+type BulkJob struct {
+	Row  int    `json:"row"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// defaultBulkData are the settings of the jobs of a bulk upload without a
+// template, the defaults of the form of the UI.
+func defaultBulkData() JobData {
+	return JobData{
+		Lang:    "en",
+		Zoom:    15,
+		Radius:  10000,
+		Depth:   10,
+		MaxTime: 10 * time.Minute,
+	}
+}
+
+// parseBulkJobs returns a pending job for each row of the csv r, with the
+// settings of base for the columns the row leaves empty, and the lines of
+// their rows. The rows are all checked, the invalid ones are returned with
+// the reason. An error is returned when the csv itself cannot be read.
+func parseBulkJobs(r io.Reader, base JobData) ([]Job, []BulkRowError, []int, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	// the rows may leave out the last columns
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil, errors.New("the csv is empty")
+	}
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+
+		known := false
+
+		for _, c := range bulkColumns {
+			known = known || c == name
+		}
+
+		if !known {
+			return nil, nil, nil, fmt.Errorf("unknown column %q, the columns are %s", name, strings.Join(bulkColumns, ", "))
+		}
+
+		columns[name] = i
+	}
+
+	if _, ok := columns["keyword"]; !ok {
+		return nil, nil, nil, errors.New("missing keyword column")
+	}
+
+	var (
+		jobs    []Job
+		rowErrs []BulkRowError
+		rows    []int
+	)
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		if len(jobs)+len(rowErrs) == maxBulkRows {
+			return nil, nil, nil, fmt.Errorf("more than %d rows", maxBulkRows)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+
+			return ""
+		}
+
+		job, err := bulkJob(field, base)
+		if err != nil {
+			rowErrs = append(rowErrs, BulkRowError{Row: line, Error: err.Error()})
+
+			continue
+		}
+
+		jobs = append(jobs, job)
+		rows = append(rows, line)
+	}
+
+	return jobs, rowErrs, rows, nil
+}
+
+// bulkJob returns the job of a row whose columns field returns.
+func bulkJob(field func(string) string, base JobData) (Job, error) {
+	keyword := field("keyword")
+	if keyword == "" {
+		return Job{}, errors.New("missing keyword")
+	}
+
+	job := Job{
+		ID:     uuid.New().String(),
+		Name:   field("name"),
+		Date:   time.Now().UTC(),
+		Status: StatusPending,
+		Data:   base,
+	}
+
+	if job.Name == "" {
+		job.Name = keyword
+	}
+
+	job.Data.Keywords = []string{keyword}
+
+	// a row searches around its place or in its bbox, not the ones of the template
+	if place := field("place"); place != "" {
+		job.Data.Place = place
+		job.Data.Lat, job.Data.Lon = "", ""
+		job.Data.BBox, job.Data.BBoxExclude = "", nil
+	}
+
+	if bbox := field("bbox"); bbox != "" {
+		job.Data.BBox = bbox
+		job.Data.Place = ""
+		job.Data.Lat, job.Data.Lon = "", ""
+	}
+
+	if lang := field("lang"); lang != "" {
+		job.Data.Lang = strings.ToLower(lang)
+	}
+
+	if v := field("depth"); v != "" {
+		depth, err := strconv.Atoi(v)
+		if err != nil || depth <= 0 {
+			return Job{}, errors.New("depth must be a positive number")
+		}
+
+		job.Data.Depth = depth
+	}
+
+	if v := field("max_time"); v != "" {
+		maxTime, err := parseMaxTime(v)
+		if err != nil {
+			return Job{}, err
+		}
+
+		job.Data.MaxTime = maxTime
+	}
+
+	if err := job.Validate(); err != nil {
+		return Job{}, err
+	}
+
+	return job, nil
+}
+
+// parseMaxTime parses a max time in seconds or as a duration, e.g. 10m.
+func parseMaxTime(v string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, errors.New("max_time must be a number of seconds or a duration like 10m")
+	}
+
+	return d, nil
+}
+
+// CreateJobs creates the pending jobs, all of them or none.
+func (s *Service) CreateJobs(ctx context.Context, jobs []Job) error {
+	key, hasKey := APIKeyFromContext(ctx)
+	if hasKey {
+		if err := s.checkQuota(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	for i := range jobs {
+		setOwner(ctx, &jobs[i].Data)
+		setAPIKey(ctx, &jobs[i].Data)
+		setSlack(ctx, &jobs[i].Data)
+
+		if err := jobs[i].Data.setWebhookSecrets(); err != nil {
+			return err
+		}
+	}
+
+	if creator, ok := s.repo.(BulkJobCreator); ok {
+		if err := creator.CreateJobs(ctx, jobs); err != nil {
+			return err
+		}
+	} else {
+		for i := range jobs {
+			if err := s.repo.Create(ctx, &jobs[i]); err != nil {
+				for j := range i {
+					_ = s.repo.Delete(ctx, jobs[j].ID)
+				}
+
+				return err
+			}
+		}
+	}
+
+	for i := range jobs {
+		s.publishStatus(&jobs[i])
+		s.audit(ctx, AuditJobCreate, jobs[i].ID, jobs[i].Name)
+	}
+
+	return nil
+}
+
+type apiBulkError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Rows    []BulkRowError `json:"rows,omitempty"`
+}
+
+type apiBulkResponse struct {
+	Jobs []BulkJob `json:"jobs"`
+}
+
+// apiBulkCreateJobs creates a job for each row of the uploaded csv, as the
+// body or as the file field of a form. No job is created when a row is
+// invalid, the reason of each invalid row is returned instead.
+func (s *Server) apiBulkCreateJobs(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkInput)
+
+	var input io.Reader = r.Body
+
+	// the body is not parsed as a form unless it is one, curl posts the csv
+	// as application/x-www-form-urlencoded
+	templateID := r.URL.Query().Get("template_id")
+	tenant := r.URL.Query().Get("tenant")
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "missing csv file: " + err.Error(),
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+
+		defer file.Close()
+
+		input = file
+		templateID = r.FormValue("template_id")
+		tenant = r.FormValue("tenant")
+	}
+
+	base := defaultBulkData()
+
+	if templateID != "" {
+		t, err := s.svc.GetTemplate(r.Context(), templateID)
+		if errors.Is(err, ErrTemplatesNotSupported) {
+			renderTemplateError(w, err)
+
+			return
+		}
+
+		if err != nil {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: fmt.Sprintf("template %s not found", templateID),
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+
+		base = t.Data
+		base.Owner = ""
+	}
+
+	if tenant = strings.TrimSpace(tenant); tenant != "" {
+		base.Tenant = tenant
+	}
+
+	jobs, rowErrs, rows, err := parseBulkJobs(input, base)
+	if err == nil && len(rowErrs) == 0 && len(jobs) == 0 {
+		err = errors.New("the csv has no rows")
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	for i := range jobs {
+		if err := s.svc.ValidateServerSettings(&jobs[i].Data); err != nil {
+			rowErrs = append(rowErrs, BulkRowError{Row: rows[i], Error: err.Error()})
+		}
+	}
+
+	if len(rowErrs) > 0 {
+		slices.SortFunc(rowErrs, func(a, b BulkRowError) int {
+			return a.Row - b.Row
+		})
+
+		ans := apiBulkError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("%d invalid rows, no job was created", len(rowErrs)),
+			Rows:    rowErrs,
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, ans)
+
+		return
+	}
+
+	if err := s.svc.CreateJobs(r.Context(), jobs); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrQuotaExceeded) {
+			code = http.StatusTooManyRequests
+		}
+
+		apiError := apiError{
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, apiError)
+
+		return
+	}
+
+	ans := apiBulkResponse{Jobs: make([]BulkJob, 0, len(jobs))}
+
+	for i := range jobs {
+		ans.Jobs = append(ans.Jobs, BulkJob{Row: rows[i], ID: jobs[i].ID, Name: jobs[i].Name})
+	}
+
+	renderJSON(w, http.StatusCreated, ans)
+}
@@ -0,0 +1,131 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingRepo fails to create the job named fail.
+type failingRepo struct {
+	*memRepo
+	fail string
+}
+
+func (r *failingRepo) Create(ctx context.Context, job *Job) error {
+	if job.Name == r.fail {
+		return errors.New("disk full")
+	}
+
+	return r.memRepo.Create(ctx, job)
+}
+
+func TestParseBulkJobs(t *testing.T) {
+	input := "\ufeffKeyword,name,depth,max_time,lang\n" +
+		"cafe,,2,90,DE\n" +
+		",no keyword\n" +
+		"bar,bars,-1\n" +
+		"pub,pubs,,10m\n"
+
+	jobs, rowErrs, rows, err := parseBulkJobs(strings.NewReader(input), defaultBulkData())
+	require.NoError(t, err)
+
+	require.Len(t, jobs, 2)
+	require.Equal(t, []int{2, 5}, rows)
+	require.Equal(t, "cafe", jobs[0].Name)
+	require.Equal(t, 2, jobs[0].Data.Depth)
+	require.Equal(t, "de", jobs[0].Data.Lang)
+	require.Equal(t, "pubs", jobs[1].Name)
+	require.Equal(t, 10, jobs[1].Data.Depth)
+	require.NotEqual(t, jobs[0].ID, jobs[1].ID)
+
+	require.Equal(t, []BulkRowError{
+		{Row: 3, Error: "missing keyword"},
+		{Row: 4, Error: "depth must be a positive number"},
+	}, rowErrs)
+
+	_, _, _, err = parseBulkJobs(strings.NewReader("keyword,color\ncafe,red\n"), defaultBulkData())
+	require.ErrorContains(t, err, `unknown column "color"`)
+
+	_, _, _, err = parseBulkJobs(strings.NewReader("name\ncafe\n"), defaultBulkData())
+	require.ErrorContains(t, err, "missing keyword column")
+
+	_, _, _, err = parseBulkJobs(strings.NewReader(""), defaultBulkData())
+	require.ErrorContains(t, err, "empty")
+}
+
+func TestCreateJobsAllOrNone(t *testing.T) {
+	repo := &failingRepo{memRepo: newMemRepo(), fail: "c"}
+	svc := NewService(repo, "")
+
+	newJobs := func(names ...string) []Job {
+		var ans []Job
+
+		for _, name := range names {
+			job, err := bulkJob(func(c string) string {
+				if c == "keyword" {
+					return name
+				}
+
+				return ""
+			}, defaultBulkData())
+			require.NoError(t, err)
+
+			job.Name = name
+			ans = append(ans, job)
+		}
+
+		return ans
+	}
+
+	// the jobs created before the one that failed are deleted
+	err := svc.CreateJobs(context.Background(), newJobs("a", "b", "c", "d"))
+	require.EqualError(t, err, "disk full")
+	require.Empty(t, repo.jobs)
+
+	require.NoError(t, svc.CreateJobs(context.Background(), newJobs("a", "b")))
+	require.Len(t, repo.jobs, 2)
+}
+
+func TestAPIBulkCreateJobs(t *testing.T) {
+	repo := newMemRepo()
+	s := &Server{svc: NewService(repo, "")}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/bulk?tenant=sales", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		s.apiBulkCreateJobs(w, req)
+
+		return w
+	}
+
+	// an invalid row creates no job
+	w := post("keyword,depth\ncafe,1\nbar,x\n")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var bulkErr apiBulkError
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&bulkErr))
+	require.Equal(t, []BulkRowError{{Row: 3, Error: "depth must be a positive number"}}, bulkErr.Rows)
+	require.Empty(t, repo.jobs)
+
+	w = post("keyword\n")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = post("keyword\ncafe\nbar\n")
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var ans apiBulkResponse
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&ans))
+	require.Len(t, ans.Jobs, 2)
+	require.Equal(t, 2, ans.Jobs[0].Row)
+	require.Equal(t, "sales", repo.jobs[ans.Jobs[1].ID].Data.Tenant)
+}
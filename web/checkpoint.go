@@ -0,0 +1,68 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CheckpointRepository stores the pending work of running jobs, so a job
+// interrupted by a crash or a restart resumes from its last checkpoint
+// instead of starting over.
+type CheckpointRepository interface {
+	// SaveCheckpoint replaces the checkpoint of the job.
+	SaveCheckpoint(ctx context.Context, jobID string, data []byte) error
+	// Checkpoint returns nil when the job has no checkpoint.
+	Checkpoint(ctx context.Context, jobID string) ([]byte, error)
+	DeleteCheckpoint(ctx context.Context, jobID string) error
+}
+
+// ErrCheckpointsNotSupported is returned when the repository does not store checkpoints.
+var ErrCheckpointsNotSupported = errors.New("checkpoints are not supported")
+
+// WithCheckpoints keeps the checkpoints of the running jobs in repo. When
+// resume is true the jobs that were running when the process stopped are
+// put back in the queue by RequeueInterrupted and continue from them.
+func WithCheckpoints(repo CheckpointRepository, resume bool) ServiceOption {
+	return func(s *Service) {
+		s.checkpointRepo = repo
+		s.resume = resume
+	}
+}
+
+// SaveCheckpoint stores the pending work of a job.
+func (s *Service) SaveCheckpoint(ctx context.Context, jobID string, data []byte) error {
+	if s.checkpointRepo == nil {
+		return ErrCheckpointsNotSupported
+	}
+
+	return s.checkpointRepo.SaveCheckpoint(ctx, jobID, data)
+}
+
+// Checkpoint returns the pending work of a job, nil when there is none.
+func (s *Service) Checkpoint(ctx context.Context, jobID string) ([]byte, error) {
+	if s.checkpointRepo == nil {
+		return nil, nil
+	}
+
+	return s.checkpointRepo.Checkpoint(ctx, jobID)
+}
+
+// DeleteCheckpoint removes the checkpoint of a job once it is completed.
+func (s *Service) DeleteCheckpoint(ctx context.Context, jobID string) error {
+	if s.checkpointRepo == nil {
+		return nil
+	}
+
+	return s.checkpointRepo.DeleteCheckpoint(ctx, jobID)
+}
+
+// hasCheckpoint reports whether the job can continue from a checkpoint.
+func (s *Service) hasCheckpoint(ctx context.Context, jobID string) (bool, error) {
+	data, err := s.Checkpoint(ctx, jobID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the checkpoint of job %s: %w", jobID, err)
+	}
+
+	return data != nil, nil
+}
@@ -0,0 +1,401 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// AuditJobRequeue is the audit action of requeueing the dead letters of a
+// job.
+const AuditJobRequeue = "job.requeue"
+
+// DeadLetter is a place or an email job of a job that failed after all its
+// retries, kept so the place is not silently lost and can be requeued once
+// the cause is fixed, e.g. a proxy or a parser.
+type DeadLetter struct {
+	ID    string `json:"id"`
+	JobID string `json:"job_id"`
+	// Type is place or email
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Error string `json:"error"`
+	// Payload is the job as the runner saved it, to run it again
+	Payload []byte `json:"-"`
+	// Requeued dead letters run again the next time the job runs
+	Requeued  bool      `json:"requeued"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeadLetterRepository stores the dead letters of the jobs.
+type DeadLetterRepository interface {
+	CreateDeadLetters(context.Context, []DeadLetter) error
+	// SelectDeadLetters returns the dead letters of the job jobID, the
+	// oldest first.
+	SelectDeadLetters(ctx context.Context, jobID string) ([]DeadLetter, error)
+	// RequeueDeadLetters marks the dead letters ids of the job jobID, or all
+	// of them when ids is empty, as requeued and returns how many it marked.
+	RequeueDeadLetters(ctx context.Context, jobID string, ids []string) (int, error)
+	// DeleteDeadLetters deletes the dead letters ids of the job jobID, or all
+	// of them when ids is empty.
+	DeleteDeadLetters(ctx context.Context, jobID string, ids []string) error
+}
+
+// ErrDeadLettersNotSupported is returned when the repository does not store
+// the dead letters.
+var ErrDeadLettersNotSupported = errors.New("dead letters are not supported")
+
+// WithDeadLetters keeps the place and email jobs that failed after all their
+// retries in repo.
+func WithDeadLetters(repo DeadLetterRepository) ServiceOption {
+	return func(s *Service) {
+		s.deadLetterRepo = repo
+	}
+}
+
+// AddDeadLetters keeps the failed jobs of a run. It does nothing when the
+// dead letters are not stored.
+func (s *Service) AddDeadLetters(ctx context.Context, letters []DeadLetter) error {
+	if s.deadLetterRepo == nil || len(letters) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	for i := range letters {
+		if letters[i].ID == "" {
+			letters[i].ID = uuid.New().String()
+		}
+
+		if letters[i].CreatedAt.IsZero() {
+			letters[i].CreatedAt = now
+		}
+	}
+
+	return s.deadLetterRepo.CreateDeadLetters(ctx, letters)
+}
+
+// DeadLetters returns the dead letters of the job jobID.
+func (s *Service) DeadLetters(ctx context.Context, jobID string) ([]DeadLetter, error) {
+	if s.deadLetterRepo == nil {
+		return nil, ErrDeadLettersNotSupported
+	}
+
+	if _, err := s.Get(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	return s.deadLetterRepo.SelectDeadLetters(ctx, jobID)
+}
+
+// RequeueDeadLetters requeues the dead letters ids of the job jobID, or all
+// of them when ids is empty, and sets the job pending so they run again. The
+// places they find are added to the results of the job. The job must be
+// completed, failed or still pending.
+func (s *Service) RequeueDeadLetters(ctx context.Context, jobID string, ids []string) (int, error) {
+	if s.deadLetterRepo == nil {
+		return 0, ErrDeadLettersNotSupported
+	}
+
+	job, err := s.Get(ctx, jobID)
+	if err != nil {
+		return 0, err
+	}
+
+	switch job.Status {
+	case StatusOK, StatusFailed, StatusPending:
+	default:
+		return 0, fmt.Errorf("%w: the dead letters of a %s job cannot be requeued", ErrInvalidTransition, job.Status)
+	}
+
+	n, err := s.deadLetterRepo.RequeueDeadLetters(ctx, jobID, ids)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+
+	if job.Status != StatusPending {
+		job.Status = StatusPending
+
+		if err := s.Update(ctx, &job); err != nil {
+			return 0, err
+		}
+	}
+
+	s.audit(ctx, AuditJobRequeue, jobID, fmt.Sprintf("%d dead letters", n))
+
+	return n, nil
+}
+
+// RequeuedDeadLetters returns the requeued dead letters of the job jobID,
+// for the runner to run them again.
+func (s *Service) RequeuedDeadLetters(ctx context.Context, jobID string) ([]DeadLetter, error) {
+	if s.deadLetterRepo == nil {
+		return nil, nil
+	}
+
+	letters, err := s.deadLetterRepo.SelectDeadLetters(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ans []DeadLetter
+
+	for i := range letters {
+		if letters[i].Requeued {
+			ans = append(ans, letters[i])
+		}
+	}
+
+	return ans, nil
+}
+
+// DeleteDeadLetters deletes the dead letters ids of the job jobID, or all of
+// them when ids is empty.
+func (s *Service) DeleteDeadLetters(ctx context.Context, jobID string, ids []string) error {
+	if s.deadLetterRepo == nil {
+		return nil
+	}
+
+	return s.deadLetterRepo.DeleteDeadLetters(ctx, jobID, ids)
+}
+
+// ErrorsPath returns the path of the csv of the places of the job id that
+// failed after all their retries in its last run, which the runner writes
+// next to its results.
+func (s *Service) ErrorsPath(id string) (string, error) {
+	if strings.Contains(id, "/") || strings.Contains(id, "\\") || strings.Contains(id, "..") {
+		return "", fmt.Errorf("invalid file name")
+	}
+
+	return filepath.Join(s.dataFolder, id+".errors.csv"), nil
+}
+
+type apiRequeueRequest struct {
+	// IDs are the dead letters to requeue, all of them when empty
+	IDs []string `json:"ids"`
+}
+
+type apiRequeueResponse struct {
+	Requeued int `json:"requeued"`
+}
+
+func (s *Server) apiGetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	letters, err := s.svc.DeadLetters(r.Context(), id.String())
+	if err != nil {
+		renderDeadLetterError(w, err)
+
+		return
+	}
+
+	if letters == nil {
+		letters = []DeadLetter{}
+	}
+
+	renderJSON(w, http.StatusOK, letters)
+}
+
+func (s *Server) apiRequeueDeadLetters(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	var req apiRequeueRequest
+
+	// the body is optional
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	n, err := s.svc.RequeueDeadLetters(r.Context(), id.String(), req.IDs)
+	if err != nil {
+		renderDeadLetterError(w, err)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, apiRequeueResponse{Requeued: n})
+}
+
+// apiGetJobErrors returns the csv of the places of a job that failed after
+// all their retries, only its header when none failed.
+func (s *Server) apiGetJobErrors(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if _, err := s.svc.Get(r.Context(), id.String()); err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	datapath, err := s.svc.ErrorsPath(id.String())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	s.svc.audit(r.Context(), AuditJobDownload, id.String(), "errors")
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filepath.Base(datapath)))
+	w.Header().Set("Content-Type", "text/csv")
+
+	file, err := os.Open(datapath)
+	if err != nil {
+		cw := csv.NewWriter(w)
+		_ = cw.Write(gmaps.FailureCsvHeaders())
+		cw.Flush()
+
+		return
+	}
+
+	defer file.Close()
+
+	_, _ = io.Copy(w, file)
+}
+
+func renderDeadLetterError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, ErrDeadLettersNotSupported):
+		code = http.StatusNotImplemented
+	case errors.Is(err, ErrInvalidTransition):
+		code = http.StatusConflict
+	case errors.Is(err, ErrNotFound):
+		code = http.StatusNotFound
+	}
+
+	apiError := apiError{
+		Code:    code,
+		Message: err.Error(),
+	}
+
+	renderJSON(w, code, apiError)
+}
+
+// deadLetters renders the dead letters of a job, with a form to requeue
+// them. A post requeues the checked ones.
+func (s *Server) deadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Invalid ID", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	data := struct {
+		JobID    string
+		Letters  []DeadLetter
+		Message  string
+		Requeued int
+	}{
+		JobID: id.String(),
+	}
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		ids := r.Form["ids"]
+		if len(ids) == 0 {
+			data.Message = "Select the places to requeue"
+		} else {
+			n, err := s.svc.RequeueDeadLetters(r.Context(), id.String(), ids)
+			if err != nil {
+				data.Message = err.Error()
+			}
+
+			data.Requeued = n
+		}
+	}
+
+	letters, err := s.svc.DeadLetters(r.Context(), id.String())
+
+	switch {
+	case errors.Is(err, ErrDeadLettersNotSupported):
+		data.Message = "The failed places are not kept by this server"
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	data.Letters = letters
+
+	tmpl, ok := s.tmpl["static/templates/dead_letters.html"]
+	if !ok {
+		http.Error(w, "missing tpl", http.StatusInternalServerError)
+
+		return
+	}
+
+	_ = tmpl.Execute(w, data)
+}
@@ -0,0 +1,97 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// BrowserStatsFunc returns the number of open browser contexts and pages.
+type BrowserStatsFunc func() (contexts, pages int)
+
+// RuntimeStats is a snapshot of the process, to follow the memory of long crawls.
+type RuntimeStats struct {
+	Goroutines      int       `json:"goroutines"`
+	HeapAlloc       uint64    `json:"heap_alloc"`
+	HeapInuse       uint64    `json:"heap_inuse"`
+	HeapObjects     uint64    `json:"heap_objects"`
+	Sys             uint64    `json:"sys"`
+	NumGC           uint32    `json:"num_gc"`
+	BrowserContexts int       `json:"browser_contexts"`
+	Pages           int       `json:"pages"`
+	Uptime          string    `json:"uptime"`
+	GeneratedAt     time.Time `json:"generated_at"`
+}
+
+// WithDebug serves /debug/pprof and the runtime stats at /debug/stats.
+// The requests must send the token as a bearer token or the token query
+// parameter. browsers may be nil.
+func WithDebug(token string, browsers BrowserStatsFunc) ServerOption {
+	return func(s *Server) {
+		s.debugToken = token
+		s.browserStats = browsers
+	}
+}
+
+func (s *Server) registerDebug(mux *http.ServeMux) {
+	mux.Handle("/debug/pprof/", s.requireDebugToken(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", s.requireDebugToken(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", s.requireDebugToken(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", s.requireDebugToken(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", s.requireDebugToken(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/stats", s.requireDebugToken(http.HandlerFunc(s.debugStats)))
+}
+
+func (s *Server) requireDebugToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.debugToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) debugStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, s.runtimeStats())
+}
+
+func (s *Server) runtimeStats() RuntimeStats {
+	var mem runtime.MemStats
+
+	runtime.ReadMemStats(&mem)
+
+	ans := RuntimeStats{
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   mem.HeapAlloc,
+		HeapInuse:   mem.HeapInuse,
+		HeapObjects: mem.HeapObjects,
+		Sys:         mem.Sys,
+		NumGC:       mem.NumGC,
+		Uptime:      time.Since(s.started).Round(time.Second).String(),
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	if s.browserStats != nil {
+		ans.BrowserContexts, ans.Pages = s.browserStats()
+	}
+
+	return ans
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugToken(t *testing.T) {
+	s := &Server{
+		debugToken: "s3cret",
+		started:    time.Now(),
+		browserStats: func() (int, int) {
+			return 2, 5
+		},
+	}
+
+	mux := http.NewServeMux()
+	s.registerDebug(mux)
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		header string
+		want   int
+	}{
+		{name: "no token", target: "/debug/stats", want: http.StatusUnauthorized},
+		{name: "wrong token", target: "/debug/stats?token=guess", want: http.StatusUnauthorized},
+		{name: "wrong bearer", target: "/debug/stats?token=s3cret", header: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "query token", target: "/debug/stats?token=s3cret", want: http.StatusOK},
+		{name: "bearer token", target: "/debug/stats", header: "Bearer s3cret", want: http.StatusOK},
+		{name: "pprof", target: "/debug/pprof/", header: "Bearer s3cret", want: http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, http.NoBody)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.want, rec.Code)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/stats?token=s3cret", http.NoBody)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var stats RuntimeStats
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Positive(t, stats.Goroutines)
+	require.Positive(t, stats.HeapAlloc)
+	require.Equal(t, 2, stats.BrowserContexts)
+	require.Equal(t, 5, stats.Pages)
+}
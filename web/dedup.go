@@ -0,0 +1,210 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/deduper"
+)
+
+// maxDedupImportSize limits the size of an uploaded dedup set (about 8M keys).
+const maxDedupImportSize = 64 << 20
+
+// TrackDedup registers the deduper of a running job so its stats are
+// reported in the status summary. The returned function must be called
+// when the job stops and adds the stats to the totals.
+func (s *Service) TrackDedup(d *deduper.Instrumented) func() {
+	s.dedupMu.Lock()
+
+	if s.dedupRunning == nil {
+		s.dedupRunning = make(map[*deduper.Instrumented]struct{})
+	}
+
+	s.dedupRunning[d] = struct{}{}
+	s.dedupMu.Unlock()
+
+	return func() {
+		s.dedupMu.Lock()
+		defer s.dedupMu.Unlock()
+
+		s.dedupTotal = s.dedupTotal.Add(d.Stats())
+
+		delete(s.dedupRunning, d)
+	}
+}
+
+// DedupStats returns the stats of the dedupers of all the jobs run since start.
+func (s *Service) DedupStats() deduper.Stats {
+	s.dedupMu.Lock()
+	defer s.dedupMu.Unlock()
+
+	ans := s.dedupTotal
+
+	for d := range s.dedupRunning {
+		ans = ans.Add(d.Stats())
+	}
+
+	return ans
+}
+
+// DedupPath returns the file where the dedup set of the job is kept.
+// It is loaded when the job starts and written when it stops.
+func (s *Service) DedupPath(id string) (string, error) {
+	if strings.Contains(id, "/") || strings.Contains(id, "\\") || strings.Contains(id, "..") {
+		return "", fmt.Errorf("invalid file name")
+	}
+
+	return filepath.Join(s.dataFolder, id+".dedup"), nil
+}
+
+// ImportDedup adds an exported dedup set to the one of a pending or paused job,
+// so the places already scraped on another machine are skipped.
+func (s *Service) ImportDedup(ctx context.Context, id string, r io.Reader) error {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != StatusPending && job.Status != StatusPaused {
+		return fmt.Errorf("%w: cannot import into a %s job", ErrInvalidTransition, job.Status)
+	}
+
+	datapath, err := s.DedupPath(id)
+	if err != nil {
+		return err
+	}
+
+	dedup := deduper.New()
+
+	exp, ok := dedup.(deduper.Exporter)
+	if !ok {
+		return deduper.ErrExportNotSupported
+	}
+
+	if _, err := os.Stat(datapath); err == nil {
+		if err := deduper.ImportFile(dedup, datapath); err != nil {
+			return err
+		}
+	}
+
+	if err := exp.Import(r); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidDedupSet, err)
+	}
+
+	return deduper.ExportFile(dedup, datapath)
+}
+
+var errInvalidDedupSet = errors.New("invalid dedup set")
+
+func (s *Server) apiGetJobDedup(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if _, err := s.svc.Get(r.Context(), id.String()); err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	datapath, err := s.svc.DedupPath(id.String())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	file, err := os.Open(datapath)
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	defer file.Close()
+
+	s.svc.audit(r.Context(), AuditJobDownload, id.String(), "dedup")
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filepath.Base(datapath)))
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	_, _ = io.Copy(w, file)
+}
+
+func (s *Server) apiImportJobDedup(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if _, err := s.svc.Get(r.Context(), id.String()); err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxDedupImportSize)
+
+	if err := s.svc.ImportDedup(r.Context(), id.String(), body); err != nil {
+		code := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(err, ErrInvalidTransition):
+			code = http.StatusConflict
+		case errors.Is(err, errInvalidDedupSet):
+			code = http.StatusUnprocessableEntity
+		}
+
+		apiError := apiError{
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, apiError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobDefinitionVersion is the current version of the exported job definition format.
+const JobDefinitionVersion = 1
+
+// JobDefinition is the reusable part of a job. It has the same shape as the
+// create job request, so an exported definition can be posted back to /api/v1/jobs
+// and kept in version control next to the rest of the infrastructure.
+// MaxTime is expressed in seconds.
+type JobDefinition struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	JobData
+}
+
+// NewJobDefinition exports the definition of an existing job. The values of
+// its headers and cookies are redacted and have to be filled in again before
+// the definition is posted back.
+func NewJobDefinition(job *Job) JobDefinition {
+	ans := JobDefinition{
+		Version: JobDefinitionVersion,
+		Name:    job.Name,
+		JobData: job.Data.Redacted(),
+	}
+
+	ans.MaxTime /= time.Second
+
+	return ans
+}
+
+// Job returns a new pending job built from the definition.
+func (d *JobDefinition) Job() Job {
+	ans := Job{
+		ID:     uuid.New().String(),
+		Name:   d.Name,
+		Date:   time.Now().UTC(),
+		Status: StatusPending,
+		Data:   d.JobData,
+	}
+
+	ans.Data.MaxTime *= time.Second
+
+	return ans
+}
+
+// Validate checks the definition without creating a job.
+func (d *JobDefinition) Validate() error {
+	if d.Version < 0 || d.Version > JobDefinitionVersion {
+		return fmt.Errorf("unsupported version: %d", d.Version)
+	}
+
+	job := d.Job()
+
+	return job.Validate()
+}
+
+// DecodeJobDefinition parses a job definition. Unknown fields are rejected
+// so typos in hand written definitions do not go unnoticed.
+func DecodeJobDefinition(r io.Reader) (JobDefinition, error) {
+	var ans JobDefinition
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&ans); err != nil {
+		return JobDefinition{}, err
+	}
+
+	return ans, nil
+}
+
+type apiValidateResponse struct {
+	Valid bool `json:"valid"`
+}
+
+func (s *Server) apiGetJobDefinition(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	job, err := s.svc.Get(r.Context(), id.String())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", job.ID))
+	}
+
+	renderJSON(w, http.StatusOK, NewJobDefinition(&job))
+}
+
+func (s *Server) apiValidateJobDefinition(w http.ResponseWriter, r *http.Request) {
+	def, err := DecodeJobDefinition(r.Body)
+	if err == nil {
+		err = def.Validate()
+	}
+
+	if err == nil {
+		err = s.svc.ValidateServerSettings(&def.JobData)
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, apiValidateResponse{Valid: true})
+}
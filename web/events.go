@@ -0,0 +1,285 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The types of the job events.
+const (
+	// EventStatus is a change of the status of a job, its data is the status
+	EventStatus = "status"
+	// EventProgress is a change of the progress of a running job, its data is the JobProgress
+	EventProgress = "progress"
+	// EventLog is a log line of a job, its data is the line
+	EventLog = "log"
+	// EventEntries is a place written to the results, its data is the number written so far
+	EventEntries = "entries"
+)
+
+const (
+	eventBuffer       = 256
+	progressInterval  = 2 * time.Second
+	keepaliveInterval = 15 * time.Second
+)
+
+// JobEvent is something that happened to a job.
+type JobEvent struct {
+	JobID string    `json:"job_id"`
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+type subscriber struct {
+	jobID string
+	ch    chan JobEvent
+}
+
+type eventBroker struct {
+	mu         sync.Mutex
+	subs       map[*subscriber]struct{}
+	lastStatus map[string]string
+}
+
+// Subscribe returns the events of the job jobID, or of all the jobs when it
+// is empty, until the returned function is called. The events are dropped
+// when the subscriber does not keep up.
+func (s *Service) Subscribe(jobID string) (<-chan JobEvent, func()) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+
+	if s.events.subs == nil {
+		s.events.subs = map[*subscriber]struct{}{}
+	}
+
+	sub := &subscriber{jobID: jobID, ch: make(chan JobEvent, eventBuffer)}
+	s.events.subs[sub] = struct{}{}
+
+	return sub.ch, func() {
+		s.events.mu.Lock()
+		defer s.events.mu.Unlock()
+
+		delete(s.events.subs, sub)
+	}
+}
+
+// Publish sends ev to the subscribers of its job.
+func (s *Service) Publish(ev JobEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+
+	for sub := range s.events.subs {
+		if sub.jobID != "" && sub.jobID != ev.JobID {
+			continue
+		}
+
+		select {
+		case sub.ch <- ev:
+		default:
+		}
+	}
+}
+
+// publishStatus publishes the status of job, and notifies its webhooks, when
+// it changed.
+func (s *Service) publishStatus(job *Job) {
+	s.events.mu.Lock()
+
+	if s.events.lastStatus == nil {
+		s.events.lastStatus = map[string]string{}
+	}
+
+	changed := s.events.lastStatus[job.ID] != job.Status
+	s.events.lastStatus[job.ID] = job.Status
+
+	s.events.mu.Unlock()
+
+	if changed {
+		s.Publish(JobEvent{JobID: job.ID, Type: EventStatus, Data: job.Status})
+		s.notifyWebhooks(job)
+		s.notifySlack(job)
+	}
+}
+
+// forgetStatus drops the last status of the deleted job id.
+func (s *Service) forgetStatus(id string) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+
+	delete(s.events.lastStatus, id)
+}
+
+// Logf logs a line of the job jobID and publishes it.
+func (s *Service) Logf(jobID, format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+
+	log.Print(line)
+
+	s.Publish(JobEvent{JobID: jobID, Type: EventLog, Data: line})
+}
+
+// runningProgress returns the progress of the running jobs.
+func (s *Service) runningProgress() []JobProgress {
+	s.progressMu.Lock()
+
+	ids := make([]string, 0, len(s.running))
+	for id := range s.running {
+		ids = append(ids, id)
+	}
+
+	s.progressMu.Unlock()
+
+	ans := make([]JobProgress, 0, len(ids))
+
+	for _, id := range ids {
+		if p := s.liveProgress(id); p != nil {
+			ans = append(ans, *p)
+		}
+	}
+
+	return ans
+}
+
+// apiJobEvents streams the events of a job, or of all the jobs when the
+// request has no id, as Server-Sent Events. The progress of the running
+// jobs is sent when it changes.
+func (s *Server) apiJobEvents(w http.ResponseWriter, r *http.Request) {
+	var jobID string
+
+	if r.PathValue("id") != "" {
+		id, ok := getIDFromRequest(r)
+		if !ok {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "Invalid ID",
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+
+		jobID = id.String()
+	}
+
+	var current *Job
+
+	if jobID != "" {
+		job, err := s.svc.Get(r.Context(), jobID)
+		if err != nil {
+			apiError := apiError{
+				Code:    http.StatusNotFound,
+				Message: http.StatusText(http.StatusNotFound),
+			}
+
+			renderJSON(w, http.StatusNotFound, apiError)
+
+			return
+		}
+
+		current = &job
+	}
+
+	events, unsubscribe := s.svc.Subscribe(jobID)
+	defer unsubscribe()
+
+	// the stream of all the jobs of a user who is not an admin has its jobs only
+	user, restricted := UserFromContext(r.Context())
+	restricted = restricted && !user.IsAdmin() && jobID == ""
+	visible := map[string]bool{}
+
+	canSee := func(id string) bool {
+		if !restricted {
+			return true
+		}
+
+		ok, seen := visible[id]
+		if !seen {
+			_, err := s.svc.Get(r.Context(), id)
+			ok = err == nil
+			visible[id] = ok
+		}
+
+		return ok
+	}
+
+	rc := http.NewResponseController(w)
+
+	// the stream outlives the write timeout of the server
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(ev JobEvent) bool {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return true
+		}
+
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+			return false
+		}
+
+		return rc.Flush() == nil
+	}
+
+	if current != nil && !send(JobEvent{JobID: current.ID, Type: EventStatus, Time: time.Now().UTC(), Data: current.Status}) {
+		return
+	}
+
+	progressTicker := time.NewTicker(progressInterval)
+	defer progressTicker.Stop()
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	last := map[string]JobProgress{}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			if !canSee(ev.JobID) {
+				continue
+			}
+
+			if !send(ev) {
+				return
+			}
+		case <-progressTicker.C:
+			for _, p := range s.svc.runningProgress() {
+				if (jobID != "" && p.JobID != jobID) || !canSee(p.JobID) {
+					continue
+				}
+
+				// the eta changes with the clock, the counters tell if something was done
+				if prev, ok := last[p.JobID]; ok && prev.Progress == p.Progress {
+					continue
+				}
+
+				last[p.JobID] = p
+
+				if !send(JobEvent{JobID: p.JobID, Type: EventProgress, Time: time.Now().UTC(), Data: p}) {
+					return
+				}
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/geocoder"
+)
+
+// MaxResolvePlaces is the maximum number of places resolved by one request.
+const MaxResolvePlaces = 100
+
+// ErrGeocoderNotConfigured is returned when the service has no geocoder.
+var ErrGeocoderNotConfigured = errors.New("geocoder is not configured")
+
+// ResolvedPlace is the location of a place name, with the bounding box to
+// create a tiled job for it. Error is set when the place was not resolved.
+type ResolvedPlace struct {
+	Place  string  `json:"place"`
+	Name   string  `json:"name,omitempty"`
+	Lat    float64 `json:"lat,omitempty"`
+	Lon    float64 `json:"lon,omitempty"`
+	BBox   string  `json:"bbox,omitempty"`
+	Zoom   int     `json:"zoom,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// WithGeocoder resolves place names with g. It is expected to be cached and
+// rate limited, see geocoder.NewCached.
+func WithGeocoder(g geocoder.Geocoder) ServiceOption {
+	return func(s *Service) {
+		s.geocoder = g
+	}
+}
+
+// ResolvePlaces geocodes the places one after the other, restricted to the
+// country code when it is not empty. The places that are not found have
+// their error set. It stops when ctx is done.
+func (s *Service) ResolvePlaces(ctx context.Context, places []string, country string) ([]ResolvedPlace, error) {
+	if s.geocoder == nil {
+		return nil, ErrGeocoderNotConfigured
+	}
+
+	const (
+		defaultZoom   = 15
+		defaultRadius = 10000
+	)
+
+	ans := make([]ResolvedPlace, len(places))
+
+	for i, place := range places {
+		ans[i].Place = place
+
+		res, err := s.geocoder.Geocode(ctx, place, country)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			ans[i].Error = err.Error()
+
+			continue
+		}
+
+		ans[i].Name = res.Name
+		ans[i].Lat = res.Lat
+		ans[i].Lon = res.Lon
+		ans[i].BBox = res.BBox()
+		ans[i].Zoom = res.Zoom(defaultZoom)
+		ans[i].Radius = res.Radius(defaultRadius)
+	}
+
+	return ans, nil
+}
+
+type resolvePlacesRequest struct {
+	Places  []string `json:"places"`
+	Country string   `json:"country"`
+}
+
+type resolvePlacesResponse struct {
+	Places []ResolvedPlace `json:"places"`
+}
+
+func (r *resolvePlacesRequest) validate() error {
+	places := r.Places[:0]
+
+	for _, p := range r.Places {
+		if p = strings.TrimSpace(p); p != "" {
+			places = append(places, p)
+		}
+	}
+
+	r.Places = places
+
+	if len(r.Places) == 0 {
+		return errors.New("missing places")
+	}
+
+	if len(r.Places) > MaxResolvePlaces {
+		return fmt.Errorf("at most %d places can be resolved at once", MaxResolvePlaces)
+	}
+
+	if r.Country != "" && len(r.Country) != 2 {
+		return errors.New("invalid country")
+	}
+
+	return nil
+}
+
+func (s *Server) apiResolvePlaces(w http.ResponseWriter, r *http.Request) {
+	var req resolvePlacesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err == nil {
+		err = req.validate()
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	// places that are not cached are resolved at the pace of the provider,
+	// which can take longer than the write timeout of the server
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	places, err := s.svc.ResolvePlaces(r.Context(), req.Places, req.Country)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrGeocoderNotConfigured) {
+			code = http.StatusNotImplemented
+		}
+
+		apiError := apiError{
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, resolvePlacesResponse{Places: places})
+}
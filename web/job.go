@@ -3,7 +3,18 @@ package web
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
+
+	"github.com/gosom/google-maps-scraper/browser"
+	"github.com/gosom/google-maps-scraper/emailverify"
+	"github.com/gosom/google-maps-scraper/geocoder"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/profile"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/tiling"
 )
 
 var jobs []Job
@@ -13,6 +24,16 @@ const (
 	StatusWorking = "working"
 	StatusOK      = "ok"
 	StatusFailed  = "failed"
+	StatusPaused  = "paused"
+	// StatusWaiting is a job waiting for the job it depends on to complete
+	StatusWaiting = "waiting"
+)
+
+const (
+	// MaxPriority is the highest priority of a job
+	MaxPriority = 10
+	// MaxShare is the highest concurrency share of a job
+	MaxShare = 10
 )
 
 type SelectParams struct {
@@ -28,12 +49,68 @@ type JobRepository interface {
 	Update(context.Context, *Job) error
 }
 
+// ClaimLease is how long a server keeps a job it claimed without renewing
+// the claim. The working jobs of a server that stopped renewing them, e.g.
+// it crashed, are claimed again by the others after it.
+const ClaimLease = 5 * time.Minute
+
+// JobClaimer is a JobRepository shared by several servers. It hands a
+// pending job to one of them only.
+type JobClaimer interface {
+	// ClaimPending marks a pending job, or a working job whose claim is
+	// older than ClaimLease, as working and returns it, or ErrNotFound when
+	// there is none.
+	ClaimPending(context.Context) (Job, error)
+	// RenewClaims renews the claims of the working jobs ids.
+	RenewClaims(ctx context.Context, ids []string) error
+}
+
 type Job struct {
 	ID     string
 	Name   string
 	Date   time.Time
 	Status string
 	Data   JobData
+	Stats  JobStats
+}
+
+// JobStats are the results of a finished job.
+type JobStats struct {
+	Places int `json:"places"`
+	// ReviewsComplete is the number of places whose extracted reviews match their review count.
+	ReviewsComplete int `json:"reviews_complete"`
+	// Dropped is the number of places dropped by the noise filter.
+	Dropped int `json:"dropped"`
+	// Emails is the number of places with at least one email.
+	Emails int `json:"emails"`
+	// ResultBytes is the size of the result file.
+	ResultBytes int64 `json:"result_bytes"`
+	// Uploaded is set when the result file was copied to the object store.
+	Uploaded bool `json:"uploaded,omitempty"`
+	// FinishedAt is when the job completed or failed, it is set by the
+	// service.
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+// FinishedAt returns when the job completed or failed, its creation date for
+// the jobs that finished before the time was recorded.
+func (j *Job) FinishedAt() time.Time {
+	if j.Stats.FinishedAt.IsZero() {
+		return j.Date
+	}
+
+	return j.Stats.FinishedAt
+}
+
+// ReviewsCompleteness is the share of the places with complete reviews.
+//
+//nolint:gocritic // this is used in template
+func (s JobStats) ReviewsCompleteness() float64 {
+	if s.Places == 0 {
+		return 0
+	}
+
+	return float64(s.ReviewsComplete) / float64(s.Places)
 }
 
 func (j *Job) Validate() error {
@@ -61,22 +138,113 @@ func (j *Job) Validate() error {
 }
 
 type JobData struct {
-	Keywords []string      `json:"keywords"`
-	Lang     string        `json:"lang"`
-	Zoom     int           `json:"zoom"`
-	Lat      string        `json:"lat"`
-	Lon      string        `json:"lon"`
-	FastMode bool          `json:"fast_mode"`
-	Radius   int           `json:"radius"`
-	Depth    int           `json:"depth"`
-	Email    bool          `json:"email"`
-	MaxTime  time.Duration `json:"max_time"`
-	Proxies  []string      `json:"proxies"`
-	UseCroxy bool          `json:"use_croxy"`
+	// Tenant is the customer the job runs for, the usage is reported by tenant
+	Tenant string `json:"tenant,omitempty"`
+	// Owner is the id of the user that created the job, it is set by the
+	// server when -auth is on
+	Owner string `json:"owner,omitempty"`
+	// APIKeyID is the API key the job was created with, its places count
+	// towards the quota of the key
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// Slack is the Slack user that created the job with the slash command,
+	// it is set by the server
+	Slack *SlackOrigin `json:"slack,omitempty"`
+	// Pinned jobs are never deleted by the retention policy
+	Pinned   bool     `json:"pinned,omitempty"`
+	Keywords []string `json:"keywords"`
+	Lang     string   `json:"lang"`
+	Region   string   `json:"region"`
+	Zoom     int      `json:"zoom"`
+	Lat      string   `json:"lat"`
+	Lon      string   `json:"lon"`
+	// Place is geocoded to the search center when no coordinates are given
+	Place string `json:"place,omitempty"`
+	// Geocoders are the providers the place is geocoded with, in order.
+	// GeocoderKeys holds the API keys by provider, the environment is used for the missing ones.
+	Geocoders    []string          `json:"geocoders,omitempty"`
+	GeocoderKeys map[string]string `json:"geocoder_keys,omitempty"`
+	// BBox (minlat,minlon,maxlat,maxlon) is split in tiles that are searched one by one
+	BBox       string `json:"bbox,omitempty"`
+	TileSystem string `json:"tile_system,omitempty"`
+	TileLevel  int    `json:"tile_level,omitempty"`
+	// BBoxExclude are the zones of the bbox that are not searched, see tiling.ParseZone
+	BBoxExclude []string `json:"bbox_exclude,omitempty"`
+	// TileMaxLevel is the level down to which saturated tiles are split in fast mode
+	TileMaxLevel int `json:"tile_max_level,omitempty"`
+	// TileRadius covers the circle of Radius around the center with hexagonal tiles of this radius in meters
+	TileRadius int           `json:"tile_radius,omitempty"`
+	FastMode   bool          `json:"fast_mode"`
+	Radius     int           `json:"radius"`
+	Depth      int           `json:"depth"`
+	Email      bool          `json:"email"`
+	MaxTime    time.Duration `json:"max_time"`
+	Proxies    []string      `json:"proxies"`
+	// ProxyGroup is a proxy group of the server used instead of Proxies
+	ProxyGroup string `json:"proxy_group,omitempty"`
+	// BrowserEngine is chromium, firefox or webkit. Empty uses the engine of the server
+	BrowserEngine string `json:"browser_engine,omitempty"`
+	// Headful shows the windows of the browsers
+	Headful bool `json:"headful,omitempty"`
+	// RotateFingerprint gives each browser of the job a random fingerprint
+	RotateFingerprint bool `json:"rotate_fingerprint,omitempty"`
+	// Screenshots keeps a screenshot of each place page, see Entry.ScreenshotPath
+	Screenshots bool `json:"screenshots,omitempty"`
+	// SolveCaptchas solves the captchas with the solver of the server instead of waiting them out
+	SolveCaptchas bool   `json:"solve_captchas,omitempty"`
+	UseCroxy      bool   `json:"use_croxy"`
+	SortBy        string `json:"sort_by"`
+	// Fallback are the strategies the keywords are searched with in turn, e.g. static:1,browser:3,croxy,
+	// see gmaps.ParseFallbackChain. Empty uses the fallback of the server
+	Fallback string `json:"fallback,omitempty"`
+	// FallbackOn are the conditions on which a search falls back: empty, blocked, parse or error
+	FallbackOn []string `json:"fallback_on,omitempty"`
+	// FuzzyDedup merges entries with the same normalized title and location
+	FuzzyDedup bool `json:"fuzzy_dedup"`
+	// StableOutput writes the places sorted by data_id, once each, when the job completes
+	StableOutput bool `json:"stable_output,omitempty"`
+	// Normalize cleans up the whitespace and unicode form of the title, address and description
+	Normalize   bool `json:"normalize,omitempty"`
+	StripEmojis bool `json:"strip_emojis,omitempty"`
+	// ReviewInsights computes the sentiment, keywords and rating per year of the reviews
+	ReviewInsights bool `json:"review_insights,omitempty"`
+	// TranslateTo translates the reviews to this language with the translator of the server
+	TranslateTo string `json:"translate_to,omitempty"`
+	// NoiseFilter drops places that are not businesses, NoiseCategories replaces the default categories
+	NoiseFilter     bool     `json:"noise_filter,omitempty"`
+	NoiseCategories []string `json:"noise_categories,omitempty"`
+	// EmailTypes keeps the emails of these types: disposable, role or personal. Empty keeps all of them
+	EmailTypes []string `json:"email_types,omitempty"`
+	// Enrichers are run over the homepages crawled for emails instead of the ones of the server
+	Enrichers []string `json:"enrichers,omitempty"`
+	// Headers and Cookies are sent with the requests to Google
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies string            `json:"cookies,omitempty"`
+	// Profile limits the settings of the job, e.g. conservative. Empty uses the default of the server
+	Profile string `json:"profile,omitempty"`
+	// OutputPrefix is appended to the object store prefix when uploading the results
+	OutputPrefix string `json:"output_prefix"`
+	// DependsOn is the job whose results are the input of this job, through Transform.
+	// The job waits until it completes.
+	DependsOn string `json:"depends_on,omitempty"`
+	// Transform is applied to the places of DependsOn, or to the ones uploaded to
+	// /api/v1/enrich without it, instead of searching the keywords
+	Transform string `json:"transform,omitempty"`
+	// Webhooks are notified when the job starts, passes progress milestones, completes and fails
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// Writers are the outputs the places are written to besides the csv file, e.g. postgres and webhook
+	Writers []JobWriter `json:"writers,omitempty"`
+	// CSV is the layout of the csv file: its columns, delimiter, quoting and nested fields
+	CSV *gmaps.CSVLayout `json:"csv,omitempty"`
+	// Priority orders the pending jobs, the highest first. A pending job
+	// preempts a running job of a lower priority when the runner is busy
+	Priority int `json:"priority,omitempty"`
+	// Share is the weight of the job in the split of the concurrency of the
+	// server between the running jobs, 1 when it is 0
+	Share int `json:"share,omitempty"`
 }
 
 func (d *JobData) Validate() error {
-	if len(d.Keywords) == 0 {
+	if len(d.Keywords) == 0 && d.Transform == "" {
 		return errors.New("missing keywords")
 	}
 
@@ -88,7 +256,11 @@ func (d *JobData) Validate() error {
 		return errors.New("invalid lang")
 	}
 
-	if d.Depth == 0 {
+	if err := gmaps.ValidateRegion(d.Region); err != nil {
+		return err
+	}
+
+	if d.Depth == 0 && d.Transform == "" {
 		return errors.New("missing depth")
 	}
 
@@ -96,9 +268,202 @@ func (d *JobData) Validate() error {
 		return errors.New("missing max time")
 	}
 
-	if d.FastMode && (d.Lat == "" || d.Lon == "") {
+	if d.FastMode && (d.Lat == "" || d.Lon == "") && d.Place == "" && d.BBox == "" {
 		return errors.New("missing geo coordinates")
 	}
 
+	if err := geocoder.ValidateProviders(d.Geocoders, d.GeocoderKeys); err != nil {
+		return err
+	}
+
+	if d.BBox != "" {
+		if _, err := tiling.ParseBBox(d.BBox); err != nil {
+			return err
+		}
+
+		if _, err := d.Exclusions(); err != nil {
+			return err
+		}
+
+		if err := tiling.ValidateSystem(d.TileSystem, d.TileLevel); err != nil {
+			return err
+		}
+
+		if err := tiling.ValidateMaxLevel(d.TileSystem, d.TileLevel, d.TileMaxLevel); err != nil {
+			return err
+		}
+
+		if d.TileMaxLevel > 0 && !d.FastMode {
+			return errors.New("tile_max_level can only be used in fast mode")
+		}
+
+		if d.Place != "" {
+			return errors.New("bbox cannot be used with place")
+		}
+	}
+
+	if d.TileRadius < 0 {
+		return errors.New("tile_radius must not be negative")
+	}
+
+	if d.TileRadius > 0 {
+		if !d.FastMode {
+			return errors.New("tile_radius can only be used in fast mode")
+		}
+
+		if d.BBox != "" {
+			return errors.New("tile_radius cannot be used with bbox")
+		}
+	}
+
+	if err := browser.ValidateEngine(d.BrowserEngine); err != nil {
+		return err
+	}
+
+	if d.FastMode && (d.BrowserEngine != "" || d.Headful) {
+		return errors.New("browser_engine and headful cannot be used in fast mode")
+	}
+
+	if d.ProxyGroup != "" && len(d.Proxies) > 0 {
+		return errors.New("only one of proxies and proxy_group can be used")
+	}
+
+	if len(d.BBoxExclude) > 0 && d.BBox == "" {
+		return errors.New("bbox_exclude can only be used with bbox")
+	}
+
+	if d.TranslateTo != "" && len(d.TranslateTo) != 2 {
+		return errors.New("invalid translate_to")
+	}
+
+	if d.StripEmojis && !d.Normalize {
+		return errors.New("strip_emojis can only be used with normalize")
+	}
+
+	if !emailverify.ValidTypes(d.EmailTypes) {
+		return errors.New("invalid email_types")
+	}
+
+	if _, err := gmaps.LookupEnrichers(d.Enrichers); err != nil {
+		return errors.New("invalid enrichers")
+	}
+
+	if err := profile.Validate(d.Profile); err != nil {
+		return err
+	}
+
+	if len(d.Tenant) > 100 {
+		return errors.New("tenant must be at most 100 characters")
+	}
+
+	if strings.Contains(d.OutputPrefix, "..") || strings.HasPrefix(d.OutputPrefix, "/") {
+		return errors.New("invalid output_prefix")
+	}
+
+	if err := d.validateDependency(); err != nil {
+		return err
+	}
+
+	if err := validateWebhooks(d.Webhooks); err != nil {
+		return err
+	}
+
+	if err := validateWriters(d.Writers); err != nil {
+		return err
+	}
+
+	if d.CSV != nil {
+		if err := d.CSV.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if d.Priority < 0 || d.Priority > MaxPriority {
+		return fmt.Errorf("priority must be between 0 and %d", MaxPriority)
+	}
+
+	if d.Share < 0 || d.Share > MaxShare {
+		return fmt.Errorf("share must be between 0 and %d", MaxShare)
+	}
+
+	if d.Cookies != "" {
+		if _, err := http.ParseCookie(d.Cookies); err != nil {
+			return errors.New("invalid cookies")
+		}
+	}
+
+	if runner.ValidateSortBy(d.SortBy) != nil {
+		return errors.New("invalid sort_by")
+	}
+
+	return d.validateFallback()
+}
+
+// redactedValue replaces the secrets of a job in the API responses.
+const redactedValue = "[redacted]"
+
+// Redacted returns a copy of d with the values of the headers and the cookies
+// replaced, as they usually carry the session of a Google account. The names
+// of the headers are kept so the job can still be told apart.
+func (d *JobData) Redacted() JobData {
+	ans := *d
+
+	if len(d.Headers) > 0 {
+		ans.Headers = make(map[string]string, len(d.Headers))
+
+		for k := range d.Headers {
+			ans.Headers[k] = redactedValue
+		}
+	}
+
+	if d.Cookies != "" {
+		ans.Cookies = redactedValue
+	}
+
+	return ans
+}
+
+func (d *JobData) validateFallback() error {
+	chain, err := d.FallbackChain()
+	if err != nil {
+		return err
+	}
+
+	if len(chain.Steps) == 0 {
+		return nil
+	}
+
+	if d.BBox != "" || d.TileRadius > 0 || d.Transform != "" {
+		return errors.New("fallback cannot be used with bbox, tile_radius or transform")
+	}
+
+	if chain.Has(gmaps.StrategyStatic) && (d.Lat == "" || d.Lon == "") && d.Place == "" {
+		return errors.New("the static fallback needs geo coordinates")
+	}
+
 	return nil
 }
+
+// FallbackChain returns the fallback chain of the job, the zero chain when
+// it uses the one of the server.
+func (d *JobData) FallbackChain() (gmaps.FallbackChain, error) {
+	return gmaps.ParseFallbackChain(d.Fallback, d.FallbackOn)
+}
+
+// Warnings returns the settings of the job that are more aggressive than
+// recommended. The settings of the server are not known here.
+func (d *JobData) Warnings() []string {
+	if _, ok := profile.Get(d.Profile); ok {
+		return nil
+	}
+
+	return profile.Warnings(profile.Settings{
+		Depth: d.Depth,
+		Email: d.Email,
+	})
+}
+
+// Exclusions returns the zones of the bounding box that are not searched.
+func (d *JobData) Exclusions() (tiling.Exclusions, error) {
+	return tiling.ParseExclusions(strings.Join(d.BBoxExclude, ";"))
+}
@@ -0,0 +1,32 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobDataRedacted(t *testing.T) {
+	d := JobData{
+		Keywords: []string{"cafe"},
+		Headers:  map[string]string{"Authorization": "Bearer abc"},
+		Cookies:  "SID=abc",
+	}
+
+	got := d.Redacted()
+	require.Equal(t, map[string]string{"Authorization": redactedValue}, got.Headers)
+	require.Equal(t, redactedValue, got.Cookies)
+	require.Equal(t, []string{"cafe"}, got.Keywords)
+
+	// the job itself keeps its secrets
+	require.Equal(t, "Bearer abc", d.Headers["Authorization"])
+	require.Equal(t, "SID=abc", d.Cookies)
+
+	require.Empty(t, (&JobData{}).Redacted().Headers)
+	require.Empty(t, (&JobData{}).Redacted().Cookies)
+
+	def := NewJobDefinition(&Job{Name: "cafes", Data: JobData{Cookies: "SID=abc", MaxTime: time.Minute}})
+	require.Equal(t, redactedValue, def.Cookies)
+	require.Equal(t, time.Duration(60), def.MaxTime)
+}
@@ -0,0 +1,632 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionCookie = "gmaps_session"
+
+const (
+	// loginWindow is how long the failed sign ins are counted. Past
+	// maxUserFailures for a username or maxIPFailures from an address, the
+	// sign ins are refused until the window of the first failure ends.
+	loginWindow     = 15 * time.Minute
+	maxUserFailures = 10
+	maxIPFailures   = 50
+)
+
+// loginThrottledError is returned when a sign in is refused because of the
+// previous failures.
+type loginThrottledError struct {
+	wait time.Duration
+}
+
+func (e *loginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed sign ins, try again in %s", e.wait.Round(time.Second))
+}
+
+// WithAuth requires the users of the service to sign in to the web UI and
+// the API. The API also takes the username and password as basic auth, or
+// an API key.
+func WithAuth() ServerOption {
+	return func(s *Server) {
+		s.auth = true
+		s.keyLimiter = newKeyLimiter()
+		s.logins = newLoginLimiter()
+	}
+}
+
+// publicPaths are served without signing in: the login, the assets, the
+// specification of the API, the health checks of the probes, the status
+// page, and the endpoints that check their own secret.
+var publicPaths = []string{
+	"/login",
+	"/status",
+	"/auth/oidc/",
+	"/static/",
+	"/api/openapi.json",
+	"/api/health",
+	"/integrations/slack/",
+	"/debug/",
+}
+
+func (s *Server) registerAuth(mux *http.ServeMux) {
+	mux.HandleFunc("/login", s.login)
+	mux.HandleFunc("/logout", s.logout)
+
+	if s.oidc != nil {
+		mux.HandleFunc("/auth/oidc/login", s.oidcLogin)
+		mux.HandleFunc("/auth/oidc/callback", s.oidcCallback)
+	}
+
+	mux.HandleFunc("/api/v1/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		s.apiGetMe(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/users", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			s.apiGetUsers(w, r)
+		case http.MethodPost:
+			s.apiCreateUser(w, r)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/keys", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			s.apiGetAPIKeys(w, r)
+		case http.MethodPost:
+			s.apiCreateAPIKey(w, r)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodDelete {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		s.apiDeleteAPIKey(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/keys/{id}/usage", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		s.apiGetAPIKeyUsage(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodDelete {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		s.apiDeleteUser(w, r)
+	})
+}
+
+func isPublicPath(p string) bool {
+	for _, prefix := range publicPaths {
+		if p == prefix || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(p, prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authenticate serves the requests of signed in users with the user in
+// their context. The others are sent to the login page, or get a 401 from
+// the API.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		user, key, err := s.requestUser(r)
+
+		var throttled *loginThrottledError
+		if errors.As(err, &throttled) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(throttled.wait.Seconds())+1))
+
+			apiError := apiError{
+				Code:    http.StatusTooManyRequests,
+				Message: err.Error(),
+			}
+
+			renderJSON(w, http.StatusTooManyRequests, apiError)
+
+			return
+		}
+
+		if err == nil && key != nil {
+			s.serveAPIKey(w, r.WithContext(ContextWithUser(r.Context(), &user)), key, next)
+
+			return
+		}
+
+		if err == nil {
+			next.ServeHTTP(w, r.WithContext(ContextWithUser(r.Context(), &user)))
+
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/"):
+			w.Header().Set("WWW-Authenticate", `Basic realm="google-maps-scraper"`)
+
+			apiError := apiError{
+				Code:    http.StatusUnauthorized,
+				Message: http.StatusText(http.StatusUnauthorized),
+			}
+
+			renderJSON(w, http.StatusUnauthorized, apiError)
+		case r.Header.Get("HX-Request") != "":
+			// htmx follows this header instead of swapping the login page in
+			w.Header().Set("HX-Redirect", "/login")
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		}
+	})
+}
+
+// requestUser returns the user of the API key, the session cookie or the
+// basic auth of r, and the key when it was made with one.
+func (s *Server) requestUser(r *http.Request) (User, *APIKey, error) {
+	raw := r.Header.Get("X-API-Key")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		raw = bearer
+	}
+
+	if raw != "" {
+		user, key, err := s.svc.APIKeyUser(r.Context(), raw)
+		if err != nil {
+			return User{}, nil, err
+		}
+
+		return user, &key, nil
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		user, err := s.svc.SessionUser(r.Context(), cookie.Value)
+
+		return user, nil, err
+	}
+
+	if username, password, ok := r.BasicAuth(); ok {
+		user, err := s.signIn(r, username, password)
+
+		return user, nil, err
+	}
+
+	return User{}, nil, ErrInvalidCredentials
+}
+
+// serveAPIKey serves a request made with key within its rate limit and
+// counts it.
+func (s *Server) serveAPIKey(w http.ResponseWriter, r *http.Request, key *APIKey, next http.Handler) {
+	if ok, wait := s.keyLimiter.allow(key, time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+
+		apiError := apiError{
+			Code:    http.StatusTooManyRequests,
+			Message: fmt.Sprintf("rate limit of %d requests per minute exceeded", key.RateLimit),
+		}
+
+		renderJSON(w, http.StatusTooManyRequests, apiError)
+
+		return
+	}
+
+	if err := s.svc.countAPIKeyRequest(r.Context(), key); err != nil {
+		log.Printf("failed to count a request of api key %s: %v", key.ID, err)
+	}
+
+	next.ServeHTTP(w, r.WithContext(ContextWithAPIKey(r.Context(), key)))
+}
+
+// signIn returns the user of username when password is its password, unless
+// there were too many failed sign ins for username or from the address of r.
+func (s *Server) signIn(r *http.Request, username, password string) (User, error) {
+	ip := clientIP(r)
+	now := time.Now()
+
+	if wait := s.logins.wait(ip, username, now); wait > 0 {
+		return User{}, &loginThrottledError{wait: wait}
+	}
+
+	user, err := s.svc.Authenticate(r.Context(), username, password)
+
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		s.logins.fail(ip, username, now)
+	case err == nil:
+		s.logins.succeed(username)
+	}
+
+	return user, err
+}
+
+// clientIP returns the address r comes from. The forwarded headers are not
+// trusted, they are set by the clients.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// loginLimiter counts the failed sign ins by username and by address.
+type loginLimiter struct {
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+}
+
+type loginFailures struct {
+	count int
+	first time.Time
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{failures: make(map[string]*loginFailures)}
+}
+
+// wait returns how long the sign ins of username from ip are refused, 0
+// when they are not.
+func (l *loginLimiter) wait(ip, username string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var ans time.Duration
+
+	for key, limit := range map[string]int{"user:" + username: maxUserFailures, "ip:" + ip: maxIPFailures} {
+		f, ok := l.failures[key]
+		if !ok || f.count < limit {
+			continue
+		}
+
+		ans = max(ans, f.first.Add(loginWindow).Sub(now))
+	}
+
+	return ans
+}
+
+func (l *loginLimiter) fail(ip, username string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// forget the failures of the windows that ended
+	for key, f := range l.failures {
+		if now.Sub(f.first) >= loginWindow {
+			delete(l.failures, key)
+		}
+	}
+
+	for _, key := range []string{"user:" + username, "ip:" + ip} {
+		f, ok := l.failures[key]
+		if !ok {
+			f = &loginFailures{first: now}
+			l.failures[key] = f
+		}
+
+		f.count++
+	}
+}
+
+// succeed forgets the failures of username, not the ones of its address.
+func (l *loginLimiter) succeed(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, "user:"+username)
+}
+
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user *User) error {
+	token, err := s.svc.NewSession(r.Context(), user)
+	if err != nil {
+		return err
+	}
+
+	// Lax keeps the cookie off the forms posted from other sites
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := s.tmpl["static/templates/login.html"]
+	if !ok {
+		http.Error(w, "missing tpl", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := struct {
+		Error    string
+		Username string
+		OIDC     bool
+	}{
+		OIDC: s.oidc != nil,
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = tmpl.Execute(w, data)
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		data.Username = strings.TrimSpace(r.Form.Get("username"))
+
+		user, err := s.signIn(r, data.Username, r.Form.Get("password"))
+
+		var throttled *loginThrottledError
+		if errors.As(err, &throttled) {
+			data.Error = err.Error()
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(throttled.wait.Seconds())+1))
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = tmpl.Execute(w, data)
+
+			return
+		}
+
+		if errors.Is(err, ErrInvalidCredentials) {
+			data.Error = err.Error()
+
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = tmpl.Execute(w, data)
+
+			return
+		}
+
+		if err == nil {
+			err = s.startSession(w, r, &user)
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := s.svc.DeleteSession(r.Context(), cookie.Value); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// requireAdmin responds with a 403 unless the user of r is an admin.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if user, ok := UserFromContext(r.Context()); ok && user.IsAdmin() {
+		return true
+	}
+
+	apiError := apiError{
+		Code:    http.StatusForbidden,
+		Message: "admin only",
+	}
+
+	renderJSON(w, http.StatusForbidden, apiError)
+
+	return false
+}
+
+func (s *Server) apiGetMe(w http.ResponseWriter, r *http.Request) {
+	user, _ := UserFromContext(r.Context())
+
+	renderJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) apiGetUsers(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	users, err := s.svc.Users(r.Context())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, users)
+}
+
+type apiCreateUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+func (s *Server) apiCreateUser(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var req apiCreateUserRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = RoleUser
+	}
+
+	user := User{
+		Username: req.Username,
+		Role:     req.Role,
+	}
+
+	if err := s.svc.CreateUser(r.Context(), &user, req.Password); err != nil {
+		code := http.StatusUnprocessableEntity
+		if errors.Is(err, ErrAlreadyExists) {
+			code = http.StatusConflict
+		}
+
+		apiError := apiError{
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, user)
+}
+
+func (s *Server) apiDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if me, _ := UserFromContext(r.Context()); me.ID == id.String() {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "cannot delete yourself",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if err := s.svc.DeleteUser(r.Context(), id.String()); err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
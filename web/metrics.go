@@ -0,0 +1,610 @@
+package web
+
+import (
+	"context"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+const (
+	defaultMetricsInterval  = time.Minute
+	defaultMetricsRetention = 90 * 24 * time.Hour
+)
+
+// MetricsSnapshot holds the counters of one collection interval.
+type MetricsSnapshot struct {
+	Time          time.Time `json:"time"`
+	JobsCompleted int       `json:"jobs_completed"`
+	JobsFailed    int       `json:"jobs_failed"`
+	// JobsBlocked are the completed jobs that did not produce any result,
+	// which is what usually happens when Google blocks the scraper.
+	JobsBlocked int `json:"jobs_blocked"`
+	Places      int `json:"places"`
+	// Captchas are the search and place pages where Google served a captcha.
+	Captchas int `json:"captchas"`
+	// CaptchasSolved are the captchas solved by the captcha solver and
+	// CaptchaCost what they cost, from the -captcha-cost of the server.
+	CaptchasSolved int     `json:"captchas_solved"`
+	CaptchaCost    float64 `json:"captcha_cost"`
+}
+
+// MetricsRepository stores the metrics snapshots so they survive restarts.
+type MetricsRepository interface {
+	InsertMetrics(context.Context, *MetricsSnapshot) error
+	SelectMetrics(ctx context.Context, since time.Time) ([]MetricsSnapshot, error)
+	DeleteMetrics(ctx context.Context, before time.Time) error
+}
+
+// MetricsCollector counts the finished jobs and the scraped places
+// since the last snapshot.
+type MetricsCollector struct {
+	mu          sync.Mutex
+	current     MetricsSnapshot
+	lastCaptcha time.Time
+	// circuits are the hosts whose circuit is open or half open
+	circuits map[string]string
+}
+
+func (c *MetricsCollector) AddPlaces(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current.Places += n
+}
+
+// CaptchaServed records a page where Google served a captcha.
+func (c *MetricsCollector) CaptchaServed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current.Captchas++
+	c.lastCaptcha = time.Now().UTC()
+}
+
+// CaptchaSolved records a captcha solved by the captcha solver for cost.
+func (c *MetricsCollector) CaptchaSolved(cost float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current.CaptchasSolved++
+	c.current.CaptchaCost += cost
+}
+
+// LastCaptcha returns when Google last served a captcha, zero if it did not
+// since the server started.
+func (c *MetricsCollector) LastCaptcha() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastCaptcha
+}
+
+// CircuitChanged records the new state of the circuit of host: open,
+// half_open or closed.
+func (c *MetricsCollector) CircuitChanged(host, state string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if state == "closed" {
+		delete(c.circuits, host)
+
+		return
+	}
+
+	if c.circuits == nil {
+		c.circuits = make(map[string]string)
+	}
+
+	c.circuits[host] = state
+}
+
+// Circuits returns the hosts whose circuit is open or half open, with its
+// state.
+func (c *MetricsCollector) Circuits() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return maps.Clone(c.circuits)
+}
+
+// JobFinished records a job that finished with the given status and number of results.
+func (c *MetricsCollector) JobFinished(status string, places int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch status {
+	case StatusOK:
+		c.current.JobsCompleted++
+
+		if places == 0 {
+			c.current.JobsBlocked++
+		}
+	case StatusFailed:
+		c.current.JobsFailed++
+	}
+}
+
+// snapshot returns the counters collected so far and resets them.
+func (c *MetricsCollector) snapshot(now time.Time) MetricsSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ans := c.current
+	ans.Time = now
+
+	c.current = MetricsSnapshot{}
+
+	return ans
+}
+
+// WithMetrics persists the metrics every interval and keeps them for retention.
+// Zero values use the defaults of one minute and 90 days.
+func WithMetrics(repo MetricsRepository, interval, retention time.Duration) ServiceOption {
+	return func(s *Service) {
+		if interval <= 0 {
+			interval = defaultMetricsInterval
+		}
+
+		if retention <= 0 {
+			retention = defaultMetricsRetention
+		}
+
+		s.metricsRepo = repo
+		s.metricsInterval = interval
+		s.metricsRetention = retention
+	}
+}
+
+// WithMetricsObserver calls fn with the point of each metrics snapshot, e.g.
+// to raise alerts. It needs WithMetrics.
+func WithMetricsObserver(fn func(MetricsPoint)) ServiceOption {
+	return func(s *Service) {
+		s.metricsObserver = fn
+	}
+}
+
+// Metrics returns the collector where the runner records the job metrics.
+func (s *Service) Metrics() *MetricsCollector {
+	return &s.metrics
+}
+
+// RunMetrics writes a metrics snapshot every interval until ctx is done.
+// It returns immediately when no metrics repository is configured.
+func (s *Service) RunMetrics(ctx context.Context) error {
+	if s.metricsRepo == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(s.metricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// keep what was collected since the last tick
+			s.saveMetrics(context.Background(), time.Now().UTC())
+
+			return nil
+		case now := <-ticker.C:
+			s.saveMetrics(ctx, now.UTC())
+		}
+	}
+}
+
+func (s *Service) saveMetrics(ctx context.Context, now time.Time) {
+	snapshot := s.metrics.snapshot(now)
+
+	if s.metricsObserver != nil {
+		s.metricsObserver(snapshot.point(s.metricsInterval))
+	}
+
+	if err := s.metricsRepo.InsertMetrics(ctx, &snapshot); err != nil {
+		log.Printf("failed to save metrics: %v", err)
+	}
+
+	if err := s.metricsRepo.DeleteMetrics(ctx, now.Add(-s.metricsRetention)); err != nil {
+		log.Printf("failed to delete old metrics: %v", err)
+	}
+}
+
+// MetricsPoint is the aggregation of the snapshots of one bucket.
+type MetricsPoint struct {
+	Time           time.Time `json:"time"`
+	JobsPerMinute  float64   `json:"jobs_per_minute"`
+	ErrorRate      float64   `json:"error_rate"`
+	BlockRate      float64   `json:"block_rate"`
+	Places         int       `json:"places"`
+	PlacesPerHour  float64   `json:"places_per_hour"`
+	Captchas       int       `json:"captchas"`
+	CaptchasSolved int       `json:"captchas_solved"`
+	CaptchaCost    float64   `json:"captcha_cost"`
+}
+
+// point returns the point of the snapshot, which covers d.
+func (m *MetricsSnapshot) point(d time.Duration) MetricsPoint {
+	finished := m.JobsCompleted + m.JobsFailed
+
+	p := MetricsPoint{
+		Time:           m.Time,
+		JobsPerMinute:  float64(finished) / d.Minutes(),
+		Places:         m.Places,
+		PlacesPerHour:  float64(m.Places) / d.Hours(),
+		Captchas:       m.Captchas,
+		CaptchasSolved: m.CaptchasSolved,
+		CaptchaCost:    m.CaptchaCost,
+	}
+
+	if finished > 0 {
+		p.ErrorRate = float64(m.JobsFailed) / float64(finished)
+	}
+
+	if m.JobsCompleted > 0 {
+		p.BlockRate = float64(m.JobsBlocked) / float64(m.JobsCompleted)
+	}
+
+	return p
+}
+
+// MetricsHistory aggregates the persisted snapshots since the given time
+// in buckets of the given size. Buckets without snapshots are omitted.
+func (s *Service) MetricsHistory(ctx context.Context, since time.Time, bucket time.Duration) ([]MetricsPoint, error) {
+	if s.metricsRepo == nil {
+		return nil, nil
+	}
+
+	snapshots, err := s.metricsRepo.SelectMetrics(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ans []MetricsPoint
+		cur MetricsSnapshot
+	)
+
+	flush := func() {
+		if cur.Time.IsZero() {
+			return
+		}
+
+		ans = append(ans, cur.point(bucket))
+	}
+
+	for i := range snapshots {
+		t := snapshots[i].Time.Truncate(bucket)
+
+		if !t.Equal(cur.Time) {
+			flush()
+
+			cur = MetricsSnapshot{Time: t}
+		}
+
+		cur.JobsCompleted += snapshots[i].JobsCompleted
+		cur.JobsFailed += snapshots[i].JobsFailed
+		cur.JobsBlocked += snapshots[i].JobsBlocked
+		cur.Places += snapshots[i].Places
+		cur.Captchas += snapshots[i].Captchas
+		cur.CaptchasSolved += snapshots[i].CaptchasSolved
+		cur.CaptchaCost += snapshots[i].CaptchaCost
+	}
+
+	flush()
+
+	return ans, nil
+}
+
+// metricsRange parses the days and bucket query parameters.
+// It defaults to the last 7 days in hourly buckets.
+func metricsRange(r *http.Request) (since time.Time, bucket time.Duration, ok bool) {
+	const maxDays = 90
+
+	days := 7
+	bucket = time.Hour
+
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDays {
+			return since, bucket, false
+		}
+
+		days = n
+	}
+
+	if v := r.URL.Query().Get("bucket"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			return since, bucket, false
+		}
+
+		bucket = d
+	}
+
+	since = time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour)
+
+	return since, bucket, true
+}
+
+// metricSeries are the values of the metrics points by metric name.
+var metricSeries = map[string]func(MetricsPoint) float64{
+	"jobs_per_minute": func(p MetricsPoint) float64 { return p.JobsPerMinute },
+	"error_rate":      func(p MetricsPoint) float64 { return p.ErrorRate },
+	"block_rate":      func(p MetricsPoint) float64 { return p.BlockRate },
+	"places":          func(p MetricsPoint) float64 { return float64(p.Places) },
+	"places_per_hour": func(p MetricsPoint) float64 { return p.PlacesPerHour },
+	"captchas":        func(p MetricsPoint) float64 { return float64(p.Captchas) },
+	"captchas_solved": func(p MetricsPoint) float64 { return float64(p.CaptchasSolved) },
+	"captcha_cost":    func(p MetricsPoint) float64 { return p.CaptchaCost },
+}
+
+// MetricSample is a value of a metric at a time.
+type MetricSample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// MetricHistory is the time series of a metric.
+type MetricHistory struct {
+	Name    string         `json:"name"`
+	Bucket  string         `json:"bucket"`
+	Samples []MetricSample `json:"samples"`
+}
+
+// historyRange parses the range and bucket query parameters of the history
+// of a metric. The range is a Go duration or a number of days, e.g. 7d, up
+// to 90 days, and defaults to 24h. The bucket defaults to about a hundredth
+// of the range, at least a minute.
+func historyRange(r *http.Request) (since time.Time, bucket time.Duration, ok bool) {
+	const (
+		maxRange       = 90 * 24 * time.Hour
+		defaultBuckets = 100
+	)
+
+	rng := 24 * time.Hour
+
+	if v := r.URL.Query().Get("range"); v != "" {
+		var err error
+
+		if days, found := strings.CutSuffix(v, "d"); found {
+			var n int
+
+			n, err = strconv.Atoi(days)
+			rng = time.Duration(n) * 24 * time.Hour
+		} else {
+			rng, err = time.ParseDuration(v)
+		}
+
+		if err != nil || rng < time.Minute || rng > maxRange {
+			return since, bucket, false
+		}
+	}
+
+	bucket = max((rng / defaultBuckets).Truncate(time.Minute), time.Minute)
+
+	if v := r.URL.Query().Get("bucket"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			return since, bucket, false
+		}
+
+		bucket = d
+	}
+
+	since = time.Now().UTC().Add(-rng)
+
+	return since, bucket, true
+}
+
+func (s *Server) apiGetMetricHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+
+	value, ok := metricSeries[name]
+	if !ok {
+		names := make([]string, 0, len(metricSeries))
+		for k := range metricSeries {
+			names = append(names, k)
+		}
+
+		slices.Sort(names)
+
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "name must be one of " + strings.Join(names, ", "),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	since, bucket, ok := historyRange(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "range must be between 1m and 90d and bucket at least 1m",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	points, err := s.svc.MetricsHistory(r.Context(), since, bucket)
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	ans := MetricHistory{
+		Name:    name,
+		Bucket:  bucket.String(),
+		Samples: make([]MetricSample, 0, len(points)),
+	}
+
+	for i := range points {
+		ans.Samples = append(ans.Samples, MetricSample{Time: points[i].Time, Value: value(points[i])})
+	}
+
+	renderJSON(w, http.StatusOK, ans)
+}
+
+func (s *Server) apiGetMetrics(w http.ResponseWriter, r *http.Request) {
+	since, bucket, ok := metricsRange(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "days must be between 1 and 90 and bucket at least 1m",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	points, err := s.svc.MetricsHistory(r.Context(), since, bucket)
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	if points == nil {
+		points = []MetricsPoint{}
+	}
+
+	renderJSON(w, http.StatusOK, points)
+}
+
+// chartSeries is a line chart rendered as an SVG polyline.
+type chartSeries struct {
+	Title  string
+	Max    string
+	Points string
+	From   string
+	To     string
+}
+
+const (
+	chartWidth  = 600
+	chartHeight = 150
+)
+
+func newChartSeries(title string, points []MetricsPoint, value func(MetricsPoint) float64, format func(float64) string) chartSeries {
+	ans := chartSeries{Title: title, Max: format(0)}
+
+	if len(points) == 0 {
+		return ans
+	}
+
+	maxValue := 0.0
+	for i := range points {
+		maxValue = max(maxValue, value(points[i]))
+	}
+
+	from, to := points[0].Time, points[len(points)-1].Time
+	span := to.Sub(from).Seconds()
+
+	buf := make([]byte, 0, len(points)*16)
+
+	for i := range points {
+		x := 0.0
+		if span > 0 {
+			x = points[i].Time.Sub(from).Seconds() / span * chartWidth
+		}
+
+		y := float64(chartHeight)
+		if maxValue > 0 {
+			y -= value(points[i]) / maxValue * chartHeight
+		}
+
+		buf = strconv.AppendFloat(buf, x, 'f', 1, 64)
+		buf = append(buf, ',')
+		buf = strconv.AppendFloat(buf, y, 'f', 1, 64)
+		buf = append(buf, ' ')
+	}
+
+	ans.Max = format(maxValue)
+	ans.Points = string(buf)
+	ans.From = from.Format("2006-01-02 15:04")
+	ans.To = to.Format("2006-01-02 15:04")
+
+	return ans
+}
+
+// metrics renders the trend charts of the persisted metrics for the
+// browsers, and serves the Prometheus metrics to the other clients.
+func (s *Server) metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		metrics.Handler().ServeHTTP(w, r)
+
+		return
+	}
+
+	since, bucket, ok := metricsRange(r)
+	if !ok {
+		http.Error(w, "days must be between 1 and 90 and bucket at least 1m", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	points, err := s.svc.MetricsHistory(r.Context(), since, bucket)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	tmpl, ok := s.tmpl["static/templates/metrics.html"]
+	if !ok {
+		http.Error(w, "missing tpl", http.StatusInternalServerError)
+
+		return
+	}
+
+	rate := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	percent := func(v float64) string { return strconv.FormatFloat(v*100, 'f', 1, 64) + "%" } //nolint:gomnd // percentage
+	count := func(v float64) string { return strconv.FormatFloat(v, 'f', 0, 64) }
+
+	data := struct {
+		Width  int
+		Height int
+		Charts []chartSeries
+	}{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Charts: []chartSeries{
+			newChartSeries("Jobs per minute", points, func(p MetricsPoint) float64 { return p.JobsPerMinute }, rate),
+			newChartSeries("Error rate", points, func(p MetricsPoint) float64 { return p.ErrorRate }, percent),
+			newChartSeries("Block rate", points, func(p MetricsPoint) float64 { return p.BlockRate }, percent),
+			newChartSeries("Places per hour", points, func(p MetricsPoint) float64 { return p.PlacesPerHour }, count),
+			newChartSeries("Captchas", points, func(p MetricsPoint) float64 { return float64(p.Captchas) }, count),
+			newChartSeries("Captcha cost", points, func(p MetricsPoint) float64 { return p.CaptchaCost }, rate),
+		},
+	}
+
+	_ = tmpl.Execute(w, data)
+}
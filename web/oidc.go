@@ -0,0 +1,349 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oidcStateCookie = "gmaps_oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+// OIDCConfig signs the users in with an OpenID Connect provider, e.g.
+// Google, Okta or Keycloak, with the authorization code flow.
+type OIDCConfig struct {
+	// Issuer is the url of the provider, its configuration is discovered
+	// at /.well-known/openid-configuration
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the url of /auth/oidc/callback of the server, as
+	// registered with the provider
+	RedirectURL string
+	// Admins are the verified emails of the users made admins when they
+	// sign in, the others are users
+	Admins []string
+}
+
+// WithOIDC adds a sign in with the OpenID Connect provider of cfg to the
+// login page. The users are created the first time they sign in, and are
+// never the users with a password.
+func WithOIDC(cfg OIDCConfig) ServerOption {
+	return func(s *Server) {
+		s.oidc = &oidcProvider{
+			cfg:  cfg,
+			http: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+}
+
+type oidcEndpoints struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type oidcClaims struct {
+	Sub   string `json:"sub"`
+	Email string `json:"email"`
+	// EmailVerified is a boolean, or a string with some providers
+	EmailVerified     any    `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// verifiedEmail returns the email of the user, empty when the provider did
+// not verify it.
+func (c *oidcClaims) verifiedEmail() string {
+	if c.EmailVerified != true && c.EmailVerified != "true" {
+		return ""
+	}
+
+	return strings.ToLower(c.Email)
+}
+
+// username is the verified email of the user, the name it prefers or its
+// subject. It only names the user, who is known by its issuer and subject.
+func (c *oidcClaims) username() string {
+	if email := c.verifiedEmail(); email != "" {
+		return email
+	}
+
+	if c.PreferredUsername != "" {
+		return c.PreferredUsername
+	}
+
+	return c.Sub
+}
+
+// external is the issuer and the subject of the user, unique to it.
+func (c *oidcClaims) external(issuer string) string {
+	return strings.TrimSuffix(issuer, "/") + "#" + c.Sub
+}
+
+type oidcProvider struct {
+	cfg  OIDCConfig
+	http *http.Client
+
+	mu        sync.Mutex
+	endpoints *oidcEndpoints
+}
+
+// discover returns the endpoints of the provider, fetched once.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcEndpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	u := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+
+	var ans oidcEndpoints
+
+	if err := p.getJSON(ctx, u, "", &ans); err != nil {
+		return nil, fmt.Errorf("failed to discover the OIDC provider: %w", err)
+	}
+
+	if ans.AuthorizationEndpoint == "" || ans.TokenEndpoint == "" || ans.UserinfoEndpoint == "" {
+		return nil, errors.New("the OIDC provider has no authorization, token or userinfo endpoint")
+	}
+
+	p.endpoints = &ans
+
+	return p.endpoints, nil
+}
+
+// authURL returns the url the user signs in at. The verifier is the PKCE
+// code verifier of the sign in.
+func (p *oidcProvider) authURL(ctx context.Context, state, verifier string) (string, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(endpoints.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return endpoints.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// claims exchanges the code of a sign in for an access token and returns
+// the claims of the userinfo endpoint. The token comes straight from the
+// provider over TLS, so it is trusted without checking the ID token.
+func (p *oidcProvider) claims(ctx context.Context, code, verifier string) (oidcClaims, error) {
+	endpoints, err := p.discover(ctx)
+	if err != nil {
+		return oidcClaims{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oidcClaims{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return oidcClaims{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return oidcClaims{}, fmt.Errorf("the OIDC token endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return oidcClaims{}, err
+	}
+
+	var ans oidcClaims
+
+	if err := p.getJSON(ctx, endpoints.UserinfoEndpoint, token.AccessToken, &ans); err != nil {
+		return oidcClaims{}, fmt.Errorf("failed to get the OIDC userinfo: %w", err)
+	}
+
+	if ans.Sub == "" {
+		return oidcClaims{}, errors.New("the OIDC userinfo has no subject")
+	}
+
+	return ans, nil
+}
+
+func (p *oidcProvider) isAdmin(c *oidcClaims) bool {
+	verified := c.verifiedEmail()
+
+	return verified != "" && slices.ContainsFunc(p.cfg.Admins, func(email string) bool {
+		return strings.EqualFold(email, verified)
+	})
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, u, bearer string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("responded with status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// oidcLogin sends the user to the provider. The state and the PKCE verifier
+// are kept in a cookie until the callback.
+func (s *Server) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	verifier, err := randomToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	authURL, err := s.oidc.authURL(r.Context(), state, verifier)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		http.Error(w, "the identity provider is not available", http.StatusBadGateway)
+
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state + "." + verifier,
+		Path:     "/auth/oidc/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oidcCallback signs in the user the provider sent back.
+func (s *Server) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		http.Error(w, "the sign in expired, try again", http.StatusBadRequest)
+
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc/", MaxAge: -1})
+
+	state, verifier, _ := strings.Cut(cookie.Value, ".")
+
+	q := r.URL.Query()
+
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(state)) != 1 {
+		http.Error(w, "invalid state", http.StatusBadRequest)
+
+		return
+	}
+
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "the identity provider refused the sign in: "+e, http.StatusUnauthorized)
+
+		return
+	}
+
+	claims, err := s.oidc.claims(r.Context(), q.Get("code"), verifier)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		http.Error(w, "the sign in failed", http.StatusUnauthorized)
+
+		return
+	}
+
+	user, err := s.svc.externalUser(r.Context(), claims.external(s.oidc.cfg.Issuer), claims.username(), s.oidc.isAdmin(&claims))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if err := s.startSession(w, r, &user); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec returns the OpenAPI specification of the API, written in
+// static/spec/spec.yaml, as json.
+func openAPISpec() ([]byte, error) {
+	data, err := static.ReadFile("static/spec/spec.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var spec map[string]any
+
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(spec)
+}
+
+// openAPI serves the OpenAPI specification the clients are generated from.
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ans := apiError{
+			Code:    http.StatusMethodNotAllowed,
+			Message: "Method not allowed",
+		}
+
+		renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write(s.openAPISpec)
+}
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// The transforms of the results of the job a job depends on.
+const (
+	// TransformEmails crawls the websites of the places for emails, the places are not scraped again
+	TransformEmails = "emails"
+	// TransformPlaces scrapes the places again, e.g. in another language or with their emails
+	TransformPlaces = "places"
+)
+
+// ErrInvalidDependency is returned when the job a new job depends on does not
+// exist or failed, or when its input was not uploaded.
+var ErrInvalidDependency = errors.New("invalid dependency")
+
+func (d *JobData) validateDependency() error {
+	if d.Transform == "" {
+		if d.DependsOn != "" {
+			return errors.New("missing transform")
+		}
+
+		return nil
+	}
+
+	switch d.Transform {
+	case TransformEmails, TransformPlaces:
+	default:
+		return errors.New("invalid transform")
+	}
+
+	if d.DependsOn != "" {
+		if _, err := uuid.Parse(d.DependsOn); err != nil {
+			return errors.New("invalid depends_on")
+		}
+	}
+
+	if d.FastMode || d.BBox != "" || d.TileRadius > 0 || d.Place != "" {
+		return errors.New("transform cannot be used with fast_mode, bbox, tile_radius or place")
+	}
+
+	return nil
+}
+
+// prepareTransform checks the input of a new transform job. A job that
+// depends on another one is pending when the other job already completed
+// and waiting otherwise. The other jobs need their uploaded input.
+func (s *Service) prepareTransform(ctx context.Context, job *Job) error {
+	if job.Data.DependsOn == "" {
+		if _, err := os.Stat(s.InputPath(job.ID)); err != nil {
+			return fmt.Errorf("%w: missing depends_on or uploaded input", ErrInvalidDependency)
+		}
+	} else {
+		parent, err := s.Get(ctx, job.Data.DependsOn)
+		if err != nil {
+			return fmt.Errorf("%w: job %s not found", ErrInvalidDependency, job.Data.DependsOn)
+		}
+
+		// the places of the parent are read from its csv file
+		if !parent.Data.CSV.IsDefault() {
+			return fmt.Errorf("%w: the csv of job %s has a custom layout", ErrInvalidDependency, parent.ID)
+		}
+
+		switch parent.Status {
+		case StatusOK:
+			job.Status = StatusPending
+		case StatusFailed:
+			return fmt.Errorf("%w: job %s failed", ErrInvalidDependency, parent.ID)
+		default:
+			job.Status = StatusWaiting
+		}
+	}
+
+	// the websites of the places are crawled, see TransformEmails
+	if job.Data.Transform == TransformEmails {
+		job.Data.Email = true
+	}
+
+	return nil
+}
+
+// InputPath returns the path of the uploaded input of a transform job.
+func (s *Service) InputPath(id string) string {
+	return filepath.Join(s.dataFolder, id+".input")
+}
+
+// SaveInput stores the uploaded input of the transform job id.
+func (s *Service) SaveInput(id string, r io.Reader) error {
+	f, err := os.Create(s.InputPath(id))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReleaseDependents queues the jobs waiting for job when it completed, and
+// fails them when it failed. Other statuses leave them waiting.
+func (s *Service) ReleaseDependents(ctx context.Context, job *Job) error {
+	var status string
+
+	switch job.Status {
+	case StatusOK:
+		status = StatusPending
+	case StatusFailed:
+		status = StatusFailed
+	default:
+		return nil
+	}
+
+	waiting, err := s.repo.Select(ctx, SelectParams{Status: StatusWaiting})
+	if err != nil {
+		return err
+	}
+
+	for i := range waiting {
+		if waiting[i].Data.DependsOn != job.ID {
+			continue
+		}
+
+		waiting[i].Status = status
+
+		if err := s.Update(ctx, &waiting[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
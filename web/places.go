@@ -0,0 +1,217 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const maxPlaceDays = 3650
+
+// Place is a place of the dataset kept across the jobs. Its Result is the
+// one of the last job that scraped it.
+type Place struct {
+	// Key is the data_id of the place, or its cid or link when it has none
+	Key string
+	Result
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// TimesSeen is the number of times the jobs scraped the place
+	TimesSeen int
+}
+
+// PlaceParams filters the places of the dataset. JobID is ignored.
+type PlaceParams struct {
+	ResultParams
+	// SeenSince keeps the places scraped since then, all of them when zero
+	SeenSince time.Time
+}
+
+// PlaceRepository stores the dataset of the places scraped by all the jobs,
+// one row per place.
+type PlaceRepository interface {
+	// UpsertPlaces adds the places, or updates the ones seen before and keeps
+	// when they were first seen.
+	UpsertPlaces(context.Context, []Place) error
+	// SelectPlaces returns the places matching params, the last seen first,
+	// and how many match without the offset and the limit.
+	SelectPlaces(context.Context, PlaceParams) ([]Place, int, error)
+}
+
+// ErrPlacesNotSupported is returned when the places are not kept across the
+// jobs.
+var ErrPlacesNotSupported = errors.New("the places dataset is not enabled")
+
+// WithPlaces keeps the places scraped by all the jobs in repo, deduplicated
+// by their data_id.
+func WithPlaces(repo PlaceRepository) ServiceOption {
+	return func(s *Service) {
+		s.placeRepo = repo
+	}
+}
+
+// placeKey returns the key of e in the dataset, empty when it cannot be told
+// apart from the other places.
+func placeKey(e *gmaps.Entry) string {
+	switch {
+	case e.DataID != "":
+		return e.DataID
+	case e.Cid != "":
+		return "cid:" + e.Cid
+	case e.Link == "":
+		return ""
+	}
+
+	return gmaps.DedupKey(e.Link)
+}
+
+// storePlaces adds the results to the dataset, as seen now.
+func (s *Service) storePlaces(ctx context.Context, entries []*gmaps.Entry, results []Result) error {
+	now := time.Now().UTC()
+
+	places := make([]Place, 0, len(results))
+
+	for i := range results {
+		key := placeKey(entries[i])
+		if key == "" {
+			continue
+		}
+
+		places = append(places, Place{
+			Key:       key,
+			Result:    results[i],
+			FirstSeen: now,
+			LastSeen:  now,
+			TimesSeen: 1,
+		})
+	}
+
+	if len(places) == 0 {
+		return nil
+	}
+
+	return s.placeRepo.UpsertPlaces(ctx, places)
+}
+
+// Places returns the places of the dataset that match params, with the given
+// fields only, or all of them when fields is empty. Every place also has its
+// first_seen, last_seen, times_seen and last_job_id.
+func (s *Service) Places(ctx context.Context, params PlaceParams, fields []string) (ResultPage, error) {
+	if s.placeRepo == nil {
+		return ResultPage{}, ErrPlacesNotSupported
+	}
+
+	places, total, err := s.placeRepo.SelectPlaces(ctx, params)
+	if err != nil {
+		return ResultPage{}, err
+	}
+
+	ans := ResultPage{
+		Total:   total,
+		Offset:  params.Offset,
+		Limit:   params.Limit,
+		Results: make([]map[string]any, 0, len(places)),
+	}
+
+	for i := range places {
+		extra := map[string]any{
+			"first_seen":  places[i].FirstSeen,
+			"last_seen":   places[i].LastSeen,
+			"times_seen":  places[i].TimesSeen,
+			"last_job_id": places[i].JobID,
+		}
+
+		m, err := places[i].fields(extra, fields)
+		if err != nil {
+			return ResultPage{}, err
+		}
+
+		ans.Results = append(ans.Results, m)
+	}
+
+	return ans, nil
+}
+
+// apiGetPlaces serves the places of the dataset, to the admins only with
+// -auth as they come from the jobs of all the users.
+func (s *Server) apiGetPlaces(w http.ResponseWriter, r *http.Request) {
+	if _, ok := UserFromContext(r.Context()); ok && !requireAdmin(w, r) {
+		return
+	}
+
+	filter, fields, err := resultParams(r, "")
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	params := PlaceParams{ResultParams: filter}
+
+	if v := r.URL.Query().Get("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 || days > maxPlaceDays {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "days must be between 1 and " + strconv.Itoa(maxPlaceDays),
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+
+		params.SeenSince = time.Now().UTC().AddDate(0, 0, -days)
+	}
+
+	export := r.URL.Query().Get("export")
+
+	switch export {
+	case "":
+	case "csv", "json":
+		params.Offset, params.Limit = 0, 0
+	default:
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "export must be csv or json",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	page, err := s.svc.Places(r.Context(), params, fields)
+
+	switch {
+	case errors.Is(err, ErrPlacesNotSupported):
+		apiError := apiError{
+			Code:    http.StatusNotImplemented,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusNotImplemented, apiError)
+
+		return
+	case err != nil:
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	renderResults(w, "places", export, page, fields)
+}
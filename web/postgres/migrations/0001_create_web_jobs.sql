@@ -0,0 +1,11 @@
+CREATE TABLE web_jobs (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL,
+    data JSONB NOT NULL,
+    stats JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX web_jobs_status_created_at ON web_jobs (status, created_at);
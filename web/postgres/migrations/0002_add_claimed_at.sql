@@ -0,0 +1 @@
+ALTER TABLE web_jobs ADD COLUMN claimed_at TIMESTAMP WITH TIME ZONE;
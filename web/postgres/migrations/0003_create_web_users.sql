@@ -0,0 +1,38 @@
+CREATE TABLE web_users (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    external TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX web_users_external ON web_users (external) WHERE external != '';
+
+CREATE TABLE web_sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX web_sessions_user_id ON web_sessions (user_id);
+
+CREATE TABLE web_api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    rate_limit INT NOT NULL,
+    monthly_places INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX web_api_keys_user_id ON web_api_keys (user_id);
+
+CREATE TABLE web_api_key_usage (
+    key_id TEXT NOT NULL,
+    month TEXT NOT NULL,
+    requests INT NOT NULL,
+    PRIMARY KEY (key_id, month)
+);
@@ -0,0 +1,315 @@
+// Package postgres stores the jobs of the web runner in Postgres, so several
+// servers behind a load balancer share them.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // postgres driver
+
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationsLock is the key of the advisory lock held while migrating, so
+// the servers that start together migrate one after the other.
+const migrationsLock = 7_436_911_205
+
+type repo struct {
+	db *sql.DB
+}
+
+var (
+	_ web.JobRepository  = (*repo)(nil)
+	_ web.JobClaimer     = (*repo)(nil)
+	_ web.BulkJobCreator = (*repo)(nil)
+)
+
+// New connects to the database of dsn and applies the migrations it misses.
+func New(ctx context.Context, dsn string) (web.JobRepository, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	if err := migrate(ctx, db); err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("failed to migrate the job store: %w", err)
+	}
+
+	return &repo{db: db}, nil
+}
+
+// migrate applies the migrations that are not in schema_migrations yet, in
+// the order of their number, each in its transaction.
+func migrate(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(names)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationsLock); err != nil {
+		return err
+	}
+
+	const createTable = `CREATE TABLE IF NOT EXISTS web_schema_migrations (
+		version INT PRIMARY KEY,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+	)`
+
+	if _, err := tx.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		number, _, _ := strings.Cut(strings.TrimPrefix(name, "migrations/"), "_")
+
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			return fmt.Errorf("invalid migration %s: %w", name, err)
+		}
+
+		var applied bool
+
+		const q = `SELECT EXISTS (SELECT 1 FROM web_schema_migrations WHERE version = $1)`
+
+		if err := tx.QueryRowContext(ctx, q, version).Scan(&applied); err != nil {
+			return err
+		}
+
+		if applied {
+			continue
+		}
+
+		script, err := migrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+
+		const record = `INSERT INTO web_schema_migrations (version, applied_at) VALUES ($1, $2)`
+
+		if _, err := tx.ExecContext(ctx, record, version, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+const jobColumns = `id, name, status, data, stats, created_at`
+
+func (repo *repo) Get(ctx context.Context, id string) (web.Job, error) {
+	const q = `SELECT ` + jobColumns + ` FROM web_jobs WHERE id = $1`
+
+	return rowToJob(repo.db.QueryRowContext(ctx, q, id))
+}
+
+func (repo *repo) Create(ctx context.Context, job *web.Job) error {
+	data, stats, err := marshalJob(job)
+	if err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO web_jobs (id, name, status, data, stats, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = repo.db.ExecContext(ctx, q, job.ID, job.Name, job.Status, data, stats, job.Date.UTC(), time.Now().UTC())
+
+	return err
+}
+
+func (repo *repo) CreateJobs(ctx context.Context, jobs []web.Job) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const q = `INSERT INTO web_jobs (id, name, status, data, stats, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	now := time.Now().UTC()
+
+	for i := range jobs {
+		data, stats, err := marshalJob(&jobs[i])
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, q, jobs[i].ID, jobs[i].Name, jobs[i].Status, data, stats, jobs[i].Date.UTC(), now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *repo) Delete(ctx context.Context, id string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM web_jobs WHERE id = $1`, id)
+
+	return err
+}
+
+func (repo *repo) Select(ctx context.Context, params web.SelectParams) ([]web.Job, error) {
+	q := `SELECT ` + jobColumns + ` FROM web_jobs`
+
+	var args []any
+
+	if params.Status != "" {
+		args = append(args, params.Status)
+		q += ` WHERE status = $1`
+	}
+
+	q += ` ORDER BY created_at DESC`
+
+	if params.Limit > 0 {
+		args = append(args, params.Limit)
+		q += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	rows, err := repo.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.Job
+
+	for rows.Next() {
+		job, err := rowToJob(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, job)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) Update(ctx context.Context, job *web.Job) error {
+	data, stats, err := marshalJob(job)
+	if err != nil {
+		return err
+	}
+
+	// the claim of a job ends when it stops working
+	const q = `UPDATE web_jobs SET name = $1, status = $2, data = $3, stats = $4, updated_at = $5,
+		claimed_at = CASE WHEN status = $7 AND $2 = $7 THEN claimed_at END
+		WHERE id = $6`
+
+	_, err = repo.db.ExecContext(ctx, q, job.Name, job.Status, data, stats, time.Now().UTC(), job.ID, web.StatusWorking)
+
+	return err
+}
+
+// ClaimPending marks the newest pending job as working, like the servers
+// on SQLite pick it, and skips the jobs other servers are claiming. The
+// working jobs whose claim was not renewed for web.ClaimLease, or that were
+// never claimed, are claimed again.
+func (repo *repo) ClaimPending(ctx context.Context) (web.Job, error) {
+	const q = `UPDATE web_jobs SET status = $1, updated_at = $2, claimed_at = $2
+		WHERE id = (
+			SELECT id FROM web_jobs
+			WHERE status = $3 OR (status = $1 AND COALESCE(claimed_at, updated_at) < $4)
+			ORDER BY COALESCE((data->>'priority')::int, 0) DESC, created_at DESC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	now := time.Now().UTC()
+
+	return rowToJob(repo.db.QueryRowContext(ctx, q, web.StatusWorking, now, web.StatusPending, now.Add(-web.ClaimLease)))
+}
+
+// RenewClaims renews the claims of the working jobs ids.
+func (repo *repo) RenewClaims(ctx context.Context, ids []string) error {
+	const q = `UPDATE web_jobs SET claimed_at = $1 WHERE status = $2 AND id = ANY($3)`
+
+	_, err := repo.db.ExecContext(ctx, q, time.Now().UTC(), web.StatusWorking, ids)
+
+	return err
+}
+
+type scannable interface {
+	Scan(dest ...any) error
+}
+
+func rowToJob(row scannable) (web.Job, error) {
+	var (
+		ans         web.Job
+		data, stats []byte
+	)
+
+	err := row.Scan(&ans.ID, &ans.Name, &ans.Status, &data, &stats, &ans.Date)
+	if errors.Is(err, sql.ErrNoRows) {
+		return web.Job{}, web.ErrNotFound
+	}
+
+	if err != nil {
+		return web.Job{}, err
+	}
+
+	ans.Date = ans.Date.UTC()
+
+	if err := json.Unmarshal(data, &ans.Data); err != nil {
+		return web.Job{}, err
+	}
+
+	if err := json.Unmarshal(stats, &ans.Stats); err != nil {
+		return web.Job{}, err
+	}
+
+	return ans, nil
+}
+
+func marshalJob(job *web.Job) (data, stats []byte, err error) {
+	data, err = json.Marshal(job.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats, err = json.Marshal(job.Stats)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, stats, nil
+}
@@ -0,0 +1,169 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+// newTestRepo returns the job store of the database of WEB_TEST_DSN, emptied
+// and migrated. Run with: WEB_TEST_DSN=postgres://... go test -tags integration ./web/postgres/
+func newTestRepo(t *testing.T) *repo {
+	t.Helper()
+
+	dsn := os.Getenv("WEB_TEST_DSN")
+	if dsn == "" {
+		t.Skip("WEB_TEST_DSN is not set")
+	}
+
+	ctx := context.Background()
+
+	r, err := New(ctx, dsn)
+	require.NoError(t, err)
+
+	db := r.(*repo).db
+
+	_, err = db.ExecContext(ctx, `DROP TABLE IF EXISTS web_jobs, web_users, web_sessions, web_api_keys, web_api_key_usage, web_schema_migrations`)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Close())
+
+	r, err = New(ctx, dsn)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = r.(*repo).db.Close() })
+
+	return r.(*repo)
+}
+
+func newJob(id string, priority int, date time.Time) web.Job {
+	return web.Job{
+		ID:     id,
+		Name:   id,
+		Status: web.StatusPending,
+		Date:   date,
+		Data:   web.JobData{Keywords: []string{"cafe"}, Priority: priority},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	r := newTestRepo(t)
+
+	// the migrations already applied are skipped
+	require.NoError(t, migrate(context.Background(), r.db))
+
+	var versions int
+
+	require.NoError(t, r.db.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM web_schema_migrations`).Scan(&versions))
+	require.Equal(t, 3, versions)
+}
+
+func TestRepository(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	job := newJob("1", 0, now)
+	require.NoError(t, r.Create(ctx, &job))
+
+	got, err := r.Get(ctx, "1")
+	require.NoError(t, err)
+	require.Equal(t, job.Data.Keywords, got.Data.Keywords)
+	require.True(t, now.Equal(got.Date))
+
+	got.Status = web.StatusOK
+	got.Stats.Places = 5
+
+	require.NoError(t, r.Update(ctx, &got))
+
+	jobs, err := r.Select(ctx, web.SelectParams{Status: web.StatusOK})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, 5, jobs[0].Stats.Places)
+
+	require.NoError(t, r.Delete(ctx, "1"))
+
+	_, err = r.Get(ctx, "1")
+	require.ErrorIs(t, err, web.ErrNotFound)
+}
+
+func TestCreateJobsAtomic(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+
+	existing := newJob("2", 0, now)
+	require.NoError(t, r.Create(ctx, &existing))
+
+	// the second job has the id of an existing one, so none is created
+	err := r.CreateJobs(ctx, []web.Job{newJob("1", 0, now), newJob("2", 0, now)})
+	require.Error(t, err)
+
+	_, err = r.Get(ctx, "1")
+	require.ErrorIs(t, err, web.ErrNotFound)
+
+	require.NoError(t, r.CreateJobs(ctx, []web.Job{newJob("1", 0, now), newJob("3", 0, now)}))
+
+	jobs, err := r.Select(ctx, web.SelectParams{})
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+}
+
+func TestClaimPending(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+
+	for _, job := range []web.Job{
+		newJob("old", 0, now.Add(-time.Hour)),
+		newJob("new", 0, now),
+		newJob("urgent", 5, now.Add(-2*time.Hour)),
+	} {
+		require.NoError(t, r.Create(ctx, &job))
+	}
+
+	// the highest priority first, then the newest
+	for _, want := range []string{"urgent", "new", "old"} {
+		got, err := r.ClaimPending(ctx)
+		require.NoError(t, err)
+		require.Equal(t, want, got.ID)
+		require.Equal(t, web.StatusWorking, got.Status)
+	}
+
+	_, err := r.ClaimPending(ctx)
+	require.ErrorIs(t, err, web.ErrNotFound)
+
+	// the claim of "old" is stale, the one of "new" was renewed
+	stale := now.Add(-web.ClaimLease - time.Minute)
+
+	_, err = r.db.ExecContext(ctx, `UPDATE web_jobs SET claimed_at = $1 WHERE id IN ('old', 'new')`, stale)
+	require.NoError(t, err)
+
+	require.NoError(t, r.RenewClaims(ctx, []string{"new"}))
+
+	got, err := r.ClaimPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "old", got.ID)
+
+	_, err = r.ClaimPending(ctx)
+	require.ErrorIs(t, err, web.ErrNotFound)
+
+	// a job that stops working is not claimed again
+	got.Status = web.StatusOK
+	require.NoError(t, r.Update(ctx, &got))
+
+	var claimed *time.Time
+
+	require.NoError(t, r.db.QueryRowContext(ctx, `SELECT claimed_at FROM web_jobs WHERE id = 'old'`).Scan(&claimed))
+	require.Nil(t, claimed)
+}
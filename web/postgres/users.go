@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+var (
+	_ web.UserRepository   = (*repo)(nil)
+	_ web.APIKeyRepository = (*repo)(nil)
+)
+
+// uniqueViolation is the code of the errors of the unique constraints.
+const uniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+const userColumns = `id, username, role, password_hash, external, created_at`
+
+func (repo *repo) CreateUser(ctx context.Context, u *web.User) error {
+	const q = `INSERT INTO web_users (` + userColumns + `) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := repo.db.ExecContext(ctx, q, u.ID, u.Username, u.Role, u.PasswordHash, u.External, u.CreatedAt.UTC())
+	if isUniqueViolation(err) {
+		return fmt.Errorf("user %s: %w", u.Username, web.ErrAlreadyExists)
+	}
+
+	return err
+}
+
+func (repo *repo) GetUser(ctx context.Context, id string) (web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM web_users WHERE id = $1`
+
+	return rowToUser(repo.db.QueryRowContext(ctx, q, id))
+}
+
+func (repo *repo) GetUserByUsername(ctx context.Context, username string) (web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM web_users WHERE username = $1`
+
+	return rowToUser(repo.db.QueryRowContext(ctx, q, username))
+}
+
+func (repo *repo) GetUserByExternal(ctx context.Context, external string) (web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM web_users WHERE external = $1 AND external != ''`
+
+	return rowToUser(repo.db.QueryRowContext(ctx, q, external))
+}
+
+func (repo *repo) SelectUsers(ctx context.Context) ([]web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM web_users ORDER BY username`
+
+	rows, err := repo.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.User
+
+	for rows.Next() {
+		u, err := rowToUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, u)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) UpdateUser(ctx context.Context, u *web.User) error {
+	const q = `UPDATE web_users SET username = $1, role = $2, password_hash = $3 WHERE id = $4`
+
+	_, err := repo.db.ExecContext(ctx, q, u.Username, u.Role, u.PasswordHash, u.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("user %s: %w", u.Username, web.ErrAlreadyExists)
+	}
+
+	return err
+}
+
+func (repo *repo) DeleteUser(ctx context.Context, id string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM web_sessions WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM web_api_keys WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM web_users WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *repo) CreateSession(ctx context.Context, session *web.Session) error {
+	const q = `INSERT INTO web_sessions (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+
+	_, err := repo.db.ExecContext(ctx, q, session.TokenHash, session.UserID, session.ExpiresAt.UTC())
+
+	return err
+}
+
+func (repo *repo) GetSession(ctx context.Context, tokenHash string) (web.Session, error) {
+	const q = `SELECT token_hash, user_id, expires_at FROM web_sessions WHERE token_hash = $1`
+
+	var session web.Session
+
+	err := repo.db.QueryRowContext(ctx, q, tokenHash).Scan(&session.TokenHash, &session.UserID, &session.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return web.Session{}, web.ErrNotFound
+	}
+
+	if err != nil {
+		return web.Session{}, err
+	}
+
+	session.ExpiresAt = session.ExpiresAt.UTC()
+
+	return session, nil
+}
+
+func (repo *repo) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM web_sessions WHERE token_hash = $1`, tokenHash)
+
+	return err
+}
+
+func (repo *repo) DeleteExpiredSessions(ctx context.Context, now time.Time) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM web_sessions WHERE expires_at < $1`, now.UTC())
+
+	return err
+}
+
+func rowToUser(row scannable) (web.User, error) {
+	var u web.User
+
+	err := row.Scan(&u.ID, &u.Username, &u.Role, &u.PasswordHash, &u.External, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return web.User{}, web.ErrNotFound
+	}
+
+	if err != nil {
+		return web.User{}, err
+	}
+
+	u.CreatedAt = u.CreatedAt.UTC()
+
+	return u, nil
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, rate_limit, monthly_places, created_at`
+
+func (repo *repo) CreateAPIKey(ctx context.Context, key *web.APIKey) error {
+	const q = `INSERT INTO web_api_keys (` + apiKeyColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := repo.db.ExecContext(ctx, q, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.RateLimit, key.MonthlyPlaces, key.CreatedAt.UTC())
+
+	return err
+}
+
+func (repo *repo) GetAPIKey(ctx context.Context, id string) (web.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM web_api_keys WHERE id = $1`
+
+	return rowToAPIKey(repo.db.QueryRowContext(ctx, q, id))
+}
+
+func (repo *repo) GetAPIKeyByHash(ctx context.Context, keyHash string) (web.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM web_api_keys WHERE key_hash = $1`
+
+	return rowToAPIKey(repo.db.QueryRowContext(ctx, q, keyHash))
+}
+
+func (repo *repo) SelectAPIKeys(ctx context.Context, userID string) ([]web.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM web_api_keys WHERE $1 = '' OR user_id = $1 ORDER BY created_at`
+
+	rows, err := repo.db.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.APIKey
+
+	for rows.Next() {
+		key, err := rowToAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, key)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) DeleteAPIKey(ctx context.Context, id string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM web_api_keys WHERE id = $1`, id)
+
+	return err
+}
+
+func (repo *repo) IncrAPIKeyRequests(ctx context.Context, keyID, month string) error {
+	const q = `INSERT INTO web_api_key_usage (key_id, month, requests) VALUES ($1, $2, 1)
+		ON CONFLICT (key_id, month) DO UPDATE SET requests = web_api_key_usage.requests + 1`
+
+	_, err := repo.db.ExecContext(ctx, q, keyID, month)
+
+	return err
+}
+
+func (repo *repo) APIKeyRequests(ctx context.Context, keyID, month string) (int, error) {
+	const q = `SELECT requests FROM web_api_key_usage WHERE key_id = $1 AND month = $2`
+
+	var ans int
+
+	err := repo.db.QueryRowContext(ctx, q, keyID, month).Scan(&ans)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return ans, err
+}
+
+func rowToAPIKey(row scannable) (web.APIKey, error) {
+	var key web.APIKey
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.RateLimit, &key.MonthlyPlaces, &key.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return web.APIKey{}, web.ErrNotFound
+	}
+
+	if err != nil {
+		return web.APIKey{}, err
+	}
+
+	key.CreatedAt = key.CreatedAt.UTC()
+
+	return key, nil
+}
@@ -0,0 +1,106 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/web"
+)
+
+func TestUsers(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	alice := web.User{ID: "1", Username: "alice", Role: web.RoleUser, PasswordHash: "hash", CreatedAt: now}
+	require.NoError(t, r.CreateUser(ctx, &alice))
+
+	dup := web.User{ID: "2", Username: "alice", Role: web.RoleUser, PasswordHash: "hash", CreatedAt: now}
+	require.ErrorIs(t, r.CreateUser(ctx, &dup), web.ErrAlreadyExists)
+
+	// the users that did not sign in with an identity provider share an empty external id
+	bob := web.User{ID: "3", Username: "bob", Role: web.RoleAdmin, PasswordHash: "hash", CreatedAt: now}
+	require.NoError(t, r.CreateUser(ctx, &bob))
+
+	got, err := r.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	require.Equal(t, alice, got)
+
+	_, err = r.GetUserByExternal(ctx, "")
+	require.ErrorIs(t, err, web.ErrNotFound)
+
+	alice.Username = "alice2"
+	require.NoError(t, r.UpdateUser(ctx, &alice))
+
+	users, err := r.SelectUsers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []web.User{alice, bob}, users)
+
+	session := web.Session{TokenHash: "token", UserID: alice.ID, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, r.CreateSession(ctx, &session))
+
+	expired := web.Session{TokenHash: "expired", UserID: alice.ID, ExpiresAt: now.Add(-time.Hour)}
+	require.NoError(t, r.CreateSession(ctx, &expired))
+	require.NoError(t, r.DeleteExpiredSessions(ctx, now))
+
+	_, err = r.GetSession(ctx, "expired")
+	require.ErrorIs(t, err, web.ErrNotFound)
+
+	gotSession, err := r.GetSession(ctx, "token")
+	require.NoError(t, err)
+	require.Equal(t, session, gotSession)
+
+	// deleting a user deletes its sessions
+	require.NoError(t, r.DeleteUser(ctx, alice.ID))
+
+	_, err = r.GetUser(ctx, alice.ID)
+	require.ErrorIs(t, err, web.ErrNotFound)
+
+	_, err = r.GetSession(ctx, "token")
+	require.ErrorIs(t, err, web.ErrNotFound)
+}
+
+func TestAPIKeys(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	key := web.APIKey{ID: "k1", UserID: "u1", Name: "ci", Prefix: "gms_abcd", KeyHash: "h1", RateLimit: 60, MonthlyPlaces: 1000, CreatedAt: now}
+	require.NoError(t, r.CreateAPIKey(ctx, &key))
+
+	other := web.APIKey{ID: "k2", UserID: "u2", Name: "other", Prefix: "gms_efgh", KeyHash: "h2", CreatedAt: now.Add(time.Second)}
+	require.NoError(t, r.CreateAPIKey(ctx, &other))
+
+	got, err := r.GetAPIKeyByHash(ctx, "h1")
+	require.NoError(t, err)
+	require.Equal(t, key, got)
+
+	keys, err := r.SelectAPIKeys(ctx, "u1")
+	require.NoError(t, err)
+	require.Equal(t, []web.APIKey{key}, keys)
+
+	keys, err = r.SelectAPIKeys(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, []web.APIKey{key, other}, keys)
+
+	requests, err := r.APIKeyRequests(ctx, key.ID, "2026-10")
+	require.NoError(t, err)
+	require.Zero(t, requests)
+
+	require.NoError(t, r.IncrAPIKeyRequests(ctx, key.ID, "2026-10"))
+	require.NoError(t, r.IncrAPIKeyRequests(ctx, key.ID, "2026-10"))
+
+	requests, err = r.APIKeyRequests(ctx, key.ID, "2026-10")
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+
+	require.NoError(t, r.DeleteAPIKey(ctx, key.ID))
+
+	_, err = r.GetAPIKey(ctx, key.ID)
+	require.ErrorIs(t, err, web.ErrNotFound)
+}
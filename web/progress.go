@@ -0,0 +1,181 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/exiter"
+)
+
+// JobProgress is the progress of a job. The counters are the ones of the
+// current run of a working job, zero for the other jobs.
+type JobProgress struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+	exiter.Progress
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// Percent is the share of the seeds and of the places found that are
+	// done, from 0 to 100. Places are found while the seeds run, so it can
+	// go down.
+	Percent float64 `json:"percent"`
+	// ETA is when the job should complete at the pace so far, nil until
+	// something is done.
+	ETA *time.Time `json:"eta,omitempty"`
+}
+
+type runningJob struct {
+	exitMonitor exiter.Exiter
+	startedAt   time.Time
+}
+
+// TrackProgress makes the counters of exitMonitor the progress of the
+// running job jobID. The returned function must be called when the job
+// stops.
+func (s *Service) TrackProgress(jobID string, exitMonitor exiter.Exiter) func() {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+
+	if s.running == nil {
+		s.running = map[string]runningJob{}
+	}
+
+	s.running[jobID] = runningJob{exitMonitor: exitMonitor, startedAt: time.Now().UTC()}
+
+	return func() {
+		s.progressMu.Lock()
+		defer s.progressMu.Unlock()
+
+		delete(s.running, jobID)
+	}
+}
+
+// Progress returns the progress of the job id.
+func (s *Service) Progress(ctx context.Context, id string) (*JobProgress, error) {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if ans := s.liveProgress(id); ans != nil {
+		ans.Status = job.Status
+
+		return ans, nil
+	}
+
+	ans := JobProgress{
+		JobID:  job.ID,
+		Status: job.Status,
+	}
+
+	if job.Status == StatusOK {
+		ans.Percent = 100
+	}
+
+	return &ans, nil
+}
+
+// liveProgress returns the progress of the running job id, nil when it is
+// not running.
+func (s *Service) liveProgress(id string) *JobProgress {
+	s.progressMu.Lock()
+	running, ok := s.running[id]
+	s.progressMu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	ans := JobProgress{
+		JobID:     id,
+		Status:    StatusWorking,
+		Progress:  running.exitMonitor.Progress(),
+		StartedAt: &running.startedAt,
+	}
+
+	total := ans.SeedCount + ans.PlacesFound
+	done := ans.SeedCompleted + ans.PlacesCompleted
+
+	if total > 0 {
+		ans.Percent = 100 * float64(done) / float64(total)
+	}
+
+	if done > 0 && done < total {
+		elapsed := time.Since(running.startedAt)
+		eta := time.Now().UTC().Add(elapsed * time.Duration(total-done) / time.Duration(done))
+		ans.ETA = &eta
+	}
+
+	return &ans
+}
+
+func (s *Server) apiGetJobProgress(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	progress, err := s.svc.Progress(r.Context(), id.String())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, progress)
+}
+
+// progress renders the progress bar of a working job.
+func (s *Server) progress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Invalid ID", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	progress, err := s.svc.Progress(r.Context(), id.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	tmpl, ok := s.tmpl["static/templates/job_progress.html"]
+	if !ok {
+		http.Error(w, "missing tpl", http.StatusInternalServerError)
+
+		return
+	}
+
+	data := struct {
+		*JobProgress
+		Remaining string
+	}{
+		JobProgress: progress,
+	}
+
+	if progress.ETA != nil {
+		data.Remaining = time.Until(*progress.ETA).Round(time.Second).String()
+	}
+
+	_ = tmpl.Execute(w, data)
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"fmt"
+	"slices"
+)
+
+// WithProxyGroups sets the names of the proxy groups the jobs can use.
+func WithProxyGroups(names []string) ServiceOption {
+	return func(s *Service) {
+		s.proxyGroups = names
+	}
+}
+
+// ProxyGroups returns the names of the proxy groups the jobs can use.
+func (s *Service) ProxyGroups() []string {
+	return s.proxyGroups
+}
+
+// ValidateProxyGroup returns an error when the group is not one of the
+// server. An empty group is valid.
+func (s *Service) ValidateProxyGroup(name string) error {
+	if name == "" || slices.Contains(s.proxyGroups, name) {
+		return nil
+	}
+
+	return fmt.Errorf("unknown proxy_group %q", name)
+}
+
+// WithCaptchaSolving lets the jobs solve the captchas with the solver of the server.
+func WithCaptchaSolving() ServiceOption {
+	return func(s *Service) {
+		s.captchaSolving = true
+	}
+}
+
+// CaptchaSolving reports whether the server has a captcha solver.
+func (s *Service) CaptchaSolving() bool {
+	return s.captchaSolving
+}
+
+// WithTranslation lets the jobs translate the reviews with the translator of the server.
+func WithTranslation() ServiceOption {
+	return func(s *Service) {
+		s.translation = true
+	}
+}
+
+// Translation reports whether the server has a translator.
+func (s *Service) Translation() bool {
+	return s.translation
+}
+
+// ValidateServerSettings returns an error when the job uses a proxy group, a
+// captcha solver, a translator or a writer the server does not have.
+func (s *Service) ValidateServerSettings(d *JobData) error {
+	if err := s.ValidateProxyGroup(d.ProxyGroup); err != nil {
+		return err
+	}
+
+	if d.SolveCaptchas && !s.captchaSolving {
+		return fmt.Errorf("solve_captchas needs a captcha solver on the server (-captcha-solver)")
+	}
+
+	if d.TranslateTo != "" && !s.translation {
+		return fmt.Errorf("translate_to needs a translator on the server (-translate-reviews)")
+	}
+
+	if err := s.validateWriterTypes(d.Writers); err != nil {
+		return err
+	}
+
+	return nil
+}
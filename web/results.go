@@ -0,0 +1,408 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const (
+	defaultResultsLimit = 50
+	maxResultsLimit     = 500
+)
+
+// resultsCSVFields are the columns of the csv export when no fields are
+// selected.
+var resultsCSVFields = []string{
+	"title", "category", "address", "phone", "web_site", "emails",
+	"review_rating", "review_count", "link", "data_id",
+}
+
+// Result is a place scraped by a job, kept to preview and search the results
+// without downloading them.
+type Result struct {
+	JobID    string
+	Title    string
+	Category string
+	City     string
+	Rating   float64
+	HasEmail bool
+	// Text is what the search matches: the title, the categories, the
+	// address, the website, the phone and the emails
+	Text string
+	// Entry is the place as json
+	Entry json.RawMessage
+}
+
+// newResult returns the result of the entry e of the job jobID.
+func newResult(jobID string, e *gmaps.Entry) (Result, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return Result{}, err
+	}
+
+	text := []string{e.Title, e.Category, e.Address, e.WebSite, e.Phone}
+	text = append(text, e.Categories...)
+	text = append(text, e.Emails...)
+
+	return Result{
+		JobID:    jobID,
+		Title:    e.Title,
+		Category: e.Category,
+		City:     e.CompleteAddress.City,
+		Rating:   e.ReviewRating,
+		HasEmail: len(e.Emails) > 0,
+		Text:     strings.ToLower(strings.Join(text, " ")),
+		Entry:    data,
+	}, nil
+}
+
+// ResultParams filters the results of a job. The zero values match
+// everything.
+type ResultParams struct {
+	// JobID is the job of the results, an empty one matches all the jobs
+	JobID string
+	// Query is searched in the text of the results, case insensitive
+	Query string
+	// Category and City match case insensitive
+	Category  string
+	City      string
+	MinRating float64
+	HasEmail  *bool
+	Offset    int
+	// Limit of 0 returns all the results
+	Limit int
+}
+
+// ResultRepository stores the places scraped by the jobs.
+type ResultRepository interface {
+	InsertResults(context.Context, []Result) error
+	// SelectResults returns the results matching params, in the order they
+	// were scraped, and how many match without the offset and the limit.
+	SelectResults(context.Context, ResultParams) ([]Result, int, error)
+	DeleteResults(ctx context.Context, jobID string) error
+}
+
+// ErrResultsNotSupported is returned when the repository does not store
+// the results.
+var ErrResultsNotSupported = errors.New("results are not supported")
+
+// WithResults keeps the places scraped by the jobs in repo, to be previewed
+// and searched.
+func WithResults(repo ResultRepository) ServiceOption {
+	return func(s *Service) {
+		s.resultRepo = repo
+	}
+}
+
+// StoresResults reports whether the places scraped by the jobs are kept, in
+// the results of the jobs or in the places dataset.
+func (s *Service) StoresResults() bool {
+	return s.resultRepo != nil || s.placeRepo != nil
+}
+
+// StoreResults keeps the entries scraped by the job jobID.
+func (s *Service) StoreResults(ctx context.Context, jobID string, entries []*gmaps.Entry) error {
+	if !s.StoresResults() || len(entries) == 0 {
+		return nil
+	}
+
+	results := make([]Result, 0, len(entries))
+
+	for _, e := range entries {
+		result, err := newResult(jobID, e)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, result)
+	}
+
+	if s.resultRepo != nil {
+		if err := s.resultRepo.InsertResults(ctx, results); err != nil {
+			return err
+		}
+	}
+
+	if s.placeRepo != nil {
+		return s.storePlaces(ctx, entries, results)
+	}
+
+	return nil
+}
+
+// ResetResults forgets the results of the job jobID, when it starts over.
+func (s *Service) ResetResults(ctx context.Context, jobID string) error {
+	if s.resultRepo == nil {
+		return nil
+	}
+
+	return s.resultRepo.DeleteResults(ctx, jobID)
+}
+
+// ResultPage is a page of the results of a job.
+type ResultPage struct {
+	Total   int              `json:"total"`
+	Offset  int              `json:"offset"`
+	Limit   int              `json:"limit"`
+	Results []map[string]any `json:"results"`
+}
+
+// Results returns the results of the job of params.JobID that match params,
+// with the given fields only, or all of them when fields is empty.
+func (s *Service) Results(ctx context.Context, params ResultParams, fields []string) (ResultPage, error) {
+	if s.resultRepo == nil {
+		return ResultPage{}, ErrResultsNotSupported
+	}
+
+	if _, err := s.Get(ctx, params.JobID); err != nil {
+		return ResultPage{}, err
+	}
+
+	results, total, err := s.resultRepo.SelectResults(ctx, params)
+	if err != nil {
+		return ResultPage{}, err
+	}
+
+	ans := ResultPage{
+		Total:   total,
+		Offset:  params.Offset,
+		Limit:   params.Limit,
+		Results: make([]map[string]any, 0, len(results)),
+	}
+
+	for i := range results {
+		m, err := results[i].fields(nil, fields)
+		if err != nil {
+			return ResultPage{}, err
+		}
+
+		ans.Results = append(ans.Results, m)
+	}
+
+	return ans, nil
+}
+
+// fields returns the entry of r with extra, keeping the given fields only,
+// or all of them when fields is empty.
+func (r *Result) fields(extra map[string]any, fields []string) (map[string]any, error) {
+	var m map[string]any
+
+	if err := json.Unmarshal(r.Entry, &m); err != nil {
+		return nil, err
+	}
+
+	for k, v := range extra {
+		m[k] = v
+	}
+
+	if len(fields) == 0 {
+		return m, nil
+	}
+
+	selected := make(map[string]any, len(fields))
+
+	for _, f := range fields {
+		selected[f] = m[f]
+	}
+
+	return selected, nil
+}
+
+// resultParams parses the filters, the fields and the page of r.
+func resultParams(r *http.Request, id string) (ResultParams, []string, error) {
+	q := r.URL.Query()
+
+	params := ResultParams{
+		JobID:    id,
+		Query:    strings.TrimSpace(q.Get("q")),
+		Category: q.Get("category"),
+		City:     q.Get("city"),
+		Limit:    defaultResultsLimit,
+	}
+
+	if v := q.Get("min_rating"); v != "" {
+		rating, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return params, nil, errors.New("min_rating must be a number")
+		}
+
+		params.MinRating = rating
+	}
+
+	if v := q.Get("has_email"); v != "" {
+		hasEmail, err := strconv.ParseBool(v)
+		if err != nil {
+			return params, nil, errors.New("has_email must be true or false")
+		}
+
+		params.HasEmail = &hasEmail
+	}
+
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return params, nil, errors.New("offset must be a positive number")
+		}
+
+		params.Offset = offset
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxResultsLimit {
+			return params, nil, fmt.Errorf("limit must be between 1 and %d", maxResultsLimit)
+		}
+
+		params.Limit = limit
+	}
+
+	var fields []string
+
+	for _, f := range strings.Split(q.Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+
+	return params, fields, nil
+}
+
+// apiGetJobResults serves a page of the results of a job. With export=csv or
+// export=json all the results matching the filters are downloaded instead.
+func (s *Server) apiGetJobResults(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	params, fields, err := resultParams(r, id.String())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	export := r.URL.Query().Get("export")
+
+	switch export {
+	case "":
+	case "csv", "json":
+		params.Offset, params.Limit = 0, 0
+	default:
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "export must be csv or json",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	page, err := s.svc.Results(r.Context(), params, fields)
+
+	switch {
+	case errors.Is(err, ErrResultsNotSupported):
+		apiError := apiError{
+			Code:    http.StatusNotImplemented,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusNotImplemented, apiError)
+
+		return
+	case err != nil:
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	if export != "" {
+		s.svc.audit(r.Context(), AuditJobDownload, id.String(), "results as "+export)
+	}
+
+	renderResults(w, id.String()+"-results", export, page, fields)
+}
+
+// renderResults writes page as json, or as a csv or json attachment name
+// when export is set.
+func renderResults(w http.ResponseWriter, name, export string, page ResultPage, fields []string) {
+	switch export {
+	case "csv":
+		if len(fields) == 0 {
+			fields = resultsCSVFields
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", name))
+		w.Header().Set("Content-Type", "text/csv")
+
+		cw := csv.NewWriter(w)
+
+		_ = cw.Write(fields)
+
+		for _, m := range page.Results {
+			row := make([]string, len(fields))
+
+			for i, f := range fields {
+				row[i] = csvValue(m[f])
+			}
+
+			_ = cw.Write(row)
+		}
+
+		cw.Flush()
+	case "json":
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", name))
+
+		renderJSON(w, http.StatusOK, page.Results)
+	default:
+		renderJSON(w, http.StatusOK, page)
+	}
+}
+
+// csvValue formats a json value for a csv cell, the objects and the arrays
+// as json.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+
+	data, _ := json.Marshal(v)
+
+	return string(data)
+}
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultRetentionInterval = time.Hour
+
+// RetentionPolicy deletes the old completed and failed jobs, with their
+// files and rows, so a long running server does not fill its disk. The
+// pinned jobs are always kept.
+type RetentionPolicy struct {
+	// MaxAge deletes the jobs created longer ago, 0 keeps them
+	MaxAge time.Duration
+	// MaxDataBytes deletes the oldest jobs while their files in the data
+	// folder are larger, 0 for no cap. The database is not counted
+	MaxDataBytes int64
+	// Interval is how often the policy is applied
+	Interval time.Duration
+}
+
+// WithRetention applies policy in RunRetention.
+func WithRetention(policy RetentionPolicy) ServiceOption {
+	return func(s *Service) {
+		if policy.Interval <= 0 {
+			policy.Interval = defaultRetentionInterval
+		}
+
+		s.retention = policy
+	}
+}
+
+// RunRetention applies the retention policy when it starts and then every
+// interval until ctx is done. It returns immediately without a policy.
+func (s *Service) RunRetention(ctx context.Context) error {
+	if s.retention.MaxAge <= 0 && s.retention.MaxDataBytes <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(s.retention.Interval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := s.applyRetention(ctx, time.Now().UTC()); err != nil {
+			log.Printf("failed to apply the retention policy: %v", err)
+		} else if deleted > 0 {
+			log.Printf("retention policy deleted %d jobs", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pin exempts the job id from the retention policy.
+func (s *Service) Pin(ctx context.Context, id string) error {
+	return s.setPinned(ctx, id, true)
+}
+
+// Unpin lets the retention policy delete the job id again.
+func (s *Service) Unpin(ctx context.Context, id string) error {
+	return s.setPinned(ctx, id, false)
+}
+
+func (s *Service) setPinned(ctx context.Context, id string, pinned bool) error {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// the runner writes back the job it works on
+	if job.Status == StatusWorking {
+		return fmt.Errorf("%w: cannot pin or unpin a working job", ErrInvalidTransition)
+	}
+
+	job.Data.Pinned = pinned
+
+	return s.Update(ctx, &job)
+}
+
+// applyRetention deletes the jobs older than the max age, then the oldest
+// jobs while their files are over the cap, and returns how many it
+// deleted. Only the completed and failed jobs that are not pinned are
+// deleted.
+func (s *Service) applyRetention(ctx context.Context, now time.Time) (int, error) {
+	jobs, err := s.repo.Select(ctx, SelectParams{})
+	if err != nil {
+		return 0, err
+	}
+
+	var candidates []Job
+
+	for i := range jobs {
+		if jobs[i].Data.Pinned || (jobs[i].Status != StatusOK && jobs[i].Status != StatusFailed) {
+			continue
+		}
+
+		candidates = append(candidates, jobs[i])
+	}
+
+	// the oldest first
+	slices.SortFunc(candidates, func(a, b Job) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	deleted := 0
+
+	if s.retention.MaxAge > 0 {
+		cutoff := now.Add(-s.retention.MaxAge)
+
+		for len(candidates) > 0 && candidates[0].Date.Before(cutoff) {
+			if err := s.delete(ctx, candidates[0].ID, "retention: older than the max age"); err != nil {
+				return deleted, err
+			}
+
+			candidates = candidates[1:]
+			deleted++
+		}
+	}
+
+	if s.retention.MaxDataBytes <= 0 {
+		return deleted, nil
+	}
+
+	sizes, err := jobFileSizes(s.dataFolder)
+	if err != nil {
+		return deleted, err
+	}
+
+	var size int64
+
+	for i := range jobs {
+		size += sizes[jobs[i].ID]
+	}
+
+	for size > s.retention.MaxDataBytes && len(candidates) > 0 {
+		id := candidates[0].ID
+
+		if err := s.delete(ctx, id, "retention: data folder over its size cap"); err != nil {
+			return deleted, err
+		}
+
+		size -= sizes[id]
+		candidates = candidates[1:]
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// jobFileSizes returns the size of the files in dir by the name they start
+// with up to the first dot. The files of a job, and its folder, are named
+// after its id.
+func jobFileSizes(dir string) (map[string]int64, error) {
+	ans := make(map[string]int64)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		name, _, _ := strings.Cut(rel, string(filepath.Separator))
+		name, _, _ = strings.Cut(name, ".")
+
+		ans[name] += info.Size()
+
+		return nil
+	})
+
+	return ans, err
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeJobFile writes size bytes to the csv of the job id in dir.
+func writeJobFile(t *testing.T, dir, id string, size int) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, id+".csv"), make([]byte, size), 0o600))
+}
+
+func remainingJobs(t *testing.T, repo *memRepo) []string {
+	t.Helper()
+
+	var ans []string
+
+	for id := range repo.jobs {
+		ans = append(ans, id)
+	}
+
+	slices.Sort(ans)
+
+	return ans
+}
+
+func TestApplyRetentionMaxAge(t *testing.T) {
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour)
+
+	repo := newMemRepo(
+		Job{ID: "ok", Date: old, Status: StatusOK},
+		Job{ID: "failed", Date: old, Status: StatusFailed},
+		Job{ID: "pinned", Date: old, Status: StatusOK, Data: JobData{Pinned: true}},
+		Job{ID: "working", Date: old, Status: StatusWorking},
+		Job{ID: "pending", Date: old, Status: StatusPending},
+		Job{ID: "recent", Date: now, Status: StatusOK},
+	)
+
+	dir := t.TempDir()
+	writeJobFile(t, dir, "ok", 10)
+
+	svc := NewService(repo, dir, WithRetention(RetentionPolicy{MaxAge: 24 * time.Hour}))
+
+	deleted, err := svc.applyRetention(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 2, deleted)
+	require.Equal(t, []string{"pending", "pinned", "recent", "working"}, remainingJobs(t, repo))
+
+	_, err = os.Stat(filepath.Join(dir, "ok.csv"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestApplyRetentionSizeCap(t *testing.T) {
+	now := time.Now().UTC()
+
+	repo := newMemRepo(
+		Job{ID: "oldest", Date: now.Add(-3 * time.Hour), Status: StatusOK},
+		Job{ID: "pinned", Date: now.Add(-4 * time.Hour), Status: StatusOK, Data: JobData{Pinned: true}},
+		Job{ID: "working", Date: now.Add(-5 * time.Hour), Status: StatusWorking},
+		Job{ID: "older", Date: now.Add(-2 * time.Hour), Status: StatusFailed},
+		Job{ID: "newest", Date: now.Add(-time.Hour), Status: StatusOK},
+	)
+
+	dir := t.TempDir()
+
+	for _, id := range []string{"oldest", "pinned", "working", "older", "newest"} {
+		writeJobFile(t, dir, id, 100)
+	}
+
+	// the files of a job in its folder count as well
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "newest"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "newest", "part"), make([]byte, 50), 0o600))
+
+	// 550 bytes, the pinned and the working jobs are kept whatever their size
+	svc := NewService(repo, dir, WithRetention(RetentionPolicy{MaxDataBytes: 350}))
+
+	deleted, err := svc.applyRetention(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 2, deleted)
+	require.Equal(t, []string{"newest", "pinned", "working"}, remainingJobs(t, repo))
+
+	// under the cap nothing is deleted
+	deleted, err = svc.applyRetention(context.Background(), now)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+}
+
+func TestJobFileSizes(t *testing.T) {
+	dir := t.TempDir()
+
+	writeJobFile(t, dir, "a", 10)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.frontier"), make([]byte, 5), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "b"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b", "x.json"), make([]byte, 7), 0o600))
+
+	sizes, err := jobFileSizes(dir)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"a": 15, "b": 7}, sizes)
+
+	sizes, err = jobFileSizes(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	require.Empty(t, sizes)
+}
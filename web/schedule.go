@@ -0,0 +1,497 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleCheckInterval is how often the scheduler looks for due schedules
+const scheduleCheckInterval = 30 * time.Second
+
+// The results of a schedule run.
+const (
+	ScheduleRunCreated = "created"
+	// ScheduleRunSkipped is a run skipped because the job of the previous run is not finished
+	ScheduleRunSkipped = "skipped"
+	ScheduleRunFailed  = "failed"
+)
+
+// Schedule creates a job from its job data each time its cron expression is due.
+type Schedule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Cron is a standard cron expression of 5 fields, or a descriptor like
+	// @weekly, in UTC unless it starts with CRON_TZ=Europe/Berlin
+	Cron string  `json:"cron"`
+	Data JobData `json:"data"`
+	// LastJobID is the job of the last run, the next runs are skipped until it is finished
+	LastJobID string    `json:"last_job_id,omitempty"`
+	NextRun   time.Time `json:"next_run"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScheduleRun is one time a schedule was due.
+type ScheduleRun struct {
+	ScheduleID string `json:"schedule_id"`
+	// JobID is the job created by the run, empty when the run was skipped or failed
+	JobID  string    `json:"job_id,omitempty"`
+	Status string    `json:"status"`
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// ScheduleRepository stores the schedules and the history of their runs.
+type ScheduleRepository interface {
+	CreateSchedule(context.Context, *Schedule) error
+	GetSchedule(context.Context, string) (Schedule, error)
+	SelectSchedules(context.Context) ([]Schedule, error)
+	UpdateSchedule(context.Context, *Schedule) error
+	// ClaimScheduleRun moves the next run of the schedule id from prev to
+	// next, so the servers sharing the schedules run it once. It reports
+	// false when the next run is not prev anymore, another server claimed it.
+	ClaimScheduleRun(ctx context.Context, id string, prev, next time.Time) (bool, error)
+	// DeleteSchedule deletes the schedule and its history, not its jobs.
+	DeleteSchedule(context.Context, string) error
+	InsertScheduleRun(context.Context, *ScheduleRun) error
+	// SelectScheduleRuns returns the last runs of a schedule, the most recent first.
+	SelectScheduleRuns(ctx context.Context, scheduleID string, limit int) ([]ScheduleRun, error)
+}
+
+// ErrSchedulesNotSupported is returned when the repository does not store schedules.
+var ErrSchedulesNotSupported = errors.New("schedules are not supported")
+
+// WithSchedules keeps the schedules in repo and runs them with RunSchedules.
+func WithSchedules(repo ScheduleRepository) ServiceOption {
+	return func(s *Service) {
+		s.scheduleRepo = repo
+	}
+}
+
+// Validate checks the schedule and the job it creates.
+func (sc *Schedule) Validate() error {
+	if sc.Name == "" {
+		return errors.New("missing name")
+	}
+
+	if _, err := cron.ParseStandard(sc.Cron); err != nil {
+		return fmt.Errorf("invalid cron: %w", err)
+	}
+
+	if sc.Data.DependsOn != "" {
+		return errors.New("schedules cannot depend on a job")
+	}
+
+	job := sc.job(time.Now().UTC())
+
+	return job.Validate()
+}
+
+// job returns the pending job of a run of the schedule at now.
+func (sc *Schedule) job(now time.Time) Job {
+	return Job{
+		ID:     uuid.New().String(),
+		Name:   sc.Name + " " + now.Format("2006-01-02 15:04"),
+		Date:   now,
+		Status: StatusPending,
+		Data:   sc.Data,
+	}
+}
+
+// next sets the next run of the schedule after now.
+func (sc *Schedule) next(now time.Time) error {
+	spec, err := cron.ParseStandard(sc.Cron)
+	if err != nil {
+		return fmt.Errorf("invalid cron: %w", err)
+	}
+
+	sc.NextRun = spec.Next(now).UTC()
+
+	return nil
+}
+
+// CreateSchedule validates and stores a new schedule. The jobs of a schedule
+// created with an API key count towards its quota.
+func (s *Service) CreateSchedule(ctx context.Context, sc *Schedule) error {
+	if s.scheduleRepo == nil {
+		return ErrSchedulesNotSupported
+	}
+
+	if err := sc.Validate(); err != nil {
+		return err
+	}
+
+	setOwner(ctx, &sc.Data)
+	setAPIKey(ctx, &sc.Data)
+	setSlack(ctx, &sc.Data)
+
+	if key, ok := APIKeyFromContext(ctx); ok {
+		if err := s.checkQuota(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	// the jobs of the schedule are signed with the same secrets
+	if err := sc.Data.setWebhookSecrets(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	sc.ID = uuid.New().String()
+	sc.CreatedAt = now
+
+	if err := sc.next(now); err != nil {
+		return err
+	}
+
+	return s.scheduleRepo.CreateSchedule(ctx, sc)
+}
+
+func (s *Service) Schedules(ctx context.Context) ([]Schedule, error) {
+	if s.scheduleRepo == nil {
+		return nil, ErrSchedulesNotSupported
+	}
+
+	schedules, err := s.scheduleRepo.SelectSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := schedules[:0]
+
+	for i := range schedules {
+		if canAccess(ctx, &schedules[i].Data) {
+			ans = append(ans, schedules[i])
+		}
+	}
+
+	return ans, nil
+}
+
+// GetSchedule returns the schedule id, ErrNotFound when the user of ctx may
+// not see it.
+func (s *Service) GetSchedule(ctx context.Context, id string) (Schedule, error) {
+	if s.scheduleRepo == nil {
+		return Schedule{}, ErrSchedulesNotSupported
+	}
+
+	sc, err := s.scheduleRepo.GetSchedule(ctx, id)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	if !canAccess(ctx, &sc.Data) {
+		return Schedule{}, ErrNotFound
+	}
+
+	return sc, nil
+}
+
+// DeleteSchedule stops a schedule. The jobs it created are kept.
+func (s *Service) DeleteSchedule(ctx context.Context, id string) error {
+	if _, err := s.GetSchedule(ctx, id); err != nil {
+		return err
+	}
+
+	return s.scheduleRepo.DeleteSchedule(ctx, id)
+}
+
+// ScheduleHistory returns the last runs of a schedule, the most recent first.
+func (s *Service) ScheduleHistory(ctx context.Context, id string, limit int) ([]ScheduleRun, error) {
+	if s.scheduleRepo == nil {
+		return nil, ErrSchedulesNotSupported
+	}
+
+	return s.scheduleRepo.SelectScheduleRuns(ctx, id, limit)
+}
+
+// RunSchedules creates the jobs of the due schedules until ctx is done. The
+// runs missed while the server was stopped are run once when it starts. It
+// returns immediately when no schedule repository is configured.
+func (s *Service) RunSchedules(ctx context.Context) error {
+	if s.scheduleRepo == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runDueSchedules(ctx, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) runDueSchedules(ctx context.Context, now time.Time) {
+	schedules, err := s.scheduleRepo.SelectSchedules(ctx)
+	if err != nil {
+		log.Printf("failed to select the schedules: %v", err)
+
+		return
+	}
+
+	for i := range schedules {
+		if schedules[i].NextRun.After(now) {
+			continue
+		}
+
+		if err := s.runSchedule(ctx, &schedules[i], now); err != nil {
+			log.Printf("failed to run schedule %s: %v", schedules[i].ID, err)
+		}
+	}
+}
+
+// runSchedule claims the run of a due schedule and creates its job, unless
+// the job of its previous run is still pending, running or paused, and
+// records the run. A run claimed by another server is left to it.
+func (s *Service) runSchedule(ctx context.Context, sc *Schedule, now time.Time) error {
+	prevRun := sc.NextRun
+
+	if err := sc.next(now); err != nil {
+		return err
+	}
+
+	claimed, err := s.scheduleRepo.ClaimScheduleRun(ctx, sc.ID, prevRun, sc.NextRun)
+	if err != nil || !claimed {
+		return err
+	}
+
+	run := ScheduleRun{
+		ScheduleID: sc.ID,
+		Status:     ScheduleRunCreated,
+		Time:       now,
+	}
+
+	// a deleted job is finished
+	prev, err := s.repo.Get(ctx, sc.LastJobID)
+	if sc.LastJobID != "" && err == nil && (prev.Status == StatusPending || prev.Status == StatusWorking || prev.Status == StatusPaused) {
+		run.Status = ScheduleRunSkipped
+		run.Reason = fmt.Sprintf("job %s is %s", prev.ID, prev.Status)
+	} else {
+		job := sc.job(now)
+
+		err := s.scheduleQuota(ctx, sc)
+		if err == nil {
+			err = s.repo.Create(ctx, &job)
+		}
+
+		if err != nil {
+			run.Status = ScheduleRunFailed
+			run.Reason = err.Error()
+		} else {
+			s.publishStatus(&job)
+
+			run.JobID = job.ID
+			sc.LastJobID = job.ID
+		}
+	}
+
+	if err := s.scheduleRepo.InsertScheduleRun(ctx, &run); err != nil {
+		return err
+	}
+
+	return s.scheduleRepo.UpdateSchedule(ctx, sc)
+}
+
+// scheduleQuota fails when the schedule was created with an API key that was
+// revoked or whose quota is used up.
+func (s *Service) scheduleQuota(ctx context.Context, sc *Schedule) error {
+	if sc.Data.APIKeyID == "" || s.apiKeyRepo == nil {
+		return nil
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKey(ctx, sc.Data.APIKeyID)
+	if err != nil {
+		return fmt.Errorf("api key %s: %w", sc.Data.APIKeyID, err)
+	}
+
+	return s.checkQuota(ctx, &key)
+}
+
+type apiScheduleRequest struct {
+	Name string `json:"name"`
+	Cron string `json:"cron"`
+	JobData
+}
+
+type apiScheduleResponse struct {
+	ID       string    `json:"id"`
+	NextRun  time.Time `json:"next_run"`
+	Warnings []string  `json:"warnings,omitempty"`
+}
+
+func (s *Server) apiCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req apiScheduleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	sc := Schedule{
+		Name: req.Name,
+		Cron: req.Cron,
+		Data: req.JobData,
+	}
+
+	// convert to seconds
+	sc.Data.MaxTime *= time.Second
+
+	err := sc.Validate()
+	if err == nil {
+		err = s.svc.ValidateServerSettings(&sc.Data)
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if err := s.svc.CreateSchedule(r.Context(), &sc); err != nil {
+		renderScheduleError(w, err)
+
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, apiScheduleResponse{
+		ID:       sc.ID,
+		NextRun:  sc.NextRun,
+		Warnings: sc.Data.Warnings(),
+	})
+}
+
+func (s *Server) apiGetSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := s.svc.Schedules(r.Context())
+	if err != nil {
+		renderScheduleError(w, err)
+
+		return
+	}
+
+	if schedules == nil {
+		schedules = []Schedule{}
+	}
+
+	renderJSON(w, http.StatusOK, schedules)
+}
+
+func (s *Server) apiGetSchedule(w http.ResponseWriter, r *http.Request) {
+	sc, ok := s.scheduleFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	renderJSON(w, http.StatusOK, sc)
+}
+
+func (s *Server) apiDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	sc, ok := s.scheduleFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.svc.DeleteSchedule(r.Context(), sc.ID); err != nil {
+		renderScheduleError(w, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) apiGetScheduleHistory(w http.ResponseWriter, r *http.Request) {
+	const historyLimit = 100
+
+	sc, ok := s.scheduleFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := s.svc.ScheduleHistory(r.Context(), sc.ID, historyLimit)
+	if err != nil {
+		renderScheduleError(w, err)
+
+		return
+	}
+
+	if runs == nil {
+		runs = []ScheduleRun{}
+	}
+
+	renderJSON(w, http.StatusOK, runs)
+}
+
+// scheduleFromRequest returns the schedule of the id of the request. It
+// renders the error and returns false when there is none.
+func (s *Server) scheduleFromRequest(w http.ResponseWriter, r *http.Request) (Schedule, bool) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return Schedule{}, false
+	}
+
+	sc, err := s.svc.GetSchedule(r.Context(), id.String())
+	if errors.Is(err, ErrSchedulesNotSupported) {
+		renderScheduleError(w, err)
+
+		return Schedule{}, false
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return Schedule{}, false
+	}
+
+	return sc, true
+}
+
+func renderScheduleError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, ErrSchedulesNotSupported) {
+		code = http.StatusNotImplemented
+	}
+
+	apiError := apiError{
+		Code:    code,
+		Message: err.Error(),
+	}
+
+	renderJSON(w, code, apiError)
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDueSchedules(t *testing.T) {
+	repo := newMemRepo()
+	schedules := &memSchedules{schedules: map[string]Schedule{}}
+	svc := NewService(repo, "", WithSchedules(schedules))
+	ctx := context.Background()
+
+	now := time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)
+
+	due := Schedule{ID: "due", Name: "hourly", Cron: "0 * * * *", Data: testJobData(), NextRun: now.Add(-30 * time.Minute)}
+	later := Schedule{ID: "later", Name: "daily", Cron: "@daily", Data: testJobData(), NextRun: now.Add(13 * time.Hour)}
+
+	require.NoError(t, schedules.CreateSchedule(ctx, &due))
+	require.NoError(t, schedules.CreateSchedule(ctx, &later))
+
+	svc.runDueSchedules(ctx, now)
+
+	// the due schedule created a job and runs again at the next hour
+	require.Len(t, schedules.runs, 1)
+	require.Equal(t, "due", schedules.runs[0].ScheduleID)
+	require.Equal(t, ScheduleRunCreated, schedules.runs[0].Status)
+
+	job, ok := repo.jobs[schedules.runs[0].JobID]
+	require.True(t, ok)
+	require.Equal(t, now, job.Date)
+	require.Equal(t, "hourly 2026-10-16 10:30", job.Name)
+
+	got := schedules.schedules["due"]
+	require.Equal(t, now.Truncate(time.Hour).Add(time.Hour), got.NextRun)
+	require.Equal(t, job.ID, got.LastJobID)
+
+	// the other one is not due
+	require.Equal(t, later, schedules.schedules["later"])
+
+	// nothing is due until the next hour
+	svc.runDueSchedules(ctx, now.Add(10*time.Minute))
+	require.Len(t, schedules.runs, 1)
+	require.Len(t, repo.jobs, 1)
+}
+
+func TestRunScheduleClaimed(t *testing.T) {
+	repo := newMemRepo()
+	schedules := &memSchedules{schedules: map[string]Schedule{}}
+	ctx := context.Background()
+
+	now := time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)
+
+	sc := Schedule{ID: "due", Name: "hourly", Cron: "0 * * * *", Data: testJobData(), NextRun: now.Add(-30 * time.Minute)}
+	require.NoError(t, schedules.CreateSchedule(ctx, &sc))
+
+	// two servers sharing the schedules both see it due
+	first, second := sc, sc
+
+	require.NoError(t, NewService(repo, "", WithSchedules(schedules)).runSchedule(ctx, &first, now))
+	require.NoError(t, NewService(repo, "", WithSchedules(schedules)).runSchedule(ctx, &second, now))
+
+	// and the run is claimed by the first one only
+	require.Len(t, schedules.runs, 1)
+	require.Len(t, repo.jobs, 1)
+	require.Equal(t, first.LastJobID, schedules.schedules["due"].LastJobID)
+}
@@ -1,42 +1,201 @@
 package web
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/geocoder"
 )
 
+// ObjectStore is an S3-compatible storage where the result files are copied
+// when a job completes.
+type ObjectStore interface {
+	Upload(ctx context.Context, bucketName, key string, body io.Reader) error
+	PresignGet(ctx context.Context, bucketName, key string, expires time.Duration) (string, error)
+	Delete(ctx context.Context, bucketName, key string) error
+}
+
+type ServiceOption func(*Service)
+
 type Service struct {
 	repo       JobRepository
 	dataFolder string
+
+	store       ObjectStore
+	storeBucket string
+	storePrefix string
+
+	dedupMu      sync.Mutex
+	dedupTotal   deduper.Stats
+	dedupRunning map[*deduper.Instrumented]struct{}
+
+	metrics          MetricsCollector
+	metricsRepo      MetricsRepository
+	metricsInterval  time.Duration
+	metricsRetention time.Duration
+	metricsObserver  func(MetricsPoint)
+
+	progressMu sync.Mutex
+	running    map[string]runningJob
+
+	events eventBroker
+
+	tilesRepo TileRepository
+
+	checkpointRepo CheckpointRepository
+	resume         bool
+
+	scheduleRepo ScheduleRepository
+
+	templateRepo TemplateRepository
+
+	userRepo  UserRepository
+	passwords *passwordCache
+
+	auditRepo AuditRepository
+
+	retention RetentionPolicy
+
+	resultRepo ResultRepository
+	placeRepo  PlaceRepository
+
+	apiKeyRepo   APIKeyRepository
+	apiKeyLimits APIKeyLimits
+
+	geocoder geocoder.Geocoder
+
+	proxyGroups []string
+
+	captchaSolving bool
+	translation    bool
+
+	publicURL string
+
+	writerTypes []string
+	// pluginWriters are the names of the writers of the plugins
+	pluginWriters []string
+
+	deadLetterRepo DeadLetterRepository
+
+	statusMu sync.Mutex
+	status   StatusSummary
 }
 
-func NewService(repo JobRepository, dataFolder string) *Service {
-	return &Service{
+func NewService(repo JobRepository, dataFolder string, opts ...ServiceOption) *Service {
+	ans := Service{
 		repo:       repo,
 		dataFolder: dataFolder,
 	}
+
+	for _, opt := range opts {
+		opt(&ans)
+	}
+
+	return &ans
 }
 
+// WithObjectStore uploads the results of completed jobs to the bucket
+// under the given prefix and serves downloads via pre-signed URLs.
+func WithObjectStore(store ObjectStore, bucket, prefix string) ServiceOption {
+	return func(s *Service) {
+		s.store = store
+		s.storeBucket = bucket
+		s.storePrefix = prefix
+	}
+}
+
+// Create stores a new job, owned by the user of ctx. A job created with an
+// API key counts towards its quota. A job that depends on another one waits
+// for it to complete.
 func (s *Service) Create(ctx context.Context, job *Job) error {
-	return s.repo.Create(ctx, job)
+	setOwner(ctx, &job.Data)
+	setAPIKey(ctx, &job.Data)
+	setSlack(ctx, &job.Data)
+
+	if key, ok := APIKeyFromContext(ctx); ok {
+		if err := s.checkQuota(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	if err := job.Data.setWebhookSecrets(); err != nil {
+		return err
+	}
+
+	if job.Data.Transform != "" {
+		if err := s.prepareTransform(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.Create(ctx, job); err != nil {
+		return err
+	}
+
+	s.publishStatus(job)
+	s.audit(ctx, AuditJobCreate, job.ID, job.Name)
+
+	return nil
 }
 
+// All returns the jobs the user of ctx may see.
 func (s *Service) All(ctx context.Context) ([]Job, error) {
-	return s.repo.Select(ctx, SelectParams{})
+	jobs, err := s.repo.Select(ctx, SelectParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	ans := jobs[:0]
+
+	for i := range jobs {
+		if canAccess(ctx, &jobs[i].Data) {
+			ans = append(ans, jobs[i])
+		}
+	}
+
+	return ans, nil
 }
 
+// Get returns the job id, ErrNotFound when the user of ctx may not see it.
 func (s *Service) Get(ctx context.Context, id string) (Job, error) {
-	return s.repo.Get(ctx, id)
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+
+	if !canAccess(ctx, &job.Data) {
+		return Job{}, ErrNotFound
+	}
+
+	return job, nil
 }
 
+// Delete deletes the job id with its files and rows.
 func (s *Service) Delete(ctx context.Context, id string) error {
+	return s.delete(ctx, id, "")
+}
+
+// delete deletes the job id and records why in the audit log.
+func (s *Service) delete(ctx context.Context, id, reason string) error {
 	if strings.Contains(id, "/") || strings.Contains(id, "\\") || strings.Contains(id, "..") {
 		return fmt.Errorf("invalid file name")
 	}
 
+	if job, err := s.repo.Get(ctx, id); err == nil && !canAccess(ctx, &job.Data) {
+		return ErrNotFound
+	}
+
 	datapath := filepath.Join(s.dataFolder, id+".csv")
 
 	if _, err := os.Stat(datapath); err == nil {
@@ -47,15 +206,303 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 		return err
 	}
 
-	return s.repo.Delete(ctx, id)
+	for _, ext := range []string{".frontier", ".dedup", ".input", ".errors.csv", ".store"} {
+		if err := os.Remove(filepath.Join(s.dataFolder, id+ext)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// the screenshots of the job
+	if err := os.RemoveAll(filepath.Join(s.dataFolder, id)); err != nil {
+		return err
+	}
+
+	if s.tilesRepo != nil {
+		if err := s.tilesRepo.DeleteTiles(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := s.DeleteCheckpoint(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.ResetResults(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.DeleteDeadLetters(ctx, id, nil); err != nil {
+		return err
+	}
+
+	if s.store != nil {
+		job, err := s.repo.Get(ctx, id)
+		if err == nil {
+			if err := s.store.Delete(ctx, s.storeBucket, s.objectKey(&job)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.forgetStatus(id)
+	s.audit(ctx, AuditJobDelete, id, reason)
+
+	// the jobs waiting for a deleted job can never run
+	return s.ReleaseDependents(ctx, &Job{ID: id, Status: StatusFailed})
+}
+
+// ErrInvalidTransition is returned when a job cannot be paused or resumed in its current status.
+var ErrInvalidTransition = errors.New("invalid job status transition")
+
+// Pause pauses a pending or running job. A running job stops scheduling new
+// work and its pending work is saved, so the worker can move to other jobs.
+func (s *Service) Pause(ctx context.Context, id string) error {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != StatusPending && job.Status != StatusWorking {
+		return fmt.Errorf("%w: cannot pause a %s job", ErrInvalidTransition, job.Status)
+	}
+
+	if job.Status == StatusWorking && job.Data.FastMode {
+		return fmt.Errorf("%w: running fast mode jobs cannot be paused", ErrInvalidTransition)
+	}
+
+	job.Status = StatusPaused
+
+	if err := s.Update(ctx, &job); err != nil {
+		return err
+	}
+
+	s.audit(ctx, AuditJobPause, id, "")
+
+	return nil
+}
+
+// Resume puts a paused job back in the queue.
+// It continues from the saved pending work if there is any.
+// A failed job with a checkpoint is resumed from it.
+func (s *Service) Resume(ctx context.Context, id string) error {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	resumable := job.Status == StatusPaused
+
+	if job.Status == StatusFailed {
+		resumable, err = s.hasCheckpoint(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !resumable {
+		return fmt.Errorf("%w: cannot resume a %s job", ErrInvalidTransition, job.Status)
+	}
+
+	job.Status = StatusPending
+
+	if err := s.Update(ctx, &job); err != nil {
+		return err
+	}
+
+	s.audit(ctx, AuditJobResume, id, "")
+
+	return nil
 }
 
+// Update stores job and publishes its status when it changed.
 func (s *Service) Update(ctx context.Context, job *Job) error {
-	return s.repo.Update(ctx, job)
+	switch job.Status {
+	case StatusOK, StatusFailed:
+		if job.Stats.FinishedAt.IsZero() {
+			job.Stats.FinishedAt = time.Now().UTC()
+		}
+	default:
+		job.Stats.FinishedAt = time.Time{}
+	}
+
+	if err := s.repo.Update(ctx, job); err != nil {
+		return err
+	}
+
+	s.publishStatus(job)
+
+	return nil
 }
 
+// SelectPending returns the jobs to scrape, the highest priority first. When
+// the repository is shared by several servers a single job is claimed, so no
+// other server scrapes it.
 func (s *Service) SelectPending(ctx context.Context) ([]Job, error) {
-	return s.repo.Select(ctx, SelectParams{Status: StatusPending, Limit: 1})
+	claimer, ok := s.repo.(JobClaimer)
+	if !ok {
+		return s.Pending(ctx)
+	}
+
+	job, err := claimer.ClaimPending(ctx)
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return []Job{job}, nil
+}
+
+// RenewClaims renews the claims of the jobs ids the server runs, when the
+// repository is shared by several servers, see ClaimLease.
+func (s *Service) RenewClaims(ctx context.Context, ids []string) error {
+	claimer, ok := s.repo.(JobClaimer)
+	if !ok || len(ids) == 0 {
+		return nil
+	}
+
+	return claimer.RenewClaims(ctx, ids)
+}
+
+// Pending returns the pending jobs, the highest priority first, without
+// claiming them.
+func (s *Service) Pending(ctx context.Context) ([]Job, error) {
+	jobs, err := s.repo.Select(ctx, SelectParams{Status: StatusPending})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(jobs, func(a, b Job) int {
+		return cmp.Compare(b.Data.Priority, a.Data.Priority)
+	})
+
+	return jobs, nil
+}
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// StatusSummary is the public health summary of the service.
+// It must not contain job names, keywords or any other user data.
+type StatusSummary struct {
+	Health          string        `json:"health"`
+	QueueDepth      int           `json:"queue_depth"`
+	JobsInProgress  int           `json:"jobs_in_progress"`
+	RecentCompleted int           `json:"recent_completed"`
+	RecentFailed    int           `json:"recent_failed"`
+	RecentErrorRate float64       `json:"recent_error_rate"`
+	LastCaptcha     *time.Time    `json:"last_captcha,omitempty"`
+	OpenCircuits    int           `json:"open_circuits"`
+	Dedup           deduper.Stats `json:"dedup"`
+	GeneratedAt     time.Time     `json:"generated_at"`
+}
+
+//nolint:gocritic // this is used in template
+func (s StatusSummary) ErrorRatePercent() float64 {
+	return s.RecentErrorRate * 100 //nolint:gomnd // percentage
+}
+
+//nolint:gocritic // this is used in template
+func (s StatusSummary) DedupHitRatePercent() float64 {
+	return s.Dedup.HitRate() * 100 //nolint:gomnd // percentage
+}
+
+// statusCacheTTL is how long the summary of Status is served before the jobs
+// are read again, /status is public and must not be a cheap way to load the
+// database.
+const statusCacheTTL = 10 * time.Second
+
+// Status summarizes the jobs that finished in the last 24 hours.
+// The service is reported degraded when more than half of the finished jobs
+// failed, when Google served a captcha in the last 15 minutes or when the
+// circuit of Google is open. The summary is cached for 10 seconds.
+func (s *Service) Status(ctx context.Context) StatusSummary {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	if time.Since(s.status.GeneratedAt) < statusCacheTTL {
+		return s.status
+	}
+
+	s.status = s.summarize(ctx)
+
+	return s.status
+}
+
+func (s *Service) summarize(ctx context.Context) StatusSummary {
+	const (
+		recentWindow      = 24 * time.Hour
+		degradedErrorRate = 0.5
+		captchaWindow     = 15 * time.Minute
+	)
+
+	ans := StatusSummary{
+		Health:      HealthOK,
+		Dedup:       s.DedupStats(),
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	jobs, err := s.repo.Select(ctx, SelectParams{})
+	if err != nil {
+		ans.Health = HealthDown
+
+		return ans
+	}
+
+	since := ans.GeneratedAt.Add(-recentWindow)
+
+	for i := range jobs {
+		switch jobs[i].Status {
+		case StatusPending:
+			ans.QueueDepth++
+		case StatusWorking:
+			ans.JobsInProgress++
+		case StatusOK:
+			if jobs[i].FinishedAt().After(since) {
+				ans.RecentCompleted++
+			}
+		case StatusFailed:
+			if jobs[i].FinishedAt().After(since) {
+				ans.RecentFailed++
+			}
+		}
+	}
+
+	if finished := ans.RecentCompleted + ans.RecentFailed; finished > 0 {
+		ans.RecentErrorRate = float64(ans.RecentFailed) / float64(finished)
+	}
+
+	if ans.RecentErrorRate > degradedErrorRate {
+		ans.Health = HealthDegraded
+	}
+
+	if last := s.metrics.LastCaptcha(); !last.IsZero() {
+		ans.LastCaptcha = &last
+
+		if ans.GeneratedAt.Sub(last) < captchaWindow {
+			ans.Health = HealthDegraded
+		}
+	}
+
+	// the hosts are not listed, they are the websites of the places
+	for host := range s.metrics.Circuits() {
+		ans.OpenCircuits++
+
+		if strings.HasPrefix(host, "google.") {
+			ans.Health = HealthDegraded
+		}
+	}
+
+	return ans
 }
 
 func (s *Service) GetCSV(_ context.Context, id string) (string, error) {
@@ -71,3 +518,59 @@ func (s *Service) GetCSV(_ context.Context, id string) (string, error) {
 
 	return datapath, nil
 }
+
+// UploadResults copies the csv file of the job to the object store and marks
+// the job uploaded. It is a no-op when no object store is configured. Only the
+// csv is uploaded, the other files of the job are served by the server.
+func (s *Service) UploadResults(ctx context.Context, job *Job) error {
+	if s.store == nil {
+		return nil
+	}
+
+	datapath, err := s.GetCSV(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.Open(datapath)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	if err := s.store.Upload(ctx, s.storeBucket, s.objectKey(job), fd); err != nil {
+		return err
+	}
+
+	job.Stats.Uploaded = true
+
+	return nil
+}
+
+// GetDownloadURL returns a pre-signed URL for the results of the job.
+// It returns an empty string when no object store is configured or when the
+// results of the job are not in it, in which case they are downloaded from
+// the server.
+func (s *Service) GetDownloadURL(ctx context.Context, id string) (string, error) {
+	if s.store == nil {
+		return "", nil
+	}
+
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if job.Status != StatusOK || !job.Stats.Uploaded {
+		return "", nil
+	}
+
+	const expires = 15 * time.Minute
+
+	return s.store.PresignGet(ctx, s.storeBucket, s.objectKey(&job), expires)
+}
+
+func (s *Service) objectKey(job *Job) string {
+	return path.Join(s.storePrefix, job.Data.OutputPrefix, job.ID+".csv")
+}
@@ -0,0 +1,185 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is a JobRepository in memory.
+type memRepo struct {
+	jobs    map[string]Job
+	selects int
+}
+
+func newMemRepo(jobs ...Job) *memRepo {
+	ans := memRepo{jobs: map[string]Job{}}
+
+	for i := range jobs {
+		ans.jobs[jobs[i].ID] = jobs[i]
+	}
+
+	return &ans
+}
+
+func (r *memRepo) Get(_ context.Context, id string) (Job, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+
+	return job, nil
+}
+
+func (r *memRepo) Create(_ context.Context, job *Job) error {
+	r.jobs[job.ID] = *job
+
+	return nil
+}
+
+func (r *memRepo) Delete(_ context.Context, id string) error {
+	delete(r.jobs, id)
+
+	return nil
+}
+
+func (r *memRepo) Select(_ context.Context, params SelectParams) ([]Job, error) {
+	r.selects++
+
+	var ans []Job
+
+	for id := range r.jobs {
+		if params.Status == "" || r.jobs[id].Status == params.Status {
+			ans = append(ans, r.jobs[id])
+		}
+	}
+
+	return ans, nil
+}
+
+func (r *memRepo) Update(_ context.Context, job *Job) error {
+	r.jobs[job.ID] = *job
+
+	return nil
+}
+
+func TestServiceStatus(t *testing.T) {
+	old := time.Now().UTC().Add(-48 * time.Hour)
+
+	repo := newMemRepo(
+		// created two days ago but finished an hour ago
+		Job{ID: "1", Date: old, Status: StatusOK, Stats: JobStats{FinishedAt: time.Now().UTC().Add(-time.Hour)}},
+		// finished before the finish time was recorded
+		Job{ID: "2", Date: old, Status: StatusFailed},
+		Job{ID: "3", Date: old, Status: StatusPending},
+	)
+
+	svc := NewService(repo, t.TempDir())
+
+	got := svc.Status(context.Background())
+	require.Equal(t, 1, got.RecentCompleted)
+	require.Equal(t, 0, got.RecentFailed)
+	require.Equal(t, 1, got.QueueDepth)
+	require.Equal(t, HealthOK, got.Health)
+
+	// the summary is cached
+	repo.jobs["3"] = Job{ID: "3", Date: old, Status: StatusWorking}
+
+	require.Equal(t, got, svc.Status(context.Background()))
+	require.Equal(t, 1, repo.selects)
+}
+
+func TestServiceUpdateFinishedAt(t *testing.T) {
+	job := Job{ID: "1", Date: time.Now().UTC(), Status: StatusWorking}
+
+	svc := NewService(newMemRepo(job), t.TempDir())
+
+	require.NoError(t, svc.Update(context.Background(), &job))
+	require.True(t, job.Stats.FinishedAt.IsZero())
+
+	job.Status = StatusOK
+	require.NoError(t, svc.Update(context.Background(), &job))
+	require.False(t, job.Stats.FinishedAt.IsZero())
+
+	finished := job.Stats.FinishedAt
+
+	// an update of a finished job, e.g. pinning it, keeps the time
+	job.Data.Pinned = true
+	require.NoError(t, svc.Update(context.Background(), &job))
+	require.Equal(t, finished, job.Stats.FinishedAt)
+	require.Equal(t, finished, job.FinishedAt())
+
+	// a job run again is not finished anymore
+	job.Status = StatusPending
+	require.NoError(t, svc.Update(context.Background(), &job))
+	require.True(t, job.Stats.FinishedAt.IsZero())
+	require.Equal(t, job.Date, job.FinishedAt())
+}
+
+type fakeStore struct {
+	err      error
+	uploaded []string
+}
+
+func (f *fakeStore) Upload(_ context.Context, _, key string, _ io.Reader) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.uploaded = append(f.uploaded, key)
+
+	return nil
+}
+
+func (f *fakeStore) PresignGet(_ context.Context, bucket, key string, _ time.Duration) (string, error) {
+	return "https://" + bucket + "/" + key, nil
+}
+
+func (f *fakeStore) Delete(context.Context, string, string) error {
+	return nil
+}
+
+func TestServiceGetDownloadURL(t *testing.T) {
+	dir := t.TempDir()
+	store := &fakeStore{}
+
+	job := Job{ID: "1", Date: time.Now().UTC(), Status: StatusOK}
+	repo := newMemRepo(job)
+
+	svc := NewService(repo, dir, WithObjectStore(store, "bucket", ""))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1.csv"), []byte("title\n"), 0o600))
+
+	// not uploaded, the csv is served by the server
+	u, err := svc.GetDownloadURL(context.Background(), "1")
+	require.NoError(t, err)
+	require.Empty(t, u)
+
+	store.err = errors.New("access denied")
+	require.Error(t, svc.UploadResults(context.Background(), &job))
+	require.False(t, job.Stats.Uploaded)
+
+	store.err = nil
+	require.NoError(t, svc.UploadResults(context.Background(), &job))
+	require.True(t, job.Stats.Uploaded)
+	require.Equal(t, []string{"1.csv"}, store.uploaded)
+	require.NoError(t, svc.Update(context.Background(), &job))
+
+	u, err = svc.GetDownloadURL(context.Background(), "1")
+	require.NoError(t, err)
+	require.Equal(t, "https://bucket/1.csv", u)
+
+	// a failed job is never redirected
+	job.Status = StatusFailed
+	require.NoError(t, svc.Update(context.Background(), &job))
+
+	u, err = svc.GetDownloadURL(context.Background(), "1")
+	require.NoError(t, err)
+	require.Empty(t, u)
+}
@@ -0,0 +1,327 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	slackMaxBodySize   = 1 << 16
+	slackMaxRequestAge = 5 * time.Minute
+)
+
+// slackResponseURLPrefix is the prefix of the response urls of the slash
+// commands, the follow-ups of the jobs are posted to no other url.
+var slackResponseURLPrefix = "https://hooks.slack.com/"
+
+type slackResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// SlackOrigin is the Slack user that created a job with the slash command,
+// the only one the status of the job is shown to.
+type SlackOrigin struct {
+	TeamID string `json:"team_id"`
+	UserID string `json:"user_id"`
+	// ResponseURL is the url of the command the changes of the status of
+	// the job are posted to
+	ResponseURL string `json:"response_url,omitempty"`
+}
+
+const slackCtxKey ctxKey = "slack"
+
+// contextWithSlack returns ctx for the jobs created by the slash command of
+// origin.
+func contextWithSlack(ctx context.Context, origin *SlackOrigin) context.Context {
+	return context.WithValue(ctx, slackCtxKey, origin)
+}
+
+// setSlack sets the Slack user of ctx as the one that created the job of d,
+// and clears the one the client gave.
+func setSlack(ctx context.Context, d *JobData) {
+	d.Slack = nil
+
+	if origin, ok := ctx.Value(slackCtxKey).(*SlackOrigin); ok {
+		d.Slack = origin
+	}
+}
+
+// slackCommand handles a Slack slash command. The text of the command is either
+//
+//	status <job id>
+//
+// or a list of keywords separated by ";" followed by optional settings after a "|":
+//
+//	coffee in athens; bakery in athens | lang=el region=gr depth=5 maxtime=20m email
+func (s *Server) slackCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, slackMaxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if !verifySlackSignature(s.slackSigningSecret, r.Header, body, time.Now()) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	text := strings.TrimSpace(form.Get("text"))
+
+	origin := &SlackOrigin{TeamID: form.Get("team_id"), UserID: form.Get("user_id")}
+
+	if id, ok := strings.CutPrefix(text, "status"); ok && (id == "" || id[0] == ' ') {
+		s.slackStatus(w, r, origin, strings.TrimSpace(id))
+
+		return
+	}
+
+	// the follow-ups are posted to slack only
+	if u := form.Get("response_url"); strings.HasPrefix(u, slackResponseURLPrefix) {
+		origin.ResponseURL = u
+	}
+
+	job, err := jobFromSlackText(text, form.Get("user_name"))
+	if err != nil {
+		renderJSON(w, http.StatusOK, slackResponse{
+			ResponseType: "ephemeral",
+			Text:         fmt.Sprintf("Could not create the job: %v", err),
+		})
+
+		return
+	}
+
+	if err := s.svc.Create(contextWithSlack(r.Context(), origin), &job); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, slackResponse{
+		ResponseType: "ephemeral",
+		Text: fmt.Sprintf("Job `%s` created with %d keyword(s). You will be told here when it starts and ends, "+
+			"or use `%s status %s` to follow it.",
+			job.ID, len(job.Data.Keywords), form.Get("command"), job.ID),
+	})
+}
+
+// slackStatus replies with the status of the job id to the Slack user of
+// origin, when that user created it.
+func (s *Server) slackStatus(w http.ResponseWriter, r *http.Request, origin *SlackOrigin, id string) {
+	if _, err := uuid.Parse(id); err != nil {
+		renderJSON(w, http.StatusOK, slackResponse{ResponseType: "ephemeral", Text: "Invalid job id"})
+
+		return
+	}
+
+	job, err := s.svc.Get(r.Context(), id)
+	if err != nil || !job.Data.Slack.created(origin) {
+		renderJSON(w, http.StatusOK, slackResponse{ResponseType: "ephemeral", Text: "Job not found"})
+
+		return
+	}
+
+	renderJSON(w, http.StatusOK, slackResponse{ResponseType: "ephemeral", Text: s.svc.slackStatusText(&job)})
+}
+
+// created reports whether the job of o was created by the Slack user of
+// other. It is false for the jobs not created from Slack.
+func (o *SlackOrigin) created(other *SlackOrigin) bool {
+	return o != nil && o.TeamID != "" && o.UserID != "" && o.TeamID == other.TeamID && o.UserID == other.UserID
+}
+
+// slackStatusText returns the status of job for Slack, with the link to its
+// results once it is done. The link is the one of -public-url, or of the
+// object storage, and is left out when neither is set.
+func (s *Service) slackStatusText(job *Job) string {
+	text := fmt.Sprintf("Job `%s` (%s) is *%s*", job.ID, job.Name, job.Status)
+
+	if job.Status != StatusOK {
+		return text
+	}
+
+	text += fmt.Sprintf(" with %d places", job.Stats.Places)
+
+	if u := s.webhookDownloadURL(job); u != "" {
+		text += ". Download: " + u
+	}
+
+	return text
+}
+
+// notifySlack posts the change of the status of job to the slash command
+// that created it: when it starts, completes or fails.
+func (s *Service) notifySlack(job *Job) {
+	if job.Data.Slack == nil || job.Data.Slack.ResponseURL == "" {
+		return
+	}
+
+	switch job.Status {
+	case StatusWorking, StatusOK, StatusFailed:
+	default:
+		return
+	}
+
+	body, err := json.Marshal(slackResponse{ResponseType: "ephemeral", Text: s.slackStatusText(job)})
+	if err != nil {
+		return
+	}
+
+	go func(u string) {
+		if err := postSlack(u, body); err != nil {
+			s.Logf(job.ID, "failed to post the status of job %s to slack: %v", job.ID, err)
+		}
+	}(job.Data.Slack.ResponseURL)
+}
+
+// postSlack posts body to the response url u of a slash command.
+func postSlack(u string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func jobFromSlackText(text, userName string) (Job, error) {
+	keywordsPart, settingsPart, _ := strings.Cut(text, "|")
+
+	job := Job{
+		ID:     uuid.New().String(),
+		Name:   "slack",
+		Date:   time.Now().UTC(),
+		Status: StatusPending,
+		Data: JobData{
+			Lang:    "en",
+			Zoom:    15,
+			Depth:   10,
+			MaxTime: 10 * time.Minute,
+		},
+	}
+
+	if userName != "" {
+		job.Name = "slack: " + userName
+	}
+
+	for _, k := range strings.Split(keywordsPart, ";") {
+		if k = strings.TrimSpace(k); k != "" {
+			job.Data.Keywords = append(job.Data.Keywords, k)
+		}
+	}
+
+	for _, setting := range strings.Fields(settingsPart) {
+		key, value, _ := strings.Cut(setting, "=")
+
+		var err error
+
+		switch key {
+		case "lang":
+			job.Data.Lang = value
+		case "region":
+			job.Data.Region = strings.ToLower(value)
+		case "depth":
+			job.Data.Depth, err = strconv.Atoi(value)
+		case "zoom":
+			job.Data.Zoom, err = strconv.Atoi(value)
+		case "maxtime":
+			job.Data.MaxTime, err = time.ParseDuration(value)
+		case "email":
+			job.Data.Email = true
+		case "sort":
+			job.Data.SortBy = value
+		default:
+			err = fmt.Errorf("unknown setting %q", key)
+		}
+
+		if err != nil {
+			return Job{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	if job.Data.MaxTime < 3*time.Minute {
+		return Job{}, fmt.Errorf("maxtime must be more than 3m")
+	}
+
+	if err := job.Validate(); err != nil {
+		return Job{}, err
+	}
+
+	return job, nil
+}
+
+// verifySlackSignature checks the request signature as described in
+// https://api.slack.com/authentication/verifying-requests-from-slack
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+
+	ts, err := strconv.ParseInt(header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if age := now.Sub(time.Unix(ts, 0)); age > slackMaxRequestAge || age < -slackMaxRequestAge {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	var base bytes.Buffer
+
+	base.WriteString("v0:")
+	base.WriteString(strconv.FormatInt(ts, 10))
+	base.WriteString(":")
+	base.Write(body)
+
+	mac.Write(base.Bytes())
+
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature")))
+}
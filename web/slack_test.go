@@ -0,0 +1,225 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func slackHeader(secret string, ts int64, body string) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + strconv.FormatInt(ts, 10) + ":" + body))
+
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(ts, 10))
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	return header
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&command=%2Fgmaps&text=cafe")
+	now := time.Unix(1531420618, 0)
+
+	t.Run("slack example", func(t *testing.T) {
+		// https://api.slack.com/authentication/verifying-requests-from-slack
+		example := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")
+
+		header := http.Header{}
+		header.Set("X-Slack-Request-Timestamp", "1531420618")
+		header.Set("X-Slack-Signature", "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503")
+
+		require.True(t, verifySlackSignature(secret, header, example, now))
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		require.True(t, verifySlackSignature(secret, slackHeader(secret, now.Unix(), string(body)), body, now))
+		// within the window on both sides
+		require.True(t, verifySlackSignature(secret, slackHeader(secret, now.Unix(), string(body)), body, now.Add(4*time.Minute)))
+		require.True(t, verifySlackSignature(secret, slackHeader(secret, now.Unix(), string(body)), body, now.Add(-4*time.Minute)))
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		require.False(t, verifySlackSignature(secret, slackHeader("another secret", now.Unix(), string(body)), body, now))
+		require.False(t, verifySlackSignature(secret, slackHeader(secret, now.Unix(), string(body)), []byte("text=tampered"), now))
+
+		header := slackHeader(secret, now.Unix(), string(body))
+		header.Del("X-Slack-Signature")
+
+		require.False(t, verifySlackSignature(secret, header, body, now))
+
+		// the signature is bound to its timestamp
+		header = slackHeader(secret, now.Unix(), string(body))
+		header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(now.Unix()+1, 10))
+
+		require.False(t, verifySlackSignature(secret, header, body, now))
+	})
+
+	t.Run("stale timestamp", func(t *testing.T) {
+		header := slackHeader(secret, now.Unix(), string(body))
+
+		require.False(t, verifySlackSignature(secret, header, body, now.Add(6*time.Minute)))
+		require.False(t, verifySlackSignature(secret, header, body, now.Add(-6*time.Minute)))
+
+		header.Set("X-Slack-Request-Timestamp", "yesterday")
+
+		require.False(t, verifySlackSignature(secret, header, body, now))
+	})
+
+	t.Run("no secret", func(t *testing.T) {
+		require.False(t, verifySlackSignature("", slackHeader("", now.Unix(), string(body)), body, now))
+	})
+}
+
+func TestJobFromSlackText(t *testing.T) {
+	job, err := jobFromSlackText("coffee in athens; bakery in athens | lang=el region=GR depth=5 maxtime=20m email", "alice")
+	require.NoError(t, err)
+	require.Equal(t, "slack: alice", job.Name)
+	require.Equal(t, []string{"coffee in athens", "bakery in athens"}, job.Data.Keywords)
+	require.Equal(t, "el", job.Data.Lang)
+	require.Equal(t, "gr", job.Data.Region)
+	require.Equal(t, 5, job.Data.Depth)
+	require.Equal(t, 20*time.Minute, job.Data.MaxTime)
+	require.True(t, job.Data.Email)
+
+	for _, text := range []string{
+		"",
+		"cafe | depth=x",
+		"cafe | maxtime=1m",
+		"cafe | speed=fast",
+	} {
+		_, err := jobFromSlackText(text, "")
+		require.Error(t, err, text)
+	}
+}
+
+func TestSlackCommand(t *testing.T) {
+	allowPrivate(t)
+
+	const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+	posted := make(chan slackResponse, 4)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackResponse
+
+		if err := json.NewDecoder(r.Body).Decode(&msg); err == nil {
+			posted <- msg
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	prefix := slackResponseURLPrefix
+	slackResponseURLPrefix = srv.URL + "/"
+
+	t.Cleanup(func() { slackResponseURLPrefix = prefix })
+
+	repo := newMemRepo()
+	s := &Server{
+		svc:                NewService(repo, "", WithPublicURL("https://scraper.example.com")),
+		slackSigningSecret: secret,
+	}
+
+	command := func(user, text string) slackResponse {
+		form := url.Values{
+			"command":      {"/gmaps"},
+			"team_id":      {"T1"},
+			"user_id":      {user},
+			"text":         {text},
+			"response_url": {srv.URL + "/commands/1"},
+		}.Encode()
+
+		req := httptest.NewRequest(http.MethodPost, "/integrations/slack/command", strings.NewReader(form))
+		req.Header = slackHeader(secret, time.Now().Unix(), form)
+		req.Host = "attacker.example.com"
+		req.Header.Set("X-Forwarded-Proto", "http")
+
+		w := httptest.NewRecorder()
+		s.slackCommand(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var ans slackResponse
+
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&ans))
+
+		return ans
+	}
+
+	command("U1", "coffee in athens")
+	require.Len(t, repo.jobs, 1)
+
+	var job Job
+	for _, j := range repo.jobs {
+		job = j
+	}
+
+	require.Equal(t, &SlackOrigin{TeamID: "T1", UserID: "U1", ResponseURL: srv.URL + "/commands/1"}, job.Data.Slack)
+
+	// the changes of the status are posted to the command
+	job.Status = StatusWorking
+	require.NoError(t, s.svc.Update(context.Background(), &job))
+
+	job.Status = StatusOK
+	job.Stats.Places = 12
+	require.NoError(t, s.svc.Update(context.Background(), &job))
+
+	var texts []string
+
+	for range 2 {
+		select {
+		case msg := <-posted:
+			texts = append(texts, msg.Text)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the status was not posted")
+		}
+	}
+
+	// the posts are sent in the background, in any order
+	slices.Sort(texts)
+
+	require.Contains(t, texts[0], "is *ok* with 12 places. Download: https://scraper.example.com/api/v1/jobs/"+job.ID+"/download")
+	require.Contains(t, texts[1], "is *working*")
+
+	// the status is shown to the user that created the job only, with the
+	// link of the public url
+	ans := command("U1", "status "+job.ID)
+	require.Contains(t, ans.Text, "Download: https://scraper.example.com/api/v1/jobs/"+job.ID+"/download")
+	require.NotContains(t, ans.Text, "attacker")
+
+	require.Equal(t, "Job not found", command("U2", "status "+job.ID).Text)
+}
+
+func TestSetSlack(t *testing.T) {
+	// the origin is set by the slash command only
+	d := JobData{Slack: &SlackOrigin{TeamID: "T1", UserID: "U1", ResponseURL: "https://example.com"}}
+
+	setSlack(context.Background(), &d)
+	require.Nil(t, d.Slack)
+
+	origin := &SlackOrigin{TeamID: "T1", UserID: "U1"}
+
+	setSlack(contextWithSlack(context.Background(), origin), &d)
+	require.Equal(t, origin, d.Slack)
+
+	// a job that was not created from slack is shown to no slack user
+	require.False(t, (*SlackOrigin)(nil).created(origin))
+	require.False(t, (&SlackOrigin{}).created(&SlackOrigin{}))
+	require.True(t, origin.created(&SlackOrigin{TeamID: "T1", UserID: "U1"}))
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite" // sqlite driver
 
+	"github.com/gosom/google-maps-scraper/geocoder"
 	"github.com/gosom/google-maps-scraper/web"
 )
 
@@ -38,9 +42,9 @@ func (repo *repo) Create(ctx context.Context, job *web.Job) error {
 		return err
 	}
 
-	const q = `INSERT INTO jobs (id, name, status, data, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	const q = `INSERT INTO jobs (id, name, status, data, created_at, updated_at, stats) VALUES (?, ?, ?, ?, ?, ?, ?)`
 
-	_, err = repo.db.ExecContext(ctx, q, item.ID, item.Name, item.Status, item.Data, item.CreatedAt, item.UpdatedAt)
+	_, err = repo.db.ExecContext(ctx, q, item.ID, item.Name, item.Status, item.Data, item.CreatedAt, item.UpdatedAt, item.Stats)
 	if err != nil {
 		return err
 	}
@@ -48,6 +52,34 @@ func (repo *repo) Create(ctx context.Context, job *web.Job) error {
 	return nil
 }
 
+var _ web.BulkJobCreator = (*repo)(nil)
+
+func (repo *repo) CreateJobs(ctx context.Context, jobs []web.Job) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const q = `INSERT INTO jobs (id, name, status, data, created_at, updated_at, stats) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	for i := range jobs {
+		item, err := jobToRow(&jobs[i])
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, q, item.ID, item.Name, item.Status, item.Data, item.CreatedAt, item.UpdatedAt, item.Stats); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (repo *repo) Delete(ctx context.Context, id string) error {
 	const q = `DELETE FROM jobs WHERE id = ?`
 
@@ -106,9 +138,9 @@ func (repo *repo) Update(ctx context.Context, job *web.Job) error {
 		return err
 	}
 
-	const q = `UPDATE jobs SET name = ?, status = ?, data = ?, updated_at = ? WHERE id = ?`
+	const q = `UPDATE jobs SET name = ?, status = ?, data = ?, updated_at = ?, stats = ? WHERE id = ?`
 
-	_, err = repo.db.ExecContext(ctx, q, item.Name, item.Status, item.Data, item.UpdatedAt, item.ID)
+	_, err = repo.db.ExecContext(ctx, q, item.Name, item.Status, item.Data, item.UpdatedAt, item.Stats, item.ID)
 
 	return err
 }
@@ -120,7 +152,7 @@ type scannable interface {
 func rowToJob(row scannable) (web.Job, error) {
 	var j job
 
-	err := row.Scan(&j.ID, &j.Name, &j.Status, &j.Data, &j.CreatedAt, &j.UpdatedAt)
+	err := row.Scan(&j.ID, &j.Name, &j.Status, &j.Data, &j.CreatedAt, &j.UpdatedAt, &j.Stats)
 	if err != nil {
 		return web.Job{}, err
 	}
@@ -137,6 +169,11 @@ func rowToJob(row scannable) (web.Job, error) {
 		return web.Job{}, err
 	}
 
+	err = json.Unmarshal([]byte(j.Stats), &ans.Stats)
+	if err != nil {
+		return web.Job{}, err
+	}
+
 	return ans, nil
 }
 
@@ -146,6 +183,11 @@ func jobToRow(item *web.Job) (job, error) {
 		return job{}, err
 	}
 
+	stats, err := json.Marshal(item.Stats)
+	if err != nil {
+		return job{}, err
+	}
+
 	return job{
 		ID:        item.ID,
 		Name:      item.Name,
@@ -153,6 +195,7 @@ func jobToRow(item *web.Job) (job, error) {
 		Data:      string(data),
 		CreatedAt: item.Date.Unix(),
 		UpdatedAt: time.Now().UTC().Unix(),
+		Stats:     string(stats),
 	}, nil
 }
 
@@ -163,6 +206,7 @@ type job struct {
 	Data      string
 	CreatedAt int64
 	UpdatedAt int64
+	Stats     string
 }
 
 func initDatabase(path string) (*sql.DB, error) {
@@ -214,6 +258,1274 @@ func createSchema(db *sql.DB) error {
 			updated_at INT NOT NULL
 		)
 	`)
+	if err != nil {
+		return err
+	}
+
+	if err := addColumn(db, "jobs", "stats", `TEXT NOT NULL DEFAULT '{}'`); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS metrics (
+			created_at INT NOT NULL,
+			jobs_completed INT NOT NULL,
+			jobs_failed INT NOT NULL,
+			jobs_blocked INT NOT NULL,
+			places INT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS metrics_created_at ON metrics (created_at)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if err := addColumn(db, "metrics", "captchas", `INT NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+
+	if err := addColumn(db, "metrics", "captchas_solved", `INT NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+
+	if err := addColumn(db, "metrics", "captcha_cost", `REAL NOT NULL DEFAULT 0`); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS tiles (
+			job_id TEXT NOT NULL,
+			id TEXT NOT NULL,
+			level INT NOT NULL,
+			state TEXT NOT NULL,
+			entries INT NOT NULL,
+			updated_at INT NOT NULL,
+			PRIMARY KEY (job_id, id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS checkpoints (
+			job_id TEXT PRIMARY KEY,
+			data BLOB NOT NULL,
+			updated_at INT NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schedules (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			cron TEXT NOT NULL,
+			data TEXT NOT NULL,
+			last_job_id TEXT NOT NULL,
+			next_run_at INT NOT NULL,
+			created_at INT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS schedule_runs (
+			schedule_id TEXT NOT NULL,
+			job_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			created_at INT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS schedule_runs_schedule_id ON schedule_runs (schedule_id, created_at)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS geocode_cache (
+			provider TEXT NOT NULL,
+			place TEXT NOT NULL,
+			country TEXT NOT NULL,
+			lat REAL NOT NULL,
+			lon REAL NOT NULL,
+			name TEXT NOT NULL,
+			bbox TEXT NOT NULL,
+			created_at INT NOT NULL,
+			PRIMARY KEY (provider, place, country)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS users (
+			id TEXT PRIMARY KEY,
+			username TEXT NOT NULL UNIQUE,
+			role TEXT NOT NULL,
+			password_hash TEXT NOT NULL,
+			created_at INT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			expires_at INT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS sessions_user_id ON sessions (user_id);
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			rate_limit INT NOT NULL,
+			monthly_places INT NOT NULL,
+			created_at INT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS api_keys_user_id ON api_keys (user_id);
+		CREATE TABLE IF NOT EXISTS api_key_usage (
+			key_id TEXT NOT NULL,
+			month TEXT NOT NULL,
+			requests INT NOT NULL,
+			PRIMARY KEY (key_id, month)
+		);
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at INT NOT NULL,
+			user_id TEXT NOT NULL,
+			username TEXT NOT NULL,
+			api_key_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			job_id TEXT NOT NULL,
+			details TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS audit_log_job_id ON audit_log (job_id);
+		CREATE INDEX IF NOT EXISTS audit_log_created_at ON audit_log (created_at);
+		CREATE TABLE IF NOT EXISTS results (
+			job_id TEXT NOT NULL,
+			title TEXT NOT NULL,
+			category TEXT NOT NULL,
+			city TEXT NOT NULL,
+			rating REAL NOT NULL,
+			has_email INT NOT NULL,
+			search_text TEXT NOT NULL,
+			entry TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS results_job_id ON results (job_id);
+		CREATE TABLE IF NOT EXISTS places (
+			key TEXT PRIMARY KEY,
+			job_id TEXT NOT NULL,
+			title TEXT NOT NULL,
+			category TEXT NOT NULL,
+			city TEXT NOT NULL,
+			rating REAL NOT NULL,
+			has_email INT NOT NULL,
+			search_text TEXT NOT NULL,
+			entry TEXT NOT NULL,
+			first_seen INT NOT NULL,
+			last_seen INT NOT NULL,
+			times_seen INT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS places_last_seen ON places (last_seen);
+		CREATE TABLE IF NOT EXISTS job_templates (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			data TEXT NOT NULL,
+			created_at INT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS dead_letters (
+			id TEXT PRIMARY KEY,
+			job_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			url TEXT NOT NULL,
+			error TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			requeued INT NOT NULL,
+			created_at INT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS dead_letters_job_id ON dead_letters (job_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if err := addColumn(db, "users", "external", `TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS users_external ON users (external) WHERE external != ''`)
+
+	return err
+}
+
+var _ web.MetricsRepository = (*repo)(nil)
+
+func (repo *repo) InsertMetrics(ctx context.Context, m *web.MetricsSnapshot) error {
+	const q = `INSERT INTO metrics (created_at, jobs_completed, jobs_failed, jobs_blocked, places, captchas, captchas_solved, captcha_cost) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := repo.db.ExecContext(ctx, q, m.Time.Unix(), m.JobsCompleted, m.JobsFailed, m.JobsBlocked, m.Places, m.Captchas, m.CaptchasSolved, m.CaptchaCost)
+
+	return err
+}
+
+func (repo *repo) SelectMetrics(ctx context.Context, since time.Time) ([]web.MetricsSnapshot, error) {
+	const q = `SELECT created_at, jobs_completed, jobs_failed, jobs_blocked, places, captchas, captchas_solved, captcha_cost FROM metrics WHERE created_at >= ? ORDER BY created_at`
+
+	rows, err := repo.db.QueryContext(ctx, q, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.MetricsSnapshot
+
+	for rows.Next() {
+		var (
+			m         web.MetricsSnapshot
+			createdAt int64
+		)
+
+		if err := rows.Scan(&createdAt, &m.JobsCompleted, &m.JobsFailed, &m.JobsBlocked, &m.Places, &m.Captchas, &m.CaptchasSolved, &m.CaptchaCost); err != nil {
+			return nil, err
+		}
+
+		m.Time = time.Unix(createdAt, 0).UTC()
+
+		ans = append(ans, m)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) DeleteMetrics(ctx context.Context, before time.Time) error {
+	const q = `DELETE FROM metrics WHERE created_at < ?`
+
+	_, err := repo.db.ExecContext(ctx, q, before.Unix())
+
+	return err
+}
+
+var _ web.TileRepository = (*repo)(nil)
+
+func (repo *repo) SaveTiles(ctx context.Context, jobID string, tiles []web.TileProgress) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const q = `INSERT INTO tiles (job_id, id, level, state, entries, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (job_id, id) DO UPDATE SET level = excluded.level, state = excluded.state, entries = excluded.entries, updated_at = excluded.updated_at`
+
+	for i := range tiles {
+		t := &tiles[i]
+
+		_, err := tx.ExecContext(ctx, q, jobID, t.ID, t.Level, t.State, t.Entries, t.UpdatedAt.Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *repo) SelectTiles(ctx context.Context, jobID string) ([]web.TileProgress, error) {
+	const q = `SELECT id, level, state, entries, updated_at FROM tiles WHERE job_id = ? ORDER BY id`
+
+	rows, err := repo.db.QueryContext(ctx, q, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.TileProgress
+
+	for rows.Next() {
+		var (
+			t         web.TileProgress
+			updatedAt int64
+		)
+
+		if err := rows.Scan(&t.ID, &t.Level, &t.State, &t.Entries, &updatedAt); err != nil {
+			return nil, err
+		}
+
+		t.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+
+		ans = append(ans, t)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) DeleteTiles(ctx context.Context, jobID string) error {
+	const q = `DELETE FROM tiles WHERE job_id = ?`
+
+	_, err := repo.db.ExecContext(ctx, q, jobID)
+
+	return err
+}
+
+var _ web.CheckpointRepository = (*repo)(nil)
+
+func (repo *repo) SaveCheckpoint(ctx context.Context, jobID string, data []byte) error {
+	const q = `INSERT INTO checkpoints (job_id, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (job_id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`
+
+	_, err := repo.db.ExecContext(ctx, q, jobID, data, time.Now().UTC().Unix())
+
+	return err
+}
+
+func (repo *repo) Checkpoint(ctx context.Context, jobID string) ([]byte, error) {
+	const q = `SELECT data FROM checkpoints WHERE job_id = ?`
+
+	var data []byte
+
+	err := repo.db.QueryRowContext(ctx, q, jobID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func (repo *repo) DeleteCheckpoint(ctx context.Context, jobID string) error {
+	const q = `DELETE FROM checkpoints WHERE job_id = ?`
+
+	_, err := repo.db.ExecContext(ctx, q, jobID)
+
+	return err
+}
+
+var _ web.ScheduleRepository = (*repo)(nil)
+
+func (repo *repo) CreateSchedule(ctx context.Context, sc *web.Schedule) error {
+	data, err := json.Marshal(sc.Data)
+	if err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO schedules (id, name, cron, data, last_job_id, next_run_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = repo.db.ExecContext(ctx, q, sc.ID, sc.Name, sc.Cron, string(data), sc.LastJobID, sc.NextRun.Unix(), sc.CreatedAt.Unix())
+
+	return err
+}
+
+func (repo *repo) GetSchedule(ctx context.Context, id string) (web.Schedule, error) {
+	const q = `SELECT id, name, cron, data, last_job_id, next_run_at, created_at FROM schedules WHERE id = ?`
+
+	return rowToSchedule(repo.db.QueryRowContext(ctx, q, id))
+}
+
+func (repo *repo) SelectSchedules(ctx context.Context) ([]web.Schedule, error) {
+	const q = `SELECT id, name, cron, data, last_job_id, next_run_at, created_at FROM schedules ORDER BY created_at`
+
+	rows, err := repo.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.Schedule
+
+	for rows.Next() {
+		sc, err := rowToSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, sc)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) UpdateSchedule(ctx context.Context, sc *web.Schedule) error {
+	data, err := json.Marshal(sc.Data)
+	if err != nil {
+		return err
+	}
+
+	const q = `UPDATE schedules SET name = ?, cron = ?, data = ?, last_job_id = ?, next_run_at = ? WHERE id = ?`
+
+	_, err = repo.db.ExecContext(ctx, q, sc.Name, sc.Cron, string(data), sc.LastJobID, sc.NextRun.Unix(), sc.ID)
+
+	return err
+}
+
+func (repo *repo) ClaimScheduleRun(ctx context.Context, id string, prev, next time.Time) (bool, error) {
+	const q = `UPDATE schedules SET next_run_at = ? WHERE id = ? AND next_run_at = ?`
+
+	res, err := repo.db.ExecContext(ctx, q, next.Unix(), id, prev.Unix())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return n == 1, err
+}
+
+func (repo *repo) DeleteSchedule(ctx context.Context, id string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schedule_runs WHERE schedule_id = ?`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schedules WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *repo) InsertScheduleRun(ctx context.Context, run *web.ScheduleRun) error {
+	const q = `INSERT INTO schedule_runs (schedule_id, job_id, status, reason, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := repo.db.ExecContext(ctx, q, run.ScheduleID, run.JobID, run.Status, run.Reason, run.Time.Unix())
+
+	return err
+}
+
+func (repo *repo) SelectScheduleRuns(ctx context.Context, scheduleID string, limit int) ([]web.ScheduleRun, error) {
+	const q = `SELECT schedule_id, job_id, status, reason, created_at FROM schedule_runs WHERE schedule_id = ? ORDER BY created_at DESC, rowid DESC LIMIT ?`
+
+	rows, err := repo.db.QueryContext(ctx, q, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.ScheduleRun
+
+	for rows.Next() {
+		var (
+			run       web.ScheduleRun
+			createdAt int64
+		)
+
+		if err := rows.Scan(&run.ScheduleID, &run.JobID, &run.Status, &run.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+
+		run.Time = time.Unix(createdAt, 0).UTC()
+
+		ans = append(ans, run)
+	}
+
+	return ans, rows.Err()
+}
+
+func rowToSchedule(row scannable) (web.Schedule, error) {
+	var (
+		sc        web.Schedule
+		data      string
+		nextRun   int64
+		createdAt int64
+	)
+
+	if err := row.Scan(&sc.ID, &sc.Name, &sc.Cron, &data, &sc.LastJobID, &nextRun, &createdAt); err != nil {
+		return web.Schedule{}, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &sc.Data); err != nil {
+		return web.Schedule{}, err
+	}
+
+	sc.NextRun = time.Unix(nextRun, 0).UTC()
+	sc.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+	return sc, nil
+}
+
+var _ geocoder.Cache = (*repo)(nil)
+
+func (repo *repo) GetGeocode(ctx context.Context, key geocoder.CacheKey) (geocoder.Result, bool, error) {
+	const q = `SELECT lat, lon, name, bbox FROM geocode_cache WHERE provider = ? AND place = ? AND country = ?`
+
+	var (
+		res  geocoder.Result
+		bbox string
+	)
+
+	err := repo.db.QueryRowContext(ctx, q, key.Provider, key.Place, key.Country).Scan(&res.Lat, &res.Lon, &res.Name, &bbox)
+	if errors.Is(err, sql.ErrNoRows) {
+		return geocoder.Result{}, false, nil
+	}
+
+	if err != nil {
+		return geocoder.Result{}, false, err
+	}
+
+	if err := json.Unmarshal([]byte(bbox), &res.BoundingBox); err != nil {
+		return geocoder.Result{}, false, err
+	}
+
+	return res, true, nil
+}
+
+func (repo *repo) SaveGeocode(ctx context.Context, key geocoder.CacheKey, res geocoder.Result) error {
+	bbox, err := json.Marshal(res.BoundingBox)
+	if err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO geocode_cache (provider, place, country, lat, lon, name, bbox, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (provider, place, country) DO UPDATE SET lat = excluded.lat, lon = excluded.lon, name = excluded.name, bbox = excluded.bbox, created_at = excluded.created_at`
+
+	_, err = repo.db.ExecContext(ctx, q, key.Provider, key.Place, key.Country, res.Lat, res.Lon, res.Name, string(bbox), time.Now().UTC().Unix())
+
+	return err
+}
+
+var _ web.UserRepository = (*repo)(nil)
+
+const userColumns = `id, username, role, password_hash, external, created_at`
+
+func (repo *repo) CreateUser(ctx context.Context, u *web.User) error {
+	const q = `INSERT INTO users (` + userColumns + `) VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := repo.db.ExecContext(ctx, q, u.ID, u.Username, u.Role, u.PasswordHash, u.External, u.CreatedAt.Unix())
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return fmt.Errorf("user %s: %w", u.Username, web.ErrAlreadyExists)
+	}
+
+	return err
+}
+
+func (repo *repo) GetUser(ctx context.Context, id string) (web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+	return rowToUser(repo.db.QueryRowContext(ctx, q, id))
+}
+
+func (repo *repo) GetUserByUsername(ctx context.Context, username string) (web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM users WHERE username = ?`
+
+	return rowToUser(repo.db.QueryRowContext(ctx, q, username))
+}
+
+func (repo *repo) GetUserByExternal(ctx context.Context, external string) (web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM users WHERE external = ? AND external != ''`
+
+	return rowToUser(repo.db.QueryRowContext(ctx, q, external))
+}
+
+func (repo *repo) SelectUsers(ctx context.Context) ([]web.User, error) {
+	const q = `SELECT ` + userColumns + ` FROM users ORDER BY username`
+
+	rows, err := repo.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.User
+
+	for rows.Next() {
+		u, err := rowToUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, u)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) UpdateUser(ctx context.Context, u *web.User) error {
+	const q = `UPDATE users SET username = ?, role = ?, password_hash = ? WHERE id = ?`
+
+	_, err := repo.db.ExecContext(ctx, q, u.Username, u.Role, u.PasswordHash, u.ID)
+
+	return err
+}
+
+func (repo *repo) DeleteUser(ctx context.Context, id string) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM api_keys WHERE user_id = ?`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *repo) CreateSession(ctx context.Context, session *web.Session) error {
+	const q = `INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)`
+
+	_, err := repo.db.ExecContext(ctx, q, session.TokenHash, session.UserID, session.ExpiresAt.Unix())
+
+	return err
+}
+
+func (repo *repo) GetSession(ctx context.Context, tokenHash string) (web.Session, error) {
+	const q = `SELECT token_hash, user_id, expires_at FROM sessions WHERE token_hash = ?`
+
+	var (
+		session   web.Session
+		expiresAt int64
+	)
+
+	err := repo.db.QueryRowContext(ctx, q, tokenHash).Scan(&session.TokenHash, &session.UserID, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return web.Session{}, web.ErrNotFound
+	}
+
+	if err != nil {
+		return web.Session{}, err
+	}
+
+	session.ExpiresAt = time.Unix(expiresAt, 0).UTC()
+
+	return session, nil
+}
+
+func (repo *repo) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+
+	return err
+}
+
+func (repo *repo) DeleteExpiredSessions(ctx context.Context, now time.Time) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < ?`, now.Unix())
+
+	return err
+}
+
+func rowToUser(row scannable) (web.User, error) {
+	var (
+		u         web.User
+		createdAt int64
+	)
+
+	err := row.Scan(&u.ID, &u.Username, &u.Role, &u.PasswordHash, &u.External, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return web.User{}, web.ErrNotFound
+	}
+
+	if err != nil {
+		return web.User{}, err
+	}
+
+	u.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+	return u, nil
+}
+
+var _ web.APIKeyRepository = (*repo)(nil)
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, rate_limit, monthly_places, created_at`
+
+func (repo *repo) CreateAPIKey(ctx context.Context, key *web.APIKey) error {
+	const q = `INSERT INTO api_keys (` + apiKeyColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := repo.db.ExecContext(ctx, q, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.RateLimit, key.MonthlyPlaces, key.CreatedAt.Unix())
+
+	return err
+}
+
+func (repo *repo) GetAPIKey(ctx context.Context, id string) (web.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ?`
+
+	return rowToAPIKey(repo.db.QueryRowContext(ctx, q, id))
+}
+
+func (repo *repo) GetAPIKeyByHash(ctx context.Context, keyHash string) (web.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = ?`
+
+	return rowToAPIKey(repo.db.QueryRowContext(ctx, q, keyHash))
+}
+
+func (repo *repo) SelectAPIKeys(ctx context.Context, userID string) ([]web.APIKey, error) {
+	const q = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE ? = '' OR user_id = ? ORDER BY created_at`
+
+	rows, err := repo.db.QueryContext(ctx, q, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.APIKey
+
+	for rows.Next() {
+		key, err := rowToAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, key)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) DeleteAPIKey(ctx context.Context, id string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
+
+	return err
+}
+
+func (repo *repo) IncrAPIKeyRequests(ctx context.Context, keyID, month string) error {
+	const q = `INSERT INTO api_key_usage (key_id, month, requests) VALUES (?, ?, 1)
+		ON CONFLICT (key_id, month) DO UPDATE SET requests = requests + 1`
+
+	_, err := repo.db.ExecContext(ctx, q, keyID, month)
+
+	return err
+}
+
+func (repo *repo) APIKeyRequests(ctx context.Context, keyID, month string) (int, error) {
+	const q = `SELECT requests FROM api_key_usage WHERE key_id = ? AND month = ?`
+
+	var ans int
+
+	err := repo.db.QueryRowContext(ctx, q, keyID, month).Scan(&ans)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return ans, err
+}
+
+func rowToAPIKey(row scannable) (web.APIKey, error) {
+	var (
+		key       web.APIKey
+		createdAt int64
+	)
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.RateLimit, &key.MonthlyPlaces, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return web.APIKey{}, web.ErrNotFound
+	}
+
+	if err != nil {
+		return web.APIKey{}, err
+	}
+
+	key.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+	return key, nil
+}
+
+var _ web.AuditRepository = (*repo)(nil)
+
+func (repo *repo) CreateAuditEntry(ctx context.Context, entry *web.AuditEntry) error {
+	const q = `INSERT INTO audit_log (created_at, user_id, username, api_key_id, action, job_id, details) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	res, err := repo.db.ExecContext(ctx, q, entry.Time.Unix(), entry.UserID, entry.Username, entry.APIKeyID, entry.Action, entry.JobID, entry.Details)
+	if err != nil {
+		return err
+	}
+
+	entry.ID, err = res.LastInsertId()
+
+	return err
+}
+
+func (repo *repo) SelectAuditEntries(ctx context.Context, params web.AuditParams) ([]web.AuditEntry, error) {
+	q := `SELECT id, created_at, user_id, username, api_key_id, action, job_id, details FROM audit_log WHERE 1 = 1`
+
+	var args []any
+
+	for _, f := range []struct {
+		column string
+		value  string
+	}{
+		{"job_id", params.JobID},
+		{"user_id", params.UserID},
+		{"action", params.Action},
+	} {
+		if f.value != "" {
+			q += ` AND ` + f.column + ` = ?`
+
+			args = append(args, f.value)
+		}
+	}
+
+	if !params.Since.IsZero() {
+		q += ` AND created_at >= ?`
+
+		args = append(args, params.Since.Unix())
+	}
+
+	if !params.Until.IsZero() {
+		q += ` AND created_at < ?`
+
+		args = append(args, params.Until.Unix())
+	}
+
+	q += ` ORDER BY id DESC`
+
+	if params.Limit > 0 {
+		q += ` LIMIT ?`
+
+		args = append(args, params.Limit)
+	}
+
+	rows, err := repo.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.AuditEntry
+
+	for rows.Next() {
+		var (
+			entry     web.AuditEntry
+			createdAt int64
+		)
+
+		if err := rows.Scan(&entry.ID, &createdAt, &entry.UserID, &entry.Username, &entry.APIKeyID, &entry.Action, &entry.JobID, &entry.Details); err != nil {
+			return nil, err
+		}
+
+		entry.Time = time.Unix(createdAt, 0).UTC()
+
+		ans = append(ans, entry)
+	}
+
+	return ans, rows.Err()
+}
+
+var _ web.ResultRepository = (*repo)(nil)
+
+func (repo *repo) InsertResults(ctx context.Context, results []web.Result) error {
+	const q = `INSERT INTO results (job_id, title, category, city, rating, has_email, search_text, entry) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for i := range results {
+		r := &results[i]
+
+		if _, err := tx.ExecContext(ctx, q, r.JobID, r.Title, r.Category, r.City, r.Rating, r.HasEmail, r.Text, string(r.Entry)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, with \ as the escape.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// resultsWhere returns the WHERE clause of the results and the places
+// matching params, and its arguments.
+func resultsWhere(params *web.ResultParams) (string, []any) {
+	where := ` WHERE 1 = 1`
+
+	var args []any
+
+	if params.JobID != "" {
+		where += ` AND job_id = ?`
+
+		args = append(args, params.JobID)
+	}
+
+	if params.Query != "" {
+		where += ` AND search_text LIKE ? ESCAPE '\'`
+
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(params.Query))+"%")
+	}
+
+	if params.Category != "" {
+		where += ` AND category = ? COLLATE NOCASE`
+
+		args = append(args, params.Category)
+	}
+
+	if params.City != "" {
+		where += ` AND city = ? COLLATE NOCASE`
+
+		args = append(args, params.City)
+	}
+
+	if params.MinRating > 0 {
+		where += ` AND rating >= ?`
+
+		args = append(args, params.MinRating)
+	}
+
+	if params.HasEmail != nil {
+		where += ` AND has_email = ?`
+
+		args = append(args, *params.HasEmail)
+	}
+
+	return where, args
+}
+
+func (repo *repo) SelectResults(ctx context.Context, params web.ResultParams) ([]web.Result, int, error) {
+	where, args := resultsWhere(&params)
+
+	var total int
+
+	if err := repo.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM results`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	q := `SELECT job_id, title, category, city, rating, has_email, search_text, entry FROM results` + where + ` ORDER BY rowid`
+
+	if params.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+
+		args = append(args, params.Limit, params.Offset)
+	}
+
+	rows, err := repo.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.Result
+
+	for rows.Next() {
+		var (
+			r     web.Result
+			entry string
+		)
+
+		if err := rows.Scan(&r.JobID, &r.Title, &r.Category, &r.City, &r.Rating, &r.HasEmail, &r.Text, &entry); err != nil {
+			return nil, 0, err
+		}
+
+		r.Entry = []byte(entry)
+
+		ans = append(ans, r)
+	}
+
+	return ans, total, rows.Err()
+}
+
+func (repo *repo) DeleteResults(ctx context.Context, jobID string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM results WHERE job_id = ?`, jobID)
+
+	return err
+}
+
+var _ web.PlaceRepository = (*repo)(nil)
+
+func (repo *repo) UpsertPlaces(ctx context.Context, places []web.Place) error {
+	const q = `INSERT INTO places (key, job_id, title, category, city, rating, has_email, search_text, entry, first_seen, last_seen, times_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			job_id = excluded.job_id,
+			title = excluded.title,
+			category = excluded.category,
+			city = excluded.city,
+			rating = excluded.rating,
+			has_email = excluded.has_email,
+			search_text = excluded.search_text,
+			entry = excluded.entry,
+			last_seen = excluded.last_seen,
+			times_seen = places.times_seen + excluded.times_seen`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for i := range places {
+		p := &places[i]
+
+		_, err := tx.ExecContext(ctx, q, p.Key, p.JobID, p.Title, p.Category, p.City, p.Rating, p.HasEmail, p.Text, string(p.Entry),
+			p.FirstSeen.Unix(), p.LastSeen.Unix(), p.TimesSeen)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *repo) SelectPlaces(ctx context.Context, params web.PlaceParams) ([]web.Place, int, error) {
+	params.JobID = ""
+
+	where, args := resultsWhere(&params.ResultParams)
+
+	if !params.SeenSince.IsZero() {
+		where += ` AND last_seen >= ?`
+
+		args = append(args, params.SeenSince.Unix())
+	}
+
+	var total int
+
+	if err := repo.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM places`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	q := `SELECT key, job_id, title, category, city, rating, has_email, search_text, entry, first_seen, last_seen, times_seen FROM places` +
+		where + ` ORDER BY last_seen DESC, key`
+
+	if params.Limit > 0 {
+		q += ` LIMIT ? OFFSET ?`
+
+		args = append(args, params.Limit, params.Offset)
+	}
+
+	rows, err := repo.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.Place
+
+	for rows.Next() {
+		var (
+			p                   web.Place
+			entry               string
+			firstSeen, lastSeen int64
+		)
+
+		err := rows.Scan(&p.Key, &p.JobID, &p.Title, &p.Category, &p.City, &p.Rating, &p.HasEmail, &p.Text, &entry,
+			&firstSeen, &lastSeen, &p.TimesSeen)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		p.Entry = []byte(entry)
+		p.FirstSeen = time.Unix(firstSeen, 0).UTC()
+		p.LastSeen = time.Unix(lastSeen, 0).UTC()
+
+		ans = append(ans, p)
+	}
+
+	return ans, total, rows.Err()
+}
+
+var _ web.TemplateRepository = (*repo)(nil)
+
+func (repo *repo) CreateTemplate(ctx context.Context, t *web.JobTemplate) error {
+	data, err := json.Marshal(t.Data)
+	if err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO job_templates (id, name, data, created_at) VALUES (?, ?, ?, ?)`
+
+	_, err = repo.db.ExecContext(ctx, q, t.ID, t.Name, string(data), t.CreatedAt.Unix())
+
+	return err
+}
+
+func (repo *repo) GetTemplate(ctx context.Context, id string) (web.JobTemplate, error) {
+	const q = `SELECT id, name, data, created_at FROM job_templates WHERE id = ?`
+
+	return rowToTemplate(repo.db.QueryRowContext(ctx, q, id))
+}
+
+func (repo *repo) SelectTemplates(ctx context.Context) ([]web.JobTemplate, error) {
+	const q = `SELECT id, name, data, created_at FROM job_templates ORDER BY name, created_at`
+
+	rows, err := repo.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.JobTemplate
+
+	for rows.Next() {
+		t, err := rowToTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, t)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) DeleteTemplate(ctx context.Context, id string) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM job_templates WHERE id = ?`, id)
+
+	return err
+}
+
+func rowToTemplate(row scannable) (web.JobTemplate, error) {
+	var (
+		t         web.JobTemplate
+		data      string
+		createdAt int64
+	)
+
+	if err := row.Scan(&t.ID, &t.Name, &data, &createdAt); err != nil {
+		return web.JobTemplate{}, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &t.Data); err != nil {
+		return web.JobTemplate{}, err
+	}
+
+	t.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+	return t, nil
+}
+
+var _ web.DeadLetterRepository = (*repo)(nil)
+
+func (repo *repo) CreateDeadLetters(ctx context.Context, letters []web.DeadLetter) error {
+	const q = `INSERT INTO dead_letters (id, job_id, type, url, error, payload, requeued, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for i := range letters {
+		l := &letters[i]
+
+		_, err := tx.ExecContext(ctx, q, l.ID, l.JobID, l.Type, l.URL, l.Error, l.Payload, l.Requeued, l.CreatedAt.Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *repo) SelectDeadLetters(ctx context.Context, jobID string) ([]web.DeadLetter, error) {
+	const q = `SELECT id, job_id, type, url, error, payload, requeued, created_at FROM dead_letters WHERE job_id = ? ORDER BY created_at, rowid`
+
+	rows, err := repo.db.QueryContext(ctx, q, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ans []web.DeadLetter
+
+	for rows.Next() {
+		var (
+			l         web.DeadLetter
+			createdAt int64
+		)
+
+		if err := rows.Scan(&l.ID, &l.JobID, &l.Type, &l.URL, &l.Error, &l.Payload, &l.Requeued, &createdAt); err != nil {
+			return nil, err
+		}
+
+		l.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+		ans = append(ans, l)
+	}
+
+	return ans, rows.Err()
+}
+
+func (repo *repo) RequeueDeadLetters(ctx context.Context, jobID string, ids []string) (int, error) {
+	where, args := deadLettersWhere(jobID, ids)
+
+	res, err := repo.db.ExecContext(ctx, `UPDATE dead_letters SET requeued = 1`+where+` AND requeued = 0`, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
+
+func (repo *repo) DeleteDeadLetters(ctx context.Context, jobID string, ids []string) error {
+	where, args := deadLettersWhere(jobID, ids)
+
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM dead_letters`+where, args...)
+
+	return err
+}
+
+// deadLettersWhere returns the where clause of the dead letters ids of the
+// job jobID, all of them when ids is empty.
+func deadLettersWhere(jobID string, ids []string) (string, []any) {
+	where := ` WHERE job_id = ?`
+	args := []any{jobID}
+
+	if len(ids) > 0 {
+		where += ` AND id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	return where, args
+}
+
+// addColumn adds a column to the table of a database created by an older version.
+func addColumn(db *sql.DB, table, column, definition string) error {
+	var exists bool
+
+	const q = `SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`
+
+	if err := db.QueryRow(q, table, column).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	_, err := db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
 
 	return err
 }
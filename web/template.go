@@ -0,0 +1,443 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobTemplate is a named set of job settings, e.g. the language, the depth,
+// the filters and the proxy group of a recurring crawl, the jobs are created
+// from instead of entering them again.
+type JobTemplate struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Data are the settings of the jobs, the keywords are optional
+	Data      JobData   `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TemplateRepository stores the job templates.
+type TemplateRepository interface {
+	CreateTemplate(context.Context, *JobTemplate) error
+	GetTemplate(context.Context, string) (JobTemplate, error)
+	// SelectTemplates returns the templates by name.
+	SelectTemplates(context.Context) ([]JobTemplate, error)
+	DeleteTemplate(context.Context, string) error
+}
+
+// ErrTemplatesNotSupported is returned when the repository does not store
+// job templates.
+var ErrTemplatesNotSupported = errors.New("job templates are not supported")
+
+// WithTemplates keeps the job templates in repo.
+func WithTemplates(repo TemplateRepository) ServiceOption {
+	return func(s *Service) {
+		s.templateRepo = repo
+	}
+}
+
+// Validate checks the template and the jobs it creates.
+func (t *JobTemplate) Validate() error {
+	if t.Name == "" {
+		return errors.New("missing name")
+	}
+
+	if len(t.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+
+	if t.Data.DependsOn != "" || t.Data.Transform != "" {
+		return errors.New("templates cannot depend on a job or transform places")
+	}
+
+	data := t.Data
+
+	// the keywords are given with each job
+	if len(data.Keywords) == 0 {
+		data.Keywords = []string{t.Name}
+	}
+
+	return data.Validate()
+}
+
+// clone returns a new pending job with the settings of j, named name or
+// after j when it is empty. It belongs to the user that creates it.
+func (j *Job) clone(name string) Job {
+	if name == "" {
+		name = j.Name + " (copy)"
+	}
+
+	ans := Job{
+		ID:     uuid.New().String(),
+		Name:   name,
+		Date:   time.Now().UTC(),
+		Status: StatusPending,
+		Data:   j.Data,
+	}
+
+	ans.Data.Owner = ""
+	ans.Data.APIKeyID = ""
+	ans.Data.Slack = nil
+	ans.Data.Pinned = false
+
+	return ans
+}
+
+// TemplatesEnabled reports whether the job templates are stored.
+func (s *Service) TemplatesEnabled() bool {
+	return s.templateRepo != nil
+}
+
+// CreateTemplate validates and stores a new template.
+func (s *Service) CreateTemplate(ctx context.Context, t *JobTemplate) error {
+	if s.templateRepo == nil {
+		return ErrTemplatesNotSupported
+	}
+
+	t.Name = strings.TrimSpace(t.Name)
+
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	t.Data.Owner = ""
+	t.Data.APIKeyID = ""
+	t.Data.Slack = nil
+	t.Data.Pinned = false
+
+	setOwner(ctx, &t.Data)
+
+	t.ID = uuid.New().String()
+	t.CreatedAt = time.Now().UTC()
+
+	return s.templateRepo.CreateTemplate(ctx, t)
+}
+
+// Templates returns the templates the user of ctx may see.
+func (s *Service) Templates(ctx context.Context) ([]JobTemplate, error) {
+	if s.templateRepo == nil {
+		return nil, ErrTemplatesNotSupported
+	}
+
+	templates, err := s.templateRepo.SelectTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := templates[:0]
+
+	for i := range templates {
+		if canAccess(ctx, &templates[i].Data) {
+			ans = append(ans, templates[i])
+		}
+	}
+
+	return ans, nil
+}
+
+// GetTemplate returns the template id, ErrNotFound when the user of ctx may
+// not see it.
+func (s *Service) GetTemplate(ctx context.Context, id string) (JobTemplate, error) {
+	if s.templateRepo == nil {
+		return JobTemplate{}, ErrTemplatesNotSupported
+	}
+
+	t, err := s.templateRepo.GetTemplate(ctx, id)
+	if err != nil {
+		return JobTemplate{}, err
+	}
+
+	if !canAccess(ctx, &t.Data) {
+		return JobTemplate{}, ErrNotFound
+	}
+
+	return t, nil
+}
+
+// DeleteTemplate deletes a template. The jobs created from it are kept.
+func (s *Service) DeleteTemplate(ctx context.Context, id string) error {
+	if _, err := s.GetTemplate(ctx, id); err != nil {
+		return err
+	}
+
+	return s.templateRepo.DeleteTemplate(ctx, id)
+}
+
+// SettingsJSON returns the settings of the template as the create job
+// request, with the max time in seconds, to fill the form of the UI.
+//
+//nolint:gocritic // this is used in template
+func (t JobTemplate) SettingsJSON() string {
+	data := t.Data
+	data.MaxTime /= time.Second
+
+	b, _ := json.Marshal(data)
+
+	return string(b)
+}
+
+// applyTemplate returns the create job request body with the settings of
+// the template id for the fields it does not set.
+func (s *Server) applyTemplate(ctx context.Context, id string, body []byte) (apiScrapeRequest, error) {
+	t, err := s.svc.GetTemplate(ctx, id)
+	if errors.Is(err, ErrTemplatesNotSupported) {
+		return apiScrapeRequest{}, err
+	}
+
+	if err != nil {
+		return apiScrapeRequest{}, fmt.Errorf("template %s not found", id)
+	}
+
+	req := apiScrapeRequest{JobData: t.Data}
+
+	req.Owner = ""
+	req.MaxTime /= time.Second
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return apiScrapeRequest{}, err
+	}
+
+	return req, nil
+}
+
+type apiTemplateRequest struct {
+	Name string `json:"name"`
+	JobData
+}
+
+type apiTemplateResponse struct {
+	ID string `json:"id"`
+}
+
+func (s *Server) apiCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req apiTemplateRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	t := JobTemplate{
+		Name: req.Name,
+		Data: req.JobData,
+	}
+
+	// convert to seconds
+	t.Data.MaxTime *= time.Second
+
+	err := t.Validate()
+	if err == nil {
+		err = s.svc.ValidateServerSettings(&t.Data)
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if err := s.svc.CreateTemplate(r.Context(), &t); err != nil {
+		renderTemplateError(w, err)
+
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, apiTemplateResponse{ID: t.ID})
+}
+
+func (s *Server) apiGetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := s.svc.Templates(r.Context())
+	if err != nil {
+		renderTemplateError(w, err)
+
+		return
+	}
+
+	if templates == nil {
+		templates = []JobTemplate{}
+	}
+
+	renderJSON(w, http.StatusOK, templates)
+}
+
+func (s *Server) apiGetTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.templateFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	renderJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) apiDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.templateFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.svc.DeleteTemplate(r.Context(), t.ID); err != nil {
+		renderTemplateError(w, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// templateFromRequest returns the template of the id of the request. It
+// renders the error and returns false when there is none.
+func (s *Server) templateFromRequest(w http.ResponseWriter, r *http.Request) (JobTemplate, bool) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return JobTemplate{}, false
+	}
+
+	t, err := s.svc.GetTemplate(r.Context(), id.String())
+	if errors.Is(err, ErrTemplatesNotSupported) {
+		renderTemplateError(w, err)
+
+		return JobTemplate{}, false
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return JobTemplate{}, false
+	}
+
+	return t, true
+}
+
+func renderTemplateError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, ErrTemplatesNotSupported) {
+		code = http.StatusNotImplemented
+	}
+
+	apiError := apiError{
+		Code:    code,
+		Message: err.Error(),
+	}
+
+	renderJSON(w, code, apiError)
+}
+
+type apiCloneRequest struct {
+	// Name of the new job, the name of the job with " (copy)" when empty
+	Name string `json:"name"`
+}
+
+// apiCloneJob creates a pending job with the settings of the job of the
+// request.
+func (s *Server) apiCloneJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	var req apiCloneRequest
+
+	// the body is optional
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	job, err := s.svc.Get(r.Context(), id.String())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	newJob := job.clone(strings.TrimSpace(req.Name))
+
+	err = newJob.Validate()
+	if err == nil {
+		err = s.svc.ValidateServerSettings(&newJob.Data)
+	}
+
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if err := s.svc.Create(r.Context(), &newJob); err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrInvalidDependency):
+			code = http.StatusUnprocessableEntity
+		case errors.Is(err, ErrQuotaExceeded):
+			code = http.StatusTooManyRequests
+		}
+
+		apiError := apiError{
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, apiError)
+
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, apiScrapeResponse{
+		ID:       newJob.ID,
+		Warnings: newJob.Data.Warnings(),
+	})
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memTemplates keeps the job templates in memory.
+type memTemplates struct {
+	templates map[string]JobTemplate
+}
+
+func (m *memTemplates) CreateTemplate(_ context.Context, t *JobTemplate) error {
+	m.templates[t.ID] = *t
+
+	return nil
+}
+
+func (m *memTemplates) GetTemplate(_ context.Context, id string) (JobTemplate, error) {
+	t, ok := m.templates[id]
+	if !ok {
+		return JobTemplate{}, ErrNotFound
+	}
+
+	return t, nil
+}
+
+func (m *memTemplates) SelectTemplates(context.Context) ([]JobTemplate, error) {
+	var ans []JobTemplate
+
+	for _, t := range m.templates {
+		ans = append(ans, t)
+	}
+
+	slices.SortFunc(ans, func(a, b JobTemplate) int { return strings.Compare(a.Name, b.Name) })
+
+	return ans, nil
+}
+
+func (m *memTemplates) DeleteTemplate(_ context.Context, id string) error {
+	delete(m.templates, id)
+
+	return nil
+}
+
+func TestJobTemplateValidate(t *testing.T) {
+	data := testJobData()
+	data.Keywords = nil
+
+	// the keywords are given with each job
+	require.NoError(t, (&JobTemplate{Name: "berlin cafes", Data: data}).Validate())
+
+	require.ErrorContains(t, (&JobTemplate{Data: data}).Validate(), "missing name")
+
+	dependent := data
+	dependent.DependsOn = "job"
+
+	require.Error(t, (&JobTemplate{Name: "enrich", Data: dependent}).Validate())
+
+	invalid := data
+	invalid.Lang = ""
+
+	require.ErrorContains(t, (&JobTemplate{Name: "cafes", Data: invalid}).Validate(), "missing lang")
+}
+
+func TestJobClone(t *testing.T) {
+	data := testJobData()
+	data.Owner = "alice"
+	data.APIKeyID = "key"
+	data.Pinned = true
+
+	job := Job{ID: "1", Name: "cafes", Status: StatusOK, Data: data}
+
+	clone := job.clone("")
+	require.NotEqual(t, job.ID, clone.ID)
+	require.Equal(t, "cafes (copy)", clone.Name)
+	require.Equal(t, StatusPending, clone.Status)
+	require.Equal(t, data.Keywords, clone.Data.Keywords)
+	require.Empty(t, clone.Data.Owner)
+	require.Empty(t, clone.Data.APIKeyID)
+	require.False(t, clone.Data.Pinned)
+
+	require.Equal(t, "bars", job.clone("bars").Name)
+}
+
+func TestTemplates(t *testing.T) {
+	svc := NewService(newMemRepo(), "", WithTemplates(&memTemplates{templates: map[string]JobTemplate{}}))
+
+	data := testJobData()
+	data.Owner = "bob"
+
+	tmpl := JobTemplate{Name: " cafes ", Data: data}
+
+	// the template belongs to the user that creates it
+	require.NoError(t, svc.CreateTemplate(userContext(testAlice), &tmpl))
+	require.Equal(t, "cafes", tmpl.Name)
+	require.Equal(t, "alice", tmpl.Data.Owner)
+
+	templates, err := svc.Templates(userContext(testAlice))
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+
+	templates, err = svc.Templates(userContext(testBob))
+	require.NoError(t, err)
+	require.Empty(t, templates)
+
+	_, err = svc.GetTemplate(userContext(testBob), tmpl.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.ErrorIs(t, svc.DeleteTemplate(userContext(testBob), tmpl.ID), ErrNotFound)
+
+	// the fields of the request override the ones of the template
+	s := &Server{svc: svc}
+
+	req, err := s.applyTemplate(userContext(testAlice), tmpl.ID, []byte(`{"name":"bars","keywords":["bar"],"depth":3}`))
+	require.NoError(t, err)
+	require.Equal(t, "bars", req.Name)
+	require.Equal(t, []string{"bar"}, req.Keywords)
+	require.Equal(t, 3, req.Depth)
+	require.Equal(t, "en", req.Lang)
+	require.Equal(t, time.Duration(60), req.MaxTime)
+	require.Empty(t, req.Owner)
+
+	require.NoError(t, svc.DeleteTemplate(userContext(testAdmin), tmpl.ID))
+
+	_, err = svc.GetTemplate(userContext(testAlice), tmpl.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewService(newMemRepo(), "").Templates(context.Background())
+	require.ErrorIs(t, err, ErrTemplatesNotSupported)
+}
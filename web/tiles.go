@@ -0,0 +1,187 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// A tile is subdivided when its search was saturated and it was split in
+// smaller tiles that are not done yet.
+const (
+	TileStatePending    = "pending"
+	TileStateDone       = "done"
+	TileStateSubdivided = "subdivided"
+)
+
+// TileProgress is the state of one tile of a bbox job.
+type TileProgress struct {
+	ID        string    `json:"id"`
+	Level     int       `json:"level"`
+	State     string    `json:"state"`
+	Entries   int       `json:"entries"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TileSummary counts the tiles of a job by state.
+type TileSummary struct {
+	Total      int `json:"total"`
+	Pending    int `json:"pending"`
+	Done       int `json:"done"`
+	Subdivided int `json:"subdivided"`
+	Entries    int `json:"entries"`
+}
+
+// TileRepository stores the progress of the tiles of bbox jobs, so a crawl
+// that was interrupted continues with the tiles that are not done yet.
+type TileRepository interface {
+	// SaveTiles inserts the tiles or updates the ones that already exist.
+	SaveTiles(ctx context.Context, jobID string, tiles []TileProgress) error
+	SelectTiles(ctx context.Context, jobID string) ([]TileProgress, error)
+	DeleteTiles(ctx context.Context, jobID string) error
+}
+
+// ErrTilesNotSupported is returned when the repository does not store tile progress.
+var ErrTilesNotSupported = errors.New("tile progress is not supported")
+
+// WithTiles keeps the progress of the tiles of bbox jobs in repo.
+func WithTiles(repo TileRepository) ServiceOption {
+	return func(s *Service) {
+		s.tilesRepo = repo
+	}
+}
+
+// SaveTiles stores the state of the given tiles of a job.
+func (s *Service) SaveTiles(ctx context.Context, jobID string, tiles []TileProgress) error {
+	if s.tilesRepo == nil {
+		return ErrTilesNotSupported
+	}
+
+	return s.tilesRepo.SaveTiles(ctx, jobID, tiles)
+}
+
+// Tiles returns the progress of the tiles of a job, ordered by id.
+// Jobs without a bounding box have no tiles.
+func (s *Service) Tiles(ctx context.Context, jobID string) ([]TileProgress, error) {
+	if s.tilesRepo == nil {
+		return nil, ErrTilesNotSupported
+	}
+
+	return s.tilesRepo.SelectTiles(ctx, jobID)
+}
+
+// RequeueInterrupted puts back in the queue the bbox jobs that were running
+// when the process stopped, so they continue with the tiles that are not done,
+// and with WithCheckpoints resume the jobs that have a checkpoint.
+// It must be called before the worker starts.
+func (s *Service) RequeueInterrupted(ctx context.Context) error {
+	if s.tilesRepo == nil && !s.resume {
+		return nil
+	}
+
+	jobs, err := s.repo.Select(ctx, SelectParams{Status: StatusWorking})
+	if err != nil {
+		return err
+	}
+
+	for i := range jobs {
+		requeue := jobs[i].Data.BBox != "" && s.tilesRepo != nil
+
+		if !requeue && s.resume {
+			requeue, err = s.hasCheckpoint(ctx, jobs[i].ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !requeue {
+			continue
+		}
+
+		jobs[i].Status = StatusPending
+
+		if err := s.Update(ctx, &jobs[i]); err != nil {
+			return fmt.Errorf("failed to requeue job %s: %w", jobs[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// SummarizeTiles counts the tiles by state.
+func SummarizeTiles(tiles []TileProgress) TileSummary {
+	ans := TileSummary{Total: len(tiles)}
+
+	for i := range tiles {
+		switch tiles[i].State {
+		case TileStatePending:
+			ans.Pending++
+		case TileStateDone:
+			ans.Done++
+		case TileStateSubdivided:
+			ans.Subdivided++
+		}
+
+		ans.Entries += tiles[i].Entries
+	}
+
+	return ans
+}
+
+type tilesResponse struct {
+	Summary TileSummary    `json:"summary"`
+	Tiles   []TileProgress `json:"tiles"`
+}
+
+func (s *Server) apiGetJobTiles(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if _, err := s.svc.Get(r.Context(), id.String()); err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	tiles, err := s.svc.Tiles(r.Context(), id.String())
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrTilesNotSupported) {
+			code = http.StatusNotImplemented
+		}
+
+		apiError := apiError{
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, apiError)
+
+		return
+	}
+
+	if tiles == nil {
+		tiles = []TileProgress{}
+	}
+
+	renderJSON(w, http.StatusOK, tilesResponse{
+		Summary: SummarizeTiles(tiles),
+		Tiles:   tiles,
+	})
+}
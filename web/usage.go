@@ -0,0 +1,166 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultTenant is the tenant of the jobs created without one.
+const DefaultTenant = "default"
+
+// TenantUsage is what the jobs of a user and a tenant created in a month
+// used. The tenant is set by whoever creates the job, the user by the server.
+type TenantUsage struct {
+	// User is the id of the user that created the jobs, empty without -auth
+	User   string `json:"user"`
+	Tenant string `json:"tenant"`
+	// Month is in the YYYY-MM format.
+	Month     string `json:"month"`
+	Jobs      int    `json:"jobs"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Places    int    `json:"places"`
+	// Emails is the number of places written with at least one email.
+	Emails int `json:"emails"`
+	// ResultBytes is the size of the result files.
+	ResultBytes int64 `json:"result_bytes"`
+}
+
+var usageCSVHeaders = []string{"user", "tenant", "month", "jobs", "completed", "failed", "places", "emails", "result_bytes"}
+
+func (u *TenantUsage) csvRow() []string {
+	return []string{
+		u.User,
+		u.Tenant,
+		u.Month,
+		strconv.Itoa(u.Jobs),
+		strconv.Itoa(u.Completed),
+		strconv.Itoa(u.Failed),
+		strconv.Itoa(u.Places),
+		strconv.Itoa(u.Emails),
+		strconv.FormatInt(u.ResultBytes, 10),
+	}
+}
+
+// Usage aggregates the jobs created in the month of the given time, in UTC,
+// by user and tenant, of the jobs the user of ctx may see. They are ordered
+// by user then tenant.
+func (s *Service) Usage(ctx context.Context, month time.Time) ([]TenantUsage, error) {
+	jobs, err := s.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	label := start.Format("2006-01")
+
+	type usageKey struct{ user, tenant string }
+
+	byTenant := make(map[usageKey]*TenantUsage)
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		if job.Date.Before(start) || !job.Date.Before(end) {
+			continue
+		}
+
+		tenant := job.Data.Tenant
+		if tenant == "" {
+			tenant = DefaultTenant
+		}
+
+		k := usageKey{user: job.Data.Owner, tenant: tenant}
+
+		u, ok := byTenant[k]
+		if !ok {
+			u = &TenantUsage{User: k.user, Tenant: tenant, Month: label}
+			byTenant[k] = u
+		}
+
+		u.Jobs++
+
+		switch job.Status {
+		case StatusOK:
+			u.Completed++
+		case StatusFailed:
+			u.Failed++
+		}
+
+		u.Places += job.Stats.Places
+		u.Emails += job.Stats.Emails
+		u.ResultBytes += job.Stats.ResultBytes
+	}
+
+	ans := make([]TenantUsage, 0, len(byTenant))
+	for _, u := range byTenant {
+		ans = append(ans, *u)
+	}
+
+	sort.Slice(ans, func(i, j int) bool {
+		if ans[i].User != ans[j].User {
+			return ans[i].User < ans[j].User
+		}
+
+		return ans[i].Tenant < ans[j].Tenant
+	})
+
+	return ans, nil
+}
+
+func (s *Server) apiGetUsage(w http.ResponseWriter, r *http.Request) {
+	month := time.Now().UTC()
+
+	if v := r.URL.Query().Get("month"); v != "" {
+		var err error
+
+		month, err = time.Parse("2006-01", v)
+		if err != nil {
+			apiError := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "month must be in the YYYY-MM format",
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+			return
+		}
+	}
+
+	usage, err := s.svc.Usage(r.Context(), month)
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		renderJSON(w, http.StatusOK, usage)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=usage-%s.csv", month.Format("2006-01")))
+
+	cw := csv.NewWriter(w)
+
+	_ = cw.Write(usageCSVHeaders)
+
+	for i := range usage {
+		_ = cw.Write(usage[i].csvRow())
+	}
+
+	cw.Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -17,6 +18,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/health"
 )
 
 //go:embed static
@@ -26,12 +30,36 @@ type Server struct {
 	tmpl map[string]*template.Template
 	srv  *http.Server
 	svc  *Service
+
+	slackSigningSecret string
+	debugToken         string
+	browserStats       BrowserStatsFunc
+	started            time.Time
+
+	// auth requires the users to sign in, oidc is nil without OIDC
+	auth       bool
+	oidc       *oidcProvider
+	keyLimiter *keyLimiter
+	logins     *loginLimiter
+
+	// openAPISpec is the specification of the API as json
+	openAPISpec []byte
 }
 
-func New(svc *Service, addr string) (*Server, error) {
+type ServerOption func(*Server)
+
+// WithSlackSigningSecret enables the Slack slash command endpoint.
+func WithSlackSigningSecret(secret string) ServerOption {
+	return func(s *Server) {
+		s.slackSigningSecret = secret
+	}
+}
+
+func New(svc *Service, addr string, opts ...ServerOption) (*Server, error) {
 	ans := Server{
-		svc:  svc,
-		tmpl: make(map[string]*template.Template),
+		svc:     svc,
+		tmpl:    make(map[string]*template.Template),
+		started: time.Now(),
 		srv: &http.Server{
 			Addr:              addr,
 			ReadHeaderTimeout: 10 * time.Second,
@@ -42,6 +70,17 @@ func New(svc *Service, addr string) (*Server, error) {
 		},
 	}
 
+	for _, opt := range opts {
+		opt(&ans)
+	}
+
+	spec, err := openAPISpec()
+	if err != nil {
+		return nil, fmt.Errorf("invalid openapi specification: %w", err)
+	}
+
+	ans.openAPISpec = spec
+
 	staticFS, err := fs.Sub(static, "static")
 	if err != nil {
 		return nil, err
@@ -62,11 +101,37 @@ func New(svc *Service, addr string) (*Server, error) {
 
 		ans.delete(w, r)
 	})
+	mux.HandleFunc("/progress", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		ans.progress(w, r)
+	})
+	mux.HandleFunc("/deadletters", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		ans.deadLetters(w, r)
+	})
 	mux.HandleFunc("/jobs", ans.getJobs)
+	mux.HandleFunc("/status", ans.status)
+	mux.HandleFunc("/metrics", ans.metrics)
+
+	if ans.slackSigningSecret != "" {
+		mux.HandleFunc("/integrations/slack/command", ans.slackCommand)
+	}
+
+	if ans.debugToken != "" {
+		ans.registerDebug(mux)
+	}
+
+	if ans.auth {
+		ans.registerAuth(mux)
+	}
 	mux.HandleFunc("/", ans.index)
 
 	// api routes
 	mux.HandleFunc("/api/docs", ans.redocHandler)
+	mux.HandleFunc("/api/openapi.json", ans.openAPI)
+	mux.Handle("/api/health", health.Handler())
 	mux.HandleFunc("/api/v1/jobs", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -99,12 +164,470 @@ func New(svc *Service, addr string) (*Server, error) {
 
 			renderJSON(w, http.StatusMethodNotAllowed, ans)
 		}
-	})
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/download", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.download(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/results", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetJobResults(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/definition", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetJobDefinition(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/clone", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodPost {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiCloneJob(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/pause", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodPost {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiChangeJobState(w, r, ans.svc.Pause)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/pin", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		switch r.Method {
+		case http.MethodPost:
+			ans.apiChangeJobState(w, r, ans.svc.Pin)
+		case http.MethodDelete:
+			ans.apiChangeJobState(w, r, ans.svc.Unpin)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodPost {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiChangeJobState(w, r, ans.svc.Resume)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/dedup", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		switch r.Method {
+		case http.MethodGet:
+			ans.apiGetJobDedup(w, r)
+		case http.MethodPut:
+			ans.apiImportJobDedup(w, r)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/progress", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetJobProgress(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiJobEvents(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/deadletters", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetDeadLetters(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/errors", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetJobErrors(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/deadletters/requeue", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodPost {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiRequeueDeadLetters(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiJobEvents(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/tiles", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetJobTiles(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetMetrics(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/metrics/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetMetricHistory(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/geocode", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiResolvePlaces(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/usage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetUsage(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/places", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetPlaces(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/audit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetAudit(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/schedules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			ans.apiCreateSchedule(w, r)
+		case http.MethodGet:
+			ans.apiGetSchedules(w, r)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/schedules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		switch r.Method {
+		case http.MethodGet:
+			ans.apiGetSchedule(w, r)
+		case http.MethodDelete:
+			ans.apiDeleteSchedule(w, r)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/schedules/{id}/history", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		if r.Method != http.MethodGet {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiGetScheduleHistory(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/templates", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			ans.apiCreateTemplate(w, r)
+		case http.MethodGet:
+			ans.apiGetTemplates(w, r)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/templates/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r = requestWithID(r)
+
+		switch r.Method {
+		case http.MethodGet:
+			ans.apiGetTemplate(w, r)
+		case http.MethodDelete:
+			ans.apiDeleteTemplate(w, r)
+		default:
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/enrich", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
+
+		ans.apiEnrich(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/bulk", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			ans := apiError{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "Method not allowed",
+			}
+
+			renderJSON(w, http.StatusMethodNotAllowed, ans)
+
+			return
+		}
 
-	mux.HandleFunc("/api/v1/jobs/{id}/download", func(w http.ResponseWriter, r *http.Request) {
-		r = requestWithID(r)
+		ans.apiBulkCreateJobs(w, r)
+	})
 
-		if r.Method != http.MethodGet {
+	mux.HandleFunc("/api/v1/jobs/validate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
 			ans := apiError{
 				Code:    http.StatusMethodNotAllowed,
 				Message: "Method not allowed",
@@ -115,10 +638,16 @@ func New(svc *Service, addr string) (*Server, error) {
 			return
 		}
 
-		ans.download(w, r)
+		ans.apiValidateJobDefinition(w, r)
 	})
 
-	handler := securityHeaders(mux)
+	var handler http.Handler = mux
+
+	if ans.auth {
+		handler = ans.authenticate(handler)
+	}
+
+	handler = securityHeaders(handler)
 	ans.srv.Handler = handler
 
 	tmplsKeys := []string{
@@ -126,6 +655,11 @@ func New(svc *Service, addr string) (*Server, error) {
 		"static/templates/job_rows.html",
 		"static/templates/job_row.html",
 		"static/templates/redoc.html",
+		"static/templates/status.html",
+		"static/templates/metrics.html",
+		"static/templates/job_progress.html",
+		"static/templates/login.html",
+		"static/templates/dead_letters.html",
 	}
 
 	for _, key := range tmplsKeys {
@@ -169,6 +703,7 @@ type formData struct {
 	MaxTime  string
 	Keywords []string
 	Language string
+	Region   string
 	Zoom     int
 	FastMode bool
 	Radius   int
@@ -178,6 +713,19 @@ type formData struct {
 	Email    bool
 	Proxies  []string
 	UseCroxy bool
+	SortBy   string
+	// ProxyGroups are the proxy groups of the server to choose from
+	ProxyGroups []string
+	// CaptchaSolving is set when the server has a captcha solver
+	CaptchaSolving bool
+	// Translation is set when the server has a translator
+	Translation bool
+	// TemplatesEnabled is set when the job templates are stored, Templates
+	// are the ones of the user to start from
+	TemplatesEnabled bool
+	Templates        []JobTemplate
+	// User is the signed in user, nil without -auth
+	User *User
 }
 
 type ctxKey string
@@ -241,6 +789,18 @@ func (s *Server) index(w http.ResponseWriter, r *http.Request) {
 		Depth:    10,
 		Email:    false,
 		UseCroxy: false,
+
+		ProxyGroups:    s.svc.ProxyGroups(),
+		CaptchaSolving: s.svc.CaptchaSolving(),
+		Translation:    s.svc.Translation(),
+
+		TemplatesEnabled: s.svc.TemplatesEnabled(),
+	}
+
+	data.User, _ = UserFromContext(r.Context())
+
+	if data.TemplatesEnabled {
+		data.Templates, _ = s.svc.Templates(r.Context())
 	}
 
 	_ = tmpl.Execute(w, data)
@@ -265,7 +825,9 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 		Name:   r.Form.Get("name"),
 		Date:   time.Now().UTC(),
 		Status: StatusPending,
-		Data:   JobData{},
+		Data: JobData{
+			Tenant: strings.TrimSpace(r.Form.Get("tenant")),
+		},
 	}
 
 	maxTimeStr := r.Form.Get("maxtime")
@@ -303,6 +865,7 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 	}
 
 	newJob.Data.Lang = r.Form.Get("lang")
+	newJob.Data.Region = strings.ToLower(r.Form.Get("region"))
 
 	newJob.Data.Zoom, err = strconv.Atoi(r.Form.Get("zoom"))
 	if err != nil {
@@ -324,6 +887,43 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 
 	newJob.Data.Lat = r.Form.Get("latitude")
 	newJob.Data.Lon = r.Form.Get("longitude")
+	newJob.Data.Place = strings.TrimSpace(r.Form.Get("place"))
+
+	if tileRadius := r.Form.Get("tileradius"); tileRadius != "" {
+		newJob.Data.TileRadius, err = strconv.Atoi(tileRadius)
+		if err != nil {
+			http.Error(w, "invalid tile radius", http.StatusUnprocessableEntity)
+
+			return
+		}
+	}
+
+	if bbox := strings.TrimSpace(r.Form.Get("bbox")); bbox != "" {
+		newJob.Data.BBox = bbox
+		newJob.Data.TileSystem = r.Form.Get("tilesystem")
+
+		newJob.Data.TileLevel, err = strconv.Atoi(r.Form.Get("tilelevel"))
+		if err != nil {
+			http.Error(w, "invalid tile level", http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		for _, zone := range strings.Split(r.Form.Get("bboxexclude"), "\n") {
+			if zone = strings.TrimSpace(zone); zone != "" {
+				newJob.Data.BBoxExclude = append(newJob.Data.BBoxExclude, zone)
+			}
+		}
+
+		if maxLevel := r.Form.Get("tilemaxlevel"); maxLevel != "" {
+			newJob.Data.TileMaxLevel, err = strconv.Atoi(maxLevel)
+			if err != nil {
+				http.Error(w, "invalid tile max level", http.StatusUnprocessableEntity)
+
+				return
+			}
+		}
+	}
 
 	newJob.Data.Depth, err = strconv.Atoi(r.Form.Get("depth"))
 	if err != nil {
@@ -335,6 +935,21 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 	newJob.Data.Email = r.Form.Get("email") == "on"
 
 	newJob.Data.UseCroxy = r.Form.Get("usecroxy") == "on"
+	newJob.Data.Fallback = strings.TrimSpace(r.Form.Get("fallback"))
+	newJob.Data.RotateFingerprint = r.Form.Get("rotatefingerprint") == "on"
+	newJob.Data.BrowserEngine = r.Form.Get("browserengine")
+	newJob.Data.Headful = r.Form.Get("headful") == "on"
+	newJob.Data.Screenshots = r.Form.Get("screenshots") == "on"
+
+	newJob.Data.SortBy = r.Form.Get("sortby")
+
+	newJob.Data.FuzzyDedup = r.Form.Get("fuzzydedup") == "on"
+	newJob.Data.StableOutput = r.Form.Get("stableoutput") == "on"
+	newJob.Data.Normalize = r.Form.Get("normalize") == "on"
+	newJob.Data.StripEmojis = newJob.Data.Normalize && r.Form.Get("stripemojis") == "on"
+	newJob.Data.ReviewInsights = r.Form.Get("reviewinsights") == "on"
+	newJob.Data.NoiseFilter = r.Form.Get("noisefilter") == "on"
+	newJob.Data.Profile = r.Form.Get("profile")
 
 	proxies := strings.Split(r.Form.Get("proxies"), "\n")
 	if len(proxies) > 0 {
@@ -348,14 +963,94 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	headers, err := gmaps.ParseHeaders(strings.Split(r.Form.Get("headers"), "\n"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	if len(headers) > 0 {
+		newJob.Data.Headers = headers
+	}
+
+	newJob.Data.Cookies = strings.TrimSpace(r.Form.Get("cookies"))
+	newJob.Data.ProxyGroup = r.Form.Get("proxy_group")
+	newJob.Data.SolveCaptchas = r.Form.Get("solvecaptchas") == "on"
+	newJob.Data.TranslateTo = r.Form.Get("translateto")
+
+	if priority := r.Form.Get("priority"); priority != "" {
+		newJob.Data.Priority, err = strconv.Atoi(priority)
+		if err != nil {
+			http.Error(w, "invalid priority", http.StatusUnprocessableEntity)
+
+			return
+		}
+	}
+
+	if share := r.Form.Get("share"); share != "" {
+		newJob.Data.Share, err = strconv.Atoi(share)
+		if err != nil {
+			http.Error(w, "invalid share", http.StatusUnprocessableEntity)
+
+			return
+		}
+	}
+
+	csvLayout := gmaps.CSVLayout{
+		Delimiter: r.Form.Get("csvdelimiter"),
+		Nested:    r.Form.Get("csvnested"),
+		Extended:  r.Form.Get("csvextended") == "on",
+	}
+
+	for _, col := range strings.Split(r.Form.Get("csvcolumns"), ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			csvLayout.Columns = append(csvLayout.Columns, col)
+		}
+	}
+
+	if r.Form.Get("csvquoteall") == "on" {
+		csvLayout.Quote = gmaps.CSVQuoteAll
+	}
+
+	if !csvLayout.IsDefault() {
+		newJob.Data.CSV = &csvLayout
+	}
+
+	if webhook := strings.TrimSpace(r.Form.Get("webhook")); webhook != "" {
+		newJob.Data.Webhooks = []Webhook{{URL: webhook, Secret: r.Form.Get("webhooksecret")}}
+	}
+
 	err = newJob.Validate()
+	if err == nil {
+		err = s.svc.ValidateServerSettings(&newJob.Data)
+	}
+
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 
 		return
 	}
 
+	// the settings are saved first, so a job is not created twice when
+	// they cannot be
+	if name := strings.TrimSpace(r.Form.Get("template_name")); name != "" {
+		t := JobTemplate{Name: name, Data: newJob.Data}
+
+		if err := s.svc.CreateTemplate(r.Context(), &t); err != nil {
+			http.Error(w, "failed to save the template: "+err.Error(), http.StatusUnprocessableEntity)
+
+			return
+		}
+	}
+
 	err = s.svc.Create(r.Context(), &newJob)
+	if errors.Is(err, ErrInvalidDependency) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+		return
+	}
+
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 
@@ -385,7 +1080,7 @@ func (s *Server) getJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jobs, err := s.svc.All(context.Background())
+	jobs, err := s.svc.All(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 
@@ -411,6 +1106,23 @@ func (s *Server) download(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, err := s.svc.Get(ctx, id.String()); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	downloadURL, err := s.svc.GetDownloadURL(ctx, id.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if downloadURL != "" {
+		s.svc.audit(ctx, AuditJobDownload, id.String(), "csv from the object store")
+		http.Redirect(w, r, downloadURL, http.StatusFound)
+		return
+	}
+
 	filePath, err := s.svc.GetCSV(ctx, id.String())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -424,6 +1136,8 @@ func (s *Server) download(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
+	s.svc.audit(ctx, AuditJobDownload, id.String(), "csv")
+
 	fileName := filepath.Base(filePath)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
 	w.Header().Set("Content-Type", "text/csv")
@@ -435,6 +1149,40 @@ func (s *Server) download(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// status is a public read-only summary of the scraper health. It is meant to be
+// embedded by other products so it does not expose any job details.
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	summary := s.svc.Status(r.Context())
+
+	code := http.StatusOK
+	if summary.Health == HealthDown {
+		code = http.StatusServiceUnavailable
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		renderJSON(w, code, summary)
+
+		return
+	}
+
+	tmpl, ok := s.tmpl["static/templates/status.html"]
+	if !ok {
+		http.Error(w, "missing tpl", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(code)
+
+	_ = tmpl.Execute(w, summary)
+}
+
 func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -466,11 +1214,15 @@ type apiError struct {
 
 type apiScrapeRequest struct {
 	Name string
+	// TemplateID is the template whose settings the fields of the request
+	// override
+	TemplateID string `json:"template_id,omitempty"`
 	JobData
 }
 
 type apiScrapeResponse struct {
-	ID string `json:"id"`
+	ID       string   `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 func (s *Server) redocHandler(w http.ResponseWriter, _ *http.Request) {
@@ -487,7 +1239,11 @@ func (s *Server) redocHandler(w http.ResponseWriter, _ *http.Request) {
 func (s *Server) apiScrape(w http.ResponseWriter, r *http.Request) {
 	var req apiScrapeRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+
 	if err != nil {
 		ans := apiError{
 			Code:    http.StatusUnprocessableEntity,
@@ -499,6 +1255,26 @@ func (s *Server) apiScrape(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.TemplateID != "" {
+		req, err = s.applyTemplate(r.Context(), req.TemplateID, body)
+
+		switch {
+		case errors.Is(err, ErrTemplatesNotSupported):
+			renderTemplateError(w, err)
+
+			return
+		case err != nil:
+			ans := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: err.Error(),
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, ans)
+
+			return
+		}
+	}
+
 	newJob := Job{
 		ID:     uuid.New().String(),
 		Name:   req.Name,
@@ -511,6 +1287,10 @@ func (s *Server) apiScrape(w http.ResponseWriter, r *http.Request) {
 	newJob.Data.MaxTime *= time.Second
 
 	err = newJob.Validate()
+	if err == nil {
+		err = s.svc.ValidateServerSettings(&newJob.Data)
+	}
+
 	if err != nil {
 		ans := apiError{
 			Code:    http.StatusUnprocessableEntity,
@@ -524,18 +1304,123 @@ func (s *Server) apiScrape(w http.ResponseWriter, r *http.Request) {
 
 	err = s.svc.Create(r.Context(), &newJob)
 	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrInvalidDependency):
+			code = http.StatusUnprocessableEntity
+		case errors.Is(err, ErrQuotaExceeded):
+			code = http.StatusTooManyRequests
+		}
+
 		ans := apiError{
-			Code:    http.StatusInternalServerError,
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, ans)
+
+		return
+	}
+
+	ans := apiScrapeResponse{
+		ID:       newJob.ID,
+		Warnings: newJob.Data.Warnings(),
+	}
+
+	renderJSON(w, http.StatusCreated, ans)
+}
+
+// maxEnrichInput is the maximum size of the input uploaded to /api/v1/enrich.
+const maxEnrichInput = 64 << 20
+
+// apiEnrich creates a job that crawls the websites of the places in the
+// uploaded results csv, or in the uploaded list of websites, for emails.
+func (s *Server) apiEnrich(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	data := JobData{
+		Lang:      q.Get("lang"),
+		Transform: q.Get("transform"),
+		Tenant:    q.Get("tenant"),
+	}
+
+	if data.Lang == "" {
+		data.Lang = "en"
+	}
+
+	if data.Transform == "" {
+		data.Transform = TransformEmails
+	}
+
+	if v := q.Get("max_time"); v != "" {
+		maxTime, err := strconv.Atoi(v)
+		if err != nil || maxTime <= 0 {
+			ans := apiError{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "invalid max_time",
+			}
+
+			renderJSON(w, http.StatusUnprocessableEntity, ans)
+
+			return
+		}
+
+		data.MaxTime = time.Duration(maxTime) * time.Second
+	}
+
+	newJob := Job{
+		ID:     uuid.New().String(),
+		Name:   q.Get("name"),
+		Date:   time.Now().UTC(),
+		Status: StatusPending,
+		Data:   data,
+	}
+
+	err := newJob.Validate()
+	if err == nil {
+		err = s.svc.ValidateServerSettings(&newJob.Data)
+	}
+
+	if err != nil {
+		ans := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, ans)
+
+		return
+	}
+
+	err = s.svc.SaveInput(newJob.ID, http.MaxBytesReader(w, r.Body, maxEnrichInput))
+	if err == nil {
+		err = s.svc.Create(r.Context(), &newJob)
+		if err != nil {
+			_ = os.Remove(s.svc.InputPath(newJob.ID))
+		}
+	}
+
+	if err != nil {
+		code := http.StatusInternalServerError
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.Is(err, ErrInvalidDependency) || errors.As(err, &maxBytesErr) {
+			code = http.StatusUnprocessableEntity
+		}
+
+		ans := apiError{
+			Code:    code,
 			Message: err.Error(),
 		}
 
-		renderJSON(w, http.StatusInternalServerError, ans)
+		renderJSON(w, code, ans)
 
 		return
 	}
 
 	ans := apiScrapeResponse{
-		ID: newJob.ID,
+		ID:       newJob.ID,
+		Warnings: newJob.Data.Warnings(),
 	}
 
 	renderJSON(w, http.StatusCreated, ans)
@@ -554,6 +1439,10 @@ func (s *Server) apiGetJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for i := range jobs {
+		jobs[i].Data = jobs[i].Data.Redacted()
+	}
+
 	renderJSON(w, http.StatusOK, jobs)
 }
 
@@ -582,6 +1471,8 @@ func (s *Server) apiGetJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	job.Data = job.Data.Redacted()
+
 	renderJSON(w, http.StatusOK, job)
 }
 
@@ -613,6 +1504,63 @@ func (s *Server) apiDeleteJob(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (s *Server) apiChangeJobState(w http.ResponseWriter, r *http.Request, fn func(context.Context, string) error) {
+	id, ok := getIDFromRequest(r)
+	if !ok {
+		apiError := apiError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid ID",
+		}
+
+		renderJSON(w, http.StatusUnprocessableEntity, apiError)
+
+		return
+	}
+
+	if _, err := s.svc.Get(r.Context(), id.String()); err != nil {
+		apiError := apiError{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}
+
+		renderJSON(w, http.StatusNotFound, apiError)
+
+		return
+	}
+
+	if err := fn(r.Context(), id.String()); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidTransition) {
+			code = http.StatusConflict
+		}
+
+		apiError := apiError{
+			Code:    code,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, code, apiError)
+
+		return
+	}
+
+	job, err := s.svc.Get(r.Context(), id.String())
+	if err != nil {
+		apiError := apiError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+
+		renderJSON(w, http.StatusInternalServerError, apiError)
+
+		return
+	}
+
+	job.Data = job.Data.Redacted()
+
+	renderJSON(w, http.StatusOK, job)
+}
+
 func renderJSON(w http.ResponseWriter, code int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
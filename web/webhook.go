@@ -0,0 +1,420 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The events a webhook of a job is notified of.
+const (
+	WebhookJobStarted   = "job.started"
+	WebhookJobProgress  = "job.progress"
+	WebhookJobCompleted = "job.completed"
+	WebhookJobFailed    = "job.failed"
+)
+
+const (
+	maxWebhooks          = 10
+	webhookAttempts      = 5
+	webhookTimeout       = 30 * time.Second
+	webhookDownloadValid = 24 * time.Hour
+)
+
+// webhookFirstBackoff is the wait before the second attempt of a
+// notification, doubled before each next one.
+var webhookFirstBackoff = 2 * time.Second
+
+// webhookMilestones are the percents of the progress notified to the
+// webhooks, once each.
+var webhookMilestones = []float64{25, 50, 75}
+
+var webhookEvents = []string{WebhookJobStarted, WebhookJobProgress, WebhookJobCompleted, WebhookJobFailed}
+
+// Webhook is a url notified of the lifecycle of a job, so external systems
+// react to it without polling.
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret signs the notifications, see SignWebhook. A random one is set
+	// when the job is created without it, and it is returned with the job to
+	// the users that may see it
+	Secret string `json:"secret,omitempty"`
+	// Events are the events notified, all of them when empty
+	Events []string `json:"events,omitempty"`
+}
+
+// WebhookPayload is the json posted to a webhook.
+type WebhookPayload struct {
+	// ID is the same for all the attempts of a notification
+	ID      string    `json:"id"`
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	JobID   string    `json:"job_id"`
+	JobName string    `json:"job_name"`
+	Status  string    `json:"status"`
+	// Progress is set for job.progress
+	Progress *JobProgress `json:"progress,omitempty"`
+	// Stats and DownloadURL are set for job.completed. The download url is
+	// empty unless the server has a public url or an object store
+	Stats       *JobStats `json:"stats,omitempty"`
+	DownloadURL string    `json:"download_url,omitempty"`
+}
+
+// WithPublicURL is the url the server is reached at, e.g.
+// https://scraper.example.com, for the download urls sent to the webhooks.
+func WithPublicURL(u string) ServiceOption {
+	return func(s *Service) {
+		s.publicURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// validateWebhooks checks the webhooks of a job.
+func validateWebhooks(webhooks []Webhook) error {
+	if len(webhooks) > maxWebhooks {
+		return fmt.Errorf("at most %d webhooks", maxWebhooks)
+	}
+
+	for i := range webhooks {
+		if err := validateWebhookURL(webhooks[i].URL); err != nil {
+			return err
+		}
+
+		for _, ev := range webhooks[i].Events {
+			if !slices.Contains(webhookEvents, ev) {
+				return fmt.Errorf("invalid webhook event %q, the events are %s", ev, strings.Join(webhookEvents, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateWebhookURL checks the url a webhook posts to. The urls of a host
+// name are checked when they are posted to, see webhookClient.
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook url %q", rawURL)
+	}
+
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !publicWebhookAddr(ip) {
+		return fmt.Errorf("the webhook url %q is not a public address", rawURL)
+	}
+
+	return nil
+}
+
+var allowPrivateWebhooks atomic.Bool
+
+// AllowPrivateWebhooks lets the webhooks and the webhook writers post to
+// the private, loopback and link-local addresses, e.g. a service of the same
+// network. They are refused by default, so the users cannot make the server
+// post to its internal services.
+func AllowPrivateWebhooks(allow bool) {
+	allowPrivateWebhooks.Store(allow)
+}
+
+// publicWebhookAddr reports whether a webhook may post to ip.
+func publicWebhookAddr(ip netip.Addr) bool {
+	if allowPrivateWebhooks.Load() {
+		return true
+	}
+
+	ip = ip.Unmap()
+
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier grade NAT range of RFC 6598.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// setWebhookSecrets sets a random secret for the webhooks and the webhook
+// writers of d that have none, so their posts are always signed.
+func (d *JobData) setWebhookSecrets() error {
+	for i := range d.Webhooks {
+		if d.Webhooks[i].Secret == "" {
+			secret, err := randomToken()
+			if err != nil {
+				return err
+			}
+
+			d.Webhooks[i].Secret = secret
+		}
+	}
+
+	for i := range d.Writers {
+		if d.Writers[i].Type == WriterWebhook && d.Writers[i].Secret == "" {
+			secret, err := randomToken()
+			if err != nil {
+				return err
+			}
+
+			d.Writers[i].Secret = secret
+		}
+	}
+
+	return nil
+}
+
+// notifies reports whether the webhook is notified of ev.
+func (h *Webhook) notifies(ev string) bool {
+	return len(h.Events) == 0 || slices.Contains(h.Events, ev)
+}
+
+// SignWebhook returns the signature of a notification, sent as the
+// X-Webhook-Signature header: sha256= followed by the hex HMAC-SHA256 of
+// the timestamp of the X-Webhook-Timestamp header, a dot and the body, with
+// the secret of the webhook as the key.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyWebhooks notifies the webhooks of job of the change of its status.
+func (s *Service) notifyWebhooks(job *Job) {
+	if len(job.Data.Webhooks) == 0 {
+		return
+	}
+
+	payload := WebhookPayload{
+		JobID:   job.ID,
+		JobName: job.Name,
+		Status:  job.Status,
+	}
+
+	switch job.Status {
+	case StatusWorking:
+		payload.Event = WebhookJobStarted
+
+		if slices.ContainsFunc(job.Data.Webhooks, func(h Webhook) bool { return h.notifies(WebhookJobProgress) }) {
+			go s.watchMilestones(job.ID, job.Name, job.Data.Webhooks)
+		}
+	case StatusOK:
+		payload.Event = WebhookJobCompleted
+		stats := job.Stats
+		payload.Stats = &stats
+		payload.DownloadURL = s.webhookDownloadURL(job)
+	case StatusFailed:
+		payload.Event = WebhookJobFailed
+	default:
+		return
+	}
+
+	s.sendWebhooks(job.Data.Webhooks, &payload)
+}
+
+// watchMilestones notifies the webhooks of the job jobID each time its
+// progress passes a milestone, until it stops working.
+func (s *Service) watchMilestones(jobID, name string, webhooks []Webhook) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	next := 0
+
+	for range ticker.C {
+		s.events.mu.Lock()
+		status := s.events.lastStatus[jobID]
+		s.events.mu.Unlock()
+
+		if status != StatusWorking {
+			return
+		}
+
+		progress := s.liveProgress(jobID)
+		if progress == nil {
+			continue
+		}
+
+		passed := passedMilestones(next, progress.Percent)
+		if passed == next {
+			continue
+		}
+
+		next = passed
+
+		s.sendWebhooks(webhooks, &WebhookPayload{
+			Event:    WebhookJobProgress,
+			JobID:    jobID,
+			JobName:  name,
+			Status:   StatusWorking,
+			Progress: progress,
+		})
+	}
+}
+
+// passedMilestones returns the number of milestones passed at percent when
+// next were notified. The percent can go down, a milestone is notified once.
+func passedMilestones(next int, percent float64) int {
+	for next < len(webhookMilestones) && percent >= webhookMilestones[next] {
+		next++
+	}
+
+	return next
+}
+
+// webhookDownloadURL returns the url the results of job are downloaded
+// from, empty when it is not known.
+func (s *Service) webhookDownloadURL(job *Job) string {
+	if s.store != nil && job.Stats.Uploaded {
+		u, err := s.store.PresignGet(context.Background(), s.storeBucket, s.objectKey(job), webhookDownloadValid)
+		if err == nil {
+			return u
+		}
+	}
+
+	if s.publicURL == "" {
+		return ""
+	}
+
+	return s.publicURL + "/api/v1/jobs/" + job.ID + "/download"
+}
+
+// sendWebhooks posts payload to the webhooks notified of its event, in the
+// background.
+func (s *Service) sendWebhooks(webhooks []Webhook, payload *WebhookPayload) {
+	payload.ID = uuid.New().String()
+	payload.Time = time.Now().UTC()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		s.Logf(payload.JobID, "failed to encode the %s webhook of job %s: %v", payload.Event, payload.JobID, err)
+
+		return
+	}
+
+	for i := range webhooks {
+		if !webhooks[i].notifies(payload.Event) {
+			continue
+		}
+
+		go func(h Webhook) {
+			if err := DeliverWebhook(&h, payload.ID, payload.Event, body); err != nil {
+				s.Logf(payload.JobID, "failed to notify the webhook %s of %s of job %s: %v", h.URL, payload.Event, payload.JobID, err)
+			}
+		}(webhooks[i])
+	}
+}
+
+// errPermanent is a response of a webhook that is not retried.
+var errPermanent = errors.New("permanent failure")
+
+// DeliverWebhook posts body to h as the notification id of event, retried
+// with an exponential backoff while it fails with a network error, a 429 or
+// a 5xx. It is signed with the secret of h.
+func DeliverWebhook(h *Webhook, id, event string, body []byte) error {
+	backoff := webhookFirstBackoff
+
+	var err error
+
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+
+			backoff *= 2
+		}
+
+		err = postWebhook(h, id, event, body)
+		if err == nil || errors.Is(err, errPermanent) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%d attempts: %w", webhookAttempts, err)
+}
+
+func postWebhook(h *Webhook, id, event string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "google-maps-scraper-webhook")
+	req.Header.Set("X-Webhook-ID", id)
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+
+	if h.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", SignWebhook(h.Secret, timestamp, body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: webhook responded with status %d", errPermanent, resp.StatusCode)
+	}
+}
+
+// webhookClient does not follow redirects, a notification is posted to the
+// url of the webhook only. It connects to the public addresses only, checked
+// once the host name is resolved, see AllowPrivateWebhooks.
+var webhookClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: checkWebhookDial,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// errPrivateWebhook is a webhook whose host resolves to an address that is
+// not public.
+var errPrivateWebhook = errors.New("the webhook host is not a public address")
+
+// checkWebhookDial refuses the connections of the webhooks to the addresses
+// that are not public.
+func checkWebhookDial(_, address string, _ syscall.RawConn) error {
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+
+	if !publicWebhookAddr(addr.Addr()) {
+		return fmt.Errorf("%w: %w: %s", errPermanent, errPrivateWebhook, addr.Addr())
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignWebhook(t *testing.T) {
+	require.Equal(t,
+		"sha256=2b9dee6c893e4bf012ad34ee7b89d492b9567b4f47740ccbf0f161ba3717dc08",
+		SignWebhook("s3cret", "1700000000", []byte(`{"id":"1"}`)),
+	)
+}
+
+// allowPrivate lets the webhooks of the test post to the local test servers.
+func allowPrivate(t *testing.T) {
+	t.Helper()
+
+	AllowPrivateWebhooks(true)
+	t.Cleanup(func() { AllowPrivateWebhooks(false) })
+
+	backoff := webhookFirstBackoff
+	webhookFirstBackoff = time.Millisecond
+
+	t.Cleanup(func() { webhookFirstBackoff = backoff })
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	allowPrivate(t)
+
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "n1", r.Header.Get("X-Webhook-ID"))
+		require.Equal(t, WebhookJobCompleted, r.Header.Get("X-Webhook-Event"))
+		require.Equal(t, SignWebhook("s3cret", r.Header.Get("X-Webhook-Timestamp"), []byte("{}")), r.Header.Get("X-Webhook-Signature"))
+
+		// the first attempts fail and are retried
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	h := Webhook{URL: srv.URL, Secret: "s3cret"}
+
+	require.NoError(t, DeliverWebhook(&h, "n1", WebhookJobCompleted, []byte("{}")))
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestDeliverWebhookFailures(t *testing.T) {
+	allowPrivate(t)
+
+	var calls atomic.Int32
+
+	status := http.StatusBadRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	h := Webhook{URL: srv.URL}
+
+	// a 4xx is not retried
+	err := DeliverWebhook(&h, "n1", WebhookJobFailed, []byte("{}"))
+	require.ErrorIs(t, err, errPermanent)
+	require.Equal(t, int32(1), calls.Load())
+
+	// a 5xx is retried until the last attempt
+	calls.Store(0)
+
+	status = http.StatusBadGateway
+
+	err = DeliverWebhook(&h, "n1", WebhookJobFailed, []byte("{}"))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errPermanent)
+	require.Equal(t, int32(webhookAttempts), calls.Load())
+}
+
+func TestDeliverWebhookPrivateAddress(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// the test server listens on a loopback address
+	err := DeliverWebhook(&Webhook{URL: srv.URL}, "n1", WebhookJobStarted, []byte("{}"))
+	require.ErrorIs(t, err, errPrivateWebhook)
+	require.ErrorIs(t, err, errPermanent)
+	require.Zero(t, calls.Load())
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	for _, u := range []string{
+		"https://example.com/hooks",
+		"http://8.8.8.8:8080/hooks",
+	} {
+		require.NoError(t, validateWebhooks([]Webhook{{URL: u}}), u)
+	}
+
+	for _, u := range []string{
+		"ftp://example.com",
+		"https://",
+		"http://127.0.0.1:8080/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hooks",
+		"http://[::1]/hooks",
+		"http://[::ffff:192.168.1.1]/hooks",
+		"http://100.64.0.1/hooks",
+		"http://0.0.0.0/hooks",
+	} {
+		require.Error(t, validateWebhooks([]Webhook{{URL: u}}), u)
+	}
+
+	require.Error(t, validateWebhooks([]Webhook{{URL: "https://example.com", Events: []string{"job.deleted"}}}))
+	require.Error(t, validateWriters([]JobWriter{{Type: WriterWebhook, URL: "http://192.168.0.10/places"}}))
+
+	AllowPrivateWebhooks(true)
+	t.Cleanup(func() { AllowPrivateWebhooks(false) })
+
+	require.NoError(t, validateWebhooks([]Webhook{{URL: "http://127.0.0.1:8080/hooks"}}))
+}
+
+func TestSetWebhookSecrets(t *testing.T) {
+	d := JobData{
+		Webhooks: []Webhook{{URL: "https://example.com/a"}, {URL: "https://example.com/b", Secret: "mine"}},
+		Writers:  []JobWriter{{Type: WriterWebhook, URL: "https://example.com/places"}, {Type: WriterPostgres}},
+	}
+
+	require.NoError(t, d.setWebhookSecrets())
+	require.Len(t, d.Webhooks[0].Secret, 43)
+	require.Equal(t, "mine", d.Webhooks[1].Secret)
+	require.NotEmpty(t, d.Writers[0].Secret)
+	require.NotEqual(t, d.Webhooks[0].Secret, d.Writers[0].Secret)
+	require.Empty(t, d.Writers[1].Secret)
+}
+
+func TestPassedMilestones(t *testing.T) {
+	require.Equal(t, 0, passedMilestones(0, 10))
+	require.Equal(t, 1, passedMilestones(0, 25))
+	require.Equal(t, 3, passedMilestones(0, 90))
+	require.Equal(t, 2, passedMilestones(1, 60))
+
+	// a percent that went down notifies nothing again
+	require.Equal(t, 2, passedMilestones(2, 30))
+	require.Equal(t, 3, passedMilestones(3, 100))
+}
+
+func TestServiceCreateSignsWebhooks(t *testing.T) {
+	svc := NewService(newMemRepo(), t.TempDir())
+
+	job := Job{ID: "1", Data: JobData{Webhooks: []Webhook{{URL: "https://example.com/a"}}}}
+
+	require.NoError(t, svc.Create(context.Background(), &job))
+
+	got, err := svc.Get(context.Background(), "1")
+	require.NoError(t, err)
+	require.NotEmpty(t, got.Data.Webhooks[0].Secret)
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// The types of the writers of the results of a job.
+const (
+	// WriterCSV is the csv file of the job, downloaded from
+	// /api/v1/jobs/{id}/download. It is always written
+	WriterCSV = "csv"
+	// WriterPostgres inserts the places in the results table of the
+	// database of -dsn
+	WriterPostgres = "postgres"
+	// WriterWebhook posts the places to a url in batches, as json
+	WriterWebhook = "webhook"
+	// WriterPlugin is a writer of the plugins of -writer-plugins, chosen by
+	// its name
+	WriterPlugin = "plugin"
+)
+
+const maxWriters = 10
+
+// WriterWebhookEvent is the X-Webhook-Event of the posts of the webhook
+// writer.
+const WriterWebhookEvent = "job.places"
+
+// JobWriter is an output the places of a job are written to, besides its
+// csv file.
+type JobWriter struct {
+	Type string `json:"type"`
+	// URL is the url the webhook writer posts to
+	URL string `json:"url,omitempty"`
+	// Secret signs the posts of the webhook writer like the notifications
+	// of the webhooks, see SignWebhook. A random one is set when it is empty
+	Secret string `json:"secret,omitempty"`
+	// Name is the name of the plugin writer
+	Name string `json:"name,omitempty"`
+	// Config is the configuration of the plugin writer, e.g. the spreadsheet
+	// of a Google Sheets writer
+	Config map[string]any `json:"config,omitempty"`
+}
+
+// validateWriters checks the writers of a job.
+func validateWriters(writers []JobWriter) error {
+	if len(writers) > maxWriters {
+		return fmt.Errorf("at most %d writers", maxWriters)
+	}
+
+	for i := range writers {
+		if writers[i].Type != WriterPlugin && (writers[i].Name != "" || len(writers[i].Config) > 0) {
+			return fmt.Errorf("the %s writer has no name or config", writers[i].Type)
+		}
+
+		switch writers[i].Type {
+		case WriterCSV, WriterPostgres:
+			if writers[i].URL != "" {
+				return fmt.Errorf("the %s writer has no url", writers[i].Type)
+			}
+		case WriterPlugin:
+			if writers[i].Name == "" {
+				return errors.New("missing name of the plugin writer")
+			}
+
+			if writers[i].URL != "" {
+				return fmt.Errorf("the %s writer has no url", writers[i].Type)
+			}
+		case WriterWebhook:
+			if err := validateWebhookURL(writers[i].URL); err != nil {
+				return fmt.Errorf("the webhook writer: %w", err)
+			}
+		case "":
+			return errors.New("missing writer type")
+		default:
+			return fmt.Errorf("unknown writer type %q", writers[i].Type)
+		}
+	}
+
+	return nil
+}
+
+// WithWriters sets the types of the writers the jobs can use, besides the
+// csv file.
+func WithWriters(types []string) ServiceOption {
+	return func(s *Service) {
+		s.writerTypes = types
+	}
+}
+
+// WithPluginWriters sets the names of the plugin writers the jobs can use.
+func WithPluginWriters(names []string) ServiceOption {
+	return func(s *Service) {
+		s.pluginWriters = names
+	}
+}
+
+// WriterTypes returns the types of the writers the jobs can use.
+func (s *Service) WriterTypes() []string {
+	return s.writerTypes
+}
+
+// validateWriterTypes returns an error when a writer of the job is not
+// available on the server.
+func (s *Service) validateWriterTypes(writers []JobWriter) error {
+	for i := range writers {
+		if writers[i].Type == WriterPlugin {
+			if !slices.Contains(s.pluginWriters, writers[i].Name) {
+				return fmt.Errorf("the plugin writer %s is not available on the server", writers[i].Name)
+			}
+
+			continue
+		}
+
+		if writers[i].Type == WriterCSV || slices.Contains(s.writerTypes, writers[i].Type) {
+			continue
+		}
+
+		return fmt.Errorf("the %s writer is not available on the server", writers[i].Type)
+	}
+
+	return nil
+}
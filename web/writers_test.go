@@ -0,0 +1,44 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWriters(t *testing.T) {
+	require.NoError(t, validateWriters([]JobWriter{
+		{Type: WriterCSV},
+		{Type: WriterPostgres},
+		{Type: WriterWebhook, URL: "https://example.com/places"},
+		{Type: WriterPlugin, Name: "sheets", Config: map[string]any{"sheet": "leads"}},
+	}))
+
+	for _, writers := range [][]JobWriter{
+		{{}},
+		{{Type: "s3"}},
+		{{Type: WriterPostgres, URL: "https://example.com"}},
+		{{Type: WriterWebhook}},
+		{{Type: WriterPlugin}},
+		{{Type: WriterPlugin, Name: "sheets", URL: "https://example.com"}},
+		{{Type: WriterCSV, Name: "sheets"}},
+		{{Type: WriterPostgres, Config: map[string]any{"table": "places"}}},
+		make([]JobWriter, maxWriters+1),
+	} {
+		require.Error(t, validateWriters(writers), writers)
+	}
+}
+
+func TestValidateWriterTypes(t *testing.T) {
+	svc := NewService(newMemRepo(), "", WithWriters([]string{WriterWebhook}), WithPluginWriters([]string{"sheets"}))
+
+	// the csv file is always written
+	require.NoError(t, svc.validateWriterTypes([]JobWriter{
+		{Type: WriterCSV},
+		{Type: WriterWebhook},
+		{Type: WriterPlugin, Name: "sheets"},
+	}))
+
+	require.ErrorContains(t, svc.validateWriterTypes([]JobWriter{{Type: WriterPostgres}}), "postgres writer is not available")
+	require.ErrorContains(t, svc.validateWriterTypes([]JobWriter{{Type: WriterPlugin, Name: "s3"}}), "plugin writer s3 is not available")
+}